@@ -33,6 +33,8 @@ import { isRegularMouseClick } from '@utils/mouse';
 import { isE2ETestEnvironment } from '@utils/e2e';
 import { useKernelInstanceEvents, useKernelInstancesQuery } from '@hooks/code';
 import { useAllPathsInvalidation } from '@hooks/all-paths';
+import { useImportProgressToasts } from '@hooks/imports';
+import { useUnchangedLinksToast } from '@hooks/notes';
 
 const KernelInfo = lazy(() =>
   import('@/routes/kernel-info').then((module) => ({
@@ -63,6 +65,8 @@ function App() {
 
   useTagEvents();
   useAllPathsInvalidation();
+  useImportProgressToasts();
+  useUnchangedLinksToast();
   useThemeSetting();
   useProjectSettings();
   useKernelInstancesQuery();
@@ -5,6 +5,7 @@ import {
   type ContextMenuData,
   type DialogDataType,
   type KernelInstanceData,
+  type KernelScope,
   type LanguagesWithKernels,
   type ProjectSettings,
 } from '@/types';
@@ -79,6 +80,7 @@ export const mostRecentItemsAtom = atom<
 export const projectSettingsAtom = atom<ProjectSettings>({
   pinnedNotes: new Set<string>([]),
   projectPath: '',
+  linkedFolders: [],
   appearance: {
     theme: 'light',
     accentColor: 'rgb(96, 165, 250)',
@@ -107,6 +109,9 @@ export const projectSettingsAtom = atom<ProjectSettings>({
     pythonVenvPath: '',
     customPythonVenvPaths: [],
   },
+  attachments: {
+    keepDuplicates: false,
+  },
 });
 
 // Tracks whether project settings have been loaded from the backend at least once.
@@ -191,6 +196,39 @@ export const kernelInstancesByLanguageAtom = atom((get) =>
   }, structuredClone(EMPTY_KERNEL_INSTANCES_BY_LANGUAGE))
 );
 
+/**
+ * Map of `${noteId}|${language}` -> the kernel scope the backend resolved for
+ * that note, filled in by useKernelScopeQuery.
+ */
+export const kernelScopesAtom = atom<Record<string, KernelScope>>({});
+
+export function kernelScopeKey(noteId: string, language: string) {
+  return `${noteId}|${language}`;
+}
+
+/**
+ * Finds the instance serving (noteId, language). Folder and project kernels
+ * are shared, so instances are matched on the note's resolved scope; until
+ * the scope is known, only the kernel the note started itself matches.
+ */
+export function findKernelInstanceForNote(
+  instances: Record<string, KernelInstanceData>,
+  scopes: Record<string, KernelScope>,
+  noteId: string,
+  language: LanguagesWithKernels
+): KernelInstanceData | null {
+  const scope = scopes[kernelScopeKey(noteId, language)];
+  return (
+    Object.values(instances).find(
+      (i) =>
+        i.language === language &&
+        (scope
+          ? i.scopeType === scope.scopeType && i.scopeId === scope.scopeId
+          : i.noteId === noteId)
+    ) ?? null
+  );
+}
+
 /**
  * Derived: the instance for a given (noteId, language), or null if none exists.
  *
@@ -201,14 +239,14 @@ export const kernelInstancesByLanguageAtom = atom((get) =>
  */
 export const kernelInstanceForNoteAtomFamily = atomFamily(
   ({ noteId, language }: { noteId: string; language: LanguagesWithKernels }) =>
-    atom((get) => {
-      const all = get(kernelInstancesAtom);
-      return (
-        Object.values(all).find(
-          (i) => i.noteId === noteId && i.language === language
-        ) ?? null
-      );
-    }),
+    atom((get) =>
+      findKernelInstanceForNote(
+        get(kernelInstancesAtom),
+        get(kernelScopesAtom),
+        noteId,
+        language
+      )
+    ),
   (a, b) => a.noteId === b.noteId && a.language === b.language
 );
 
@@ -4,7 +4,11 @@ import { MotionIconButton } from '@components/buttons';
 import { Duplicate2 } from '@/icons/duplicate-2';
 import { motion } from 'motion/react';
 import { cn } from '@utils/string-formatting';
-import { useSendInputReplyMutation } from '@hooks/code';
+import {
+  useSendInputReplyMutation,
+  useShowTruncatedOutputMutation,
+} from '@hooks/code';
+import { useCodeBlockWidgets } from '@hooks/code-comms';
 import type { CodeBlockResultProps } from './types';
 
 const RUNNING_STATUS_TEXT = 'Running';
@@ -101,7 +105,7 @@ export function CodeResult({
 }: CodeBlockResultProps) {
   const { id } = identity;
   const { executionId, status } = execution;
-  const { isExpanded, codeMirrorInstance } = shell;
+  const { isExpanded, codeMirrorInstance, lexicalEditor } = shell;
   const {
     lastExecutedResult,
     setLastExecutedResult,
@@ -121,6 +125,13 @@ export function CodeResult({
     id,
     execution.kernelInstanceId
   );
+  const { mutate: showTruncatedOutput, isPending: isShowingTruncatedOutput } =
+    useShowTruncatedOutputMutation(
+      lexicalEditor,
+      id,
+      execution.kernelInstanceId
+    );
+  useCodeBlockWidgets(resultContainerRef, id, lastExecutedResult);
 
   return (
     <motion.footer
@@ -192,6 +203,18 @@ export function CodeResult({
               value: inputEl.value,
             });
           }}
+          onClick={(e) => {
+            // "Show more" buttons on held-back output notices
+            const notice = (e.target as HTMLElement)
+              .closest('button')
+              ?.closest<HTMLElement>('[data-truncated-handle]');
+            const handle = notice?.dataset.truncatedHandle;
+            if (!notice || !handle || isShowingTruncatedOutput) return;
+            showTruncatedOutput({
+              handle,
+              offset: Number(notice.dataset.truncatedOffset ?? 0),
+            });
+          }}
           aria-live="polite"
           dangerouslySetInnerHTML={{ __html: lastExecutedResult }}
           className={cn(
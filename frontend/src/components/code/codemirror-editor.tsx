@@ -13,6 +13,7 @@ import {
 import { useDecodedNotesWildcardPath } from '@hooks/routes';
 import { useInspectTooltip } from '@hooks/code-codemirror';
 import { useCompletionExtension } from '@hooks/code-completion';
+import { useDiagnosticsExtension } from '@hooks/lsp';
 import { getCodemirrorKeymap } from '@utils/codemirror';
 import { focusEditor } from '@components/code';
 import { useLexicalNodeSelection } from '@lexical/react/useLexicalNodeSelection';
@@ -23,7 +24,7 @@ import type {
   CodeBlockShellProps,
 } from './types';
 import { cn } from '@utils/string-formatting';
-import { LANGUAGES, type Languages } from '@/types';
+import { LANGUAGES, type Languages, isValidKernelLanguage } from '@/types';
 import { useNodeInNodeSelection } from '@hooks/lexical';
 import { useEffect, useRef, useState, type WheelEvent } from 'react';
 import { languageDisplayConfig } from './language-config';
@@ -119,6 +120,12 @@ export function CodeMirrorEditor({
     kernelInstanceId,
     executionId,
   });
+  const diagnosticsExtension = useDiagnosticsExtension({
+    language,
+    noteId,
+    blockId: id,
+    view: codeMirrorInstance?.view,
+  });
 
   const debouncedSetCode = debounce(setCode, 300);
   const projectSettings = useAtomValue(projectSettingsAtom);
@@ -180,10 +187,10 @@ export function CodeMirrorEditor({
       clearTimeout(lspNotifyTimeoutRef.current);
     }
 
-    if (language !== 'python' || !noteId) return;
+    if (!isValidKernelLanguage(language) || !noteId) return;
 
     lspNotifyTimeoutRef.current = setTimeout(() => {
-      void NotifyBlockEdit(noteId, id, blockOrder, newSource).catch(
+      void NotifyBlockEdit(noteId, language, id, blockOrder, newSource).catch(
         () => undefined
       );
     }, 100);
@@ -204,8 +211,8 @@ export function CodeMirrorEditor({
       if (lspNotifyTimeoutRef.current) {
         clearTimeout(lspNotifyTimeoutRef.current);
       }
-      if (language !== 'python' || !noteId) return;
-      void NotifyBlockRemoved(noteId, id).catch(() => undefined);
+      if (!isValidKernelLanguage(language) || !noteId) return;
+      void NotifyBlockRemoved(noteId, language, id).catch(() => undefined);
     };
   }, [language, noteId, id]);
 
@@ -290,6 +297,7 @@ export function CodeMirrorEditor({
             ? [loadedLanguage.extension]
             : []),
           completionExtension,
+          diagnosticsExtension,
           inspectTooltip,
         ]}
         theme={isDarkModeOn ? vscodeDark : vscodeLight}
@@ -1,6 +1,7 @@
 import { Play } from '@/icons/circle-play';
 import {
   useEnsureKernelMutation,
+  useKernelScopeQuery,
   useSendExecuteRequestMutation,
   useSendInterruptRequestMutation,
 } from '@hooks/code';
@@ -15,7 +16,11 @@ import {
 import { MediaStop } from '@/icons/media-stop';
 import { Loader } from '@/icons/loader';
 import { getDefaultStore } from 'jotai';
-import { kernelInstancesAtom } from '@/atoms';
+import {
+  findKernelInstanceForNote,
+  kernelInstancesAtom,
+  kernelScopesAtom,
+} from '@/atoms';
 import { Tooltip } from '@components/tooltip';
 import { MotionIconButton } from '@components/buttons';
 import type { RefObject } from 'react';
@@ -26,11 +31,12 @@ function getInstanceForNote(
   noteId: string,
   language: LanguagesWithKernels
 ): KernelInstanceData | null {
-  const all = getDefaultStore().get(kernelInstancesAtom);
-  return (
-    Object.values(all).find(
-      (i) => i.noteId === noteId && i.language === language
-    ) ?? null
+  const store = getDefaultStore();
+  return findKernelInstanceForNote(
+    store.get(kernelInstancesAtom),
+    store.get(kernelScopesAtom),
+    noteId,
+    language
   );
 }
 
@@ -54,6 +60,7 @@ export function PlayButton({
   kernelInstanceId: string | null;
 }) {
   const noteId = useDecodedNotesWildcardPath() ?? '';
+  useKernelScopeQuery(noteId, language);
   const [editor] = useLexicalComposerContext();
   const { mutate: executeCode } = useSendExecuteRequestMutation({
     noteId,
@@ -9,9 +9,14 @@ import { cn } from '@utils/string-formatting';
 import { FolderOpen } from '@/icons/folder-open';
 import { ChevronDown } from '@/icons/chevron-down';
 import { PowerOff } from '@/icons/power-off';
+import { Link } from '@/icons/link';
 import { PythonVenvDialog } from '../python-venv-dialog';
+import { JupyterServerDialog } from '../jupyter-server-dialog';
 import {
+  useAttachKernelConnectionFileMutation,
+  useConnectJupyterServerKernelMutation,
   useEnsureKernelMutation,
+  useKernelScopeQuery,
   usePythonVenvSubmitMutation,
   useShutdownKernelMutation,
 } from '@hooks/code';
@@ -53,10 +58,41 @@ const languageSpecificOptions: {
   heartbeatFailure: {},
 };
 
+// Ways to use a kernel bytebook did not launch, offered while none is running.
+const attachKernelOptions: KernelOption[] = [
+  {
+    id: 'attach-connection-file',
+    label: (
+      <span className="flex items-center gap-1.5 will-change-transform">
+        <Link
+          className="will-change-transform"
+          height="0.90625rem"
+          width="0.90625rem"
+        />
+        Attach Connection File…
+      </span>
+    ),
+  },
+  {
+    id: 'connect-jupyter-server',
+    label: (
+      <span className="flex items-center gap-1.5 will-change-transform">
+        <Link
+          className="will-change-transform"
+          height="0.90625rem"
+          width="0.90625rem"
+        />
+        Connect to Jupyter Server…
+      </span>
+    ),
+  },
+];
+
 export function KernelLanguageHeartbeat({ language }: { language: Languages }) {
   const setDialogData = useSetAtom(dialogDataAtom);
   const projectSettings = useAtomValue(projectSettingsAtom);
   const noteId = useDecodedNotesWildcardPath() ?? '';
+  useKernelScopeQuery(noteId, language);
   const instance = useAtomValue(
     kernelInstanceForNoteAtomFamily({
       noteId,
@@ -69,6 +105,10 @@ export function KernelLanguageHeartbeat({ language }: { language: Languages }) {
 
   const { mutate: shutdownKernel } = useShutdownKernelMutation();
   const { mutate: ensureKernel } = useEnsureKernelMutation();
+  const { mutate: attachConnectionFile } =
+    useAttachKernelConnectionFileMutation();
+  const { mutateAsync: connectJupyterServerKernel } =
+    useConnectJupyterServerKernelMutation();
   const { mutateAsync: submitPythonVenv } =
     usePythonVenvSubmitMutation(projectSettings);
 
@@ -102,6 +142,7 @@ export function KernelLanguageHeartbeat({ language }: { language: Languages }) {
             ),
           },
           ...heartbeatFailureOptions,
+          ...attachKernelOptions,
         ];
 
   function handleAction(key: Key) {
@@ -120,6 +161,39 @@ export function KernelLanguageHeartbeat({ language }: { language: Languages }) {
           language: language as LanguagesWithKernels,
         });
         break;
+      case 'attach-connection-file':
+        attachConnectionFile({
+          noteId,
+          language: language as LanguagesWithKernels,
+        });
+        break;
+      case 'connect-jupyter-server':
+        setDialogData({
+          isOpen: true,
+          isPending: false,
+          title: 'Connect to Jupyter Server',
+          dialogClassName: 'w-[min(36rem,90vw)]',
+          children: (errorText) => (
+            <JupyterServerDialog
+              language={language as LanguagesWithKernels}
+              errorText={errorText}
+            />
+          ),
+          onSubmit: async (formData, setErrorText) => {
+            try {
+              return await connectJupyterServerKernel({
+                noteId,
+                language: language as LanguagesWithKernels,
+                formData,
+                setErrorText,
+              });
+            } catch {
+              // onError fills in the dialog's error text; keep it open.
+              return false;
+            }
+          },
+        });
+        break;
       case 'change-venv':
         setDialogData({
           isOpen: true,
@@ -47,7 +47,7 @@ import { CUSTOM_TRANSFORMERS } from './transformers';
 import { debouncedNoteHandleChange } from './utils/note-commands.ts';
 import { useAutoScrollDuringDrag } from '@hooks/draggable';
 import { useCodeCleanup } from './hooks/code';
-import { useLspNoteLifecycle } from '@hooks/lsp';
+import { useLspDiagnosticsEvents, useLspNoteLifecycle } from '@hooks/lsp';
 import { useNoteIntersectionObserver } from './hooks/intersection-observer';
 import { FilePath } from '@utils/path';
 import type { PlaceholderLineData } from './types';
@@ -119,6 +119,7 @@ export function NotesEditor({
   useCodeCleanup(noteContainerRef);
   useNoteIntersectionObserver(folder, note, noteContainerRef);
   useLspNoteLifecycle();
+  useLspDiagnosticsEvents();
 
   return (
     <LexicalComposer initialConfig={editorConfig}>
@@ -0,0 +1,140 @@
+import { useMutation } from '@tanstack/react-query';
+import { useState } from 'react';
+import { ListJupyterServerKernels } from '@bindings/services/codeservice';
+import { getDefaultButtonVariants } from '@/animations';
+import { Loader } from '@/icons/loader';
+import type { LanguagesWithKernels } from '@/types';
+import { MotionButton } from '@components/buttons';
+import { DialogErrorText } from '@components/dialog';
+import { AppTextField } from '@components/input';
+import { AppRadio, AppRadioGroup } from '@components/radio-button';
+
+// The kernelspec each language's kernel is usually installed under.
+const DEFAULT_KERNEL_NAMES: Record<LanguagesWithKernels, string> = {
+  python: 'python3',
+  go: 'gonb',
+  javascript: 'deno',
+  java: 'java',
+};
+
+// Server URLs and tokens are longer than the text field's default limit.
+const MAX_FIELD_LENGTH = 2048;
+
+/**
+ * Form for connecting a note's kernel to a Jupyter Server. The running
+ * kernels can be listed and picked, or a new kernel started from a
+ * kernelspec.
+ */
+export function JupyterServerDialog({
+  language,
+  errorText,
+}: {
+  language: LanguagesWithKernels;
+  errorText: string;
+}) {
+  const [serverUrl, setServerUrl] = useState('');
+  const [token, setToken] = useState('');
+  const {
+    mutate: listKernels,
+    data: kernels,
+    error: listError,
+    isPending: isListing,
+  } = useMutation({
+    mutationFn: async () => {
+      const res = await ListJupyterServerKernels(serverUrl.trim(), token);
+      if (!res.success) {
+        // Shown inline below the fields, so this is not a QueryError, which
+        // would also show a toast.
+        throw new Error(res.message);
+      }
+      return res.data ?? [];
+    },
+  });
+  const isListDisabled = isListing || serverUrl.trim().length === 0;
+
+  return (
+    <section className="flex flex-col gap-3.5">
+      <p>
+        Run this note&apos;s {language} code on a kernel managed by a Jupyter
+        Server, such as JupyterLab or JupyterHub.
+      </p>
+      <AppTextField
+        name="server-url"
+        label="Server URL"
+        placeholder="http://localhost:8888"
+        maxLength={MAX_FIELD_LENGTH}
+        value={serverUrl}
+        onChange={setServerUrl}
+        autoFocus
+      />
+      <div className="flex items-end gap-3">
+        <AppTextField
+          name="token"
+          label="Token"
+          type="password"
+          className="flex-1"
+          maxLength={MAX_FIELD_LENGTH}
+          value={token}
+          onChange={setToken}
+        />
+        <MotionButton
+          {...getDefaultButtonVariants({ disabled: isListDisabled })}
+          className="w-fit text-nowrap"
+          isDisabled={isListDisabled}
+          onClick={() => listKernels()}
+        >
+          {isListing ? <Loader /> : 'List Kernels'}
+        </MotionButton>
+      </div>
+
+      <AppRadioGroup
+        name="kernel-id"
+        defaultValue=""
+        aria-label="Jupyter Server kernel"
+      >
+        <div className="flex flex-col gap-2 p-2 bg-zinc-150 dark:bg-zinc-750 rounded-md">
+          <AppRadio value="">Start a new kernel</AppRadio>
+          <AppTextField
+            name="kernel-name"
+            label="Kernelspec"
+            defaultValue={DEFAULT_KERNEL_NAMES[language]}
+          />
+        </div>
+        {kernels?.map((kernel) => (
+          <span
+            key={kernel.id}
+            className="flex items-center gap-1.5 py-1 px-2 bg-zinc-150 dark:bg-zinc-750 rounded-md"
+            title={kernel.id}
+          >
+            <AppRadio value={kernel.id}>
+              {kernel.name}{' '}
+              <span className="text-xs text-zinc-500 dark:text-zinc-400">
+                {kernel.execution_state} · {kernel.id.slice(0, 8)}
+              </span>
+            </AppRadio>
+          </span>
+        ))}
+        {kernels?.length === 0 && (
+          <p className="text-sm text-zinc-500 dark:text-zinc-400">
+            No kernels are running on this server
+          </p>
+        )}
+      </AppRadioGroup>
+
+      {listError && (
+        <DialogErrorText
+          errorText={listError.message}
+          className="text-red-500 text-sm"
+        />
+      )}
+      <DialogErrorText errorText={errorText} className="text-red-500 text-sm" />
+      <MotionButton
+        type="submit"
+        {...getDefaultButtonVariants()}
+        className="w-32 ml-auto flex items-center justify-center"
+      >
+        Connect
+      </MotionButton>
+    </section>
+  );
+}
@@ -10,6 +10,14 @@ import { $applyNodeReplacement, DecoratorNode } from 'lexical';
 import type { JSX } from 'react';
 import { Code } from '@components/code';
 import { CodeBlockStatus, Languages } from '@/types';
+import type { TruncatedOutputPage } from '@bindings/jupyter_protocol/sockets/models';
+import {
+  displayDataToHtml,
+  setTruncatedNotice,
+  showTruncatedPage,
+  updateDisplay,
+  wrapDisplay,
+} from '@utils/code-output';
 
 export interface CodePayload {
   id: string;
@@ -232,37 +240,69 @@ export class CodeNode extends DecoratorNode<JSX.Element> {
 
   setDisplayResult(
     mimeTypeToData: Record<string, string>,
+    displayId: string,
     editor: LexicalEditor
   ): void {
     editor.update(() => {
       const writable = this.getWritable();
-      // 1. If there's HTML, show that
-      if (mimeTypeToData['text/html']) {
-        writable.__lastExecutedResult = mimeTypeToData['text/html'];
-        writable.__lastRan = new Date().toISOString();
-        return;
-      }
-      // 2. Otherwise, if there's an image, show that
-      const imageEntry = Object.entries(mimeTypeToData).find(([mt]) =>
-        mt.startsWith('image/')
+      writable.__lastExecutedResult =
+        (writable.__lastExecutedResult ?? '') +
+        wrapDisplay(displayDataToHtml(mimeTypeToData), displayId);
+      writable.__lastRan = new Date().toISOString();
+    });
+  }
+
+  // Replaces an earlier display in place, e.g. one frame of a progress bar.
+  updateDisplayResult(
+    mimeTypeToData: Record<string, string>,
+    displayId: string,
+    editor: LexicalEditor
+  ): void {
+    editor.update(() => {
+      const writable = this.getWritable();
+      const updated = updateDisplay(
+        writable.__lastExecutedResult ?? '',
+        displayId,
+        displayDataToHtml(mimeTypeToData)
       );
-      if (imageEntry) {
-        const [mt, data] = imageEntry;
-        writable.__lastExecutedResult = `<img src="data:${mt};base64,${data}" alt="result"/>`;
-        writable.__lastRan = new Date().toISOString();
-        return;
-      }
-      // 3. Fallback to plain text
-      if (mimeTypeToData['text/plain']) {
-        writable.__lastExecutedResult = `<pre>${mimeTypeToData['text/plain']}</pre>`;
-        writable.__lastRan = new Date().toISOString();
-        return;
-      }
-      // 4. Anything else, just dump it
-      Object.values(mimeTypeToData).forEach((data) => {
-        writable.__lastExecutedResult = `<div>${data}</div>`;
-        writable.__lastRan = new Date().toISOString();
-      });
+      if (updated === null) return;
+      writable.__lastExecutedResult = updated;
+      writable.__lastRan = new Date().toISOString();
+    });
+  }
+
+  // Shows or updates the notice for stream output the backend held back.
+  setTruncatedNotice(
+    handle: string,
+    hiddenBytes: number,
+    editor: LexicalEditor
+  ): void {
+    editor.update(() => {
+      const writable = this.getWritable();
+      writable.__lastExecutedResult = setTruncatedNotice(
+        writable.__lastExecutedResult ?? '',
+        handle,
+        hiddenBytes
+      );
+      writable.__lastRan = new Date().toISOString();
+    });
+  }
+
+  // Inserts a page of held-back stream output above its notice.
+  showTruncatedPage(
+    handle: string,
+    page: TruncatedOutputPage,
+    editor: LexicalEditor
+  ): void {
+    editor.update(() => {
+      const writable = this.getWritable();
+      const updated = showTruncatedPage(
+        writable.__lastExecutedResult ?? '',
+        handle,
+        page
+      );
+      if (updated === null) return;
+      writable.__lastExecutedResult = updated;
     });
   }
 
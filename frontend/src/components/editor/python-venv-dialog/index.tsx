@@ -1,7 +1,8 @@
-import { useQuery, useMutation } from '@tanstack/react-query';
+import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import {
   ChooseCustomVirtualEnvironmentPath,
-  GetPythonVirtualEnvironments,
+  CreatePythonEnvironment,
+  GetPythonEnvironments,
 } from '@bindings/services/codeservice';
 import { Loader } from '@/icons/loader';
 import { AppRadio, AppRadioGroup } from '@components/radio-button';
@@ -14,19 +15,133 @@ import { QueryError } from '@utils/query';
 import { PlainCodeSnippet } from '@components/plain-code-snippet';
 import { FloppyDisk } from '@/icons/floppy-disk';
 import { DialogErrorText } from '@components/dialog';
-import { useState } from 'react';
+import { useRef, useState } from 'react';
 import { SidebarAccordion } from '@components/accordion';
 import { DesktopArrowDown } from '@/icons/desktop-arrow-down';
 import { cn } from '@utils/string-formatting';
 import { RevealFolderOrFileInFinder } from '@bindings/services/noteservice';
 import { queryKeys } from '@utils/query-keys';
+import { AppTextField } from '@components/input';
+import { useWailsEvent } from '@hooks/events';
+import { PYTHON_ENVIRONMENT_PROGRESS } from '@utils/events';
+import { logger } from '@utils/logging';
+import { toast } from 'sonner';
+import { DEFAULT_SONNER_OPTIONS } from '@utils/general';
+import type { PythonEnvironmentProgressEventData } from '@bindings/kernel_manager/models';
+
+/**
+ * Creates a virtual environment in the project's code folder and shows the
+ * backend's progress until it is ready to be picked.
+ */
+function CreatePythonEnvironmentForm() {
+  const queryClient = useQueryClient();
+  const [name, setName] = useState('');
+  const [creatingPath, setCreatingPath] = useState<string | null>(null);
+  const [progressText, setProgressText] = useState('');
+  const [errorText, setErrorText] = useState('');
+  // Setup starts before CreatePythonEnvironment resolves, so events that
+  // arrive before the path is known are kept and applied once it is.
+  const earlyProgress = useRef(
+    new Map<string, PythonEnvironmentProgressEventData>()
+  );
+
+  function applyProgress(data: PythonEnvironmentProgressEventData) {
+    if (data.stage === 'error') {
+      setCreatingPath(null);
+      setProgressText('');
+      setErrorText(data.message);
+      return;
+    }
+    setProgressText(data.message);
+    if (data.stage === 'done') {
+      setCreatingPath(null);
+      setName('');
+      toast.success(data.message, DEFAULT_SONNER_OPTIONS);
+      void queryClient.invalidateQueries({
+        queryKey: queryKeys.pythonVenvs(),
+      });
+    }
+  }
+
+  useWailsEvent(PYTHON_ENVIRONMENT_PROGRESS, (body) => {
+    logger.event(PYTHON_ENVIRONMENT_PROGRESS, body);
+    const data = body.data;
+    if (creatingPath === null) {
+      earlyProgress.current.set(data.path, data);
+      return;
+    }
+    if (data.path === creatingPath) applyProgress(data);
+  });
+
+  const isCreateDisabled = creatingPath !== null || name.trim().length === 0;
+
+  async function createEnvironment() {
+    if (isCreateDisabled) return;
+    setErrorText('');
+    setProgressText('');
+    earlyProgress.current.clear();
+    const res = await CreatePythonEnvironment(name);
+    if (!res.success || !res.data) {
+      setErrorText(res.message);
+      return;
+    }
+    setCreatingPath(res.data);
+    setProgressText(res.message);
+    const progress = earlyProgress.current.get(res.data);
+    earlyProgress.current.clear();
+    if (progress) applyProgress(progress);
+  }
+
+  return (
+    <div className="flex flex-col gap-2 p-2 bg-zinc-150 dark:bg-zinc-750 rounded-md">
+      <div className="flex items-end gap-3">
+        <AppTextField
+          label="Create a new virtual environment"
+          placeholder="bytebook-venv"
+          className="flex-1"
+          value={name}
+          onChange={setName}
+          isDisabled={creatingPath !== null}
+          // The dialog is a form, so Enter creates the environment instead of
+          // saving the selection.
+          onKeyDown={(e) => {
+            if (e.key !== 'Enter') return;
+            e.preventDefault();
+            void createEnvironment();
+          }}
+        />
+        <MotionButton
+          {...getDefaultButtonVariants({ disabled: isCreateDisabled })}
+          className="w-fit text-nowrap"
+          isDisabled={isCreateDisabled}
+          onClick={() => {
+            void createEnvironment();
+          }}
+        >
+          {creatingPath ? <Loader /> : 'Create'}
+        </MotionButton>
+      </div>
+      {progressText.length > 0 && (
+        <p className="text-xs text-zinc-600 dark:text-zinc-300 overflow-hidden text-nowrap overflow-ellipsis">
+          {progressText}
+        </p>
+      )}
+      {errorText.length > 0 && (
+        <DialogErrorText
+          errorText={errorText}
+          className="text-red-500 text-sm"
+        />
+      )}
+    </div>
+  );
+}
 
 export function PythonVenvDialog({ errorText }: { errorText: string }) {
   const [customVenvPath, setCustomVenvPath] = useState<string | null>(null);
   const [isInstructionsOpen, setIsInstructionsOpen] = useState(false);
   const { data, error, isLoading } = useQuery({
     queryKey: queryKeys.pythonVenvs(),
-    queryFn: () => GetPythonVirtualEnvironments(),
+    queryFn: () => GetPythonEnvironments(),
     refetchInterval: 3500,
   });
   const projectSettings = useAtomValue(projectSettingsAtom);
@@ -53,7 +168,7 @@ export function PythonVenvDialog({ errorText }: { errorText: string }) {
     },
   });
 
-  const pythonVenvPaths = data?.data ?? [];
+  const pythonEnvironments = data?.data ?? [];
 
   return (
     <section className="flex flex-col gap-3.5">
@@ -105,27 +220,29 @@ export function PythonVenvDialog({ errorText }: { errorText: string }) {
 
       {isLoading && <Loader />}
       {data?.success &&
-        (pythonVenvPaths?.length ? (
+        (pythonEnvironments.length ? (
           <AppRadioGroup
             name="venv-path-option"
             defaultValue={projectSettings.code.pythonVenvPath}
             aria-label="Python virtual environment"
           >
-            {pythonVenvPaths.map((venvPath) => (
+            {pythonEnvironments.map((environment) => (
               <span
                 className="flex items-center gap-1.5 group py-1 px-2 bg-zinc-150 dark:bg-zinc-750 rounded-md "
-                key={venvPath}
+                key={environment.path}
+                title={environment.path}
               >
-                <AppRadio value={venvPath}>
-                  {venvPath.split('/').length > 3
-                    ? venvPath.split('/').slice(-3).join('/')
-                    : venvPath}
+                <AppRadio value={environment.path}>
+                  {environment.name}{' '}
+                  <span className="text-xs text-zinc-500 dark:text-zinc-400">
+                    {environment.kind}
+                  </span>
                 </AppRadio>
                 <MotionIconButton
                   className="opacity-0 focus:opacity-100 group-hover:opacity-100 transition-opacity ml-auto"
                   {...getDefaultButtonVariants()}
                   onClick={() => {
-                    void RevealFolderOrFileInFinder(venvPath, false);
+                    void RevealFolderOrFileInFinder(environment.path, false);
                   }}
                 >
                   <ShareRight height="1rem" width="1rem" />
@@ -166,8 +283,9 @@ export function PythonVenvDialog({ errorText }: { errorText: string }) {
             </div>
           </AppRadioGroup>
         ) : (
-          <p>No virtual environments found</p>
+          <p>No Python environments found</p>
         ))}
+      <CreatePythonEnvironmentForm />
 
       {(error || !data?.success) && (
         <DialogErrorText
@@ -34,6 +34,8 @@ import {
 import { SettingsDropdown } from './settings-dropdown';
 import type { PlaceholderLineData } from '../types';
 import {
+  useCodeBlockClearOutput,
+  useCodeBlockOutputTruncated,
   useCodeBlockDisplayData,
   useCodeBlockExecuteInput,
   useCodeBlockExecuteResult,
@@ -41,8 +43,10 @@ import {
   useCodeBlockIOPubError,
   useCodeBlockStatus,
   useCodeBlockStream,
+  useCodeBlockUpdateDisplayData,
   useKernelCodeNodeCleanupEvents,
 } from '@hooks/code';
+import { useCodeBlockComms } from '@hooks/code-comms';
 import { NoteFindPanel } from './note-find-panel/index';
 import { useToggleSidebarEvent } from '@/routes/notes-sidebar/render-note/hooks';
 import { routeUrls } from '@utils/routes';
@@ -126,10 +130,14 @@ export function Toolbar({
   useCodeBlockStream(editor);
   useCodeBlockIOPubError(editor);
   useCodeBlockDisplayData(editor);
+  useCodeBlockUpdateDisplayData(editor);
+  useCodeBlockClearOutput(editor);
+  useCodeBlockOutputTruncated(editor);
   useCodeBlockInputRequest(editor);
   useCodeBlockStatus(editor);
   useCodeBlockExecuteResult(editor);
   useCodeBlockExecuteInput(editor);
+  useCodeBlockComms();
   useToggleSidebarEvent(animationControls);
 
   const FloatingPlugin = noteContainerElement
@@ -0,0 +1,217 @@
+import { useAtomValue, useSetAtom } from 'jotai/react';
+import { useQueryClient } from '@tanstack/react-query';
+import { toast } from 'sonner';
+import { CheckUnusedAttachments } from '@bindings/services/searchservice';
+import type { UnusedAttachment } from '@bindings/search/models';
+import { dialogDataAtom, projectSettingsAtom } from '@/atoms';
+import { getDefaultButtonVariants } from '@/animations';
+import { Loader } from '@/icons/loader';
+import { Trash } from '@/icons/trash';
+import { MotionButton } from '@components/buttons';
+import { AppCheckbox } from '@components/checkbox';
+import { DialogErrorText } from '@components/dialog';
+import { AppSwitch } from '@components/switch';
+import { useMoveToTrashMutation } from '@hooks/notes';
+import { useUpdateProjectSettingsMutation } from '@hooks/project-settings';
+import { useUnusedAttachmentsQuery } from '@hooks/search';
+import { DEFAULT_SONNER_OPTIONS } from '@utils/general';
+import { queryKeys } from '@utils/query-keys';
+import { formatByteSize } from '@utils/string-formatting';
+import { SettingsRow } from './settings-row';
+
+export function DuplicateAttachmentsRow() {
+  const projectSettings = useAtomValue(projectSettingsAtom);
+  const { mutate: updateProjectSettings } = useUpdateProjectSettingsMutation();
+
+  return (
+    <SettingsRow
+      title="Reuse Duplicate Attachments"
+      description="When an added file has the same content as a file already in the destination folder, link to the existing file instead of storing another copy. Other folders are not checked."
+    >
+      <div className="flex items-center gap-1.5">
+        <AppSwitch
+          isSelected={!projectSettings.attachments.keepDuplicates}
+          onChange={(isSelected: boolean) => {
+            updateProjectSettings({
+              newProjectSettings: {
+                ...projectSettings,
+                attachments: {
+                  ...projectSettings.attachments,
+                  keepDuplicates: !isSelected,
+                },
+              },
+            });
+          }}
+          aria-label="Reuse duplicate attachments"
+        />
+      </div>
+    </SettingsRow>
+  );
+}
+
+const ATTACHMENT_PATH_FIELD = 'attachment-path';
+
+function UnusedAttachmentsDialogChildren({
+  attachments,
+  errorText,
+}: {
+  attachments: UnusedAttachment[];
+  errorText: string;
+}) {
+  return (
+    <>
+      <fieldset className="flex flex-col gap-2">
+        <p className="text-sm text-zinc-500 dark:text-zinc-400">
+          The selected attachments will be moved to the trash. Attachments that
+          a note has started using since the list was loaded are kept.
+        </p>
+        <div className="flex flex-col gap-1 max-h-60 overflow-y-auto">
+          {attachments.map((attachment) => (
+            <AppCheckbox
+              key={attachment.path}
+              name={ATTACHMENT_PATH_FIELD}
+              value={attachment.path}
+              defaultSelected
+            >
+              <span className="truncate" title={attachment.path}>
+                {attachment.path}
+              </span>
+              <span className="ml-auto text-xs text-zinc-500 dark:text-zinc-400 text-nowrap">
+                {formatByteSize(attachment.size)}
+              </span>
+            </AppCheckbox>
+          ))}
+        </div>
+      </fieldset>
+      <DialogErrorText errorText={errorText} />
+      <MotionButton
+        type="submit"
+        {...getDefaultButtonVariants()}
+        className="w-[calc(100%-1.5rem)] mx-auto justify-center"
+      >
+        <Trash width="1.25rem" height="1.25rem" />
+        <span>Move to Trash</span>
+      </MotionButton>
+    </>
+  );
+}
+
+export function UnusedAttachmentsRow() {
+  const queryClient = useQueryClient();
+  const setDialogData = useSetAtom(dialogDataAtom);
+  const { data: unusedAttachments = [], isLoading } =
+    useUnusedAttachmentsQuery();
+  const { mutateAsync: moveToTrash, isPending } = useMoveToTrashMutation();
+  const totalSize = unusedAttachments.reduce(
+    (total, attachment) => total + attachment.size,
+    0
+  );
+
+  function openTrashDialog() {
+    const attachments = unusedAttachments;
+    setDialogData({
+      isOpen: true,
+      isPending: false,
+      title: 'Move Unused Attachments to Trash',
+      dialogClassName: 'w-[min(36rem,90vw)]',
+      children: (errorText) => (
+        <UnusedAttachmentsDialogChildren
+          attachments={attachments}
+          errorText={errorText}
+        />
+      ),
+      onSubmit: async (formData, setErrorText) => {
+        const selected = formData
+          .getAll(ATTACHMENT_PATH_FIELD)
+          .map((value) => value.toString());
+        if (selected.length === 0) {
+          setErrorText('Select at least one attachment');
+          return false;
+        }
+
+        // The list may be stale, so only files that no note on disk uses
+        // right now are trashed.
+        const res = await CheckUnusedAttachments(selected);
+        if (!res.success) {
+          setErrorText(res.message);
+          return false;
+        }
+        const stillUnused = res.data ?? [];
+        const nowUsed = selected.length - stillUnused.length;
+        if (nowUsed > 0) {
+          toast.warning(
+            `Kept ${nowUsed} ${nowUsed === 1 ? 'attachment that is' : 'attachments that are'} now used by a note`,
+            DEFAULT_SONNER_OPTIONS
+          );
+        }
+
+        if (stillUnused.length > 0) {
+          try {
+            await moveToTrash({ paths: stillUnused });
+          } catch (e) {
+            setErrorText(e instanceof Error ? e.message : String(e));
+            return false;
+          }
+        }
+        // The index only drops the files once the watcher sees them go, so
+        // they are removed from the list straight away.
+        queryClient.setQueryData(
+          queryKeys.unusedAttachments(),
+          (previous: UnusedAttachment[] | undefined) =>
+            previous?.filter(({ path }) => !stillUnused.includes(path))
+        );
+        void queryClient.invalidateQueries({
+          queryKey: queryKeys.unusedAttachments(),
+        });
+        return true;
+      },
+    });
+  }
+
+  return (
+    <SettingsRow
+      title="Unused Attachments"
+      description={
+        unusedAttachments.length === 0
+          ? 'Attachments that no note links to or embeds are listed here.'
+          : `${unusedAttachments.length} ${unusedAttachments.length === 1 ? 'attachment is' : 'attachments are'} not used by any note (${formatByteSize(totalSize)}).`
+      }
+    >
+      <div className="flex flex-col gap-2">
+        {isLoading && <Loader width="1.4375rem" height="1.4375rem" />}
+        {unusedAttachments.length > 0 && (
+          <ul className="flex flex-col gap-1 max-h-40 overflow-y-auto text-sm">
+            {unusedAttachments.map((attachment) => (
+              <li
+                key={attachment.path}
+                className="flex items-center justify-between gap-2"
+              >
+                <span className="truncate" title={attachment.path}>
+                  {attachment.path}
+                </span>
+                <span className="text-xs text-zinc-500 dark:text-zinc-400 text-nowrap">
+                  {formatByteSize(attachment.size)}
+                </span>
+              </li>
+            ))}
+          </ul>
+        )}
+        <MotionButton
+          className="text-center w-44 flex items-center justify-center"
+          {...getDefaultButtonVariants()}
+          isDisabled={isPending || unusedAttachments.length === 0}
+          onClick={openTrashDialog}
+        >
+          {isPending ? (
+            <Loader width="1.4375rem" height="1.4375rem" />
+          ) : (
+            <>
+              <Trash width="1.25rem" height="1.25rem" />
+              Move to Trash…
+            </>
+          )}
+        </MotionButton>
+      </div>
+    </SettingsRow>
+  );
+}
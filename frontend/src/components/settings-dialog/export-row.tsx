@@ -0,0 +1,29 @@
+import { MotionButton } from '@components/buttons';
+import { getDefaultButtonVariants } from '@/animations';
+import { Loader } from '@/icons/loader';
+import { useExportSiteMutation } from '@hooks/exports';
+import { SettingsRow } from './settings-row';
+
+export function ExportRow() {
+  const { mutate: exportSite, isPending } = useExportSiteMutation();
+
+  return (
+    <SettingsRow
+      title="Export Website"
+      description="Publish every note as a static website with an index page, tags, backlinks and search. To export a single folder, right-click it in the sidebar."
+    >
+      <MotionButton
+        className="text-center w-36 flex items-center justify-center"
+        {...getDefaultButtonVariants()}
+        isDisabled={isPending}
+        onClick={() => exportSite({ folder: '' })}
+      >
+        {isPending ? (
+          <Loader width="1.4375rem" height="1.4375rem" />
+        ) : (
+          'Export Vault'
+        )}
+      </MotionButton>
+    </SettingsRow>
+  );
+}
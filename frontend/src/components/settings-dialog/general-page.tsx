@@ -1,6 +1,13 @@
 import { FontFamilyRow } from './appearance/font-family-row';
 import { SidebarSectionsRow } from './appearance/sidebar-sections-row';
 import { ThemeRow } from './appearance/theme-row';
+import {
+  DuplicateAttachmentsRow,
+  UnusedAttachmentsRow,
+} from './attachments-row';
+import { ExportRow } from './export-row';
+import { ImportRow } from './import-row';
+import { LinkedFoldersRow } from './linked-folders-row';
 
 export function GeneralPage() {
   return (
@@ -8,6 +15,11 @@ export function GeneralPage() {
       <ThemeRow isFirst />
       <FontFamilyRow setting="ui" />
       <SidebarSectionsRow />
+      <LinkedFoldersRow />
+      <DuplicateAttachmentsRow />
+      <UnusedAttachmentsRow />
+      <ImportRow />
+      <ExportRow />
     </>
   );
 }
@@ -0,0 +1,48 @@
+import { MotionButton } from '@components/buttons';
+import { getDefaultButtonVariants } from '@/animations';
+import { Loader } from '@/icons/loader';
+import {
+  type ExternalImportSource,
+  useImportFromAppMutation,
+} from '@hooks/imports';
+import { SettingsRow } from './settings-row';
+
+const IMPORT_SOURCES: { value: ExternalImportSource; label: string }[] = [
+  { value: 'obsidian', label: 'Obsidian' },
+  { value: 'notion', label: 'Notion' },
+  { value: 'bear', label: 'Bear' },
+  { value: 'evernote', label: 'Evernote' },
+];
+
+export function ImportRow() {
+  const {
+    mutate: importFromApp,
+    isPending,
+    variables,
+  } = useImportFromAppMutation();
+
+  return (
+    <SettingsRow
+      title="Import Notes"
+      description="Bring in an Obsidian vault, a Notion Markdown & CSV export, Bear TextBundles or an Evernote .enex file. Each import becomes a new folder."
+    >
+      <div className="grid grid-cols-2 gap-2">
+        {IMPORT_SOURCES.map(({ value, label }) => (
+          <MotionButton
+            key={value}
+            className="text-center w-36 flex items-center justify-center"
+            {...getDefaultButtonVariants()}
+            isDisabled={isPending}
+            onClick={() => importFromApp({ source: value })}
+          >
+            {isPending && variables?.source === value ? (
+              <Loader width="1.4375rem" height="1.4375rem" />
+            ) : (
+              label
+            )}
+          </MotionButton>
+        ))}
+      </div>
+    </SettingsRow>
+  );
+}
@@ -0,0 +1,86 @@
+import { useState } from 'react';
+import { useAtomValue } from 'jotai/react';
+import { projectSettingsAtom } from '@/atoms';
+import {
+  useAddLinkedFolderMutation,
+  useRemoveLinkedFolderMutation,
+} from '@hooks/project-settings';
+import { MotionButton, MotionIconButton } from '@components/buttons';
+import { AppCheckbox } from '@components/checkbox';
+import { getDefaultButtonVariants } from '@/animations';
+import { FolderOpen } from '@/icons/folder-open';
+import { Trash } from '@/icons/trash';
+import { Loader } from '@/icons/loader';
+import { Tooltip } from '@components/tooltip';
+import { SettingsRow } from './settings-row';
+
+export function LinkedFoldersRow() {
+  const projectSettings = useAtomValue(projectSettingsAtom);
+  const [readOnly, setReadOnly] = useState(false);
+  const { mutate: addLinkedFolder, isPending } = useAddLinkedFolderMutation();
+  const { mutate: removeLinkedFolder } = useRemoveLinkedFolderMutation();
+  const linkedFolders = projectSettings.linkedFolders ?? [];
+
+  return (
+    <SettingsRow
+      title="Linked Folders"
+      description="Show folders from elsewhere on disk in the sidebar. Their files are watched and searchable but stay where they are."
+    >
+      <div className="flex flex-col gap-2">
+        {linkedFolders.map((folder) => (
+          <div
+            key={folder.name}
+            className="flex items-center justify-between gap-2 text-sm"
+          >
+            <span className="flex flex-col min-w-0">
+              <span className="font-medium">
+                {folder.name}
+                {folder.readOnly && (
+                  <span className="text-zinc-500 dark:text-zinc-400">
+                    {' '}
+                    (read-only)
+                  </span>
+                )}
+              </span>
+              <span
+                className="text-xs text-zinc-500 dark:text-zinc-400 truncate"
+                title={folder.path}
+              >
+                {folder.path}
+              </span>
+            </span>
+            <Tooltip content={`Unlink ${folder.name}`}>
+              <MotionIconButton
+                {...getDefaultButtonVariants()}
+                aria-label={`Unlink ${folder.name}`}
+                onClick={() => removeLinkedFolder({ name: folder.name })}
+              >
+                <Trash width="1rem" height="1rem" />
+              </MotionIconButton>
+            </Tooltip>
+          </div>
+        ))}
+        <div className="flex items-center gap-3">
+          <MotionButton
+            className="text-center w-36 flex items-center justify-center"
+            {...getDefaultButtonVariants()}
+            isDisabled={isPending}
+            onClick={() => addLinkedFolder({ readOnly })}
+          >
+            {isPending ? (
+              <Loader width="1.4375rem" height="1.4375rem" />
+            ) : (
+              <>
+                <FolderOpen width="1.25rem" height="1.25rem" />
+                Link Folder
+              </>
+            )}
+          </MotionButton>
+          <AppCheckbox isSelected={readOnly} onChange={setReadOnly}>
+            Read-only
+          </AppCheckbox>
+        </div>
+      </div>
+    </SettingsRow>
+  );
+}
@@ -1,20 +1,62 @@
 import { SettingsRow } from './settings-row';
 import { MotionButton } from '@components/buttons';
 import { getDefaultButtonVariants } from '@/animations';
-import { useRegenerateSearchIndexMutation } from '@hooks/search';
+import {
+  useIndexHealthQuery,
+  useRegenerateSearchIndexMutation,
+} from '@hooks/search';
 import { SearchContent2 } from '@/icons/search-content-2';
 import { Loader } from '@/icons/loader';
 import { cn } from '@utils/string-formatting';
 import { Tooltip } from '@components/tooltip';
+import { useImportStatusQuery, useWatcherStatusQuery } from '@hooks/imports';
+
+function describeWatcherStatus(
+  status: ReturnType<typeof useWatcherStatusQuery>['data'],
+  importWarning: string | undefined
+) {
+  if (!status || status.polled === 0) return '';
+  // The import warning explains which system limit to raise.
+  if (importWarning) return ` ${importWarning}`;
+  return ` ${status.polled} of ${status.watched + status.polled} folders are checked for changes every few seconds because the system ran out of file watchers.`;
+}
+
+function describeIndexHealth(
+  health: ReturnType<typeof useIndexHealthQuery>['data'],
+  watcherNote: string
+) {
+  const base = `Regenerate the search index to manually update search results.${watcherNote}`;
+  if (!health) return base;
+  if (health.healthy) {
+    return `${base} ${health.documentCount} of ${health.fileCount} files are indexed and up to date.`;
+  }
+  if (health.schemaVersion !== health.expectedSchemaVersion) {
+    return `${base} The index was built by an older version and is being rebuilt.`;
+  }
+  const outOfSync =
+    health.missingCount + health.staleCount + health.outdatedCount;
+  return `${base} ${outOfSync} of ${health.fileCount} files are out of sync with the index.`;
+}
 
 export function SearchPage() {
+  const { data: indexHealth, refetch: refetchIndexHealth } =
+    useIndexHealthQuery();
+  const { data: importStatus } = useImportStatusQuery();
+  const { data: watcherStatus } = useWatcherStatusQuery();
   const { mutate: regenerateSearchIndex, isPending } =
-    useRegenerateSearchIndexMutation();
+    useRegenerateSearchIndexMutation({
+      onSuccess: async () => {
+        await refetchIndexHealth();
+      },
+    });
 
   return (
     <SettingsRow
       title="Search Index"
-      description="Regenerate the search index to manually update search results."
+      description={describeIndexHealth(
+        indexHealth,
+        describeWatcherStatus(watcherStatus, importStatus?.last?.warning)
+      )}
       isFirst
     >
       <div>
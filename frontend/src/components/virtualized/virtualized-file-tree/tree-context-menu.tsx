@@ -19,10 +19,13 @@ import {
   MenuItemLabel,
   useContextMenuItems,
 } from '@components/context-menu/items';
+import { useExportSiteMutation } from '@hooks/exports';
+import { useExportNotesDialog } from '@hooks/dialogs';
 import type { DropdownItem } from '@/types';
 import { Blog } from '@/icons/blog';
 import { FolderPen } from '@/icons/folder-pen';
 import { PaperclipPlus } from '@/icons/paperclip-plus';
+import { ShareRight } from '@/icons/share-right';
 import type { TreeItemType } from './create';
 import { FILE_TYPE, FOLDER_TYPE } from '@utils/tree-item-types';
 
@@ -63,6 +66,8 @@ export function TreeContextMenu({
 }) {
   const { editTags, moveToTrash, pin, rename, revealInFinder } =
     useContextMenuItems();
+  const { mutate: exportSite } = useExportSiteMutation();
+  const openExportNotesDialog = useExportNotesDialog();
   const projectSettings = useAtomValue(projectSettingsAtom);
   const isFolder = item.kind === 'directory';
   const filePath = isFolder ? null : createFilePath(item.path);
@@ -151,6 +156,31 @@ export function TreeContextMenu({
     });
   }
 
+  if (isFolder && !isMultiSelection) {
+    rows.push({
+      key: 'export-website',
+      content: (
+        <MenuItemLabel icon={<ShareRight {...ICON_PROPS} />}>
+          Export as Website
+        </MenuItemLabel>
+      ),
+      onSelect: () => exportSite({ folder: stripTrailingSlash(item.path) }),
+    });
+  }
+
+  rows.push({
+    key: 'export-notes',
+    content: (
+      <MenuItemLabel icon={<ShareRight {...ICON_PROPS} />}>
+        Export Notes...
+      </MenuItemLabel>
+    ),
+    onSelect: () =>
+      openExportNotesDialog(
+        targetPaths.map((path) => stripTrailingSlash(path))
+      ),
+  });
+
   // pinnedNotes stores slashless paths (the settings.json format), so
   // membership checks strip pierre's folder marker.
   if (targetHasFolder) {
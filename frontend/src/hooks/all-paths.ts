@@ -12,6 +12,7 @@ import {
   FOLDER_CREATE,
   FOLDER_DELETE,
   FOLDER_RENAME,
+  IGNORE_RULES_UPDATE,
 } from '@utils/events';
 import { remapPathThroughRenames, type PathRename } from '@utils/path';
 import { QueryError } from '@utils/query';
@@ -80,6 +81,8 @@ export function useAllPathsInvalidation() {
   useWailsEvent(FILE_CREATE, invalidate);
   useWailsEvent(FOLDER_DELETE, invalidate);
   useWailsEvent(FILE_DELETE, invalidate);
+  // A .bytebookignore edit can hide or reveal whole subtrees at once.
+  useWailsEvent(IGNORE_RULES_UPDATE, invalidate);
   useWailsEvent(FOLDER_RENAME, (event) => {
     const renames = (
       (event.data as Array<{
@@ -0,0 +1,150 @@
+import { type RefObject, useEffect } from 'react';
+import { toast } from 'sonner';
+import { SendCommMessage } from '@bindings/services/codeservice';
+import {
+  KERNEL_COMM_CLOSE,
+  KERNEL_COMM_MSG,
+  KERNEL_COMM_OPEN,
+  KERNEL_INSTANCE_EXITED,
+  KERNEL_INSTANCE_SHUTDOWN,
+} from '@utils/events';
+import { DEFAULT_SONNER_OPTIONS } from '@utils/general';
+import { logger } from '@utils/logging';
+import { CommManager, mountWidgets, WIDGET_COMM_TARGET } from '@utils/widgets';
+import { useWailsEvent } from './events';
+
+type CommOwner = {
+  kernelInstanceId: string;
+  commId: string;
+  codeBlockId: string;
+};
+
+// One comm manager per code block, created when the block first opens a
+// widget or renders its output.
+const commManagers = new Map<string, CommManager>();
+
+// The execution that opened each block's widgets. Messages sent back for a
+// widget are parented to it, so output from widget callbacks lands in the
+// same block without resetting it.
+const commTargets = new Map<
+  string,
+  { kernelInstanceId: string; executionId: string }
+>();
+
+// Comm messages sent from kernel threads carry no parent, so the block that
+// owns a comm is remembered from its comm_open.
+const commOwners = new Map<string, CommOwner>();
+
+function commOwnerKey(kernelInstanceId: string, commId: string) {
+  return `${kernelInstanceId}|${commId}`;
+}
+
+function sendCommData(
+  codeBlockId: string,
+  commId: string,
+  data: Record<string, unknown>
+) {
+  const target = commTargets.get(codeBlockId);
+  if (!target) return;
+  const message = {
+    msgType: 'comm_msg',
+    commId,
+    targetName: '',
+    data,
+    metadata: {},
+    buffers: [],
+  };
+  void SendCommMessage(
+    target.kernelInstanceId,
+    codeBlockId,
+    target.executionId,
+    message
+  ).then((res) => {
+    if (!res.success) toast.error(res.message, DEFAULT_SONNER_OPTIONS);
+  });
+}
+
+function getCommManager(codeBlockId: string) {
+  let manager = commManagers.get(codeBlockId);
+  if (!manager) {
+    manager = new CommManager((commId, data) =>
+      sendCommData(codeBlockId, commId, data)
+    );
+    commManagers.set(codeBlockId, manager);
+  }
+  return manager;
+}
+
+function forgetKernelComms(kernelInstanceId: string) {
+  for (const [key, owner] of commOwners) {
+    if (owner.kernelInstanceId !== kernelInstanceId) continue;
+    commManagers.get(owner.codeBlockId)?.close(owner.commId);
+    commOwners.delete(key);
+  }
+}
+
+/**
+ * Routes kernel comm events to the comm manager of the code block that opened
+ * the comm. Only ipywidgets comms are handled; other targets are ignored.
+ */
+export function useCodeBlockComms() {
+  useWailsEvent(KERNEL_COMM_OPEN, (body) => {
+    logger.event(KERNEL_COMM_OPEN, body);
+    const data = body.data;
+    if (data.targetName !== WIDGET_COMM_TARGET) return;
+    const [codeBlockId, executionId] = data.messageId.split('|');
+    if (!codeBlockId || !executionId) return;
+    commOwners.set(commOwnerKey(data.kernelInstanceId, data.commId), {
+      kernelInstanceId: data.kernelInstanceId,
+      commId: data.commId,
+      codeBlockId,
+    });
+    commTargets.set(codeBlockId, {
+      kernelInstanceId: data.kernelInstanceId,
+      executionId,
+    });
+    getCommManager(codeBlockId).open(data.commId, data.data);
+  });
+
+  useWailsEvent(KERNEL_COMM_MSG, (body) => {
+    const data = body.data;
+    const owner = commOwners.get(
+      commOwnerKey(data.kernelInstanceId, data.commId)
+    );
+    if (!owner) return;
+    commManagers.get(owner.codeBlockId)?.handleMessage(data.commId, data.data);
+  });
+
+  useWailsEvent(KERNEL_COMM_CLOSE, (body) => {
+    logger.event(KERNEL_COMM_CLOSE, body);
+    const data = body.data;
+    const key = commOwnerKey(data.kernelInstanceId, data.commId);
+    const owner = commOwners.get(key);
+    if (!owner) return;
+    commManagers.get(owner.codeBlockId)?.close(data.commId);
+    commOwners.delete(key);
+  });
+
+  useWailsEvent(KERNEL_INSTANCE_SHUTDOWN, (body) => {
+    forgetKernelComms(body.data.id);
+  });
+
+  useWailsEvent(KERNEL_INSTANCE_EXITED, (body) => {
+    forgetKernelComms(body.data.id);
+  });
+}
+
+/**
+ * Renders the widgets in a code block's output whenever the output changes.
+ */
+export function useCodeBlockWidgets(
+  containerRef: RefObject<HTMLElement | null>,
+  codeBlockId: string,
+  resultHtml: string
+) {
+  useEffect(() => {
+    const container = containerRef.current;
+    if (!container) return;
+    return mountWidgets(container, getCommManager(codeBlockId));
+  }, [containerRef, codeBlockId, resultHtml]);
+}
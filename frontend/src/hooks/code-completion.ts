@@ -9,7 +9,7 @@ import { SendCompleteRequest } from '@bindings/services/codeservice';
 import { Completion as LSPCompletion } from '@bindings/services/lspservice';
 import type { CompletionItem as LSPCompletionItem } from '@bindings/lsp/models';
 import type { CompleteReplyEvent } from '@bindings/jupyter_protocol/sockets/models';
-import type { Languages } from '@/types';
+import { type Languages, isValidKernelLanguage } from '@/types';
 import { CODE_BLOCK_COMPLETE_REPLY } from '@utils/events';
 import { useWailsEvent } from './events';
 
@@ -115,6 +115,7 @@ function lspItemToCompletion(item: LSPCompletionItem): Completion {
 
 async function getLSPCompletions({
   noteId,
+  language,
   blockId,
   blockOrder,
   source,
@@ -122,6 +123,7 @@ async function getLSPCompletions({
   col,
 }: {
   noteId: string;
+  language: Languages;
   blockId: string;
   blockOrder: number;
   source: string;
@@ -130,6 +132,7 @@ async function getLSPCompletions({
 }): Promise<Completion[]> {
   const res = await LSPCompletion(
     noteId,
+    language,
     blockId,
     blockOrder,
     source,
@@ -232,7 +235,7 @@ function buildCompletionSource({
   executionId,
 }: CompletionInputs): CompletionSource {
   return async (ctx) => {
-    if (language !== 'python' || !noteId) return null;
+    if (!isValidKernelLanguage(language) || !noteId) return null;
 
     const range = getCompletionRange(ctx);
     if (!range) return null;
@@ -246,6 +249,7 @@ function buildCompletionSource({
       withTimeout(
         getLSPCompletions({
           noteId,
+          language,
           blockId,
           blockOrder,
           source,
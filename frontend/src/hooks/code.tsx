@@ -2,7 +2,11 @@ import { useAtomValue, useSetAtom } from 'jotai';
 import { logger } from '@utils/logging';
 import { CodeNode } from '@components/editor/nodes/code';
 import { useWailsEvent } from './events';
-import { kernelInstancesAtom } from '@/atoms';
+import {
+  kernelInstancesAtom,
+  kernelScopeKey,
+  kernelScopesAtom,
+} from '@/atoms';
 import {
   CodeBlockStatus,
   isValidKernelLanguage,
@@ -14,9 +18,16 @@ import {
   LanguagesWithKernels,
   ProjectSettings,
 } from '@/types';
-import { useMutation, useQuery } from '@tanstack/react-query';
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
 import {
+  AttachKernelConnectionFile,
+  CheckNoteRequirements,
+  ChooseKernelConnectionFile,
+  ConnectJupyterServerKernel,
   EnsureKernel,
+  GetKernelScope,
+  GetTruncatedOutput,
+  InstallNoteRequirements,
   IsPathAValidVirtualEnvironment,
   ListKernels,
   SendExecuteRequest,
@@ -37,6 +48,9 @@ import {
   CODE_BLOCK_STREAM,
   CODE_BLOCK_IOPUB_ERROR,
   CODE_BLOCK_DISPLAY_DATA,
+  CODE_BLOCK_UPDATE_DISPLAY_DATA,
+  CODE_BLOCK_CLEAR_OUTPUT,
+  CODE_BLOCK_OUTPUT_TRUNCATED,
   CODE_BLOCK_INPUT_REQUEST,
   KERNEL_INSTANCE_CREATED,
   KERNEL_INSTANCE_SHUTDOWN,
@@ -85,6 +99,8 @@ export function useKernelInstancesQuery() {
             id: snapshot.id,
             language: snapshot.language,
             noteId: snapshot.noteId,
+            scopeType: snapshot.scopeType,
+            scopeId: snapshot.scopeId,
             status: snapshot.activeExecutions > 0 ? 'busy' : 'idle',
             heartbeat: isKernelHeartbeatStatus(snapshot.heartbeat)
               ? snapshot.heartbeat
@@ -105,15 +121,49 @@ export function useKernelInstancesQuery() {
   }, [instances, setInstances]);
 }
 
+/**
+ * Resolves the kernel scope of (noteId, language) and records it in
+ * kernelScopesAtom, so the note finds folder and project kernels that another
+ * note started.
+ */
+export function useKernelScopeQuery(noteId: string, language: Languages) {
+  const setScopes = useSetAtom(kernelScopesAtom);
+  const hasKernel = isValidKernelLanguage(language);
+
+  const { data: scope } = useQuery({
+    queryKey: queryKeys.kernelScope(noteId, language),
+    enabled: hasKernel && noteId !== '',
+    queryFn: async () => {
+      const res = await GetKernelScope(noteId, language);
+      if (!res.success || !res.data) {
+        throw new QueryError(res.message);
+      }
+      return res.data;
+    },
+  });
+
+  useEffect(() => {
+    if (!scope) return;
+    setScopes((prev) => ({
+      ...prev,
+      [kernelScopeKey(noteId, language)]: scope,
+    }));
+  }, [scope, noteId, language, setScopes]);
+}
+
 /**
  * Wires every kernel:instance:* event into kernelInstancesAtom.
  * This is app-level state, so it should be mounted once from App.
  */
 export function useKernelInstanceEvents() {
+  const queryClient = useQueryClient();
   const setInstances = useSetAtom(kernelInstancesAtom);
 
   useWailsEvent(KERNEL_INSTANCE_CREATED, (body) => {
     logger.event(KERNEL_INSTANCE_CREATED);
+    // A note's scope can change with its frontmatter or the settings, so
+    // scopes are resolved again whenever a kernel starts.
+    void queryClient.invalidateQueries({ queryKey: queryKeys.kernelScopes() });
     const data = body.data;
     const language = data.language;
     if (!isValidKernelLanguage(language)) return;
@@ -123,6 +173,8 @@ export function useKernelInstanceEvents() {
         id: data.id,
         language,
         noteId: data.noteId,
+        scopeType: data.scopeType,
+        scopeId: data.scopeId,
         status: 'starting',
         heartbeat: isKernelHeartbeatStatus(data.heartbeat)
           ? data.heartbeat
@@ -353,28 +405,110 @@ export function useCodeBlockIOPubError(editor: LexicalEditor) {
   });
 }
 
-// Listens for display_data messages and sets rich display output (e.g. images, HTML) on the matching code block node.
+function toDisplayData(data: Record<string, string | undefined>) {
+  const displayData: Record<string, string> = {};
+  for (const [mimeType, content] of Object.entries(data)) {
+    if (content !== undefined) {
+      displayData[mimeType] = content;
+    }
+  }
+  return displayData;
+}
+
+// Listens for display_data messages and appends rich display output (e.g. images, HTML) to the matching code block node.
 export function useCodeBlockDisplayData(editor: LexicalEditor) {
   useWailsEvent(CODE_BLOCK_DISPLAY_DATA, (body) => {
     const data = body.data;
     const [codeBlockId, executionId] = data.messageId.split('|');
-    const displayData: Record<string, string> = {};
-    for (const [mimeType, content] of Object.entries(data.data)) {
-      if (content !== undefined) {
-        displayData[mimeType] = content;
-      }
-    }
+    const displayData = toDisplayData(data.data);
     updateCodeBlock(
       editor,
       codeBlockId,
       (codeNode) => {
-        codeNode.setDisplayResult(displayData, editor);
+        codeNode.setDisplayResult(displayData, data.displayId, editor);
       },
       executionId
     );
   });
 }
 
+// Listens for update_display_data messages and replaces the display with the same display_id in place, so progress bars animate instead of appending output.
+export function useCodeBlockUpdateDisplayData(editor: LexicalEditor) {
+  useWailsEvent(CODE_BLOCK_UPDATE_DISPLAY_DATA, (body) => {
+    const data = body.data;
+    const [codeBlockId, executionId] = data.messageId.split('|');
+    const displayData = toDisplayData(data.data);
+    // No executionId is passed: an update must never reset the block, and
+    // one for an older run of the block is dropped instead.
+    updateCodeBlock(editor, codeBlockId, (codeNode) => {
+      if (codeNode.getExecutionId() !== executionId) return;
+      codeNode.updateDisplayResult(displayData, data.displayId, editor);
+    });
+  });
+}
+
+// Listens for clear_output messages and clears the matching code block's output.
+export function useCodeBlockClearOutput(editor: LexicalEditor) {
+  useWailsEvent(CODE_BLOCK_CLEAR_OUTPUT, (body) => {
+    const [codeBlockId, executionId] = body.data.messageId.split('|');
+    updateCodeBlock(
+      editor,
+      codeBlockId,
+      (codeNode) => {
+        codeNode.resetLastExecutedResult(editor);
+      },
+      executionId
+    );
+  });
+}
+
+// Listens for held-back stream output and shows a notice with a "Show more"
+// button on the matching code block.
+export function useCodeBlockOutputTruncated(editor: LexicalEditor) {
+  useWailsEvent(CODE_BLOCK_OUTPUT_TRUNCATED, (body) => {
+    logger.event(CODE_BLOCK_OUTPUT_TRUNCATED, body);
+    const data = body.data;
+    const [codeBlockId, executionId] = data.messageId.split('|');
+    updateCodeBlock(
+      editor,
+      codeBlockId,
+      (codeNode) => {
+        codeNode.setTruncatedNotice(data.handle, data.hiddenBytes, editor);
+      },
+      executionId
+    );
+  });
+}
+
+// Fetches the next page of a code block's held-back output and shows it.
+export function useShowTruncatedOutputMutation(
+  editor: LexicalEditor,
+  codeBlockId: string,
+  kernelInstanceId: string | null
+) {
+  return useMutation({
+    mutationFn: async ({
+      handle,
+      offset,
+    }: {
+      handle: string;
+      offset: number;
+    }) => {
+      if (!kernelInstanceId) {
+        throw new QueryError('No kernel instance bound to this code block');
+      }
+      const res = await GetTruncatedOutput(kernelInstanceId, handle, offset, 0);
+      if (!res.success || !res.data) {
+        throw new QueryError(res.message);
+      }
+      const page = res.data;
+      updateCodeBlock(editor, codeBlockId, (codeNode) => {
+        codeNode.showTruncatedPage(handle, page, editor);
+      });
+    },
+  });
+}
+
 // Listens for input_request messages from the kernel and sets a prompt on the matching code block so the user can type a response.
 export function useCodeBlockInputRequest(editor: LexicalEditor) {
   useWailsEvent(CODE_BLOCK_INPUT_REQUEST, (body) => {
@@ -427,12 +561,78 @@ export function useSendInputReplyMutation(
   });
 }
 
+/**
+ * Shows a failed request whose kernel runs in another Python environment than
+ * the note asks for, offering to restart the kernel in the note's environment.
+ */
+function offerEnvironmentRestart(
+  message: string,
+  kernelInstanceId: string,
+  noteId: string,
+  language: string
+) {
+  toast.error(message, {
+    ...DEFAULT_SONNER_OPTIONS,
+    action: {
+      label: 'Restart Kernel',
+      onClick: async () => {
+        const shutdownRes = await ShutdownKernel(kernelInstanceId, false);
+        if (!shutdownRes.success) {
+          toast.error(shutdownRes.message, DEFAULT_SONNER_OPTIONS);
+          return;
+        }
+        const ensureRes = await EnsureKernel(noteId, language);
+        if (!ensureRes.success) {
+          toast.error(ensureRes.message, DEFAULT_SONNER_OPTIONS);
+        }
+      },
+    },
+  });
+}
+
+/**
+ * Checks the note's `requires` frontmatter in the background and, when
+ * packages are missing, offers to install them. The install's output streams
+ * into the code block like an execution of its own.
+ */
+async function offerRequirementsInstall(
+  noteId: string,
+  codeBlockId: string,
+  language: Languages
+) {
+  const res = await CheckNoteRequirements(noteId, language);
+  if (!res.success) {
+    toast.error(res.message, DEFAULT_SONNER_OPTIONS);
+    return;
+  }
+  const missing = res.data?.missing ?? [];
+  if (missing.length === 0) return;
+  toast.warning(`This note requires ${missing.join(', ')}`, {
+    ...DEFAULT_SONNER_OPTIONS,
+    action: {
+      label: 'Install',
+      onClick: async () => {
+        const installRes = await InstallNoteRequirements(
+          noteId,
+          codeBlockId,
+          crypto.randomUUID(),
+          language
+        );
+        if (!installRes.success) {
+          toast.error(installRes.message, DEFAULT_SONNER_OPTIONS);
+        }
+      },
+    },
+  });
+}
+
 /**
  * Send an execute_request. Resolves (language, noteId) on the backend to a
  * kernel instance (creating it if necessary, or returning ErrNoIdleKernelToEvict
  * if the per-language pool is full and no idle kernel can be evicted). On success
  * the resolved kernel instance id is written back onto the CodeNode so future
- * control calls (interrupt, shutdown) can target the same instance.
+ * control calls (interrupt, shutdown) can target the same instance. The first
+ * run of a note on a kernel also checks the note's requirements.
  */
 export function useSendExecuteRequestMutation({
   noteId,
@@ -465,6 +665,15 @@ export function useSendExecuteRequestMutation({
       );
       if (!res.success) {
         setStatus('idle');
+        if (res.data?.environmentMismatch) {
+          offerEnvironmentRestart(
+            res.message,
+            res.data.kernelInstanceId,
+            noteId,
+            language
+          );
+          throw new Error(res.message);
+        }
         throw new QueryError(res.message);
       }
       const instanceId = res.data?.kernelInstanceId;
@@ -475,6 +684,9 @@ export function useSendExecuteRequestMutation({
           node?.setKernelInstanceId?.(instanceId, editor);
         });
       }
+      if (res.data?.checkRequirements) {
+        void offerRequirementsInstall(noteId, codeBlockId, language);
+      }
     },
   });
 }
@@ -546,6 +758,16 @@ export function useEnsureKernelMutation() {
     }) => {
       const res = await EnsureKernel(noteId, language);
       if (!res.success) {
+        if (res.data?.environmentMismatch) {
+          offerEnvironmentRestart(
+            res.message,
+            res.data.kernelInstanceId,
+            noteId,
+            language
+          );
+          // A plain Error skips the default toast and the caller's onSuccess.
+          throw new Error(res.message);
+        }
         throw new QueryError(res.message);
       }
       return res.data?.kernelInstanceId ?? null;
@@ -553,6 +775,100 @@ export function useEnsureKernelMutation() {
   });
 }
 
+/**
+ * Asks for a kernel connection file and attaches the note's kernel scope to
+ * the running kernel it describes, e.g. one started by JupyterLab. Resolves to
+ * null when the file picker is cancelled.
+ */
+export function useAttachKernelConnectionFileMutation() {
+  return useMutation({
+    mutationFn: async ({
+      noteId,
+      language,
+    }: {
+      noteId: string;
+      language: LanguagesWithKernels;
+    }) => {
+      const chosen = await ChooseKernelConnectionFile();
+      if (!chosen.success) {
+        throw new QueryError(chosen.message);
+      }
+      if (!chosen.data) return null;
+      const res = await AttachKernelConnectionFile(
+        noteId,
+        language,
+        chosen.data
+      );
+      if (!res.success) {
+        throw new QueryError(res.message);
+      }
+      return res.data?.kernelInstanceId ?? null;
+    },
+    onSuccess: (kernelInstanceId) => {
+      if (kernelInstanceId) {
+        toast.success('Attached to kernel', DEFAULT_SONNER_OPTIONS);
+      }
+    },
+  });
+}
+
+type jupyterServerMutationParams = {
+  noteId: string;
+  language: LanguagesWithKernels;
+  formData: FormData;
+  setErrorText: Dispatch<SetStateAction<string>>;
+};
+
+/**
+ * Connects the note's kernel scope to a kernel on a Jupyter Server using the
+ * values of the Jupyter Server dialog. An empty kernel id starts a new kernel
+ * from the named kernelspec.
+ */
+export function useConnectJupyterServerKernelMutation() {
+  return useMutation({
+    mutationFn: async (variables: jupyterServerMutationParams) => {
+      const { noteId, language, formData } = variables;
+      const serverUrl = formData.get('server-url')?.toString().trim() ?? '';
+      const token = formData.get('token')?.toString() ?? '';
+      const kernelId = formData.get('kernel-id')?.toString() ?? '';
+      const kernelName = formData.get('kernel-name')?.toString().trim() ?? '';
+      if (!serverUrl) {
+        throw new Error('Enter the URL of the Jupyter Server');
+      }
+      if (!kernelId && !kernelName) {
+        throw new Error('Pick a running kernel or enter a kernelspec name');
+      }
+
+      const res = await ConnectJupyterServerKernel(
+        noteId,
+        language,
+        serverUrl,
+        token,
+        kernelId,
+        kernelName
+      );
+      if (!res.success) {
+        throw new Error(res.message);
+      }
+      return true;
+    },
+    onSuccess: () => {
+      toast.success(
+        'Connected to Jupyter Server kernel',
+        DEFAULT_SONNER_OPTIONS
+      );
+    },
+    onError: (error, variables) => {
+      const { setErrorText } = variables;
+      if (error instanceof Error) {
+        setErrorText(error.message);
+      } else {
+        setErrorText('An unknown error occurred');
+      }
+    },
+  });
+}
+
 /**
  * Hook that updates Python venv settings and shuts down all active python kernels
  * so they pick up the new interpreter on next launch.
@@ -4,8 +4,15 @@ import { dialogDataAtom } from '@/atoms';
 import { MotionButton } from '@components/buttons';
 import { DialogErrorText } from '@components/dialog';
 import { AppTextField } from '@components/input';
+import { AppRadio, AppRadioGroup } from '@components/radio-button';
 import { useSaveSearchMutation } from './search';
+import {
+  EXPORT_FORMATS,
+  type ExportFormat,
+  useExportNotesMutation,
+} from './exports';
 import { BookBookmark } from '@/icons/book-bookmark';
+import { ShareRight } from '@/icons/share-right';
 import { Table } from '@/icons/table';
 import type { LexicalEditor, RangeSelection } from 'lexical';
 import { INSERT_TABLE_COMMAND } from '@lexical/table';
@@ -223,3 +230,68 @@ export function useCreateTableDialog(): (
     });
   };
 }
+
+/**
+ * Custom hook that returns a function to open an "Export Notes" dialog.
+ *
+ * When invoked with note and folder paths, this function opens a dialog allowing the user to pick an
+ * export format. On submission, it asks where to write the files and exports the paths.
+ *
+ * @returns {(paths: string[]) => void} Function to open the export dialog for the specified paths.
+ */
+export function useExportNotesDialog(): (paths: string[]) => void {
+  const setDialogData = useSetAtom(dialogDataAtom);
+  const { mutate: exportNotes } = useExportNotesMutation();
+
+  return (paths: string[]) => {
+    setDialogData({
+      isOpen: true,
+      isPending: false,
+      title: 'Export Notes',
+      children: (errorText) => (
+        <>
+          <fieldset className="flex flex-col">
+            <AppRadioGroup
+              name="export-format"
+              defaultValue={EXPORT_FORMATS[0].value}
+              aria-label="Export format"
+            >
+              {EXPORT_FORMATS.map(({ value, label }) => (
+                <AppRadio key={value} value={value}>
+                  {label}
+                </AppRadio>
+              ))}
+            </AppRadioGroup>
+            <DialogErrorText errorText={errorText} />
+          </fieldset>
+          <MotionButton
+            {...getDefaultButtonVariants({
+              disabled: false,
+              whileHover: 1.05,
+              whileTap: 0.95,
+              whileFocus: 1.05,
+            })}
+            className="w-[calc(100%-1.5rem)] mx-auto text-center justify-center"
+            type="submit"
+          >
+            <span>Export</span>
+            <ShareRight width="1.25rem" height="1.25rem" />
+          </MotionButton>
+        </>
+      ),
+      onSubmit: (formData, setErrorText) => {
+        const format = formData.get('export-format') as ExportFormat | null;
+
+        if (!format) {
+          setErrorText('Please choose a format');
+          return Promise.resolve(false);
+        }
+
+        // The dialog closes before the folder picker opens; progress and
+        // errors are reported by the mutation's toasts.
+        exportNotes({ paths, format });
+        return Promise.resolve(true);
+      },
+    });
+  };
+}
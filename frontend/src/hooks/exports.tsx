@@ -0,0 +1,148 @@
+import { useMutation } from '@tanstack/react-query';
+import { toast } from 'sonner';
+import {
+  ChooseExportPath,
+  ExportFolderToSite,
+  ExportNotes,
+} from '@bindings/services/noteservice';
+import { DEFAULT_SONNER_OPTIONS } from '@utils/general';
+import { QueryError } from '@utils/query';
+
+// How many links leaving the export, or notes that failed to export, are
+// spelled out in a warning.
+const ITEMS_SHOWN = 3;
+
+export const EXPORT_FORMATS = [
+  { value: 'pdf', label: 'PDF' },
+  { value: 'docx', label: 'Word (DOCX)' },
+  { value: 'html', label: 'HTML' },
+  { value: 'markdown', label: 'Markdown with attachments (ZIP)' },
+] as const;
+
+export type ExportFormat = (typeof EXPORT_FORMATS)[number]['value'];
+
+/**
+ * Lists the first few items and how many more there are, one per line.
+ */
+function summarizeItems(items: string[]) {
+  const shown = items.slice(0, ITEMS_SHOWN);
+  if (items.length > ITEMS_SHOWN) {
+    shown.push(`and ${items.length - ITEMS_SHOWN} more`);
+  }
+  return shown.join('\n');
+}
+
+/**
+ * Asks where to write exported files and exports the notes and folders at
+ * paths in the given format. Notes that could not be exported, or were
+ * exported with characters replaced, are listed in warning toasts.
+ */
+export function useExportNotesMutation() {
+  return useMutation({
+    mutationFn: async ({
+      paths,
+      format,
+    }: {
+      paths: string[];
+      format: ExportFormat;
+    }) => {
+      const choice = await ChooseExportPath();
+      if (!choice.success) {
+        toast.error(choice.message, DEFAULT_SONNER_OPTIONS);
+        throw new QueryError(choice.message);
+      }
+      // The dialog returns an empty path when it is dismissed.
+      if (!choice.data) return null;
+
+      const resultPromise = (async () => {
+        const res = await ExportNotes(paths, format, choice.data);
+        if (!res.success || !res.data) throw new QueryError(res.message);
+        return res;
+      })();
+      toast.promise(resultPromise, {
+        loading: 'Exporting notes...',
+        success: (res) => res.message,
+        error: (err) =>
+          err instanceof QueryError ? err.message : 'Failed to export notes',
+      });
+      return await resultPromise;
+    },
+    onSuccess: (res) => {
+      if (!res?.data) return;
+
+      const { failed, warnings } = res.data;
+      if (failed.length > 0) {
+        toast.warning(
+          `${failed.length} ${failed.length === 1 ? 'note' : 'notes'} could not be exported`,
+          {
+            ...DEFAULT_SONNER_OPTIONS,
+            description: summarizeItems(
+              failed.map(({ note, reason }) => `${note}: ${reason}`)
+            ),
+            duration: Infinity,
+          }
+        );
+      }
+      if (warnings.length > 0) {
+        toast.warning(
+          `${warnings.length} ${warnings.length === 1 ? 'note was' : 'notes were'} exported with changes`,
+          {
+            ...DEFAULT_SONNER_OPTIONS,
+            description: summarizeItems(
+              warnings.map(({ note, message }) => `${note}: ${message}`)
+            ),
+            duration: Infinity,
+          }
+        );
+      }
+    },
+  });
+}
+
+/**
+ * Asks where to write a static website and exports a folder into it. Pass an
+ * empty folder to export the whole vault. Links that pointed outside the
+ * export are listed in a warning toast.
+ */
+export function useExportSiteMutation() {
+  return useMutation({
+    mutationFn: async ({ folder }: { folder: string }) => {
+      const choice = await ChooseExportPath();
+      if (!choice.success) {
+        toast.error(choice.message, DEFAULT_SONNER_OPTIONS);
+        throw new QueryError(choice.message);
+      }
+      // The dialog returns an empty path when it is dismissed.
+      if (!choice.data) return null;
+
+      const resultPromise = (async () => {
+        const res = await ExportFolderToSite(folder, choice.data);
+        if (!res.success || !res.data) throw new QueryError(res.message);
+        return res;
+      })();
+      toast.promise(resultPromise, {
+        loading: 'Exporting website...',
+        success: (res) => res.message,
+        error: (err) =>
+          err instanceof QueryError ? err.message : 'Failed to export website',
+      });
+      return await resultPromise;
+    },
+    onSuccess: (res) => {
+      if (!res?.data) return;
+
+      const { unresolvedLinks } = res.data;
+      if (unresolvedLinks.length === 0) return;
+      toast.warning(
+        `${unresolvedLinks.length} ${unresolvedLinks.length === 1 ? 'link points' : 'links point'} outside the export`,
+        {
+          ...DEFAULT_SONNER_OPTIONS,
+          description: summarizeItems(
+            unresolvedLinks.map(({ note, link }) => `${note}: ${link}`)
+          ),
+          duration: Infinity,
+        }
+      );
+    },
+  });
+}
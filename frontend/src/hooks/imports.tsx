@@ -0,0 +1,184 @@
+import {
+  useMutation,
+  useQuery,
+  useQueryClient,
+} from '@tanstack/react-query';
+import { toast } from 'sonner';
+import {
+  CancelImport,
+  ChooseExternalImportPath,
+  GetImportStatus,
+  GetWatcherStatus,
+  ImportFromApp,
+} from '@bindings/services/importservice';
+import { useWailsEvent } from '@hooks/events';
+import { IMPORT_PROGRESS } from '@utils/events';
+import { DEFAULT_SONNER_OPTIONS } from '@utils/general';
+import { QueryError } from '@utils/query';
+import { queryKeys } from '@utils/query-keys';
+
+// Imports smaller than this finish too quickly to be worth a toast.
+const IMPORT_TOAST_MIN_FILES = 100;
+
+function importToastId(folderPath: string) {
+  return `import:${folderPath}`;
+}
+
+/**
+ * Loads the bulk import status: the import in flight, queued folders and the
+ * last finished import. Refetched whenever an import reports progress.
+ */
+export function useImportStatusQuery() {
+  const queryClient = useQueryClient();
+
+  useWailsEvent(IMPORT_PROGRESS, () => {
+    void queryClient.invalidateQueries({ queryKey: queryKeys.importStatus() });
+  });
+
+  return useQuery({
+    queryKey: queryKeys.importStatus(),
+    queryFn: async () => {
+      const res = await GetImportStatus();
+      if (!res.success || !res.data) throw new QueryError(res.message);
+      return res.data;
+    },
+    refetchOnWindowFocus: false,
+  });
+}
+
+/**
+ * Loads how many folders are watched natively and how many are polled
+ * because the system ran out of file watchers. Imports are what add watches,
+ * so it is refetched whenever one reports progress.
+ */
+export function useWatcherStatusQuery() {
+  const queryClient = useQueryClient();
+
+  useWailsEvent(IMPORT_PROGRESS, () => {
+    void queryClient.invalidateQueries({
+      queryKey: queryKeys.watcherStatus(),
+    });
+  });
+
+  return useQuery({
+    queryKey: queryKeys.watcherStatus(),
+    queryFn: async () => {
+      const res = await GetWatcherStatus();
+      if (!res.success || !res.data) throw new QueryError(res.message);
+      return res.data;
+    },
+    refetchOnWindowFocus: false,
+  });
+}
+
+/**
+ * Shows a progress toast with a cancel action while a large folder is
+ * imported, and a warning toast when some of its folders fall back to
+ * polling because the system ran out of file watchers. The startup scan of the whole vault reports through the search
+ * reconcile events instead.
+ */
+export function useImportProgressToasts() {
+  useWailsEvent(IMPORT_PROGRESS, (event) => {
+    const progress = event.data;
+    if (progress.folderPath === '') return;
+    const id = importToastId(progress.folderPath);
+
+    if (progress.warning) {
+      toast.warning(progress.warning, {
+        ...DEFAULT_SONNER_OPTIONS,
+        id,
+        duration: Infinity,
+      });
+      return;
+    }
+    if (progress.discovered < IMPORT_TOAST_MIN_FILES) return;
+
+    if (progress.phase === 'done') {
+      toast.success(
+        `Imported ${progress.discovered} files from ${progress.folderPath}`,
+        { ...DEFAULT_SONNER_OPTIONS, id }
+      );
+      return;
+    }
+    if (progress.phase === 'cancelled') {
+      toast.message(`Cancelled import of ${progress.folderPath}`, {
+        ...DEFAULT_SONNER_OPTIONS,
+        id,
+      });
+      return;
+    }
+
+    const label =
+      progress.phase === 'watching'
+        ? `Watching ${progress.watched} of ${progress.directories} folders`
+        : `Indexed ${progress.indexed} of ${progress.discovered} files`;
+    toast.loading(`Importing ${progress.folderPath}: ${label}`, {
+      id,
+      duration: Infinity,
+      action: {
+        label: 'Cancel',
+        onClick: () => {
+          void CancelImport(progress.folderPath);
+        },
+      },
+    });
+  });
+}
+
+export type ExternalImportSource = 'obsidian' | 'notion' | 'bear' | 'evernote';
+
+// How many unconverted items are spelled out in the import warning.
+const IMPORT_ISSUES_SHOWN = 3;
+
+/**
+ * Asks for an export from another app and imports it into a new top-level
+ * folder. Anything that could not be converted is listed in a warning toast.
+ */
+export function useImportFromAppMutation() {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: async ({ source }: { source: ExternalImportSource }) => {
+      const choice = await ChooseExternalImportPath(source);
+      if (!choice.success) {
+        toast.error(choice.message, DEFAULT_SONNER_OPTIONS);
+        throw new QueryError(choice.message);
+      }
+      // The dialog returns an empty path when it is dismissed.
+      if (!choice.data) return null;
+
+      const resultPromise = (async () => {
+        const res = await ImportFromApp(source, choice.data);
+        if (!res.success || !res.data) throw new QueryError(res.message);
+        return res;
+      })();
+      toast.promise(resultPromise, {
+        loading: 'Importing notes...',
+        success: (res) => res.message,
+        error: (err) =>
+          err instanceof QueryError ? err.message : 'Failed to import notes',
+      });
+      return await resultPromise;
+    },
+    onSuccess: (res) => {
+      if (!res?.data) return;
+      void queryClient.invalidateQueries({ queryKey: queryKeys.allPaths() });
+
+      const { issues } = res.data;
+      if (issues.length === 0) return;
+      const shown = issues
+        .slice(0, IMPORT_ISSUES_SHOWN)
+        .map(({ path, reason }) => `${path}: ${reason}`);
+      if (issues.length > IMPORT_ISSUES_SHOWN) {
+        shown.push(`and ${issues.length - IMPORT_ISSUES_SHOWN} more`);
+      }
+      toast.warning(
+        `${issues.length} ${issues.length === 1 ? 'item' : 'items'} could not be fully converted`,
+        {
+          ...DEFAULT_SONNER_OPTIONS,
+          description: shown.join('\n'),
+          duration: Infinity,
+        }
+      );
+    },
+  });
+}
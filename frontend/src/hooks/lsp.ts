@@ -1,12 +1,29 @@
-import { useEffect } from 'react';
-import { NotifyNoteClosed } from '@bindings/services/lspservice';
+import { useEffect, useMemo, useRef } from 'react';
+import { useQuery, useQueryClient } from '@tanstack/react-query';
+import type { Extension, Text } from '@codemirror/state';
+import type { EditorView } from '@codemirror/view';
+import {
+  type Diagnostic as CodeMirrorDiagnostic,
+  forceLinting,
+  linter,
+} from '@codemirror/lint';
+import {
+  GetDiagnostics,
+  NotifyNoteClosed,
+} from '@bindings/services/lspservice';
+import type { Diagnostic as LSPDiagnostic } from '@bindings/lsp/models';
+import { type Languages, isValidKernelLanguage } from '@/types';
+import { LSP_DIAGNOSTICS } from '@utils/events';
+import { queryKeys } from '@utils/query-keys';
+import { useWailsEvent } from './events';
 import { useDecodedNotesWildcardPath } from './routes';
 
 /**
- * Shuts down the note's LSP instance when the user navigates to a different
- * note or the editor unmounts. Each instance is a pyright child process, so
- * without this they would accumulate until app exit. Closing a note that has
- * no LSP instance is a no-op on the backend.
+ * Shuts down the note's LSP instances when the user navigates to a different
+ * note or the editor unmounts. Each instance is a language-server child
+ * process (one per language used in the note), so without this they would
+ * accumulate until app exit. Closing a note that has no LSP instance is a
+ * no-op on the backend.
  */
 export function useLspNoteLifecycle() {
   const noteId = useDecodedNotesWildcardPath();
@@ -18,3 +35,102 @@ export function useLspNoteLifecycle() {
     };
   }, [noteId]);
 }
+
+/**
+ * Refetches a note's diagnostics whenever a language server publishes new
+ * ones. The event only carries one language's diagnostics, so the whole
+ * note is refetched rather than patched. Mount once per open note.
+ */
+export function useLspDiagnosticsEvents() {
+  const queryClient = useQueryClient();
+
+  useWailsEvent(LSP_DIAGNOSTICS, (body) => {
+    void queryClient.invalidateQueries({
+      queryKey: queryKeys.lspDiagnostics(body.data.noteId),
+    });
+  });
+}
+
+function lspSeverityToCodeMirror(
+  severity: number
+): CodeMirrorDiagnostic['severity'] {
+  switch (severity) {
+    case 1:
+      return 'error';
+    case 2:
+      return 'warning';
+    case 3:
+      return 'info';
+    default:
+      return 'hint';
+  }
+}
+
+// Converts a block-local (line, col) pair into a document offset, clamping
+// positions that a stale diagnostic points past the end of.
+function toOffset(doc: Text, line: number, col: number) {
+  if (line >= doc.lines) return doc.length;
+  const docLine = doc.line(line + 1);
+  return Math.min(docLine.from + col, docLine.to);
+}
+
+function toCodeMirrorDiagnostics(
+  doc: Text,
+  diagnostics: LSPDiagnostic[]
+): CodeMirrorDiagnostic[] {
+  return diagnostics.map((diagnostic) => {
+    const from = toOffset(doc, diagnostic.startLine, diagnostic.startCol);
+    const to = toOffset(doc, diagnostic.endLine, diagnostic.endCol);
+    return {
+      from,
+      to: Math.max(from, to),
+      severity: lspSeverityToCodeMirror(diagnostic.severity),
+      message: diagnostic.code
+        ? `${diagnostic.message} (${diagnostic.code})`
+        : diagnostic.message,
+      source: diagnostic.source || undefined,
+    };
+  });
+}
+
+// Returns a CodeMirror lint extension that underlines the language server's diagnostics for one code block. Diagnostics are loaded with GetDiagnostics and refreshed by useLspDiagnosticsEvents.
+export function useDiagnosticsExtension({
+  language,
+  noteId,
+  blockId,
+  view,
+}: {
+  language: Languages;
+  noteId: string;
+  blockId: string;
+  view: EditorView | undefined;
+}): Extension {
+  const { data: diagnostics } = useQuery({
+    queryKey: queryKeys.lspDiagnostics(noteId),
+    enabled: isValidKernelLanguage(language) && noteId !== '',
+    queryFn: async () => {
+      const res = await GetDiagnostics(noteId);
+      if (!res.success || !res.data?.available) return [];
+      return res.data.diagnostics ?? [];
+    },
+    select: (all) =>
+      all.filter((diagnostic) => diagnostic.blockId === blockId),
+  });
+
+  // The lint source reads from a ref so the extension stays stable across
+  // renders; reconfiguring it would drop the underlines until the next run.
+  const diagnosticsRef = useRef<LSPDiagnostic[]>([]);
+
+  useEffect(() => {
+    diagnosticsRef.current = diagnostics ?? [];
+    if (view) forceLinting(view);
+  }, [diagnostics, view]);
+
+  return useMemo(
+    () =>
+      linter((editorView) =>
+        toCodeMirrorDiagnostics(editorView.state.doc, diagnosticsRef.current)
+      ),
+    []
+  );
+}
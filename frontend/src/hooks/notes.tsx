@@ -30,7 +30,11 @@ import {
   type FilePath,
 } from '@utils/path';
 import { useWailsEvent } from './events';
-import { CODE_RESULTS_UPDATE, FILE_WRITE } from '@utils/events';
+import {
+  CODE_RESULTS_UPDATE,
+  FILE_WRITE,
+  LINKS_UNCHANGED,
+} from '@utils/events';
 import { useUpdateProjectSettingsMutation } from './project-settings';
 import type { Frontmatter, TrashRestoreInfo } from '@/types';
 import { $convertFromMarkdownString } from '@lexical/markdown';
@@ -345,3 +349,30 @@ export function useNoteExists(filePath: FilePath | null) {
     enabled: filePath !== null,
   });
 }
+
+// How many notes are named in the unchanged links warning.
+const UNCHANGED_NOTES_SHOWN = 5;
+
+/**
+ * Warns when renaming a file left links to it unchanged because the notes
+ * holding them are in read-only linked folders.
+ */
+export function useUnchangedLinksToast() {
+  useWailsEvent(LINKS_UNCHANGED, (e) => {
+    const links = e.data;
+    if (links.length === 0) return;
+    const notePaths = [...new Set(links.map(({ notePath }) => notePath))];
+    const shown = notePaths.slice(0, UNCHANGED_NOTES_SHOWN);
+    if (notePaths.length > UNCHANGED_NOTES_SHOWN) {
+      shown.push(`and ${notePaths.length - UNCHANGED_NOTES_SHOWN} more`);
+    }
+    toast.warning(
+      `${links.length} ${links.length === 1 ? 'link was' : 'links were'} not updated because ${notePaths.length === 1 ? 'its note is' : 'their notes are'} read-only`,
+      {
+        ...DEFAULT_SONNER_OPTIONS,
+        description: shown.join('\n'),
+        duration: Infinity,
+      }
+    );
+  });
+}
@@ -1,11 +1,20 @@
-import { useMutation, useQuery } from '@tanstack/react-query';
+import {
+  useMutation,
+  useQuery,
+  useQueryClient,
+} from '@tanstack/react-query';
 import { useSetAtom } from 'jotai';
 import { logger } from '@utils/logging';
 import { useEffect } from 'react';
 import {
+  AddLinkedFolder,
+  ChooseLinkedFolderPath,
   GetProjectSettings,
+  RemoveLinkedFolder,
   UpdateProjectSettings,
 } from '@bindings/services/settingsservice';
+import { toast } from 'sonner';
+import { DEFAULT_SONNER_OPTIONS } from '@utils/general';
 import {
   dialogDataAtom,
   projectSettingsAtom,
@@ -127,6 +136,7 @@ function validateProjectSettingsWrapper(data: ProjectSettingsJson) {
   return {
     ...data,
     pinnedNotes: new Set(data.pinnedNotes),
+    linkedFolders: data.linkedFolders ?? [],
     appearance: {
       ...data.appearance,
       accentColor,
@@ -154,6 +164,9 @@ function validateProjectSettingsWrapper(data: ProjectSettingsJson) {
       codeBlockShowLineNumbers: data.code.codeBlockShowLineNumbers ?? false,
       codeBlockDefaultLanguage,
     },
+    attachments: {
+      keepDuplicates: data.attachments?.keepDuplicates ?? false,
+    },
   };
 }
 /**
@@ -253,3 +266,63 @@ export function useUpdateProjectSettingsMutation() {
     },
   });
 }
+
+/**
+ * Lets the user pick an external folder and links it into the vault as a
+ * top-level folder named after the picked directory. The file tree picks the
+ * folder up once the backend has started watching it.
+ */
+export function useAddLinkedFolderMutation() {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: async ({ readOnly }: { readOnly: boolean }) => {
+      const choice = await ChooseLinkedFolderPath();
+      if (!choice.success) throw new QueryError(choice.message);
+      // The dialog returns an empty path when it is dismissed.
+      if (!choice.data) return null;
+
+      const name = choice.data.split(/[\\/]/).filter(Boolean).pop() ?? '';
+      const res = await AddLinkedFolder(name, choice.data, readOnly);
+      if (!res.success) throw new QueryError(res.message);
+      return res;
+    },
+    onSuccess: (res) => {
+      if (!res) return;
+      toast.success(res.message, DEFAULT_SONNER_OPTIONS);
+      void queryClient.invalidateQueries({ queryKey: queryKeys.allPaths() });
+    },
+    onError: (err) => {
+      toast.error(
+        err instanceof QueryError ? err.message : 'Failed to link folder',
+        DEFAULT_SONNER_OPTIONS
+      );
+    },
+  });
+}
+
+/**
+ * Unlinks a linked folder. The folder's files are left untouched on disk.
+ */
+export function useRemoveLinkedFolderMutation() {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: async ({ name }: { name: string }) => {
+      const res = await RemoveLinkedFolder(name);
+      if (!res.success) throw new QueryError(res.message);
+      return res;
+    },
+    onSuccess: (res) => {
+      toast.success(res.message, DEFAULT_SONNER_OPTIONS);
+      void queryClient.invalidateQueries({ queryKey: queryKeys.allPaths() });
+      void queryClient.invalidateQueries({
+        queryKey: queryKeys.fullTextSearchAll(),
+      });
+    },
+    onError: (err) => {
+      toast.error(
+        err instanceof QueryError ? err.message : 'Failed to unlink folder',
+        DEFAULT_SONNER_OPTIONS
+      );
+    },
+  });
+}
@@ -15,6 +15,8 @@ import {
   AddSavedSearch,
   RemoveSavedSearch,
   RegenerateSearchIndex,
+  CheckIndexHealth,
+  GetUnusedAttachments,
 } from '@bindings/services/searchservice';
 import {
   type FullTextSearchPage,
@@ -27,6 +29,7 @@ import {
   FILE_RENAME,
   FOLDER_DELETE,
   FOLDER_RENAME,
+  SEARCH_RECONCILE_PROGRESS,
 } from '@utils/events';
 import { useEffect, useRef } from 'react';
 import { createFilePath, type FilePath } from '@utils/path';
@@ -284,6 +287,57 @@ export function useSavedSearchUpdates() {
   });
 }
 
+/**
+ * Loads the search index health report: schema version and how the indexed
+ * documents compare with the files on disk. Refetched once the startup
+ * reconciliation finishes, since that is when the counts settle.
+ */
+export function useIndexHealthQuery() {
+  const queryClient = useQueryClient();
+
+  useWailsEvent(SEARCH_RECONCILE_PROGRESS, (event) => {
+    const progress = event.data as { phase: string } | undefined;
+    if (progress?.phase !== 'done') return;
+    void queryClient.invalidateQueries({ queryKey: queryKeys.indexHealth() });
+  });
+
+  return useQuery({
+    queryKey: queryKeys.indexHealth(),
+    queryFn: async () => {
+      const res = await CheckIndexHealth();
+      if (!res.success || !res.data) throw new QueryError(res.message);
+      return res.data;
+    },
+    refetchOnWindowFocus: false,
+  });
+}
+
+/**
+ * Loads the attachments that no note links to or embeds. Like the index
+ * health report, it is refetched once the startup reconciliation finishes.
+ */
+export function useUnusedAttachmentsQuery() {
+  const queryClient = useQueryClient();
+
+  useWailsEvent(SEARCH_RECONCILE_PROGRESS, (event) => {
+    const progress = event.data as { phase: string } | undefined;
+    if (progress?.phase !== 'done') return;
+    void queryClient.invalidateQueries({
+      queryKey: queryKeys.unusedAttachments(),
+    });
+  });
+
+  return useQuery({
+    queryKey: queryKeys.unusedAttachments(),
+    queryFn: async () => {
+      const res = await GetUnusedAttachments();
+      if (!res.success) throw new QueryError(res.message);
+      return res.data ?? [];
+    },
+    refetchOnWindowFocus: false,
+  });
+}
+
 /**
  * Hook to regenerate the search index.
  * Shows success/error toast notifications with a loading spinner.
@@ -114,9 +114,19 @@ export const LANGUAGES = {
 export type Languages = (typeof LANGUAGES)[keyof typeof LANGUAGES];
 export type LanguagesWithKernels = Exclude<Languages, typeof LANGUAGES.TEXT>;
 
-export type KernelInstanceData = {
+/**
+ * The kernel scope a note's code runs in. Folder and project kernels are
+ * shared by every note in their scope.
+ */
+export type KernelScope = {
+  scopeType: string;
+  scopeId: string;
+};
+
+export type KernelInstanceData = KernelScope & {
   id: string;
   language: LanguagesWithKernels;
+  // The note that started the kernel.
   noteId: string;
   status: KernelStatus;
   heartbeat: KernelHeartbeatStatus;
@@ -0,0 +1,141 @@
+import '@/test/setup';
+import { describe, it, expect } from 'bun:test';
+import {
+  displayDataToHtml,
+  setTruncatedNotice,
+  showTruncatedPage,
+  updateDisplay,
+  wrapDisplay,
+} from './code-output';
+
+describe('displayDataToHtml', () => {
+  it('prefers HTML over images and text', () => {
+    expect(
+      displayDataToHtml({
+        'text/html': '<b>hi</b>',
+        'image/png': 'AAAA',
+        'text/plain': 'hi',
+      })
+    ).toBe('<b>hi</b>');
+  });
+
+  it('renders images as data URLs', () => {
+    expect(displayDataToHtml({ 'image/png': 'AAAA' })).toBe(
+      '<img src="data:image/png;base64,AAAA" alt="result"/>'
+    );
+  });
+
+  it('leaves a placeholder for widgets', () => {
+    expect(
+      displayDataToHtml({
+        'application/vnd.jupyter.widget-view+json': '{"model_id":"abc"}',
+        'text/plain': 'IntSlider(value=0)',
+      })
+    ).toBe(
+      '<div data-widget-model-id="abc"><pre>IntSlider(value=0)</pre></div>'
+    );
+  });
+});
+
+describe('updateDisplay', () => {
+  it('replaces the display with the same id in place', () => {
+    const result =
+      '<div>before</div>' +
+      wrapDisplay('<pre>10%</pre>', 'bar') +
+      wrapDisplay('<pre>other</pre>', 'other') +
+      '<div>after</div>';
+
+    expect(updateDisplay(result, 'bar', '<pre>50%</pre>')).toBe(
+      '<div>before</div>' +
+        '<div data-display-id="bar"><pre>50%</pre></div>' +
+        '<div data-display-id="other"><pre>other</pre></div>' +
+        '<div>after</div>'
+    );
+  });
+
+  it('returns null when the display is not shown', () => {
+    expect(updateDisplay('<div>text</div>', 'bar', 'x')).toBeNull();
+  });
+
+  it('matches display ids that contain quotes', () => {
+    const result = wrapDisplay('old', 'a"b');
+    expect(updateDisplay(result, 'a"b', 'new')).toBe(
+      '<div data-display-id="a&quot;b">new</div>'
+    );
+  });
+});
+
+describe('wrapDisplay', () => {
+  it('leaves displays without an id unwrapped', () => {
+    expect(wrapDisplay('<pre>x</pre>', '')).toBe('<pre>x</pre>');
+  });
+});
+
+function noticeText(resultHtml: string) {
+  const template = document.createElement('template');
+  template.innerHTML = resultHtml;
+  const label = template.content.querySelector('[data-truncated-handle] span');
+  return label?.textContent;
+}
+
+describe('setTruncatedNotice', () => {
+  it('appends one notice per handle and updates its count', () => {
+    let result = setTruncatedNotice('<div>out</div>', 'output-1', 1024);
+    expect(noticeText(result)).toBe('1.0 KB of output hidden');
+
+    result = setTruncatedNotice(result, 'output-1', 2048);
+    expect(result.match(/data-truncated-handle/g)).toHaveLength(1);
+    expect(noticeText(result)).toBe('2.0 KB of output hidden');
+  });
+});
+
+describe('showTruncatedPage', () => {
+  const page = { text: 'more', totalBytes: 8, hiddenBytes: 8 };
+
+  it('inserts pages above the notice until every page is shown', () => {
+    let result = setTruncatedNotice('<div>out</div>', 'output-1', 8);
+    result =
+      showTruncatedPage(result, 'output-1', {
+        ...page,
+        nextOffset: 4,
+        done: false,
+      }) ?? '';
+    expect(result).toContain('<div>more</div><div class=');
+    expect(result).toContain('data-truncated-offset="4"');
+    expect(noticeText(result)).toBe('4 B of output hidden');
+
+    result =
+      showTruncatedPage(result, 'output-1', {
+        ...page,
+        nextOffset: 8,
+        done: true,
+      }) ?? '';
+    expect(result).toBe('<div>out</div><div>more</div><div>more</div>');
+  });
+
+  it('notes output the backend discarded', () => {
+    const result = showTruncatedPage(
+      setTruncatedNotice('', 'output-1', 2048),
+      'output-1',
+      {
+        text: 'kept',
+        nextOffset: 4,
+        totalBytes: 4,
+        hiddenBytes: 2052,
+        done: true,
+      }
+    );
+    expect(result).toContain('2.0 KB of output was discarded');
+    expect(result).not.toContain('data-truncated-handle');
+  });
+
+  it('returns null when the notice is gone', () => {
+    expect(
+      showTruncatedPage('<div>out</div>', 'output-1', {
+        ...page,
+        nextOffset: 8,
+        done: true,
+      })
+    ).toBeNull();
+  });
+});
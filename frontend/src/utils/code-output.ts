@@ -0,0 +1,161 @@
+import type { TruncatedOutputPage } from '@bindings/jupyter_protocol/sockets/models';
+import { formatByteSize } from './string-formatting';
+import { getWidgetModelId, WIDGET_VIEW_MIME_TYPE } from './widgets';
+
+/**
+ * Converts a display_data or update_display_data mime bundle into the HTML
+ * shown in a code block's output.
+ */
+export function displayDataToHtml(mimeTypeToData: Record<string, string>) {
+  // 1. If it's a widget, leave a placeholder that the code block output
+  // renders the widget into, falling back to its text
+  const widgetView = mimeTypeToData[WIDGET_VIEW_MIME_TYPE];
+  const widgetModelId = widgetView ? getWidgetModelId(widgetView) : null;
+  if (widgetModelId) {
+    return `<div data-widget-model-id="${widgetModelId}"><pre>${mimeTypeToData['text/plain'] ?? ''}</pre></div>`;
+  }
+  // 2. If there's HTML, show that
+  if (mimeTypeToData['text/html']) {
+    return mimeTypeToData['text/html'];
+  }
+  // 3. Otherwise, if there's an image, show that
+  const imageEntry = Object.entries(mimeTypeToData).find(([mt]) =>
+    mt.startsWith('image/')
+  );
+  if (imageEntry) {
+    const [mt, data] = imageEntry;
+    return `<img src="data:${mt};base64,${data}" alt="result"/>`;
+  }
+  // 4. Fallback to plain text
+  if (mimeTypeToData['text/plain']) {
+    return `<pre>${mimeTypeToData['text/plain']}</pre>`;
+  }
+  // 5. Anything else, just dump it
+  return Object.values(mimeTypeToData)
+    .map((data) => `<div>${data}</div>`)
+    .join('');
+}
+
+function escapeAttribute(value: string) {
+  return value
+    .replaceAll('&', '&amp;')
+    .replaceAll('"', '&quot;')
+    .replaceAll('<', '&lt;');
+}
+
+/**
+ * Wraps a display's HTML so a later update_display_data with the same
+ * display_id can find and replace it. Displays without an id are not wrapped.
+ */
+export function wrapDisplay(html: string, displayId: string) {
+  if (!displayId) return html;
+  return `<div data-display-id="${escapeAttribute(displayId)}">${html}</div>`;
+}
+
+/**
+ * Replaces the content of every display with displayId in resultHtml and
+ * returns the new HTML, or null when resultHtml has no such display.
+ */
+export function updateDisplay(
+  resultHtml: string,
+  displayId: string,
+  html: string
+): string | null {
+  const template = document.createElement('template');
+  template.innerHTML = resultHtml;
+  const displays =
+    template.content.querySelectorAll<HTMLElement>('[data-display-id]');
+  let found = false;
+  for (const display of displays) {
+    if (display.dataset.displayId !== displayId) continue;
+    display.innerHTML = html;
+    found = true;
+  }
+  return found ? template.innerHTML : null;
+}
+
+function truncatedNoticeText(hiddenBytes: number) {
+  return `${formatByteSize(hiddenBytes)} of output hidden`;
+}
+
+function findTruncatedNotice(content: DocumentFragment, handle: string) {
+  const notices = content.querySelectorAll<HTMLElement>(
+    '[data-truncated-handle]'
+  );
+  for (const notice of notices) {
+    if (notice.dataset.truncatedHandle === handle) return notice;
+  }
+  return null;
+}
+
+/**
+ * Shows that hiddenBytes of an execution's stream output were held back by the
+ * backend. The notice's "Show more" button pages the output in with
+ * showTruncatedPage. A notice already shown for handle only has its count
+ * updated.
+ */
+export function setTruncatedNotice(
+  resultHtml: string,
+  handle: string,
+  hiddenBytes: number
+): string {
+  const template = document.createElement('template');
+  template.innerHTML = resultHtml;
+  const existing = findTruncatedNotice(template.content, handle);
+  if (existing) {
+    const shown = Number(existing.dataset.truncatedOffset ?? 0);
+    const label = existing.querySelector('span');
+    if (label) label.textContent = truncatedNoticeText(hiddenBytes - shown);
+    return template.innerHTML;
+  }
+  return (
+    resultHtml +
+    `<div class="flex items-center gap-2 text-zinc-500 dark:text-zinc-400" data-truncated-handle="${escapeAttribute(handle)}" data-truncated-offset="0">` +
+    `<span>${truncatedNoticeText(hiddenBytes)}</span>` +
+    '<button type="button" class="px-2 py-1 bg-zinc-200 dark:bg-zinc-700 hover:bg-zinc-300 dark:hover:bg-zinc-600">Show more</button>' +
+    '</div>'
+  );
+}
+
+/**
+ * Inserts a page of held-back output above its notice and advances the
+ * notice's offset. Once every page is shown the notice is removed, or left
+ * as a note of how much output the backend discarded. Returns null when the
+ * notice is no longer shown.
+ */
+export function showTruncatedPage(
+  resultHtml: string,
+  handle: string,
+  page: Pick<
+    TruncatedOutputPage,
+    'text' | 'nextOffset' | 'totalBytes' | 'hiddenBytes' | 'done'
+  >
+): string | null {
+  const template = document.createElement('template');
+  template.innerHTML = resultHtml;
+  const notice = findTruncatedNotice(template.content, handle);
+  if (!notice) return null;
+
+  const output = document.createElement('div');
+  output.innerHTML = page.text;
+  notice.before(output);
+  notice.dataset.truncatedOffset = String(page.nextOffset);
+
+  const discarded = page.hiddenBytes - page.totalBytes;
+  if (!page.done) {
+    const label = notice.querySelector('span');
+    if (label) {
+      label.textContent = truncatedNoticeText(
+        page.hiddenBytes - page.nextOffset
+      );
+    }
+  } else if (discarded > 0) {
+    const note = document.createElement('div');
+    note.className = 'text-zinc-500 dark:text-zinc-400';
+    note.textContent = `${formatByteSize(discarded)} of output was discarded`;
+    notice.replaceWith(note);
+  } else {
+    notice.remove();
+  }
+  return template.innerHTML;
+}
@@ -15,7 +15,11 @@ import {
 } from 'lexical';
 import { handleRunOrInterruptCode, type EnsureKernelFunction } from './code';
 import { getDefaultStore } from 'jotai';
-import { kernelInstancesAtom } from '@/atoms';
+import {
+  findKernelInstanceForNote,
+  kernelInstancesAtom,
+  kernelScopesAtom,
+} from '@/atoms';
 
 /**
  * Looks up the live kernel instance for (noteId, language) in the global jotai store.
@@ -25,11 +29,12 @@ function getInstanceForNote(
   noteId: string,
   language: LanguagesWithKernels
 ): KernelInstanceData | null {
-  const all = getDefaultStore().get(kernelInstancesAtom);
-  return (
-    Object.values(all).find(
-      (i) => i.noteId === noteId && i.language === language
-    ) ?? null
+  const store = getDefaultStore();
+  return findKernelInstanceForNote(
+    store.get(kernelInstancesAtom),
+    store.get(kernelScopesAtom),
+    noteId,
+    language
   );
 }
 
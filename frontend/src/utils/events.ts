@@ -9,6 +9,7 @@ export const FILE_CREATE = 'file:create';
 export const FILE_DELETE = 'file:delete';
 export const FILE_RENAME = 'file:rename';
 export const FILE_WRITE = 'file:write';
+export const LINKS_UNCHANGED = 'links:unchanged';
 
 // Folder events
 export const FOLDER_RENAME = 'folder:rename';
@@ -33,6 +34,11 @@ export const SETTINGS_UPDATE = 'settings:update';
 export const TAGS_INDEX_UPDATE = 'tags:index_update';
 export const SAVED_SEARCH_UPDATE = 'saved-search:update';
 export const CODE_RESULTS_UPDATE = 'code-results:update';
+export const IGNORE_RULES_UPDATE = 'ignore:update';
+
+// Search index events
+export const SEARCH_RECONCILE_PROGRESS = 'search:reconcile:progress';
+export const IMPORT_PROGRESS = 'import:progress';
 
 // Kernel instance events (per-instance, not per-language)
 export const KERNEL_INSTANCE_CREATED = 'kernel:instance:created';
@@ -42,16 +48,31 @@ export const KERNEL_INSTANCE_HEARTBEAT = 'kernel:instance:heartbeat';
 export const KERNEL_INSTANCE_LAUNCH_ERROR = 'kernel:instance:launch_error';
 export const KERNEL_INSTANCE_EXITED = 'kernel:instance:exited';
 
+// Kernel comm events (ipywidgets and other comm targets)
+export const KERNEL_COMM_OPEN = 'kernel:comm:open';
+export const KERNEL_COMM_MSG = 'kernel:comm:msg';
+export const KERNEL_COMM_CLOSE = 'kernel:comm:close';
+
+// LSP events
+export const LSP_DIAGNOSTICS = 'lsp:diagnostics';
+
 // Code block events (still scoped by messageId)
 export const CODE_BLOCK_STREAM = 'code:code-block:stream';
 export const CODE_BLOCK_EXECUTE_RESULT = 'code:code-block:execute_result';
 export const CODE_BLOCK_DISPLAY_DATA = 'code:code-block:display_data';
+export const CODE_BLOCK_UPDATE_DISPLAY_DATA =
+  'code:code-block:update_display_data';
+export const CODE_BLOCK_CLEAR_OUTPUT = 'code:code-block:clear_output';
 export const CODE_BLOCK_EXECUTE_INPUT = 'code:code-block:execute_input';
 export const CODE_BLOCK_STATUS = 'code:code-block:status';
 export const CODE_BLOCK_IOPUB_ERROR = 'code:code-block:iopub_error';
 export const CODE_BLOCK_INPUT_REQUEST = 'code:code-block:input_request';
 export const CODE_BLOCK_INSPECT_REPLY = 'code:code-block:inspect_reply';
 export const CODE_BLOCK_COMPLETE_REPLY = 'code:code-block:complete_reply';
+export const CODE_BLOCK_OUTPUT_TRUNCATED = 'code:code-block:output_truncated';
+
+// Python environment events
+export const PYTHON_ENVIRONMENT_PROGRESS = 'code:python-environment:progress';
 
 // Returns true if the Wails event was emitted by the current window.
 export async function isEventInCurrentWindow(data: WailsEvent) {
@@ -34,11 +34,21 @@ export const queryKeys = {
   treeFilterPathsAll: () => ['tree-filter-paths'] as const,
   treeFilterPaths: (searchQuery: string) =>
     ['tree-filter-paths', searchQuery] as const,
+  indexHealth: () => ['index-health'] as const,
+  unusedAttachments: () => ['unused-attachments'] as const,
+  importStatus: () => ['import-status'] as const,
+  watcherStatus: () => ['watcher-status'] as const,
 
   // Settings & kernels
   projectSettings: () => ['project-settings'] as const,
   kernelInstances: () => ['kernel-instances'] as const,
+  kernelScopes: () => ['kernel-scope'] as const,
+  kernelScope: (noteId: string, language: string) =>
+    ['kernel-scope', noteId, language] as const,
   kernelDescriptor: (language: string) =>
     ['kernel-descriptor', language] as const,
   pythonVenvs: () => ['python-venvs'] as const,
+
+  // Language servers
+  lspDiagnostics: (noteId: string) => ['lsp-diagnostics', noteId] as const,
 };
@@ -16,6 +16,7 @@ import {
   unescapeFileContentFromMarkdown,
   unescapeUnderscore,
   formatDate,
+  formatByteSize,
 } from './string-formatting';
 
 describe('cn', () => {
@@ -305,3 +306,14 @@ describe('formatDate', () => {
     });
   });
 });
+
+describe('formatByteSize', () => {
+  it('keeps small sizes in bytes', () => {
+    expect(formatByteSize(512)).toBe('512 B');
+  });
+
+  it('uses the largest unit with one decimal', () => {
+    expect(formatByteSize(1536)).toBe('1.5 KB');
+    expect(formatByteSize(3 * 1024 * 1024)).toBe('3.0 MB');
+  });
+});
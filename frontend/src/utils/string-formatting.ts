@@ -276,3 +276,16 @@ export function formatDate(
     day: 'numeric',
   });
 }
+
+const BYTE_UNITS = ['B', 'KB', 'MB', 'GB'];
+
+// Formats a byte count with the largest unit that keeps it at or above 1, e.g. "1.5 KB".
+export function formatByteSize(bytes: number) {
+  let size = bytes;
+  let unit = 0;
+  while (size >= 1024 && unit < BYTE_UNITS.length - 1) {
+    size /= 1024;
+    unit++;
+  }
+  return `${unit === 0 ? size : size.toFixed(1)} ${BYTE_UNITS[unit]}`;
+}
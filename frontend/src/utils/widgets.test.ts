@@ -0,0 +1,182 @@
+import '@/test/setup';
+import { describe, it, expect } from 'bun:test';
+import {
+  CommManager,
+  getWidgetModelId,
+  mountWidgets,
+  renderWidget,
+} from './widgets';
+
+type SentMessage = { commId: string; data: Record<string, unknown> };
+
+function createManager() {
+  const sent: SentMessage[] = [];
+  const manager = new CommManager((commId, data) => {
+    sent.push({ commId, data });
+  });
+  return { manager, sent };
+}
+
+function fireInput(element: HTMLInputElement, value: string) {
+  element.value = value;
+  element.dispatchEvent(new window.Event('input'));
+}
+
+describe('CommManager', () => {
+  it('merges update messages from the kernel into the model', () => {
+    const { manager } = createManager();
+    manager.open('slider', {
+      state: { _model_name: 'IntSliderModel', value: 1, max: 10 },
+    });
+    manager.handleMessage('slider', { method: 'update', state: { value: 4 } });
+    expect(manager.getState('slider')).toEqual({
+      _model_name: 'IntSliderModel',
+      value: 4,
+      max: 10,
+    });
+  });
+
+  it('ignores messages for comms that were never opened', () => {
+    const { manager } = createManager();
+    manager.handleMessage('missing', { method: 'update', state: { value: 1 } });
+    expect(manager.getState('missing')).toBeUndefined();
+  });
+
+  it('sends user edits back to the kernel as update messages', () => {
+    const { manager, sent } = createManager();
+    manager.open('slider', { state: { _model_name: 'IntSliderModel' } });
+    manager.setState('slider', { value: 7 });
+    expect(sent).toEqual([
+      {
+        commId: 'slider',
+        data: { method: 'update', state: { value: 7 }, buffer_paths: [] },
+      },
+    ]);
+    expect(manager.getState('slider')?.value).toBe(7);
+  });
+
+  it('forgets closed comms', () => {
+    const { manager } = createManager();
+    manager.open('progress', { state: { _model_name: 'IntProgressModel' } });
+    manager.close('progress');
+    expect(manager.getState('progress')).toBeUndefined();
+  });
+});
+
+describe('getWidgetModelId', () => {
+  it('reads the model id of a widget view', () => {
+    expect(getWidgetModelId('{"model_id":"abc","version_major":2}')).toBe(
+      'abc'
+    );
+  });
+
+  it('returns null for malformed views', () => {
+    expect(getWidgetModelId('not json')).toBeNull();
+    expect(getWidgetModelId('{}')).toBeNull();
+    expect(getWidgetModelId('{"model_id":"a\\"><img>"}')).toBeNull();
+  });
+});
+
+describe('renderWidget', () => {
+  it('updates a progress bar in place when the kernel changes it', () => {
+    const { manager } = createManager();
+    manager.open('bar', {
+      state: { _model_name: 'FloatProgressModel', value: 0, max: 10 },
+    });
+    const view = renderWidget(manager, 'bar');
+    const progress = view?.element.querySelector('progress');
+    expect(progress?.value).toBe(0);
+
+    manager.handleMessage('bar', { method: 'update', state: { value: 5 } });
+    expect(view?.element.querySelector('progress')).toBe(progress);
+    expect(progress?.value).toBe(5);
+    expect(progress?.max).toBe(10);
+  });
+
+  it('round-trips slider edits through the kernel', () => {
+    const { manager, sent } = createManager();
+    manager.open('slider', {
+      state: { _model_name: 'IntSliderModel', value: 2, min: 0, max: 10 },
+    });
+    const view = renderWidget(manager, 'slider');
+    const input = view?.element.querySelector('input');
+    if (!input) throw new Error('slider input not rendered');
+
+    fireInput(input, '6');
+    expect(sent).toEqual([
+      {
+        commId: 'slider',
+        data: { method: 'update', state: { value: 6 }, buffer_paths: [] },
+      },
+    ]);
+
+    manager.handleMessage('slider', {
+      method: 'echo_update',
+      state: { value: 3 },
+    });
+    expect(input.value).toBe('3');
+  });
+
+  it('sends button clicks as custom messages', () => {
+    const { manager, sent } = createManager();
+    manager.open('button', {
+      state: { _model_name: 'ButtonModel', description: 'Run' },
+    });
+    const view = renderWidget(manager, 'button');
+    expect(view?.element.textContent).toBe('Run');
+
+    (view?.element as HTMLButtonElement).click();
+    expect(sent).toEqual([
+      {
+        commId: 'button',
+        data: { method: 'custom', content: { event: 'click' } },
+      },
+    ]);
+  });
+
+  it('renders the children of a box like a tqdm bar', () => {
+    const { manager } = createManager();
+    manager.open('prefix', {
+      state: { _model_name: 'HTMLModel', value: '50%' },
+    });
+    manager.open('bar', {
+      state: { _model_name: 'FloatProgressModel', value: 5, max: 10 },
+    });
+    manager.open('box', {
+      state: {
+        _model_name: 'HBoxModel',
+        children: ['IPY_MODEL_prefix', 'IPY_MODEL_bar'],
+      },
+    });
+    const view = renderWidget(manager, 'box');
+    expect(view?.element.textContent).toContain('50%');
+    expect(view?.element.querySelector('progress')?.value).toBe(5);
+  });
+
+  it('returns null for models that are not open', () => {
+    const { manager } = createManager();
+    expect(renderWidget(manager, 'missing')).toBeNull();
+  });
+});
+
+describe('mountWidgets', () => {
+  it('replaces known placeholders and keeps the fallback for others', () => {
+    const { manager } = createManager();
+    manager.open('label', {
+      state: { _model_name: 'LabelModel', value: 'hi' },
+    });
+    const container = document.createElement('div');
+    container.innerHTML =
+      '<div data-widget-model-id="label">Label(value=hi)</div>' +
+      '<div data-widget-model-id="gone">IntSlider(value=1)</div>';
+
+    const unmount = mountWidgets(container, manager);
+    const [known, unknown] = container.querySelectorAll('div');
+    expect(known.querySelector('.widget-label')?.textContent).toBe('hi');
+    expect(unknown.textContent).toBe('IntSlider(value=1)');
+
+    unmount();
+    manager.handleMessage('label', { method: 'update', state: { value: 'x' } });
+    expect(known.textContent).toBe('hi');
+  });
+});
@@ -0,0 +1,403 @@
+/** The comm target ipywidgets opens its models on. */
+export const WIDGET_COMM_TARGET = 'jupyter.widget';
+
+/** The mime type of a display_data output that shows a widget. */
+export const WIDGET_VIEW_MIME_TYPE = 'application/vnd.jupyter.widget-view+json';
+
+// Widgets refer to other widgets (e.g. a box's children) as "IPY_MODEL_<id>".
+const MODEL_REFERENCE_PREFIX = 'IPY_MODEL_';
+
+export type WidgetState = Record<string, unknown>;
+
+/** Sends comm_msg data for a comm to the kernel. */
+export type CommSender = (
+  commId: string,
+  data: Record<string, unknown>
+) => void;
+
+function asWidgetState(value: unknown): WidgetState {
+  if (typeof value !== 'object' || value === null) return {};
+  return value as WidgetState;
+}
+
+/**
+ * Holds the ipywidgets models shown by one code block, keyed by comm id, and
+ * keeps them in sync with the kernel. Views subscribe to a model to re-render
+ * when the kernel changes it; edits made in a view go back through setState.
+ */
+export class CommManager {
+  private models = new Map<string, WidgetState>();
+  private listeners = new Map<string, Set<() => void>>();
+  private send: CommSender;
+
+  constructor(send: CommSender) {
+    this.send = send;
+  }
+
+  /** Handles a comm_open carrying a widget's initial state. */
+  open(commId: string, data: Record<string, unknown>) {
+    this.models.set(commId, { ...asWidgetState(data.state) });
+    this.notify(commId);
+  }
+
+  /** Handles a comm_msg from the kernel. Only state updates are understood. */
+  handleMessage(commId: string, data: Record<string, unknown>) {
+    const model = this.models.get(commId);
+    if (!model) return;
+    if (data.method !== 'update' && data.method !== 'echo_update') return;
+    this.models.set(commId, { ...model, ...asWidgetState(data.state) });
+    this.notify(commId);
+  }
+
+  /** Handles a comm_close, after which the widget can no longer be shown. */
+  close(commId: string) {
+    this.models.delete(commId);
+    this.notify(commId);
+  }
+
+  getState(modelId: string): WidgetState | undefined {
+    return this.models.get(modelId);
+  }
+
+  /** Calls listener whenever the model changes until unsubscribed. */
+  subscribe(modelId: string, listener: () => void) {
+    const listeners = this.listeners.get(modelId) ?? new Set<() => void>();
+    this.listeners.set(modelId, listeners);
+    listeners.add(listener);
+    return () => {
+      listeners.delete(listener);
+    };
+  }
+
+  /** Applies a change made in the editor and syncs it to the kernel. */
+  setState(modelId: string, patch: WidgetState) {
+    const model = this.models.get(modelId);
+    if (!model) return;
+    this.models.set(modelId, { ...model, ...patch });
+    this.notify(modelId);
+    this.send(modelId, { method: 'update', state: patch, buffer_paths: [] });
+  }
+
+  /** Sends a custom message, e.g. a button click, to the kernel-side widget. */
+  sendCustom(modelId: string, content: Record<string, unknown>) {
+    if (!this.models.has(modelId)) return;
+    this.send(modelId, { method: 'custom', content });
+  }
+
+  private notify(modelId: string) {
+    this.listeners.get(modelId)?.forEach((listener) => listener());
+  }
+}
+
+// Model ids are written into the output HTML, so anything but a plain id is
+// rejected.
+const MODEL_ID_PATTERN = /^[\w-]+$/;
+
+/** Returns the model id of a widget-view+json display bundle, if valid. */
+export function getWidgetModelId(widgetView: string): string | null {
+  try {
+    const parsed = JSON.parse(widgetView) as { model_id?: unknown };
+    const modelId = parsed.model_id;
+    if (typeof modelId !== 'string' || !MODEL_ID_PATTERN.test(modelId)) {
+      return null;
+    }
+    return modelId;
+  } catch {
+    return null;
+  }
+}
+
+type WidgetView = {
+  element: HTMLElement;
+  update: (state: WidgetState) => void;
+  dispose?: () => void;
+};
+
+function text(value: unknown) {
+  return value === undefined || value === null ? '' : String(value);
+}
+
+function createDescription() {
+  const label = document.createElement('span');
+  label.className = 'widget-description shrink-0';
+  return label;
+}
+
+function progressView(): WidgetView {
+  const element = document.createElement('div');
+  element.className = 'widget-progress flex items-center gap-2';
+  const label = createDescription();
+  const progress = document.createElement('progress');
+  progress.className = 'flex-1';
+  element.append(label, progress);
+  return {
+    element,
+    update(state) {
+      const min = Number(state.min ?? 0);
+      label.textContent = text(state.description);
+      progress.max = Number(state.max ?? 100) - min;
+      progress.value = Number(state.value ?? 0) - min;
+      progress.dataset.barStyle = text(state.bar_style);
+    },
+  };
+}
+
+function sliderView(manager: CommManager, modelId: string): WidgetView {
+  const element = document.createElement('div');
+  element.className = 'widget-slider flex items-center gap-2';
+  const label = createDescription();
+  const input = document.createElement('input');
+  input.type = 'range';
+  input.className = 'flex-1';
+  const readout = document.createElement('span');
+  readout.className = 'widget-readout';
+  element.append(label, input, readout);
+
+  function sync() {
+    manager.setState(modelId, { value: Number(input.value) });
+  }
+  input.addEventListener('input', () => {
+    if (manager.getState(modelId)?.continuous_update !== false) sync();
+  });
+  input.addEventListener('change', () => {
+    if (manager.getState(modelId)?.continuous_update === false) sync();
+  });
+
+  return {
+    element,
+    update(state) {
+      label.textContent = text(state.description);
+      input.min = text(state.min ?? 0);
+      input.max = text(state.max ?? 100);
+      input.step = text(state.step ?? 1);
+      input.value = text(state.value ?? 0);
+      input.disabled = state.disabled === true;
+      readout.textContent = state.readout === false ? '' : text(state.value);
+    },
+  };
+}
+
+function checkboxView(manager: CommManager, modelId: string): WidgetView {
+  const element = document.createElement('label');
+  element.className = 'widget-checkbox flex items-center gap-2';
+  const input = document.createElement('input');
+  input.type = 'checkbox';
+  const label = createDescription();
+  element.append(input, label);
+  input.addEventListener('change', () => {
+    manager.setState(modelId, { value: input.checked });
+  });
+  return {
+    element,
+    update(state) {
+      label.textContent = text(state.description);
+      input.checked = state.value === true;
+      input.disabled = state.disabled === true;
+    },
+  };
+}
+
+function textView(manager: CommManager, modelId: string): WidgetView {
+  const element = document.createElement('div');
+  element.className = 'widget-text flex items-center gap-2';
+  const label = createDescription();
+  const input = document.createElement('input');
+  input.type = 'text';
+  input.className = 'flex-1 bg-zinc-100 dark:bg-zinc-800 px-1.5 py-1';
+  element.append(label, input);
+  function sync() {
+    if (manager.getState(modelId)?.value === input.value) return;
+    manager.setState(modelId, { value: input.value });
+  }
+  input.addEventListener('change', sync);
+  // Code block output is rendered inside a form that answers input()
+  // prompts, so Enter syncs the value instead of submitting it.
+  input.addEventListener('keydown', (event) => {
+    if (event.key !== 'Enter') return;
+    event.preventDefault();
+    sync();
+  });
+  return {
+    element,
+    update(state) {
+      label.textContent = text(state.description);
+      input.placeholder = text(state.placeholder);
+      input.value = text(state.value);
+      input.disabled = state.disabled === true;
+    },
+  };
+}
+
+function buttonView(manager: CommManager, modelId: string): WidgetView {
+  const element = document.createElement('button');
+  element.type = 'button';
+  element.className =
+    'widget-button self-start px-2 py-1 bg-zinc-200 dark:bg-zinc-700 hover:bg-zinc-300 dark:hover:bg-zinc-600';
+  element.addEventListener('click', () => {
+    manager.sendCustom(modelId, { event: 'click' });
+  });
+  return {
+    element,
+    update(state) {
+      element.textContent = text(state.description);
+      element.disabled = state.disabled === true;
+    },
+  };
+}
+
+function htmlView(): WidgetView {
+  const element = document.createElement('div');
+  element.className = 'widget-html flex items-center gap-2';
+  const label = createDescription();
+  const content = document.createElement('div');
+  element.append(label, content);
+  return {
+    element,
+    update(state) {
+      label.textContent = text(state.description);
+      content.innerHTML = text(state.value);
+    },
+  };
+}
+
+function labelView(): WidgetView {
+  const element = document.createElement('span');
+  element.className = 'widget-label';
+  return {
+    element,
+    update(state) {
+      element.textContent = text(state.value);
+    },
+  };
+}
+
+function boxView(
+  manager: CommManager,
+  direction: 'row' | 'column'
+): WidgetView {
+  const element = document.createElement('div');
+  element.className = `widget-box flex gap-2 ${
+    direction === 'row' ? 'flex-row items-center' : 'flex-col'
+  }`;
+  let children: WidgetView[] = [];
+  let renderedChildren = '';
+
+  function disposeChildren() {
+    children.forEach((child) => child.dispose?.());
+    children = [];
+  }
+
+  return {
+    element,
+    update(state) {
+      const references = Array.isArray(state.children)
+        ? state.children.map(text)
+        : [];
+      const key = references.join(',');
+      if (key === renderedChildren) return;
+      renderedChildren = key;
+      disposeChildren();
+      for (const reference of references) {
+        const child = renderWidget(
+          manager,
+          reference.startsWith(MODEL_REFERENCE_PREFIX)
+            ? reference.slice(MODEL_REFERENCE_PREFIX.length)
+            : reference
+        );
+        if (child) children.push(child);
+      }
+      element.replaceChildren(...children.map((child) => child.element));
+    },
+    dispose: disposeChildren,
+  };
+}
+
+function unsupportedView(modelName: string): WidgetView {
+  const element = document.createElement('span');
+  element.className = 'widget-unsupported text-zinc-500 dark:text-zinc-400';
+  element.textContent = `${modelName || 'This widget'} is not supported`;
+  return { element, update: () => undefined };
+}
+
+function createView(
+  manager: CommManager,
+  modelId: string,
+  modelName: string
+): WidgetView {
+  switch (modelName) {
+    case 'IntProgressModel':
+    case 'FloatProgressModel':
+      return progressView();
+    case 'IntSliderModel':
+    case 'FloatSliderModel':
+      return sliderView(manager, modelId);
+    case 'CheckboxModel':
+      return checkboxView(manager, modelId);
+    case 'TextModel':
+      return textView(manager, modelId);
+    case 'ButtonModel':
+      return buttonView(manager, modelId);
+    case 'HTMLModel':
+    case 'HTMLMathModel':
+      return htmlView();
+    case 'LabelModel':
+      return labelView();
+    case 'HBoxModel':
+      return boxView(manager, 'row');
+    case 'BoxModel':
+    case 'VBoxModel':
+      return boxView(manager, 'column');
+    default:
+      return unsupportedView(modelName);
+  }
+}
+
+/**
+ * Builds the DOM for a widget model and keeps it updated while the model
+ * changes. Returns null when the model is not open in manager, e.g. for
+ * output restored from disk after the kernel that owned it went away.
+ */
+export function renderWidget(
+  manager: CommManager,
+  modelId: string
+): WidgetView | null {
+  const state = manager.getState(modelId);
+  if (!state) return null;
+
+  const view = createView(manager, modelId, text(state._model_name));
+  view.update(state);
+  const unsubscribe = manager.subscribe(modelId, () => {
+    const latest = manager.getState(modelId);
+    if (latest) view.update(latest);
+  });
+  return {
+    ...view,
+    dispose() {
+      unsubscribe();
+      view.dispose?.();
+    },
+  };
+}
+
+/**
+ * Renders every widget placeholder (an element with data-widget-model-id)
+ * inside container. Placeholders whose model is unknown keep their fallback
+ * text. Returns a function that stops the rendered widgets from updating.
+ */
+export function mountWidgets(container: HTMLElement, manager: CommManager) {
+  const views: WidgetView[] = [];
+  const placeholders = container.querySelectorAll<HTMLElement>(
+    '[data-widget-model-id]'
+  );
+  for (const placeholder of placeholders) {
+    const modelId = placeholder.dataset.widgetModelId;
+    if (!modelId) continue;
+    const view = renderWidget(manager, modelId);
+    if (!view) continue;
+    placeholder.replaceChildren(view.element);
+    views.push(view);
+  }
+  return () => {
+    views.forEach((view) => view.dispose?.());
+  };
+}
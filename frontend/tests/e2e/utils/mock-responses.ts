@@ -73,6 +73,9 @@ export const MOCK_PROJECT_SETTINGS_RESPONSE = {
       pythonVenvPath: '',
       customPythonVenvPaths: [],
     },
+    attachments: {
+      keepDuplicates: false,
+    },
   },
 };
 
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/export"
+)
+
+// runCommand runs the command-line subcommand named by args, if any, so the
+// app binary can be scripted without opening a window. It returns the
+// subcommand's exit code and whether one ran; the app starts as usual
+// otherwise.
+func runCommand(args []string) (exitCode int, ran bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+
+	switch args[0] {
+	case "export-site":
+		return runExportSite(args[1:]), true
+	}
+	return 0, false
+}
+
+// runExportSite handles `bytebook export-site [-folder name] <destination>`.
+func runExportSite(args []string) int {
+	flags := flag.NewFlagSet("export-site", flag.ContinueOnError)
+	folder := flags.String("folder", "", "folder to export, relative to notes (default: the whole vault)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: bytebook export-site [-folder name] <destination>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	projectPath, err := config.GetProjectPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	report, err := export.ExportSite(projectPath, *folder, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not export website: %v\n", err)
+		return 1
+	}
+	for _, link := range report.UnresolvedLinks {
+		fmt.Fprintf(os.Stderr, "%s: %s is not part of the export\n", link.Note, link.Link)
+	}
+	fmt.Printf("Exported %d notes and %d attachments to %s\n", report.Notes, report.Attachments, report.Destination)
+	return 0
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+// LinkedFolderJson mounts an external directory, such as a repository's
+// docs/ folder, into the vault as a virtual top-level folder called Name.
+// Read-only folders are watched and indexed but never written to.
+type LinkedFolderJson struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"readOnly"`
+}
+
+// ErrReadOnlyLinkedFolder is returned when a write targets a read-only linked
+// folder.
+var ErrReadOnlyLinkedFolder = errors.New("linked folder is read-only")
+
+// linkedFolderCache holds the linked folders configured in the last project
+// read, keyed by the modification time and size of its settings.json, so the
+// hot paths that resolve note paths do not re-read settings on every call.
+// The folders that passed validation are cached with them and dropped
+// whenever settings.json changes. Validation also depends on the filesystem,
+// e.g. a drive being mounted, so it is redone once it is older than
+// linkedFolderRevalidateAfter. invalid records the last error logged for each
+// folder so it is only logged again when it changes.
+var linkedFolderCache struct {
+	mu          sync.Mutex
+	projectPath string
+	modTime     time.Time
+	size        int64
+	configured  []LinkedFolderJson
+	valid       []LinkedFolderJson
+	validatedAt time.Time
+	invalid     map[string]string
+}
+
+// linkedFolderRevalidateAfter is how long validated linked folders are reused
+// before their directories are checked again.
+var linkedFolderRevalidateAfter = 2 * time.Second
+
+// LinkedFolders returns the valid linked folders configured for projectPath.
+// Invalid entries are logged and left out until they become valid again.
+func LinkedFolders(projectPath string) []LinkedFolderJson {
+	settingsPath := filepath.Join(projectPath, "settings", "settings.json")
+	info, err := os.Stat(settingsPath)
+	if err != nil {
+		return []LinkedFolderJson{}
+	}
+
+	linkedFolderCache.mu.Lock()
+	defer linkedFolderCache.mu.Unlock()
+	if linkedFolderCache.projectPath != projectPath ||
+		!linkedFolderCache.modTime.Equal(info.ModTime()) ||
+		linkedFolderCache.size != info.Size() {
+		var settings ProjectSettingsJson
+		if err := util.ReadJsonFromPath(settingsPath, &settings); err != nil {
+			log.Printf("Error reading linked folders from %s: %v", settingsPath, err)
+			return []LinkedFolderJson{}
+		}
+		linkedFolderCache.projectPath = projectPath
+		linkedFolderCache.modTime = info.ModTime()
+		linkedFolderCache.size = info.Size()
+		linkedFolderCache.configured = settings.LinkedFolders
+		linkedFolderCache.valid = nil
+		linkedFolderCache.invalid = map[string]string{}
+	}
+
+	if linkedFolderCache.valid == nil ||
+		time.Since(linkedFolderCache.validatedAt) >= linkedFolderRevalidateAfter {
+		linkedFolderCache.valid = validateLinkedFolders(projectPath, linkedFolderCache.configured)
+		linkedFolderCache.validatedAt = time.Now()
+	}
+	return append([]LinkedFolderJson{}, linkedFolderCache.valid...)
+}
+
+// validateLinkedFolders returns the configured folders that can be mounted,
+// logging each invalid one once per distinct error. The caller must hold
+// linkedFolderCache.mu.
+func validateLinkedFolders(projectPath string, configured []LinkedFolderJson) []LinkedFolderJson {
+	folders := []LinkedFolderJson{}
+	for _, folder := range configured {
+		if err := ValidateLinkedFolder(projectPath, folder, folders); err != nil {
+			if linkedFolderCache.invalid[folder.Name] != err.Error() {
+				log.Printf("Skipping linked folder %q: %v", folder.Name, err)
+				linkedFolderCache.invalid[folder.Name] = err.Error()
+			}
+			continue
+		}
+		delete(linkedFolderCache.invalid, folder.Name)
+		folder.Path = filepath.Clean(folder.Path)
+		folders = append(folders, folder)
+	}
+	return folders
+}
+
+// ValidateLinkedFolder checks that folder can be mounted next to existing.
+// The name must be a single visible path segment that is not already used by
+// notes/ or another linked folder, and the path must be an absolute directory
+// that does not overlap notes/ or another linked folder.
+func ValidateLinkedFolder(projectPath string, folder LinkedFolderJson, existing []LinkedFolderJson) error {
+	name := strings.TrimSpace(folder.Name)
+	if name == "" || name != folder.Name {
+		return fmt.Errorf("name %q must not be empty or padded with spaces", folder.Name)
+	}
+	if strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("name %q must be a single folder name that does not start with a dot", name)
+	}
+	if !filepath.IsAbs(folder.Path) {
+		return fmt.Errorf("path %q must be absolute", folder.Path)
+	}
+	if !util.IsDirectory(folder.Path) {
+		return fmt.Errorf("path %q is not a directory", folder.Path)
+	}
+
+	notesPath := filepath.Join(projectPath, "notes")
+	if exists, _ := util.FileOrFolderExists(filepath.Join(notesPath, name)); exists {
+		return fmt.Errorf("name %q is already used by a folder in notes", name)
+	}
+	path := filepath.Clean(folder.Path)
+	if pathsOverlap(path, notesPath) {
+		return fmt.Errorf("path %q overlaps the notes folder", folder.Path)
+	}
+	for _, other := range existing {
+		if strings.EqualFold(other.Name, name) {
+			return fmt.Errorf("name %q is already used by another linked folder", name)
+		}
+		if pathsOverlap(path, filepath.Clean(other.Path)) {
+			return fmt.Errorf("path %q overlaps linked folder %q", folder.Path, other.Name)
+		}
+	}
+	return nil
+}
+
+// AddLinkedFolder validates folder against the linked folders already in
+// settings.json and appends it.
+func AddLinkedFolder(projectPath string, folder LinkedFolderJson) error {
+	return updateLinkedFoldersOnDisk(projectPath, func(folders []LinkedFolderJson) ([]LinkedFolderJson, error) {
+		if err := ValidateLinkedFolder(projectPath, folder, folders); err != nil {
+			return nil, err
+		}
+		folder.Path = filepath.Clean(folder.Path)
+		return append(folders, folder), nil
+	})
+}
+
+// RemoveLinkedFolder unmounts the linked folder called name. The external
+// directory itself is left untouched.
+func RemoveLinkedFolder(projectPath, name string) error {
+	return updateLinkedFoldersOnDisk(projectPath, func(folders []LinkedFolderJson) ([]LinkedFolderJson, error) {
+		remaining := make([]LinkedFolderJson, 0, len(folders))
+		for _, folder := range folders {
+			if folder.Name != name {
+				remaining = append(remaining, folder)
+			}
+		}
+		if len(remaining) == len(folders) {
+			return nil, fmt.Errorf("no linked folder is called %q", name)
+		}
+		return remaining, nil
+	})
+}
+
+// updateLinkedFoldersOnDisk reads settings.json, applies transform to
+// LinkedFolders and writes the result back.
+func updateLinkedFoldersOnDisk(
+	projectPath string,
+	transform func([]LinkedFolderJson) ([]LinkedFolderJson, error),
+) error {
+	settingsPath := filepath.Join(projectPath, "settings", "settings.json")
+
+	var cfg ProjectSettingsJson
+	if err := util.ReadJsonFromPath(settingsPath, &cfg); err != nil {
+		return err
+	}
+	if cfg.LinkedFolders == nil {
+		cfg.LinkedFolders = []LinkedFolderJson{}
+	}
+
+	updated, err := transform(cfg.LinkedFolders)
+	if err != nil {
+		return err
+	}
+	cfg.LinkedFolders = updated
+	return util.WriteJsonToPath(settingsPath, cfg)
+}
+
+// ResolveNotesPath turns a path relative to notes/ into an absolute path. A
+// path whose first segment names a linked folder resolves inside that folder;
+// everything else resolves inside notes/. Paths that escape their root are
+// rejected.
+func ResolveNotesPath(projectPath, relPath string) (string, error) {
+	if folder, rest, ok := splitLinkedFolderPath(projectPath, relPath); ok {
+		return util.SafeJoin(folder.Path, rest)
+	}
+	return util.SafeJoin(filepath.Join(projectPath, "notes"), relPath)
+}
+
+// NotesRelativePath is the inverse of ResolveNotesPath: it returns absPath
+// relative to notes/, with files in linked folders under the folder's name.
+// It reports false for paths outside notes/ and every linked folder.
+func NotesRelativePath(projectPath, absPath string) (string, bool) {
+	if rel, ok := relativeInside(filepath.Join(projectPath, "notes"), absPath); ok {
+		return rel, true
+	}
+	for _, folder := range LinkedFolders(projectPath) {
+		if rel, ok := relativeInside(folder.Path, absPath); ok {
+			return filepath.Join(folder.Name, rel), true
+		}
+	}
+	return "", false
+}
+
+// LinkedFolderFor returns the linked folder that relPath, relative to notes/,
+// points into.
+func LinkedFolderFor(projectPath, relPath string) (LinkedFolderJson, bool) {
+	folder, _, ok := splitLinkedFolderPath(projectPath, relPath)
+	return folder, ok
+}
+
+// IsLinkedFolderRoot reports whether relPath is the top-level folder of a
+// linked folder. Linked roots are managed in settings, so they cannot be
+// renamed, moved or deleted like ordinary folders.
+func IsLinkedFolderRoot(projectPath, relPath string) bool {
+	_, rest, ok := splitLinkedFolderPath(projectPath, relPath)
+	return ok && rest == ""
+}
+
+// CheckNotesPathWritable returns ErrReadOnlyLinkedFolder when relPath points
+// into a read-only linked folder.
+func CheckNotesPathWritable(projectPath, relPath string) error {
+	folder, ok := LinkedFolderFor(projectPath, relPath)
+	if ok && folder.ReadOnly {
+		return fmt.Errorf("%w: %s", ErrReadOnlyLinkedFolder, folder.Name)
+	}
+	return nil
+}
+
+// splitLinkedFolderPath splits relPath into the linked folder named by its
+// first segment and the remainder.
+func splitLinkedFolderPath(projectPath, relPath string) (LinkedFolderJson, string, bool) {
+	cleaned := filepath.ToSlash(filepath.Clean(relPath))
+	cleaned = strings.TrimPrefix(cleaned, "/")
+	first, rest, _ := strings.Cut(cleaned, "/")
+	if first == "" || first == "." || first == ".." {
+		return LinkedFolderJson{}, "", false
+	}
+	for _, folder := range LinkedFolders(projectPath) {
+		if folder.Name == first {
+			return folder, filepath.FromSlash(rest), true
+		}
+	}
+	return LinkedFolderJson{}, "", false
+}
+
+// relativeInside returns path relative to root, or false when path is outside
+// root. The root itself is "".
+func relativeInside(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return "", true
+	}
+	return rel, true
+}
+
+// pathsOverlap reports whether a and b are the same directory or one contains
+// the other.
+func pathsOverlap(a, b string) bool {
+	_, aInB := relativeInside(b, a)
+	_, bInA := relativeInside(a, b)
+	return aInB || bInA
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupLinkedFolderProject creates a project with default settings and an
+// external docs directory outside it.
+func setupLinkedFolderProject(t *testing.T) (string, string) {
+	t.Helper()
+	tempDir := t.TempDir()
+	projectPath := filepath.Join(tempDir, "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(projectPath, "notes", "journal"), 0755))
+	_, err := GetProjectSettings(projectPath)
+	require.NoError(t, err)
+
+	docsPath := filepath.Join(tempDir, "repo", "docs")
+	require.NoError(t, os.MkdirAll(docsPath, 0755))
+	return projectPath, docsPath
+}
+
+func TestLinkedFolders(t *testing.T) {
+	t.Run("adds, resolves and removes a linked folder", func(t *testing.T) {
+		projectPath, docsPath := setupLinkedFolderProject(t)
+		err := AddLinkedFolder(projectPath, LinkedFolderJson{Name: "docs", Path: docsPath, ReadOnly: true})
+		require.NoError(t, err)
+
+		assert.Equal(t, []LinkedFolderJson{{Name: "docs", Path: docsPath, ReadOnly: true}}, LinkedFolders(projectPath))
+
+		settings, err := GetProjectSettings(projectPath)
+		require.NoError(t, err)
+		assert.Len(t, settings.LinkedFolders, 1)
+
+		resolved, err := ResolveNotesPath(projectPath, filepath.Join("docs", "guide", "intro.md"))
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(docsPath, "guide", "intro.md"), resolved)
+
+		resolved, err = ResolveNotesPath(projectPath, filepath.Join("journal", "today.md"))
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(projectPath, "notes", "journal", "today.md"), resolved)
+
+		_, err = ResolveNotesPath(projectPath, filepath.Join("docs", "..", "..", "secret.md"))
+		assert.Error(t, err)
+		_, err = ResolveNotesPath(projectPath, filepath.Join("docs", "guide", "..", "..", "..", "secret.md"))
+		assert.Error(t, err)
+
+		rel, ok := NotesRelativePath(projectPath, filepath.Join(docsPath, "guide", "intro.md"))
+		assert.True(t, ok)
+		assert.Equal(t, filepath.Join("docs", "guide", "intro.md"), rel)
+		rel, ok = NotesRelativePath(projectPath, docsPath)
+		assert.True(t, ok)
+		assert.Equal(t, "docs", rel)
+		_, ok = NotesRelativePath(projectPath, filepath.Dir(docsPath))
+		assert.False(t, ok)
+
+		assert.True(t, IsLinkedFolderRoot(projectPath, "docs"))
+		assert.False(t, IsLinkedFolderRoot(projectPath, filepath.Join("docs", "guide")))
+		assert.ErrorIs(t, CheckNotesPathWritable(projectPath, filepath.Join("docs", "intro.md")), ErrReadOnlyLinkedFolder)
+		assert.NoError(t, CheckNotesPathWritable(projectPath, filepath.Join("journal", "today.md")))
+
+		require.NoError(t, RemoveLinkedFolder(projectPath, "docs"))
+		assert.Empty(t, LinkedFolders(projectPath))
+		assert.Error(t, RemoveLinkedFolder(projectPath, "docs"))
+	})
+
+	t.Run("rejects invalid linked folders", func(t *testing.T) {
+		projectPath, docsPath := setupLinkedFolderProject(t)
+		require.NoError(t, AddLinkedFolder(projectPath, LinkedFolderJson{Name: "docs", Path: docsPath}))
+
+		otherPath := filepath.Join(filepath.Dir(docsPath), "other")
+		require.NoError(t, os.MkdirAll(otherPath, 0755))
+
+		invalid := []LinkedFolderJson{
+			{Name: "", Path: otherPath},
+			{Name: ".hidden", Path: otherPath},
+			{Name: "a/b", Path: otherPath},
+			{Name: "journal", Path: otherPath},
+			{Name: "Docs", Path: otherPath},
+			{Name: "other", Path: "relative/path"},
+			{Name: "other", Path: filepath.Join(otherPath, "missing")},
+			{Name: "other", Path: filepath.Join(docsPath, "nested")},
+			{Name: "other", Path: filepath.Join(projectPath, "notes", "journal")},
+			{Name: "other", Path: projectPath},
+		}
+		require.NoError(t, os.MkdirAll(filepath.Join(docsPath, "nested"), 0755))
+		for _, folder := range invalid {
+			assert.Error(t, AddLinkedFolder(projectPath, folder), "%+v", folder)
+		}
+		assert.NoError(t, AddLinkedFolder(projectPath, LinkedFolderJson{Name: "other", Path: otherPath}))
+		assert.Len(t, LinkedFolders(projectPath), 2)
+	})
+
+	t.Run("skips entries while they are not valid", func(t *testing.T) {
+		revalidateAfter := linkedFolderRevalidateAfter
+		linkedFolderRevalidateAfter = 0
+		t.Cleanup(func() { linkedFolderRevalidateAfter = revalidateAfter })
+
+		projectPath, docsPath := setupLinkedFolderProject(t)
+		require.NoError(t, AddLinkedFolder(projectPath, LinkedFolderJson{Name: "docs", Path: docsPath}))
+		assert.Len(t, LinkedFolders(projectPath), 1)
+
+		// Unmount the folder without touching settings.json.
+		require.NoError(t, os.RemoveAll(docsPath))
+		assert.Empty(t, LinkedFolders(projectPath))
+
+		resolved, err := ResolveNotesPath(projectPath, filepath.Join("docs", "intro.md"))
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(projectPath, "notes", "docs", "intro.md"), resolved)
+
+		// Remount it.
+		require.NoError(t, os.MkdirAll(docsPath, 0755))
+		assert.Equal(t, []LinkedFolderJson{{Name: "docs", Path: docsPath}}, LinkedFolders(projectPath))
+	})
+
+	t.Run("reuses validation until settings change", func(t *testing.T) {
+		projectPath, docsPath := setupLinkedFolderProject(t)
+		require.NoError(t, AddLinkedFolder(projectPath, LinkedFolderJson{Name: "docs", Path: docsPath}))
+		assert.Len(t, LinkedFolders(projectPath), 1)
+
+		// The directory check is cached, so removing it is not noticed yet.
+		require.NoError(t, os.RemoveAll(docsPath))
+		assert.Len(t, LinkedFolders(projectPath), 1)
+
+		// Changing settings.json drops the cached validation.
+		otherPath := filepath.Join(filepath.Dir(docsPath), "other")
+		require.NoError(t, os.MkdirAll(otherPath, 0755))
+		require.NoError(t, AddLinkedFolder(projectPath, LinkedFolderJson{Name: "other", Path: otherPath}))
+		assert.Equal(t, []LinkedFolderJson{{Name: "other", Path: otherPath}}, LinkedFolders(projectPath))
+	})
+}
@@ -27,6 +27,27 @@ const DefaultCodeBlockLanguage = "python"
 
 var ValidCodeBlockLanguages = []string{"python", "go", "javascript", "java", "text"}
 
+// Kernel scopes control which notes share a kernel session.
+const (
+	KernelScopeNote    = "note"
+	KernelScopeFolder  = "folder"
+	KernelScopeProject = "project"
+)
+
+var ValidKernelScopes = []string{KernelScopeNote, KernelScopeFolder, KernelScopeProject}
+
+// Kernel eviction policies decide what happens when a language's kernel pool is full.
+const (
+	// KernelEvictionLRU shuts down the least recently used idle kernel.
+	KernelEvictionLRU = "lru"
+	// KernelEvictionDeny refuses to start another kernel.
+	KernelEvictionDeny = "deny"
+)
+
+var ValidKernelEvictionPolicies = []string{KernelEvictionLRU, KernelEvictionDeny}
+
+const DefaultMaxKernelsPerLanguage = 3
+
 var UserHomeDir = os.UserHomeDir
 
 type BackendResponseWithData[T any] struct {
@@ -107,6 +128,10 @@ func CreateProjectDirectories(projectPath string) error {
 		return fmt.Errorf("failed to create per-instance kernels directory: %v", err)
 	}
 
+	if err := os.MkdirAll(filepath.Join(projectPath, "code", ".history"), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create kernel history directory: %v", err)
+	}
+
 	if err := os.MkdirAll(filepath.Join(projectPath, "notes"), os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create project notes directory: %v", err)
 	}
@@ -139,22 +164,61 @@ type AppearanceProjectSettingsJson struct {
 	SidebarVisibility            SidebarVisibilitySettings `json:"sidebarVisibility"`
 }
 
+// KernelLanguageSettings holds the per-language kernel policy. A zero value in
+// any field means "no limit".
+type KernelLanguageSettings struct {
+	// ExecutionTimeoutSeconds is how long a single execute_request may run before
+	// the kernel is interrupted (and restarted if the interrupt is ignored).
+	ExecutionTimeoutSeconds int `json:"executionTimeoutSeconds"`
+	// MemoryLimitMB caps the kernel process's memory. It is enforced through
+	// cgroup v2 on Linux and skipped where cgroups are unavailable.
+	MemoryLimitMB int `json:"memoryLimitMb"`
+	// CPULimitPercent caps the kernel process's CPU usage as a percentage of one core.
+	CPULimitPercent int `json:"cpuLimitPercent"`
+	// Scope is the default kernel scope for the language: one of
+	// ValidKernelScopes. Notes can override it with `kernelScope` frontmatter.
+	Scope string `json:"scope"`
+	// MaxKernels is the pool size for the language. Zero uses DefaultMaxKernelsPerLanguage.
+	MaxKernels int `json:"maxKernels"`
+	// IdleTimeoutMinutes shuts down kernels with no activity for this long.
+	IdleTimeoutMinutes int `json:"idleTimeoutMinutes"`
+	// EvictionPolicy is one of ValidKernelEvictionPolicies.
+	EvictionPolicy string `json:"evictionPolicy"`
+}
+
 type CodeProjectSettingsJson struct {
-	CodeBlockVimMode         bool     `json:"codeBlockVimMode"`
-	CodeBlockFontFamily      string   `json:"codeBlockFontFamily"`
-	CodeBlockFontSize        int      `json:"codeBlockFontSize"`
-	CodeBlockLineWrapping    bool     `json:"codeBlockLineWrapping"`
-	CodeBlockShowLineNumbers bool     `json:"codeBlockShowLineNumbers"`
-	CodeBlockDefaultLanguage string   `json:"codeBlockDefaultLanguage"`
-	PythonVenvPath           string   `json:"pythonVenvPath"`
-	CustomPythonVenvPaths    []string `json:"customPythonVenvPaths"`
+	CodeBlockVimMode         bool                              `json:"codeBlockVimMode"`
+	CodeBlockFontFamily      string                            `json:"codeBlockFontFamily"`
+	CodeBlockFontSize        int                               `json:"codeBlockFontSize"`
+	CodeBlockLineWrapping    bool                              `json:"codeBlockLineWrapping"`
+	CodeBlockShowLineNumbers bool                              `json:"codeBlockShowLineNumbers"`
+	CodeBlockDefaultLanguage string                            `json:"codeBlockDefaultLanguage"`
+	PythonVenvPath           string                            `json:"pythonVenvPath"`
+	CustomPythonVenvPaths    []string                          `json:"customPythonVenvPaths"`
+	KernelSettings           map[string]KernelLanguageSettings `json:"kernelSettings"`
+}
+
+// AttachmentsProjectSettingsJson controls how files added to the vault are
+// stored.
+type AttachmentsProjectSettingsJson struct {
+	// KeepDuplicates copies every added file even when its folder already
+	// holds a file with the same content. By default that file is reused.
+	KeepDuplicates bool `json:"keepDuplicates"`
+}
+
+// KernelSettingsFor returns the kernel settings for a language. Languages with
+// no configured settings get no limits, a per-note scope and the default LRU pool.
+func (c CodeProjectSettingsJson) KernelSettingsFor(language string) KernelLanguageSettings {
+	return normalizeKernelLanguageSettings(c.KernelSettings[language])
 }
 
 type ProjectSettingsJson struct {
-	PinnedNotes []string                      `json:"pinnedNotes"`
-	ProjectPath string                        `json:"projectPath"`
-	Appearance  AppearanceProjectSettingsJson `json:"appearance"`
-	Code        CodeProjectSettingsJson       `json:"code"`
+	PinnedNotes   []string                       `json:"pinnedNotes"`
+	ProjectPath   string                         `json:"projectPath"`
+	Appearance    AppearanceProjectSettingsJson  `json:"appearance"`
+	Code          CodeProjectSettingsJson        `json:"code"`
+	LinkedFolders []LinkedFolderJson             `json:"linkedFolders"`
+	Attachments   AttachmentsProjectSettingsJson `json:"attachments"`
 }
 
 // GetProjectSettings retrieves the project settings from the settings.json file.
@@ -188,6 +252,11 @@ func GetProjectSettings(projectPath string) (ProjectSettingsJson, error) {
 			CodeBlockDefaultLanguage: DefaultCodeBlockLanguage,
 			PythonVenvPath:           "",
 			CustomPythonVenvPaths:    []string{},
+			KernelSettings:           map[string]KernelLanguageSettings{},
+		},
+		LinkedFolders: []LinkedFolderJson{},
+		Attachments: AttachmentsProjectSettingsJson{
+			KeepDuplicates: false,
 		},
 	}
 
@@ -235,11 +304,41 @@ func GetProjectSettings(projectPath string) (ProjectSettingsJson, error) {
 		projectSettings.Code.CodeBlockDefaultLanguage = DefaultCodeBlockLanguage
 	}
 
+	if projectSettings.Code.KernelSettings == nil {
+		projectSettings.Code.KernelSettings = map[string]KernelLanguageSettings{}
+	}
+	for language, kernelSettings := range projectSettings.Code.KernelSettings {
+		projectSettings.Code.KernelSettings[language] = normalizeKernelLanguageSettings(kernelSettings)
+	}
+
+	if projectSettings.LinkedFolders == nil {
+		projectSettings.LinkedFolders = []LinkedFolderJson{}
+	}
+
 	projectSettings = ValidateProjectSettings(projectPath, projectSettings)
 
 	return projectSettings, nil
 }
 
+// normalizeKernelLanguageSettings replaces negative limits with zero ("no
+// limit") and unknown scopes, pool sizes and eviction policies with defaults.
+func normalizeKernelLanguageSettings(s KernelLanguageSettings) KernelLanguageSettings {
+	s.ExecutionTimeoutSeconds = max(s.ExecutionTimeoutSeconds, 0)
+	s.MemoryLimitMB = max(s.MemoryLimitMB, 0)
+	s.CPULimitPercent = max(s.CPULimitPercent, 0)
+	s.IdleTimeoutMinutes = max(s.IdleTimeoutMinutes, 0)
+	if s.MaxKernels <= 0 {
+		s.MaxKernels = DefaultMaxKernelsPerLanguage
+	}
+	if !slices.Contains(ValidKernelScopes, s.Scope) {
+		s.Scope = KernelScopeNote
+	}
+	if !slices.Contains(ValidKernelEvictionPolicies, s.EvictionPolicy) {
+		s.EvictionPolicy = KernelEvictionLRU
+	}
+	return s
+}
+
 // ValidateProjectSettings validates pinned notes and updates the accent color
 // in the project settings without writing to disk.
 func ValidateProjectSettings(
@@ -261,12 +360,15 @@ func ValidateProjectSettings(
 
 /*
 GetValidPinned returns a list of valid pinned paths.
-It checks if the pinned path exists in the notes folder and returns all of the pinned paths that exist.
+It checks if the pinned path exists in the notes folder or a linked folder and returns all of the pinned paths that exist.
 */
 func GetValidPinned(projectPath string, projectSettings ProjectSettingsJson) []string {
 	validPinnedNotes := []string{}
 	for _, pinnedNote := range projectSettings.PinnedNotes {
-		pathToPinnedNote := filepath.Join(projectPath, "notes", pinnedNote)
+		pathToPinnedNote, err := ResolveNotesPath(projectPath, pinnedNote)
+		if err != nil {
+			continue
+		}
 		pathExists, _ := util.FileOrFolderExists(pathToPinnedNote)
 		if pathExists {
 			validPinnedNotes = append(validPinnedNotes, pinnedNote)
@@ -275,6 +377,18 @@ func GetValidPinned(projectPath string, projectSettings ProjectSettingsJson) []s
 	return validPinnedNotes
 }
 
+// KeepDuplicateAttachments reports whether the project stores another copy of
+// an added file whose content is already in the destination folder. Unlike
+// GetProjectSettings it never creates settings.json; a missing or unreadable
+// file means the default, which reuses duplicates.
+func KeepDuplicateAttachments(projectPath string) bool {
+	var cfg ProjectSettingsJson
+	if err := util.ReadJsonFromPath(filepath.Join(projectPath, "settings", "settings.json"), &cfg); err != nil {
+		return false
+	}
+	return cfg.Attachments.KeepDuplicates
+}
+
 // updatePinnedNotesOnDisk reads settings.json, applies transform to PinnedNotes,
 // and writes back only if the resulting slice differs from the original. It is
 // a no-op (and returns nil) when settings.json does not exist.
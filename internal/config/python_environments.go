@@ -0,0 +1,215 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+// Python environment kinds reported in PythonEnvironment.Kind.
+const (
+	PythonEnvironmentVenv        = "venv"
+	PythonEnvironmentConda       = "conda"
+	PythonEnvironmentUV          = "uv"
+	PythonEnvironmentPoetry      = "poetry"
+	PythonEnvironmentPyenv       = "pyenv"
+	PythonEnvironmentInterpreter = "interpreter"
+)
+
+// PythonEnvironment is a Python installation a kernel can be launched with.
+// Path is the environment's root directory, the value stored in
+// pythonVenvPath and in `pythonEnvironment` frontmatter.
+type PythonEnvironment struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// condaInstallDirs are the home-relative directories conda, mamba and their
+// installers use for the base environment.
+var condaInstallDirs = []string{
+	"miniconda3",
+	"anaconda3",
+	"miniforge3",
+	"mambaforge",
+	"micromamba",
+	filepath.Join("opt", "miniconda3"),
+	filepath.Join("opt", "anaconda3"),
+}
+
+// FindPythonEnvironments lists the Python environments bytebook can run
+// kernels in: venv, uv and Poetry environments in the project's code folder,
+// conda environments, Poetry's shared environments, pyenv versions, and the
+// user's custom paths. Directories without a Python interpreter are skipped.
+func FindPythonEnvironments(projectPath string, customPaths []string) ([]PythonEnvironment, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = ""
+	}
+	return findPythonEnvironments(projectPath, customPaths, home, os.Getenv)
+}
+
+func findPythonEnvironments(projectPath string, customPaths []string, home string, getenv func(string) string) ([]PythonEnvironment, error) {
+	found := map[string]PythonEnvironment{}
+	add := func(path, kind, name string) {
+		if _, exists := found[path]; exists || !util.IsPythonEnvironment(path) {
+			return
+		}
+		if kind == "" {
+			kind = PythonEnvironmentKind(path)
+		}
+		if name == "" {
+			name = filepath.Base(path)
+		}
+		found[path] = PythonEnvironment{Path: path, Kind: kind, Name: name}
+	}
+
+	pathToCodeFolder := filepath.Join(projectPath, "code")
+	entries, err := os.ReadDir(pathToCodeFolder)
+	if err != nil && !os.IsNotExist(err) {
+		return []PythonEnvironment{}, fmt.Errorf("couldn't read files in %s", pathToCodeFolder)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pathToEntry := filepath.Join(pathToCodeFolder, entry.Name())
+		add(pathToEntry, "", "")
+		// uv and Poetry projects keep their environment in .venv.
+		add(filepath.Join(pathToEntry, ".venv"), "", entry.Name())
+	}
+
+	if home != "" {
+		for _, path := range condaEnvironmentPaths(home) {
+			name := filepath.Base(path)
+			if filepath.Base(filepath.Dir(path)) != "envs" {
+				name = "base"
+			}
+			add(path, PythonEnvironmentConda, name)
+		}
+		for _, path := range subdirectories(poetryVirtualenvsDir(home, getenv)) {
+			add(path, PythonEnvironmentPoetry, "")
+		}
+		pyenvRoot := getenv("PYENV_ROOT")
+		if pyenvRoot == "" {
+			pyenvRoot = filepath.Join(home, ".pyenv")
+		}
+		for _, path := range subdirectories(filepath.Join(pyenvRoot, "versions")) {
+			add(path, PythonEnvironmentPyenv, "")
+		}
+	}
+
+	for _, path := range customPaths {
+		add(path, "", "")
+	}
+
+	environments := make([]PythonEnvironment, 0, len(found))
+	for _, environment := range found {
+		environments = append(environments, environment)
+	}
+	sort.Slice(environments, func(a, b int) bool {
+		return environments[a].Path < environments[b].Path
+	})
+	return environments, nil
+}
+
+// PythonEnvironmentKind classifies the environment at dir. Virtual
+// environments made by uv or for a Poetry project are reported as such.
+func PythonEnvironmentKind(dir string) string {
+	switch {
+	case util.IsCondaEnv(dir):
+		return PythonEnvironmentConda
+	case !util.IsVirtualEnv(dir):
+		return PythonEnvironmentInterpreter
+	case pyvenvCreatedByUV(dir):
+		return PythonEnvironmentUV
+	case strings.Contains(filepath.ToSlash(dir), "pypoetry/virtualenvs/"):
+		return PythonEnvironmentPoetry
+	}
+	projectDir := filepath.Dir(dir)
+	if exists, _ := util.FileOrFolderExists(filepath.Join(projectDir, "poetry.lock")); exists {
+		return PythonEnvironmentPoetry
+	}
+	if exists, _ := util.FileOrFolderExists(filepath.Join(projectDir, "uv.lock")); exists {
+		return PythonEnvironmentUV
+	}
+	return PythonEnvironmentVenv
+}
+
+// pyvenvCreatedByUV reports whether pyvenv.cfg has the "uv = <version>" key
+// uv writes.
+func pyvenvCreatedByUV(dir string) bool {
+	file, err := os.Open(filepath.Join(dir, "pyvenv.cfg"))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, _, found := strings.Cut(scanner.Text(), "=")
+		if found && strings.TrimSpace(key) == "uv" {
+			return true
+		}
+	}
+	return false
+}
+
+// condaEnvironmentPaths returns the environments conda has recorded in
+// ~/.conda/environments.txt plus the base and envs/ of well-known installs.
+func condaEnvironmentPaths(home string) []string {
+	paths := []string{}
+	if data, err := os.ReadFile(filepath.Join(home, ".conda", "environments.txt")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				paths = append(paths, line)
+			}
+		}
+	}
+	for _, dir := range condaInstallDirs {
+		base := filepath.Join(home, dir)
+		if !util.IsCondaEnv(base) {
+			continue
+		}
+		paths = append(paths, base)
+		paths = append(paths, subdirectories(filepath.Join(base, "envs"))...)
+	}
+	return paths
+}
+
+// poetryVirtualenvsDir returns where Poetry creates environments for projects
+// that do not keep them in-project.
+func poetryVirtualenvsDir(home string, getenv func(string) string) string {
+	if dir := getenv("POETRY_VIRTUALENVS_PATH"); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "Caches", "pypoetry", "virtualenvs")
+	}
+	cacheDir := getenv("XDG_CACHE_HOME")
+	if cacheDir == "" {
+		cacheDir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheDir, "pypoetry", "virtualenvs")
+}
+
+// subdirectories returns the directories directly inside dir, or none if dir
+// cannot be read.
+func subdirectories(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []string{}
+	}
+	paths := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths
+}
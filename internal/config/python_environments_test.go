@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// makePythonEnvironment creates a directory with a bin/python3 interpreter and
+// the given marker files, e.g. "pyvenv.cfg" or "conda-meta/".
+func makePythonEnvironment(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "bin"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bin", "python3"), []byte{}, 0755))
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if name[len(name)-1] == '/' {
+			assert.NoError(t, os.MkdirAll(path, 0755))
+			continue
+		}
+		assert.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+}
+
+func TestFindPythonEnvironments(t *testing.T) {
+	t.Run("Finds environments from every supported tool", func(t *testing.T) {
+		projectDir := t.TempDir()
+		home := t.TempDir()
+		codeDir := filepath.Join(projectDir, "code")
+
+		venv := filepath.Join(codeDir, "venv")
+		makePythonEnvironment(t, venv, map[string]string{"pyvenv.cfg": "home = /usr/bin"})
+		uvVenv := filepath.Join(codeDir, "fast")
+		makePythonEnvironment(t, uvVenv, map[string]string{"pyvenv.cfg": "home = /usr/bin\nuv = 0.5.0"})
+		poetryProject := filepath.Join(codeDir, "analysis")
+		makePythonEnvironment(t, filepath.Join(poetryProject, ".venv"), map[string]string{"pyvenv.cfg": "home = /usr/bin"})
+		assert.NoError(t, os.WriteFile(filepath.Join(poetryProject, "poetry.lock"), []byte{}, 0644))
+		assert.NoError(t, os.MkdirAll(filepath.Join(codeDir, "not-an-env"), 0755))
+
+		condaBase := filepath.Join(home, "miniconda3")
+		makePythonEnvironment(t, condaBase, map[string]string{"conda-meta/": ""})
+		condaEnv := filepath.Join(condaBase, "envs", "science")
+		makePythonEnvironment(t, condaEnv, map[string]string{"conda-meta/": ""})
+		pyenvVersion := filepath.Join(home, ".pyenv", "versions", "3.12.1")
+		makePythonEnvironment(t, pyenvVersion, nil)
+		poetryCache := filepath.Join(home, "poetry-envs", "analysis-py3.12")
+		makePythonEnvironment(t, poetryCache, map[string]string{"pyvenv.cfg": "home = /usr/bin"})
+
+		getenv := func(key string) string {
+			if key == "POETRY_VIRTUALENVS_PATH" {
+				return filepath.Join(home, "poetry-envs")
+			}
+			return ""
+		}
+		environments, err := findPythonEnvironments(projectDir, []string{venv}, home, getenv)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []PythonEnvironment{
+			{Path: venv, Kind: PythonEnvironmentVenv, Name: "venv"},
+			{Path: uvVenv, Kind: PythonEnvironmentUV, Name: "fast"},
+			{Path: filepath.Join(poetryProject, ".venv"), Kind: PythonEnvironmentPoetry, Name: "analysis"},
+			{Path: condaBase, Kind: PythonEnvironmentConda, Name: "base"},
+			{Path: condaEnv, Kind: PythonEnvironmentConda, Name: "science"},
+			{Path: pyenvVersion, Kind: PythonEnvironmentPyenv, Name: "3.12.1"},
+			{Path: poetryCache, Kind: PythonEnvironmentPoetry, Name: "analysis-py3.12"},
+		}, environments)
+	})
+
+	t.Run("Skips custom paths without an interpreter", func(t *testing.T) {
+		projectDir := t.TempDir()
+		custom := filepath.Join(t.TempDir(), "custom")
+		makePythonEnvironment(t, custom, nil)
+
+		environments, err := findPythonEnvironments(projectDir, []string{custom, t.TempDir()}, "", os.Getenv)
+		assert.NoError(t, err)
+		assert.Equal(t, []PythonEnvironment{
+			{Path: custom, Kind: PythonEnvironmentInterpreter, Name: "custom"},
+		}, environments)
+	})
+}
@@ -54,6 +54,16 @@ func ListenToEvents(params EventParams) {
 		handleFolderCreateEvent(params, event)
 	})
 
+	params.App.Event.On(util.EventIgnoreRulesUpdate, func(event *application.CustomEvent) {
+		log.Printf("%s: %+v", util.EventIgnoreRulesUpdate, event.Data)
+		handleIgnoreRulesUpdateEvent(params, event)
+	})
+
+	// Settings Events
+	params.App.Event.On(util.EventSettingsUpdate, func(event *application.CustomEvent) {
+		handleSettingsUpdateEvent(params)
+	})
+
 	// Tag Events
 	params.App.Event.On(util.EventTagsUpdate, func(event *application.CustomEvent) {
 		log.Printf("%s: %+v", util.EventTagsUpdate, event.Data)
@@ -78,7 +78,11 @@ func addFoldersToIndex(params EventParams, data []util.FolderCreateEventData) {
 		}
 
 		// Index files within the folder
-		pathOnDisk := filepath.Join(params.ProjectPath, "notes", folderPath)
+		pathOnDisk, err := config.ResolveNotesPath(params.ProjectPath, folderPath)
+		if err != nil {
+			log.Printf("Error resolving folder %s: %v", folderPath, err)
+			continue
+		}
 		updatedBatch, err := search.IndexFilesInFolderWithBatch(pathOnDisk, folderPath, idx, batch)
 		if err != nil {
 			log.Printf("Error indexing files for folder %s: %v", folderPath, err)
@@ -212,10 +216,14 @@ func renameFoldersInIndex(params EventParams, data []util.FolderRenameEventData)
 		deleteRenameFolderFromIndexLocked(idx, oldFolderPath)
 
 		// Step 2: Re-index all files in the new folder
-		newFolderPathOnDisk := filepath.Join(params.ProjectPath, "notes", newFolderPath)
+		newFolderPathOnDisk, err := config.ResolveNotesPath(params.ProjectPath, newFolderPath)
+		if err != nil {
+			log.Printf("Error resolving folder %s: %v", newFolderPath, err)
+			continue
+		}
 		batch := idx.NewBatch()
 
-		batch, err := search.IndexFilesInFolderWithBatch(newFolderPathOnDisk, newFolderPath, idx, batch)
+		batch, err = search.IndexFilesInFolderWithBatch(newFolderPathOnDisk, newFolderPath, idx, batch)
 		if err != nil {
 			log.Printf("Error re-indexing folder %s: %v", newFolderPath, err)
 			continue
@@ -270,7 +278,11 @@ func updateMarkdownFilesForFolderRename(projectPath, oldFolderPath, newFolderPat
 	workerGroup := new(errgroup.Group)
 	workerGroup.SetLimit(util.WORKER_COUNT)
 
-	newFolderPathOnDisk := filepath.Join(projectPath, "notes", newFolderPath)
+	newFolderPathOnDisk, err := config.ResolveNotesPath(projectPath, newFolderPath)
+	if err != nil {
+		log.Printf("Error resolving folder %s: %v", newFolderPath, err)
+		return
+	}
 
 	// When the note folder is renamed, all notes need path updates
 	files, err := os.ReadDir(newFolderPathOnDisk)
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"log"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/notes"
+	"github.com/etesam913/bytebook/internal/search"
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// handleIgnoreRulesUpdateEvent reconciles the index with changed
+// .bytebookignore rules: documents the new rules exclude are removed, and the
+// folder is re-imported so files they no longer exclude are indexed and
+// watched again.
+func handleIgnoreRulesUpdateEvent(params EventParams, event *application.CustomEvent) {
+	data, ok := event.Data.([]util.IgnoreRulesUpdateEventData)
+	if !ok {
+		log.Println("Ignore rules update event data is not []util.IgnoreRulesUpdateEventData")
+		return
+	}
+
+	deleteIgnoredFromIndex(params, data)
+
+	if params.ImportCoordinator != nil {
+		for _, item := range data {
+			params.ImportCoordinator.EnqueueFolderImport(item.FolderPath)
+		}
+	}
+}
+
+// deleteIgnoredFromIndex removes every indexed document under the updated
+// folders that the current ignore rules exclude. An empty FolderPath is the
+// notes root.
+func deleteIgnoredFromIndex(params EventParams, data []util.IgnoreRulesUpdateEventData) {
+	idx := params.Index.RLock()
+	defer params.Index.RUnlock()
+	batch := idx.NewBatch()
+
+	ignore := notes.NewIgnoreMatcher(params.ProjectPath)
+
+	for _, eventData := range data {
+		var folderQuery query.Query = bleve.NewMatchAllQuery()
+		if eventData.FolderPath != "" {
+			folderQuery = createFolderAndDescendantsQuery(eventData.FolderPath)
+		}
+
+		searchRequest := bleve.NewSearchRequest(folderQuery)
+		searchRequest.Size = search.MaxDeleteSearchResults
+
+		searchResult, err := idx.Search(searchRequest)
+		if err != nil {
+			log.Printf("Error searching for documents in folder %s: %v", eventData.FolderPath, err)
+			continue
+		}
+
+		for _, hit := range searchResult.Hits {
+			// Document IDs are note paths relative to notes/.
+			docPath, err := config.ResolveNotesPath(params.ProjectPath, hit.ID)
+			if err != nil || !ignore.Match(docPath, false) {
+				continue
+			}
+			batch.Delete(hit.ID)
+			if batch.Size() >= search.DefaultBatchSize {
+				if err := idx.Batch(batch); err != nil {
+					log.Printf("Error flushing delete batch for folder %s: %v", eventData.FolderPath, err)
+				}
+				batch = idx.NewBatch()
+			}
+		}
+	}
+
+	if batch.Size() > 0 {
+		if err := idx.Batch(batch); err != nil {
+			log.Printf("Error batching delete operations: %v", err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/etesam913/bytebook/internal/notes"
+	"github.com/etesam913/bytebook/internal/search"
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteIgnoredFromIndex(t *testing.T) {
+	t.Run("removes documents newly excluded by the root ignore file", func(t *testing.T) {
+		params := createTestParams(t)
+		createMarkdownNoteInFolder(t, params.ProjectPath, "drafts", "one.md", "# one")
+		createMarkdownNoteInFolder(t, params.ProjectPath, "", "run.log", "output")
+		createMarkdownNoteInFolder(t, params.ProjectPath, "folder", "keep.md", "# keep")
+		require.NoError(t, search.IndexAllFiles(params.ProjectPath, rawIndex(params)))
+
+		ignorePath := filepath.Join(params.ProjectPath, "notes", notes.IgnoreFileName)
+		require.NoError(t, os.WriteFile(ignorePath, []byte("drafts/\n*.log\n"), 0644))
+
+		deleteIgnoredFromIndex(params, []util.IgnoreRulesUpdateEventData{{FolderPath: ""}})
+
+		for _, docID := range []string{filepath.Join("drafts", "one.md"), "run.log"} {
+			doc, err := rawIndex(params).Document(docID)
+			assert.NoError(t, err)
+			assert.Nil(t, doc, docID)
+		}
+		doc, err := rawIndex(params).Document(filepath.Join("folder", "keep.md"))
+		assert.NoError(t, err)
+		assert.NotNil(t, doc)
+	})
+
+	t.Run("only searches below the updated folder", func(t *testing.T) {
+		params := createTestParams(t)
+		createMarkdownNoteInFolder(t, params.ProjectPath, "project", "secret.md", "# secret")
+		createMarkdownNoteInFolder(t, params.ProjectPath, "other", "secret.md", "# secret")
+		require.NoError(t, search.IndexAllFiles(params.ProjectPath, rawIndex(params)))
+
+		ignorePath := filepath.Join(params.ProjectPath, "notes", "project", notes.IgnoreFileName)
+		require.NoError(t, os.WriteFile(ignorePath, []byte("secret.md\n"), 0644))
+
+		deleteIgnoredFromIndex(params, []util.IgnoreRulesUpdateEventData{{FolderPath: "project"}})
+
+		doc, err := rawIndex(params).Document(filepath.Join("project", "secret.md"))
+		assert.NoError(t, err)
+		assert.Nil(t, doc)
+		doc, err = rawIndex(params).Document(filepath.Join("other", "secret.md"))
+		assert.NoError(t, err)
+		assert.NotNil(t, doc)
+	})
+}
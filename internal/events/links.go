@@ -1,48 +1,33 @@
 package events
 
 import (
-	"fmt"
 	"log"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/blevesearch/bleve/v2"
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/search"
 	"github.com/etesam913/bytebook/internal/util"
 	"golang.org/x/sync/errgroup"
 )
 
-// EncodeLinkSegment percent-encodes a path segment using the same rules as the
-// frontend's encodeLinkUrl (JS encodeURIComponent + ( )-escaping) so generated
-// URL paths match the encoded form stored in markdown and indexed in bleve.
-func EncodeLinkSegment(s string) string {
-	var b strings.Builder
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		switch {
-		case (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'),
-			c == '-', c == '_', c == '.', c == '~', c == '!', c == '*', c == '\'':
-			b.WriteByte(c)
-		default:
-			fmt.Fprintf(&b, "%%%02X", c)
-		}
-	}
-	return b.String()
+// linkReplacement rewrites links to oldURLPath so they point at newURLPath.
+type linkReplacement struct {
+	oldURLPath string
+	newURLPath string
 }
 
 // replaceLocalLinksInNotes finds all notes that contain internal links to
 // renamed files and updates those links to reflect the new paths.
 // It queries the bleve index to efficiently locate notes with matching links,
 // then uses bounded parallelism to read/modify/write the affected files.
-func replaceLocalLinksInNotes(params EventParams, data []map[string]string) {
+// Notes in read-only linked folders are never written; their links are
+// returned as unchanged.
+func replaceLocalLinksInNotes(params EventParams, data []map[string]string) []util.UnchangedLinkEventData {
 	if params.Index == nil {
-		return
-	}
-
-	type linkReplacement struct {
-		oldURLPath string
-		newURLPath string
+		return nil
 	}
 
 	var replacements []linkReplacement
@@ -65,8 +50,8 @@ func replaceLocalLinksInNotes(params EventParams, data []map[string]string) {
 			continue
 		}
 
-		oldURLPath := "/notes/" + EncodeLinkSegment(oldFolder) + "/" + EncodeLinkSegment(oldNoteName)
-		newURLPath := "/notes/" + EncodeLinkSegment(newFolder) + "/" + EncodeLinkSegment(newNoteName)
+		oldURLPath := "/notes/" + util.EncodeLinkSegment(oldFolder) + "/" + util.EncodeLinkSegment(oldNoteName)
+		newURLPath := "/notes/" + util.EncodeLinkSegment(newFolder) + "/" + util.EncodeLinkSegment(newNoteName)
 
 		if oldURLPath != newURLPath {
 			replacements = append(replacements, linkReplacement{oldURLPath, newURLPath})
@@ -74,7 +59,7 @@ func replaceLocalLinksInNotes(params EventParams, data []map[string]string) {
 	}
 
 	if len(replacements) == 0 {
-		return
+		return nil
 	}
 
 	// Map from relativePath -> list of replacements that apply to that note
@@ -90,14 +75,31 @@ func replaceLocalLinksInNotes(params EventParams, data []map[string]string) {
 	params.Index.RUnlock()
 
 	if len(noteReplacements) == 0 {
-		return
+		return nil
 	}
 
 	workerGroup := new(errgroup.Group)
 	workerGroup.SetLimit(util.WORKER_COUNT)
 
+	var unchanged []util.UnchangedLinkEventData
 	for relPath, repls := range noteReplacements {
-		absPath := filepath.Join(params.ProjectPath, "notes", relPath)
+		if err := config.CheckNotesPathWritable(params.ProjectPath, relPath); err != nil {
+			for _, repl := range repls {
+				log.Printf("Leaving link to %s unchanged in %s: %v", repl.oldURLPath, relPath, err)
+				unchanged = append(unchanged, util.UnchangedLinkEventData{
+					NotePath:   relPath,
+					OldURLPath: repl.oldURLPath,
+					NewURLPath: repl.newURLPath,
+				})
+			}
+			continue
+		}
+
+		absPath, err := config.ResolveNotesPath(params.ProjectPath, relPath)
+		if err != nil {
+			log.Printf("Error resolving note %s for link replacement: %v", relPath, err)
+			continue
+		}
 
 		workerGroup.Go(func() error {
 			content, err := os.ReadFile(absPath)
@@ -128,6 +130,14 @@ func replaceLocalLinksInNotes(params EventParams, data []map[string]string) {
 	if err := workerGroup.Wait(); err != nil {
 		log.Printf("Error during link replacement: %v", err)
 	}
+
+	sort.Slice(unchanged, func(i, j int) bool {
+		if unchanged[i].NotePath != unchanged[j].NotePath {
+			return unchanged[i].NotePath < unchanged[j].NotePath
+		}
+		return unchanged[i].OldURLPath < unchanged[j].OldURLPath
+	})
+	return unchanged
 }
 
 // FindNotesWithLink queries the bleve index for markdown notes whose links field
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -140,6 +142,52 @@ func TestReplaceLocalLinksInNotes(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, originalContent, string(content))
 	})
+
+	t.Run("leaves notes in read-only linked folders unchanged", func(t *testing.T) {
+		params := createTestParams(t)
+		require.NoError(t, os.MkdirAll(filepath.Join(params.ProjectPath, "settings"), 0755))
+		require.NoError(t, util.WriteJsonToPath(
+			filepath.Join(params.ProjectPath, "settings", "settings.json"),
+			config.ProjectSettingsJson{},
+		))
+		externalDir := t.TempDir()
+		require.NoError(t, config.AddLinkedFolder(params.ProjectPath, config.LinkedFolderJson{
+			Name:     "docs",
+			Path:     externalDir,
+			ReadOnly: true,
+		}))
+
+		createMarkdownNoteInFolder(t, params.ProjectPath, "folder1", "target.md", "# Target")
+		linkingContent := "# Docs\nSee [target](/notes/folder1/target.md)."
+		require.NoError(t, os.WriteFile(filepath.Join(externalDir, "readme.md"), []byte(linkingContent), 0644))
+		localContent := "# Local\nSee [target](/notes/folder1/target.md)."
+		createMarkdownNoteInFolder(t, params.ProjectPath, "folder1", "local.md", localContent)
+
+		addCreatedNotesToIndex(params, []map[string]string{
+			{"folder": "folder1", "note": "target.md"},
+			{"folder": "folder1", "note": "local.md"},
+			{"folder": "docs", "note": "readme.md"},
+		})
+
+		unchanged := replaceLocalLinksInNotes(params, []map[string]string{{
+			"oldFolder": "folder1",
+			"oldNote":   "target.md",
+			"newFolder": "folder1",
+			"newNote":   "renamed.md",
+		}})
+
+		assert.Equal(t, []util.UnchangedLinkEventData{{
+			NotePath:   "docs/readme.md",
+			OldURLPath: "/notes/folder1/target.md",
+			NewURLPath: "/notes/folder1/renamed.md",
+		}}, unchanged)
+		content, err := os.ReadFile(filepath.Join(externalDir, "readme.md"))
+		require.NoError(t, err)
+		assert.Equal(t, linkingContent, string(content))
+		content, err = os.ReadFile(filepath.Join(params.ProjectPath, "notes", "folder1", "local.md"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "/notes/folder1/renamed.md")
+	})
 }
 
 func TestFindNotesWithLink(t *testing.T) {
@@ -170,7 +218,7 @@ func TestFindNotesWithLink(t *testing.T) {
 
 		hits := FindNotesWithLink(
 			rawIndex(params),
-			"/notes/"+EncodeLinkSegment("My Folder")+"/"+EncodeLinkSegment("Target Note.md"),
+			"/notes/"+util.EncodeLinkSegment("My Folder")+"/"+util.EncodeLinkSegment("Target Note.md"),
 			1,
 		)
 
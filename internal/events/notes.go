@@ -74,10 +74,14 @@ func addCreatedNotesToIndex(params EventParams, data []map[string]string) {
 		}
 
 		notePath := filepath.Join(folder, noteName)
-		filePath := filepath.Join(params.ProjectPath, "notes", notePath)
+		filePath, err := config.ResolveNotesPath(params.ProjectPath, notePath)
+		if err != nil {
+			log.Printf("Error resolving note %s: %v", notePath, err)
+			continue
+		}
 
 		// Retry logic to handle race condition where file might not be immediately available
-		err := util.RetryWithExponentialBackoff(
+		err = util.RetryWithExponentialBackoff(
 			func() error {
 				if filepath.Ext(noteName) == ".md" {
 					_, err := search.AddMarkdownNoteToBatch(
@@ -125,6 +129,7 @@ func addCreatedNotesToIndex(params EventParams, data []map[string]string) {
 // handleFileRenameEvent handles the event when a file is renamed.
 // It extracts the rename data from the event, updates the search index,
 // and replaces local links in other notes that reference the renamed file.
+// Links that could not be replaced are reported to the frontend.
 func handleFileRenameEvent(params EventParams, event *application.CustomEvent) {
 	data, ok := event.Data.([]util.FileRenameEventData)
 	if !ok {
@@ -141,7 +146,13 @@ func handleFileRenameEvent(params EventParams, event *application.CustomEvent) {
 	}
 
 	renameFilesInIndex(params, converted)
-	replaceLocalLinksInNotes(params, converted)
+	unchanged := replaceLocalLinksInNotes(params, converted)
+	if len(unchanged) > 0 && params.App != nil {
+		params.App.Event.EmitEvent(&application.CustomEvent{
+			Name: util.EventLinksUnchanged,
+			Data: unchanged,
+		})
+	}
 }
 
 // renameFilesInIndex updates the search index to reflect renamed files.
@@ -179,8 +190,16 @@ func renameFilesInIndex(params EventParams, data []map[string]string) {
 
 		batch.Delete(oldNotePath)
 
-		oldFilePath := filepath.Join(params.ProjectPath, "notes", oldNotePath)
-		newFilePath := filepath.Join(params.ProjectPath, "notes", newNotePath)
+		oldFilePath, err := config.ResolveNotesPath(params.ProjectPath, oldNotePath)
+		if err != nil {
+			log.Printf("Error resolving note %s: %v", oldNotePath, err)
+			continue
+		}
+		newFilePath, err := config.ResolveNotesPath(params.ProjectPath, newNotePath)
+		if err != nil {
+			log.Printf("Error resolving note %s: %v", newNotePath, err)
+			continue
+		}
 		if err := sidecar.Move(oldFilePath, newFilePath); err != nil {
 			log.Printf("Error moving sidecar from %s to %s: %v", oldFilePath, newFilePath, err)
 		}
@@ -331,9 +350,13 @@ func updateNotesInIndex(params EventParams, data []map[string]string) {
 
 		// The file watcher already read the note when it emitted the event, so
 		// prefer the markdown from the payload and only fall back to disk.
+		noteFilePath, err := config.ResolveNotesPath(params.ProjectPath, notePath)
+		if err != nil {
+			log.Printf("Error resolving note %s: %v", notePath, err)
+			continue
+		}
 		markdown, ok := note["markdown"]
 		if !ok {
-			noteFilePath := filepath.Join(params.ProjectPath, "notes", notePath)
 			content, err := os.ReadFile(noteFilePath)
 			if err != nil {
 				log.Printf("Error reading note file %s: %v", noteFilePath, err)
@@ -347,8 +370,9 @@ func updateNotesInIndex(params EventParams, data []map[string]string) {
 			folder,
 			noteName,
 		)
+		bleveMarkdownDocument.ModTime = search.FileModTime(noteFilePath)
 
-		err := idx.Index(notePath, bleveMarkdownDocument)
+		err = idx.Index(notePath, bleveMarkdownDocument)
 		if err != nil {
 			log.Printf("Error indexing note %s: %v", notePath, err)
 		}
@@ -0,0 +1,10 @@
+package events
+
+// handleSettingsUpdateEvent brings watches and the search index in line with
+// the linked folders after settings.json changes.
+func handleSettingsUpdateEvent(params EventParams) {
+	if params.ImportCoordinator == nil {
+		return
+	}
+	params.ImportCoordinator.SyncLinkedFolders()
+}
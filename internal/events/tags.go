@@ -4,6 +4,7 @@ import (
 	"log"
 	"path/filepath"
 
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/search"
 	"github.com/etesam913/bytebook/internal/util"
 	"github.com/wailsapp/wails/v3/pkg/application"
@@ -36,7 +37,11 @@ func reIndexNotesWithUpdatedTags(
 	batch := idx.NewBatch()
 
 	for _, folderAndNoteName := range folderAndNoteNames {
-		filePath := filepath.Join(params.ProjectPath, "notes", folderAndNoteName)
+		filePath, err := config.ResolveNotesPath(params.ProjectPath, folderAndNoteName)
+		if err != nil {
+			log.Printf("Error resolving note %s: %v", folderAndNoteName, err)
+			continue
+		}
 
 		// Extract folder and filename from the path
 		folder := filepath.Dir(folderAndNoteName)
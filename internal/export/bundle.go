@@ -0,0 +1,126 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/etesam913/bytebook/internal/notes"
+)
+
+var (
+	// markdownLinkRegex matches markdown links and images, capturing the
+	// leading "!", the text and the destination.
+	markdownLinkRegex = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	// blankLineRegex matches the blank lines that would end an HTML block in
+	// markdown.
+	blankLineRegex = regexp.MustCompile(`\n[ \t]*\n`)
+)
+
+// markdownBundleExporter writes notes as zip files holding the note's
+// markdown and an attachments folder with the files it shows or links to.
+// Code results are appended after their code blocks as HTML.
+type markdownBundleExporter struct{}
+
+func (markdownBundleExporter) Extension() string { return ".zip" }
+
+func (markdownBundleExporter) Export(w io.Writer, note *Note) error {
+	bundle := &markdownBundle{note: note, attachmentNames: map[string]string{}, taken: map[string]bool{}}
+
+	var markdown strings.Builder
+	markdown.WriteString(note.Frontmatter)
+	last := 0
+	for _, match := range notes.CODE_BLOCK_REGEX.FindAllStringIndex(note.Markdown, -1) {
+		markdown.WriteString(bundle.rewriteLinks(note.Markdown[last:match[0]]))
+		codeBlock := note.Markdown[match[0]:match[1]]
+		markdown.WriteString(codeBlock)
+		if result, ok := bundle.codeResult(codeBlock); ok {
+			markdown.WriteString("\n\n<div class=\"code-result\">")
+			markdown.WriteString(strings.TrimSpace(blankLineRegex.ReplaceAllString(result, "\n")))
+			markdown.WriteString("</div>")
+		}
+		last = match[1]
+	}
+	markdown.WriteString(bundle.rewriteLinks(note.Markdown[last:]))
+
+	archive := zip.NewWriter(w)
+	if err := writeZipFile(archive, note.Title+".md", []byte(markdown.String())); err != nil {
+		return err
+	}
+	for _, attachment := range bundle.attachments {
+		if err := writeZipFile(archive, "attachments/"+attachment.name, attachment.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+type bundleAttachment struct {
+	name string
+	data []byte
+}
+
+type markdownBundle struct {
+	note        *Note
+	attachments []bundleAttachment
+	// attachmentNames maps targets relative to notes/ to their names in the
+	// attachments folder, so a file used twice is stored once.
+	attachmentNames map[string]string
+	taken           map[string]bool
+}
+
+// rewriteLinks points /notes/ links and images at the bundled attachments.
+// Links to other notes, and to files that cannot be read, become their text.
+func (b *markdownBundle) rewriteLinks(markdown string) string {
+	return markdownLinkRegex.ReplaceAllStringFunc(markdown, func(link string) string {
+		match := markdownLinkRegex.FindStringSubmatch(link)
+		bang, text, destination := match[1], match[2], match[3]
+		if _, _, ok := notesLinkTarget(destination); !ok {
+			return link
+		}
+		name, ok := b.addAttachment(destination)
+		if !ok {
+			return text
+		}
+		return fmt.Sprintf("%s[%s](attachments/%s)", bang, text, url.PathEscape(name))
+	})
+}
+
+// addAttachment adds the file a /notes/ link points to and returns its name
+// in the attachments folder.
+func (b *markdownBundle) addAttachment(destination string) (string, bool) {
+	target, _, _ := notesLinkTarget(destination)
+	if name, ok := b.attachmentNames[target]; ok {
+		return name, true
+	}
+	data, _, ok := b.note.readAttachment(destination)
+	if !ok {
+		return "", false
+	}
+
+	base := path.Base(target)
+	ext := path.Ext(base)
+	name := base
+	for i := 1; b.taken[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s %d%s", strings.TrimSuffix(base, ext), i, ext)
+	}
+	b.taken[strings.ToLower(name)] = true
+	b.attachmentNames[target] = name
+	b.attachments = append(b.attachments, bundleAttachment{name: name, data: data})
+	return name, true
+}
+
+// codeResult returns the visible result of a fenced code block.
+func (b *markdownBundle) codeResult(codeBlock string) (string, bool) {
+	info, _, _ := strings.Cut(codeBlock, "\n")
+	match := codeBlockIDRegex.FindStringSubmatch(info)
+	if match == nil {
+		return "", false
+	}
+	result, ok := b.note.CodeResults[match[1]]
+	return result, ok
+}
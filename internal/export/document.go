@@ -0,0 +1,331 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/etesam913/bytebook/internal/notes"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// The PDF and DOCX exporters draw notes themselves, so a note is first
+// flattened into a list of blocks that both of them can lay out.
+
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	codeBlock
+	// resultBlock is the text output of a code block.
+	resultBlock
+	imageBlock
+	tableBlock
+	ruleBlock
+)
+
+type spanStyle struct {
+	bold, italic, strike, code bool
+}
+
+// span is a run of text in one style.
+type span struct {
+	text  string
+	style spanStyle
+	// link is the URL the span links to, if any.
+	link string
+}
+
+type block struct {
+	kind blockKind
+	// level is the level of a heading.
+	level int
+	// indent is how deeply the block is nested in lists and quotes.
+	indent int
+	quote  bool
+	// marker starts the first paragraph of a list item, e.g. "•" or "2.".
+	marker string
+	spans  []span
+	// text is the content of code and result blocks.
+	text  string
+	image *docImage
+	// rows holds a table's cells; the first row is the header.
+	rows [][][]span
+}
+
+type docImage struct {
+	data []byte
+	// format is "png", "jpeg" or "gif".
+	format        string
+	width, height int
+}
+
+var (
+	resultImageRegex     = regexp.MustCompile(`(?i)<img[^>]*\ssrc="(data:[^"]+)"[^>]*>`)
+	resultLineBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>|</(?:div|p|pre|tr|li|h[1-6])>`)
+	resultCellRegex      = regexp.MustCompile(`(?i)</t[dh]>`)
+	blankLinesRegex      = regexp.MustCompile(`\n{3,}`)
+)
+
+type documentBuilder struct {
+	note   *Note
+	source []byte
+	blocks []block
+}
+
+// buildDocument flattens a note into blocks. Images are loaded and checked
+// here so the exporters only see images they can embed; any other image is
+// replaced by its alt text.
+func buildDocument(note *Note) []block {
+	doc, source := note.parse(newMarkdown())
+	builder := &documentBuilder{note: note, source: source}
+	builder.addBlocks(doc, block{})
+	return builder.blocks
+}
+
+// addBlocks adds the block-level children of parent. context carries the
+// indent, quote and pending list marker of the enclosing containers.
+func (b *documentBuilder) addBlocks(parent ast.Node, context block) {
+	for child := parent.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Heading:
+			b.addInline(n, block{kind: headingBlock, level: n.Level, indent: context.indent, quote: context.quote})
+		case *ast.Paragraph, *ast.TextBlock:
+			b.addInline(n, block{kind: paragraphBlock, indent: context.indent, quote: context.quote, marker: context.marker})
+			context.marker = ""
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			var code strings.Builder
+			for i := 0; i < n.Lines().Len(); i++ {
+				line := n.Lines().At(i)
+				code.Write(line.Value(b.source))
+			}
+			b.blocks = append(b.blocks, block{
+				kind:   codeBlock,
+				indent: context.indent,
+				text:   strings.TrimRight(code.String(), "\n"),
+			})
+			if fenced, ok := n.(*ast.FencedCodeBlock); ok {
+				if result, ok := codeResult(fenced); ok {
+					b.addResult(result, context.indent)
+				}
+			}
+		case *ast.Blockquote:
+			b.addBlocks(n, block{indent: context.indent + 1, quote: true})
+		case *ast.List:
+			number := n.Start
+			for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+				marker := "•"
+				if n.IsOrdered() {
+					marker = fmt.Sprintf("%d.", number)
+					number++
+				}
+				b.addBlocks(item, block{indent: context.indent + 1, quote: context.quote, marker: marker})
+			}
+		case *ast.ThematicBreak:
+			b.blocks = append(b.blocks, block{kind: ruleBlock})
+		case *east.Table:
+			b.addTable(n, context.indent)
+		case *ast.HTMLBlock:
+			// Raw HTML is left out, as it is on the exported website.
+		default:
+			b.addBlocks(n, context)
+		}
+	}
+}
+
+// addInline adds a paragraph-like block for node. Images split the block,
+// since the exporters place them on their own lines.
+func (b *documentBuilder) addInline(node ast.Node, template block) {
+	current := template
+	flush := func() {
+		if len(current.spans) > 0 {
+			b.blocks = append(b.blocks, current)
+		}
+		current = template
+		current.marker = ""
+	}
+	b.addSpans(node, spanStyle{}, "", &current.spans, func(img *ast.Image) {
+		flush()
+		b.addImage(string(img.Destination), b.plainText(img), template.indent)
+	})
+	flush()
+}
+
+// addSpans appends the inline content of parent to spans. onImage is called
+// for images; when it is nil, images are replaced by their alt text.
+func (b *documentBuilder) addSpans(parent ast.Node, style spanStyle, link string, spans *[]span, onImage func(*ast.Image)) {
+	emit := func(text string, style spanStyle, link string) {
+		if last := len(*spans) - 1; last >= 0 && (*spans)[last].style == style && (*spans)[last].link == link {
+			(*spans)[last].text += text
+			return
+		}
+		*spans = append(*spans, span{text: text, style: style, link: link})
+	}
+
+	for child := parent.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			emit(string(n.Segment.Value(b.source)), style, link)
+			if n.HardLineBreak() {
+				emit("\n", style, link)
+			} else if n.SoftLineBreak() {
+				emit(" ", style, link)
+			}
+		case *ast.String:
+			emit(string(n.Value), style, link)
+		case *ast.CodeSpan:
+			codeStyle := style
+			codeStyle.code = true
+			b.addSpans(n, codeStyle, link, spans, onImage)
+		case *ast.Emphasis:
+			emphasisStyle := style
+			if n.Level >= 2 {
+				emphasisStyle.bold = true
+			} else {
+				emphasisStyle.italic = true
+			}
+			b.addSpans(n, emphasisStyle, link, spans, onImage)
+		case *east.Strikethrough:
+			strikeStyle := style
+			strikeStyle.strike = true
+			b.addSpans(n, strikeStyle, link, spans, onImage)
+		case *ast.Link:
+			b.addSpans(n, style, externalURL(string(n.Destination)), spans, onImage)
+		case *ast.AutoLink:
+			emit(string(n.Label(b.source)), style, externalURL(string(n.URL(b.source))))
+		case *ast.Image:
+			if onImage != nil {
+				onImage(n)
+			} else if alt := b.plainText(n); alt != "" {
+				emit(alt, style, link)
+			}
+		case *east.TaskCheckBox:
+			if n.IsChecked {
+				emit("[x] ", style, link)
+			} else {
+				emit("[ ] ", style, link)
+			}
+		case *ast.RawHTML:
+		default:
+			b.addSpans(n, style, link, spans, onImage)
+		}
+	}
+}
+
+// plainText returns the text inside an inline node, such as an image's alt
+// text.
+func (b *documentBuilder) plainText(node ast.Node) string {
+	var spans []span
+	b.addSpans(node, spanStyle{}, "", &spans, nil)
+	return spansText(spans)
+}
+
+func (b *documentBuilder) addTable(table *east.Table, indent int) {
+	var rows [][][]span
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells [][]span
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			var spans []span
+			b.addSpans(cell, spanStyle{}, "", &spans, nil)
+			cells = append(cells, spans)
+		}
+		rows = append(rows, cells)
+	}
+	b.blocks = append(b.blocks, block{kind: tableBlock, indent: indent, rows: rows})
+}
+
+// addImage adds the image at destination, which may be a /notes/ link or a
+// data URI. Images that cannot be loaded or embedded become their alt text.
+func (b *documentBuilder) addImage(destination, alt string, indent int) {
+	var data []byte
+	if strings.HasPrefix(destination, "data:") {
+		data, _ = decodeDataURI(destination)
+	} else {
+		data, _, _ = b.note.readAttachment(destination)
+	}
+
+	if img, ok := newDocImage(data); ok {
+		b.blocks = append(b.blocks, block{kind: imageBlock, indent: indent, image: img})
+		return
+	}
+	if alt == "" {
+		alt = "image"
+	}
+	b.blocks = append(b.blocks, block{
+		kind:   paragraphBlock,
+		indent: indent,
+		spans:  []span{{text: "[" + alt + "]", style: spanStyle{italic: true}}},
+	})
+}
+
+// addResult adds a code block's result. Results are HTML written by the
+// editor: images are kept and everything else is reduced to its text.
+func (b *documentBuilder) addResult(resultHTML string, indent int) {
+	addText := func(fragment string) {
+		text := resultLineBreakRegex.ReplaceAllString(fragment, "\n")
+		text = resultCellRegex.ReplaceAllString(text, "\t")
+		text = html.UnescapeString(notes.HTML_TAG_REGEX.ReplaceAllString(text, ""))
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t")
+		}
+		text = strings.Trim(blankLinesRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"), "\n")
+		if text != "" {
+			b.blocks = append(b.blocks, block{kind: resultBlock, indent: indent, text: text})
+		}
+	}
+
+	last := 0
+	for _, match := range resultImageRegex.FindAllStringSubmatchIndex(resultHTML, -1) {
+		addText(resultHTML[last:match[0]])
+		b.addImage(html.UnescapeString(resultHTML[match[2]:match[3]]), "result", indent)
+		last = match[1]
+	}
+	addText(resultHTML[last:])
+}
+
+// newDocImage checks that data is an image the exporters can embed.
+func newDocImage(data []byte) (*docImage, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width == 0 || config.Height == 0 {
+		return nil, false
+	}
+	return &docImage{data: data, format: format, width: config.Width, height: config.Height}, true
+}
+
+// decodeDataURI returns the contents of a base64 data URI.
+func decodeDataURI(uri string) ([]byte, bool) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, false
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+	return data, err == nil
+}
+
+// externalURL returns destination when it can be opened outside Bytebook.
+// Links between notes and to attachments cannot, so they are dropped.
+func externalURL(destination string) string {
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return ""
+	}
+	switch parsed.Scheme {
+	case "http", "https", "mailto":
+		return destination
+	}
+	return ""
+}
@@ -0,0 +1,321 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// docxTwipsPerIndent is the indent of one list or quote level, in
+	// twentieths of a point.
+	docxTwipsPerIndent = 360
+	// docxEMUPerPixel converts pixels at 96 DPI to the EMUs used for drawings.
+	docxEMUPerPixel = 9525
+	// docxMaxImageWidth is the width of the text column on an A4 page with
+	// one-inch margins, in EMUs.
+	docxMaxImageWidth = 5731510
+)
+
+// docxExporter writes notes as Word documents. The package is assembled by
+// hand; headings, code and quotes use styles defined in styles.xml so they
+// can be restyled in Word.
+type docxExporter struct{}
+
+func (docxExporter) Extension() string { return ".docx" }
+
+func (docxExporter) Export(w io.Writer, note *Note) error {
+	d := &docxDocument{}
+	d.paragraph("Title", 0, "", []span{{text: note.Title}})
+	for _, b := range buildDocument(note) {
+		d.block(b)
+	}
+
+	archive := zip.NewWriter(w)
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxPackageRels},
+		{"word/styles.xml", docxStyles},
+		{"word/_rels/document.xml.rels", d.relationships()},
+		{"word/document.xml", d.document()},
+	}
+	for _, file := range files {
+		if err := writeZipFile(archive, file.name, []byte(file.content)); err != nil {
+			return err
+		}
+	}
+	for i, img := range d.images {
+		if err := writeZipFile(archive, docxImageName(i, img), img.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+type docxDocument struct {
+	body   strings.Builder
+	images []*docImage
+	// links holds the targets of hyperlinks; each gets a relationship.
+	links []string
+}
+
+func (d *docxDocument) block(b block) {
+	switch b.kind {
+	case headingBlock:
+		d.paragraph(fmt.Sprintf("Heading%d", b.level), b.indent, "", b.spans)
+	case paragraphBlock:
+		style := ""
+		if b.quote {
+			style = "Quote"
+		}
+		d.paragraph(style, b.indent, b.marker, b.spans)
+	case codeBlock:
+		d.paragraph("Code", b.indent, "", []span{{text: b.text}})
+	case resultBlock:
+		d.paragraph("CodeResult", b.indent, "", []span{{text: b.text}})
+	case imageBlock:
+		d.image(b.image, b.indent)
+	case tableBlock:
+		d.table(b.rows)
+	case ruleBlock:
+		d.body.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr></w:pPr></w:p>`)
+	}
+}
+
+func (d *docxDocument) paragraph(style string, indent int, marker string, spans []span) {
+	d.body.WriteString("<w:p>")
+	d.paragraphProperties(style, indent, marker != "")
+	if marker != "" {
+		d.run(span{text: marker + "\t"})
+	}
+	d.runs(spans)
+	d.body.WriteString("</w:p>")
+}
+
+func (d *docxDocument) paragraphProperties(style string, indent int, hanging bool) {
+	if style == "" && indent == 0 {
+		return
+	}
+	d.body.WriteString("<w:pPr>")
+	if style != "" {
+		fmt.Fprintf(&d.body, `<w:pStyle w:val="%s"/>`, style)
+	}
+	if indent > 0 {
+		if hanging {
+			fmt.Fprintf(&d.body, `<w:ind w:left="%d" w:hanging="%d"/>`, indent*docxTwipsPerIndent, docxTwipsPerIndent)
+		} else {
+			fmt.Fprintf(&d.body, `<w:ind w:left="%d"/>`, indent*docxTwipsPerIndent)
+		}
+	}
+	d.body.WriteString("</w:pPr>")
+}
+
+func (d *docxDocument) runs(spans []span) {
+	for _, s := range spans {
+		if s.link == "" {
+			d.run(s)
+			continue
+		}
+		d.links = append(d.links, s.link)
+		fmt.Fprintf(&d.body, `<w:hyperlink r:id="%s">`, docxLinkID(len(d.links)-1))
+		d.run(s)
+		d.body.WriteString("</w:hyperlink>")
+	}
+}
+
+// run writes one styled run. Line breaks and tabs become their Word
+// elements.
+func (d *docxDocument) run(s span) {
+	d.body.WriteString("<w:r>")
+	if s.style != (spanStyle{}) || s.link != "" {
+		d.body.WriteString("<w:rPr>")
+		if s.link != "" {
+			d.body.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+		}
+		if s.style.code {
+			d.body.WriteString(`<w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/>`)
+		}
+		if s.style.bold {
+			d.body.WriteString("<w:b/>")
+		}
+		if s.style.italic {
+			d.body.WriteString("<w:i/>")
+		}
+		if s.style.strike {
+			d.body.WriteString("<w:strike/>")
+		}
+		d.body.WriteString("</w:rPr>")
+	}
+	for i, line := range strings.Split(s.text, "\n") {
+		if i > 0 {
+			d.body.WriteString("<w:br/>")
+		}
+		for j, part := range strings.Split(line, "\t") {
+			if j > 0 {
+				d.body.WriteString("<w:tab/>")
+			}
+			if part != "" {
+				d.body.WriteString(`<w:t xml:space="preserve">`)
+				_ = xml.EscapeText(&d.body, []byte(part))
+				d.body.WriteString("</w:t>")
+			}
+		}
+	}
+	d.body.WriteString("</w:r>")
+}
+
+// image writes an inline picture at its natural size, shrunk to the text
+// column.
+func (d *docxDocument) image(img *docImage, indent int) {
+	d.images = append(d.images, img)
+	id := len(d.images)
+	width := int64(img.width) * docxEMUPerPixel
+	height := int64(img.height) * docxEMUPerPixel
+	if width > docxMaxImageWidth {
+		height = height * docxMaxImageWidth / width
+		width = docxMaxImageWidth
+	}
+
+	d.body.WriteString("<w:p>")
+	d.paragraphProperties("", indent, false)
+	fmt.Fprintf(&d.body, `<w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%[1]d" cy="%[2]d"/><wp:docPr id="%[3]d" name="Picture %[3]d"/>`+
+		`<a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">`+
+		`<a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:nvPicPr><pic:cNvPr id="%[3]d" name="Picture %[3]d"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%[4]s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[1]d" cy="%[2]d"/></a:xfrm>`+
+		`<a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr></pic:pic>`+
+		`</a:graphicData></a:graphic></wp:inline></w:drawing></w:r>`,
+		width, height, id, docxImageID(id-1))
+	d.body.WriteString("</w:p>")
+}
+
+func (d *docxDocument) table(rows [][][]span) {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return
+	}
+
+	d.body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/></w:tblPr><w:tblGrid>`)
+	for range columns {
+		d.body.WriteString("<w:gridCol/>")
+	}
+	d.body.WriteString("</w:tblGrid>")
+	for i, row := range rows {
+		d.body.WriteString("<w:tr>")
+		for j := range columns {
+			d.body.WriteString("<w:tc><w:p>")
+			if j < len(row) {
+				cell := row[j]
+				if i == 0 {
+					cell = make([]span, len(row[j]))
+					for k, s := range row[j] {
+						s.style.bold = true
+						cell[k] = s
+					}
+				}
+				d.runs(cell)
+			}
+			d.body.WriteString("</w:p></w:tc>")
+		}
+		d.body.WriteString("</w:tr>")
+	}
+	// Word needs a paragraph between a table and whatever follows it.
+	d.body.WriteString("</w:tbl><w:p/>")
+}
+
+func (d *docxDocument) document() string {
+	return xml.Header +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+		`xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"><w:body>` +
+		d.body.String() +
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/>` +
+		`<w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="708" w:footer="708" w:gutter="0"/>` +
+		`</w:sectPr></w:body></w:document>`
+}
+
+func (d *docxDocument) relationships() string {
+	var rels strings.Builder
+	rels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	rels.WriteString(`<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`)
+	for i, img := range d.images {
+		fmt.Fprintf(&rels, `<Relationship Id="%s" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="%s"/>`,
+			docxImageID(i), strings.TrimPrefix(docxImageName(i, img), "word/"))
+	}
+	for i, link := range d.links {
+		fmt.Fprintf(&rels, `<Relationship Id="%s" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="`, docxLinkID(i))
+		_ = xml.EscapeText(&rels, []byte(link))
+		rels.WriteString(`" TargetMode="External"/>`)
+	}
+	rels.WriteString("</Relationships>")
+	return rels.String()
+}
+
+func docxImageID(i int) string { return fmt.Sprintf("rIdImage%d", i+1) }
+
+func docxLinkID(i int) string { return fmt.Sprintf("rIdLink%d", i+1) }
+
+func docxImageName(i int, img *docImage) string {
+	return fmt.Sprintf("word/media/image%d.%s", i+1, img.format)
+}
+
+func writeZipFile(archive *zip.Writer, name string, content []byte) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	return err
+}
+
+const docxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Default Extension="png" ContentType="image/png"/>` +
+	`<Default Extension="jpeg" ContentType="image/jpeg"/>` +
+	`<Default Extension="gif" ContentType="image/gif"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`</Types>`
+
+const docxPackageRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`</Relationships>`
+
+const docxStyles = xml.Header + `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:cs="Calibri"/><w:sz w:val="22"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="120" w:line="276" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:spacing w:after="240"/></w:pPr><w:rPr><w:b/><w:sz w:val="48"/></w:rPr></w:style>` +
+	docxHeadingStyles +
+	`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/>` +
+	`<w:rPr><w:i/><w:color w:val="595959"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F4F4F5"/><w:spacing w:after="120" w:line="240" w:lineRule="auto"/></w:pPr>` +
+	`<w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/><w:sz w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="CodeResult"><w:name w:val="Code Result"/><w:basedOn w:val="Code"/>` +
+	`<w:pPr><w:shd w:val="clear" w:color="auto" w:fill="auto"/><w:pBdr><w:left w:val="single" w:sz="8" w:space="8" w:color="D4D4D8"/></w:pBdr></w:pPr>` +
+	`<w:rPr><w:color w:val="3F3F46"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="2563EB"/><w:u w:val="single"/></w:rPr></w:style>` +
+	`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr><w:tblBorders>` +
+	`<w:top w:val="single" w:sz="4" w:color="D4D4D8"/><w:left w:val="single" w:sz="4" w:color="D4D4D8"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:color="D4D4D8"/><w:right w:val="single" w:sz="4" w:color="D4D4D8"/>` +
+	`<w:insideH w:val="single" w:sz="4" w:color="D4D4D8"/><w:insideV w:val="single" w:sz="4" w:color="D4D4D8"/>` +
+	`</w:tblBorders></w:tblPr></w:style>` +
+	`</w:styles>`
+
+const docxHeadingStyles = `<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="36"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="200"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="30"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="200"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="26"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading4"><w:name w:val="heading 4"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:b/><w:sz w:val="24"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading5"><w:name w:val="heading 5"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="4"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading6"><w:name w:val="heading 6"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="5"/></w:pPr><w:rPr><w:b/><w:i/></w:rPr></w:style>`
@@ -0,0 +1,290 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/notes"
+	"github.com/etesam913/bytebook/internal/notes/sidecar"
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+// Format names a format notes can be exported to.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatPDF      Format = "pdf"
+	FormatDOCX     Format = "docx"
+)
+
+// Exporter writes a single note in one format. Exported files stand on their
+// own: images and code results are embedded rather than linked.
+type Exporter interface {
+	// Extension is the extension of the files the exporter writes, such as
+	// ".pdf".
+	Extension() string
+	// Export writes note to w.
+	Export(w io.Writer, note *Note) error
+}
+
+// exporters holds the exporter for each format.
+var exporters = map[Format]Exporter{
+	FormatMarkdown: markdownBundleExporter{},
+	FormatHTML:     htmlExporter{},
+	FormatPDF:      pdfExporter{},
+	FormatDOCX:     docxExporter{},
+}
+
+// ExporterFor returns the exporter for format.
+func ExporterFor(format Format) (Exporter, bool) {
+	exporter, ok := exporters[format]
+	return exporter, ok
+}
+
+var errNoNotesToExport = errors.New("there are no notes to export")
+
+// Note is a note loaded for export.
+type Note struct {
+	ProjectPath string
+	// Path is relative to notes/.
+	Path  string
+	Title string
+	Tags  []string
+	// Frontmatter is the note's frontmatter block, or "" when it has none.
+	Frontmatter string
+	// Markdown is the note's body without its frontmatter.
+	Markdown string
+	// CodeResults maps code block ids to the HTML of their visible results.
+	CodeResults map[string]string
+
+	// warnings are problems an exporter worked around, such as text a
+	// format cannot show, reported with the export.
+	warnings []string
+}
+
+// warn records a problem that did not stop the note from being exported.
+func (n *Note) warn(message string) {
+	n.warnings = append(n.warnings, message)
+}
+
+// LoadNote reads the note at notePath, relative to notes/, with its tags and
+// the code results saved in its sidecar.
+func LoadNote(projectPath, notePath string) (*Note, error) {
+	absPath, err := config.ResolveNotesPath(projectPath, notePath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	markdown := string(content)
+
+	note := &Note{
+		ProjectPath: projectPath,
+		Path:        filepath.ToSlash(notePath),
+		Title:       strings.TrimSuffix(filepath.Base(notePath), filepath.Ext(notePath)),
+		Frontmatter: notes.FRONTMATTER_REGEX.FindString(markdown),
+		CodeResults: map[string]string{},
+	}
+	note.Tags, _ = notes.GetTagsFromFrontmatter(markdown)
+	note.Markdown = strings.TrimPrefix(markdown, note.Frontmatter)
+
+	codeResults, err := sidecar.ReadCodeResults(absPath)
+	if err != nil {
+		log.Printf("could not read code results for %s: %v", notePath, err)
+	}
+	for _, codeBlock := range codeResults.CodeBlocks {
+		if !codeBlock.AreResultsHidden {
+			note.CodeResults[codeBlock.CodeBlockID] = codeBlock.ResultHTML
+		}
+	}
+	return note, nil
+}
+
+// readAttachment returns the contents of the file a /notes/ link points to.
+func (n *Note) readAttachment(destination string) ([]byte, string, bool) {
+	target, _, ok := notesLinkTarget(destination)
+	if !ok || path.Ext(target) == ".md" {
+		return nil, "", false
+	}
+	absPath, err := config.ResolveNotesPath(n.ProjectPath, target)
+	if err != nil {
+		return nil, "", false
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, "", false
+	}
+	return data, target, true
+}
+
+// FailedExport is a note that could not be exported.
+type FailedExport struct {
+	Note   string `json:"note"`
+	Reason string `json:"reason"`
+}
+
+// ExportWarning is a note that was exported with something left out or
+// replaced.
+type ExportWarning struct {
+	Note    string `json:"note"`
+	Message string `json:"message"`
+}
+
+// ExportReport summarises exporting several notes.
+type ExportReport struct {
+	// Destination is the exported file when a single note was exported, and
+	// the folder holding the exported notes otherwise.
+	Destination string          `json:"destination"`
+	Notes       int             `json:"notes"`
+	Failed      []FailedExport  `json:"failed"`
+	Warnings    []ExportWarning `json:"warnings"`
+}
+
+// ExportNotes exports the notes and folders at paths, relative to notes/, in
+// format. A single note is written to parentPath as one file; anything else
+// goes into a new folder in parentPath where each selected folder keeps its
+// layout. A note that fails to export is reported and the rest carry on.
+func ExportNotes(projectPath string, paths []string, format Format, parentPath string) (ExportReport, error) {
+	report := ExportReport{Failed: []FailedExport{}, Warnings: []ExportWarning{}}
+	exporter, ok := ExporterFor(format)
+	if !ok {
+		return report, fmt.Errorf("cannot export to %s", format)
+	}
+
+	// outputs maps each note to export to its path inside the export folder.
+	type output struct{ notePath, outPath string }
+	var outputs []output
+	for _, selected := range paths {
+		selected = strings.Trim(filepath.ToSlash(selected), "/")
+		if path.Ext(selected) == ".md" {
+			outputs = append(outputs, output{selected, path.Base(selected)})
+			continue
+		}
+
+		prefix := path.Base(selected)
+		if len(paths) == 1 {
+			prefix = ""
+		}
+		notePaths, err := listFolderNotes(projectPath, selected)
+		if err != nil {
+			return report, err
+		}
+		for _, notePath := range notePaths {
+			outputs = append(outputs, output{notePath, path.Join(prefix, strings.TrimPrefix(notePath, selected+"/"))})
+		}
+	}
+	if len(outputs) == 0 {
+		return report, errNoNotesToExport
+	}
+
+	var root string
+	if len(paths) == 1 && len(outputs) == 1 && outputs[0].notePath == strings.Trim(filepath.ToSlash(paths[0]), "/") {
+		root = parentPath
+	} else {
+		name := "Bytebook"
+		if len(paths) == 1 {
+			name = path.Base(strings.Trim(filepath.ToSlash(paths[0]), "/"))
+		}
+		var err error
+		root, err = util.CreateUniqueNameForFileIfExists(filepath.Join(parentPath, name+" Export"))
+		if err != nil {
+			return report, err
+		}
+		report.Destination = root
+	}
+
+	for _, out := range outputs {
+		outPath := filepath.Join(root, filepath.FromSlash(strings.TrimSuffix(out.outPath, ".md")+exporter.Extension()))
+		written, warnings, err := exportNoteToFile(projectPath, out.notePath, exporter, outPath)
+		if err != nil {
+			report.Failed = append(report.Failed, FailedExport{Note: out.notePath, Reason: err.Error()})
+			continue
+		}
+		for _, warning := range warnings {
+			report.Warnings = append(report.Warnings, ExportWarning{Note: out.notePath, Message: warning})
+		}
+		if report.Destination == "" {
+			report.Destination = written
+		}
+		report.Notes++
+	}
+	return report, nil
+}
+
+// exportNoteToFile exports one note to outPath, numbering the name if a file
+// is already there, and returns the path it wrote and the exporter's warnings.
+func exportNoteToFile(projectPath, notePath string, exporter Exporter, outPath string) (string, []string, error) {
+	note, err := LoadNote(projectPath, notePath)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return "", nil, err
+	}
+	outPath, err = util.CreateUniqueNameForFileIfExists(outPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return "", nil, err
+	}
+	err = exporter.Export(out, note)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return "", nil, err
+	}
+	return outPath, note.warnings, nil
+}
+
+// listFolderNotes returns the notes in folder and its subfolders, relative
+// to notes/, skipping hidden and ignored files.
+func listFolderNotes(projectPath, folder string) ([]string, error) {
+	absFolder, err := config.ResolveNotesPath(projectPath, folder)
+	if err != nil {
+		return nil, err
+	}
+	ignore := notes.NewIgnoreMatcher(projectPath)
+
+	var notePaths []string
+	err = filepath.WalkDir(absFolder, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == absFolder {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || ignore.Match(filePath, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || filepath.Ext(filePath) != ".md" {
+			return nil
+		}
+		relPath, err := filepath.Rel(absFolder, filePath)
+		if err != nil {
+			return err
+		}
+		notePaths = append(notePaths, path.Join(folder, filepath.ToSlash(relPath)))
+		return nil
+	})
+	return notePaths, err
+}
@@ -0,0 +1,237 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/etesam913/bytebook/internal/notes/sidecar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.String()
+}
+
+// setupExportNote creates a project holding a note that uses every feature
+// the exporters handle, and returns the project and the loaded note.
+func setupExportNote(t *testing.T) (string, *Note) {
+	t.Helper()
+	projectDir := setupExportProject(t, map[string]string{
+		"Docs/Home.md": "---\ntags:\n  - guide\n---\n" +
+			"# Welcome\n\nSee [the plan](/notes/Docs/Plan.md) and [the site](https://example.com).\n\n" +
+			"![diagram](/notes/Docs/diagram.png)\n\n" +
+			"- **bold** item\n- ~~struck~~ `code`\n\n" +
+			"| Name | Value |\n| --- | --- |\n| a | 1 |\n\n" +
+			"```python id=\"block-1\"\nprint(1 < 2)\n```\n",
+		"Docs/Plan.md":     "The plan.\n",
+		"Docs/diagram.png": testPNG(t),
+	})
+	homePath := filepath.Join(projectDir, "notes", "Docs", "Home.md")
+	require.NoError(t, sidecar.WriteCodeResults(homePath, sidecar.CodeResults{
+		CodeBlocks: []sidecar.CodeBlock{{CodeBlockID: "block-1", ResultHTML: "<div>True</div>\n\n<div>done</div>"}},
+	}))
+
+	note, err := LoadNote(projectDir, "Docs/Home.md")
+	require.NoError(t, err)
+	return projectDir, note
+}
+
+func exportToBuffer(t *testing.T, format Format, note *Note) []byte {
+	t.Helper()
+	exporter, ok := ExporterFor(format)
+	require.True(t, ok)
+	var buf bytes.Buffer
+	require.NoError(t, exporter.Export(&buf, note))
+	return buf.Bytes()
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		reader.Close()
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+func TestLoadNote(t *testing.T) {
+	_, note := setupExportNote(t)
+
+	assert.Equal(t, "Docs/Home.md", note.Path)
+	assert.Equal(t, "Home", note.Title)
+	assert.Equal(t, []string{"guide"}, note.Tags)
+	assert.True(t, strings.HasPrefix(note.Markdown, "# Welcome"))
+	assert.Equal(t, map[string]string{"block-1": "<div>True</div>\n\n<div>done</div>"}, note.CodeResults)
+}
+
+func TestExporters(t *testing.T) {
+	t.Run("markdown bundles the note with its attachments", func(t *testing.T) {
+		_, note := setupExportNote(t)
+
+		files := readZip(t, exportToBuffer(t, FormatMarkdown, note))
+
+		require.Contains(t, files, "Home.md")
+		markdown := files["Home.md"]
+		assert.True(t, strings.HasPrefix(markdown, "---\ntags:\n  - guide\n---\n# Welcome"))
+		assert.Contains(t, markdown, "See the plan and [the site](https://example.com).")
+		assert.Contains(t, markdown, "![diagram](attachments/diagram.png)")
+		assert.Contains(t, markdown, "```\n\n<div class=\"code-result\"><div>True</div>\n<div>done</div></div>")
+		assert.Equal(t, testPNG(t), files["attachments/diagram.png"])
+	})
+
+	t.Run("html embeds images and styles", func(t *testing.T) {
+		_, note := setupExportNote(t)
+
+		html := string(exportToBuffer(t, FormatHTML, note))
+
+		assert.Contains(t, html, "<title>Home</title>")
+		assert.Contains(t, html, ".code-result {")
+		assert.Contains(t, html, `<img src="data:image/png;base64,`)
+		assert.Contains(t, html, "See the plan and <a href=\"https://example.com\">the site</a>.")
+		assert.Contains(t, html, `<div class="code-result"><div>True</div>`)
+		assert.Contains(t, html, "<li>#guide</li>")
+	})
+
+	t.Run("pdf", func(t *testing.T) {
+		_, note := setupExportNote(t)
+
+		pdf := exportToBuffer(t, FormatPDF, note)
+
+		assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+		assert.Contains(t, string(pdf), "/Subtype /Image")
+	})
+
+	t.Run("pdf replaces and reports characters without a glyph", func(t *testing.T) {
+		note := &Note{Title: "Note", Markdown: "Привет, αβγ → 日本 😀 and `код`\n"}
+
+		pdf := exportToBuffer(t, FormatPDF, note)
+
+		assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+		assert.Contains(t, string(pdf), "/FontFile2")
+		require.Len(t, note.warnings, 1)
+		assert.Equal(t, `3 characters have no glyph in the PDF font and were replaced with "?": 日 (U+65E5), 本 (U+672C), 😀 (U+1F600)`, note.warnings[0])
+	})
+
+	t.Run("docx", func(t *testing.T) {
+		_, note := setupExportNote(t)
+
+		files := readZip(t, exportToBuffer(t, FormatDOCX, note))
+
+		for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/styles.xml", "word/_rels/document.xml.rels"} {
+			assert.Contains(t, files, name)
+		}
+		assert.Equal(t, testPNG(t), files["word/media/image1.png"])
+
+		document := files["word/document.xml"]
+		assert.Contains(t, document, `<w:pStyle w:val="Heading1"/>`)
+		assert.Contains(t, document, "print(1 &lt; 2)")
+		assert.Contains(t, document, `<w:pStyle w:val="CodeResult"/>`)
+		assert.Contains(t, document, `<w:b/></w:rPr><w:t xml:space="preserve">bold</w:t>`)
+		assert.Contains(t, document, `<w:strike/></w:rPr><w:t xml:space="preserve">struck</w:t>`)
+		assert.Contains(t, document, "<w:tbl>")
+		assert.Contains(t, document, `<a:blip r:embed="rIdImage1"/>`)
+		assert.NotContains(t, document, "/notes/")
+
+		rels := files["word/_rels/document.xml.rels"]
+		assert.Contains(t, rels, `Target="https://example.com" TargetMode="External"`)
+		assert.Contains(t, rels, `Target="media/image1.png"`)
+	})
+
+	t.Run("missing images become their alt text", func(t *testing.T) {
+		note := &Note{Title: "Note", Markdown: "![lost](/notes/missing.png)\n"}
+
+		blocks := buildDocument(note)
+
+		require.Len(t, blocks, 1)
+		assert.Equal(t, []span{{text: "[lost]", style: spanStyle{italic: true}}}, blocks[0].spans)
+	})
+}
+
+func TestExportNotes(t *testing.T) {
+	t.Run("a single note is written to the destination", func(t *testing.T) {
+		projectDir, _ := setupExportNote(t)
+		destination := t.TempDir()
+
+		report, err := ExportNotes(projectDir, []string{"Docs/Home.md"}, FormatPDF, destination)
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join(destination, "Home.pdf"), report.Destination)
+		assert.Equal(t, 1, report.Notes)
+		assert.Empty(t, report.Failed)
+		assert.FileExists(t, report.Destination)
+
+		report, err = ExportNotes(projectDir, []string{"Docs/Home.md"}, FormatPDF, destination)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(destination, "Home 1.pdf"), report.Destination)
+	})
+
+	t.Run("a folder is written to a new folder", func(t *testing.T) {
+		projectDir, _ := setupExportNote(t)
+		destination := t.TempDir()
+
+		report, err := ExportNotes(projectDir, []string{"Docs"}, FormatDOCX, destination)
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join(destination, "Docs Export"), report.Destination)
+		assert.Equal(t, 2, report.Notes)
+		assert.FileExists(t, filepath.Join(report.Destination, "Home.docx"))
+		assert.FileExists(t, filepath.Join(report.Destination, "Plan.docx"))
+	})
+
+	t.Run("a selection keeps each folder's name", func(t *testing.T) {
+		projectDir, _ := setupExportNote(t)
+		require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "notes", "Other"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, "notes", "Other", "Note.md"), []byte("other"), 0644))
+		destination := t.TempDir()
+
+		report, err := ExportNotes(projectDir, []string{"Docs", "Other/Note.md", "Missing.md"}, FormatHTML, destination)
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join(destination, "Bytebook Export"), report.Destination)
+		assert.Equal(t, 3, report.Notes)
+		assert.FileExists(t, filepath.Join(report.Destination, "Docs", "Home.html"))
+		assert.FileExists(t, filepath.Join(report.Destination, "Note.html"))
+		require.Len(t, report.Failed, 1)
+		assert.Equal(t, "Missing.md", report.Failed[0].Note)
+	})
+
+	t.Run("warnings are reported with the note", func(t *testing.T) {
+		projectDir := setupExportProject(t, map[string]string{"Notes/CJK.md": "日本語\n"})
+
+		report, err := ExportNotes(projectDir, []string{"Notes/CJK.md"}, FormatPDF, t.TempDir())
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, report.Notes)
+		require.Len(t, report.Warnings, 1)
+		assert.Equal(t, "Notes/CJK.md", report.Warnings[0].Note)
+		assert.Contains(t, report.Warnings[0].Message, "3 characters have no glyph")
+	})
+
+	t.Run("unknown formats are rejected", func(t *testing.T) {
+		projectDir, _ := setupExportNote(t)
+
+		_, err := ExportNotes(projectDir, []string{"Docs/Home.md"}, Format("rtf"), t.TempDir())
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,250 @@
+package export
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"log"
+	"sync"
+)
+
+// PDFs are drawn with embedded DejaVu fonts so text outside Windows-1252,
+// such as Cyrillic and Greek, is not garbled. To keep the binary small the
+// fonts are subsets made with fpdf.UTF8CutFont, covering Latin, Greek,
+// Cyrillic, punctuation, currency, arrows, math, technical symbols, box
+// drawing and geometric shapes. Characters outside them, such as Hebrew,
+// Arabic, CJK and emoji, are replaced and reported instead.
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	dejaVuSans []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	dejaVuSansBold []byte
+	//go:embed fonts/DejaVuSansCondensed-Oblique.ttf
+	dejaVuSansOblique []byte
+	//go:embed fonts/DejaVuSansCondensed-BoldOblique.ttf
+	dejaVuSansBoldOblique []byte
+	//go:embed fonts/DejaVuSansMono.ttf
+	dejaVuSansMono []byte
+)
+
+const (
+	pdfSans = "DejaVuSans"
+	pdfMono = "DejaVuSansMono"
+)
+
+// pdfFont is one embedded font file, registered with fpdf under family and
+// style ("", "B", "I" or "BI").
+type pdfFont struct {
+	family string
+	style  string
+	data   []byte
+
+	once     sync.Once
+	coverage map[rune]bool
+}
+
+var pdfFonts = []*pdfFont{
+	{family: pdfSans, style: "", data: dejaVuSans},
+	{family: pdfSans, style: "B", data: dejaVuSansBold},
+	{family: pdfSans, style: "I", data: dejaVuSansOblique},
+	{family: pdfSans, style: "BI", data: dejaVuSansBoldOblique},
+	{family: pdfMono, style: "", data: dejaVuSansMono},
+}
+
+// lookupPDFFont returns the embedded font for family and style, falling back
+// to the family's regular face. The mono family only has a regular face.
+func lookupPDFFont(family, style string) *pdfFont {
+	var regular *pdfFont
+	for _, font := range pdfFonts {
+		if font.family != family {
+			continue
+		}
+		if font.style == style {
+			return font
+		}
+		if font.style == "" {
+			regular = font
+		}
+	}
+	return regular
+}
+
+// has reports whether the font has a glyph for r. The font's cmap is read on
+// first use; a font whose cmap cannot be read is assumed to cover everything.
+func (f *pdfFont) has(r rune) bool {
+	f.once.Do(func() {
+		coverage, err := glyphCoverage(f.data)
+		if err != nil {
+			log.Printf("could not read the glyphs of %s %q: %v", f.family, f.style, err)
+		}
+		f.coverage = coverage
+	})
+	return f.coverage == nil || f.coverage[r]
+}
+
+var errBadFont = errors.New("malformed TrueType font")
+
+// fontData reads big-endian integers from a font file without panicking on
+// truncated input.
+type fontData []byte
+
+func (d fontData) u16(offset int) (int, error) {
+	if offset < 0 || offset+2 > len(d) {
+		return 0, errBadFont
+	}
+	return int(binary.BigEndian.Uint16(d[offset:])), nil
+}
+
+func (d fontData) u32(offset int) (int, error) {
+	if offset < 0 || offset+4 > len(d) {
+		return 0, errBadFont
+	}
+	return int(binary.BigEndian.Uint32(d[offset:])), nil
+}
+
+// glyphCoverage returns the runes a TrueType font maps to a glyph, read from
+// the Unicode subtable of its cmap. Subtable formats 12 (all planes) and 4
+// (Basic Multilingual Plane) are understood.
+func glyphCoverage(ttf []byte) (map[rune]bool, error) {
+	d := fontData(ttf)
+	cmap, err := findTable(d, "cmap")
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := d.u16(cmap + 2)
+	if err != nil {
+		return nil, err
+	}
+	format4, format12 := -1, -1
+	for i := 0; i < count; i++ {
+		record := cmap + 4 + 8*i
+		platform, err := d.u16(record)
+		if err != nil {
+			return nil, err
+		}
+		encoding, err := d.u16(record + 2)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := d.u32(record + 4)
+		if err != nil {
+			return nil, err
+		}
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+		switch format, _ := d.u16(cmap + offset); format {
+		case 4:
+			format4 = cmap + offset
+		case 12:
+			format12 = cmap + offset
+		}
+	}
+
+	switch {
+	case format12 >= 0:
+		return format12Coverage(d, format12)
+	case format4 >= 0:
+		return format4Coverage(d, format4)
+	}
+	return nil, errors.New("font has no Unicode cmap subtable")
+}
+
+// findTable returns the offset of the table called tag.
+func findTable(d fontData, tag string) (int, error) {
+	count, err := d.u16(4)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < count; i++ {
+		record := 12 + 16*i
+		if record+16 > len(d) {
+			return 0, errBadFont
+		}
+		if string(d[record:record+4]) == tag {
+			return d.u32(record + 8)
+		}
+	}
+	return 0, errors.New("font has no " + tag + " table")
+}
+
+func format4Coverage(d fontData, table int) (map[rune]bool, error) {
+	segCountX2, err := d.u16(table + 6)
+	if err != nil {
+		return nil, err
+	}
+	endCodes := table + 14
+	startCodes := endCodes + segCountX2 + 2
+	idDeltas := startCodes + segCountX2
+	idRangeOffsets := idDeltas + segCountX2
+
+	coverage := map[rune]bool{}
+	for seg := 0; seg < segCountX2/2; seg++ {
+		end, err := d.u16(endCodes + 2*seg)
+		if err != nil {
+			return nil, err
+		}
+		start, err := d.u16(startCodes + 2*seg)
+		if err != nil {
+			return nil, err
+		}
+		delta, err := d.u16(idDeltas + 2*seg)
+		if err != nil {
+			return nil, err
+		}
+		rangeOffsetAt := idRangeOffsets + 2*seg
+		rangeOffset, err := d.u16(rangeOffsetAt)
+		if err != nil {
+			return nil, err
+		}
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			glyph := (c + delta) & 0xFFFF
+			if rangeOffset != 0 {
+				glyph, err = d.u16(rangeOffsetAt + rangeOffset + 2*(c-start))
+				if err != nil {
+					return nil, err
+				}
+				if glyph != 0 {
+					glyph = (glyph + delta) & 0xFFFF
+				}
+			}
+			if glyph != 0 {
+				coverage[rune(c)] = true
+			}
+		}
+	}
+	return coverage, nil
+}
+
+func format12Coverage(d fontData, table int) (map[rune]bool, error) {
+	groups, err := d.u32(table + 12)
+	if err != nil {
+		return nil, err
+	}
+	coverage := map[rune]bool{}
+	for i := 0; i < groups; i++ {
+		group := table + 16 + 12*i
+		start, err := d.u32(group)
+		if err != nil {
+			return nil, err
+		}
+		end, err := d.u32(group + 4)
+		if err != nil {
+			return nil, err
+		}
+		startGlyph, err := d.u32(group + 8)
+		if err != nil {
+			return nil, err
+		}
+		if end < start || end > 0x10FFFF {
+			return nil, errBadFont
+		}
+		for c := start; c <= end; c++ {
+			if startGlyph+(c-start) != 0 {
+				coverage[rune(c)] = true
+			}
+		}
+	}
+	return coverage, nil
+}
@@ -0,0 +1,31 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlyphCoverage(t *testing.T) {
+	for _, font := range pdfFonts {
+		coverage, err := glyphCoverage(font.data)
+		require.NoError(t, err, font.family+font.style)
+
+		for _, c := range "Aé€Жλ→─■" {
+			assert.True(t, coverage[c], "%s %q should have %q", font.family, font.style, c)
+		}
+		for _, c := range "日א" {
+			assert.False(t, coverage[c], "%s %q should not have %q", font.family, font.style, c)
+		}
+	}
+
+	_, err := glyphCoverage([]byte("not a font"))
+	assert.Error(t, err)
+}
+
+func TestLookupPDFFont(t *testing.T) {
+	assert.Equal(t, "BI", lookupPDFFont(pdfSans, "BI").style)
+	assert.Equal(t, "", lookupPDFFont(pdfMono, "B").style)
+	assert.Nil(t, lookupPDFFont("Helvetica", ""))
+}
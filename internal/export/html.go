@@ -0,0 +1,83 @@
+package export
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// htmlExporter writes notes as single HTML files. The stylesheet is inlined
+// and attachments shown as images are embedded as data URIs, so the file can
+// be opened or sent on its own.
+type htmlExporter struct{}
+
+func (htmlExporter) Extension() string { return ".html" }
+
+func (htmlExporter) Export(w io.Writer, note *Note) error {
+	md := newMarkdown()
+	doc, source := note.parse(md)
+
+	var unwrap []ast.Node
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Image:
+			if _, _, ok := notesLinkTarget(string(n.Destination)); !ok {
+				return ast.WalkContinue, nil
+			}
+			if dataURI, ok := note.attachmentDataURI(string(n.Destination)); ok {
+				n.Destination = []byte(dataURI)
+			} else {
+				unwrap = append(unwrap, n)
+			}
+		case *ast.Link:
+			// Other notes and attachments are not part of the file, so links
+			// to them are reduced to their text.
+			if _, _, ok := notesLinkTarget(string(n.Destination)); ok {
+				unwrap = append(unwrap, n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	unwrapNodes(unwrap)
+
+	content, err := renderHTML(md, doc, source)
+	if err != nil {
+		return err
+	}
+	style, err := siteFS.ReadFile("site/style.css")
+	if err != nil {
+		return err
+	}
+	return siteTemplates.ExecuteTemplate(w, "document.html", struct {
+		Title   string
+		Tags    []string
+		Style   template.CSS
+		Content template.HTML
+	}{
+		Title:   note.Title,
+		Tags:    note.Tags,
+		Style:   template.CSS(style),
+		Content: content,
+	})
+}
+
+// attachmentDataURI returns the file a /notes/ link points to as a data URI.
+func (n *Note) attachmentDataURI(destination string) (string, bool) {
+	data, target, ok := n.readAttachment(destination)
+	if !ok {
+		return "", false
+	}
+	mimeType := mime.TypeByExtension(path.Ext(target))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), true
+}
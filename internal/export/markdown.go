@@ -0,0 +1,156 @@
+package export
+
+import (
+	"bytes"
+	"html/template"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	gmutil "github.com/yuin/goldmark/util"
+)
+
+// codeBlockIDRegex matches the id the editor writes into a code block's info
+// string, e.g. ```python id="...".
+var codeBlockIDRegex = regexp.MustCompile(`\bid="([^"]*)"`)
+
+// codeResultAttribute carries a code block's saved result from parsing to the
+// renderers.
+var codeResultAttribute = []byte("data-bytebook-result")
+
+// newMarkdown returns the goldmark converter used for exported notes. Raw HTML
+// in notes stays escaped; code results are trusted because Bytebook wrote
+// them.
+func newMarkdown() goldmark.Markdown {
+	return goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(gmutil.Prioritized(codeBlockRenderer{}, 100)),
+		),
+	)
+}
+
+// codeBlockRenderer renders fenced code blocks followed by the results saved
+// in the note's sidecar, unless the results were hidden in the editor.
+type codeBlockRenderer struct{}
+
+func (codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, renderCodeBlock)
+}
+
+func renderCodeBlock(w gmutil.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+
+	_, _ = w.WriteString(`<div class="code-block"><pre><code`)
+	if language := n.Language(source); language != nil {
+		_, _ = w.WriteString(` class="language-` + template.HTMLEscapeString(string(language)) + `"`)
+	}
+	_ = w.WriteByte('>')
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		_, _ = w.Write(gmutil.EscapeHTML(line.Value(source)))
+	}
+	_, _ = w.WriteString("</code></pre>")
+	if result, ok := codeResult(n); ok {
+		_, _ = w.WriteString(`<div class="code-result">`)
+		_, _ = w.WriteString(result)
+		_, _ = w.WriteString("</div>")
+	}
+	_, _ = w.WriteString("</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// codeResult returns the result attached to a code block by parse.
+func codeResult(n *ast.FencedCodeBlock) (string, bool) {
+	result, ok := n.AttributeString(string(codeResultAttribute))
+	if !ok {
+		return "", false
+	}
+	return string(result.([]byte)), true
+}
+
+// parse parses the note's markdown and pairs each code block with its
+// visible result.
+func (n *Note) parse(md goldmark.Markdown) (ast.Node, []byte) {
+	source := []byte(n.Markdown)
+	doc := md.Parser().Parse(text.NewReader(source))
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		codeBlock, ok := node.(*ast.FencedCodeBlock)
+		if !entering || !ok || codeBlock.Info == nil {
+			return ast.WalkContinue, nil
+		}
+		match := codeBlockIDRegex.FindSubmatch(codeBlock.Info.Segment.Value(source))
+		if match == nil {
+			return ast.WalkContinue, nil
+		}
+		if result, ok := n.CodeResults[string(match[1])]; ok {
+			codeBlock.SetAttribute(codeResultAttribute, []byte(result))
+		}
+		return ast.WalkContinue, nil
+	})
+	return doc, source
+}
+
+// renderHTML renders a parsed note's body.
+func renderHTML(md goldmark.Markdown, doc ast.Node, source []byte) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+// notesLinkTarget decodes a /notes/ link into its target relative to notes/
+// and its fragment. It reports false for other destinations.
+func notesLinkTarget(destination string) (string, string, bool) {
+	if !strings.HasPrefix(destination, "/notes/") {
+		return "", "", false
+	}
+	linkPath, fragment, _ := strings.Cut(destination, "#")
+	linkPath, _, _ = strings.Cut(linkPath, "?")
+	decoded, err := url.PathUnescape(strings.TrimPrefix(linkPath, "/notes/"))
+	if err != nil {
+		return "", "", false
+	}
+	return path.Clean(decoded), fragment, true
+}
+
+// unwrapNodes replaces links with their text and removes images. It is run
+// after a walk so the walk never iterates over a changed parent.
+func unwrapNodes(nodes []ast.Node) {
+	for _, node := range nodes {
+		parent := node.Parent()
+		if _, isImage := node.(*ast.Image); !isImage {
+			for child := node.FirstChild(); child != nil; {
+				next := child.NextSibling()
+				parent.InsertBefore(parent, node, child)
+				child = next
+			}
+		}
+		parent.RemoveChild(parent, node)
+	}
+}
+
+// relativeSitePath returns the URL of the site path target as seen from a
+// page in fromDir, with each segment escaped.
+func relativeSitePath(fromDir, target string) string {
+	var segments []string
+	if fromDir != "." {
+		for range strings.Split(fromDir, "/") {
+			segments = append(segments, "..")
+		}
+	}
+	for _, segment := range strings.Split(target, "/") {
+		segments = append(segments, url.PathEscape(segment))
+	}
+	return strings.Join(segments, "/")
+}
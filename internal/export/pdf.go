@@ -0,0 +1,293 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfMargin      = 20.0
+	pdfIndent      = 7.0
+	pdfFontSize    = 11.0
+	pdfLineHeight  = 5.5
+	pdfCodeSize    = 9.0
+	pdfCodeHeight  = 4.5
+	pdfBlockSpace  = 2.5
+	pdfPixelsPerMM = 96 / 25.4
+)
+
+// pdfHeadingSizes are the font sizes of headings by level.
+var pdfHeadingSizes = map[int]float64{1: 20, 2: 16, 3: 14, 4: 12, 5: 11, 6: 11}
+
+// pdfMissingGlyph replaces characters the embedded fonts cannot draw.
+const pdfMissingGlyph = "?"
+
+// pdfMissingShown is how many characters without a glyph are listed in a
+// note's export warning.
+const pdfMissingShown = 20
+
+// pdfExporter lays notes out as A4 PDFs with fpdf, so no browser is needed.
+// Text is drawn with the embedded DejaVu fonts; characters they have no glyph
+// for are replaced and listed in the note's warnings.
+type pdfExporter struct{}
+
+func (pdfExporter) Extension() string { return ".pdf" }
+
+func (pdfExporter) Export(w io.Writer, note *Note) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle(note.Title, true)
+	pdf.SetCreator("Bytebook", true)
+	for _, font := range pdfFonts {
+		pdf.AddUTF8FontFromBytes(font.family, font.style, font.data)
+	}
+	pdf.AddPage()
+
+	r := &pdfRenderer{pdf: pdf, missing: map[rune]bool{}}
+	r.heading(note.Title, 22)
+	for _, b := range buildDocument(note) {
+		r.block(b)
+		if pdf.Err() {
+			return pdf.Error()
+		}
+	}
+	if len(r.missing) > 0 {
+		note.warn(missingGlyphsWarning(r.missing))
+	}
+	return pdf.Output(w)
+}
+
+type pdfRenderer struct {
+	pdf    *fpdf.Fpdf
+	font   *pdfFont
+	images int
+	// missing holds the characters that were replaced because the font in
+	// use had no glyph for them.
+	missing map[rune]bool
+}
+
+// setFont selects an embedded font. Underline ("U") and strikethrough ("S")
+// are drawn by fpdf, so only bold and italic pick the font file; the mono
+// family has neither.
+func (r *pdfRenderer) setFont(family, style string, size float64) {
+	face := strings.NewReplacer("U", "", "S", "").Replace(style)
+	if family == pdfMono {
+		style = strings.NewReplacer("B", "", "I", "").Replace(style)
+		face = ""
+	}
+	r.font = lookupPDFFont(family, face)
+	r.pdf.SetFont(family, style, size)
+}
+
+// text replaces the characters the current font has no glyph for. fpdf
+// only draws characters in the Basic Multilingual Plane, so emoji and other
+// characters past U+FFFF are replaced too.
+func (r *pdfRenderer) text(s string) string {
+	var out strings.Builder
+	for _, c := range s {
+		if unicode.IsControl(c) || (c <= 0xFFFF && r.font.has(c)) {
+			out.WriteRune(c)
+			continue
+		}
+		r.missing[c] = true
+		out.WriteString(pdfMissingGlyph)
+	}
+	return out.String()
+}
+
+// missingGlyphsWarning describes the characters a PDF could not show.
+func missingGlyphsWarning(missing map[rune]bool) string {
+	characters := make([]rune, 0, len(missing))
+	for c := range missing {
+		characters = append(characters, c)
+	}
+	sort.Slice(characters, func(i, j int) bool { return characters[i] < characters[j] })
+
+	shown := characters[:min(len(characters), pdfMissingShown)]
+	list := make([]string, len(shown))
+	for i, c := range shown {
+		list[i] = fmt.Sprintf("%c (U+%04X)", c, c)
+	}
+	warning := fmt.Sprintf("%d characters have no glyph in the PDF font and were replaced with %q: %s",
+		len(characters), pdfMissingGlyph, strings.Join(list, ", "))
+	if len(characters) > len(shown) {
+		warning += fmt.Sprintf(" and %d more", len(characters)-len(shown))
+	}
+	return warning
+}
+
+func (r *pdfRenderer) block(b block) {
+	left := pdfMargin + float64(b.indent)*pdfIndent
+	r.pdf.SetLeftMargin(left)
+	r.pdf.SetX(left)
+
+	switch b.kind {
+	case headingBlock:
+		r.pdf.Ln(pdfBlockSpace)
+		r.heading(spansText(b.spans), pdfHeadingSizes[b.level])
+	case paragraphBlock:
+		if b.marker != "" {
+			r.setFont(pdfSans, "", pdfFontSize)
+			r.pdf.SetX(left - pdfIndent + 1)
+			r.pdf.CellFormat(pdfIndent-1, pdfLineHeight, r.text(b.marker), "", 0, "L", false, 0, "")
+		}
+		if b.quote {
+			r.pdf.SetTextColor(90, 90, 90)
+		}
+		r.spans(b.spans)
+		r.pdf.SetTextColor(0, 0, 0)
+		r.pdf.Ln(pdfLineHeight + pdfBlockSpace)
+	case codeBlock:
+		r.setFont(pdfMono, "", pdfCodeSize)
+		r.pdf.SetFillColor(244, 244, 245)
+		r.pdf.MultiCell(r.width(), pdfCodeHeight, r.text(b.text), "", "L", true)
+		r.pdf.Ln(pdfBlockSpace)
+	case resultBlock:
+		r.setFont(pdfMono, "", pdfCodeSize)
+		r.pdf.SetTextColor(60, 60, 60)
+		r.pdf.SetDrawColor(212, 212, 216)
+		r.pdf.MultiCell(r.width(), pdfCodeHeight, r.text(b.text), "L", "L", false)
+		r.pdf.SetTextColor(0, 0, 0)
+		r.pdf.Ln(pdfBlockSpace)
+	case imageBlock:
+		r.image(b.image, left)
+	case tableBlock:
+		r.table(b.rows, left)
+	case ruleBlock:
+		y := r.pdf.GetY() + pdfBlockSpace
+		r.pdf.SetDrawColor(212, 212, 216)
+		r.pdf.Line(left, y, left+r.width(), y)
+		r.pdf.Ln(2 * pdfBlockSpace)
+	}
+}
+
+func (r *pdfRenderer) heading(text string, size float64) {
+	r.setFont(pdfSans, "B", size)
+	r.pdf.MultiCell(r.width(), size*0.45, r.text(text), "", "L", false)
+	r.pdf.Ln(pdfBlockSpace)
+}
+
+// spans writes styled text that wraps at the margins.
+func (r *pdfRenderer) spans(spans []span) {
+	for _, s := range spans {
+		family, style := pdfSans, ""
+		if s.style.code {
+			family = pdfMono
+		}
+		if s.style.bold {
+			style += "B"
+		}
+		if s.style.italic {
+			style += "I"
+		}
+		if s.style.strike {
+			style += "S"
+		}
+		if s.link != "" {
+			style += "U"
+			r.pdf.SetTextColor(37, 99, 235)
+		}
+		r.setFont(family, style, pdfFontSize)
+		if s.link != "" {
+			r.pdf.WriteLinkString(pdfLineHeight, r.text(s.text), s.link)
+			r.pdf.SetTextColor(0, 0, 0)
+		} else {
+			r.pdf.Write(pdfLineHeight, r.text(s.text))
+		}
+	}
+}
+
+// image draws an image at its natural size, shrunk to fit the page.
+func (r *pdfRenderer) image(img *docImage, left float64) {
+	r.images++
+	name := fmt.Sprintf("image%d", r.images)
+	options := fpdf.ImageOptions{ImageType: img.format, ReadDpi: false}
+	r.pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(img.data))
+
+	_, pageHeight := r.pdf.GetPageSize()
+	maxHeight := pageHeight - 2*pdfMargin
+	width := float64(img.width) / pdfPixelsPerMM
+	height := float64(img.height) / pdfPixelsPerMM
+	if width > r.width() {
+		height *= r.width() / width
+		width = r.width()
+	}
+	if height > maxHeight {
+		width *= maxHeight / height
+		height = maxHeight
+	}
+
+	if r.pdf.GetY()+height > pageHeight-pdfMargin {
+		r.pdf.AddPage()
+	}
+	y := r.pdf.GetY()
+	r.pdf.ImageOptions(name, left, y, width, height, false, options, 0, "")
+	r.pdf.SetY(y + height + pdfBlockSpace)
+}
+
+// table draws a grid with equal columns; rows grow to fit wrapped text.
+func (r *pdfRenderer) table(rows [][][]span, left float64) {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return
+	}
+	columnWidth := r.width() / float64(columns)
+	_, pageHeight := r.pdf.GetPageSize()
+	r.pdf.SetDrawColor(212, 212, 216)
+
+	for i, row := range rows {
+		style := ""
+		if i == 0 {
+			style = "B"
+		}
+		r.setFont(pdfSans, style, pdfFontSize)
+
+		cells := make([]string, columns)
+		lines := 1
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = r.text(spansText(row[j]))
+			}
+			lines = max(lines, len(r.pdf.SplitText(cells[j], columnWidth)))
+		}
+		height := float64(lines) * pdfLineHeight
+
+		if r.pdf.GetY()+height > pageHeight-pdfMargin {
+			r.pdf.AddPage()
+		}
+		y := r.pdf.GetY()
+		for j, cell := range cells {
+			x := left + float64(j)*columnWidth
+			r.pdf.Rect(x, y, columnWidth, height, "D")
+			r.pdf.SetXY(x, y)
+			r.pdf.MultiCell(columnWidth, pdfLineHeight, cell, "", "L", false)
+		}
+		r.pdf.SetXY(left, y+height)
+	}
+	r.pdf.Ln(pdfBlockSpace)
+}
+
+// width is the space between the current left margin and the right margin.
+func (r *pdfRenderer) width() float64 {
+	pageWidth, _ := r.pdf.GetPageSize()
+	left, _, right, _ := r.pdf.GetMargins()
+	return pageWidth - left - right
+}
+
+func spansText(spans []span) string {
+	var text strings.Builder
+	for _, s := range spans {
+		text.WriteString(s.text)
+	}
+	return text.String()
+}
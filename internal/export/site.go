@@ -0,0 +1,481 @@
+package export
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/notes"
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+)
+
+// siteAssetsDir holds the site's stylesheet, search script and search index.
+// It is reserved at the site's root along with index.html.
+const siteAssetsDir = "_bytebook"
+
+var (
+	//go:embed site
+	siteFS embed.FS
+
+	siteTemplates = template.Must(template.ParseFS(siteFS, "site/*.html"))
+
+	errDestinationNotEmpty = errors.New("the destination folder is not empty")
+	errDestinationInVault  = errors.New("the destination folder cannot be inside the notes folder")
+)
+
+// UnresolvedLink is a /notes/ link in an exported note whose target is not
+// part of the export. Its text is kept, but it no longer links anywhere.
+type UnresolvedLink struct {
+	Note string `json:"note"`
+	Link string `json:"link"`
+}
+
+// SiteReport summarises a static site export.
+type SiteReport struct {
+	Destination     string           `json:"destination"`
+	Notes           int              `json:"notes"`
+	Attachments     int              `json:"attachments"`
+	UnresolvedLinks []UnresolvedLink `json:"unresolvedLinks"`
+}
+
+// siteFile is a file being exported.
+type siteFile struct {
+	// relPath is relative to notes/, as used in /notes/ links.
+	relPath string
+	absPath string
+	// outPath is the slash-separated path inside the site.
+	outPath string
+}
+
+// sitePage is a rendered note as seen by the templates.
+type sitePage struct {
+	Title     string
+	Folder    string
+	URL       string
+	Tags      []string
+	Backlinks []sitePageLink
+	Content   template.HTML
+	Root      string
+}
+
+type sitePageLink struct {
+	Title string
+	URL   string
+}
+
+type siteTag struct {
+	Name  string
+	Notes []sitePageLink
+}
+
+// searchEntry is one note in the site's search index.
+type searchEntry struct {
+	Title  string   `json:"title"`
+	Folder string   `json:"folder"`
+	URL    string   `json:"url"`
+	Tags   []string `json:"tags"`
+	Text   string   `json:"text"`
+}
+
+type siteBuilder struct {
+	projectPath    string
+	destination    string
+	markdown       goldmark.Markdown
+	files          []*siteFile
+	filesByRelPath map[string]*siteFile
+	taken          map[string]bool
+}
+
+// ExportSite writes folder, relative to notes/, to destination as a static
+// website. Pass "" to export the whole vault, linked folders included. Notes
+// become HTML pages with their code results, attachments are copied, links
+// between exported files become relative links, and an index page lists every
+// note with its tags and backlinks and searches a prebuilt JSON index.
+//
+// destination must be empty or missing, and outside the vault. The search
+// index is fetched at runtime, so search needs the site to be served over
+// HTTP rather than opened from disk.
+func ExportSite(projectPath, folder, destination string) (SiteReport, error) {
+	report := SiteReport{Destination: destination, UnresolvedLinks: []UnresolvedLink{}}
+
+	if err := checkSiteDestination(projectPath, destination); err != nil {
+		return report, err
+	}
+
+	builder := &siteBuilder{
+		projectPath:    projectPath,
+		destination:    destination,
+		markdown:       newMarkdown(),
+		filesByRelPath: map[string]*siteFile{},
+		taken:          map[string]bool{"index.html": true, siteAssetsDir: true},
+	}
+	if err := builder.collect(folder); err != nil {
+		return report, err
+	}
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return report, err
+	}
+
+	parsed := map[*siteFile]*parsedNote{}
+	tagsByFile := map[*siteFile][]string{}
+	var noteFiles []*siteFile
+	for _, file := range builder.files {
+		if path.Ext(file.relPath) != ".md" {
+			if err := builder.copyAttachment(file); err != nil {
+				return report, err
+			}
+			report.Attachments++
+			continue
+		}
+
+		loaded, err := LoadNote(builder.projectPath, file.relPath)
+		if err != nil {
+			return report, err
+		}
+		note, unresolved := builder.parseNote(file, loaded)
+		for _, link := range unresolved {
+			report.UnresolvedLinks = append(report.UnresolvedLinks, UnresolvedLink{Note: file.relPath, Link: link})
+		}
+		parsed[file] = note
+		tagsByFile[file] = loaded.Tags
+		noteFiles = append(noteFiles, file)
+	}
+
+	backlinks := map[string][]*siteFile{}
+	for _, file := range noteFiles {
+		for _, target := range util.SliceToSet(parsed[file].links).Elements() {
+			if target != file.relPath {
+				backlinks[target] = append(backlinks[target], file)
+			}
+		}
+	}
+
+	pages := make([]sitePage, 0, len(noteFiles))
+	search := make([]searchEntry, 0, len(noteFiles))
+	for _, file := range noteFiles {
+		content, err := renderHTML(builder.markdown, parsed[file].doc, parsed[file].source)
+		if err != nil {
+			return report, fmt.Errorf("could not render %s: %w", file.relPath, err)
+		}
+		folder := path.Dir(file.relPath)
+		if folder == "." {
+			folder = ""
+		}
+		page := sitePage{
+			Title:   noteTitle(file),
+			Folder:  folder,
+			URL:     relativeSitePath(".", file.outPath),
+			Tags:    tagsByFile[file],
+			Content: content,
+			Root:    siteRoot(file.outPath),
+		}
+		sortSiteFiles(backlinks[file.relPath])
+		for _, source := range backlinks[file.relPath] {
+			page.Backlinks = append(page.Backlinks, sitePageLink{
+				Title: noteTitle(source),
+				URL:   relativeSitePath(path.Dir(file.outPath), source.outPath),
+			})
+		}
+		if err := builder.writeTemplate(file.outPath, "note.html", page); err != nil {
+			return report, err
+		}
+
+		pages = append(pages, page)
+		search = append(search, searchEntry{
+			Title:  page.Title,
+			Folder: page.Folder,
+			URL:    page.URL,
+			Tags:   page.Tags,
+			Text:   notes.GetTextContent(string(parsed[file].source)),
+		})
+	}
+	report.Notes = len(pages)
+
+	if err := builder.writeIndex(pages, search); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// parsedNote is a note parsed and rewritten for the site, ready to be
+// rendered once every page's backlinks are known.
+type parsedNote struct {
+	source []byte
+	doc    ast.Node
+	// links are the notes this note links to, relative to notes/.
+	links []string
+}
+
+// parseNote parses a note and rewrites it for the site: links and images
+// pointing into the export become relative links to the exported pages and
+// files, and links leaving it are reduced to their text. It returns the
+// /notes/ links that could not be resolved.
+func (s *siteBuilder) parseNote(file *siteFile, loaded *Note) (*parsedNote, []string) {
+	doc, source := loaded.parse(s.markdown)
+	note := &parsedNote{source: source, doc: doc}
+
+	var unresolved []string
+	var unwrap []ast.Node
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var destination *[]byte
+		switch n := node.(type) {
+		case *ast.Link:
+			destination = &n.Destination
+		case *ast.Image:
+			destination = &n.Destination
+		default:
+			return ast.WalkContinue, nil
+		}
+
+		rewritten, target, ok := s.rewriteLink(file, string(*destination))
+		if !ok {
+			unresolved = append(unresolved, string(*destination))
+			unwrap = append(unwrap, node)
+			return ast.WalkContinue, nil
+		}
+		*destination = []byte(rewritten)
+		if _, isLink := node.(*ast.Link); isLink && path.Ext(target) == ".md" {
+			note.links = append(note.links, target)
+		}
+		return ast.WalkContinue, nil
+	})
+	unwrapNodes(unwrap)
+	return note, unresolved
+}
+
+// rewriteLink maps a link destination found in file to its place in the
+// site. Destinations outside /notes/ are returned unchanged. For /notes/
+// links it also returns the target relative to notes/, and reports false when
+// the target is not part of the export.
+func (s *siteBuilder) rewriteLink(file *siteFile, destination string) (string, string, bool) {
+	if !strings.HasPrefix(destination, "/notes/") {
+		return destination, "", true
+	}
+	target, fragment, ok := notesLinkTarget(destination)
+	if !ok {
+		return "", "", false
+	}
+	targetFile, ok := s.filesByRelPath[target]
+	if !ok {
+		return "", "", false
+	}
+
+	relative := relativeSitePath(path.Dir(file.outPath), targetFile.outPath)
+	if fragment != "" {
+		relative += "#" + fragment
+	}
+	return relative, target, true
+}
+
+// checkSiteDestination rejects destinations that already hold files or that
+// the vault's watcher would pick up.
+func checkSiteDestination(projectPath, destination string) error {
+	absDestination, err := filepath.Abs(destination)
+	if err != nil {
+		return err
+	}
+	if _, ok := config.NotesRelativePath(projectPath, absDestination); ok {
+		return errDestinationInVault
+	}
+
+	entries, err := os.ReadDir(destination)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return errDestinationNotEmpty
+	}
+	return nil
+}
+
+// collect lists the files to export, skipping hidden and ignored files.
+func (s *siteBuilder) collect(folder string) error {
+	folder = strings.Trim(filepath.ToSlash(folder), "/")
+	ignore := notes.NewIgnoreMatcher(s.projectPath)
+
+	type root struct{ absPath, relPath string }
+	var roots []root
+	if folder == "" {
+		roots = append(roots, root{filepath.Join(s.projectPath, "notes"), ""})
+		for _, linked := range config.LinkedFolders(s.projectPath) {
+			roots = append(roots, root{linked.Path, linked.Name})
+		}
+	} else {
+		absPath, err := config.ResolveNotesPath(s.projectPath, folder)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a folder", folder)
+		}
+		roots = append(roots, root{absPath, folder})
+	}
+
+	for _, r := range roots {
+		err := filepath.WalkDir(r.absPath, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if filePath == r.absPath {
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") || ignore.Match(filePath, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+
+			relToRoot, err := filepath.Rel(r.absPath, filePath)
+			if err != nil {
+				return err
+			}
+			relPath := path.Join(r.relPath, filepath.ToSlash(relToRoot))
+			outPath := strings.TrimPrefix(strings.TrimPrefix(relPath, folder), "/")
+			if path.Ext(outPath) == ".md" {
+				outPath = strings.TrimSuffix(outPath, ".md") + ".html"
+			}
+
+			file := &siteFile{relPath: relPath, absPath: filePath, outPath: s.claim(outPath)}
+			s.files = append(s.files, file)
+			s.filesByRelPath[relPath] = file
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claim reserves outPath in the site, numbering it when a page and a file, or
+// a page and the site's own files, would otherwise collide. Paths are
+// compared case-insensitively so the site survives case-insensitive disks.
+func (s *siteBuilder) claim(outPath string) string {
+	if first, rest, ok := strings.Cut(outPath, "/"); ok && strings.EqualFold(first, siteAssetsDir) {
+		outPath = first + " 1/" + rest
+	}
+
+	candidate := outPath
+	ext := path.Ext(outPath)
+	for i := 1; s.taken[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s %d%s", strings.TrimSuffix(outPath, ext), i, ext)
+	}
+	s.taken[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func (s *siteBuilder) copyAttachment(file *siteFile) error {
+	destination := filepath.Join(s.destination, filepath.FromSlash(file.outPath))
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+	return util.CopyFile(file.absPath, destination, false)
+}
+
+func (s *siteBuilder) writeTemplate(outPath, name string, data any) error {
+	destination := filepath.Join(s.destination, filepath.FromSlash(outPath))
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return siteTemplates.ExecuteTemplate(out, name, data)
+}
+
+// writeIndex writes the index page, the search index and the site's static
+// assets.
+func (s *siteBuilder) writeIndex(pages []sitePage, search []searchEntry) error {
+	sort.SliceStable(pages, func(i, j int) bool {
+		return strings.ToLower(pages[i].URL) < strings.ToLower(pages[j].URL)
+	})
+
+	notesByTag := map[string][]sitePageLink{}
+	for _, page := range pages {
+		for _, tag := range page.Tags {
+			notesByTag[tag] = append(notesByTag[tag], sitePageLink{Title: page.Title, URL: page.URL})
+		}
+	}
+	tags := make([]siteTag, 0, len(notesByTag))
+	for name, tagged := range notesByTag {
+		tags = append(tags, siteTag{Name: name, Notes: tagged})
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name) })
+
+	err := s.writeTemplate("index.html", "index.html", struct {
+		Title string
+		Pages []sitePage
+		Tags  []siteTag
+		Root  string
+	}{
+		Title: filepath.Base(s.destination),
+		Pages: pages,
+		Tags:  tags,
+		Root:  "",
+	})
+	if err != nil {
+		return err
+	}
+
+	assetsDir := filepath.Join(s.destination, siteAssetsDir)
+	if err := os.MkdirAll(assetsDir, 0755); err != nil {
+		return err
+	}
+	for _, name := range []string{"style.css", "search.js"} {
+		content, err := siteFS.ReadFile("site/" + name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(assetsDir, name), content, 0644); err != nil {
+			return err
+		}
+	}
+	searchJSON, err := json.Marshal(search)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(assetsDir, "search-index.json"), searchJSON, 0644)
+}
+
+func noteTitle(file *siteFile) string {
+	return strings.TrimSuffix(path.Base(file.relPath), ".md")
+}
+
+// siteRoot returns the relative prefix that leads from the page at outPath
+// back to the site's root.
+func siteRoot(outPath string) string {
+	return strings.Repeat("../", strings.Count(outPath, "/"))
+}
+
+func sortSiteFiles(files []*siteFile) {
+	sort.Slice(files, func(i, j int) bool {
+		return strings.ToLower(files[i].outPath) < strings.ToLower(files[j].outPath)
+	})
+}
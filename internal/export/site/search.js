@@ -0,0 +1,63 @@
+// Searches the index written next to this script. Every word typed must
+// appear in a note's title, folder, tags or text.
+(function () {
+  const input = document.getElementById('search');
+  const results = document.getElementById('search-results');
+  const maxResults = 50;
+  let entries = null;
+
+  function load() {
+    if (!entries) {
+      entries = fetch('_bytebook/search-index.json')
+        .then((response) => response.json())
+        .then((index) =>
+          index.map((entry) => ({
+            entry,
+            haystack: [entry.title, entry.folder, entry.tags.join(' '), entry.text]
+              .join('\n')
+              .toLowerCase(),
+          }))
+        );
+    }
+    return entries;
+  }
+
+  function snippet(text, term) {
+    const at = text.toLowerCase().indexOf(term);
+    if (at === -1) {
+      return text.slice(0, 120);
+    }
+    const start = Math.max(0, at - 40);
+    return (start > 0 ? '…' : '') + text.slice(start, at + 80);
+  }
+
+  input.addEventListener('input', async () => {
+    const terms = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+    results.replaceChildren();
+    results.hidden = terms.length === 0;
+    if (terms.length === 0) {
+      return;
+    }
+
+    const index = await load();
+    const matches = index
+      .filter(({ haystack }) => terms.every((term) => haystack.includes(term)))
+      .slice(0, maxResults);
+
+    for (const { entry } of matches) {
+      const item = document.createElement('li');
+      const link = document.createElement('a');
+      link.href = entry.url;
+      link.textContent = entry.folder ? `${entry.folder}/${entry.title}` : entry.title;
+      const preview = document.createElement('p');
+      preview.textContent = snippet(entry.text, terms[0]);
+      item.append(link, preview);
+      results.append(item);
+    }
+    if (matches.length === 0) {
+      const item = document.createElement('li');
+      item.textContent = 'No notes found';
+      results.append(item);
+    }
+  });
+})();
@@ -0,0 +1,137 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/etesam913/bytebook/internal/notes/sidecar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupExportProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	projectDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "settings"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "settings", "settings.json"), []byte("{}"), 0644))
+	for name, content := range files {
+		filePath := filepath.Join(projectDir, "notes", filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	}
+	return projectDir
+}
+
+func readSiteFile(t *testing.T, siteDir, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(siteDir, filepath.FromSlash(name)))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestExportSite(t *testing.T) {
+	t.Run("renders notes with relative links, attachments and code results", func(t *testing.T) {
+		projectDir := setupExportProject(t, map[string]string{
+			"Docs/Home.md": "---\ntags:\n  - guide\nlastUpdated: 2024-01-01\n---\n" +
+				"# Welcome\n\nSee [the plan](/notes/Docs/Sub/My%20Plan.md#goals) and " +
+				"[elsewhere](/notes/Other/Note.md).\n\n![diagram](/notes/Docs/diagram.png)\n\n" +
+				"```python id=\"block-1\"\nprint(1 < 2)\n```\n",
+			"Docs/Sub/My Plan.md": "Back to [home](/notes/Docs/Home.md).\n",
+			"Docs/diagram.png":    "png",
+			"Docs/.hidden.md":     "hidden",
+			"Other/Note.md":       "outside the export",
+		})
+		homePath := filepath.Join(projectDir, "notes", "Docs", "Home.md")
+		require.NoError(t, sidecar.WriteCodeResults(homePath, sidecar.CodeResults{
+			CodeBlocks: []sidecar.CodeBlock{{CodeBlockID: "block-1", ResultHTML: "<pre>True</pre>"}},
+		}))
+		siteDir := filepath.Join(t.TempDir(), "site")
+
+		report, err := ExportSite(projectDir, "Docs", siteDir)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, report.Notes)
+		assert.Equal(t, 1, report.Attachments)
+		assert.Equal(t, []UnresolvedLink{{Note: "Docs/Home.md", Link: "/notes/Other/Note.md"}}, report.UnresolvedLinks)
+
+		home := readSiteFile(t, siteDir, "Home.html")
+		assert.Contains(t, home, `<a href="Sub/My%20Plan.html#goals">the plan</a>`)
+		assert.Contains(t, home, "and elsewhere.")
+		assert.Contains(t, home, `<img src="diagram.png" alt="diagram">`)
+		assert.Contains(t, home, `<code class="language-python">print(1 &lt; 2)`)
+		assert.Contains(t, home, `<div class="code-result"><pre>True</pre></div>`)
+		assert.Contains(t, home, `href="index.html#tag-guide"`)
+		assert.NotContains(t, home, "lastUpdated")
+		assert.Contains(t, home, `<a href="Sub/My%20Plan.html">My Plan</a>`, "backlinks are listed")
+
+		plan := readSiteFile(t, siteDir, "Sub/My Plan.html")
+		assert.Contains(t, plan, `<a href="../Home.html">home</a>`)
+		assert.Contains(t, plan, `href="../_bytebook/style.css"`)
+
+		assert.Equal(t, "png", readSiteFile(t, siteDir, "diagram.png"))
+		assert.NoFileExists(t, filepath.Join(siteDir, ".hidden.html"))
+
+		index := readSiteFile(t, siteDir, "index.html")
+		assert.Contains(t, index, `<h3 id="tag-guide">#guide</h3>`)
+		assert.Contains(t, index, "linked from 1")
+
+		var search []searchEntry
+		require.NoError(t, json.Unmarshal([]byte(readSiteFile(t, siteDir, "_bytebook/search-index.json")), &search))
+		require.Len(t, search, 2)
+		assert.Equal(t, "Home", search[0].Title)
+		assert.Equal(t, "Docs", search[0].Folder)
+		assert.Equal(t, []string{"guide"}, search[0].Tags)
+		assert.Contains(t, search[0].Text, "Welcome")
+		assert.FileExists(t, filepath.Join(siteDir, "_bytebook", "search.js"))
+	})
+
+	t.Run("hidden code results are left out", func(t *testing.T) {
+		projectDir := setupExportProject(t, map[string]string{
+			"Note.md": "```go id=\"a\"\nfmt.Println()\n```\n",
+		})
+		require.NoError(t, sidecar.WriteCodeResults(filepath.Join(projectDir, "notes", "Note.md"), sidecar.CodeResults{
+			CodeBlocks: []sidecar.CodeBlock{{CodeBlockID: "a", ResultHTML: "<p>secret</p>", AreResultsHidden: true}},
+		}))
+		siteDir := t.TempDir()
+
+		_, err := ExportSite(projectDir, "", siteDir)
+		require.NoError(t, err)
+
+		assert.NotContains(t, readSiteFile(t, siteDir, "Note.html"), "secret")
+	})
+
+	t.Run("notes named like the site's own files are renamed", func(t *testing.T) {
+		projectDir := setupExportProject(t, map[string]string{
+			"index.md":           "Links to [note](/notes/_bytebook/note.md)",
+			"_bytebook/note.md":  "nested",
+			"Readme.md":          "readme",
+			"readme.html":        "<p>attachment</p>",
+			"folder/.secret.txt": "hidden",
+		})
+		siteDir := t.TempDir()
+
+		report, err := ExportSite(projectDir, "", siteDir)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, report.Notes)
+		assert.Contains(t, readSiteFile(t, siteDir, "index 1.html"), `<a href="_bytebook%201/note.html">note</a>`)
+		assert.FileExists(t, filepath.Join(siteDir, "_bytebook 1", "note.html"))
+		assert.FileExists(t, filepath.Join(siteDir, "Readme.html"))
+		assert.FileExists(t, filepath.Join(siteDir, "readme 1.html"))
+		assert.NoFileExists(t, filepath.Join(siteDir, "folder", ".secret.txt"))
+	})
+
+	t.Run("rejects destinations that are not empty or inside the vault", func(t *testing.T) {
+		projectDir := setupExportProject(t, map[string]string{"Note.md": "note"})
+
+		siteDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(siteDir, "existing.txt"), []byte("x"), 0644))
+		_, err := ExportSite(projectDir, "", siteDir)
+		assert.ErrorIs(t, err, errDestinationNotEmpty)
+
+		_, err = ExportSite(projectDir, "", filepath.Join(projectDir, "notes", "site"))
+		assert.ErrorIs(t, err, errDestinationInVault)
+	})
+}
@@ -0,0 +1,189 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// bearMultiWordTagRegex matches Bear's #multi word tags#, which are closed
+// with a second #.
+var bearMultiWordTagRegex = regexp.MustCompile(`(?:^|\s)#([^\s#][^#\n]*[^\s#])#`)
+
+// bearInfo is the part of a TextBundle's info.json that Bear fills in.
+type bearInfo struct {
+	Bear struct {
+		CreationDate     string `json:"creationDate"`
+		ModificationDate string `json:"modificationDate"`
+	} `json:"net.shinyfrog.bear"`
+}
+
+// readBearExport reads notes exported from Bear as TextBundles. sourcePath may
+// be a single .textbundle or .textpack, or a folder or zip archive holding
+// them. Each note lands in the import folder under its bundle's name, with
+// its assets in an attachments folder tagged like the note.
+func readBearExport(sourcePath string, export *externalExport) error {
+	export.wikilinks = true
+
+	if isTextBundle(sourcePath) {
+		info, err := os.Stat(sourcePath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			readBearBundle(os.DirFS(sourcePath), path.Base(filepath.ToSlash(sourcePath)), export)
+			return nil
+		}
+		return readBearTextPack(sourcePath, export)
+	}
+
+	fsys, err := openExternalArchive(sourcePath, export)
+	if err != nil {
+		return err
+	}
+	return fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath != "." && strings.HasPrefix(d.Name(), ".") || d.Name() == "__MACOSX" {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !isTextBundle(filePath) {
+			return nil
+		}
+
+		if d.IsDir() {
+			bundle, err := fs.Sub(fsys, filePath)
+			if err != nil {
+				return err
+			}
+			readBearBundle(bundle, filePath, export)
+			return fs.SkipDir
+		}
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			export.addIssue(filePath, "could not read note: %v", err)
+			return nil
+		}
+		readBearTextPackBytes(content, filePath, export)
+		return nil
+	})
+}
+
+func isTextBundle(filePath string) bool {
+	ext := strings.ToLower(path.Ext(filepath.ToSlash(filePath)))
+	return ext == ".textbundle" || ext == ".textpack"
+}
+
+func readBearTextPack(sourcePath string, export *externalExport) error {
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+	readBearTextPackBytes(content, filepath.Base(sourcePath), export)
+	return nil
+}
+
+// readBearTextPackBytes reads a zipped TextBundle. The bundle's files are
+// either at the archive's root or in a single .textbundle folder inside it.
+func readBearTextPackBytes(content []byte, source string, export *externalExport) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		export.addIssue(source, "could not open TextPack: %v", err)
+		return
+	}
+
+	var bundle fs.FS = archive
+	if _, err := fs.Stat(archive, "info.json"); err != nil {
+		entries, _ := fs.ReadDir(archive, ".")
+		for _, entry := range entries {
+			if entry.IsDir() && isTextBundle(entry.Name()) {
+				bundle, _ = fs.Sub(archive, entry.Name())
+				break
+			}
+		}
+	}
+	readBearBundle(bundle, source, export)
+}
+
+// readBearBundle reads one TextBundle. source is the bundle's path inside the
+// export and gives the note its name.
+func readBearBundle(bundle fs.FS, source string, export *externalExport) {
+	var textName string
+	for _, name := range []string{"text.md", "text.markdown", "text.txt"} {
+		if _, err := fs.Stat(bundle, name); err == nil {
+			textName = name
+			break
+		}
+	}
+	if textName == "" {
+		export.addIssue(source, "the bundle has no text file")
+		return
+	}
+
+	content, err := fs.ReadFile(bundle, textName)
+	if err != nil {
+		export.addIssue(source, "could not read note: %v", err)
+		return
+	}
+	textInfo, err := fs.Stat(bundle, textName)
+	if err != nil {
+		export.addIssue(source, "could not read note: %v", err)
+		return
+	}
+
+	title := strings.TrimSuffix(path.Base(source), path.Ext(source))
+	markdown := string(content)
+	note := &externalNote{
+		source:   path.Join(source, textName),
+		path:     title + ".md",
+		markdown: markdown,
+		updated:  textInfo.ModTime(),
+	}
+	for _, match := range bearMultiWordTagRegex.FindAllStringSubmatch(markdown, -1) {
+		note.tags = append(note.tags, match[1])
+	}
+	note.tags = append(note.tags, inlineExternalTags(bearMultiWordTagRegex.ReplaceAllString(markdown, " "))...)
+
+	if infoJSON, err := fs.ReadFile(bundle, "info.json"); err == nil {
+		var info bearInfo
+		if err := json.Unmarshal(infoJSON, &info); err == nil {
+			if created, ok := externalTime(info.Bear.CreationDate); ok {
+				note.created = created
+			}
+			if updated, ok := externalTime(info.Bear.ModificationDate); ok {
+				note.updated = updated
+			}
+		}
+	}
+	export.notes = append(export.notes, note)
+
+	_ = fs.WalkDir(bundle, "assets", func(assetPath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			export.addIssue(path.Join(source, assetPath), "could not read attachment: %v", err)
+			return nil
+		}
+		export.attachments = append(export.attachments, &externalAttachment{
+			source:  path.Join(source, assetPath),
+			path:    path.Join("attachments", path.Base(assetPath)),
+			tags:    note.tags,
+			updated: info.ModTime(),
+			open:    func() (io.ReadCloser, error) { return bundle.Open(assetPath) },
+		})
+		return nil
+	})
+}
@@ -0,0 +1,444 @@
+package ingest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+// enexNote is one <note> of an Evernote export.
+type enexNote struct {
+	Title     string         `xml:"title"`
+	Content   string         `xml:"content"`
+	Created   string         `xml:"created"`
+	Updated   string         `xml:"updated"`
+	Tags      []string       `xml:"tag"`
+	Resources []enexResource `xml:"resource"`
+}
+
+// enexResource is a file attached to a note, referenced from the content by
+// the MD5 hash of its data.
+type enexResource struct {
+	Data     string `xml:"data"`
+	Mime     string `xml:"mime"`
+	FileName string `xml:"resource-attributes>file-name"`
+}
+
+var (
+	markdownBlankLinesRegex    = regexp.MustCompile(`\n{3,}`)
+	markdownTrailingSpaceRegex = regexp.MustCompile(`(?m)[ \t]+$`)
+	enmlWhitespaceRegex        = regexp.MustCompile(`[\s\x{00a0}]+`)
+)
+
+// readEvernoteExport reads an Evernote .enex file, or a folder of them with
+// one file per notebook. Notebooks from a folder become subfolders. Note
+// content is converted from ENML to markdown and attachments are stored in
+// an attachments folder, tagged like their note.
+func readEvernoteExport(sourcePath string, export *externalExport) error {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return readEnexFile(sourcePath, "", util.Set[string]{}, export)
+	}
+
+	entries, err := os.ReadDir(sourcePath)
+	if err != nil {
+		return err
+	}
+	seen := util.Set[string]{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.ToLower(filepath.Ext(entry.Name())) != ".enex" {
+			continue
+		}
+		notebook := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if err := readEnexFile(filepath.Join(sourcePath, entry.Name()), notebook, seen, export); err != nil {
+			export.addIssue(entry.Name(), "could not read notebook: %v", err)
+		}
+	}
+	return nil
+}
+
+// readEnexFile streams the notes out of one .enex file into notebook, a
+// folder relative to the import folder. seen holds the resources already
+// added.
+func readEnexFile(enexPath string, notebook string, seen util.Set[string], export *externalExport) error {
+	file, err := os.Open(enexPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := xml.NewDecoder(file)
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+
+		var note enexNote
+		if err := decoder.DecodeElement(&note, &start); err != nil {
+			return err
+		}
+		addEnexNote(note, filepath.Base(enexPath), notebook, seen, export)
+	}
+}
+
+// addEnexNote adds one note and its resources to export. seen holds the
+// resources already added.
+func addEnexNote(note enexNote, enexName string, notebook string, seen util.Set[string], export *externalExport) {
+	title := strings.TrimSpace(note.Title)
+	if title == "" {
+		title = "Untitled"
+	}
+	title = strings.Join(strings.Fields(util.CleanFileName(title)), " ")
+	source := path.Join(enexName, title+".md")
+
+	// Resources are stored under their hash, so one shared by several notes
+	// is only written once.
+	media := map[string]enexMedia{}
+	for _, resource := range note.Resources {
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(resource.Data), ""))
+		if err != nil {
+			export.addIssue(source, "could not decode attachment %s: %v", resource.FileName, err)
+			continue
+		}
+		sum := md5.Sum(data)
+		hash := hex.EncodeToString(sum[:])
+		mediaSource := path.Join(enexName, "resources", hash)
+		media[hash] = enexMedia{source: mediaSource, mime: resource.Mime, name: enexResourceName(resource)}
+
+		if !seen.Has(mediaSource) {
+			seen.Add(mediaSource)
+			export.attachments = append(export.attachments, &externalAttachment{
+				source: mediaSource,
+				path:   path.Join(notebook, "attachments", enexResourceName(resource)),
+				tags:   note.Tags,
+				open:   func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil },
+			})
+		}
+	}
+
+	markdown, unsupported := enmlToMarkdown(note.Content, media)
+	for _, reason := range unsupported {
+		export.addIssue(source, "%s", reason)
+	}
+
+	converted := &externalNote{
+		source:   source,
+		path:     path.Join(notebook, title+".md"),
+		markdown: markdown,
+		tags:     note.Tags,
+	}
+	if created, ok := externalTime(note.Created); ok {
+		converted.created = created
+	}
+	if updated, ok := externalTime(note.Updated); ok {
+		converted.updated = updated
+	}
+	export.notes = append(export.notes, converted)
+}
+
+// enexResourceName returns the resource's file name, or a name derived from
+// its MIME type when Evernote did not keep one.
+func enexResourceName(resource enexResource) string {
+	if name := strings.TrimSpace(resource.FileName); name != "" {
+		return name
+	}
+	name := "attachment"
+	if extensions, err := mime.ExtensionsByType(resource.Mime); err == nil && len(extensions) > 0 {
+		name += extensions[0]
+	}
+	return name
+}
+
+type enexMedia struct {
+	source string
+	mime   string
+	name   string
+}
+
+// enmlNode is an element or text node of a note's ENML content.
+type enmlNode struct {
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*enmlNode
+}
+
+// enmlRenderer converts ENML to markdown and records what it had to drop.
+type enmlRenderer struct {
+	media       map[string]enexMedia
+	unsupported []string
+}
+
+// enmlToMarkdown converts a note's ENML content to markdown. Attachments are
+// linked by their source in media so they resolve with the rest of the
+// export's links. It also returns a description of each part of the note that
+// could not be converted.
+func enmlToMarkdown(content string, media map[string]enexMedia) (string, []string) {
+	root, err := parseENML(content)
+	if err != nil {
+		return "", []string{fmt.Sprintf("could not read the note's content: %v", err)}
+	}
+
+	r := &enmlRenderer{media: media}
+	markdown := r.renderBlock(root, 0)
+	markdown = markdownTrailingSpaceRegex.ReplaceAllString(markdown, "")
+	markdown = markdownBlankLinesRegex.ReplaceAllString(markdown, "\n\n")
+	return strings.TrimSpace(markdown) + "\n", r.unsupported
+}
+
+func parseENML(content string) (*enmlNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	root := &enmlNode{name: "root"}
+	stack := []*enmlNode{root}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &enmlNode{name: strings.ToLower(t.Name.Local), attrs: map[string]string{}}
+			for _, attr := range t.Attr {
+				node.attrs[strings.ToLower(attr.Name.Local)] = attr.Value
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.children = append(parent.children, &enmlNode{text: string(t)})
+		}
+	}
+}
+
+// renderBlock renders node's children. Consecutive blocks share the newline
+// between them, so a run of divs reads as consecutive lines. depth is the
+// list nesting level.
+func (r *enmlRenderer) renderBlock(node *enmlNode, depth int) string {
+	var b strings.Builder
+	for _, child := range node.children {
+		rendered := r.render(child, depth)
+		if strings.HasSuffix(b.String(), "\n") {
+			rendered = strings.TrimPrefix(rendered, "\n")
+		}
+		if strings.HasSuffix(b.String(), " ") {
+			rendered = strings.TrimPrefix(rendered, " ")
+		}
+		b.WriteString(rendered)
+	}
+	return b.String()
+}
+
+func (r *enmlRenderer) render(node *enmlNode, depth int) string {
+	if node.name == "" {
+		return enmlWhitespaceRegex.ReplaceAllString(node.text, " ")
+	}
+
+	switch node.name {
+	case "en-note", "root", "span", "font", "u", "sup", "sub", "small", "big", "center", "section", "article":
+		return r.renderBlock(node, depth)
+	case "div":
+		if strings.Contains(node.attrs["style"], "-en-codeblock") {
+			return "\n\n```\n" + strings.TrimSpace(enmlText(node)) + "\n```\n\n"
+		}
+		return "\n" + strings.TrimSpace(r.renderBlock(node, depth)) + "\n"
+	case "p":
+		return "\n\n" + strings.TrimSpace(r.renderBlock(node, depth)) + "\n\n"
+	case "br":
+		return "\n"
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(node.name[1:])
+		return "\n\n" + strings.Repeat("#", level) + " " + strings.TrimSpace(r.renderBlock(node, depth)) + "\n\n"
+	case "b", "strong":
+		return wrapInline(r.renderBlock(node, depth), "**")
+	case "i", "em":
+		return wrapInline(r.renderBlock(node, depth), "_")
+	case "s", "strike", "del":
+		return wrapInline(r.renderBlock(node, depth), "~~")
+	case "code":
+		return wrapInline(enmlText(node), "`")
+	case "pre":
+		return "\n\n```\n" + strings.TrimSpace(enmlText(node)) + "\n```\n\n"
+	case "hr":
+		return "\n\n---\n\n"
+	case "a":
+		text := strings.TrimSpace(r.renderBlock(node, depth))
+		href := strings.TrimSpace(node.attrs["href"])
+		if href == "" {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return fmt.Sprintf("[%s](%s)", text, href)
+	case "img":
+		if src := node.attrs["src"]; src != "" {
+			return fmt.Sprintf("![%s](%s)", node.attrs["alt"], src)
+		}
+		return ""
+	case "ul", "ol":
+		return r.renderList(node, depth)
+	case "blockquote":
+		inner := strings.TrimSpace(r.renderBlock(node, depth))
+		return "\n\n> " + strings.ReplaceAll(inner, "\n", "\n> ") + "\n\n"
+	case "table":
+		return r.renderTable(node)
+	case "en-todo":
+		if node.attrs["checked"] == "true" {
+			return "- [x] "
+		}
+		return "- [ ] "
+	case "en-media":
+		return r.renderMedia(node)
+	case "en-crypt":
+		r.unsupported = append(r.unsupported, "encrypted text was left out")
+		return ""
+	default:
+		return r.renderBlock(node, depth)
+	}
+}
+
+func (r *enmlRenderer) renderList(node *enmlNode, depth int) string {
+	var b strings.Builder
+	b.WriteString("\n")
+	number := 1
+	for _, child := range node.children {
+		if child.name != "li" {
+			continue
+		}
+		marker := "- "
+		if node.name == "ol" {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		item := strings.TrimSpace(r.renderBlock(child, depth+1))
+		// A checkbox already starts its own list item.
+		item = strings.TrimPrefix(item, "- ")
+		b.WriteString(strings.Repeat("   ", depth) + marker + item + "\n")
+	}
+	if depth == 0 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (r *enmlRenderer) renderTable(node *enmlNode) string {
+	var rows [][]string
+	var collect func(*enmlNode)
+	collect = func(n *enmlNode) {
+		for _, child := range n.children {
+			if child.name != "tr" {
+				collect(child)
+				continue
+			}
+			var cells []string
+			for _, cell := range child.children {
+				if cell.name == "td" || cell.name == "th" {
+					cells = append(cells, markdownTableCell(r.renderBlock(cell, 0)))
+				}
+			}
+			rows = append(rows, cells)
+		}
+	}
+	collect(node)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	var b strings.Builder
+	b.WriteString("\n\n")
+	for i, row := range rows {
+		cells := make([]string, columns)
+		copy(cells, row)
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString(strings.Repeat("| --- ", columns) + "|\n")
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func (r *enmlRenderer) renderMedia(node *enmlNode) string {
+	media, ok := r.media[strings.ToLower(node.attrs["hash"])]
+	if !ok {
+		r.unsupported = append(r.unsupported, "an attachment is missing from the export")
+		return ""
+	}
+	// Angle brackets keep spaces in the notebook's name inside the link.
+	if strings.HasPrefix(media.mime, "image/") {
+		return fmt.Sprintf("![%s](<%s>)", media.name, media.source)
+	}
+	return fmt.Sprintf("[%s](<%s>)", media.name, media.source)
+}
+
+// enmlText returns the raw text inside node, keeping its whitespace.
+func enmlText(node *enmlNode) string {
+	if node.name == "" {
+		return node.text
+	}
+	if node.name == "br" {
+		return "\n"
+	}
+	var b strings.Builder
+	for _, child := range node.children {
+		b.WriteString(enmlText(child))
+	}
+	if node.name == "div" || node.name == "p" {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// wrapInline wraps text in a markdown marker, keeping surrounding spaces
+// outside the marker so the emphasis still parses.
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+	return leading + marker + trimmed + marker + trailing
+}
@@ -0,0 +1,633 @@
+package ingest
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/notes"
+	"github.com/etesam913/bytebook/internal/notes/sidecar"
+	"github.com/etesam913/bytebook/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// ExternalSource names an app whose export can be imported into the vault.
+type ExternalSource string
+
+const (
+	ExternalSourceObsidian ExternalSource = "obsidian"
+	ExternalSourceNotion   ExternalSource = "notion"
+	ExternalSourceBear     ExternalSource = "bear"
+	ExternalSourceEvernote ExternalSource = "evernote"
+)
+
+// ExternalImportIssue is something in an export that could not be converted
+// faithfully. Path is relative to the export.
+type ExternalImportIssue struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ExternalImportReport summarizes an import from another app. Folder is the
+// new top-level notes folder holding everything that was imported.
+type ExternalImportReport struct {
+	Folder      string                `json:"folder"`
+	Notes       int                   `json:"notes"`
+	Attachments int                   `json:"attachments"`
+	Issues      []ExternalImportIssue `json:"issues"`
+}
+
+// externalNote is a note read from an export, before its links are rewritten.
+type externalNote struct {
+	// source is the note's slash-separated path inside the export. Relative
+	// links in the note resolve against its directory.
+	source string
+	// path is the slash-separated destination relative to the import folder.
+	path        string
+	markdown    string
+	frontmatter map[string]any
+	tags        []string
+	created     time.Time
+	updated     time.Time
+}
+
+// externalAttachment is a non-markdown file read from an export.
+type externalAttachment struct {
+	source  string
+	path    string
+	tags    []string
+	updated time.Time
+	open    func() (io.ReadCloser, error)
+}
+
+// externalExport collects everything a reader found in an export.
+type externalExport struct {
+	notes       []*externalNote
+	attachments []*externalAttachment
+	issues      []ExternalImportIssue
+	// wikilinks marks exports whose notes link to each other by name with
+	// [[...]] as well as with markdown links.
+	wikilinks bool
+	// closers are archives the attachments are read from, closed once the
+	// import is written.
+	closers []io.Closer
+}
+
+func (e *externalExport) close() {
+	for _, closer := range e.closers {
+		if err := closer.Close(); err != nil {
+			log.Printf("Error closing import source: %v", err)
+		}
+	}
+}
+
+func (e *externalExport) addIssue(source string, format string, args ...any) {
+	e.issues = append(e.issues, ExternalImportIssue{
+		Path:   source,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// externalReader reads the export at sourcePath into export. Problems with
+// individual files are recorded as issues; an error aborts the import.
+type externalReader func(sourcePath string, export *externalExport) error
+
+var externalReaders = map[ExternalSource]externalReader{
+	ExternalSourceObsidian: readObsidianVault,
+	ExternalSourceNotion:   readNotionExport,
+	ExternalSourceBear:     readBearExport,
+	ExternalSourceEvernote: readEvernoteExport,
+}
+
+// errNotDirectory is returned by readers that need a folder.
+var errNotDirectory = errors.New("choose the exported folder, not a file")
+
+var (
+	externalMarkdownLinkRegex = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)]*)\)`)
+	externalWikilinkRegex     = regexp.MustCompile(`(!?)\[\[([^\]|#^]*)(?:[#^][^\]|]*)?(?:\|([^\]]*))?\]\]`)
+	externalURLSchemeRegex    = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// ImportExternal converts the export of another app at sourcePath into a new
+// top-level notes folder named after the export. Links are rewritten to
+// /notes/... form, tags and dates are stored in frontmatter (or sidecars for
+// attachments), and the folder is then indexed and watched like any other
+// bulk import. Anything that could not be converted is listed in the report.
+func (c *BulkImportCoordinator) ImportExternal(source ExternalSource, sourcePath string) (ExternalImportReport, error) {
+	read, ok := externalReaders[source]
+	if !ok {
+		return ExternalImportReport{}, fmt.Errorf("unknown import source %q", source)
+	}
+
+	export := &externalExport{}
+	defer export.close()
+	if err := read(sourcePath, export); err != nil {
+		return ExternalImportReport{}, err
+	}
+	if len(export.notes) == 0 && len(export.attachments) == 0 {
+		return ExternalImportReport{}, fmt.Errorf("found nothing to import in %s", filepath.Base(sourcePath))
+	}
+
+	report, err := writeExternalExport(c.ctx, c.projectPath, externalFolderName(sourcePath), export)
+	if err != nil {
+		return report, err
+	}
+
+	c.EnqueueFolderImport(report.Folder)
+	return report, nil
+}
+
+// externalFolderName derives the notes folder name from the export's path.
+func externalFolderName(sourcePath string) string {
+	name := filepath.Base(filepath.Clean(sourcePath))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return util.CleanFileName(stripNotionID(name))
+}
+
+// writeExternalExport plans destination paths for everything in export,
+// rewrites links, and writes the result into a new top-level notes folder.
+// Files are staged in a hidden directory and renamed into place at the end,
+// so the watcher and indexer see the folder appear complete.
+func writeExternalExport(
+	ctx context.Context,
+	projectPath string,
+	folderName string,
+	export *externalExport,
+) (ExternalImportReport, error) {
+	notesPath := filepath.Join(projectPath, "notes")
+	folder, err := uniqueExternalFolder(projectPath, folderName)
+	if err != nil {
+		return ExternalImportReport{}, err
+	}
+	folderPath := filepath.Join(notesPath, folder)
+
+	planExternalPaths(export)
+	links := newExternalLinkResolver(folder, export)
+
+	stagingPath, err := os.MkdirTemp(notesPath, ".import-*")
+	if err != nil {
+		return ExternalImportReport{}, err
+	}
+	defer os.RemoveAll(stagingPath)
+
+	report := ExternalImportReport{Folder: folder}
+	for _, attachment := range export.attachments {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := writeExternalAttachment(stagingPath, attachment); err != nil {
+			export.addIssue(attachment.source, "could not copy attachment: %v", err)
+			attachment.tags = nil
+			continue
+		}
+		report.Attachments++
+	}
+	for _, note := range export.notes {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		markdown := links.rewrite(note, export)
+		if err := writeExternalNote(stagingPath, note, markdown); err != nil {
+			export.addIssue(note.source, "could not write note: %v", err)
+			continue
+		}
+		report.Notes++
+	}
+
+	if err := os.Rename(stagingPath, folderPath); err != nil {
+		return report, err
+	}
+
+	// Sidecars are written through the project paths once the files are in
+	// place, so they land where the rest of the app looks for them.
+	for _, attachment := range export.attachments {
+		if len(attachment.tags) == 0 {
+			continue
+		}
+		attachmentFolder, fileName := util.SplitFolderAndFile(filepath.Join(folder, filepath.FromSlash(attachment.path)))
+		if err := sidecar.WriteTags(projectPath, attachmentFolder, fileName, attachment.tags); err != nil {
+			export.addIssue(attachment.source, "could not save tags: %v", err)
+		}
+	}
+
+	report.Issues = export.issues
+	if report.Issues == nil {
+		report.Issues = []ExternalImportIssue{}
+	}
+	log.Printf("Imported %d notes and %d attachments into %s with %d issues",
+		report.Notes, report.Attachments, folder, len(report.Issues))
+	return report, nil
+}
+
+// uniqueExternalFolder returns name, or name with a number appended, such
+// that it names neither an existing notes folder nor a linked folder.
+func uniqueExternalFolder(projectPath, name string) (string, error) {
+	candidate := name
+	for i := 1; ; i++ {
+		exists, err := util.FileOrFolderExists(filepath.Join(projectPath, "notes", candidate))
+		if err != nil {
+			return "", err
+		}
+		if !exists && !config.IsLinkedFolderRoot(projectPath, candidate) {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s %d", name, i)
+	}
+}
+
+// planExternalPaths cleans every destination path segment and renames files
+// whose destinations collide, ignoring case since most filesystems do.
+func planExternalPaths(export *externalExport) {
+	taken := util.Set[string]{}
+	claim := func(planned string) string {
+		segments := strings.Split(planned, "/")
+		for i, segment := range segments {
+			segments[i] = util.CleanFileName(segment)
+		}
+		cleaned := strings.Join(segments, "/")
+
+		candidate := cleaned
+		ext := path.Ext(cleaned)
+		for i := 1; taken.Has(strings.ToLower(candidate)); i++ {
+			candidate = fmt.Sprintf("%s %d%s", strings.TrimSuffix(cleaned, ext), i, ext)
+		}
+		taken.Add(strings.ToLower(candidate))
+		return candidate
+	}
+
+	for _, note := range export.notes {
+		if path.Ext(note.path) != ".md" {
+			note.path += ".md"
+		}
+		note.path = claim(note.path)
+	}
+	for _, attachment := range export.attachments {
+		attachment.path = claim(attachment.path)
+	}
+}
+
+// externalLinkResolver maps links found in an export to their destinations.
+type externalLinkResolver struct {
+	folder string
+	// bySource maps lowercased export paths to destinations.
+	bySource map[string]string
+	// byName maps lowercased file names to destinations for [[...]] links.
+	// Notes are also listed without their extension.
+	byName map[string]string
+}
+
+func newExternalLinkResolver(folder string, export *externalExport) *externalLinkResolver {
+	r := &externalLinkResolver{
+		folder:   folder,
+		bySource: map[string]string{},
+		byName:   map[string]string{},
+	}
+	addName := func(name, destination string) {
+		key := strings.ToLower(name)
+		// The shortest path wins, as in Obsidian.
+		if existing, ok := r.byName[key]; !ok || len(destination) < len(existing) {
+			r.byName[key] = destination
+		}
+	}
+	for _, attachment := range export.attachments {
+		r.bySource[strings.ToLower(attachment.source)] = attachment.path
+		addName(path.Base(attachment.source), attachment.path)
+	}
+	for _, note := range export.notes {
+		r.bySource[strings.ToLower(note.source)] = note.path
+		base := path.Base(note.source)
+		addName(base, note.path)
+		addName(strings.TrimSuffix(base, path.Ext(base)), note.path)
+		addName(strings.TrimSuffix(path.Base(note.path), ".md"), note.path)
+	}
+	return r
+}
+
+// rewrite returns note's markdown with every link it can resolve pointing at
+// the imported files. Unresolved links are left as they are and reported.
+// Code blocks are not touched.
+func (r *externalLinkResolver) rewrite(note *externalNote, export *externalExport) string {
+	var b strings.Builder
+	last := 0
+	for _, block := range notes.CODE_BLOCK_REGEX.FindAllStringIndex(note.markdown, -1) {
+		b.WriteString(r.rewriteText(note, export, note.markdown[last:block[0]]))
+		b.WriteString(note.markdown[block[0]:block[1]])
+		last = block[1]
+	}
+	b.WriteString(r.rewriteText(note, export, note.markdown[last:]))
+	return b.String()
+}
+
+func (r *externalLinkResolver) rewriteText(note *externalNote, export *externalExport, text string) string {
+	if export.wikilinks {
+		text = externalWikilinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+			parts := externalWikilinkRegex.FindStringSubmatch(match)
+			embed, target, alias := parts[1] == "!", strings.TrimSpace(parts[2]), strings.TrimSpace(parts[3])
+			if target == "" {
+				// A link to a heading in the same note.
+				return match
+			}
+
+			destination, ok := r.resolve(note, target, true)
+			if !ok {
+				export.addIssue(note.source, "could not find the note or file linked as [[%s]]", target)
+				return match
+			}
+
+			text := alias
+			if text == "" || embed && isEmbedSize(alias) {
+				text = strings.TrimSuffix(path.Base(target), ".md")
+			}
+			if embed && path.Ext(destination) != ".md" {
+				return fmt.Sprintf("![%s](%s)", text, r.url(destination))
+			}
+			if embed {
+				export.addIssue(note.source, "the embedded note %s was converted to a link", target)
+			}
+			return fmt.Sprintf("[%s](%s)", text, r.url(destination))
+		})
+	}
+
+	return externalMarkdownLinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		parts := externalMarkdownLinkRegex.FindStringSubmatch(match)
+		bang, text, rawURL := parts[1], parts[2], strings.TrimSpace(parts[3])
+		target := externalLinkTarget(rawURL)
+		if target == "" || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/notes/") {
+			return match
+		}
+
+		if strings.HasPrefix(target, "evernote:") {
+			// Evernote links between notes carry no title, but the link text
+			// usually is one.
+			if destination, ok := r.resolve(note, text, true); ok && path.Ext(destination) == ".md" {
+				return fmt.Sprintf("[%s](%s)", text, r.url(destination))
+			}
+			export.addIssue(note.source, "the Evernote link %q could not be matched to an imported note", text)
+			return match
+		}
+		if externalURLSchemeRegex.MatchString(target) {
+			return match
+		}
+
+		destination, ok := r.resolve(note, target, export.wikilinks)
+		if !ok {
+			export.addIssue(note.source, "could not find the note or file linked as %s", target)
+			return match
+		}
+		return fmt.Sprintf("%s[%s](%s)", bang, text, r.url(destination))
+	})
+}
+
+// resolve finds the destination of target, first relative to the note, then
+// relative to the export's root and finally, if byName is set, by file name.
+func (r *externalLinkResolver) resolve(note *externalNote, target string, byName bool) (string, bool) {
+	candidates := []string{
+		path.Join(path.Dir(note.source), target),
+		path.Clean(strings.TrimPrefix(target, "/")),
+	}
+	for _, candidate := range candidates {
+		for _, key := range []string{candidate, candidate + ".md"} {
+			if destination, ok := r.bySource[strings.ToLower(key)]; ok {
+				return destination, true
+			}
+		}
+	}
+	if byName {
+		destination, ok := r.byName[strings.ToLower(path.Base(target))]
+		return destination, ok
+	}
+	return "", false
+}
+
+func (r *externalLinkResolver) url(destination string) string {
+	return util.EncodeNoteURLPath(path.Join(filepath.ToSlash(r.folder), destination))
+}
+
+// externalLinkTarget strips the angle brackets, title and fragment from a
+// markdown link destination and decodes it.
+func externalLinkTarget(rawURL string) string {
+	if strings.HasPrefix(rawURL, "<") {
+		end := strings.Index(rawURL, ">")
+		if end < 0 {
+			return ""
+		}
+		rawURL = rawURL[1:end]
+	} else if i := strings.IndexAny(rawURL, " \t"); i >= 0 {
+		rawURL = rawURL[:i]
+	}
+	if externalURLSchemeRegex.MatchString(rawURL) || strings.HasPrefix(rawURL, "#") {
+		return rawURL
+	}
+	if i := strings.Index(rawURL, "#"); i >= 0 {
+		rawURL = rawURL[:i]
+	}
+	if decoded, err := url.PathUnescape(rawURL); err == nil {
+		return decoded
+	}
+	return rawURL
+}
+
+// isEmbedSize reports whether an embed alias is an Obsidian image size such
+// as "300" or "300x200" rather than alt text.
+func isEmbedSize(alias string) bool {
+	width, height, _ := strings.Cut(alias, "x")
+	if _, err := strconv.Atoi(width); err != nil {
+		return false
+	}
+	if height == "" {
+		return true
+	}
+	_, err := strconv.Atoi(height)
+	return err == nil
+}
+
+func writeExternalAttachment(stagingPath string, attachment *externalAttachment) error {
+	destination := filepath.Join(stagingPath, filepath.FromSlash(attachment.path))
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+
+	reader, err := attachment.open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	file, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return setExternalModTime(destination, attachment.updated)
+}
+
+func writeExternalNote(stagingPath string, note *externalNote, markdown string) error {
+	destination := filepath.Join(stagingPath, filepath.FromSlash(note.path))
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+
+	content, err := externalNoteContent(note, markdown)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(destination, []byte(content), 0644); err != nil {
+		return err
+	}
+	return setExternalModTime(destination, note.updated)
+}
+
+// externalNoteContent prepends the note's frontmatter, adding its tags and
+// created/updated dates in the format the editor writes.
+func externalNoteContent(note *externalNote, markdown string) (string, error) {
+	frontmatter := note.frontmatter
+	if frontmatter == nil {
+		frontmatter = map[string]any{}
+	}
+	if len(note.tags) > 0 {
+		tags := util.SliceToSet(note.tags).Elements()
+		sort.Strings(tags)
+		frontmatter["tags"] = tags
+	}
+	if _, ok := frontmatter["createdDate"]; !ok && !note.created.IsZero() {
+		frontmatter["createdDate"] = formatExternalTime(note.created)
+	}
+	if _, ok := frontmatter["lastUpdated"]; !ok && !note.updated.IsZero() {
+		frontmatter["lastUpdated"] = formatExternalTime(note.updated)
+	}
+	if len(frontmatter) == 0 {
+		return markdown, nil
+	}
+
+	yamlBytes, err := yaml.Marshal(frontmatter)
+	if err != nil {
+		return "", err
+	}
+	return "---\n" + strings.TrimSpace(string(yamlBytes)) + "\n---\n\n" + strings.TrimLeft(markdown, "\n"), nil
+}
+
+// formatExternalTime matches JavaScript's Date.toISOString.
+func formatExternalTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func setExternalModTime(filePath string, updated time.Time) error {
+	if updated.IsZero() {
+		return nil
+	}
+	return os.Chtimes(filePath, updated, updated)
+}
+
+// splitExternalFrontmatter separates YAML frontmatter from the rest of the
+// markdown. Frontmatter that fails to parse is left in the body.
+func splitExternalFrontmatter(markdown string) (map[string]any, string) {
+	normalized := strings.ReplaceAll(markdown, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return nil, markdown
+	}
+	end := strings.Index(normalized[4:], "\n---")
+	if end < 0 {
+		return nil, markdown
+	}
+	yamlContent := normalized[4 : 4+end]
+	rest := normalized[4+end+len("\n---"):]
+	if newline := strings.Index(rest, "\n"); newline >= 0 {
+		rest = rest[newline+1:]
+	} else {
+		rest = ""
+	}
+
+	frontmatter := map[string]any{}
+	if err := yaml.Unmarshal([]byte(yamlContent), &frontmatter); err != nil {
+		return nil, markdown
+	}
+	return frontmatter, rest
+}
+
+// externalTags normalizes tags from a frontmatter value, which may be a list
+// or a comma or space separated string.
+func externalTags(value any) []string {
+	var raw []string
+	switch v := value.(type) {
+	case string:
+		raw = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+
+	tags := []string{}
+	for _, tag := range raw {
+		tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// openExternalArchive returns the contents of a folder or zip archive as a
+// file system. Archives stay open until the export is closed.
+func openExternalArchive(sourcePath string, export *externalExport) (fs.FS, error) {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(sourcePath), nil
+	}
+
+	archive, err := zip.OpenReader(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a folder or zip archive", filepath.Base(sourcePath))
+	}
+	export.closers = append(export.closers, archive)
+	return archive, nil
+}
+
+// externalTime parses a date from frontmatter or app metadata.
+func externalTime(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range []string{
+			time.RFC3339Nano,
+			"2006-01-02T15:04:05",
+			"2006-01-02 15:04:05",
+			"2006-01-02 15:04",
+			"2006-01-02",
+			"20060102T150405Z",
+			"January 2, 2006 3:04 PM",
+			"January 2, 2006",
+		} {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,236 @@
+package ingest
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/etesam913/bytebook/internal/notes/sidecar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupExternalImportProject(t *testing.T) string {
+	t.Helper()
+	projectDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "settings"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "notes"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "settings", "settings.json"), []byte("{}"), 0644))
+	return projectDir
+}
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	}
+}
+
+func importTestExport(t *testing.T, projectDir string, read externalReader, sourcePath string) ExternalImportReport {
+	t.Helper()
+	export := &externalExport{}
+	defer export.close()
+	require.NoError(t, read(sourcePath, export))
+	report, err := writeExternalExport(context.Background(), projectDir, externalFolderName(sourcePath), export)
+	require.NoError(t, err)
+	return report
+}
+
+func readImportedNote(t *testing.T, projectDir string, relPath string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(projectDir, "notes", filepath.FromSlash(relPath)))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestImportExternal(t *testing.T) {
+	t.Run("obsidian vault keeps its layout and rewrites wikilinks", func(t *testing.T) {
+		projectDir := setupExternalImportProject(t)
+		vault := filepath.Join(t.TempDir(), "My Vault")
+		writeTestFiles(t, vault, map[string]string{
+			"Home.md": "---\ntags: [inbox]\ncreated: 2023-04-05\naliases: [Start]\n---\n" +
+				"See [[Projects/Plan|the plan]] and [[Plan#Goals]].\n" +
+				"![[diagram.png|300]]\n[Relative](Projects/Plan.md)\n[[Missing]] #idea #123\n" +
+				"```\n[[Plan]] #notatag\n```\n",
+			"Projects/Plan.md":         "# Plan",
+			"attachments/diagram.png":  "png",
+			".obsidian/workspace.json": "{}",
+		})
+
+		report := importTestExport(t, projectDir, readObsidianVault, vault)
+
+		assert.Equal(t, "My Vault", report.Folder)
+		assert.Equal(t, 2, report.Notes)
+		assert.Equal(t, 1, report.Attachments)
+		require.Len(t, report.Issues, 1)
+		assert.Equal(t, "Home.md", report.Issues[0].Path)
+		assert.Contains(t, report.Issues[0].Reason, "[[Missing]]")
+
+		home := readImportedNote(t, projectDir, "My Vault/Home.md")
+		assert.Contains(t, home, "[the plan](/notes/My%20Vault/Projects/Plan.md)")
+		assert.Contains(t, home, "[Plan](/notes/My%20Vault/Projects/Plan.md)")
+		assert.Contains(t, home, "![diagram.png](/notes/My%20Vault/attachments/diagram.png)")
+		assert.Contains(t, home, "[Relative](/notes/My%20Vault/Projects/Plan.md)")
+		assert.Contains(t, home, "```\n[[Plan]] #notatag\n```")
+
+		frontmatter, _ := splitExternalFrontmatter(home)
+		assert.Equal(t, []any{"idea", "inbox"}, frontmatter["tags"])
+		assert.Equal(t, "2023-04-05T00:00:00.000Z", frontmatter["createdDate"])
+		assert.Equal(t, []any{"Start"}, frontmatter["aliases"])
+		assert.NotEmpty(t, frontmatter["lastUpdated"])
+
+		_, err := os.Stat(filepath.Join(projectDir, "notes", "My Vault", ".obsidian"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("notion zip strips page ids and converts databases", func(t *testing.T) {
+		projectDir := setupExternalImportProject(t)
+		zipPath := filepath.Join(t.TempDir(), "Export.zip")
+		file, err := os.Create(zipPath)
+		require.NoError(t, err)
+		writer := zip.NewWriter(file)
+		for name, content := range map[string]string{
+			"Roadmap 0123456789abcdef0123456789abcdef.md": "# Roadmap\n\nTags: work, q1\nCreated: March 1, 2023 10:00 AM\nOwner: Sam\n\n" +
+				"See [Tasks](Roadmap%200123456789abcdef0123456789abcdef/Tasks%20fedcba9876543210fedcba9876543210.csv) " +
+				"and ![](Roadmap%200123456789abcdef0123456789abcdef/chart.png)\n",
+			"Roadmap 0123456789abcdef0123456789abcdef/chart.png":                                      "png",
+			"Roadmap 0123456789abcdef0123456789abcdef/Tasks fedcba9876543210fedcba9876543210.csv":     "Name,Status\nShip,Done\n",
+			"Roadmap 0123456789abcdef0123456789abcdef/Tasks fedcba9876543210fedcba9876543210_all.csv": "Name,Status\nShip,Done\nPlan|it,Todo\n",
+		} {
+			entry, err := writer.Create(name)
+			require.NoError(t, err)
+			_, err = entry.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, writer.Close())
+		require.NoError(t, file.Close())
+
+		report := importTestExport(t, projectDir, readNotionExport, zipPath)
+
+		assert.Equal(t, "Export", report.Folder)
+		assert.Equal(t, 2, report.Notes)
+		assert.Equal(t, 1, report.Attachments)
+		assert.Empty(t, report.Issues)
+
+		roadmap := readImportedNote(t, projectDir, "Export/Roadmap.md")
+		assert.Contains(t, roadmap, "[Tasks](/notes/Export/Roadmap/Tasks.md)")
+		assert.Contains(t, roadmap, "![](/notes/Export/Roadmap/chart.png)")
+		frontmatter, body := splitExternalFrontmatter(roadmap)
+		assert.True(t, strings.HasPrefix(strings.TrimLeft(body, "\n"), "# Roadmap\n\nSee"), body)
+		assert.Equal(t, []any{"q1", "work"}, frontmatter["tags"])
+		assert.Equal(t, "Sam", frontmatter["Owner"])
+		assert.Equal(t, "2023-03-01T10:00:00.000Z", frontmatter["createdDate"])
+
+		tasks := readImportedNote(t, projectDir, "Export/Roadmap/Tasks.md")
+		assert.Contains(t, tasks, "| Name | Status |\n| --- | --- |\n| Ship | Done |\n| Plan\\|it | Todo |\n")
+	})
+
+	t.Run("bear textbundles bring tags, dates and assets", func(t *testing.T) {
+		projectDir := setupExternalImportProject(t)
+		exportDir := filepath.Join(t.TempDir(), "Bear Notes")
+		writeTestFiles(t, exportDir, map[string]string{
+			"Groceries.textbundle/text.md": "# Groceries\n#home/errands #weekly plan# milk\n" +
+				"![](assets/list%20photo.jpg)\n[[Recipes]]\n",
+			"Groceries.textbundle/info.json": `{"net.shinyfrog.bear": {` +
+				`"creationDate": "2022-01-02T03:04:05Z", "modificationDate": "2022-02-03T04:05:06Z"}}`,
+			"Groceries.textbundle/assets/list photo.jpg": "jpg",
+			"Recipes.textbundle/text.md":                 "# Recipes",
+		})
+
+		report := importTestExport(t, projectDir, readBearExport, exportDir)
+
+		assert.Equal(t, 2, report.Notes)
+		assert.Equal(t, 1, report.Attachments)
+		assert.Empty(t, report.Issues)
+
+		groceries := readImportedNote(t, projectDir, "Bear Notes/Groceries.md")
+		assert.Contains(t, groceries, "![](/notes/Bear%20Notes/attachments/list%20photo.jpg)")
+		assert.Contains(t, groceries, "[Recipes](/notes/Bear%20Notes/Recipes.md)")
+		frontmatter, _ := splitExternalFrontmatter(groceries)
+		assert.Equal(t, []any{"home/errands", "weekly plan"}, frontmatter["tags"])
+		assert.Equal(t, "2022-01-02T03:04:05.000Z", frontmatter["createdDate"])
+		assert.Equal(t, "2022-02-03T04:05:06.000Z", frontmatter["lastUpdated"])
+
+		tags, err := sidecar.GetTags(projectDir, "Bear Notes/attachments/list photo.jpg")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"home/errands", "weekly plan"}, tags)
+	})
+
+	t.Run("evernote enex is converted to markdown", func(t *testing.T) {
+		projectDir := setupExternalImportProject(t)
+		enexPath := filepath.Join(t.TempDir(), "Travel.enex")
+		writeTestFiles(t, filepath.Dir(enexPath), map[string]string{
+			"Travel.enex": `<?xml version="1.0" encoding="UTF-8"?>
+<en-export>
+  <note>
+    <title>Trip / Plan</title>
+    <content><![CDATA[<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd">
+<en-note><h1>Packing</h1><div><en-todo checked="true"/>Passport</div><div><en-todo/>Charger&nbsp;cable</div>
+<ul><li>Hat</li><li>Sun <b>cream</b></li></ul>
+<div>See <a href="https://example.com">site</a> and <a href="evernote:///view/1/s1/abc/abc/">Budget</a>.</div>
+<div><en-media hash="5eb63bbbe01eeed093cb22bb8f5acdc3" type="image/png"/></div>
+<en-crypt>secret</en-crypt></en-note>]]></content>
+    <created>20210304T050607Z</created>
+    <updated>20210405T060708Z</updated>
+    <tag>travel</tag>
+    <resource>
+      <data encoding="base64">aGVsbG8gd29ybGQ=</data>
+      <mime>image/png</mime>
+      <resource-attributes><file-name>map.png</file-name></resource-attributes>
+    </resource>
+  </note>
+  <note>
+    <title>Budget</title>
+    <content><![CDATA[<en-note><div>Money</div></en-note>]]></content>
+  </note>
+</en-export>`,
+		})
+
+		report := importTestExport(t, projectDir, readEvernoteExport, enexPath)
+
+		assert.Equal(t, "Travel", report.Folder)
+		assert.Equal(t, 2, report.Notes)
+		assert.Equal(t, 1, report.Attachments)
+		require.Len(t, report.Issues, 1)
+		assert.Contains(t, report.Issues[0].Reason, "encrypted")
+
+		plan := readImportedNote(t, projectDir, "Travel/Trip Plan.md")
+		frontmatter, body := splitExternalFrontmatter(plan)
+		assert.Equal(t, []any{"travel"}, frontmatter["tags"])
+		assert.Equal(t, "2021-03-04T05:06:07.000Z", frontmatter["createdDate"])
+		assert.Equal(t, "2021-04-05T06:07:08.000Z", frontmatter["lastUpdated"])
+		assert.Contains(t, body, "# Packing")
+		assert.Contains(t, body, "- [x] Passport\n- [ ] Charger cable")
+		assert.Contains(t, body, "- Hat\n- Sun **cream**")
+		assert.Contains(t, body, "See [site](https://example.com) and [Budget](/notes/Travel/Budget.md).")
+		assert.Contains(t, body, "![map.png](/notes/Travel/attachments/map.png)")
+		assert.NotContains(t, body, "secret")
+
+		content, err := os.ReadFile(filepath.Join(projectDir, "notes", "Travel", "attachments", "map.png"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(content))
+	})
+
+	t.Run("imports never overwrite an existing folder", func(t *testing.T) {
+		projectDir := setupExternalImportProject(t)
+		require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "notes", "Vault"), 0755))
+		vault := filepath.Join(t.TempDir(), "Vault")
+		writeTestFiles(t, vault, map[string]string{"a.md": "a", "A.md": "b"})
+
+		report := importTestExport(t, projectDir, readObsidianVault, vault)
+
+		assert.Equal(t, "Vault 1", report.Folder)
+		entries, err := os.ReadDir(filepath.Join(projectDir, "notes"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 2, "the staging folder is removed")
+		imported, err := os.ReadDir(filepath.Join(projectDir, "notes", "Vault 1"))
+		require.NoError(t, err)
+		assert.Len(t, imported, 2, "case-insensitive duplicates are renamed")
+	})
+}
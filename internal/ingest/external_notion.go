@@ -0,0 +1,234 @@
+package ingest
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+var (
+	// notionIDRegex matches the page ID Notion appends to exported names.
+	notionIDRegex = regexp.MustCompile(`\s+(?:[0-9a-f]{32}|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+	// notionPropertyRegex matches a "Name: value" property line written under
+	// a page's title.
+	notionPropertyRegex = regexp.MustCompile(`^([^:\n]{1,40}): (.*)$`)
+)
+
+// readNotionExport reads a Notion "Markdown & CSV" export, either the zip
+// Notion produces or the folder it unpacks to. Page IDs are stripped from
+// names, the properties under each page's title become frontmatter, and
+// databases become notes holding a table.
+func readNotionExport(sourcePath string, export *externalExport) error {
+	fsys, err := openExternalArchive(sourcePath, export)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	err = fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == "." {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || d.Name() == "__MACOSX" {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			files = append(files, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sources := util.SliceToSet(files)
+
+	for _, source := range files {
+		info, err := fs.Stat(fsys, source)
+		if err != nil {
+			export.addIssue(source, "could not read file: %v", err)
+			continue
+		}
+		destination := notionPath(source)
+
+		switch path.Ext(source) {
+		case ".md":
+			content, err := fs.ReadFile(fsys, source)
+			if err != nil {
+				export.addIssue(source, "could not read page: %v", err)
+				continue
+			}
+			export.notes = append(export.notes, notionNote(source, destination, string(content), info.ModTime()))
+		case ".csv":
+			// Databases are exported twice when some rows are filtered out of
+			// the default view; "_all" holds every row.
+			stem := strings.TrimSuffix(source, ".csv")
+			if sources.Has(stem + "_all.csv") {
+				continue
+			}
+			linkSource := source
+			if plain, ok := strings.CutSuffix(stem, "_all"); ok && sources.Has(plain+".csv") {
+				linkSource = plain + ".csv"
+				destination = notionPath(linkSource)
+			}
+			note, err := notionDatabaseNote(fsys, source, linkSource, destination, info.ModTime())
+			if err != nil {
+				export.addIssue(source, "could not convert database: %v", err)
+				continue
+			}
+			export.notes = append(export.notes, note)
+		case ".zip":
+			export.addIssue(source, "nested archives are not imported; extract it and import the folder instead")
+		default:
+			export.attachments = append(export.attachments, &externalAttachment{
+				source:  source,
+				path:    destination,
+				updated: info.ModTime(),
+				open:    func() (io.ReadCloser, error) { return fsys.Open(source) },
+			})
+		}
+	}
+	return nil
+}
+
+// stripNotionID removes the page ID Notion appends to a file or folder name.
+func stripNotionID(name string) string {
+	ext := path.Ext(name)
+	if strings.ContainsAny(ext, " ") {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(name, ext)
+	if stripped := notionIDRegex.ReplaceAllString(stem, ""); stripped != "" {
+		return stripped + ext
+	}
+	return name
+}
+
+// notionPath strips page IDs from every segment of an export path.
+func notionPath(source string) string {
+	segments := strings.Split(source, "/")
+	for i, segment := range segments {
+		segments[i] = stripNotionID(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// notionNote converts one exported page. The property block Notion writes
+// between the title and the body is moved into frontmatter.
+func notionNote(source, destination, content string, modTime time.Time) *externalNote {
+	note := &externalNote{
+		source:      source,
+		path:        destination,
+		frontmatter: map[string]any{},
+		updated:     modTime,
+	}
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	start := 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+		start = 1
+	}
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	end := start
+	for end < len(lines) && notionPropertyRegex.MatchString(lines[end]) {
+		end++
+	}
+	if end == start || end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+		note.markdown = content
+		return note
+	}
+
+	for _, line := range lines[start:end] {
+		match := notionPropertyRegex.FindStringSubmatch(line)
+		key, value := strings.TrimSpace(match[1]), strings.TrimSpace(match[2])
+		switch strings.ToLower(key) {
+		case "tags", "tag", "labels":
+			note.tags = append(note.tags, externalTags(strings.ReplaceAll(value, ", ", ","))...)
+		case "created", "created time", "date created":
+			if created, ok := externalTime(value); ok {
+				note.created = created
+			}
+		case "last edited time", "last edited", "updated", "last updated":
+			if updated, ok := externalTime(value); ok {
+				note.updated = updated
+			}
+		default:
+			note.frontmatter[key] = value
+		}
+	}
+
+	// Drop the blank line that closed the property block.
+	if end < len(lines) {
+		end++
+	}
+	body := append(lines[:start:start], lines[end:]...)
+	note.markdown = strings.Join(body, "\n")
+	return note
+}
+
+// notionDatabaseNote turns an exported database into a note with a table.
+func notionDatabaseNote(
+	fsys fs.FS,
+	source string,
+	linkSource string,
+	destination string,
+	modTime time.Time,
+) (*externalNote, error) {
+	file, err := fsys.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the database is empty")
+	}
+
+	title := strings.TrimSuffix(path.Base(destination), ".csv")
+	var b strings.Builder
+	b.WriteString("# " + title + "\n\n")
+	for i, row := range rows {
+		cells := make([]string, len(rows[0]))
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = markdownTableCell(row[j])
+			}
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString(strings.Repeat("| --- ", len(cells)) + "|\n")
+		}
+	}
+
+	return &externalNote{
+		source:   linkSource,
+		path:     strings.TrimSuffix(destination, ".csv") + ".md",
+		markdown: b.String(),
+		updated:  modTime,
+	}, nil
+}
+
+func markdownTableCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,130 @@
+package ingest
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/notes"
+)
+
+// externalInlineTagRegex matches #tags written in the note body, as used by
+// Obsidian and Bear. A tag needs at least one non-digit so issue numbers such
+// as #123 are left alone.
+var externalInlineTagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+
+// readObsidianVault reads an Obsidian vault folder. Notes keep their place in
+// the vault; frontmatter is preserved, and frontmatter and inline tags are
+// merged into the frontmatter tags. Obsidian's settings and trash folders are
+// skipped with the rest of the hidden files.
+func readObsidianVault(sourcePath string, export *externalExport) error {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errNotDirectory
+	}
+	export.wikilinks = true
+
+	return filepath.WalkDir(sourcePath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == sourcePath {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourcePath, filePath)
+		if err != nil {
+			return err
+		}
+		source := filepath.ToSlash(relPath)
+		info, err := d.Info()
+		if err != nil {
+			export.addIssue(source, "could not read file: %v", err)
+			return nil
+		}
+
+		if path.Ext(source) != ".md" {
+			if path.Ext(source) == ".canvas" {
+				export.addIssue(source, "canvases are copied as plain files and cannot be opened as notes")
+			}
+			export.attachments = append(export.attachments, &externalAttachment{
+				source:  source,
+				path:    source,
+				updated: info.ModTime(),
+				open:    func() (io.ReadCloser, error) { return os.Open(filePath) },
+			})
+			return nil
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			export.addIssue(source, "could not read note: %v", err)
+			return nil
+		}
+		export.notes = append(export.notes, obsidianNote(source, string(content), info.ModTime()))
+		return nil
+	})
+}
+
+// obsidianNote converts one vault note. Obsidian has no standard created
+// field, so the common "created" and "date" properties are used when present.
+func obsidianNote(source, content string, modTime time.Time) *externalNote {
+	frontmatter, body := splitExternalFrontmatter(content)
+	note := &externalNote{
+		source:      source,
+		path:        source,
+		markdown:    body,
+		frontmatter: frontmatter,
+		updated:     modTime,
+	}
+
+	for _, key := range []string{"tags", "tag"} {
+		if value, ok := frontmatter[key]; ok {
+			note.tags = append(note.tags, externalTags(value)...)
+			delete(frontmatter, key)
+		}
+	}
+	note.tags = append(note.tags, inlineExternalTags(body)...)
+
+	for _, key := range []string{"created", "date"} {
+		if created, ok := externalTime(frontmatter[key]); ok {
+			note.created = created
+			break
+		}
+	}
+	for _, key := range []string{"updated", "modified"} {
+		if updated, ok := externalTime(frontmatter[key]); ok {
+			note.updated = updated
+			break
+		}
+	}
+	return note
+}
+
+// inlineExternalTags returns the #tags in markdown outside code blocks.
+func inlineExternalTags(markdown string) []string {
+	tags := []string{}
+	for _, match := range externalInlineTagRegex.FindAllStringSubmatch(notes.CODE_BLOCK_REGEX.ReplaceAllString(markdown, ""), -1) {
+		if tag := strings.Trim(match[1], "/"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
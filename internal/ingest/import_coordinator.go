@@ -1,21 +1,25 @@
 package ingest
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
+	"github.com/blevesearch/bleve/v2"
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/notes"
 	"github.com/etesam913/bytebook/internal/search"
 	"github.com/etesam913/bytebook/internal/util"
 	"github.com/fsnotify/fsnotify"
+	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 const (
@@ -24,16 +28,25 @@ const (
 	bulkImportSettleDelay      = 250 * time.Millisecond
 	watchAddRetryLimit         = 3
 	watchAddRetryDelay         = 50 * time.Millisecond
+	// importIndexChunkSize is how many discovered files are indexed between
+	// progress reports and cancellation checks.
+	importIndexChunkSize = 500
+	// importWatchProgressInterval is how many directories are watched between
+	// progress reports.
+	importWatchProgressInterval = 250
 )
 
 type importRequest struct {
 	relativeFolderPath string
 	settleDelay        time.Duration
+	// reconcile compares the index against disk instead of only adding
+	// missing documents, so edits and deletions made while the app was closed
+	// are picked up.
+	reconcile bool
 }
 
 type BulkImportCoordinator struct {
 	projectPath string
-	notesPath   string
 	index       *search.IndexHolder
 	watcher     *fsnotify.Watcher
 	registry    *notes.DirectoryWatchRegistry
@@ -41,7 +54,18 @@ type BulkImportCoordinator struct {
 	mu       sync.Mutex
 	pending  util.Set[string]
 	inFlight util.Set[string]
-
+	// linked is the set of linked folders the watches and index were last
+	// synced with.
+	linked []config.LinkedFolderJson
+
+	// active is the progress of the in-flight import and cancelActive stops
+	// it; last is the final progress of the most recent finished import.
+	active       *util.ImportProgressEventData
+	cancelActive context.CancelFunc
+	last         *util.ImportProgressEventData
+
+	ctx    context.Context
+	stop   context.CancelFunc
 	jobs   chan importRequest
 	close  chan struct{}
 	closed sync.Once
@@ -57,14 +81,17 @@ func NewBulkImportCoordinator(
 	watcher *fsnotify.Watcher,
 	registry *notes.DirectoryWatchRegistry,
 ) *BulkImportCoordinator {
+	ctx, stop := context.WithCancel(context.Background())
 	coordinator := &BulkImportCoordinator{
 		projectPath: projectPath,
-		notesPath:   filepath.Join(projectPath, "notes"),
 		index:       index,
 		watcher:     watcher,
 		registry:    registry,
 		pending:     make(util.Set[string]),
 		inFlight:    make(util.Set[string]),
+		linked:      config.LinkedFolders(projectPath),
+		ctx:         ctx,
+		stop:        stop,
 		jobs:        make(chan importRequest, bulkImportQueueSize),
 		close:       make(chan struct{}),
 	}
@@ -74,9 +101,50 @@ func NewBulkImportCoordinator(
 	return coordinator
 }
 
-// EnqueueInitialScan schedules a full notes-tree scan used during startup catch-up.
+// EnqueueInitialScan schedules a full notes-tree scan used during startup
+// catch-up. The scan reconciles the index with disk: unchanged files are
+// skipped, changed files are re-indexed and documents for deleted files are
+// removed.
 func (c *BulkImportCoordinator) EnqueueInitialScan() {
-	c.enqueue(importRequest{relativeFolderPath: ""})
+	c.enqueue(importRequest{relativeFolderPath: "", reconcile: true})
+}
+
+// SyncLinkedFolders brings watches and the index in line with the linked
+// folders in settings after they change. Watches on unlinked folders are
+// dropped right away; a reconciling scan of the whole vault then indexes newly
+// linked folders and removes documents of unlinked ones. Settings updates that
+// leave the linked folders alone are ignored.
+func (c *BulkImportCoordinator) SyncLinkedFolders() {
+	linked := config.LinkedFolders(c.projectPath)
+	c.mu.Lock()
+	unchanged := slices.Equal(c.linked, linked)
+	c.linked = linked
+	c.mu.Unlock()
+	if unchanged {
+		return
+	}
+
+	c.removeUnlinkedWatches()
+	c.enqueue(importRequest{relativeFolderPath: "", reconcile: true})
+}
+
+// removeUnlinkedWatches drops watches and polling on directories that are no
+// longer inside notes/ or a linked folder.
+func (c *BulkImportCoordinator) removeUnlinkedWatches() {
+	if c.registry == nil {
+		return
+	}
+	for _, path := range c.registry.Snapshot() {
+		if _, ok := config.NotesRelativePath(c.projectPath, path); ok {
+			continue
+		}
+		if c.watcher != nil && !c.registry.IsPolled(path) {
+			if err := c.watcher.Remove(path); err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
+				log.Printf("Error removing watcher for %s: %v", path, err)
+			}
+		}
+		c.registry.Remove(path)
+	}
 }
 
 // EnqueueFolderImport schedules ingestion for a newly created notes subtree.
@@ -89,13 +157,38 @@ func (c *BulkImportCoordinator) EnqueueFolderImport(relativeFolderPath string) {
 	})
 }
 
-// Shutdown stops the coordinator loop. It does not cancel in-flight work mid-step.
+// Shutdown stops the coordinator loop and cancels the in-flight import at its
+// next checkpoint.
 func (c *BulkImportCoordinator) Shutdown() {
 	c.closed.Do(func() {
+		c.stop()
 		close(c.close)
 	})
 }
 
+// CancelImport stops the import of relativeFolderPath. A queued import is
+// dropped; an in-flight one stops at its next checkpoint, keeping whatever it
+// already indexed and watched. It reports whether a matching import was found.
+func (c *BulkImportCoordinator) CancelImport(relativeFolderPath string) bool {
+	normalized, ok := normalizeNotesRelativeDirectoryPath(relativeFolderPath)
+	if !ok {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending.Has(normalized) {
+		c.pending.Remove(normalized)
+		return true
+	}
+	if c.inFlight.Has(normalized) && c.cancelActive != nil {
+		c.cancelActive()
+		return true
+	}
+	return false
+}
+
 // enqueue normalizes a request and adds it to the work queue unless an equal or
 // broader subtree is already pending or in flight.
 func (c *BulkImportCoordinator) enqueue(req importRequest) {
@@ -129,44 +222,73 @@ func (c *BulkImportCoordinator) run() {
 		case <-c.close:
 			return
 		case req := <-c.jobs:
-			if !c.startRequest(req.relativeFolderPath) {
+			ctx, ok := c.startRequest(req.relativeFolderPath)
+			if !ok {
 				continue
 			}
 
 			if req.settleDelay > 0 {
 				select {
 				case <-time.After(req.settleDelay):
+				case <-ctx.Done():
 				case <-c.close:
-					c.finishRequest(req.relativeFolderPath)
+					c.finishRequest(ctx, req.relativeFolderPath)
 					return
 				}
 			}
 
-			c.processRequest(req.relativeFolderPath)
-			c.finishRequest(req.relativeFolderPath)
+			c.processRequest(ctx, req)
+			c.finishRequest(ctx, req.relativeFolderPath)
 		}
 	}
 }
 
-// startRequest moves a queued subtree from pending to in-flight.
-func (c *BulkImportCoordinator) startRequest(relativeFolderPath string) bool {
+// startRequest moves a queued subtree from pending to in-flight and returns
+// the context that cancels it.
+func (c *BulkImportCoordinator) startRequest(relativeFolderPath string) (context.Context, bool) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if !c.pending.Has(relativeFolderPath) {
-		return false
+		c.mu.Unlock()
+		return nil, false
 	}
 
 	c.pending.Remove(relativeFolderPath)
 	c.inFlight.Add(relativeFolderPath)
-	return true
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.cancelActive = cancel
+	c.active = &util.ImportProgressEventData{FolderPath: relativeFolderPath, Phase: "queued"}
+	progress := *c.active
+	c.mu.Unlock()
+
+	emitImportProgress(progress)
+	return ctx, true
 }
 
-// finishRequest clears the in-flight marker for a completed subtree.
-func (c *BulkImportCoordinator) finishRequest(relativeFolderPath string) {
+// finishRequest clears the in-flight marker for a completed subtree and
+// reports its final progress as "done" or, if ctx was cancelled, "cancelled".
+func (c *BulkImportCoordinator) finishRequest(ctx context.Context, relativeFolderPath string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	cancelled := ctx.Err() != nil
 	c.inFlight.Remove(relativeFolderPath)
+	if c.cancelActive != nil {
+		c.cancelActive()
+		c.cancelActive = nil
+	}
+	progress := c.active
+	c.active = nil
+	if progress == nil {
+		c.mu.Unlock()
+		return
+	}
+	progress.Phase = "done"
+	if cancelled {
+		progress.Phase = "cancelled"
+	}
+	c.last = progress
+	final := *progress
+	c.mu.Unlock()
+
+	emitImportProgress(final)
 }
 
 // isCoveredLocked reports whether the subtree is already represented by an equal
@@ -208,11 +330,18 @@ func (c *BulkImportCoordinator) removeDescendantsLocked(paths util.Set[string],
 }
 
 // processRequest performs a single subtree import: discover once, index files,
-// then add directory watches while logging a compact summary.
-func (c *BulkImportCoordinator) processRequest(relativeFolderPath string) {
-	rootPath := c.notesPath
-	if relativeFolderPath != "" {
-		rootPath = filepath.Join(c.notesPath, relativeFolderPath)
+// then add directory watches while reporting progress and logging a compact
+// summary. It returns early once ctx is cancelled.
+func (c *BulkImportCoordinator) processRequest(ctx context.Context, req importRequest) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	relativeFolderPath := req.relativeFolderPath
+	rootPath, err := config.ResolveNotesPath(c.projectPath, relativeFolderPath)
+	if err != nil {
+		log.Printf("Error resolving import root %q: %v", relativeFolderPath, err)
+		return
 	}
 
 	info, err := os.Stat(rootPath)
@@ -227,48 +356,173 @@ func (c *BulkImportCoordinator) processRequest(relativeFolderPath string) {
 		return
 	}
 
-	directories, filePaths, err := discoverImportSubtree(rootPath)
-	if err != nil {
-		log.Printf("Error discovering subtree %s: %v", rootPath, err)
+	c.updateProgress(func(progress *util.ImportProgressEventData) {
+		progress.Phase = "discovering"
+	})
+	ignore := notes.NewIgnoreMatcher(c.projectPath)
+	directories, filePaths, discoverErr := discoverImportSubtree(ctx, rootPath, ignore)
+	if relativeFolderPath == "" && discoverErr == nil {
+		directories, filePaths = c.discoverLinkedFolders(ctx, directories, filePaths, ignore)
 	}
+	if ctx.Err() != nil {
+		return
+	}
+	if discoverErr != nil {
+		log.Printf("Error discovering subtree %s: %v", rootPath, discoverErr)
+	}
+	c.updateProgress(func(progress *util.ImportProgressEventData) {
+		progress.Phase = "indexing"
+		progress.Discovered = len(filePaths)
+		progress.Directories = len(directories)
+	})
 
 	if c.index == nil {
 		log.Printf("Error indexing subtree %s: index is nil", rootPath)
 		return
 	}
 
-	if len(filePaths) > 0 {
-		idx := c.index.RLock()
-		err := search.IndexDiscoveredFiles(c.projectPath, filePaths, idx, bulkImportIndexWorkerCount)
-		c.index.RUnlock()
-		if err != nil {
-			log.Printf("Error indexing subtree %s: %v", rootPath, err)
-		}
+	// Reconciling deletes documents for files that were not discovered, so a
+	// partial walk falls back to the additive import instead.
+	if req.reconcile && discoverErr == nil {
+		c.reconcileIndex(relativeFolderPath, filePaths)
+		c.updateProgress(func(progress *util.ImportProgressEventData) {
+			progress.Indexed = len(filePaths)
+		})
+	} else {
+		c.indexFiles(ctx, rootPath, filePaths)
+	}
+	if ctx.Err() != nil {
+		return
 	}
 
-	summary := c.addDirectoriesToWatcher(directories)
-	if len(filePaths) > 0 || summary.added > 0 || summary.deferred > 0 {
+	c.updateProgress(func(progress *util.ImportProgressEventData) {
+		progress.Phase = "watching"
+	})
+	summary := c.addDirectoriesToWatcher(ctx, directories)
+	if summary.polled > 0 {
+		warning := watchLimitWarning(summary.polled)
+		log.Printf("bulk import root=%q: %s", relativeFolderPath, warning)
+		c.updateProgress(func(progress *util.ImportProgressEventData) {
+			progress.Warning = warning
+		})
+	}
+	if len(filePaths) > 0 || summary.added > 0 || summary.polled > 0 {
 		log.Printf(
-			"bulk import processed root=%q dirs=%d files=%d watchers_added=%d watchers_deferred=%d watchers_skipped=%d",
+			"bulk import processed root=%q dirs=%d files=%d watchers_added=%d watchers_polled=%d watchers_skipped=%d",
 			relativeFolderPath,
 			len(directories),
 			len(filePaths),
 			summary.added,
-			summary.deferred,
+			summary.polled,
 			summary.skipped,
 		)
 	}
 }
 
+// discoverLinkedFolders appends the directories and files of every linked
+// folder to a scan of the whole vault. A linked folder that cannot be walked,
+// such as one on an unmounted drive, is logged and left out, so its documents
+// are dropped by a reconciling scan until it is reachable again.
+func (c *BulkImportCoordinator) discoverLinkedFolders(
+	ctx context.Context,
+	directories []string,
+	filePaths []string,
+	ignore *notes.IgnoreMatcher,
+) ([]string, []string) {
+	for _, folder := range config.LinkedFolders(c.projectPath) {
+		linkedDirectories, linkedFilePaths, err := discoverImportSubtree(ctx, folder.Path, ignore)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			log.Printf("Error discovering linked folder %s: %v", folder.Name, err)
+			continue
+		}
+		directories = append(directories, linkedDirectories...)
+		filePaths = append(filePaths, linkedFilePaths...)
+	}
+	return directories, filePaths
+}
+
+// reconcileIndex brings the index in line with the discovered files. An index
+// built with an older schema is first rebuilt from scratch in a temporary
+// directory and swapped in; the reconcile pass that follows then finds nearly
+// everything unchanged and only reports completion.
+func (c *BulkImportCoordinator) reconcileIndex(relativeFolderPath string, filePaths []string) {
+	idx := c.index.RLock()
+	current := search.HasCurrentSchema(idx)
+	c.index.RUnlock()
+	if !current {
+		log.Printf("search index schema is outdated, rebuilding (want version %d)", search.IndexSchemaVersion)
+		emitReconcileProgress(util.SearchReconcileProgressEventData{Phase: "rebuilding", Total: len(filePaths)})
+		err := c.index.Swap(func(old bleve.Index) (bleve.Index, error) {
+			return search.RegenerateSearchIndex(c.projectPath, old)
+		})
+		if err != nil {
+			log.Printf("Error rebuilding outdated search index: %v", err)
+		}
+	}
+
+	idx = c.index.RLock()
+	stats, err := search.ReconcileIndex(c.projectPath, filePaths, idx, bulkImportIndexWorkerCount, emitReconcileProgress)
+	c.index.RUnlock()
+	if err != nil {
+		log.Printf("Error reconciling index for %q: %v", relativeFolderPath, err)
+		return
+	}
+	log.Printf(
+		"index reconciled root=%q indexed=%d deleted=%d unchanged=%d",
+		relativeFolderPath,
+		stats.Indexed,
+		stats.Deleted,
+		stats.Unchanged,
+	)
+}
+
+// emitReconcileProgress forwards startup reconciliation progress to the
+// frontend.
+func emitReconcileProgress(progress util.SearchReconcileProgressEventData) {
+	if app := application.Get(); app != nil {
+		app.Event.EmitEvent(&application.CustomEvent{
+			Name: util.EventSearchReconcileProgress,
+			Data: progress,
+		})
+	}
+}
+
+// indexFiles indexes filePaths in chunks so progress is reported as the import
+// advances and a cancelled import stops between chunks.
+func (c *BulkImportCoordinator) indexFiles(ctx context.Context, rootPath string, filePaths []string) {
+	for start := 0; start < len(filePaths); start += importIndexChunkSize {
+		if ctx.Err() != nil {
+			return
+		}
+		end := min(start+importIndexChunkSize, len(filePaths))
+
+		idx := c.index.RLock()
+		err := search.IndexDiscoveredFiles(c.projectPath, filePaths[start:end], idx, bulkImportIndexWorkerCount)
+		c.index.RUnlock()
+		if err != nil {
+			log.Printf("Error indexing subtree %s: %v", rootPath, err)
+		}
+
+		c.updateProgress(func(progress *util.ImportProgressEventData) {
+			progress.Indexed = end
+		})
+	}
+}
+
 type watchAddSummary struct {
-	added    int
-	deferred int
-	skipped  int
+	added   int
+	polled  int
+	skipped int
 }
 
-// addDirectoriesToWatcher registers watches for discovered directories until the
-// watcher budget is exhausted. Remaining paths are counted as deferred.
-func (c *BulkImportCoordinator) addDirectoriesToWatcher(directories []string) watchAddSummary {
+// addDirectoriesToWatcher registers watches for discovered directories until
+// ctx is cancelled. Once the OS refuses more watches, the remaining
+// directories are handed to the file watcher's polling fallback and counted as
+// polled, along with directories that were already polled.
+func (c *BulkImportCoordinator) addDirectoriesToWatcher(ctx context.Context, directories []string) watchAddSummary {
 	summary := watchAddSummary{}
 	if c.watcher == nil {
 		return summary
@@ -277,14 +531,26 @@ func (c *BulkImportCoordinator) addDirectoriesToWatcher(directories []string) wa
 	sort.Strings(directories)
 
 	for index, path := range directories {
+		if ctx.Err() != nil {
+			return summary
+		}
+		if index > 0 && index%importWatchProgressInterval == 0 {
+			c.reportWatchProgress(summary)
+		}
+
+		if c.registry != nil && c.registry.IsPolled(path) {
+			summary.polled++
+			continue
+		}
 		if c.registry != nil && c.registry.Has(path) {
 			summary.skipped++
 			continue
 		}
 
 		if err := addWatchWithRetry(c.watcher, path); err != nil {
-			if isTooManyOpenFiles(err) {
-				summary.deferred = len(directories) - index
+			if notes.IsWatchLimitError(err) {
+				summary.polled += c.pollDirectories(directories[index:])
+				c.reportWatchProgress(summary)
 				return summary
 			}
 
@@ -298,17 +564,45 @@ func (c *BulkImportCoordinator) addDirectoriesToWatcher(directories []string) wa
 		}
 	}
 
+	c.reportWatchProgress(summary)
 	return summary
 }
 
+// pollDirectories registers directories that could not be watched for
+// polling and returns how many are now polled. Directories that are already
+// watched natively are left alone.
+func (c *BulkImportCoordinator) pollDirectories(directories []string) int {
+	if c.registry == nil {
+		return 0
+	}
+
+	polled := 0
+	for _, path := range directories {
+		c.registry.AddPolled(path)
+		if c.registry.IsPolled(path) {
+			polled++
+		}
+	}
+	return polled
+}
+
+// reportWatchProgress publishes the watch counts of the in-flight import.
+// Directories that were already watched count as watched.
+func (c *BulkImportCoordinator) reportWatchProgress(summary watchAddSummary) {
+	c.updateProgress(func(progress *util.ImportProgressEventData) {
+		progress.Watched = summary.added + summary.skipped
+		progress.Polled = summary.polled
+	})
+}
+
 // addWatchWithRetry retries transient watch-add failures a small number of times
-// but returns immediately on EMFILE-style errors.
+// but returns immediately once the OS watch limit is hit.
 func addWatchWithRetry(watcher *fsnotify.Watcher, path string) error {
 	var lastErr error
 	for attempt := 0; attempt < watchAddRetryLimit; attempt++ {
 		if err := watcher.Add(path); err != nil {
 			lastErr = err
-			if isTooManyOpenFiles(err) {
+			if notes.IsWatchLimitError(err) {
 				return err
 			}
 			if attempt == watchAddRetryLimit-1 {
@@ -325,7 +619,9 @@ func addWatchWithRetry(watcher *fsnotify.Watcher, path string) error {
 
 // discoverImportSubtree walks a folder tree once and separates discovered
 // directories from files while preserving the existing hidden-path skip rules.
-func discoverImportSubtree(rootPath string) ([]string, []string, error) {
+// Paths excluded by .bytebookignore files are skipped as well. The walk stops
+// with ctx's error once ctx is cancelled.
+func discoverImportSubtree(ctx context.Context, rootPath string, ignore *notes.IgnoreMatcher) ([]string, []string, error) {
 	directories := []string{}
 	filePaths := []string{}
 
@@ -333,6 +629,9 @@ func discoverImportSubtree(rootPath string) ([]string, []string, error) {
 		if err != nil {
 			return err
 		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 
 		if strings.HasPrefix(d.Name(), ".") && path != rootPath {
 			if d.IsDir() {
@@ -341,6 +640,13 @@ func discoverImportSubtree(rootPath string) ([]string, []string, error) {
 			return nil
 		}
 
+		if path != rootPath && ignore.Match(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		if d.IsDir() {
 			directories = append(directories, path)
 			return nil
@@ -385,8 +691,3 @@ func pathCovers(parent, child string) bool {
 
 	return strings.HasPrefix(child, parent+string(filepath.Separator))
 }
-
-// isTooManyOpenFiles identifies watcher-add failures caused by file descriptor exhaustion.
-func isTooManyOpenFiles(err error) bool {
-	return errors.Is(err, syscall.EMFILE) || strings.Contains(strings.ToLower(err.Error()), "too many open files")
-}
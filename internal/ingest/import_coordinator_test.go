@@ -3,11 +3,15 @@ package ingest
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/blevesearch/bleve/v2"
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/notes"
 	"github.com/etesam913/bytebook/internal/search"
+	"github.com/etesam913/bytebook/internal/util"
 	"github.com/fsnotify/fsnotify"
 	"github.com/stretchr/testify/require"
 )
@@ -27,11 +31,20 @@ func TestBulkImportCoordinatorInitialScan(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "root.md"), []byte("# root"), 0644))
 	require.NoError(t, os.WriteFile(filepath.Join(nestedDir, "deep.md"), []byte("# deep"), 0644))
 
+	ignoredDir := filepath.Join(notesDir, "alpha", "build")
+	require.NoError(t, os.MkdirAll(ignoredDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(ignoredDir, "output.md"), []byte("# output"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, notes.IgnoreFileName), []byte("build/\n"), 0644))
+
 	index, err := search.OpenOrCreateIndex(projectDir)
 	require.NoError(t, err)
 	defer index.Close()
 	holder := search.NewIndexHolder(index)
 
+	// A note deleted while the app was closed must be dropped by the scan.
+	staleDoc := search.CreateMarkdownNoteBleveDocument("# gone", "", "gone.md")
+	require.NoError(t, index.Index("gone.md", staleDoc))
+
 	watcher, err := fsnotify.NewWatcher()
 	require.NoError(t, err)
 	defer watcher.Close()
@@ -55,4 +68,139 @@ func TestBulkImportCoordinatorInitialScan(t *testing.T) {
 		return registry.Has(filepath.Join(notesDir, "alpha")) &&
 			registry.Has(nestedDir)
 	}, 5*time.Second, 50*time.Millisecond)
+
+	require.False(t, registry.Has(ignoredDir))
+	doc, err := index.Document(filepath.Join("alpha", "build", "output.md"))
+	require.NoError(t, err)
+	require.Nil(t, doc)
+	doc, err = index.Document("gone.md")
+	require.NoError(t, err)
+	require.Nil(t, doc)
+}
+
+func newTestImportCoordinator(t *testing.T) (*BulkImportCoordinator, bleve.Index, string) {
+	t.Helper()
+	projectDir := t.TempDir()
+	notesDir := filepath.Join(projectDir, "notes")
+	searchDir := filepath.Join(projectDir, "search")
+	require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "settings"), 0755))
+	require.NoError(t, os.MkdirAll(notesDir, 0755))
+	require.NoError(t, os.MkdirAll(searchDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(searchDir, "saved-searches.json"), []byte("{}"), 0644))
+
+	index, err := search.OpenOrCreateIndex(projectDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { index.Close() })
+
+	watcher, err := fsnotify.NewWatcher()
+	require.NoError(t, err)
+	t.Cleanup(func() { watcher.Close() })
+
+	coordinator := NewBulkImportCoordinator(projectDir, search.NewIndexHolder(index), watcher, notes.NewDirectoryWatchRegistry())
+	t.Cleanup(coordinator.Shutdown)
+	return coordinator, index, notesDir
+}
+
+func TestBulkImportCoordinatorStatus(t *testing.T) {
+	coordinator, index, notesDir := newTestImportCoordinator(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(notesDir, "dropped", "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "dropped", "one.md"), []byte("# one"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "dropped", "nested", "two.md"), []byte("# two"), 0644))
+
+	coordinator.EnqueueFolderImport("dropped")
+
+	require.Eventually(t, func() bool {
+		return coordinator.Status().Last != nil
+	}, 5*time.Second, 20*time.Millisecond)
+
+	status := coordinator.Status()
+	require.Nil(t, status.Active)
+	require.Empty(t, status.Pending)
+	require.Equal(t, util.ImportProgressEventData{
+		FolderPath:  "dropped",
+		Phase:       "done",
+		Discovered:  2,
+		Indexed:     2,
+		Directories: 2,
+		Watched:     2,
+	}, *status.Last)
+
+	doc, err := index.Document(filepath.Join("dropped", "nested", "two.md"))
+	require.NoError(t, err)
+	require.NotNil(t, doc)
+}
+
+func TestBulkImportCoordinatorCancelImport(t *testing.T) {
+	coordinator, index, notesDir := newTestImportCoordinator(t)
+	for _, folder := range []string{"first", "second"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(notesDir, folder), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(notesDir, folder, "note.md"), []byte("# note"), 0644))
+	}
+
+	// "first" waits out the settle delay in flight while "second" is queued.
+	coordinator.EnqueueFolderImport("first")
+	require.Eventually(t, func() bool {
+		active := coordinator.Status().Active
+		return active != nil && active.FolderPath == "first"
+	}, 5*time.Second, 10*time.Millisecond)
+	coordinator.EnqueueFolderImport("second")
+	require.Equal(t, []string{"second"}, coordinator.Status().Pending)
+
+	require.True(t, coordinator.CancelImport("second"))
+	require.True(t, coordinator.CancelImport("first"))
+	require.False(t, coordinator.CancelImport("unknown"))
+
+	require.Eventually(t, func() bool {
+		last := coordinator.Status().Last
+		return last != nil && last.FolderPath == "first"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status := coordinator.Status()
+	require.Equal(t, "cancelled", status.Last.Phase)
+	require.Nil(t, status.Active)
+	require.Empty(t, status.Pending)
+
+	for _, folder := range []string{"first", "second"} {
+		doc, err := index.Document(filepath.Join(folder, "note.md"))
+		require.NoError(t, err)
+		require.Nil(t, doc)
+	}
+}
+
+func TestBulkImportCoordinatorSyncLinkedFolders(t *testing.T) {
+	coordinator, index, notesDir := newTestImportCoordinator(t)
+	projectDir := filepath.Dir(notesDir)
+	_, err := config.GetProjectSettings(projectDir)
+	require.NoError(t, err)
+
+	docsDir := filepath.Join(t.TempDir(), "docs")
+	require.NoError(t, os.MkdirAll(filepath.Join(docsDir, "guide"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(docsDir, "guide", "intro.md"), []byte("# intro"), 0644))
+	linkedDocID := filepath.Join("docs", "guide", "intro.md")
+
+	require.NoError(t, config.AddLinkedFolder(projectDir, config.LinkedFolderJson{Name: "docs", Path: docsDir}))
+	coordinator.SyncLinkedFolders()
+	require.Eventually(t, func() bool {
+		doc, err := index.Document(linkedDocID)
+		return err == nil && doc != nil
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return coordinator.registry.Has(filepath.Join(docsDir, "guide"))
+	}, 5*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, config.RemoveLinkedFolder(projectDir, "docs"))
+	coordinator.SyncLinkedFolders()
+	require.False(t, coordinator.registry.Has(filepath.Join(docsDir, "guide")))
+	require.Eventually(t, func() bool {
+		doc, err := index.Document(linkedDocID)
+		return err == nil && doc == nil
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestWatchLimitWarning(t *testing.T) {
+	warning := watchLimitWarning(12)
+	require.Contains(t, warning, "12 folders are checked for changes")
+	if runtime.GOOS == "linux" {
+		require.Contains(t, warning, "fs.inotify.max_user_watches")
+	}
 }
@@ -0,0 +1,89 @@
+package ingest
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+
+	"github.com/etesam913/bytebook/internal/notes"
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// ImportStatus is a snapshot of the coordinator's work. Active is the import
+// in flight, Pending lists queued folders sorted by path, and Last is the final
+// progress of the most recent finished import, including any warning.
+type ImportStatus struct {
+	Active  *util.ImportProgressEventData `json:"active"`
+	Pending []string                      `json:"pending"`
+	Last    *util.ImportProgressEventData `json:"last"`
+}
+
+// Status returns the current import status.
+func (c *BulkImportCoordinator) Status() ImportStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := ImportStatus{Pending: c.pending.Elements()}
+	sort.Strings(status.Pending)
+	if c.active != nil {
+		active := *c.active
+		status.Active = &active
+	}
+	if c.last != nil {
+		last := *c.last
+		status.Last = &last
+	}
+	return status
+}
+
+// WatcherStatus returns how many directories are watched natively and how
+// many fell back to polling.
+func (c *BulkImportCoordinator) WatcherStatus() notes.WatcherStatus {
+	if c.registry == nil {
+		return notes.WatcherStatus{}
+	}
+	return c.registry.Status()
+}
+
+// updateProgress applies update to the in-flight import's progress and
+// forwards the result to the frontend.
+func (c *BulkImportCoordinator) updateProgress(update func(*util.ImportProgressEventData)) {
+	c.mu.Lock()
+	if c.active == nil {
+		c.mu.Unlock()
+		return
+	}
+	update(c.active)
+	progress := *c.active
+	c.mu.Unlock()
+
+	emitImportProgress(progress)
+}
+
+// emitImportProgress forwards bulk import progress to the frontend.
+func emitImportProgress(progress util.ImportProgressEventData) {
+	if app := application.Get(); app != nil {
+		app.Event.EmitEvent(&application.CustomEvent{
+			Name: util.EventImportProgress,
+			Data: progress,
+		})
+	}
+}
+
+// watchLimitWarning tells the user that polled folders fell back to polling
+// because the OS refused more watches, and which limit to raise.
+func watchLimitWarning(polled int) string {
+	hint := "raise the open file limit (ulimit -n) and restart Bytebook"
+	if runtime.GOOS == "linux" {
+		hint = "raise the inotify limits (for example " +
+			"sudo sysctl fs.inotify.max_user_watches=524288 fs.inotify.max_user_instances=1024) " +
+			"and restart Bytebook"
+	}
+	return fmt.Sprintf(
+		"%d folders are checked for changes every few seconds instead of being watched "+
+			"because the system ran out of file watchers. To watch them, %s.",
+		polled,
+		hint,
+	)
+}
@@ -69,6 +69,11 @@ func LaunchKernel(argv []string, pathToConnectionFile, language, venvPath string
 	if err != nil {
 		return nil, nil, err
 	}
+	// Deno resolves npm packages from the node_modules that note requirements
+	// are installed into.
+	if language == "javascript" {
+		cmd.Dir = filepath.Join(projectPath, "code")
+	}
 
 	if err := cmd.Start(); err != nil {
 		return nil, stderrBuf, err
@@ -110,13 +115,15 @@ func createCommandForLanguage(argv []string, language, venvPath string) (*exec.C
 	return cmd, &stderrBuf, nil
 }
 
-// createPythonCommand creates a command specifically for Python with virtual environment support
+// createPythonCommand creates a command specifically for Python, run with the
+// interpreter of the environment at venvPath. That may be a virtual environment
+// (including uv and Poetry ones), a conda environment or a pyenv version.
 func createPythonCommand(argv []string, venvPath string) (*exec.Cmd, *bytes.Buffer, error) {
 	var stderrBuf bytes.Buffer
 
-	pythonPath := filepath.Join(venvPath, "bin", "python3")
-	if _, err := os.Stat(pythonPath); os.IsNotExist(err) {
-		return nil, &stderrBuf, fmt.Errorf("virtual environment Python not found at %s", pythonPath)
+	pythonPath := util.PythonInterpreter(venvPath)
+	if pythonPath == "" {
+		return nil, &stderrBuf, fmt.Errorf("python interpreter not found in %s", filepath.Join(venvPath, "bin"))
 	}
 
 	argvCopy := make([]string, len(argv))
@@ -129,9 +136,14 @@ func createPythonCommand(argv []string, venvPath string) (*exec.Cmd, *bytes.Buff
 	cmd := exec.Command(argvCopy[0], argvCopy[1:]...)
 
 	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("VIRTUAL_ENV=%s", venvPath),
 		fmt.Sprintf("PATH=%s/bin:%s", venvPath, os.Getenv("PATH")),
 	)
+	// Mirror what activating the environment would export.
+	if util.IsVirtualEnv(venvPath) {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("VIRTUAL_ENV=%s", venvPath))
+	} else if util.IsCondaEnv(venvPath) {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("CONDA_PREFIX=%s", venvPath))
+	}
 
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
@@ -3,17 +3,40 @@ package jupyter_protocol
 import (
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"log"
 	"time"
-
-	"github.com/pebbe/zmq4"
 )
 
 const delimiter = "<IDS|MSG>"
 
+// ErrInvalidSignature is returned by VerifySignature when a message's HMAC does
+// not match the connection key.
+var ErrInvalidSignature = errors.New("invalid message signature")
+
+// signatureHashes maps the supported signature_scheme values to their hash
+// functions. An empty scheme means hmac-sha256, the protocol default.
+var signatureHashes = map[string]func() hash.Hash{
+	"":            sha256.New,
+	"hmac-sha256": sha256.New,
+	"hmac-sha384": sha512.New384,
+	"hmac-sha512": sha512.New,
+}
+
+// ValidateSignatureScheme returns an error if scheme cannot be used to sign or
+// verify messages.
+func ValidateSignatureScheme(scheme string) error {
+	if _, ok := signatureHashes[scheme]; !ok {
+		return fmt.Errorf("unsupported signature scheme %q", scheme)
+	}
+	return nil
+}
+
 // Header defines the structure for the message header.
 type Header struct {
 	MsgID    string `json:"msg_id"`
@@ -30,16 +53,22 @@ type Message struct {
 	ParentHeader map[string]any `json:"parent_header"`
 	Metadata     map[string]any `json:"metadata"`
 	Content      map[string]any `json:"content"`
+	// Buffers are the raw binary frames that follow the content frame. They
+	// are used by comm messages (e.g. ipywidgets) and are not signed.
+	Buffers [][]byte `json:"-"`
 }
 
 // RequestParams holds common parameters for sending messages.
 type RequestParams struct {
-	MessageID string
-	SessionID string
-	MsgType   string
-	Username  string
-	Key       string
-	Content   map[string]any
+	MessageID       string
+	SessionID       string
+	MsgType         string
+	Username        string
+	Key             string
+	SignatureScheme string
+	Content         map[string]any
+	Metadata        map[string]any
+	Buffers         [][]byte
 }
 
 // newHeader creates a header as defined in the Jupyter messaging protocol.
@@ -54,17 +83,67 @@ func newHeader(msgID, msgType, session, username string) Header {
 	}
 }
 
-// signMessage computes an HMAC-SHA256 signature using the per-instance key.
-func signMessage(parts []string, key string) string {
-	h := hmac.New(sha256.New, []byte(key))
+// messageHMAC computes the HMAC of the signed frames (header, parent header,
+// metadata and content) with the connection key and scheme.
+func messageHMAC(parts [][]byte, key, scheme string) ([]byte, error) {
+	newHash, ok := signatureHashes[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature scheme %q", scheme)
+	}
+	h := hmac.New(newHash, []byte(key))
 	for _, part := range parts {
-		h.Write([]byte(part))
+		h.Write(part)
+	}
+	return h.Sum(nil), nil
+}
+
+// signMessage returns the hex-encoded signature for parts. Signing is disabled
+// when the connection key is empty, in which case the signature is empty too.
+func signMessage(parts [][]byte, key, scheme string) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+	mac, err := messageHMAC(parts, key, scheme)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(mac), nil
+}
+
+// VerifySignature checks the signature of an incoming multipart envelope
+// against the connection key using a constant-time comparison. Messages are
+// not signed when the key is empty, so every envelope verifies.
+func VerifySignature(envelope [][]byte, key, scheme string) error {
+	if key == "" {
+		return nil
+	}
+	delimiterIndex := findDelimiter(envelope)
+	if delimiterIndex == -1 || len(envelope) < delimiterIndex+6 {
+		return fmt.Errorf("malformed message: %w", ErrInvalidSignature)
+	}
+	expected, err := messageHMAC(envelope[delimiterIndex+2:delimiterIndex+6], key, scheme)
+	if err != nil {
+		return err
+	}
+	received, err := hex.DecodeString(string(envelope[delimiterIndex+1]))
+	if err != nil || !hmac.Equal(expected, received) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// findDelimiter returns the index of the delimiter frame, or -1.
+func findDelimiter(envelope [][]byte) int {
+	for i, frame := range envelope {
+		if string(frame) == delimiter {
+			return i
+		}
 	}
-	return hex.EncodeToString(h.Sum(nil))
+	return -1
 }
 
 // createMultipartMessage assembles the complete multipart message envelope.
-func createMultipartMessage(identities []string, msg Message, key string) ([][]byte, error) {
+func createMultipartMessage(identities []string, msg Message, key, scheme string) ([][]byte, error) {
 	headerBytes, err := json.Marshal(msg.Header)
 	if err != nil {
 		return nil, fmt.Errorf("error marshalling header: %w", err)
@@ -82,15 +161,11 @@ func createMultipartMessage(identities []string, msg Message, key string) ([][]b
 		return nil, fmt.Errorf("error marshalling content: %w", err)
 	}
 
-	partsForSign := []string{
-		string(headerBytes),
-		string(parentHeaderBytes),
-		string(metadataBytes),
-		string(contentBytes),
+	signature, err := signMessage([][]byte{headerBytes, parentHeaderBytes, metadataBytes, contentBytes}, key, scheme)
+	if err != nil {
+		return nil, err
 	}
 
-	signature := signMessage(partsForSign, key)
-
 	var envelope [][]byte
 	for _, id := range identities {
 		envelope = append(envelope, []byte(id))
@@ -101,6 +176,7 @@ func createMultipartMessage(identities []string, msg Message, key string) ([][]b
 	envelope = append(envelope, parentHeaderBytes)
 	envelope = append(envelope, metadataBytes)
 	envelope = append(envelope, contentBytes)
+	envelope = append(envelope, msg.Buffers...)
 
 	return envelope, nil
 }
@@ -109,13 +185,7 @@ func createMultipartMessage(identities []string, msg Message, key string) ([][]b
 // It returns the identities, the deserialized Message, and the signature.
 func ParseMultipartMessage(envelope [][]byte) (identities []string, msg Message, signature string, err error) {
 	// Locate the delimiter frame.
-	delimiterIndex := -1
-	for i, frame := range envelope {
-		if string(frame) == delimiter {
-			delimiterIndex = i
-			break
-		}
-	}
+	delimiterIndex := findDelimiter(envelope)
 	if delimiterIndex == -1 {
 		err = fmt.Errorf("delimiter not found in message")
 		return
@@ -156,22 +226,35 @@ func ParseMultipartMessage(envelope [][]byte) (identities []string, msg Message,
 		err = fmt.Errorf("error unmarshalling content: %w", err)
 		return
 	}
+	msg.Buffers = envelope[delimiterIndex+6:]
 	return
 }
 
+// MessageSender sends an assembled multipart envelope to the kernel. It is
+// satisfied by *zmq4.Socket and by the per-channel senders of a kernel's
+// socket pipeline.
+type MessageSender interface {
+	SendMessage(parts ...any) (int, error)
+}
+
 // sendMessage is a helper that sends a message using the given parameters.
-func sendMessage(socket *zmq4.Socket, params RequestParams) error {
+func sendMessage(socket MessageSender, params RequestParams) error {
 	identities := []string{"client_identity", "kernel_identity"}
 	header := newHeader(params.MessageID, params.MsgType, params.SessionID, params.Username)
 
+	metadata := params.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
 	msg := Message{
 		Header:       header,
 		ParentHeader: map[string]any{},
-		Metadata:     map[string]any{},
+		Metadata:     metadata,
 		Content:      params.Content,
+		Buffers:      params.Buffers,
 	}
 
-	envelope, err := createMultipartMessage(identities, msg, params.Key)
+	envelope, err := createMultipartMessage(identities, msg, params.Key, params.SignatureScheme)
 	if err != nil {
 		return fmt.Errorf("failed to create multipart message: %w", err)
 	}
@@ -186,15 +269,22 @@ func sendMessage(socket *zmq4.Socket, params RequestParams) error {
 
 // MessageParams contains common parameters used in all message types.
 type MessageParams struct {
-	MessageID string
-	SessionID string
-	Key       string
+	MessageID       string
+	SessionID       string
+	Key             string
+	SignatureScheme string
 }
 
 // ExecuteMessageParams contains parameters for execute request messages.
 type ExecuteMessageParams struct {
 	MessageParams
 	Code string
+	// Silent executions are not broadcast on iopub, are not stored in history
+	// and cannot request stdin. Used for introspection.
+	Silent bool
+	// UserExpressions maps names to expressions the kernel evaluates after
+	// running Code; results come back in the execute_reply.
+	UserExpressions map[string]string
 }
 
 // InputReplyMessageParams contains parameters for input reply messages.
@@ -219,19 +309,24 @@ type CompleteRequestParams struct {
 }
 
 // SendExecuteRequest sends an execute_request message to the kernel.
-func SendExecuteRequest(shellDealerSocket *zmq4.Socket, params ExecuteMessageParams) error {
+func SendExecuteRequest(shellDealerSocket MessageSender, params ExecuteMessageParams) error {
+	userExpressions := map[string]any{}
+	for name, expression := range params.UserExpressions {
+		userExpressions[name] = expression
+	}
 	requestParams := RequestParams{
-		MessageID: fmt.Sprintf("%s|%s", params.MessageID, time.Now().Format(time.RFC3339)),
-		SessionID: params.SessionID,
-		MsgType:   "execute_request",
-		Username:  "username",
-		Key:       params.Key,
+		MessageID:       fmt.Sprintf("%s|%s", params.MessageID, time.Now().Format(time.RFC3339)),
+		SessionID:       params.SessionID,
+		MsgType:         "execute_request",
+		Username:        "username",
+		Key:             params.Key,
+		SignatureScheme: params.SignatureScheme,
 		Content: map[string]any{
 			"code":             params.Code,
-			"silent":           false,
-			"store_history":    true,
-			"user_expressions": map[string]any{},
-			"allow_stdin":      true,
+			"silent":           params.Silent,
+			"store_history":    !params.Silent,
+			"user_expressions": userExpressions,
+			"allow_stdin":      !params.Silent,
 			"stop_on_error":    true,
 		},
 	}
@@ -251,13 +346,14 @@ type ShutdownMessageParams struct {
 }
 
 // SendShutdownMessage sends a shutdown_request message to the kernel.
-func SendShutdownMessage(controlDealerSocket *zmq4.Socket, params ShutdownMessageParams) error {
+func SendShutdownMessage(controlDealerSocket MessageSender, params ShutdownMessageParams) error {
 	requestParams := RequestParams{
-		MessageID: params.MessageID,
-		SessionID: params.SessionID,
-		MsgType:   "shutdown_request",
-		Username:  "username",
-		Key:       params.Key,
+		MessageID:       params.MessageID,
+		SessionID:       params.SessionID,
+		MsgType:         "shutdown_request",
+		Username:        "username",
+		Key:             params.Key,
+		SignatureScheme: params.SignatureScheme,
 		Content: map[string]any{
 			"restart": params.Restart,
 		},
@@ -272,14 +368,15 @@ func SendShutdownMessage(controlDealerSocket *zmq4.Socket, params ShutdownMessag
 }
 
 // SendInterruptMessage sends an interrupt_request message to the kernel.
-func SendInterruptMessage(controlDealerSocket *zmq4.Socket, params MessageParams) error {
+func SendInterruptMessage(controlDealerSocket MessageSender, params MessageParams) error {
 	requestParams := RequestParams{
-		MessageID: params.MessageID,
-		SessionID: params.SessionID,
-		MsgType:   "interrupt_request",
-		Username:  "username",
-		Key:       params.Key,
-		Content:   map[string]any{},
+		MessageID:       params.MessageID,
+		SessionID:       params.SessionID,
+		MsgType:         "interrupt_request",
+		Username:        "username",
+		Key:             params.Key,
+		SignatureScheme: params.SignatureScheme,
+		Content:         map[string]any{},
 	}
 
 	if err := sendMessage(controlDealerSocket, requestParams); err != nil {
@@ -291,13 +388,14 @@ func SendInterruptMessage(controlDealerSocket *zmq4.Socket, params MessageParams
 }
 
 // SendInputReplyMessage sends an input_reply message to the kernel in response to an input_request.
-func SendInputReplyMessage(stdinDealerSocket *zmq4.Socket, params InputReplyMessageParams) error {
+func SendInputReplyMessage(stdinDealerSocket MessageSender, params InputReplyMessageParams) error {
 	requestParams := RequestParams{
-		MessageID: params.MessageID,
-		SessionID: params.SessionID,
-		MsgType:   "input_reply",
-		Username:  "username",
-		Key:       params.Key,
+		MessageID:       params.MessageID,
+		SessionID:       params.SessionID,
+		MsgType:         "input_reply",
+		Username:        "username",
+		Key:             params.Key,
+		SignatureScheme: params.SignatureScheme,
 		Content: map[string]any{
 			"value": params.Value,
 		},
@@ -312,13 +410,14 @@ func SendInputReplyMessage(stdinDealerSocket *zmq4.Socket, params InputReplyMess
 }
 
 // SendInspectRequest sends an inspect_request message to the kernel.
-func SendInspectRequest(shellDealerSocket *zmq4.Socket, params InspectRequestParams) error {
+func SendInspectRequest(shellDealerSocket MessageSender, params InspectRequestParams) error {
 	requestParams := RequestParams{
-		MessageID: params.MessageID,
-		SessionID: params.SessionID,
-		MsgType:   "inspect_request",
-		Username:  "username",
-		Key:       params.Key,
+		MessageID:       params.MessageID,
+		SessionID:       params.SessionID,
+		MsgType:         "inspect_request",
+		Username:        "username",
+		Key:             params.Key,
+		SignatureScheme: params.SignatureScheme,
 		Content: map[string]any{
 			"code":         params.Code,
 			"cursor_pos":   params.CursorPos,
@@ -335,13 +434,14 @@ func SendInspectRequest(shellDealerSocket *zmq4.Socket, params InspectRequestPar
 }
 
 // SendCompleteRequest sends a complete_request message to the kernel.
-func SendCompleteRequest(shellDealerSocket *zmq4.Socket, params CompleteRequestParams) error {
+func SendCompleteRequest(shellDealerSocket MessageSender, params CompleteRequestParams) error {
 	requestParams := RequestParams{
-		MessageID: params.MessageID,
-		SessionID: params.SessionID,
-		MsgType:   "complete_request",
-		Username:  "username",
-		Key:       params.Key,
+		MessageID:       params.MessageID,
+		SessionID:       params.SessionID,
+		MsgType:         "complete_request",
+		Username:        "username",
+		Key:             params.Key,
+		SignatureScheme: params.SignatureScheme,
 		Content: map[string]any{
 			"code":       params.Code,
 			"cursor_pos": params.CursorPos,
@@ -355,3 +455,100 @@ func SendCompleteRequest(shellDealerSocket *zmq4.Socket, params CompleteRequestP
 	log.Println("complete_request 💬 sent successfully")
 	return nil
 }
+
+// CommMessageParams contains parameters for comm_open, comm_msg and comm_close
+// messages. TargetName is only used by comm_open.
+type CommMessageParams struct {
+	MessageParams
+	CommID     string
+	TargetName string
+	Data       map[string]any
+	Metadata   map[string]any
+	Buffers    [][]byte
+}
+
+// SendCommOpen sends a comm_open message to the kernel on the shell socket.
+func SendCommOpen(shellDealerSocket MessageSender, params CommMessageParams) error {
+	return sendCommMessage(shellDealerSocket, "comm_open", params, map[string]any{
+		"comm_id":     params.CommID,
+		"target_name": params.TargetName,
+		"data":        commData(params.Data),
+	})
+}
+
+// SendCommMessage sends a comm_msg message to the kernel on the shell socket.
+func SendCommMessage(shellDealerSocket MessageSender, params CommMessageParams) error {
+	return sendCommMessage(shellDealerSocket, "comm_msg", params, map[string]any{
+		"comm_id": params.CommID,
+		"data":    commData(params.Data),
+	})
+}
+
+// SendCommClose sends a comm_close message to the kernel on the shell socket.
+func SendCommClose(shellDealerSocket MessageSender, params CommMessageParams) error {
+	return sendCommMessage(shellDealerSocket, "comm_close", params, map[string]any{
+		"comm_id": params.CommID,
+		"data":    commData(params.Data),
+	})
+}
+
+func sendCommMessage(shellDealerSocket MessageSender, msgType string, params CommMessageParams, content map[string]any) error {
+	requestParams := RequestParams{
+		MessageID:       params.MessageID,
+		SessionID:       params.SessionID,
+		MsgType:         msgType,
+		Username:        "username",
+		Key:             params.Key,
+		SignatureScheme: params.SignatureScheme,
+		Content:         content,
+		Metadata:        params.Metadata,
+		Buffers:         params.Buffers,
+	}
+
+	if err := sendMessage(shellDealerSocket, requestParams); err != nil {
+		return fmt.Errorf("failed to send %s message: %w", msgType, err)
+	}
+
+	log.Printf("%s 💬 sent successfully", msgType)
+	return nil
+}
+
+// commData returns data, or an empty object when it is nil. The comm spec
+// requires data to be a dict.
+func commData(data map[string]any) map[string]any {
+	if data == nil {
+		return map[string]any{}
+	}
+	return data
+}
+
+// CommInfoRequestParams contains parameters for comm_info_request messages.
+type CommInfoRequestParams struct {
+	MessageParams
+	// TargetName filters the reply to comms with this target. Empty lists all comms.
+	TargetName string
+}
+
+// SendCommInfoRequest sends a comm_info_request message to the kernel.
+func SendCommInfoRequest(shellDealerSocket MessageSender, params CommInfoRequestParams) error {
+	content := map[string]any{}
+	if params.TargetName != "" {
+		content["target_name"] = params.TargetName
+	}
+	requestParams := RequestParams{
+		MessageID:       params.MessageID,
+		SessionID:       params.SessionID,
+		MsgType:         "comm_info_request",
+		Username:        "username",
+		Key:             params.Key,
+		SignatureScheme: params.SignatureScheme,
+		Content:         content,
+	}
+
+	if err := sendMessage(shellDealerSocket, requestParams); err != nil {
+		return fmt.Errorf("failed to send comm info request message: %w", err)
+	}
+
+	log.Println("comm_info_request 💬 sent successfully")
+	return nil
+}
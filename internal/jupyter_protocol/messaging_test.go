@@ -0,0 +1,79 @@
+package jupyter_protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultipartMessageBuffers(t *testing.T) {
+	msg := Message{
+		Header:       newHeader("id", "comm_msg", "session", "username"),
+		ParentHeader: map[string]any{},
+		Metadata:     map[string]any{},
+		Content:      map[string]any{"comm_id": "abc", "data": map[string]any{}},
+		Buffers:      [][]byte{{0x00, 0x01}, []byte("second")},
+	}
+
+	envelope, err := createMultipartMessage([]string{"identity"}, msg, "key", "")
+	assert.NoError(t, err)
+
+	identities, parsed, signature, err := ParseMultipartMessage(envelope)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"identity"}, identities)
+	assert.NotEmpty(t, signature)
+	assert.Equal(t, "abc", parsed.Content["comm_id"])
+	assert.Equal(t, msg.Buffers, parsed.Buffers)
+}
+
+func TestVerifySignature(t *testing.T) {
+	msg := Message{
+		Header:       newHeader("id", "status", "session", "username"),
+		ParentHeader: map[string]any{},
+		Metadata:     map[string]any{},
+		Content:      map[string]any{"execution_state": "idle"},
+	}
+
+	for _, scheme := range []string{"", "hmac-sha256", "hmac-sha512"} {
+		t.Run("accepts messages signed with "+scheme, func(t *testing.T) {
+			envelope, err := createMultipartMessage(nil, msg, "key", scheme)
+			assert.NoError(t, err)
+			assert.NoError(t, VerifySignature(envelope, "key", scheme))
+		})
+	}
+
+	t.Run("rejects a tampered message", func(t *testing.T) {
+		envelope, err := createMultipartMessage(nil, msg, "key", "hmac-sha256")
+		assert.NoError(t, err)
+		envelope[5] = []byte(`{"execution_state":"busy"}`)
+		assert.ErrorIs(t, VerifySignature(envelope, "key", "hmac-sha256"), ErrInvalidSignature)
+	})
+
+	t.Run("rejects the wrong key or scheme", func(t *testing.T) {
+		envelope, err := createMultipartMessage(nil, msg, "key", "hmac-sha256")
+		assert.NoError(t, err)
+		assert.ErrorIs(t, VerifySignature(envelope, "other", "hmac-sha256"), ErrInvalidSignature)
+		assert.ErrorIs(t, VerifySignature(envelope, "key", "hmac-sha512"), ErrInvalidSignature)
+	})
+
+	t.Run("rejects malformed envelopes", func(t *testing.T) {
+		assert.ErrorIs(t, VerifySignature([][]byte{[]byte(delimiter), []byte("sig")}, "key", ""), ErrInvalidSignature)
+		envelope, err := createMultipartMessage(nil, msg, "key", "")
+		assert.NoError(t, err)
+		envelope[1] = []byte("not-hex")
+		assert.ErrorIs(t, VerifySignature(envelope, "key", ""), ErrInvalidSignature)
+	})
+
+	t.Run("skips verification without a key", func(t *testing.T) {
+		envelope, err := createMultipartMessage(nil, msg, "", "")
+		assert.NoError(t, err)
+		assert.Empty(t, string(envelope[1]))
+		assert.NoError(t, VerifySignature(envelope, "", ""))
+	})
+
+	t.Run("unsupported schemes are errors", func(t *testing.T) {
+		assert.Error(t, ValidateSignatureScheme("hmac-md5"))
+		_, err := createMultipartMessage(nil, msg, "key", "hmac-md5")
+		assert.Error(t, err)
+	})
+}
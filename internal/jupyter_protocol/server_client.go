@@ -0,0 +1,192 @@
+package jupyter_protocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// serverRequestTimeout bounds each Jupyter Server REST call.
+const serverRequestTimeout = 15 * time.Second
+
+// ServerKernel is a kernel as reported by the Jupyter Server REST API.
+type ServerKernel struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	LastActivity   string `json:"last_activity"`
+	ExecutionState string `json:"execution_state"`
+	Connections    int    `json:"connections"`
+}
+
+// ServerError is a non-2xx response from a Jupyter Server.
+type ServerError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e ServerError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("jupyter server returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("jupyter server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// JupyterServerClient talks to the kernels REST API of a Jupyter Server
+// (JupyterLab, Notebook 7, or a bare jupyter-server).
+type JupyterServerClient struct {
+	baseURL *url.URL
+	token   string
+	http    *http.Client
+}
+
+// NewJupyterServerClient parses serverURL, e.g. "http://localhost:8888/lab".
+// A token may be passed directly or as the ?token= query parameter of the URL
+// JupyterLab prints on startup.
+func NewJupyterServerClient(serverURL, token string) (*JupyterServerClient, error) {
+	parsed, err := url.Parse(strings.TrimSpace(serverURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid jupyter server url: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("jupyter server url must start with http:// or https://")
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("jupyter server url is missing a host")
+	}
+	if token == "" {
+		token = parsed.Query().Get("token")
+	}
+
+	// The UI paths JupyterLab prints are not part of the API base.
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	for i, segment := range segments {
+		if segment == "lab" || segment == "tree" || segment == "notebooks" {
+			segments = segments[:i]
+			break
+		}
+	}
+	parsed.Path = strings.TrimSuffix("/"+strings.Join(segments, "/"), "/") + "/"
+	parsed.RawPath = ""
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+
+	return &JupyterServerClient{
+		baseURL: parsed,
+		token:   token,
+		http:    &http.Client{Timeout: serverRequestTimeout},
+	}, nil
+}
+
+// BaseURL returns the server's API base, e.g. "http://localhost:8888/".
+func (c *JupyterServerClient) BaseURL() string {
+	return c.baseURL.String()
+}
+
+// AuthHeader returns the headers that authenticate requests to the server.
+func (c *JupyterServerClient) AuthHeader() http.Header {
+	header := http.Header{}
+	if c.token != "" {
+		header.Set("Authorization", "token "+c.token)
+	}
+	return header
+}
+
+// ListKernels returns the kernels running on the server.
+func (c *JupyterServerClient) ListKernels(ctx context.Context) ([]ServerKernel, error) {
+	kernels := []ServerKernel{}
+	err := c.do(ctx, http.MethodGet, "api/kernels", nil, &kernels)
+	return kernels, err
+}
+
+// GetKernel returns one running kernel.
+func (c *JupyterServerClient) GetKernel(ctx context.Context, kernelID string) (ServerKernel, error) {
+	kernel := ServerKernel{}
+	err := c.do(ctx, http.MethodGet, "api/kernels/"+url.PathEscape(kernelID), nil, &kernel)
+	return kernel, err
+}
+
+// StartKernel starts a kernel from the named kernelspec. An empty name uses
+// the server's default kernelspec.
+func (c *JupyterServerClient) StartKernel(ctx context.Context, kernelName string) (ServerKernel, error) {
+	body := map[string]any{}
+	if kernelName != "" {
+		body["name"] = kernelName
+	}
+	kernel := ServerKernel{}
+	err := c.do(ctx, http.MethodPost, "api/kernels", body, &kernel)
+	return kernel, err
+}
+
+// ShutdownKernel stops a kernel on the server.
+func (c *JupyterServerClient) ShutdownKernel(ctx context.Context, kernelID string) error {
+	return c.do(ctx, http.MethodDelete, "api/kernels/"+url.PathEscape(kernelID), nil, nil)
+}
+
+// InterruptKernel interrupts a kernel using whichever interrupt mode its
+// kernelspec declares.
+func (c *JupyterServerClient) InterruptKernel(ctx context.Context, kernelID string) error {
+	return c.do(ctx, http.MethodPost, "api/kernels/"+url.PathEscape(kernelID)+"/interrupt", nil, nil)
+}
+
+// ChannelsURL returns the websocket URL that multiplexes a kernel's shell,
+// iopub, stdin and control channels.
+func (c *JupyterServerClient) ChannelsURL(kernelID, sessionID string) string {
+	channels := *c.baseURL.JoinPath("api/kernels", kernelID, "channels")
+	if channels.Scheme == "https" {
+		channels.Scheme = "wss"
+	} else {
+		channels.Scheme = "ws"
+	}
+	channels.RawQuery = url.Values{"session_id": {sessionID}}.Encode()
+	return channels.String()
+}
+
+// do sends a JSON request and decodes the JSON response into out, if given.
+func (c *JupyterServerClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.JoinPath(path).String(), reader)
+	if err != nil {
+		return err
+	}
+	req.Header = c.AuthHeader()
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("jupyter server request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		serverErr := ServerError{StatusCode: resp.StatusCode}
+		var payload struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&payload) == nil {
+			serverErr.Message = payload.Message
+		}
+		return serverErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid jupyter server response: %w", err)
+	}
+	return nil
+}
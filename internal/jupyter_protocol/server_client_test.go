@@ -0,0 +1,87 @@
+package jupyter_protocol
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJupyterServerClient(t *testing.T) {
+	t.Run("strips UI paths and reads the token from the URL", func(t *testing.T) {
+		client, err := NewJupyterServerClient("http://localhost:8888/user/me/lab/tree/a.ipynb?token=abc", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "http://localhost:8888/user/me/", client.BaseURL())
+		assert.Equal(t, "token abc", client.AuthHeader().Get("Authorization"))
+	})
+
+	t.Run("an explicit token wins", func(t *testing.T) {
+		client, err := NewJupyterServerClient("http://localhost:8888/?token=abc", "xyz")
+		assert.NoError(t, err)
+		assert.Equal(t, "http://localhost:8888/", client.BaseURL())
+		assert.Equal(t, "token xyz", client.AuthHeader().Get("Authorization"))
+	})
+
+	t.Run("builds websocket channel URLs", func(t *testing.T) {
+		client, _ := NewJupyterServerClient("https://hub.example.com/user/me", "")
+		assert.Equal(t, "wss://hub.example.com/user/me/api/kernels/k1/channels?session_id=s1", client.ChannelsURL("k1", "s1"))
+		assert.Empty(t, client.AuthHeader().Get("Authorization"))
+	})
+
+	t.Run("rejects non-http URLs", func(t *testing.T) {
+		_, err := NewJupyterServerClient("ftp://localhost:8888", "")
+		assert.Error(t, err)
+		_, err = NewJupyterServerClient("http:///lab", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestJupyterServerClientKernels(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Header.Get("Authorization") != "token secret":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"Forbidden"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/kernels":
+			_, _ = w.Write([]byte(`[{"id":"k1","name":"python3","execution_state":"idle","connections":1}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/kernels":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"k2","name":"python3"}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewJupyterServerClient(server.URL, "secret")
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	kernels, err := client.ListKernels(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []ServerKernel{{ID: "k1", Name: "python3", ExecutionState: "idle", Connections: 1}}, kernels)
+
+	kernel, err := client.StartKernel(ctx, "python3")
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", kernel.ID)
+
+	assert.NoError(t, client.InterruptKernel(ctx, "k2"))
+	assert.NoError(t, client.ShutdownKernel(ctx, "k2"))
+	assert.Equal(t, []string{
+		"GET /api/kernels",
+		"POST /api/kernels",
+		"POST /api/kernels/k2/interrupt",
+		"DELETE /api/kernels/k2",
+	}, requests)
+
+	unauthorized, _ := NewJupyterServerClient(server.URL, "wrong")
+	_, err = unauthorized.ListKernels(ctx)
+	var serverErr ServerError
+	assert.True(t, errors.As(err, &serverErr))
+	assert.Equal(t, ServerError{StatusCode: http.StatusForbidden, Message: "Forbidden"}, serverErr)
+}
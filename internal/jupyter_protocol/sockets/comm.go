@@ -0,0 +1,102 @@
+package sockets
+
+import (
+	"encoding/json"
+
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+// CommEvent carries a comm_open, comm_msg or comm_close sent by the kernel.
+// MessageId is the parent msg_id and is empty for comm traffic the kernel
+// sends outside of a request (e.g. progress updates from a background thread).
+// TargetName is only set for comm_open.
+type CommEvent struct {
+	KernelInstanceID string         `json:"kernelInstanceId"`
+	MessageId        string         `json:"messageId"`
+	CommID           string         `json:"commId"`
+	TargetName       string         `json:"targetName"`
+	Data             map[string]any `json:"data"`
+	Metadata         map[string]any `json:"metadata"`
+	Buffers          [][]byte       `json:"buffers"`
+}
+
+// CommInfoReplyEvent lists the kernel's open comms as a map of comm id to target name.
+type CommInfoReplyEvent struct {
+	KernelInstanceID string            `json:"kernelInstanceId"`
+	MessageId        string            `json:"messageId"`
+	Status           string            `json:"status"`
+	Comms            map[string]string `json:"comms"`
+}
+
+// commEventNames maps iopub comm message types to the event they are emitted as.
+var commEventNames = map[string]string{
+	IOPubSocket.CommOpen:  util.EventKernelCommOpen,
+	IOPubSocket.CommMsg:   util.EventKernelCommMsg,
+	IOPubSocket.CommClose: util.EventKernelCommClose,
+}
+
+// newCommEvent builds a CommEvent from a comm_open, comm_msg or comm_close message.
+func newCommEvent(instanceID, parentMsgID string, msg jupyter_protocol.Message) (CommEvent, bool) {
+	commID, ok := msg.Content["comm_id"].(string)
+	if !ok {
+		return CommEvent{}, false
+	}
+	event := CommEvent{
+		KernelInstanceID: instanceID,
+		MessageId:        parentMsgID,
+		CommID:           commID,
+		Data:             map[string]any{},
+		Metadata:         map[string]any{},
+		Buffers:          [][]byte{},
+	}
+	event.TargetName, _ = msg.Content["target_name"].(string)
+	if data, ok := msg.Content["data"].(map[string]any); ok {
+		event.Data = data
+	}
+	if msg.Metadata != nil {
+		event.Metadata = msg.Metadata
+	}
+	if msg.Buffers != nil {
+		event.Buffers = msg.Buffers
+	}
+	return event, true
+}
+
+// newCommInfoReplyEvent builds a CommInfoReplyEvent from a comm_info_reply message.
+func newCommInfoReplyEvent(instanceID, parentMsgID string, msg jupyter_protocol.Message) CommInfoReplyEvent {
+	event := CommInfoReplyEvent{
+		KernelInstanceID: instanceID,
+		MessageId:        parentMsgID,
+		Comms:            map[string]string{},
+	}
+	event.Status, _ = msg.Content["status"].(string)
+	comms, _ := msg.Content["comms"].(map[string]any)
+	for commID, info := range comms {
+		infoMap, _ := info.(map[string]any)
+		targetName, _ := infoMap["target_name"].(string)
+		event.Comms[commID] = targetName
+	}
+	return event
+}
+
+// mimeBundleToStrings flattens a display_data/execute_result mime bundle into
+// strings. JSON mime types such as application/vnd.jupyter.widget-view+json
+// carry objects, which are re-encoded as JSON text.
+func mimeBundleToStrings(dataMap map[string]any) map[string]string {
+	out := map[string]string{}
+	for mimeType, content := range dataMap {
+		switch value := content.(type) {
+		case string:
+			out[mimeType] = value
+		case nil:
+		default:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				continue
+			}
+			out[mimeType] = string(encoded)
+		}
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package sockets
+
+import (
+	"testing"
+
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCommEvent(t *testing.T) {
+	t.Run("comm_open carries target and state", func(t *testing.T) {
+		msg := jupyter_protocol.Message{
+			Content: map[string]any{
+				"comm_id":     "abc",
+				"target_name": "jupyter.widget",
+				"data":        map[string]any{"state": map[string]any{"value": float64(3)}},
+			},
+			Buffers: [][]byte{[]byte("raw")},
+		}
+		event, ok := newCommEvent("kernel-1", "", msg)
+		assert.True(t, ok)
+		assert.Equal(t, "kernel-1", event.KernelInstanceID)
+		assert.Equal(t, "abc", event.CommID)
+		assert.Equal(t, "jupyter.widget", event.TargetName)
+		assert.Equal(t, [][]byte{[]byte("raw")}, event.Buffers)
+		assert.Contains(t, event.Data, "state")
+	})
+
+	t.Run("missing comm id is dropped", func(t *testing.T) {
+		_, ok := newCommEvent("kernel-1", "", jupyter_protocol.Message{Content: map[string]any{}})
+		assert.False(t, ok)
+	})
+}
+
+func TestNewCommInfoReplyEvent(t *testing.T) {
+	msg := jupyter_protocol.Message{Content: map[string]any{
+		"status": "ok",
+		"comms": map[string]any{
+			"abc": map[string]any{"target_name": "jupyter.widget"},
+		},
+	}}
+	event := newCommInfoReplyEvent("kernel-1", "comm_info_1", msg)
+	assert.Equal(t, "ok", event.Status)
+	assert.Equal(t, map[string]string{"abc": "jupyter.widget"}, event.Comms)
+}
+
+func TestMimeBundleToStrings(t *testing.T) {
+	bundle := mimeBundleToStrings(map[string]any{
+		"text/plain": "IntSlider(value=3)",
+		"application/vnd.jupyter.widget-view+json": map[string]any{
+			"model_id":      "abc",
+			"version_major": float64(2),
+		},
+		"application/empty": nil,
+	})
+	assert.Equal(t, "IntSlider(value=3)", bundle["text/plain"])
+	assert.JSONEq(t, `{"model_id":"abc","version_major":2}`, bundle["application/vnd.jupyter.widget-view+json"])
+	assert.NotContains(t, bundle, "application/empty")
+}
@@ -15,11 +15,15 @@ var ShellSocket = struct {
 
 	// ShutdownReply is the response to a shutdown_request message, confirming kernel shutdown
 	ShutdownReply string
+
+	// CommInfoReply is the response to a comm_info_request message, listing the kernel's open comms
+	CommInfoReply string
 }{
 	ExecuteReply:  "execute_reply",
 	InspectReply:  "inspect_reply",
 	CompleteReply: "complete_reply",
 	ShutdownReply: "shutdown_reply",
+	CommInfoReply: "comm_info_reply",
 }
 
 // ControlSocket contains message type constants for the control socket
@@ -45,6 +49,12 @@ var IOPubSocket = struct {
 	// DisplayData is a message containing display data output (similar to execute_result but for display purposes)
 	DisplayData string
 
+	// UpdateDisplayData is a message replacing the data of an earlier display_data with the same display_id
+	UpdateDisplayData string
+
+	// ClearOutput is a message asking the frontend to clear the execution's output, optionally waiting for the next output
+	ClearOutput string
+
 	// ExecuteInput is a message indicating that code input was executed, containing the code and execution count
 	ExecuteInput string
 
@@ -53,13 +63,27 @@ var IOPubSocket = struct {
 
 	// Error is a message containing error information from code execution, including traceback
 	Error string
+
+	// CommOpen is a message announcing a new comm opened by the kernel (e.g. an ipywidgets model)
+	CommOpen string
+
+	// CommMsg is a message sent over an open comm
+	CommMsg string
+
+	// CommClose is a message announcing that a comm was closed
+	CommClose string
 }{
-	Stream:        "stream",
-	ExecuteResult: "execute_result",
-	DisplayData:   "display_data",
-	ExecuteInput:  "execute_input",
-	Status:        "status",
-	Error:         "error",
+	Stream:            "stream",
+	ExecuteResult:     "execute_result",
+	DisplayData:       "display_data",
+	UpdateDisplayData: "update_display_data",
+	ClearOutput:       "clear_output",
+	ExecuteInput:      "execute_input",
+	Status:            "status",
+	Error:             "error",
+	CommOpen:          "comm_open",
+	CommMsg:           "comm_msg",
+	CommClose:         "comm_close",
 }
 
 // StdinSocket contains message type constants for the stdin socket
@@ -0,0 +1,108 @@
+package sockets
+
+import "strings"
+
+// maxTrackedDisplayIDs bounds displayTracker so a kernel that mints a fresh
+// display_id per frame cannot grow it without limit.
+const maxTrackedDisplayIDs = 1000
+
+// ClearOutputEvent tells the frontend to clear a code block's output now.
+// clear_output(wait=True) is resolved by the backend: the event is only sent
+// once the next output for the execution arrives.
+type ClearOutputEvent struct {
+	MessageId string `json:"messageId"`
+}
+
+// displayTracker maps display_ids to the executions that displayed them, so an
+// update_display_data can be routed to the right code blocks, and tracks
+// clear_output(wait=True) requests that are waiting for the next output. It is
+// owned by the socket pipeline goroutine and is not safe for concurrent use.
+type displayTracker struct {
+	byDisplayID  map[string][]string
+	pendingClear map[string]bool
+}
+
+func newDisplayTracker() *displayTracker {
+	return &displayTracker{
+		byDisplayID:  map[string][]string{},
+		pendingClear: map[string]bool{},
+	}
+}
+
+// codeBlockID returns the {codeBlockId} part of a message id.
+func codeBlockID(msgId string) string {
+	id, _, _ := strings.Cut(msgId, "|")
+	return id
+}
+
+// startExecution forgets displays from earlier executions of the same code
+// block; the frontend replaces that block's output when it re-runs.
+func (d *displayTracker) startExecution(msgId string) {
+	block := codeBlockID(msgId)
+	for displayID, msgIds := range d.byDisplayID {
+		kept := msgIds[:0]
+		for _, id := range msgIds {
+			if codeBlockID(id) != block || id == msgId {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == 0 {
+			delete(d.byDisplayID, displayID)
+		} else {
+			d.byDisplayID[displayID] = kept
+		}
+	}
+}
+
+// finishExecution drops a clear_output(wait=True) that no output followed.
+func (d *displayTracker) finishExecution(msgId string) {
+	delete(d.pendingClear, msgId)
+}
+
+// trackDisplay records that msgId displayed an output with displayID.
+func (d *displayTracker) trackDisplay(displayID, msgId string) {
+	msgIds, exists := d.byDisplayID[displayID]
+	if !exists && len(d.byDisplayID) >= maxTrackedDisplayIDs {
+		return
+	}
+	for _, id := range msgIds {
+		if id == msgId {
+			return
+		}
+	}
+	d.byDisplayID[displayID] = append(msgIds, msgId)
+}
+
+// targets returns the message ids of the executions showing displayID.
+func (d *displayTracker) targets(displayID string) []string {
+	return d.byDisplayID[displayID]
+}
+
+// clearOutput handles a clear_output for msgId and reports whether the output
+// should be cleared immediately.
+func (d *displayTracker) clearOutput(msgId string, wait bool) bool {
+	if wait {
+		d.pendingClear[msgId] = true
+		return false
+	}
+	delete(d.pendingClear, msgId)
+	return true
+}
+
+// takePendingClear reports whether a deferred clear is waiting for msgId's
+// next output, and consumes it.
+func (d *displayTracker) takePendingClear(msgId string) bool {
+	if !d.pendingClear[msgId] {
+		return false
+	}
+	delete(d.pendingClear, msgId)
+	return true
+}
+
+// TransientDisplayID returns content.transient.display_id of a display_data
+// or update_display_data message, if any.
+func TransientDisplayID(content map[string]any) string {
+	transient, _ := content["transient"].(map[string]any)
+	displayID, _ := transient["display_id"].(string)
+	return displayID
+}
@@ -0,0 +1,50 @@
+package sockets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayTracker(t *testing.T) {
+	t.Run("routes updates to every execution showing the display", func(t *testing.T) {
+		d := newDisplayTracker()
+		d.trackDisplay("bar", "a|1|t")
+		d.trackDisplay("bar", "b|2|t")
+		d.trackDisplay("bar", "b|2|t")
+		assert.Equal(t, []string{"a|1|t", "b|2|t"}, d.targets("bar"))
+		assert.Empty(t, d.targets("missing"))
+	})
+
+	t.Run("re-running a block forgets its old displays", func(t *testing.T) {
+		d := newDisplayTracker()
+		d.trackDisplay("bar", "a|1|t")
+		d.trackDisplay("bar", "b|2|t")
+		d.trackDisplay("other", "a|1|t")
+
+		d.startExecution("a|3|t")
+		assert.Equal(t, []string{"b|2|t"}, d.targets("bar"))
+		assert.Empty(t, d.targets("other"))
+	})
+
+	t.Run("clear_output with wait is deferred to the next output", func(t *testing.T) {
+		d := newDisplayTracker()
+		assert.False(t, d.clearOutput("a|1|t", true))
+		assert.True(t, d.takePendingClear("a|1|t"))
+		assert.False(t, d.takePendingClear("a|1|t"))
+	})
+
+	t.Run("clear_output without wait clears now", func(t *testing.T) {
+		d := newDisplayTracker()
+		d.clearOutput("a|1|t", true)
+		assert.True(t, d.clearOutput("a|1|t", false))
+		assert.False(t, d.takePendingClear("a|1|t"))
+	})
+
+	t.Run("finished executions drop deferred clears", func(t *testing.T) {
+		d := newDisplayTracker()
+		d.clearOutput("a|1|t", true)
+		d.finishExecution("a|1|t")
+		assert.False(t, d.takePendingClear("a|1|t"))
+	})
+}
@@ -13,6 +13,9 @@ func init() {
 	application.RegisterEvent[StreamEvent](util.EventCodeBlockStream)
 	application.RegisterEvent[ExecuteResultEvent](util.EventCodeBlockExecuteResult)
 	application.RegisterEvent[ExecuteResultEvent](util.EventCodeBlockDisplayData)
+	application.RegisterEvent[ExecuteResultEvent](util.EventCodeBlockUpdateDisplayData)
+	application.RegisterEvent[ClearOutputEvent](util.EventCodeBlockClearOutput)
+	application.RegisterEvent[OutputTruncatedEvent](util.EventCodeBlockOutputTruncated)
 	application.RegisterEvent[ExecuteInputEvent](util.EventCodeBlockExecuteInput)
 	application.RegisterEvent[CodeBlockStatusEvent](util.EventCodeBlockStatus)
 	application.RegisterEvent[IopubErrorEvent](util.EventCodeBlockIopubError)
@@ -20,4 +23,8 @@ func init() {
 	application.RegisterEvent[ExecuteReplyEvent](util.EventCodeBlockExecuteReply)
 	application.RegisterEvent[InspectReplyEvent](util.EventCodeBlockInspectReply)
 	application.RegisterEvent[CompleteReplyEvent](util.EventCodeBlockCompleteReply)
+	application.RegisterEvent[CommEvent](util.EventKernelCommOpen)
+	application.RegisterEvent[CommEvent](util.EventKernelCommMsg)
+	application.RegisterEvent[CommEvent](util.EventKernelCommClose)
+	application.RegisterEvent[CommInfoReplyEvent](util.EventKernelCommInfoReply)
 }
@@ -1,25 +1,21 @@
 package sockets
 
 import (
-	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/etesam913/bytebook/internal/jupyter_protocol"
 	"github.com/etesam913/bytebook/internal/util"
-	"github.com/pebbe/zmq4"
 	"github.com/robert-nix/ansihtml"
-	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
-type ioPubSocket struct {
-	socket *zmq4.Socket
-}
-
+// ExecuteResultEvent carries execute_result, display_data and
+// update_display_data outputs. DisplayID is set when the kernel tagged the
+// output with a display_id so later updates can replace it in place.
 type ExecuteResultEvent struct {
 	MessageId string            `json:"messageId"`
 	Data      map[string]string `json:"data"`
+	DisplayID string            `json:"displayId"`
 }
 
 type ExecuteInputEvent struct {
@@ -52,186 +48,164 @@ type IopubErrorEvent struct {
 	ErrorTraceback []string `json:"errorTraceback"`
 }
 
-func createIOPubSocket(p CreateParams) *ioPubSocket {
-	sock, err := zmq4.NewSocket(zmq4.Type(zmq4.SUB))
-	if err != nil {
-		log.Print("Could not create io 🍺 socket subscriber:", err)
-		return nil
-	}
-	return &ioPubSocket{socket: sock}
-}
-
-func (i *ioPubSocket) Listen(p CreateParams) {
-	if i.socket == nil {
-		log.Println("🍺 IOPub socket is nil, cannot listen")
+// handleIOPub processes a message from the iopub channel. Stream output and
+// display updates are coalesced in the output buffer; every other output
+// flushes the buffer first so the frontend sees output in kernel order.
+func (pl *pipeline) handleIOPub(msg jupyter_protocol.Message) {
+	p := pl.p
+	msgId, hasParent := msg.ParentHeader["msg_id"].(string)
+
+	// Comm traffic is kernel-scoped and may have no parent request.
+	if eventName, isComm := commEventNames[msg.Header.MsgType]; isComm {
+		if event, ok := newCommEvent(p.InstanceID, msgId, msg); ok {
+			pl.sink.Emit(eventName, event)
+		}
 		return
 	}
-	defer i.socket.Close()
-
-	ioPubAddress := fmt.Sprintf("tcp://%s:%d", p.ConnectionInfo.IP, p.ConnectionInfo.IOPubPort)
-	if err := i.socket.Connect(ioPubAddress); err != nil {
-		log.Printf("Could not connect io 🍺 socket: %v", err)
+	if !hasParent {
 		return
 	}
-
-	app := application.Get()
-	if err := i.socket.SetSubscribe(""); err != nil {
-		log.Printf("Could not set subscribe: %v", err)
-		return
+	if p.OnIOPubMessage != nil {
+		p.OnIOPubMessage(msgId, msg)
 	}
 
-	for {
-		select {
-		case <-p.Ctx.Done():
-			log.Println("🛑 IOPub socket listener received context cancellation")
+	switch msg.Header.MsgType {
+	case IOPubSocket.Stream:
+		name, isNameString := msg.Content["name"].(string)
+		text, isTextString := msg.Content["text"].(string)
+		if isNameString && isTextString {
+			pl.flushPendingClear(msgId)
+			pl.output.addStream(msgId, name, text)
+		}
+	case IOPubSocket.ExecuteResult:
+		dataMap, exists := msg.Content["data"].(map[string]any)
+		if exists {
+			pl.flushPendingClear(msgId)
+			pl.flushOutput()
+			pl.sink.Emit(util.EventCodeBlockExecuteResult, ExecuteResultEvent{MessageId: msgId, Data: mimeBundleToStrings(dataMap)})
+		}
+	case IOPubSocket.DisplayData:
+		dataMap, exists := msg.Content["data"].(map[string]any)
+		if !exists {
 			return
-		default:
-			envelope, err := i.socket.RecvMessageBytes(zmq4.Flag(zmq4.DONTWAIT))
-			if err != nil {
-				if strings.Contains(err.Error(), "resource temporarily unavailable") {
-					time.Sleep(50 * time.Millisecond)
-					continue
-				}
-				log.Println("🍺 Error receiving message:", err)
-				continue
+		}
+		displayID := TransientDisplayID(msg.Content)
+		if displayID != "" {
+			pl.displays.trackDisplay(displayID, msgId)
+		}
+		pl.flushPendingClear(msgId)
+		pl.flushOutput()
+		pl.sink.Emit(util.EventCodeBlockDisplayData, ExecuteResultEvent{MessageId: msgId, Data: mimeBundleToStrings(dataMap), DisplayID: displayID})
+	case IOPubSocket.UpdateDisplayData:
+		// The update may come from a different execution than the one that
+		// created the display, so it is routed by display_id.
+		dataMap, exists := msg.Content["data"].(map[string]any)
+		displayID := TransientDisplayID(msg.Content)
+		if !exists || displayID == "" {
+			return
+		}
+		data := mimeBundleToStrings(dataMap)
+		for _, target := range pl.displays.targets(displayID) {
+			pl.output.addUpdate(ExecuteResultEvent{MessageId: target, Data: data, DisplayID: displayID})
+		}
+	case IOPubSocket.ClearOutput:
+		wait, _ := msg.Content["wait"].(bool)
+		if pl.displays.clearOutput(msgId, wait) {
+			pl.emitClearOutput(msgId)
+		}
+	case IOPubSocket.ExecuteInput:
+		code, isCodeString := msg.Content["code"].(string)
+		executionCount, isExecutionCountFloat := msg.Content["execution_count"].(float64)
+		pl.displays.startExecution(msgId)
+		if isCodeString && isExecutionCountFloat {
+			pl.flushOutput()
+			pl.sink.Emit(util.EventCodeBlockExecuteInput, ExecuteInputEvent{
+				MessageId:      msgId,
+				Code:           code,
+				ExecutionCount: int(executionCount),
+			})
+		}
+	case IOPubSocket.Status:
+		status, isString := msg.Content["execution_state"].(string)
+		if !isString {
+			return
+		}
+		if status == "idle" {
+			// All of the request's output arrives before its idle status.
+			pl.flushOutput()
+		}
+		pl.sink.Emit(util.EventKernelInstanceStatus, KernelStatusEvent{ID: p.InstanceID, Status: status})
+		parentMessageType, ok := msg.ParentHeader["msg_type"].(string)
+		if !ok {
+			return
+		}
+		if parentMessageType == "execute_request" {
+			if status == "idle" {
+				pl.output.finishExecution(msgId)
+				pl.displays.finishExecution(msgId)
 			}
-
-			_, msg, _, err := jupyter_protocol.ParseMultipartMessage(envelope)
+			if p.OnExecuteStatus != nil {
+				p.OnExecuteStatus(status, msgId)
+			}
+			curTime := time.Now()
+			msgParts := strings.Split(msgId, "|")
+			// messageId format: {codeBlockId}|{executionId}|{startTime}
+			if len(msgParts) < 3 {
+				return
+			}
+			requestTime, err := time.Parse(time.RFC3339, msgParts[2])
 			if err != nil {
-				log.Println("🍺 Error parsing message:", err)
-				continue
+				return
 			}
-
-			msgId, ok := msg.ParentHeader["msg_id"].(string)
-			if !ok {
-				continue
+			duration := ""
+			if status == "idle" {
+				duration = util.FormatExecutionDuration(requestTime, curTime)
 			}
-
-			switch msg.Header.MsgType {
-			case IOPubSocket.Stream:
-				name, isNameString := msg.Content["name"].(string)
-				text, isTextString := msg.Content["text"].(string)
-				if isNameString && isTextString && app != nil {
-					htmlStr := string(ansihtml.ConvertToHTML([]byte(text)))
-					app.Event.EmitEvent(&application.CustomEvent{
-						Name: util.EventCodeBlockStream,
-						Data: StreamEvent{MessageId: msgId, Name: name, Text: htmlStr},
-					})
-				}
-			case IOPubSocket.ExecuteResult:
-				dataMap, exists := msg.Content["data"].(map[string]any)
-				if exists && app != nil {
-					executionResult := ExecuteResultEvent{MessageId: msgId, Data: map[string]string{}}
-					for mimeType, content := range dataMap {
-						if contentStr, ok := content.(string); ok {
-							executionResult.Data[mimeType] = contentStr
-						}
-					}
-					app.Event.EmitEvent(&application.CustomEvent{
-						Name: util.EventCodeBlockExecuteResult,
-						Data: executionResult,
-					})
-				}
-			case IOPubSocket.DisplayData:
-				dataMap, exists := msg.Content["data"].(map[string]any)
-				if exists && app != nil {
-					displayData := ExecuteResultEvent{MessageId: msgId, Data: map[string]string{}}
-					for mimeType, content := range dataMap {
-						if contentStr, ok := content.(string); ok {
-							displayData.Data[mimeType] = contentStr
-						}
-					}
-					app.Event.EmitEvent(&application.CustomEvent{
-						Name: util.EventCodeBlockDisplayData,
-						Data: displayData,
-					})
-				}
-			case IOPubSocket.ExecuteInput:
-				code, isCodeString := msg.Content["code"].(string)
-				executionCount, isExecutionCountFloat := msg.Content["execution_count"].(float64)
-				if isCodeString && isExecutionCountFloat && app != nil {
-					app.Event.EmitEvent(&application.CustomEvent{
-						Name: util.EventCodeBlockExecuteInput,
-						Data: ExecuteInputEvent{
-							MessageId:      msgId,
-							Code:           code,
-							ExecutionCount: int(executionCount),
-						},
-					})
-				}
-			case IOPubSocket.Status:
-				status, isString := msg.Content["execution_state"].(string)
-				if !isString {
-					continue
-				}
-				if app != nil {
-					app.Event.EmitEvent(&application.CustomEvent{
-						Name: util.EventKernelInstanceStatus,
-						Data: KernelStatusEvent{ID: p.InstanceID, Status: status},
-					})
-				}
-				parentMessageType, ok := msg.ParentHeader["msg_type"].(string)
-				if !ok {
-					continue
-				}
-				if parentMessageType == "execute_request" {
-					if p.OnExecuteStatus != nil {
-						p.OnExecuteStatus(status, msgId)
-					}
-					curTime := time.Now()
-					msgParts := strings.Split(msgId, "|")
-					// messageId format: {codeBlockId}|{executionId}|{startTime}
-					if len(msgParts) < 3 {
-						continue
-					}
-					requestTime, err := time.Parse(time.RFC3339, msgParts[2])
-					if err != nil {
-						continue
-					}
-					duration := ""
-					if status == "idle" {
-						duration = util.FormatExecutionDuration(requestTime, curTime)
-					}
-					if app != nil {
-						app.Event.EmitEvent(&application.CustomEvent{
-							Name: util.EventCodeBlockStatus,
-							Data: CodeBlockStatusEvent{MessageId: msgId, Status: status, Duration: duration},
-						})
-					}
-				} else if parentMessageType == "shutdown_request" && status == "idle" {
-					p.Cancel()
-				}
-			case IOPubSocket.Error:
-				errorName := ""
-				errorValue := ""
-				errorTraceback := []string{}
-				if uncleanTraceback, ok := msg.Content["traceback"].([]any); ok {
-					for _, item := range uncleanTraceback {
-						if ansiStr, ok := item.(string); ok {
-							htmlStr := string(ansihtml.ConvertToHTML([]byte(ansiStr)))
-							errorTraceback = append(errorTraceback, htmlStr)
-						}
-					}
-				}
-				if v, ok := msg.Content["ename"].(string); ok {
-					errorName = v
-				}
-				if v, ok := msg.Content["evalue"].(string); ok {
-					errorValue = v
-				}
-				if app != nil {
-					app.Event.EmitEvent(&application.CustomEvent{
-						Name: util.EventCodeBlockIopubError,
-						Data: IopubErrorEvent{
-							MessageId:      msgId,
-							ErrorName:      errorName,
-							ErrorValue:     errorValue,
-							ErrorTraceback: errorTraceback,
-						},
-					})
+			pl.sink.Emit(util.EventCodeBlockStatus, CodeBlockStatusEvent{MessageId: msgId, Status: status, Duration: duration})
+		} else if parentMessageType == "shutdown_request" && status == "idle" {
+			p.Cancel()
+		}
+	case IOPubSocket.Error:
+		errorName := ""
+		errorValue := ""
+		errorTraceback := []string{}
+		if uncleanTraceback, ok := msg.Content["traceback"].([]any); ok {
+			for _, item := range uncleanTraceback {
+				if ansiStr, ok := item.(string); ok {
+					htmlStr := string(ansihtml.ConvertToHTML([]byte(ansiStr)))
+					errorTraceback = append(errorTraceback, htmlStr)
 				}
 			}
-			time.Sleep(100 * time.Millisecond)
 		}
+		if v, ok := msg.Content["ename"].(string); ok {
+			errorName = v
+		}
+		if v, ok := msg.Content["evalue"].(string); ok {
+			errorValue = v
+		}
+		pl.flushPendingClear(msgId)
+		pl.flushOutput()
+		pl.sink.Emit(util.EventCodeBlockIopubError, IopubErrorEvent{
+			MessageId:      msgId,
+			ErrorName:      errorName,
+			ErrorValue:     errorValue,
+			ErrorTraceback: errorTraceback,
+		})
+	}
+}
+
+// flushPendingClear emits a deferred clear_output(wait=True) right before the
+// execution's next output, so output is replaced without flicker.
+func (pl *pipeline) flushPendingClear(msgId string) {
+	if pl.displays.takePendingClear(msgId) {
+		pl.emitClearOutput(msgId)
 	}
 }
+
+// emitClearOutput clears an execution's output. Its still-buffered output
+// would be cleared anyway, so it is dropped instead of emitted.
+func (pl *pipeline) emitClearOutput(msgId string) {
+	pl.output.clearExecution(msgId)
+	pl.flushOutput()
+	pl.sink.Emit(util.EventCodeBlockClearOutput, ClearOutputEvent{MessageId: msgId})
+}
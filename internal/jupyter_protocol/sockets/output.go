@@ -0,0 +1,289 @@
+package sockets
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/robert-nix/ansihtml"
+)
+
+const (
+	// outputFlushInterval is how long stream chunks and display updates are
+	// coalesced before being emitted, capping each execution at roughly 20
+	// output events per second.
+	outputFlushInterval = 50 * time.Millisecond
+	// maxStreamBytesPerExecution is how much stream text an execution may
+	// send to the frontend. Anything beyond it is held in the overflow store.
+	maxStreamBytesPerExecution = 512 * 1024
+	// maxOverflowBytes caps the held-back text per execution; the rest is
+	// only counted.
+	maxOverflowBytes = 8 * 1024 * 1024
+	// maxOverflowHandles is how many executions' held-back output is kept.
+	maxOverflowHandles = 32
+)
+
+// OutputTruncatedEvent tells the frontend that part of an execution's stream
+// output was held back. The rest can be fetched page by page using Handle.
+type OutputTruncatedEvent struct {
+	MessageId   string `json:"messageId"`
+	Handle      string `json:"handle"`
+	HiddenBytes int    `json:"hiddenBytes"`
+}
+
+// TruncatedOutputPage is a page of held-back stream output, converted to HTML.
+// HiddenBytes also counts output beyond maxOverflowBytes that was discarded.
+type TruncatedOutputPage struct {
+	Text        string `json:"text"`
+	Offset      int    `json:"offset"`
+	NextOffset  int    `json:"nextOffset"`
+	TotalBytes  int    `json:"totalBytes"`
+	HiddenBytes int    `json:"hiddenBytes"`
+	Done        bool   `json:"done"`
+}
+
+type pendingKind int
+
+const (
+	pendingStream pendingKind = iota
+	pendingUpdate
+	pendingTruncated
+)
+
+// pendingOutput is an output event waiting for the next flush.
+type pendingOutput struct {
+	kind   pendingKind
+	msgId  string
+	name   string
+	text   []byte
+	update ExecuteResultEvent
+	handle string
+}
+
+// outputBuffer coalesces stream chunks and update_display_data messages
+// between flushes and enforces the per-execution stream budget. It is owned
+// by the pipeline goroutine; only the overflow store is shared.
+type outputBuffer struct {
+	pending     []*pendingOutput
+	deadline    time.Time
+	streamBytes map[string]int
+	overflow    *overflowStore
+}
+
+func newOutputBuffer() *outputBuffer {
+	return &outputBuffer{
+		streamBytes: map[string]int{},
+		overflow:    newOverflowStore(),
+	}
+}
+
+// flushDeadline returns when pending output must be flushed, if any is pending.
+func (b *outputBuffer) flushDeadline() (time.Time, bool) {
+	return b.deadline, len(b.pending) > 0
+}
+
+func (b *outputBuffer) push(item *pendingOutput) {
+	if len(b.pending) == 0 {
+		b.deadline = time.Now().Add(outputFlushInterval)
+	}
+	b.pending = append(b.pending, item)
+}
+
+// addStream buffers stream text. Consecutive chunks for the same execution and
+// stream are merged; text past the execution's budget goes to the overflow store.
+func (b *outputBuffer) addStream(msgId, name, text string) {
+	visible, rest := splitAt(text, maxStreamBytesPerExecution-b.streamBytes[msgId])
+	b.streamBytes[msgId] += len(visible)
+
+	if visible != "" {
+		if last := len(b.pending) - 1; last >= 0 && b.pending[last].kind == pendingStream &&
+			b.pending[last].msgId == msgId && b.pending[last].name == name {
+			b.pending[last].text = append(b.pending[last].text, visible...)
+		} else {
+			b.push(&pendingOutput{kind: pendingStream, msgId: msgId, name: name, text: []byte(visible)})
+		}
+	}
+
+	if rest != "" {
+		handle := b.overflow.append(msgId, rest)
+		for _, item := range b.pending {
+			if item.kind == pendingTruncated && item.msgId == msgId {
+				return
+			}
+		}
+		b.push(&pendingOutput{kind: pendingTruncated, msgId: msgId, handle: handle})
+	}
+}
+
+// addUpdate buffers an update_display_data event, replacing any pending update
+// for the same display in the same execution.
+func (b *outputBuffer) addUpdate(event ExecuteResultEvent) {
+	for _, item := range b.pending {
+		if item.kind == pendingUpdate && item.msgId == event.MessageId && item.update.DisplayID == event.DisplayID {
+			item.update = event
+			return
+		}
+	}
+	b.push(&pendingOutput{kind: pendingUpdate, msgId: event.MessageId, update: event})
+}
+
+// clearExecution drops pending output for an execution whose output is being
+// cleared, and restarts its stream budget.
+func (b *outputBuffer) clearExecution(msgId string) {
+	kept := b.pending[:0]
+	for _, item := range b.pending {
+		if item.msgId != msgId || item.kind == pendingTruncated {
+			kept = append(kept, item)
+		}
+	}
+	b.pending = kept
+	delete(b.streamBytes, msgId)
+}
+
+// finishExecution forgets the stream budget of a finished execution.
+func (b *outputBuffer) finishExecution(msgId string) {
+	delete(b.streamBytes, msgId)
+}
+
+// take returns the pending output in order and empties the buffer.
+func (b *outputBuffer) take() []*pendingOutput {
+	pending := b.pending
+	b.pending = nil
+	return pending
+}
+
+// flushOutput emits all pending output. It runs before any other output event
+// so the frontend sees output in the order the kernel sent it.
+func (pl *pipeline) flushOutput() {
+	for _, item := range pl.output.take() {
+		switch item.kind {
+		case pendingStream:
+			pl.sink.Emit(util.EventCodeBlockStream, StreamEvent{
+				MessageId: item.msgId,
+				Name:      item.name,
+				Text:      string(ansihtml.ConvertToHTML(item.text)),
+			})
+		case pendingUpdate:
+			pl.sink.Emit(util.EventCodeBlockUpdateDisplayData, item.update)
+		case pendingTruncated:
+			pl.sink.Emit(util.EventCodeBlockOutputTruncated, OutputTruncatedEvent{
+				MessageId:   item.msgId,
+				Handle:      item.handle,
+				HiddenBytes: pl.output.overflow.hiddenBytes(item.handle),
+			})
+		}
+	}
+}
+
+// flushOutputIfDue flushes pending output once its coalescing window has passed.
+func (pl *pipeline) flushOutputIfDue(now time.Time) {
+	if deadline, pending := pl.output.flushDeadline(); pending && !now.Before(deadline) {
+		pl.flushOutput()
+	}
+}
+
+// splitAt splits text after at most limit bytes without cutting a UTF-8 sequence.
+func splitAt(text string, limit int) (string, string) {
+	if limit <= 0 {
+		return "", text
+	}
+	if len(text) <= limit {
+		return text, ""
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit], text[limit:]
+}
+
+// overflowEntry is the held-back output of one execution.
+type overflowEntry struct {
+	text   []byte
+	hidden int
+}
+
+// overflowStore keeps the held-back stream output of the most recent
+// executions so the frontend can page through it. It is read from RPC
+// goroutines, so it has its own lock.
+type overflowStore struct {
+	mu      sync.Mutex
+	nextID  int
+	byMsgID map[string]string
+	entries map[string]*overflowEntry
+	handles []string
+}
+
+func newOverflowStore() *overflowStore {
+	return &overflowStore{
+		byMsgID: map[string]string{},
+		entries: map[string]*overflowEntry{},
+	}
+}
+
+// append adds held-back text for msgId and returns its handle.
+func (s *overflowStore) append(msgId, text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handle, ok := s.byMsgID[msgId]
+	if !ok {
+		s.nextID++
+		handle = fmt.Sprintf("output-%d", s.nextID)
+		s.byMsgID[msgId] = handle
+		s.entries[handle] = &overflowEntry{}
+		s.handles = append(s.handles, handle)
+		if len(s.handles) > maxOverflowHandles {
+			oldest := s.handles[0]
+			s.handles = s.handles[1:]
+			delete(s.entries, oldest)
+			for id, h := range s.byMsgID {
+				if h == oldest {
+					delete(s.byMsgID, id)
+				}
+			}
+		}
+	}
+
+	entry := s.entries[handle]
+	entry.hidden += len(text)
+	kept, _ := splitAt(text, maxOverflowBytes-len(entry.text))
+	entry.text = append(entry.text, kept...)
+	return handle
+}
+
+func (s *overflowStore) hiddenBytes(handle string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[handle]; ok {
+		return entry.hidden
+	}
+	return 0
+}
+
+// page returns up to limit bytes of held-back output starting at offset.
+func (s *overflowStore) page(handle string, offset, limit int) (TruncatedOutputPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[handle]
+	if !ok {
+		return TruncatedOutputPage{}, fmt.Errorf("output %s is no longer available", handle)
+	}
+
+	total := len(entry.text)
+	start := min(max(offset, 0), total)
+	for start < total && !utf8.RuneStart(entry.text[start]) {
+		start++
+	}
+	chunk, _ := splitAt(string(entry.text[start:]), limit)
+	end := start + len(chunk)
+	return TruncatedOutputPage{
+		Text:        string(ansihtml.ConvertToHTML([]byte(chunk))),
+		Offset:      start,
+		NextOffset:  end,
+		TotalBytes:  total,
+		HiddenBytes: entry.hidden,
+		Done:        end >= total,
+	}, nil
+}
@@ -0,0 +1,239 @@
+package sockets
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+	"github.com/pebbe/zmq4"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// Channel identifies one of the kernel's message sockets.
+type Channel string
+
+const (
+	ShellChannel   Channel = "shell"
+	IOPubChannel   Channel = "iopub"
+	StdinChannel   Channel = "stdin"
+	ControlChannel Channel = "control"
+)
+
+// pollErrorBackoff keeps a persistently failing poll from spinning.
+const pollErrorBackoff = 50 * time.Millisecond
+
+// inbound is a raw multipart message received on a channel.
+type inbound struct {
+	channel Channel
+	frames  [][]byte
+}
+
+// transport moves raw multipart messages between the pipeline and a kernel.
+// Poll, Send and Close are only called from the pipeline goroutine; Wake may be
+// called from any goroutine to interrupt a blocked Poll.
+type transport interface {
+	// Poll blocks until messages arrive, Wake is called, or timeout elapses.
+	// A negative timeout waits indefinitely.
+	Poll(timeout time.Duration) ([]inbound, error)
+	Send(channel Channel, frames [][]byte) error
+	Wake()
+	Close()
+}
+
+// eventSink receives the events the pipeline emits. appEventSink forwards them
+// to the Wails app; tests record them.
+type eventSink interface {
+	Emit(name string, data any)
+	EmitToCurrentWindow(name string, data any)
+}
+
+type appEventSink struct{}
+
+func (appEventSink) Emit(name string, data any) {
+	if app := application.Get(); app != nil {
+		app.Event.EmitEvent(&application.CustomEvent{Name: name, Data: data})
+	}
+}
+
+func (appEventSink) EmitToCurrentWindow(name string, data any) {
+	if app := application.Get(); app != nil {
+		if currentWindow := app.Window.Current(); currentWindow != nil {
+			currentWindow.EmitEvent(name, data)
+		}
+	}
+}
+
+// outbound is a message queued for the pipeline goroutine to send.
+type outbound struct {
+	channel Channel
+	frames  [][]byte
+	result  chan error
+}
+
+// pipeline is the single goroutine that owns a kernel's shell, iopub, stdin
+// and control sockets. It waits on all of them at once, dispatches inbound
+// messages to the per-channel handlers, and performs every send so the
+// sockets are never touched from two goroutines.
+type pipeline struct {
+	p         CreateParams
+	transport transport
+	sink      eventSink
+	outbox    chan outbound
+	done      chan struct{}
+
+	displays *displayTracker
+	output   *outputBuffer
+
+	// invalidSignatures counts inbound messages dropped because their HMAC
+	// did not match the connection key.
+	invalidSignatures atomic.Int64
+}
+
+func newPipeline(p CreateParams, t transport, sink eventSink) *pipeline {
+	return &pipeline{
+		p:         p,
+		transport: t,
+		sink:      sink,
+		outbox:    make(chan outbound, 64),
+		done:      make(chan struct{}),
+		displays:  newDisplayTracker(),
+		output:    newOutputBuffer(),
+	}
+}
+
+// run is the pipeline loop. It returns when p.Ctx is cancelled.
+func (pl *pipeline) run() {
+	defer close(pl.done)
+	defer pl.transport.Close()
+
+	go func() {
+		select {
+		case <-pl.p.Ctx.Done():
+			pl.transport.Wake()
+		case <-pl.done:
+		}
+	}()
+
+	for {
+		if pl.p.Ctx.Err() != nil {
+			log.Println("🛑 Kernel socket pipeline received context cancellation")
+			return
+		}
+		pl.sendQueued()
+
+		timeout := time.Duration(-1)
+		if deadline, pending := pl.output.flushDeadline(); pending {
+			timeout = max(time.Until(deadline), 0)
+		}
+		messages, err := pl.transport.Poll(timeout)
+		if err != nil {
+			if pl.p.Ctx.Err() == nil {
+				log.Println("Error polling kernel sockets:", err)
+				time.Sleep(pollErrorBackoff)
+			}
+			continue
+		}
+		for _, message := range messages {
+			pl.dispatch(message)
+		}
+		pl.flushOutputIfDue(time.Now())
+	}
+}
+
+// sendQueued sends every message waiting in the outbox.
+func (pl *pipeline) sendQueued() {
+	for {
+		select {
+		case out := <-pl.outbox:
+			out.result <- pl.transport.Send(out.channel, out.frames)
+		default:
+			return
+		}
+	}
+}
+
+// send queues frames for the pipeline goroutine and waits for the result. It
+// returns zmq4.ErrorSocketClosed once the pipeline has stopped, which callers
+// use to detect a dead kernel.
+func (pl *pipeline) send(channel Channel, frames [][]byte) error {
+	out := outbound{channel: channel, frames: frames, result: make(chan error, 1)}
+	select {
+	case pl.outbox <- out:
+	case <-pl.done:
+		return zmq4.ErrorSocketClosed
+	}
+	pl.transport.Wake()
+	select {
+	case err := <-out.result:
+		return err
+	case <-pl.done:
+		return zmq4.ErrorSocketClosed
+	}
+}
+
+// dispatch verifies and parses an inbound message and hands it to its
+// channel's handler. Messages with a bad signature are dropped.
+func (pl *pipeline) dispatch(in inbound) {
+	info := pl.p.ConnectionInfo
+	if err := jupyter_protocol.VerifySignature(in.frames, info.Key, info.SignatureScheme); err != nil {
+		// Only the first mismatch is logged; a wrong key fails every message.
+		if pl.invalidSignatures.Add(1) == 1 {
+			log.Printf("Dropping %s message from kernel %s: %v", in.channel, pl.p.InstanceID, err)
+		}
+		return
+	}
+	_, msg, _, err := jupyter_protocol.ParseMultipartMessage(in.frames)
+	if err != nil {
+		log.Printf("Error parsing %s message: %v", in.channel, err)
+		return
+	}
+	switch in.channel {
+	case ShellChannel:
+		pl.handleShell(msg)
+	case IOPubChannel:
+		pl.handleIOPub(msg)
+	case StdinChannel:
+		pl.handleStdin(msg)
+	case ControlChannel:
+		pl.handleControl(msg)
+	}
+}
+
+// channelSender is the jupyter_protocol.MessageSender for one channel. Sends
+// are handed to the pipeline goroutine, so it is safe for concurrent use.
+type channelSender struct {
+	pipeline *pipeline
+	channel  Channel
+}
+
+// SendMessage flattens parts the way zmq4.Socket.SendMessage does and queues
+// them on the pipeline. It returns the total number of bytes sent.
+func (s channelSender) SendMessage(parts ...any) (int, error) {
+	frames := [][]byte{}
+	for _, part := range parts {
+		switch value := part.(type) {
+		case [][]byte:
+			frames = append(frames, value...)
+		case []byte:
+			frames = append(frames, value)
+		case []string:
+			for _, str := range value {
+				frames = append(frames, []byte(str))
+			}
+		case string:
+			frames = append(frames, []byte(value))
+		default:
+			frames = append(frames, []byte(fmt.Sprintf("%v", value)))
+		}
+	}
+	if err := s.pipeline.send(s.channel, frames); err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, frame := range frames {
+		total += len(frame)
+	}
+	return total, nil
+}
@@ -0,0 +1,329 @@
+package sockets
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/pebbe/zmq4"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKernel is an in-memory transport. Messages passed to deliver are
+// returned together by the next Poll, as a real poller would after a burst.
+type fakeKernel struct {
+	mu     sync.Mutex
+	queue  []inbound
+	sent   []inbound
+	closed bool
+	wake   chan struct{}
+}
+
+func newFakeKernel() *fakeKernel {
+	return &fakeKernel{wake: make(chan struct{}, 1)}
+}
+
+func (k *fakeKernel) deliver(messages ...inbound) {
+	k.mu.Lock()
+	k.queue = append(k.queue, messages...)
+	k.mu.Unlock()
+	k.Wake()
+}
+
+func (k *fakeKernel) Poll(timeout time.Duration) ([]inbound, error) {
+	k.mu.Lock()
+	if len(k.queue) > 0 {
+		messages := k.queue
+		k.queue = nil
+		k.mu.Unlock()
+		return messages, nil
+	}
+	k.mu.Unlock()
+
+	var timer <-chan time.Time
+	if timeout >= 0 {
+		timer = time.After(timeout)
+	}
+	select {
+	case <-k.wake:
+	case <-timer:
+	}
+	return nil, nil
+}
+
+func (k *fakeKernel) Send(channel Channel, frames [][]byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.sent = append(k.sent, inbound{channel: channel, frames: frames})
+	return nil
+}
+
+func (k *fakeKernel) Wake() {
+	select {
+	case k.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (k *fakeKernel) Close() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.closed = true
+}
+
+type recordedEvent struct {
+	name string
+	data any
+}
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []recordedEvent
+}
+
+func (s *recordingSink) Emit(name string, data any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, recordedEvent{name: name, data: data})
+}
+
+func (s *recordingSink) EmitToCurrentWindow(name string, data any) {
+	s.Emit(name, data)
+}
+
+func (s *recordingSink) snapshot() []recordedEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]recordedEvent{}, s.events...)
+}
+
+func (s *recordingSink) named(name string) []any {
+	data := []any{}
+	for _, event := range s.snapshot() {
+		if event.name == name {
+			data = append(data, event.data)
+		}
+	}
+	return data
+}
+
+const testKey = "test-key"
+
+// kernelMessage builds the frames of a message signed with testKey, sent in
+// reply to the execute_request parentID.
+func kernelMessage(channel Channel, msgType, parentID string, content map[string]any) inbound {
+	header, _ := json.Marshal(map[string]any{"msg_id": "kernel-" + msgType, "msg_type": msgType})
+	parent, _ := json.Marshal(map[string]any{"msg_id": parentID, "msg_type": "execute_request"})
+	body, _ := json.Marshal(content)
+	signed := [][]byte{header, parent, []byte("{}"), body}
+
+	mac := hmac.New(sha256.New, []byte(testKey))
+	for _, frame := range signed {
+		mac.Write(frame)
+	}
+	signature := []byte(hex.EncodeToString(mac.Sum(nil)))
+	return inbound{
+		channel: channel,
+		frames:  append([][]byte{[]byte("<IDS|MSG>"), signature}, signed...),
+	}
+}
+
+func streamMessage(parentID, text string) inbound {
+	return kernelMessage(IOPubChannel, "stream", parentID, map[string]any{"name": "stdout", "text": text})
+}
+
+func idleMessage(parentID string) inbound {
+	return kernelMessage(IOPubChannel, "status", parentID, map[string]any{"execution_state": "idle"})
+}
+
+func startFakePipeline(t *testing.T) (*LanguageSockets, *fakeKernel, *recordingSink, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	kernel := newFakeKernel()
+	sink := &recordingSink{}
+	s := startPipeline(CreateParams{
+		ConnectionInfo: config.KernelConnectionInfo{Key: testKey, SignatureScheme: "hmac-sha256"},
+		Ctx:            ctx,
+		Cancel:         cancel,
+		InstanceID:     "kernel-1",
+	}, kernel, sink)
+	return s, kernel, sink, cancel
+}
+
+func TestPipeline(t *testing.T) {
+	parentID := "block|exec|" + time.Now().Format(time.RFC3339)
+
+	t.Run("coalesces stream chunks and flushes them before idle", func(t *testing.T) {
+		_, kernel, sink, _ := startFakePipeline(t)
+		kernel.deliver(
+			streamMessage(parentID, "a"),
+			streamMessage(parentID, "b"),
+			streamMessage(parentID, "c"),
+			idleMessage(parentID),
+		)
+
+		assert.Eventually(t, func() bool {
+			return len(sink.named(util.EventCodeBlockStatus)) == 1
+		}, time.Second, 5*time.Millisecond)
+
+		streams := sink.named(util.EventCodeBlockStream)
+		assert.Equal(t, []any{StreamEvent{MessageId: parentID, Name: "stdout", Text: "abc"}}, streams)
+
+		names := []string{}
+		for _, event := range sink.snapshot() {
+			names = append(names, event.name)
+		}
+		assert.Equal(t, []string{util.EventCodeBlockStream, util.EventKernelInstanceStatus, util.EventCodeBlockStatus}, names)
+	})
+
+	t.Run("flushes buffered stream output after the flush interval", func(t *testing.T) {
+		_, kernel, sink, _ := startFakePipeline(t)
+		kernel.deliver(streamMessage(parentID, "hello"))
+
+		assert.Eventually(t, func() bool {
+			return len(sink.named(util.EventCodeBlockStream)) == 1
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("holds back output beyond the execution budget", func(t *testing.T) {
+		s, kernel, sink, _ := startFakePipeline(t)
+		kernel.deliver(
+			streamMessage(parentID, strings.Repeat("x", maxStreamBytesPerExecution)),
+			streamMessage(parentID, strings.Repeat("y", 10)),
+			idleMessage(parentID),
+		)
+
+		assert.Eventually(t, func() bool {
+			return len(sink.named(util.EventCodeBlockOutputTruncated)) == 1
+		}, time.Second, 5*time.Millisecond)
+
+		streams := sink.named(util.EventCodeBlockStream)
+		assert.Len(t, streams, 1)
+		assert.Len(t, streams[0].(StreamEvent).Text, maxStreamBytesPerExecution)
+
+		truncated := sink.named(util.EventCodeBlockOutputTruncated)[0].(OutputTruncatedEvent)
+		assert.Equal(t, 10, truncated.HiddenBytes)
+
+		page, err := s.TruncatedOutput(truncated.Handle, 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("y", 10), page.Text)
+		assert.True(t, page.Done)
+
+		_, err = s.TruncatedOutput("output-missing", 0, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("clear_output drops buffered output", func(t *testing.T) {
+		_, kernel, sink, _ := startFakePipeline(t)
+		kernel.deliver(
+			streamMessage(parentID, "old"),
+			kernelMessage(IOPubChannel, "clear_output", parentID, map[string]any{"wait": false}),
+			streamMessage(parentID, "new"),
+			idleMessage(parentID),
+		)
+
+		assert.Eventually(t, func() bool {
+			return len(sink.named(util.EventCodeBlockStatus)) == 1
+		}, time.Second, 5*time.Millisecond)
+
+		assert.Equal(t, []any{ClearOutputEvent{MessageId: parentID}}, sink.named(util.EventCodeBlockClearOutput))
+		assert.Equal(t, []any{StreamEvent{MessageId: parentID, Name: "stdout", Text: "new"}}, sink.named(util.EventCodeBlockStream))
+	})
+
+	t.Run("keeps only the latest update per display", func(t *testing.T) {
+		_, kernel, sink, _ := startFakePipeline(t)
+		update := func(text string) inbound {
+			return kernelMessage(IOPubChannel, "update_display_data", parentID, map[string]any{
+				"data":      map[string]any{"text/plain": text},
+				"transient": map[string]any{"display_id": "progress"},
+			})
+		}
+		kernel.deliver(
+			kernelMessage(IOPubChannel, "display_data", parentID, map[string]any{
+				"data":      map[string]any{"text/plain": "0%"},
+				"transient": map[string]any{"display_id": "progress"},
+			}),
+			update("50%"),
+			update("100%"),
+			idleMessage(parentID),
+		)
+
+		assert.Eventually(t, func() bool {
+			return len(sink.named(util.EventCodeBlockStatus)) == 1
+		}, time.Second, 5*time.Millisecond)
+
+		assert.Equal(t, []any{ExecuteResultEvent{
+			MessageId: parentID,
+			Data:      map[string]string{"text/plain": "100%"},
+			DisplayID: "progress",
+		}}, sink.named(util.EventCodeBlockUpdateDisplayData))
+	})
+
+	t.Run("drops and counts messages with a bad signature", func(t *testing.T) {
+		s, kernel, sink, _ := startFakePipeline(t)
+		forged := streamMessage(parentID, "forged")
+		forged.frames[1] = []byte(strings.Repeat("0", 64))
+		kernel.deliver(forged, streamMessage(parentID, "genuine"), idleMessage(parentID))
+
+		assert.Eventually(t, func() bool {
+			return len(sink.named(util.EventCodeBlockStatus)) == 1
+		}, time.Second, 5*time.Millisecond)
+
+		assert.Equal(t, []any{StreamEvent{MessageId: parentID, Name: "stdout", Text: "genuine"}}, sink.named(util.EventCodeBlockStream))
+		assert.Equal(t, int64(1), s.InvalidSignatures())
+	})
+
+	t.Run("routes shell replies to the current window", func(t *testing.T) {
+		_, kernel, sink, _ := startFakePipeline(t)
+		kernel.deliver(kernelMessage(ShellChannel, "execute_reply", parentID, map[string]any{"status": "ok"}))
+
+		assert.Eventually(t, func() bool {
+			return len(sink.named(util.EventCodeBlockExecuteReply)) == 1
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("sends on the pipeline and reports closed sockets after shutdown", func(t *testing.T) {
+		s, kernel, _, cancel := startFakePipeline(t)
+
+		_, err := s.Shell.SendMessage("<IDS|MSG>", [][]byte{[]byte("sig"), []byte("{}")})
+		assert.NoError(t, err)
+		kernel.mu.Lock()
+		assert.Equal(t, []inbound{{
+			channel: ShellChannel,
+			frames:  [][]byte{[]byte("<IDS|MSG>"), []byte("sig"), []byte("{}")},
+		}}, kernel.sent)
+		kernel.mu.Unlock()
+
+		cancel()
+		assert.Eventually(t, func() bool {
+			_, err := s.Control.SendMessage("<IDS|MSG>")
+			return err == zmq4.ErrorSocketClosed
+		}, time.Second, 5*time.Millisecond)
+
+		kernel.mu.Lock()
+		assert.True(t, kernel.closed)
+		kernel.mu.Unlock()
+	})
+}
+
+func TestSplitAt(t *testing.T) {
+	head, rest := splitAt("héllo", 2)
+	assert.Equal(t, "h", head)
+	assert.Equal(t, "éllo", rest)
+
+	head, rest = splitAt("abc", 5)
+	assert.Equal(t, "abc", head)
+	assert.Empty(t, rest)
+
+	head, rest = splitAt("abc", 0)
+	assert.Empty(t, head)
+	assert.Equal(t, "abc", rest)
+}
@@ -7,16 +7,36 @@ import (
 
 	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/jupyter_protocol"
-	"github.com/pebbe/zmq4"
 )
 
-// LanguageSockets is the per-instance bundle of ZMQ sockets used to talk to a kernel.
+// LanguageSockets is the per-instance handle on a kernel's sockets. Shell,
+// Control and Stdin are safe for concurrent use: every send is performed by
+// the instance's socket pipeline goroutine.
 type LanguageSockets struct {
-	ShellSocketDealer     *zmq4.Socket
-	IOPubSocketSubscriber *zmq4.Socket
-	ControlSocketDealer   *zmq4.Socket
-	HeartbeatSocketReq    *zmq4.Socket
-	StdinSocketDealer     *zmq4.Socket
+	Shell   jupyter_protocol.MessageSender
+	Control jupyter_protocol.MessageSender
+	Stdin   jupyter_protocol.MessageSender
+
+	pipeline *pipeline
+}
+
+// InvalidSignatures returns how many inbound messages were dropped because
+// their signature did not match the connection key.
+func (s *LanguageSockets) InvalidSignatures() int64 {
+	return s.pipeline.invalidSignatures.Load()
+}
+
+// defaultTruncatedOutputPage is the page size used when TruncatedOutput is
+// called without a limit.
+const defaultTruncatedOutputPage = 64 * 1024
+
+// TruncatedOutput returns a page of the stream output held back for handle
+// after an execution exceeded its output budget.
+func (s *LanguageSockets) TruncatedOutput(handle string, offset, limit int) (TruncatedOutputPage, error) {
+	if limit <= 0 {
+		limit = defaultTruncatedOutputPage
+	}
+	return s.pipeline.output.overflow.page(handle, offset, limit)
 }
 
 // CreateParams carries everything the socket goroutines need to identify the
@@ -29,57 +49,59 @@ type CreateParams struct {
 	InstanceID     string
 	NoteID         string
 
-	// OnExecuteStatus is called from the iopub goroutine whenever a kernel
-	// status event arrives whose parent_header.msg_type == "execute_request".
-	// Used by the manager to maintain per-instance activeExecutions and the
-	// derived IsIdle() flag for LRU eviction.
+	// OnExecuteStatus is called from the socket pipeline goroutine whenever a
+	// kernel status event arrives whose parent_header.msg_type ==
+	// "execute_request". Used by the manager to maintain per-instance
+	// activeExecutions and the derived IsIdle() flag for LRU eviction.
 	OnExecuteStatus func(status, parentMsgID string)
+
+	// OnShellReply is called from the socket pipeline goroutine for every
+	// execute_reply before it is forwarded to the frontend. Returning true
+	// means the reply answered a backend-initiated request (e.g. variable
+	// introspection) and must not be emitted as a code block event.
+	OnShellReply func(parentMsgID string, msg jupyter_protocol.Message) bool
+
+	// OnIOPubMessage is called from the socket pipeline goroutine for every
+	// message that has a parent msg_id, before it is forwarded to the
+	// frontend. Used by the manager to record the instance's execution
+	// transcript.
+	OnIOPubMessage func(parentMsgID string, msg jupyter_protocol.Message)
 }
 
-// CreateSockets initializes all 5 ZMQ sockets for a kernel instance and starts
-// listener goroutines for each. The goroutines exit when p.Ctx is cancelled.
+// CreateSockets connects to a kernel's sockets and starts its socket pipeline
+// and heartbeat goroutines. The goroutines exit when p.Ctx is cancelled.
 func CreateSockets(p CreateParams) (*LanguageSockets, error) {
-	out := &LanguageSockets{}
-
-	shell := createShellSocket(p)
-	if shell == nil {
-		return nil, errors.New("failed to create shell socket dealer")
+	if err := jupyter_protocol.ValidateSignatureScheme(p.ConnectionInfo.SignatureScheme); err != nil {
+		return nil, err
 	}
-	go shell.Listen(p)
-	out.ShellSocketDealer = shell.socket
-	log.Println("🟩 created shell socket dealer")
-
-	iopub := createIOPubSocket(p)
-	if iopub == nil {
-		return nil, errors.New("failed to create IOPub socket subscriber")
+	t, err := newZMQTransport(p.ConnectionInfo, p.InstanceID)
+	if err != nil {
+		return nil, err
 	}
-	go iopub.Listen(p)
-	out.IOPubSocketSubscriber = iopub.socket
-	log.Println("🟩 created IOPub socket subscriber")
+	out := startPipeline(p, t, appEventSink{})
+	log.Println("🟩 created kernel socket pipeline")
 
 	hb := createHeartbeatSocket(p)
 	if hb == nil {
+		// Stop the pipeline started above; it closes the transport on exit.
+		p.Cancel()
+		<-out.pipeline.done
 		return nil, errors.New("failed to create heartbeat socket request")
 	}
 	go hb.Listen(p)
-	out.HeartbeatSocketReq = hb.socket
 	log.Println("🟩 created heartbeat socket request")
 
-	control := createControlSocket(p)
-	if control == nil {
-		return nil, errors.New("failed to create control socket dealer")
-	}
-	go control.Listen(p)
-	out.ControlSocketDealer = control.socket
-	log.Println("🟩 created control socket dealer")
+	return out, nil
+}
 
-	stdin := createStdinSocket(p)
-	if stdin == nil {
-		return nil, errors.New("failed to create stdin socket dealer")
+// startPipeline runs a socket pipeline over t and returns the senders for it.
+func startPipeline(p CreateParams, t transport, sink eventSink) *LanguageSockets {
+	pl := newPipeline(p, t, sink)
+	go pl.run()
+	return &LanguageSockets{
+		Shell:    channelSender{pipeline: pl, channel: ShellChannel},
+		Control:  channelSender{pipeline: pl, channel: ControlChannel},
+		Stdin:    channelSender{pipeline: pl, channel: StdinChannel},
+		pipeline: pl,
 	}
-	go stdin.Listen(p)
-	out.StdinSocketDealer = stdin.socket
-	log.Println("🟩 created stdin socket dealer")
-
-	return out, nil
 }
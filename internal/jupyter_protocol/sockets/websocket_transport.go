@@ -0,0 +1,200 @@
+package sockets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+// maxWebSocketMessageBytes is the largest kernel message accepted from a
+// Jupyter Server. Rich outputs such as images easily exceed the library's
+// 32KB default.
+const maxWebSocketMessageBytes = 64 * 1024 * 1024
+
+// websocketMessage is a kernel message as Jupyter Server relays it over the
+// kernel's channels websocket. Every channel shares the one connection.
+type websocketMessage struct {
+	Header       json.RawMessage `json:"header"`
+	ParentHeader json.RawMessage `json:"parent_header"`
+	Metadata     json.RawMessage `json:"metadata"`
+	Content      json.RawMessage `json:"content"`
+	Channel      string          `json:"channel"`
+}
+
+// websocketTransport is the transport for a kernel running behind a Jupyter
+// Server. The server strips signatures, so inbound messages are handed to the
+// pipeline unsigned and the connection key is left empty.
+type websocketTransport struct {
+	conn     *websocket.Conn
+	ctx      context.Context
+	incoming chan inbound
+	wake     chan struct{}
+	readDone chan struct{}
+}
+
+// newWebSocketTransport dials a kernel's channels websocket. onDisconnect is
+// called from the reader goroutine if the server closes the connection before
+// ctx is cancelled.
+func newWebSocketTransport(ctx context.Context, channelsURL string, header http.Header, onDisconnect func(error)) (*websocketTransport, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(dialCtx, channelsURL, &websocket.DialOptions{HTTPHeader: header})
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to kernel websocket: %w", err)
+	}
+	conn.SetReadLimit(maxWebSocketMessageBytes)
+
+	t := &websocketTransport{
+		conn:     conn,
+		ctx:      ctx,
+		incoming: make(chan inbound, maxMessagesPerPoll),
+		wake:     make(chan struct{}, 1),
+		readDone: make(chan struct{}),
+	}
+	go t.read(onDisconnect)
+	return t, nil
+}
+
+// read converts websocket messages into the multipart frames the pipeline
+// parses, with an empty signature.
+func (t *websocketTransport) read(onDisconnect func(error)) {
+	defer close(t.readDone)
+	for {
+		messageType, data, err := t.conn.Read(t.ctx)
+		if err != nil {
+			if t.ctx.Err() == nil && onDisconnect != nil {
+				onDisconnect(err)
+			}
+			return
+		}
+		// Binary messages carry comm buffers in the v1 websocket protocol,
+		// which is not negotiated.
+		if messageType != websocket.MessageText {
+			continue
+		}
+		var msg websocketMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("Error decoding kernel websocket message: %v", err)
+			continue
+		}
+		frames := [][]byte{
+			[]byte("<IDS|MSG>"),
+			{},
+			orEmptyObject(msg.Header),
+			orEmptyObject(msg.ParentHeader),
+			orEmptyObject(msg.Metadata),
+			orEmptyObject(msg.Content),
+		}
+		select {
+		case t.incoming <- inbound{channel: Channel(msg.Channel), frames: frames}:
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+func orEmptyObject(raw json.RawMessage) []byte {
+	if len(raw) == 0 || string(raw) == "null" {
+		return []byte("{}")
+	}
+	return raw
+}
+
+func (t *websocketTransport) Poll(timeout time.Duration) ([]inbound, error) {
+	var timer <-chan time.Time
+	if timeout >= 0 {
+		timer = time.After(timeout)
+	}
+
+	messages := []inbound{}
+	select {
+	case in := <-t.incoming:
+		messages = append(messages, in)
+	case <-t.wake:
+		return messages, nil
+	case <-timer:
+		return messages, nil
+	case <-t.readDone:
+		return messages, nil
+	}
+	for len(messages) < maxMessagesPerPoll {
+		select {
+		case in := <-t.incoming:
+			messages = append(messages, in)
+		default:
+			return messages, nil
+		}
+	}
+	return messages, nil
+}
+
+// Send re-encodes an assembled envelope as a websocket message. Buffers are
+// dropped because the text protocol cannot carry them.
+func (t *websocketTransport) Send(channel Channel, frames [][]byte) error {
+	if channel == IOPubChannel {
+		return fmt.Errorf("cannot send on %s channel", channel)
+	}
+	delimiterIndex := -1
+	for i, frame := range frames {
+		if string(frame) == "<IDS|MSG>" {
+			delimiterIndex = i
+			break
+		}
+	}
+	if delimiterIndex == -1 || len(frames) < delimiterIndex+6 {
+		return errors.New("malformed kernel message")
+	}
+	data, err := json.Marshal(websocketMessage{
+		Header:       frames[delimiterIndex+2],
+		ParentHeader: frames[delimiterIndex+3],
+		Metadata:     frames[delimiterIndex+4],
+		Content:      frames[delimiterIndex+5],
+		Channel:      string(channel),
+	})
+	if err != nil {
+		return err
+	}
+	writeCtx, cancel := context.WithTimeout(t.ctx, 10*time.Second)
+	defer cancel()
+	return t.conn.Write(writeCtx, websocket.MessageText, data)
+}
+
+func (t *websocketTransport) Wake() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (t *websocketTransport) Close() {
+	_ = t.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// CreateWebSocketSockets connects to a kernel behind a Jupyter Server and
+// starts its socket pipeline. There is no heartbeat channel: the kernel counts
+// as alive while the websocket is open, and p.Cancel is called when the server
+// drops it.
+func CreateWebSocketSockets(p CreateParams, channelsURL string, header http.Header) (*LanguageSockets, error) {
+	sink := appEventSink{}
+	t, err := newWebSocketTransport(p.Ctx, channelsURL, header, func(err error) {
+		log.Printf("Kernel %s websocket closed: %v", p.InstanceID, err)
+		p.HeartbeatState.UpdateHeartbeatStatus(false)
+		sink.Emit(util.EventKernelInstanceHeartbeat, HeartbeatEvent{ID: p.InstanceID, Status: "failure"})
+		p.Cancel()
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := startPipeline(p, t, sink)
+	p.HeartbeatState.UpdateHeartbeatStatus(true)
+	sink.Emit(util.EventKernelInstanceHeartbeat, HeartbeatEvent{ID: p.InstanceID, Status: "success"})
+	log.Println("🟩 created kernel websocket pipeline")
+	return out, nil
+}
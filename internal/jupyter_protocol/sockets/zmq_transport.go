@@ -0,0 +1,175 @@
+package sockets
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/pebbe/zmq4"
+)
+
+// maxMessagesPerPoll bounds how many messages are drained from one socket per
+// poll so a chatty iopub cannot starve the shell and stdin sockets.
+const maxMessagesPerPoll = 256
+
+// zmqTransport is the transport backed by the kernel's real ZMQ sockets. A
+// connected inproc PAIR lets other goroutines wake the blocked poller.
+type zmqTransport struct {
+	sockets   map[Channel]*zmq4.Socket
+	channels  map[*zmq4.Socket]Channel
+	poller    *zmq4.Poller
+	wakeRecv  *zmq4.Socket
+	wakeMu    sync.Mutex
+	wakeSend  *zmq4.Socket
+	wakeEnded bool
+}
+
+// newZMQTransport creates and connects the shell, iopub, stdin and control
+// sockets for a kernel instance.
+func newZMQTransport(info config.KernelConnectionInfo, instanceID string) (*zmqTransport, error) {
+	t := &zmqTransport{
+		sockets:  map[Channel]*zmq4.Socket{},
+		channels: map[*zmq4.Socket]Channel{},
+		poller:   zmq4.NewPoller(),
+	}
+
+	ports := []struct {
+		channel    Channel
+		socketType zmq4.Type
+		port       int
+	}{
+		{ShellChannel, zmq4.DEALER, info.ShellPort},
+		{IOPubChannel, zmq4.SUB, info.IOPubPort},
+		{StdinChannel, zmq4.DEALER, info.StdinPort},
+		{ControlChannel, zmq4.DEALER, info.ControlPort},
+	}
+	for _, port := range ports {
+		sock, err := zmq4.NewSocket(port.socketType)
+		if err != nil {
+			t.Close()
+			return nil, fmt.Errorf("could not create %s socket: %w", port.channel, err)
+		}
+		t.sockets[port.channel] = sock
+		t.channels[sock] = port.channel
+
+		if port.socketType == zmq4.DEALER {
+			// Stdin must share the shell's identity so the kernel can route
+			// input_requests to us.
+			err = sock.SetIdentity("current-session")
+		} else {
+			err = sock.SetSubscribe("")
+		}
+		if err != nil {
+			t.Close()
+			return nil, fmt.Errorf("could not configure %s socket: %w", port.channel, err)
+		}
+
+		address := fmt.Sprintf("tcp://%s:%d", info.IP, port.port)
+		if err := sock.Connect(address); err != nil {
+			t.Close()
+			return nil, fmt.Errorf("could not connect %s socket: %w", port.channel, err)
+		}
+		t.poller.Add(sock, zmq4.POLLIN)
+	}
+
+	wakeAddress := "inproc://bytebook-wake-" + instanceID
+	wakeRecv, err := zmq4.NewSocket(zmq4.PAIR)
+	if err != nil {
+		t.Close()
+		return nil, fmt.Errorf("could not create wake socket: %w", err)
+	}
+	t.wakeRecv = wakeRecv
+	if err := wakeRecv.Bind(wakeAddress); err != nil {
+		t.Close()
+		return nil, fmt.Errorf("could not bind wake socket: %w", err)
+	}
+	wakeSend, err := zmq4.NewSocket(zmq4.PAIR)
+	if err != nil {
+		t.Close()
+		return nil, fmt.Errorf("could not create wake socket: %w", err)
+	}
+	t.wakeSend = wakeSend
+	if err := wakeSend.Connect(wakeAddress); err != nil {
+		t.Close()
+		return nil, fmt.Errorf("could not connect wake socket: %w", err)
+	}
+	t.poller.Add(wakeRecv, zmq4.POLLIN)
+
+	return t, nil
+}
+
+func isAgain(err error) bool {
+	return strings.Contains(err.Error(), "resource temporarily unavailable")
+}
+
+func (t *zmqTransport) Poll(timeout time.Duration) ([]inbound, error) {
+	polled, err := t.poller.Poll(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []inbound{}
+	for _, item := range polled {
+		if item.Socket == t.wakeRecv {
+			for {
+				if _, err := t.wakeRecv.RecvBytes(zmq4.DONTWAIT); err != nil {
+					break
+				}
+			}
+			continue
+		}
+		channel := t.channels[item.Socket]
+		for range maxMessagesPerPoll {
+			frames, err := item.Socket.RecvMessageBytes(zmq4.DONTWAIT)
+			if err != nil {
+				if !isAgain(err) {
+					log.Printf("Error receiving %s message: %v", channel, err)
+				}
+				break
+			}
+			messages = append(messages, inbound{channel: channel, frames: frames})
+		}
+	}
+	return messages, nil
+}
+
+func (t *zmqTransport) Send(channel Channel, frames [][]byte) error {
+	sock, ok := t.sockets[channel]
+	if !ok || channel == IOPubChannel {
+		return fmt.Errorf("cannot send on %s channel", channel)
+	}
+	_, err := sock.SendMessageDontwait(frames)
+	return err
+}
+
+// Wake interrupts a blocked Poll. A wake that finds the pipe full is dropped;
+// one pending wake is enough.
+func (t *zmqTransport) Wake() {
+	t.wakeMu.Lock()
+	defer t.wakeMu.Unlock()
+	if t.wakeEnded || t.wakeSend == nil {
+		return
+	}
+	_, _ = t.wakeSend.SendBytes([]byte{0}, zmq4.DONTWAIT)
+}
+
+func (t *zmqTransport) Close() {
+	t.wakeMu.Lock()
+	t.wakeEnded = true
+	if t.wakeSend != nil {
+		_ = t.wakeSend.SetLinger(0)
+		_ = t.wakeSend.Close()
+	}
+	t.wakeMu.Unlock()
+
+	if t.wakeRecv != nil {
+		_ = t.wakeRecv.Close()
+	}
+	for _, sock := range t.sockets {
+		_ = sock.SetLinger(0)
+		_ = sock.Close()
+	}
+}
@@ -0,0 +1,293 @@
+package kernel_manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+	"github.com/etesam913/bytebook/internal/jupyter_protocol/sockets"
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// Kernel origins reported in KernelInstanceSnapshot.Origin.
+const (
+	// KernelOriginLocal is a kernel process launched by bytebook.
+	KernelOriginLocal = "local"
+	// KernelOriginConnectionFile is an already running kernel attached through
+	// its connection file, e.g. one started by JupyterLab or in a container.
+	KernelOriginConnectionFile = "connection_file"
+	// KernelOriginJupyterServer is a kernel reached through a Jupyter Server's
+	// REST API and channels websocket.
+	KernelOriginJupyterServer = "jupyter_server"
+)
+
+// remoteKernelTimeout bounds the REST calls made to interrupt or shut down a
+// Jupyter Server kernel.
+const remoteKernelTimeout = 10 * time.Second
+
+// KernelScopeInUseError is returned when attaching a kernel to a scope that
+// already has one. The existing kernel must be shut down first.
+type KernelScopeInUseError struct {
+	Language         string
+	KernelInstanceID string
+}
+
+func (e KernelScopeInUseError) Error() string {
+	return fmt.Sprintf("a %s kernel (%s) is already running for this scope", e.Language, e.KernelInstanceID)
+}
+
+// remoteKernel is a kernel on a Jupyter Server. Owned kernels were started by
+// bytebook and are shut down with their instance; others are only detached.
+type remoteKernel struct {
+	client   *jupyter_protocol.JupyterServerClient
+	kernelID string
+	owned    bool
+}
+
+func (r *remoteKernel) interrupt() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteKernelTimeout)
+	defer cancel()
+	return r.client.InterruptKernel(ctx, r.kernelID)
+}
+
+func (r *remoteKernel) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteKernelTimeout)
+	defer cancel()
+	return r.client.ShutdownKernel(ctx, r.kernelID)
+}
+
+// isAttached reports whether the kernel was not launched by bytebook.
+func (i *KernelInstance) isAttached() bool {
+	return i.origin == KernelOriginConnectionFile || i.origin == KernelOriginJupyterServer
+}
+
+// ReadConnectionFile reads a connection file written by jupyter_client, e.g.
+// one listed by `jupyter --runtime-dir`. Only the tcp transport is supported.
+func ReadConnectionFile(path string) (config.KernelConnectionInfo, error) {
+	info := config.KernelConnectionInfo{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, fmt.Errorf("failed to read connection file: %w", err)
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("invalid connection file: %w", err)
+	}
+	if info.Transport != "" && info.Transport != "tcp" {
+		return info, fmt.Errorf("unsupported kernel transport %q", info.Transport)
+	}
+	if info.ShellPort == 0 || info.IOPubPort == 0 || info.StdinPort == 0 || info.ControlPort == 0 || info.HBPort == 0 {
+		return info, fmt.Errorf("connection file is missing a port")
+	}
+	// Kernels listening on every interface are reached over loopback.
+	if info.IP == "" || info.IP == "0.0.0.0" {
+		info.IP = "127.0.0.1"
+	}
+	if err := jupyter_protocol.ValidateSignatureScheme(info.SignatureScheme); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// AttachConnectionFile connects to the running kernel described by the
+// connection file at path and serves (language, scope) with it. bytebook does
+// not own the kernel: shutting the instance down only disconnects.
+func (m *KernelManager) AttachConnectionFile(path, language string, scope KernelScope) (*KernelInstance, error) {
+	if !util.IsSupportedLanguage(language) {
+		return nil, LanguageNotSupportedError{Language: language}
+	}
+	info, err := ReadConnectionFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkScopeFree(language, scope); err != nil {
+		return nil, err
+	}
+
+	inst := m.newAttachedInstance(language, scope, info, KernelOriginConnectionFile, path)
+	socketSet, err := sockets.CreateSockets(inst.socketParams())
+	if err != nil {
+		inst.cancel()
+		return nil, fmt.Errorf("socket creation failed: %w", err)
+	}
+	inst.sockets = socketSet
+
+	if !waitForHeartbeat(inst, heartbeatLaunchWait) {
+		inst.cancel()
+		return nil, fmt.Errorf("kernel at %s did not respond", path)
+	}
+	return m.registerAttached(inst)
+}
+
+// JupyterServerKernelParams identifies a kernel on a Jupyter Server. An empty
+// KernelID starts a new kernel from the KernelName kernelspec; bytebook owns
+// that kernel and shuts it down with the instance.
+type JupyterServerKernelParams struct {
+	ServerURL  string
+	Token      string
+	KernelID   string
+	KernelName string
+}
+
+// ConnectJupyterServer connects to a kernel on a Jupyter Server and serves
+// (language, scope) with it.
+func (m *KernelManager) ConnectJupyterServer(ctx context.Context, language string, scope KernelScope, params JupyterServerKernelParams) (*KernelInstance, error) {
+	if !util.IsSupportedLanguage(language) {
+		return nil, LanguageNotSupportedError{Language: language}
+	}
+	client, err := jupyter_protocol.NewJupyterServerClient(params.ServerURL, params.Token)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkScopeFree(language, scope); err != nil {
+		return nil, err
+	}
+
+	remote := &remoteKernel{client: client, kernelID: params.KernelID}
+	if remote.kernelID == "" {
+		kernel, err := client.StartKernel(ctx, params.KernelName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start kernel on jupyter server: %w", err)
+		}
+		remote.kernelID = kernel.ID
+		remote.owned = true
+	} else if _, err := client.GetKernel(ctx, remote.kernelID); err != nil {
+		return nil, fmt.Errorf("kernel %s is not running on the jupyter server: %w", remote.kernelID, err)
+	}
+
+	// The server signs messages to the kernel itself, so no key is needed.
+	info := config.KernelConnectionInfo{Language: language, DisplayName: language}
+	inst := m.newAttachedInstance(language, scope, info, KernelOriginJupyterServer, client.BaseURL())
+	inst.remote = remote
+
+	socketSet, err := sockets.CreateWebSocketSockets(
+		inst.socketParams(),
+		client.ChannelsURL(remote.kernelID, inst.id),
+		client.AuthHeader(),
+	)
+	if err != nil {
+		inst.cancel()
+		if remote.owned {
+			_ = remote.shutdown()
+		}
+		return nil, err
+	}
+	inst.sockets = socketSet
+	return m.registerAttached(inst)
+}
+
+// newAttachedInstance builds the instance for a kernel bytebook did not launch.
+func (m *KernelManager) newAttachedInstance(language string, scope KernelScope, info config.KernelConnectionInfo, origin, endpoint string) *KernelInstance {
+	id := uuid.NewString()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &KernelInstance{
+		id:               id,
+		language:         language,
+		scopeType:        scope.Type,
+		scopeID:          scope.ID,
+		noteID:           scope.NoteID,
+		connectionInfo:   info,
+		origin:           origin,
+		endpoint:         endpoint,
+		settings:         m.languageSettings(language),
+		heartbeatState:   &jupyter_protocol.KernelHeartbeatState{},
+		activeExecutions: map[string]struct{}{},
+		executionTimers:  map[string]*time.Timer{},
+		executionQueue:   []string{},
+		lastActivityAt:   time.Now(),
+		ctx:              ctx,
+		cancel:           cancel,
+		manager:          m,
+		transcript:       newTranscript(m.projectPath, id, language),
+	}
+}
+
+// checkScopeFree returns a KernelScopeInUseError if (language, scope) already
+// has a kernel.
+func (m *KernelManager) checkScopeFree(language string, scope KernelScope) error {
+	if existing := m.Lookup(language, scope); existing != nil {
+		return KernelScopeInUseError{Language: language, KernelInstanceID: existing.id}
+	}
+	return nil
+}
+
+// registerAttached publishes a connected attached instance and starts watching
+// for its connection to drop.
+func (m *KernelManager) registerAttached(inst *KernelInstance) (*KernelInstance, error) {
+	key := newLangScopeKey(inst.language, inst.scope())
+	m.mu.Lock()
+	if existing, ok := m.byLangScope[key]; ok {
+		m.mu.Unlock()
+		_ = inst.shutdown("evicted", false)
+		return nil, KernelScopeInUseError{Language: inst.language, KernelInstanceID: existing.id}
+	}
+	m.instances[inst.id] = inst
+	m.byLangScope[key] = inst
+	m.mu.Unlock()
+
+	go m.watchDisconnect(inst)
+
+	if app := application.Get(); app != nil {
+		app.Event.EmitEvent(&application.CustomEvent{
+			Name: kernelInstanceCreatedEvent,
+			Data: inst.Snapshot(),
+		})
+	}
+	return inst, nil
+}
+
+// watchDisconnect plays the role of the process-exit goroutine for attached
+// kernels: once the heartbeat or websocket gives up and cancels the instance,
+// it is removed from the manager unless it was already shut down.
+func (m *KernelManager) watchDisconnect(inst *KernelInstance) {
+	<-inst.ctx.Done()
+
+	m.mu.Lock()
+	if m.instances[inst.id] != inst {
+		m.mu.Unlock()
+		return
+	}
+	m.removeFromMapsLocked(inst)
+	m.mu.Unlock()
+
+	log.Printf("kernel_manager: lost connection to attached %s kernel %s", inst.language, inst.id)
+	inst.stopExecutionTimers()
+	inst.transcript.abandon()
+	if app := application.Get(); app != nil {
+		app.Event.EmitEvent(&application.CustomEvent{
+			Name: kernelInstanceShutdownEvent,
+			Data: KernelShutdownEventData{
+				ID:       inst.id,
+				Language: inst.language,
+				NoteID:   inst.noteID,
+				Reason:   "disconnected",
+			},
+		})
+	}
+}
+
+// waitForHeartbeat waits up to wait for the instance's first heartbeat.
+func waitForHeartbeat(inst *KernelInstance, wait time.Duration) bool {
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-deadline.C:
+			return inst.IsHeartbeating()
+		case <-inst.ctx.Done():
+			return false
+		case <-ticker.C:
+			if inst.IsHeartbeating() {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package kernel_manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/etesam913/bytebook/internal/config"
+)
+
+// fakeJupyterServer is a stand-in for the parts of the Jupyter Server REST and
+// websocket API that bytebook uses. Its kernels answer every execute_request
+// by printing the code.
+type fakeJupyterServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	kernels map[string]bool
+	deleted []string
+	conns   []*websocket.Conn
+}
+
+func newFakeJupyterServer(t *testing.T) *fakeJupyterServer {
+	s := &fakeJupyterServer{kernels: map[string]bool{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/kernels", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.kernels["k1"] = true
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "k1", "name": "python3"})
+	})
+	mux.HandleFunc("GET /api/kernels/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !s.running(r.PathValue("id")) {
+			http.Error(w, `{"message":"Kernel does not exist"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": r.PathValue("id"), "name": "python3"})
+	})
+	mux.HandleFunc("DELETE /api/kernels/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		delete(s.kernels, r.PathValue("id"))
+		s.deleted = append(s.deleted, r.PathValue("id"))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/kernels/{id}/channels", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		s.serveKernel(r.Context(), conn)
+	})
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			http.Error(w, `{"message":"Forbidden"}`, http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeJupyterServer) running(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.kernels[id]
+}
+
+func (s *fakeJupyterServer) deletedKernels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.deleted...)
+}
+
+// dropConnections closes every kernel websocket, as a server restart would.
+func (s *fakeJupyterServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.CloseNow()
+	}
+}
+
+func (s *fakeJupyterServer) serveKernel(ctx context.Context, conn *websocket.Conn) {
+	send := func(channel, msgType string, parent map[string]any, content map[string]any) {
+		data, _ := json.Marshal(map[string]any{
+			"channel":       channel,
+			"header":        map[string]any{"msg_id": "kernel-" + msgType, "msg_type": msgType},
+			"parent_header": parent,
+			"metadata":      map[string]any{},
+			"content":       content,
+		})
+		_ = conn.Write(ctx, websocket.MessageText, data)
+	}
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		var msg struct {
+			Channel string         `json:"channel"`
+			Header  map[string]any `json:"header"`
+			Content map[string]any `json:"content"`
+		}
+		if json.Unmarshal(data, &msg) != nil || msg.Channel != "shell" || msg.Header["msg_type"] != "execute_request" {
+			continue
+		}
+		code, _ := msg.Content["code"].(string)
+		send("iopub", "status", msg.Header, map[string]any{"execution_state": "busy"})
+		send("iopub", "execute_input", msg.Header, map[string]any{"code": code, "execution_count": 1})
+		send("iopub", "stream", msg.Header, map[string]any{"name": "stdout", "text": code})
+		send("shell", "execute_reply", msg.Header, map[string]any{"status": "ok", "execution_count": 1})
+		send("iopub", "status", msg.Header, map[string]any{"execution_state": "idle"})
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConnectJupyterServer(t *testing.T) {
+	scope := NewKernelScope(config.KernelScopeNote, "notes/a.md")
+
+	t.Run("starts a kernel and runs code over the websocket", func(t *testing.T) {
+		server := newFakeJupyterServer(t)
+		m := New(t.TempDir(), config.AllKernels{})
+
+		inst, err := m.ConnectJupyterServer(context.Background(), "python", scope, JupyterServerKernelParams{
+			ServerURL:  server.URL + "/lab?token=secret",
+			KernelName: "python3",
+		})
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		if m.Lookup("python", scope) != inst {
+			t.Fatal("attached kernel should serve the note's scope")
+		}
+		snapshots := m.List()
+		if len(snapshots) != 1 || snapshots[0].Origin != KernelOriginJupyterServer || snapshots[0].Endpoint != server.URL+"/" {
+			t.Fatalf("unexpected snapshots: %+v", snapshots)
+		}
+		if !inst.IsHeartbeating() {
+			t.Fatal("kernel should be alive while the websocket is open")
+		}
+
+		if err := inst.SendExecute("notes/a.md", "block", "exec", "hello"); err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+		var entries []TranscriptEntry
+		waitFor(t, func() bool {
+			entries, _ = m.KernelHistory(inst.ID())
+			return len(entries) == 1 && entries[0].Status == TranscriptStatusOK
+		})
+		if len(entries[0].Outputs) != 1 || entries[0].Outputs[0].Text != "hello" {
+			t.Fatalf("unexpected outputs: %+v", entries[0].Outputs)
+		}
+		if !inst.IsIdle() {
+			t.Fatal("kernel should be idle after the execution finished")
+		}
+
+		if err := m.Shutdown(inst.ID(), false); err != nil {
+			t.Fatalf("shutdown: %v", err)
+		}
+		if got := server.deletedKernels(); len(got) != 1 || got[0] != "k1" {
+			t.Fatalf("kernel bytebook started should be shut down, deleted %v", got)
+		}
+	})
+
+	t.Run("existing kernels are detached, not shut down", func(t *testing.T) {
+		server := newFakeJupyterServer(t)
+		server.kernels["k1"] = true
+		m := New(t.TempDir(), config.AllKernels{})
+		params := JupyterServerKernelParams{ServerURL: server.URL, Token: "secret", KernelID: "k1"}
+
+		inst, err := m.ConnectJupyterServer(context.Background(), "python", scope, params)
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		_, err = m.ConnectJupyterServer(context.Background(), "python", scope, params)
+		var inUse KernelScopeInUseError
+		if !errors.As(err, &inUse) || inUse.KernelInstanceID != inst.ID() {
+			t.Fatalf("expected scope in use error, got %v", err)
+		}
+
+		if err := m.Shutdown(inst.ID(), false); err != nil {
+			t.Fatalf("shutdown: %v", err)
+		}
+		if got := server.deletedKernels(); len(got) != 0 {
+			t.Fatalf("attached kernel should keep running, deleted %v", got)
+		}
+	})
+
+	t.Run("rejects unknown kernels and bad tokens", func(t *testing.T) {
+		server := newFakeJupyterServer(t)
+		m := New(t.TempDir(), config.AllKernels{})
+
+		if _, err := m.ConnectJupyterServer(context.Background(), "python", scope, JupyterServerKernelParams{
+			ServerURL: server.URL, Token: "secret", KernelID: "missing",
+		}); err == nil {
+			t.Fatal("expected an error for a kernel that is not running")
+		}
+		if _, err := m.ConnectJupyterServer(context.Background(), "python", scope, JupyterServerKernelParams{
+			ServerURL: server.URL, Token: "wrong",
+		}); err == nil {
+			t.Fatal("expected an error for a bad token")
+		}
+		if len(m.List()) != 0 {
+			t.Fatal("failed connections should not register kernels")
+		}
+	})
+
+	t.Run("a dropped websocket removes the kernel", func(t *testing.T) {
+		server := newFakeJupyterServer(t)
+		m := New(t.TempDir(), config.AllKernels{})
+		inst, err := m.ConnectJupyterServer(context.Background(), "python", scope, JupyterServerKernelParams{
+			ServerURL: server.URL, Token: "secret",
+		})
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+
+		server.dropConnections()
+		waitFor(t, func() bool { return m.GetByID(inst.ID()) == nil })
+		if inst.IsHeartbeating() {
+			t.Fatal("disconnected kernel should not report a heartbeat")
+		}
+	})
+}
+
+func TestReadConnectionFile(t *testing.T) {
+	write := func(t *testing.T, contents string) string {
+		path := filepath.Join(t.TempDir(), "kernel-1234.json")
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("reads a jupyter_client connection file", func(t *testing.T) {
+		info, err := ReadConnectionFile(write(t, `{
+			"shell_port": 1, "iopub_port": 2, "stdin_port": 3, "control_port": 4, "hb_port": 5,
+			"ip": "0.0.0.0", "key": "abc", "transport": "tcp",
+			"signature_scheme": "hmac-sha512", "kernel_name": "python3"
+		}`))
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if info.IP != "127.0.0.1" || info.Key != "abc" || info.SignatureScheme != "hmac-sha512" || info.HBPort != 5 {
+			t.Fatalf("unexpected connection info: %+v", info)
+		}
+	})
+
+	t.Run("rejects unsupported files", func(t *testing.T) {
+		for name, contents := range map[string]string{
+			"ipc transport":  `{"shell_port": 1, "iopub_port": 2, "stdin_port": 3, "control_port": 4, "hb_port": 5, "transport": "ipc"}`,
+			"missing port":   `{"shell_port": 1, "iopub_port": 2, "stdin_port": 3, "control_port": 4}`,
+			"unknown scheme": `{"shell_port": 1, "iopub_port": 2, "stdin_port": 3, "control_port": 4, "hb_port": 5, "signature_scheme": "hmac-md5"}`,
+			"not json":       `shell_port=1`,
+		} {
+			if _, err := ReadConnectionFile(write(t, contents)); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
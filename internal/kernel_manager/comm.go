@@ -0,0 +1,100 @@
+package kernel_manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+)
+
+// Comm message types the frontend may send.
+const (
+	CommOpen  = "comm_open"
+	CommMsg   = "comm_msg"
+	CommClose = "comm_close"
+)
+
+// CommMessage is a frontend-initiated message on a comm channel, e.g. a widget
+// state update. TargetName is only used when MsgType is comm_open.
+type CommMessage struct {
+	MsgType    string         `json:"msgType"`
+	CommID     string         `json:"commId"`
+	TargetName string         `json:"targetName"`
+	Data       map[string]any `json:"data"`
+	Metadata   map[string]any `json:"metadata"`
+	Buffers    [][]byte       `json:"buffers"`
+}
+
+// SendComm sends a comm message on the shell socket and returns the generated
+// message id. Output produced while the kernel handles it (e.g. a button
+// callback printing) is parented to that id, so it routes back to codeBlockID.
+func (i *KernelInstance) SendComm(codeBlockID, executionID string, message CommMessage) (string, error) {
+	if i.sockets == nil || i.sockets.Shell == nil {
+		return "", fmt.Errorf("shell socket not initialized")
+	}
+	if message.CommID == "" {
+		return "", fmt.Errorf("comm id is required")
+	}
+
+	var send func(jupyter_protocol.MessageSender, jupyter_protocol.CommMessageParams) error
+	switch message.MsgType {
+	case CommOpen:
+		if message.TargetName == "" {
+			return "", fmt.Errorf("comm_open requires a target name")
+		}
+		send = jupyter_protocol.SendCommOpen
+	case CommMsg:
+		send = jupyter_protocol.SendCommMessage
+	case CommClose:
+		send = jupyter_protocol.SendCommClose
+	default:
+		return "", fmt.Errorf("unsupported comm message type %q", message.MsgType)
+	}
+
+	messageID := fmt.Sprintf("%s|%s|comm_%d", codeBlockID, executionID, time.Now().UnixNano())
+	err := send(i.sockets.Shell, jupyter_protocol.CommMessageParams{
+		MessageParams: jupyter_protocol.MessageParams{
+			MessageID:       messageID,
+			SessionID:       "current-session",
+			Key:             i.connectionInfo.Key,
+			SignatureScheme: i.connectionInfo.SignatureScheme,
+		},
+		CommID:     message.CommID,
+		TargetName: message.TargetName,
+		Data:       message.Data,
+		Metadata:   message.Metadata,
+		Buffers:    message.Buffers,
+	})
+	if err != nil {
+		return "", err
+	}
+	// Interacting with a widget is user activity; keep the kernel off the reaper's list.
+	i.MarkActivity()
+	return messageID, nil
+}
+
+// SendCommInfo sends a comm_info_request on the shell socket and returns the
+// generated message id. The reply is emitted as a comm info event. An empty
+// targetName lists every open comm.
+func (i *KernelInstance) SendCommInfo(targetName string) (string, error) {
+	if i.sockets == nil || i.sockets.Shell == nil {
+		return "", fmt.Errorf("shell socket not initialized")
+	}
+	messageID := fmt.Sprintf("comm_info_%d", time.Now().UnixNano())
+	err := jupyter_protocol.SendCommInfoRequest(
+		i.sockets.Shell,
+		jupyter_protocol.CommInfoRequestParams{
+			MessageParams: jupyter_protocol.MessageParams{
+				MessageID:       messageID,
+				SessionID:       "current-session",
+				Key:             i.connectionInfo.Key,
+				SignatureScheme: i.connectionInfo.SignatureScheme,
+			},
+			TargetName: targetName,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	return messageID, nil
+}
@@ -0,0 +1,99 @@
+# Variable introspection helpers for the Python kernel. This file is sent as a
+# silent execute_request before each introspection call; the helpers return
+# JSON wrapped in a str subclass whose repr() is the raw JSON, so the
+# user_expressions "text/plain" result can be decoded directly.
+def _bytebook_setup():
+    import json
+
+    class _BytebookJSON(str):
+        def __repr__(self):
+            return str(self)
+
+    hidden = {"In", "Out", "exit", "quit", "get_ipython"}
+    repr_limit = 120
+
+    def short_repr(value):
+        try:
+            text = repr(value)
+        except Exception as exc:
+            text = "<unrepresentable: %s>" % exc
+        if len(text) > repr_limit:
+            text = text[: repr_limit - 3] + "..."
+        return text
+
+    def shape_of(value):
+        shape = getattr(value, "shape", None)
+        if isinstance(shape, tuple):
+            try:
+                return [int(n) for n in shape]
+            except (TypeError, ValueError):
+                return []
+        if isinstance(value, (list, tuple, dict, set, frozenset, str, bytes)):
+            return [len(value)]
+        return []
+
+    def is_variable(name, value):
+        import types
+        if name.startswith("_") or name in hidden:
+            return False
+        return not isinstance(value, (types.ModuleType, types.FunctionType, types.BuiltinFunctionType, type))
+
+    def type_name(value):
+        kind = type(value)
+        module = kind.__module__
+        if module in ("builtins", "__main__"):
+            return kind.__name__
+        return "%s.%s" % (module.split(".")[0], kind.__name__)
+
+    def list_variables():
+        namespace = globals()
+        out = []
+        for name in sorted(namespace):
+            value = namespace[name]
+            if not is_variable(name, value):
+                continue
+            out.append({"name": name, "type": type_name(value), "shape": shape_of(value), "repr": short_repr(value)})
+        return _BytebookJSON(json.dumps(out))
+
+    def cell(value):
+        return value if isinstance(value, str) else short_repr(value)
+
+    def get_variable(name, offset, limit):
+        namespace = globals()
+        if name not in namespace:
+            raise NameError("name %r is not defined" % name)
+        value = namespace[name]
+        preview = {
+            "name": name,
+            "type": type_name(value),
+            "shape": shape_of(value),
+            "repr": short_repr(value),
+            "columns": [],
+            "rows": [],
+            "offset": offset,
+            "totalRows": 0,
+        }
+        if hasattr(value, "iloc") and hasattr(value, "columns"):
+            preview["columns"] = [str(c) for c in value.columns]
+            preview["totalRows"] = len(value)
+            page = value.iloc[offset : offset + limit]
+            preview["rows"] = [[cell(v) for v in row] for row in page.itertuples(index=False, name=None)]
+        elif hasattr(value, "ndim") and hasattr(value, "tolist") and value.ndim >= 1:
+            preview["totalRows"] = int(value.shape[0])
+            for row in value[offset : offset + limit].tolist():
+                preview["rows"].append([cell(v) for v in row] if isinstance(row, list) else [cell(row)])
+        elif isinstance(value, dict):
+            preview["columns"] = ["key", "value"]
+            items = list(value.items())
+            preview["totalRows"] = len(items)
+            preview["rows"] = [[short_repr(k), short_repr(v)] for k, v in items[offset : offset + limit]]
+        elif isinstance(value, (list, tuple, set, frozenset)):
+            items = list(value)
+            preview["totalRows"] = len(items)
+            preview["rows"] = [[short_repr(v)] for v in items[offset : offset + limit]]
+        return _BytebookJSON(json.dumps(preview))
+
+    return list_variables, get_variable
+
+_bytebook_list_variables, _bytebook_get_variable = _bytebook_setup()
+del _bytebook_setup
@@ -5,33 +5,50 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"log"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/jupyter_protocol"
 	"github.com/etesam913/bytebook/internal/jupyter_protocol/sockets"
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/google/uuid"
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 // KernelInstance represents a single kernel process and its sockets. Each instance
-// is bound to a single (language, noteID) pair.
+// is bound to a single (language, scope) pair; see KernelScope.
 type KernelInstance struct {
 	id                 string
 	language           string
 	scopeType          string
 	scopeID            string
+	noteID             string
 	connectionFilePath string
 	connectionInfo     config.KernelConnectionInfo
+	origin             string
+	endpoint           string
+	remote             *remoteKernel
+	venvPath           string
+	settings           config.KernelLanguageSettings
 	processHandle      *exec.Cmd
 	processWait        chan error
 	stderrBuf          stderrReader
+	usage              usageSampler
+	transcript         *transcript
 	sockets        *sockets.LanguageSockets
 	heartbeatState *jupyter_protocol.KernelHeartbeatState
 	mu               sync.RWMutex
 	activeExecutions map[string]struct{}
+	executionTimers  map[string]*time.Timer
+	pendingReplies   map[string]chan jupyter_protocol.Message
+	pendingFetches   map[string][]string
 	executionQueue   []string
+	fetchedRequires  util.Set[string]
+	checkedNotes     util.Set[string]
 	lastActivityAt   time.Time
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -57,6 +74,11 @@ func (i *KernelInstance) IsIdle() bool {
 	return len(i.activeExecutions) == 0 && len(i.executionQueue) == 0
 }
 
+// scope returns the KernelScope this instance was launched for.
+func (i *KernelInstance) scope() KernelScope {
+	return KernelScope{Type: i.scopeType, ID: i.scopeID, NoteID: i.noteID}
+}
+
 // LastActivity returns the timestamp of the most recent execution send / status change.
 func (i *KernelInstance) LastActivity() time.Time {
 	i.mu.RLock()
@@ -71,7 +93,8 @@ func (i *KernelInstance) MarkActivity() {
 	i.lastActivityAt = time.Now()
 }
 
-// trackExecutionStart records that an execution is in flight.
+// trackExecutionStart records that an execution is in flight and, when the
+// language has an execution timeout configured, arms its timeout timer.
 func (i *KernelInstance) trackExecutionStart(messageID string) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
@@ -80,40 +103,163 @@ func (i *KernelInstance) trackExecutionStart(messageID string) {
 	}
 	i.activeExecutions[messageID] = struct{}{}
 	i.lastActivityAt = time.Now()
+
+	if i.settings.ExecutionTimeoutSeconds <= 0 {
+		return
+	}
+	if i.executionTimers == nil {
+		i.executionTimers = map[string]*time.Timer{}
+	}
+	if _, armed := i.executionTimers[messageID]; !armed {
+		timeout := time.Duration(i.settings.ExecutionTimeoutSeconds) * time.Second
+		i.executionTimers[messageID] = time.AfterFunc(timeout, func() {
+			i.handleExecutionTimeout(messageID)
+		})
+	}
 }
 
-// trackExecutionEnd clears an in-flight execution.
+// trackExecutionEnd clears an in-flight execution and disarms its timeout.
 func (i *KernelInstance) trackExecutionEnd(messageID string) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 	delete(i.activeExecutions, messageID)
+	if timer, ok := i.executionTimers[messageID]; ok {
+		timer.Stop()
+		delete(i.executionTimers, messageID)
+	}
 	i.lastActivityAt = time.Now()
 }
 
+// isExecutionActive reports whether messageID is still in flight.
+func (i *KernelInstance) isExecutionActive(messageID string) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	_, ok := i.activeExecutions[messageID]
+	return ok
+}
+
+// stopExecutionTimers disarms every pending execution timeout.
+func (i *KernelInstance) stopExecutionTimers() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for messageID, timer := range i.executionTimers {
+		timer.Stop()
+		delete(i.executionTimers, messageID)
+	}
+}
+
+// handleExecutionTimeout runs when an execution outlives the language's
+// configured timeout. The kernel is interrupted first; if the execution is
+// still running after executionInterruptGrace the kernel is restarted.
+// Attached kernels are only interrupted.
+func (i *KernelInstance) handleExecutionTimeout(messageID string) {
+	if !i.isExecutionActive(messageID) {
+		return
+	}
+
+	// messageId format: {codeBlockId}|{executionId}|{startTime}
+	codeBlockID, rest, _ := strings.Cut(messageID, "|")
+	executionID, _, _ := strings.Cut(rest, "|")
+
+	log.Printf("kernel_manager: execution %s on kernel %s exceeded %ds, interrupting", messageID, i.id, i.settings.ExecutionTimeoutSeconds)
+	if err := i.SendInterrupt(codeBlockID, executionID); err != nil {
+		log.Printf("kernel_manager: failed to interrupt timed out execution %s: %v", messageID, err)
+	}
+	i.emitExecutionTimeout(messageID, "interrupted")
+
+	select {
+	case <-i.ctx.Done():
+		return
+	case <-time.After(executionInterruptGrace):
+	}
+	if !i.isExecutionActive(messageID) {
+		return
+	}
+
+	if i.isAttached() {
+		// bytebook cannot restart a kernel it did not launch.
+		log.Printf("kernel_manager: execution %s on attached kernel %s ignored interrupt", messageID, i.id)
+		return
+	}
+	log.Printf("kernel_manager: execution %s ignored interrupt, restarting kernel %s", messageID, i.id)
+	i.emitExecutionTimeout(messageID, "restarted")
+	if i.manager != nil {
+		if err := i.manager.restart(i, "timeout"); err != nil {
+			log.Printf("kernel_manager: failed to restart kernel %s after timeout: %v", i.id, err)
+		}
+	}
+}
+
+func (i *KernelInstance) emitExecutionTimeout(messageID, action string) {
+	if app := application.Get(); app != nil {
+		app.Event.EmitEvent(&application.CustomEvent{
+			Name: kernelInstanceExecutionTimeoutEvent,
+			Data: KernelExecutionTimeoutEventData{
+				ID:             i.id,
+				Language:       i.language,
+				NoteID:         i.noteID,
+				MessageID:      messageID,
+				TimeoutSeconds: i.settings.ExecutionTimeoutSeconds,
+				Action:         action,
+			},
+		})
+	}
+}
+
+// Usage samples the kernel process's live memory and CPU usage. A kernel whose
+// process is gone reports zero usage.
+func (i *KernelInstance) Usage() ProcessUsage {
+	if i.processHandle == nil || i.processHandle.Process == nil {
+		return ProcessUsage{}
+	}
+	usage, err := i.usage.sample(i.processHandle.Process.Pid)
+	if err != nil {
+		return ProcessUsage{}
+	}
+	return usage
+}
+
 // Snapshot returns a JSON-serializable view of this instance for the frontend.
 func (i *KernelInstance) Snapshot() KernelInstanceSnapshot {
+	usage := i.Usage()
+	var invalidSignatures int64
+	if i.sockets != nil {
+		invalidSignatures = i.sockets.InvalidSignatures()
+	}
 	i.mu.RLock()
 	defer i.mu.RUnlock()
 	return KernelInstanceSnapshot{
-		ID:               i.id,
-		Language:         i.language,
-		NoteID:           i.scopeID,
-		ScopeType:        i.scopeType,
-		Heartbeat:        boolToHeartbeatStatus(i.heartbeatState.GetHeartbeatStatus()),
-		LastActivityAt:   i.lastActivityAt.UnixMilli(),
-		ActiveExecutions: len(i.activeExecutions),
+		ID:                i.id,
+		Language:          i.language,
+		NoteID:            i.noteID,
+		ScopeType:         i.scopeType,
+		ScopeID:           i.scopeID,
+		Heartbeat:         boolToHeartbeatStatus(i.heartbeatState.GetHeartbeatStatus()),
+		LastActivityAt:    i.lastActivityAt.UnixMilli(),
+		ActiveExecutions:  len(i.activeExecutions),
+		MemoryRSSBytes:    usage.RSSBytes,
+		CPUPercent:        usage.CPUPercent,
+		InvalidSignatures: invalidSignatures,
+		Origin:            i.origin,
+		Endpoint:          i.endpoint,
 	}
 }
 
 // KernelInstanceSnapshot is a JSON-marshalable view used in events and List() results.
 type KernelInstanceSnapshot struct {
-	ID               string `json:"id"`
-	Language         string `json:"language"`
-	NoteID           string `json:"noteId"`
-	ScopeType        string `json:"scopeType"`
-	Heartbeat        string `json:"heartbeat"`
-	LastActivityAt   int64  `json:"lastActivityAt"`
-	ActiveExecutions int    `json:"activeExecutions"`
+	ID                string  `json:"id"`
+	Language          string  `json:"language"`
+	NoteID            string  `json:"noteId"`
+	ScopeType         string  `json:"scopeType"`
+	ScopeID           string  `json:"scopeId"`
+	Heartbeat         string  `json:"heartbeat"`
+	LastActivityAt    int64   `json:"lastActivityAt"`
+	ActiveExecutions  int     `json:"activeExecutions"`
+	MemoryRSSBytes    int64   `json:"memoryRssBytes"`
+	CPUPercent        float64 `json:"cpuPercent"`
+	InvalidSignatures int64   `json:"invalidSignatures"`
+	Origin            string  `json:"origin"`
+	Endpoint          string  `json:"endpoint"`
 }
 
 func boolToHeartbeatStatus(ok bool) string {
@@ -132,58 +278,134 @@ func generateHMACKey() (string, error) {
 	return hex.EncodeToString(buf), nil
 }
 
-// SendExecute sends an execute_request on this instance's shell socket. The
-// activeExecutions map is updated by the iopub goroutine via OnExecuteStatus,
-// keyed by the full Jupyter parent_msg_id (which includes the timestamp suffix).
-func (i *KernelInstance) SendExecute(codeBlockID, executionID, code string) error {
-	if i.sockets == nil || i.sockets.ShellSocketDealer == nil {
+// SendExecute sends an execute_request on this instance's shell socket and
+// records it in the transcript under noteID. The activeExecutions map is updated
+// by the socket pipeline via OnExecuteStatus, keyed by the full Jupyter
+// parent_msg_id (which includes the timestamp suffix).
+func (i *KernelInstance) SendExecute(noteID, codeBlockID, executionID, code string) error {
+	if i.sockets == nil || i.sockets.Shell == nil {
 		return fmt.Errorf("shell socket not initialized")
 	}
 	messageID := fmt.Sprintf("%s|%s", codeBlockID, executionID)
+	i.transcript.begin(noteID, codeBlockID, executionID, code)
 	if err := jupyter_protocol.SendExecuteRequest(
-		i.sockets.ShellSocketDealer,
+		i.sockets.Shell,
 		jupyter_protocol.ExecuteMessageParams{
 			MessageParams: jupyter_protocol.MessageParams{
-				MessageID: messageID,
-				SessionID: "current-session",
-				Key:       i.connectionInfo.Key,
+				MessageID:       messageID,
+				SessionID:       "current-session",
+				Key:             i.connectionInfo.Key,
+				SignatureScheme: i.connectionInfo.SignatureScheme,
 			},
 			Code: code,
 		},
 	); err != nil {
+		i.transcript.abandonExecution(codeBlockID, executionID)
 		return err
 	}
 	i.MarkActivity()
 	return nil
 }
 
-// SendInterrupt sends an interrupt_request on the control socket.
+// ReplayedExecution identifies an execution re-sent by Replay.
+type ReplayedExecution struct {
+	CodeBlockID string `json:"codeBlockId"`
+	ExecutionID string `json:"executionId"`
+}
+
+// Replay re-sends the successful executions from a transcript in their original
+// order, each under a fresh execution id. Failed, aborted and incomplete
+// executions and entries for other languages are skipped. The kernel queues the
+// requests, so a replayed error aborts the rest as it would interactively.
+func (i *KernelInstance) Replay(entries []TranscriptEntry) ([]ReplayedExecution, error) {
+	replayed := []ReplayedExecution{}
+	for _, entry := range entries {
+		if entry.Status != TranscriptStatusOK || entry.Language != i.language {
+			continue
+		}
+		executionID := uuid.NewString()
+		if err := i.SendExecute(entry.NoteID, entry.CodeBlockID, executionID, entry.Code); err != nil {
+			return replayed, fmt.Errorf("failed to replay execution %s: %w", entry.ExecutionID, err)
+		}
+		replayed = append(replayed, ReplayedExecution{CodeBlockID: entry.CodeBlockID, ExecutionID: executionID})
+	}
+	return replayed, nil
+}
+
+// handleIOPubMessage records kernel output in the transcript. Backend
+// introspection requests are not part of the user's session.
+func (i *KernelInstance) handleIOPubMessage(parentMsgID string, msg jupyter_protocol.Message) {
+	if isIntrospectionMessage(parentMsgID) {
+		return
+	}
+	i.transcript.observe(parentMsgID, msg)
+}
+
+// socketParams returns the parameters for connecting to the instance's kernel.
+func (i *KernelInstance) socketParams() sockets.CreateParams {
+	return sockets.CreateParams{
+		ConnectionInfo:  i.connectionInfo,
+		Ctx:             i.ctx,
+		Cancel:          i.cancel,
+		HeartbeatState:  i.heartbeatState,
+		InstanceID:      i.id,
+		NoteID:          i.noteID,
+		OnExecuteStatus: i.handleExecuteStatus,
+		OnShellReply:    i.handleShellReply,
+		OnIOPubMessage:  i.handleIOPubMessage,
+	}
+}
+
+// handleExecuteStatus maintains activeExecutions from execute_request status
+// messages.
+func (i *KernelInstance) handleExecuteStatus(status, parentMsgID string) {
+	// Backend introspection is not user activity and must not hold the kernel
+	// busy for eviction or trip execution timeouts.
+	if isIntrospectionMessage(parentMsgID) {
+		return
+	}
+	switch status {
+	case "busy":
+		i.trackExecutionStart(parentMsgID)
+	case "idle":
+		i.trackExecutionEnd(parentMsgID)
+	}
+}
+
+// SendInterrupt sends an interrupt_request on the control socket. Kernels on
+// a Jupyter Server are interrupted through its REST API instead, which also
+// handles kernels that only support signal interrupts.
 func (i *KernelInstance) SendInterrupt(codeBlockID, executionID string) error {
-	if i.sockets == nil || i.sockets.ControlSocketDealer == nil {
+	if i.remote != nil {
+		return i.remote.interrupt()
+	}
+	if i.sockets == nil || i.sockets.Control == nil {
 		return fmt.Errorf("control socket not initialized")
 	}
 	return jupyter_protocol.SendInterruptMessage(
-		i.sockets.ControlSocketDealer,
+		i.sockets.Control,
 		jupyter_protocol.MessageParams{
-			MessageID: fmt.Sprintf("%s|%s", codeBlockID, executionID),
-			SessionID: "current-session",
-			Key:       i.connectionInfo.Key,
+			MessageID:       fmt.Sprintf("%s|%s", codeBlockID, executionID),
+			SessionID:       "current-session",
+			Key:             i.connectionInfo.Key,
+			SignatureScheme: i.connectionInfo.SignatureScheme,
 		},
 	)
 }
 
 // SendInputReply sends an input_reply on the stdin socket.
 func (i *KernelInstance) SendInputReply(codeBlockID, executionID, value string) error {
-	if i.sockets == nil || i.sockets.StdinSocketDealer == nil {
+	if i.sockets == nil || i.sockets.Stdin == nil {
 		return fmt.Errorf("stdin socket not initialized")
 	}
 	return jupyter_protocol.SendInputReplyMessage(
-		i.sockets.StdinSocketDealer,
+		i.sockets.Stdin,
 		jupyter_protocol.InputReplyMessageParams{
 			MessageParams: jupyter_protocol.MessageParams{
-				MessageID: fmt.Sprintf("%s|%s", codeBlockID, executionID),
-				SessionID: "current-session",
-				Key:       i.connectionInfo.Key,
+				MessageID:       fmt.Sprintf("%s|%s", codeBlockID, executionID),
+				SessionID:       "current-session",
+				Key:             i.connectionInfo.Key,
+				SignatureScheme: i.connectionInfo.SignatureScheme,
 			},
 			Value: value,
 		},
@@ -192,17 +414,18 @@ func (i *KernelInstance) SendInputReply(codeBlockID, executionID, value string)
 
 // SendInspect sends an inspect_request on the shell socket and returns the generated message id.
 func (i *KernelInstance) SendInspect(codeBlockID, executionID, code string, cursorPos, detailLevel int) (string, error) {
-	if i.sockets == nil || i.sockets.ShellSocketDealer == nil {
+	if i.sockets == nil || i.sockets.Shell == nil {
 		return "", fmt.Errorf("shell socket not initialized")
 	}
 	messageID := fmt.Sprintf("%s|%s|inspect_%d", codeBlockID, executionID, time.Now().UnixNano())
 	err := jupyter_protocol.SendInspectRequest(
-		i.sockets.ShellSocketDealer,
+		i.sockets.Shell,
 		jupyter_protocol.InspectRequestParams{
 			MessageParams: jupyter_protocol.MessageParams{
-				MessageID: messageID,
-				SessionID: "current-session",
-				Key:       i.connectionInfo.Key,
+				MessageID:       messageID,
+				SessionID:       "current-session",
+				Key:             i.connectionInfo.Key,
+				SignatureScheme: i.connectionInfo.SignatureScheme,
 			},
 			Code:        code,
 			CursorPos:   cursorPos,
@@ -217,17 +440,18 @@ func (i *KernelInstance) SendInspect(codeBlockID, executionID, code string, curs
 
 // SendComplete sends a complete_request on the shell socket and returns the generated message id.
 func (i *KernelInstance) SendComplete(codeBlockID, executionID, code string, cursorPos int) (string, error) {
-	if i.sockets == nil || i.sockets.ShellSocketDealer == nil {
+	if i.sockets == nil || i.sockets.Shell == nil {
 		return "", fmt.Errorf("shell socket not initialized")
 	}
 	messageID := fmt.Sprintf("%s|%s|complete_%d", codeBlockID, executionID, time.Now().UnixNano())
 	err := jupyter_protocol.SendCompleteRequest(
-		i.sockets.ShellSocketDealer,
+		i.sockets.Shell,
 		jupyter_protocol.CompleteRequestParams{
 			MessageParams: jupyter_protocol.MessageParams{
-				MessageID: messageID,
-				SessionID: "current-session",
-				Key:       i.connectionInfo.Key,
+				MessageID:       messageID,
+				SessionID:       "current-session",
+				Key:             i.connectionInfo.Key,
+				SignatureScheme: i.connectionInfo.SignatureScheme,
 			},
 			Code:      code,
 			CursorPos: cursorPos,
@@ -239,6 +463,15 @@ func (i *KernelInstance) SendComplete(codeBlockID, executionID, code string, cur
 	return messageID, nil
 }
 
+// TruncatedOutput returns a page of the stream output that was held back for
+// handle after an execution exceeded its output budget.
+func (i *KernelInstance) TruncatedOutput(handle string, offset, limit int) (sockets.TruncatedOutputPage, error) {
+	if i.sockets == nil {
+		return sockets.TruncatedOutputPage{}, fmt.Errorf("sockets not initialized")
+	}
+	return i.sockets.TruncatedOutput(handle, offset, limit)
+}
+
 // IsHeartbeating reports whether the heartbeat goroutine has confirmed the kernel is alive.
 func (i *KernelInstance) IsHeartbeating() bool {
 	if i.heartbeatState == nil {
@@ -249,25 +482,29 @@ func (i *KernelInstance) IsHeartbeating() bool {
 
 // shutdown attempts a graceful shutdown_request followed by a Kill timeout.
 // Then cancels the context (which closes all socket goroutines), removes the
-// connection file, and emits the shutdown event.
+// connection file, and emits the shutdown event. Attached kernels are only
+// disconnected, except Jupyter Server kernels that bytebook started.
 func (i *KernelInstance) shutdown(reason string, restart bool) error {
 	graceful := 3 * time.Second
 	if i.language == "java" {
 		graceful = 5 * time.Second
 	}
 
-	if i.sockets != nil && i.heartbeatState != nil && i.heartbeatState.GetHeartbeatStatus() {
+	// Attached kernels belong to someone else; shutting them down only
+	// disconnects bytebook.
+	if !i.isAttached() && i.sockets != nil && i.heartbeatState != nil && i.heartbeatState.GetHeartbeatStatus() {
 		// gonb only honors shutdown_request on the shell socket
-		var sock = i.sockets.ControlSocketDealer
-		if i.language == "go" && i.sockets.ShellSocketDealer != nil {
-			sock = i.sockets.ShellSocketDealer
+		var sock = i.sockets.Control
+		if i.language == "go" && i.sockets.Shell != nil {
+			sock = i.sockets.Shell
 		}
 		if sock != nil {
 			_ = jupyter_protocol.SendShutdownMessage(sock, jupyter_protocol.ShutdownMessageParams{
 				MessageParams: jupyter_protocol.MessageParams{
-					MessageID: fmt.Sprintf("shutdown-%d", time.Now().UnixNano()),
-					SessionID: "current-session",
-					Key:       i.connectionInfo.Key,
+					MessageID:       fmt.Sprintf("shutdown-%d", time.Now().UnixNano()),
+					SessionID:       "current-session",
+					Key:             i.connectionInfo.Key,
+					SignatureScheme: i.connectionInfo.SignatureScheme,
 				},
 				Restart: restart,
 			})
@@ -284,12 +521,23 @@ func (i *KernelInstance) shutdown(reason string, restart bool) error {
 		}
 	}
 
+	if i.remote != nil && i.remote.owned {
+		if err := i.remote.shutdown(); err != nil {
+			log.Printf("kernel_manager: failed to shut down remote kernel %s: %v", i.remote.kernelID, err)
+		}
+	}
+
+	i.stopExecutionTimers()
+	i.transcript.abandon()
+
 	if i.cancel != nil {
 		i.cancel()
 	}
 
-	if err := removeConnectionFile(i.connectionFilePath); err != nil {
-		return fmt.Errorf("failed to remove connection file: %w", err)
+	if i.connectionFilePath != "" {
+		if err := removeConnectionFile(i.connectionFilePath); err != nil {
+			return fmt.Errorf("failed to remove connection file: %w", err)
+		}
 	}
 
 	if app := application.Get(); app != nil {
@@ -298,7 +546,7 @@ func (i *KernelInstance) shutdown(reason string, restart bool) error {
 			Data: KernelShutdownEventData{
 				ID:       i.id,
 				Language: i.language,
-				NoteID:   i.scopeID,
+				NoteID:   i.noteID,
 				Reason:   reason,
 			},
 		})
@@ -0,0 +1,221 @@
+package kernel_manager
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+)
+
+//go:embed inspectors/python.py
+var pythonInspectorSetup string
+
+// introspectionMessagePrefix marks execute_requests sent by the backend rather
+// than a code block. The id deliberately has no "|" so the iopub listener does
+// not mistake it for a {codeBlockId}|{executionId}|{startTime} id.
+const introspectionMessagePrefix = "introspect-"
+
+const (
+	defaultVariablePageSize = 50
+	maxVariablePageSize     = 500
+)
+
+// ErrIntrospectionUnsupported is returned for kernels with no variable inspector.
+var ErrIntrospectionUnsupported = errors.New("variable introspection is not supported for this kernel")
+
+// VariableSummary describes one variable in the kernel's namespace.
+type VariableSummary struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Shape []int  `json:"shape"`
+	Repr  string `json:"repr"`
+}
+
+// VariablePreview is a paginated view of a single variable. Tabular values
+// (DataFrames, arrays, dicts, sequences) fill Rows; everything else only has Repr.
+type VariablePreview struct {
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	Shape     []int      `json:"shape"`
+	Repr      string     `json:"repr"`
+	Columns   []string   `json:"columns"`
+	Rows      [][]string `json:"rows"`
+	Offset    int        `json:"offset"`
+	TotalRows int        `json:"totalRows"`
+}
+
+// variableInspector is the language-specific half of the variable explorer:
+// setup code defining helper functions, and user_expressions that call them.
+type variableInspector struct {
+	setup          string
+	listExpression func() string
+	getExpression  func(name string, offset, limit int) string
+}
+
+var variableInspectors = map[string]variableInspector{
+	"python": {
+		setup:          pythonInspectorSetup,
+		listExpression: func() string { return "_bytebook_list_variables()" },
+		getExpression: func(name string, offset, limit int) string {
+			// A JSON string literal is also a valid Python string literal.
+			quoted, _ := json.Marshal(name)
+			return fmt.Sprintf("_bytebook_get_variable(%s, %d, %d)", quoted, offset, limit)
+		},
+	},
+}
+
+// isIntrospectionMessage reports whether parentMsgID belongs to a backend-initiated
+// introspection request.
+func isIntrospectionMessage(parentMsgID string) bool {
+	return strings.HasPrefix(parentMsgID, introspectionMessagePrefix)
+}
+
+// ListVariables returns the user variables in the kernel's namespace.
+func (i *KernelInstance) ListVariables(ctx context.Context) ([]VariableSummary, error) {
+	inspector, ok := variableInspectors[i.language]
+	if !ok {
+		return nil, ErrIntrospectionUnsupported
+	}
+	variables := []VariableSummary{}
+	if err := i.evaluateUserExpression(ctx, inspector.setup, inspector.listExpression(), &variables); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// GetVariable returns a page of rows for the named variable. A non-positive
+// limit uses the default page size and large limits are capped.
+func (i *KernelInstance) GetVariable(ctx context.Context, name string, offset, limit int) (VariablePreview, error) {
+	inspector, ok := variableInspectors[i.language]
+	if !ok {
+		return VariablePreview{}, ErrIntrospectionUnsupported
+	}
+	if limit <= 0 {
+		limit = defaultVariablePageSize
+	}
+	limit = min(limit, maxVariablePageSize)
+	offset = max(offset, 0)
+
+	var preview VariablePreview
+	if err := i.evaluateUserExpression(ctx, inspector.setup, inspector.getExpression(name, offset, limit), &preview); err != nil {
+		return VariablePreview{}, err
+	}
+	return preview, nil
+}
+
+// evaluateUserExpression runs code silently, evaluates expression as a
+// user_expression, and JSON-decodes its text/plain result into out. It blocks
+// until the execute_reply arrives, ctx is done, or the kernel shuts down.
+func (i *KernelInstance) evaluateUserExpression(ctx context.Context, code, expression string, out any) error {
+	if i.sockets == nil || i.sockets.Shell == nil {
+		return fmt.Errorf("shell socket not initialized")
+	}
+
+	requestID := fmt.Sprintf("%s%d", introspectionMessagePrefix, time.Now().UnixNano())
+	replies := make(chan jupyter_protocol.Message, 1)
+	i.mu.Lock()
+	if i.pendingReplies == nil {
+		i.pendingReplies = map[string]chan jupyter_protocol.Message{}
+	}
+	i.pendingReplies[requestID] = replies
+	i.mu.Unlock()
+	defer func() {
+		i.mu.Lock()
+		delete(i.pendingReplies, requestID)
+		i.mu.Unlock()
+	}()
+
+	err := jupyter_protocol.SendExecuteRequest(
+		i.sockets.Shell,
+		jupyter_protocol.ExecuteMessageParams{
+			MessageParams: jupyter_protocol.MessageParams{
+				MessageID:       requestID,
+				SessionID:       "current-session",
+				Key:             i.connectionInfo.Key,
+				SignatureScheme: i.connectionInfo.SignatureScheme,
+			},
+			Code:            code,
+			Silent:          true,
+			UserExpressions: map[string]string{"result": expression},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-i.ctx.Done():
+		return fmt.Errorf("kernel shut down before replying")
+	case reply := <-replies:
+		return decodeUserExpression(reply, "result", out)
+	}
+}
+
+// handleShellReply routes execute_replies for introspection requests to the
+// waiting caller. It returns true when the reply belonged to an introspection
+// request, including late replies whose caller already gave up. Code block
+// replies also settle a pending FetchRequirements before reaching the frontend.
+func (i *KernelInstance) handleShellReply(parentMsgID string, msg jupyter_protocol.Message) bool {
+	if !isIntrospectionMessage(parentMsgID) {
+		i.handleFetchReply(parentMsgID, msg)
+		return false
+	}
+	// SendExecuteRequest appends "|{timestamp}" to the id we registered.
+	requestID, _, _ := strings.Cut(parentMsgID, "|")
+
+	i.mu.RLock()
+	replies, ok := i.pendingReplies[requestID]
+	i.mu.RUnlock()
+	if ok {
+		select {
+		case replies <- msg:
+		default:
+		}
+	}
+	return true
+}
+
+// decodeUserExpression extracts the named user_expression from an execute_reply
+// and JSON-decodes its text/plain representation into out.
+func decodeUserExpression(reply jupyter_protocol.Message, name string, out any) error {
+	if err := replyError(reply.Content); err != nil {
+		return err
+	}
+	expressions, ok := reply.Content["user_expressions"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("execute_reply has no user_expressions")
+	}
+	result, ok := expressions[name].(map[string]any)
+	if !ok {
+		return fmt.Errorf("execute_reply is missing user_expression %q", name)
+	}
+	if err := replyError(result); err != nil {
+		return err
+	}
+	data, _ := result["data"].(map[string]any)
+	text, ok := data["text/plain"].(string)
+	if !ok {
+		return fmt.Errorf("user_expression %q has no text/plain result", name)
+	}
+	if err := json.Unmarshal([]byte(text), out); err != nil {
+		return fmt.Errorf("failed to decode user_expression %q: %w", name, err)
+	}
+	return nil
+}
+
+// replyError converts a Jupyter {status: "error", ename, evalue} payload into an error.
+func replyError(content map[string]any) error {
+	if status, _ := content["status"].(string); status != "error" {
+		return nil
+	}
+	ename, _ := content["ename"].(string)
+	evalue, _ := content["evalue"].(string)
+	return fmt.Errorf("%s: %s", ename, evalue)
+}
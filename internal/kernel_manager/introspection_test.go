@@ -0,0 +1,96 @@
+package kernel_manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+)
+
+func TestHandleShellReply(t *testing.T) {
+	t.Run("routes reply to the pending request", func(t *testing.T) {
+		inst := newTestInstance("a", "python", time.Now(), true)
+		replies := make(chan jupyter_protocol.Message, 1)
+		inst.pendingReplies = map[string]chan jupyter_protocol.Message{"introspect-1": replies}
+
+		msg := jupyter_protocol.Message{Content: map[string]any{"status": "ok"}}
+		if !inst.handleShellReply("introspect-1|2026-01-01T00:00:00Z", msg) {
+			t.Fatal("expected introspection reply to be handled")
+		}
+		select {
+		case got := <-replies:
+			if got.Content["status"] != "ok" {
+				t.Fatalf("unexpected reply: %v", got.Content)
+			}
+		default:
+			t.Fatal("reply was not delivered")
+		}
+	})
+
+	t.Run("swallows late introspection replies", func(t *testing.T) {
+		inst := newTestInstance("a", "python", time.Now(), true)
+		if !inst.handleShellReply("introspect-2|2026-01-01T00:00:00Z", jupyter_protocol.Message{}) {
+			t.Fatal("late introspection reply should not reach the frontend")
+		}
+	})
+
+	t.Run("ignores code block replies", func(t *testing.T) {
+		inst := newTestInstance("a", "python", time.Now(), true)
+		if inst.handleShellReply("block|exec|2026-01-01T00:00:00Z", jupyter_protocol.Message{}) {
+			t.Fatal("code block reply should not be handled")
+		}
+	})
+}
+
+func TestDecodeUserExpression(t *testing.T) {
+	t.Run("decodes the text/plain JSON", func(t *testing.T) {
+		reply := jupyter_protocol.Message{Content: map[string]any{
+			"status": "ok",
+			"user_expressions": map[string]any{
+				"result": map[string]any{
+					"status": "ok",
+					"data": map[string]any{
+						"text/plain": `[{"name":"df","type":"DataFrame","shape":[3,2],"repr":"..."}]`,
+					},
+				},
+			},
+		}}
+		var variables []VariableSummary
+		if err := decodeUserExpression(reply, "result", &variables); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(variables) != 1 || variables[0].Name != "df" || len(variables[0].Shape) != 2 {
+			t.Fatalf("unexpected variables: %+v", variables)
+		}
+	})
+
+	t.Run("surfaces expression errors", func(t *testing.T) {
+		reply := jupyter_protocol.Message{Content: map[string]any{
+			"status": "ok",
+			"user_expressions": map[string]any{
+				"result": map[string]any{
+					"status": "error",
+					"ename":  "NameError",
+					"evalue": "name 'x' is not defined",
+				},
+			},
+		}}
+		var preview VariablePreview
+		err := decodeUserExpression(reply, "result", &preview)
+		if err == nil || err.Error() != "NameError: name 'x' is not defined" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("surfaces setup errors", func(t *testing.T) {
+		reply := jupyter_protocol.Message{Content: map[string]any{
+			"status": "error",
+			"ename":  "SyntaxError",
+			"evalue": "invalid syntax",
+		}}
+		var preview VariablePreview
+		if err := decodeUserExpression(reply, "result", &preview); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -20,10 +20,12 @@ import (
 )
 
 const (
-	maxKernelsPerLanguage = 3
 	heartbeatLaunchWait   = 3 * time.Second
 	javaLaunchWait        = 5 * time.Second
 	launchMaxAttempts     = 3
+	// executionInterruptGrace is how long a timed out execution has to honor
+	// the interrupt_request before the kernel is restarted.
+	executionInterruptGrace = 5 * time.Second
 )
 
 // portBindRaceErr is returned by launchOnce when the kernel process exited
@@ -38,6 +40,9 @@ const (
 	kernelInstanceShutdownEvent    = util.EventKernelInstanceShutdown
 	kernelInstanceLaunchErrorEvent = util.EventKernelInstanceLaunchError
 	kernelInstanceExitedEvent      = util.EventKernelInstanceExited
+
+	kernelInstanceExecutionTimeoutEvent = util.EventKernelInstanceExecutionTimeout
+	kernelInstanceReapedEvent           = util.EventKernelInstanceReaped
 )
 
 func init() {
@@ -45,12 +50,26 @@ func init() {
 	application.RegisterEvent[KernelShutdownEventData](util.EventKernelInstanceShutdown)
 	application.RegisterEvent[KernelLaunchErrorEventData](util.EventKernelInstanceLaunchError)
 	application.RegisterEvent[KernelExitedEventData](util.EventKernelInstanceExited)
+	application.RegisterEvent[KernelExecutionTimeoutEventData](util.EventKernelInstanceExecutionTimeout)
+	application.RegisterEvent[KernelReapedEventData](util.EventKernelInstanceReaped)
+	application.RegisterEvent[PythonEnvironmentProgressEventData](util.EventPythonEnvironmentProgress)
 }
 
 // ErrNoIdleKernelToEvict is returned by GetOrCreate when the per-language pool is full
 // and no instance is idle (no in-flight execute_request and empty queue).
 var ErrNoIdleKernelToEvict = errors.New("no idle kernel to evict")
 
+// KernelPoolFullError is returned by GetOrCreate when the per-language pool is
+// full and the language's eviction policy is "deny".
+type KernelPoolFullError struct {
+	Language   string
+	MaxKernels int
+}
+
+func (e KernelPoolFullError) Error() string {
+	return fmt.Sprintf("%s kernel pool is full (%d kernels)", e.Language, e.MaxKernels)
+}
+
 // LanguageNotSupportedError indicates the requested language has no kernel descriptor.
 type LanguageNotSupportedError struct{ Language string }
 
@@ -58,9 +77,32 @@ func (e LanguageNotSupportedError) Error() string {
 	return fmt.Sprintf("unsupported kernel language: %s", e.Language)
 }
 
-type langNoteKey struct {
-	language string
-	noteID   string
+// KernelEnvironmentMismatchError is returned by GetOrCreate when the Python
+// kernel serving the scope runs in a different environment than the one
+// requested, e.g. after a note's `pythonEnvironment` frontmatter changed. The
+// kernel must be shut down before the requested environment takes effect.
+type KernelEnvironmentMismatchError struct {
+	KernelInstanceID string
+	Running          string
+	Requested        string
+}
+
+func (e KernelEnvironmentMismatchError) Error() string {
+	return fmt.Sprintf("kernel %s runs in %s, not %s", e.KernelInstanceID, e.Running, e.Requested)
+}
+
+// checkEnvironment returns a KernelEnvironmentMismatchError if inst is a
+// launched Python kernel whose environment is not venvPath. Attached kernels
+// run wherever they were started, so they are never checked.
+func checkEnvironment(inst *KernelInstance, venvPath string) error {
+	if inst.language != "python" || inst.isAttached() || inst.venvPath == venvPath {
+		return nil
+	}
+	return KernelEnvironmentMismatchError{
+		KernelInstanceID: inst.id,
+		Running:          inst.venvPath,
+		Requested:        venvPath,
+	}
 }
 
 // KernelManager owns all live KernelInstance objects.
@@ -68,8 +110,8 @@ type KernelManager struct {
 	projectPath string
 	allKernels  config.AllKernels
 	mu         sync.Mutex
-	instances  map[string]*KernelInstance
-	byLangNote map[langNoteKey]*KernelInstance
+	instances   map[string]*KernelInstance
+	byLangScope map[langScopeKey]*KernelInstance
 }
 
 // New constructs a KernelManager. The caller should also call SetupKernelsDir(projectPath)
@@ -79,7 +121,7 @@ func New(projectPath string, allKernels config.AllKernels) *KernelManager {
 		projectPath: projectPath,
 		allKernels:  allKernels,
 		instances:   map[string]*KernelInstance{},
-		byLangNote:  map[langNoteKey]*KernelInstance{},
+		byLangScope: map[langScopeKey]*KernelInstance{},
 	}
 }
 
@@ -90,36 +132,60 @@ func (m *KernelManager) GetByID(id string) *KernelInstance {
 	return m.instances[id]
 }
 
-// List returns snapshots of all live instances.
+// Lookup returns the instance serving (language, scope), or nil.
+func (m *KernelManager) Lookup(language string, scope KernelScope) *KernelInstance {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.byLangScope[newLangScopeKey(language, scope)]
+}
+
+// List returns snapshots of all live instances. Snapshots sample process
+// usage, so they are taken outside the manager lock.
 func (m *KernelManager) List() []KernelInstanceSnapshot {
+	all := m.all()
+	out := make([]KernelInstanceSnapshot, 0, len(all))
+	for _, inst := range all {
+		out = append(out, inst.Snapshot())
+	}
+	return out
+}
+
+// all returns every live instance.
+func (m *KernelManager) all() []*KernelInstance {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	out := make([]KernelInstanceSnapshot, 0, len(m.instances))
+	out := make([]*KernelInstance, 0, len(m.instances))
 	for _, inst := range m.instances {
-		out = append(out, inst.Snapshot())
+		out = append(out, inst)
 	}
 	return out
 }
 
-// GetOrCreate returns an existing kernel for (language, noteID), or launches a new one.
-// If launching would exceed the per-language cap, an idle kernel is evicted (LRU). If
-// no idle kernel exists, ErrNoIdleKernelToEvict is returned.
-func (m *KernelManager) GetOrCreate(ctx context.Context, language, noteID, venvPath string) (*KernelInstance, error) {
+// GetOrCreate returns an existing kernel for (language, scope), or launches a new one.
+// An existing Python kernel running in another environment than venvPath is
+// returned together with a KernelEnvironmentMismatchError.
+// If launching would exceed the language's pool size, the eviction policy decides:
+// "lru" evicts the least recently used idle kernel (ErrNoIdleKernelToEvict if none
+// is idle) and "deny" returns a KernelPoolFullError.
+func (m *KernelManager) GetOrCreate(ctx context.Context, language string, scope KernelScope, venvPath string) (*KernelInstance, error) {
 	if !util.IsSupportedLanguage(language) {
 		return nil, LanguageNotSupportedError{Language: language}
 	}
+	key := newLangScopeKey(language, scope)
+	settings := m.languageSettings(language)
 
 	m.mu.Lock()
-	if inst, ok := m.byLangNote[langNoteKey{language: language, noteID: noteID}]; ok {
+	if inst, ok := m.byLangScope[key]; ok {
 		m.mu.Unlock()
-		return inst, nil
+		return inst, checkEnvironment(inst, venvPath)
 	}
 
 	// Count instances for this language and find idle candidates if at cap.
 	count := 0
 	idleCandidates := []*KernelInstance{}
 	for _, inst := range m.instances {
-		if inst.language != language {
+		// Attached kernels run elsewhere and do not count toward the pool.
+		if inst.language != language || inst.isAttached() {
 			continue
 		}
 		count++
@@ -127,7 +193,11 @@ func (m *KernelManager) GetOrCreate(ctx context.Context, language, noteID, venvP
 			idleCandidates = append(idleCandidates, inst)
 		}
 	}
-	if count >= maxKernelsPerLanguage {
+	if count >= settings.MaxKernels {
+		if settings.EvictionPolicy == config.KernelEvictionDeny {
+			m.mu.Unlock()
+			return nil, KernelPoolFullError{Language: language, MaxKernels: settings.MaxKernels}
+		}
 		if len(idleCandidates) == 0 {
 			m.mu.Unlock()
 			return nil, ErrNoIdleKernelToEvict
@@ -150,20 +220,20 @@ func (m *KernelManager) GetOrCreate(ctx context.Context, language, noteID, venvP
 	m.mu.Unlock()
 
 	// Launch a new instance outside the manager lock.
-	inst, err := m.launch(ctx, language, noteID, venvPath)
+	inst, err := m.launch(ctx, language, scope, venvPath)
 	if err != nil {
 		return nil, err
 	}
 
 	m.mu.Lock()
 	// Re-check that no concurrent goroutine raced ahead.
-	if existing, ok := m.byLangNote[langNoteKey{language: language, noteID: noteID}]; ok {
+	if existing, ok := m.byLangScope[key]; ok {
 		m.mu.Unlock()
 		_ = inst.shutdown("evicted", false)
-		return existing, nil
+		return existing, checkEnvironment(existing, venvPath)
 	}
 	m.instances[inst.id] = inst
-	m.byLangNote[langNoteKey{language: language, noteID: noteID}] = inst
+	m.byLangScope[key] = inst
 	m.mu.Unlock()
 
 	if app := application.Get(); app != nil {
@@ -188,6 +258,43 @@ func (m *KernelManager) Shutdown(id string, restart bool) error {
 	return inst.shutdown("user", restart)
 }
 
+// restart shuts inst down and launches a replacement for the same
+// (language, scope). It is a no-op if inst has already been removed.
+func (m *KernelManager) restart(inst *KernelInstance, reason string) error {
+	m.mu.Lock()
+	if m.instances[inst.id] != inst {
+		m.mu.Unlock()
+		return nil
+	}
+	m.removeFromMapsLocked(inst)
+	m.mu.Unlock()
+
+	if err := inst.shutdown(reason, true); err != nil {
+		log.Printf("kernel_manager: error during %s shutdown: %v", reason, err)
+	}
+	_, err := m.GetOrCreate(context.Background(), inst.language, inst.scope(), inst.venvPath)
+	return err
+}
+
+// languageSettings reads the per-language kernel policy from settings.json. A
+// read failure falls back to the defaults (no limits, default LRU pool) so a
+// broken settings file never prevents a kernel from launching.
+func (m *KernelManager) languageSettings(language string) config.KernelLanguageSettings {
+	return m.codeSettings().KernelSettingsFor(language)
+}
+
+// codeSettings reads the code settings of every language from settings.json,
+// falling back to the defaults on a read failure. It does file I/O, so it
+// must not be called with m.mu held.
+func (m *KernelManager) codeSettings() config.CodeProjectSettingsJson {
+	projectSettings, err := config.GetProjectSettings(m.projectPath)
+	if err != nil {
+		log.Printf("kernel_manager: failed to read kernel settings: %v", err)
+		return config.CodeProjectSettingsJson{}
+	}
+	return projectSettings.Code
+}
+
 // ShutdownAll shuts down every live instance. Used at app exit.
 func (m *KernelManager) ShutdownAll() {
 	m.mu.Lock()
@@ -196,7 +303,7 @@ func (m *KernelManager) ShutdownAll() {
 		all = append(all, inst)
 	}
 	m.instances = map[string]*KernelInstance{}
-	m.byLangNote = map[langNoteKey]*KernelInstance{}
+	m.byLangScope = map[langScopeKey]*KernelInstance{}
 	m.mu.Unlock()
 
 	wg := sync.WaitGroup{}
@@ -238,7 +345,7 @@ func (m *KernelManager) ShutdownAllByLanguage(language string) {
 // removeFromMapsLocked must be called with m.mu held.
 func (m *KernelManager) removeFromMapsLocked(inst *KernelInstance) {
 	delete(m.instances, inst.id)
-	delete(m.byLangNote, langNoteKey{language: inst.language, noteID: inst.scopeID})
+	delete(m.byLangScope, newLangScopeKey(inst.language, inst.scope()))
 }
 
 // isPortBindRaceMessage returns true when the given kernel-process stderr
@@ -252,10 +359,10 @@ func isPortBindRaceMessage(stderr string) bool {
 // launch spawns a kernel process. It retries up to launchMaxAttempts times
 // when the kernel exits during launch because of a port bind race; other
 // failures (bad argv, kernel module missing, etc.) surface immediately.
-func (m *KernelManager) launch(ctx context.Context, language, noteID, venvPath string) (*KernelInstance, error) {
+func (m *KernelManager) launch(ctx context.Context, language string, scope KernelScope, venvPath string) (*KernelInstance, error) {
 	var lastErr error
 	for attempt := 1; attempt <= launchMaxAttempts; attempt++ {
-		inst, err := m.launchOnce(ctx, language, noteID, venvPath)
+		inst, err := m.launchOnce(ctx, language, scope, venvPath)
 		if err == nil {
 			return inst, nil
 		}
@@ -269,7 +376,7 @@ func (m *KernelManager) launch(ctx context.Context, language, noteID, venvPath s
 }
 
 // launchOnce performs a single kernel launch attempt with a fresh port set.
-func (m *KernelManager) launchOnce(_ context.Context, language, noteID, venvPath string) (*KernelInstance, error) {
+func (m *KernelManager) launchOnce(_ context.Context, language string, scope KernelScope, venvPath string) (*KernelInstance, error) {
 	id := uuid.NewString()
 
 	ports, err := allocatePorts(5)
@@ -313,31 +420,41 @@ func (m *KernelManager) launchOnce(_ context.Context, language, noteID, venvPath
 		return nil, fmt.Errorf("kernel launch failed: %w", err)
 	}
 
+	settings := m.languageSettings(language)
+	releaseLimits := applyResourceLimits(cmd.Process.Pid, id, settings)
+
 	instCtx, cancel := context.WithCancel(context.Background())
 	heartbeat := &jupyter_protocol.KernelHeartbeatState{}
 
 	inst := &KernelInstance{
 		id:                 id,
 		language:           language,
-		scopeType:          "note",
-		scopeID:            noteID,
+		scopeType:          scope.Type,
+		scopeID:            scope.ID,
+		noteID:             scope.NoteID,
 		connectionFilePath: connFilePath,
 		connectionInfo:     connInfo,
+		origin:             KernelOriginLocal,
+		venvPath:           venvPath,
+		settings:           settings,
 		processHandle:      cmd,
 		processWait:        make(chan error, 1),
 		stderrBuf:          stderrBuf,
 		heartbeatState:     heartbeat,
 		activeExecutions:   map[string]struct{}{},
+		executionTimers:    map[string]*time.Timer{},
 		executionQueue:     []string{},
 		lastActivityAt:     time.Now(),
 		ctx:                instCtx,
 		cancel:             cancel,
 		manager:            m,
+		transcript:         newTranscript(m.projectPath, id, language),
 	}
 
 	// Wait for process exit asynchronously and emit lifecycle events.
 	go func() {
 		err := cmd.Wait()
+		releaseLimits()
 		inst.processWait <- err
 		close(inst.processWait)
 
@@ -362,7 +479,7 @@ func (m *KernelManager) launchOnce(_ context.Context, language, noteID, venvPath
 					Data: KernelLaunchErrorEventData{
 						ID:           inst.id,
 						Language:     inst.language,
-						NoteID:       inst.scopeID,
+						NoteID:       inst.noteID,
 						ErrorMessage: msg,
 					},
 				})
@@ -378,13 +495,15 @@ func (m *KernelManager) launchOnce(_ context.Context, language, noteID, venvPath
 		if _, stillRegistered := m.instances[inst.id]; stillRegistered {
 			m.removeFromMapsLocked(inst)
 			m.mu.Unlock()
+			inst.stopExecutionTimers()
+			inst.transcript.abandon()
 			if app := application.Get(); app != nil {
 				app.Event.EmitEvent(&application.CustomEvent{
 					Name: kernelInstanceShutdownEvent,
 					Data: KernelShutdownEventData{
 						ID:       inst.id,
 						Language: inst.language,
-						NoteID:   inst.scopeID,
+						NoteID:   inst.noteID,
 						Reason:   "exited",
 					},
 				})
@@ -396,22 +515,7 @@ func (m *KernelManager) launchOnce(_ context.Context, language, noteID, venvPath
 	}()
 
 	// Build sockets and start listener goroutines.
-	socketSet, err := sockets.CreateSockets(sockets.CreateParams{
-		ConnectionInfo: connInfo,
-		Ctx:            instCtx,
-		Cancel:         cancel,
-		HeartbeatState: heartbeat,
-		InstanceID:     id,
-		NoteID:         noteID,
-		OnExecuteStatus: func(status, parentMsgID string) {
-			switch status {
-			case "busy":
-				inst.trackExecutionStart(parentMsgID)
-			case "idle":
-				inst.trackExecutionEnd(parentMsgID)
-			}
-		},
-	})
+	socketSet, err := sockets.CreateSockets(inst.socketParams())
 	if err != nil {
 		cancel()
 		if cmd.Process != nil {
@@ -474,7 +578,8 @@ func (m *KernelManager) argvForLanguage(language string) ([]string, error) {
 	}
 }
 
-// Event payloads emitted by the manager.
+// Event payloads emitted by the manager. NoteID is the note that launched the
+// kernel; for folder- and project-scoped kernels other notes may share it.
 type KernelShutdownEventData struct {
 	ID       string `json:"id"`
 	Language string `json:"language"`
@@ -493,3 +598,15 @@ type KernelExitedEventData struct {
 	ID       string `json:"id"`
 	ExitCode int    `json:"exitCode"`
 }
+
+// KernelExecutionTimeoutEventData is emitted when an execution exceeds the
+// language's timeout. Action is "interrupted" when the interrupt_request is
+// sent and "restarted" when the kernel ignored it and was restarted.
+type KernelExecutionTimeoutEventData struct {
+	ID             string `json:"id"`
+	Language       string `json:"language"`
+	NoteID         string `json:"noteId"`
+	MessageID      string `json:"messageId"`
+	TimeoutSeconds int    `json:"timeoutSeconds"`
+	Action         string `json:"action"`
+}
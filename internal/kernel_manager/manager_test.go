@@ -0,0 +1,43 @@
+package kernel_manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+)
+
+func TestGetOrCreateEnvironmentMismatch(t *testing.T) {
+	m := New(t.TempDir(), config.AllKernels{})
+	inst := newTestInstance("a", "python", time.Now(), true)
+	inst.venvPath = "/envs/project"
+	m.instances[inst.id] = inst
+	m.byLangScope[newLangScopeKey(inst.language, inst.scope())] = inst
+
+	t.Run("same environment", func(t *testing.T) {
+		got, err := m.GetOrCreate(context.Background(), "python", inst.scope(), "/envs/project")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != inst {
+			t.Fatal("expected the running kernel")
+		}
+	})
+
+	t.Run("different environment", func(t *testing.T) {
+		got, err := m.GetOrCreate(context.Background(), "python", inst.scope(), "/envs/note")
+		var mismatch KernelEnvironmentMismatchError
+		if !errors.As(err, &mismatch) {
+			t.Fatalf("expected KernelEnvironmentMismatchError, got %v", err)
+		}
+		if got != inst {
+			t.Fatal("expected the running kernel to be returned with the error")
+		}
+		want := KernelEnvironmentMismatchError{KernelInstanceID: "a", Running: "/envs/project", Requested: "/envs/note"}
+		if mismatch != want {
+			t.Fatalf("got %+v, want %+v", mismatch, want)
+		}
+	})
+}
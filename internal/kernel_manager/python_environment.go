@@ -0,0 +1,98 @@
+package kernel_manager
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Stages reported in PythonEnvironmentProgressEventData.Stage.
+const (
+	PythonEnvironmentStageCreate  = "create"
+	PythonEnvironmentStageInstall = "install"
+	PythonEnvironmentStageDone    = "done"
+	PythonEnvironmentStageError   = "error"
+)
+
+// PythonEnvironmentProgressEventData is one line of progress from
+// CreatePythonEnvironment, keyed by the environment's path. The final event
+// has stage "done" or "error".
+type PythonEnvironmentProgressEventData struct {
+	Path    string `json:"path"`
+	Stage   string `json:"stage"`
+	Message string `json:"message"`
+}
+
+// setupStep is one command run while setting up an environment.
+type setupStep struct {
+	stage string
+	argv  []string
+}
+
+// pythonEnvironmentSteps returns the commands that create a virtual environment
+// at path and install ipykernel into it. uv is used when uvPath is set since it
+// is much faster than venv and pip.
+func pythonEnvironmentSteps(path, uvPath string) []setupStep {
+	python := filepath.Join(path, "bin", "python3")
+	if uvPath != "" {
+		return []setupStep{
+			{stage: PythonEnvironmentStageCreate, argv: []string{uvPath, "venv", path}},
+			{stage: PythonEnvironmentStageInstall, argv: []string{uvPath, "pip", "install", "--python", python, "ipykernel"}},
+		}
+	}
+	return []setupStep{
+		{stage: PythonEnvironmentStageCreate, argv: []string{"python3", "-m", "venv", path}},
+		{stage: PythonEnvironmentStageInstall, argv: []string{python, "-m", "pip", "install", "ipykernel"}},
+	}
+}
+
+// CreatePythonEnvironment creates a virtual environment at path and installs
+// ipykernel into it so it can run kernels straight away. Each line the tools
+// print is passed to progress with the stage that produced it.
+func CreatePythonEnvironment(ctx context.Context, path string, progress func(stage, line string)) error {
+	uvPath, err := exec.LookPath("uv")
+	if err != nil {
+		uvPath = ""
+	}
+	for _, step := range pythonEnvironmentSteps(path, uvPath) {
+		progress(step.stage, strings.Join(step.argv, " "))
+		err := runStreaming(ctx, "", step.argv, func(line string) {
+			progress(step.stage, line)
+		})
+		if err != nil {
+			return fmt.Errorf("%s failed: %w", filepath.Base(step.argv[0]), err)
+		}
+	}
+	return nil
+}
+
+// runStreaming runs argv in dir, calling onLine for each line written to
+// stdout or stderr, and returns once the command exits.
+func runStreaming(ctx context.Context, dir string, argv []string, onLine func(string)) error {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	scanned := make(chan struct{})
+	go func() {
+		defer close(scanned)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			onLine(scanner.Text())
+		}
+		// Keep draining so the command never blocks on a full pipe.
+		_, _ = io.Copy(io.Discard, reader)
+	}()
+
+	err := cmd.Run()
+	_ = writer.Close()
+	<-scanned
+	return err
+}
@@ -0,0 +1,50 @@
+package kernel_manager
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPythonEnvironmentSteps(t *testing.T) {
+	t.Run("uses uv when available", func(t *testing.T) {
+		steps := pythonEnvironmentSteps("/p/env", "/bin/uv")
+		want := []setupStep{
+			{stage: PythonEnvironmentStageCreate, argv: []string{"/bin/uv", "venv", "/p/env"}},
+			{stage: PythonEnvironmentStageInstall, argv: []string{"/bin/uv", "pip", "install", "--python", "/p/env/bin/python3", "ipykernel"}},
+		}
+		if !reflect.DeepEqual(steps, want) {
+			t.Fatalf("got %+v, want %+v", steps, want)
+		}
+	})
+
+	t.Run("falls back to venv and pip", func(t *testing.T) {
+		steps := pythonEnvironmentSteps("/p/env", "")
+		want := []setupStep{
+			{stage: PythonEnvironmentStageCreate, argv: []string{"python3", "-m", "venv", "/p/env"}},
+			{stage: PythonEnvironmentStageInstall, argv: []string{"/p/env/bin/python3", "-m", "pip", "install", "ipykernel"}},
+		}
+		if !reflect.DeepEqual(steps, want) {
+			t.Fatalf("got %+v, want %+v", steps, want)
+		}
+	})
+}
+
+func TestRunStreaming(t *testing.T) {
+	lines := []string{}
+	err := runStreaming(context.Background(), t.TempDir(), []string{"sh", "-c", "echo out; echo err >&2"}, func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	sort.Strings(lines)
+	if !reflect.DeepEqual(lines, []string{"err", "out"}) {
+		t.Fatalf("unexpected lines: %v", lines)
+	}
+
+	if err := runStreaming(context.Background(), "", []string{"sh", "-c", "exit 3"}, func(string) {}); err == nil {
+		t.Fatal("expected a failing command to return an error")
+	}
+}
@@ -0,0 +1,94 @@
+package kernel_manager
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// idleReaperInterval is how often the reaper looks for idle kernels.
+const idleReaperInterval = 30 * time.Second
+
+// KernelReapedEventData is emitted when the idle reaper shuts a kernel down,
+// so the UI can explain why the kernel disappeared.
+type KernelReapedEventData struct {
+	ID                 string `json:"id"`
+	Language           string `json:"language"`
+	NoteID             string `json:"noteId"`
+	IdleTimeoutMinutes int    `json:"idleTimeoutMinutes"`
+	LastActivityAt     int64  `json:"lastActivityAt"`
+}
+
+// StartIdleReaper launches the background goroutine that shuts down kernels
+// with no activity for longer than their language's IdleTimeoutMinutes. The
+// goroutine exits when ctx is cancelled.
+func (m *KernelManager) StartIdleReaper(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(idleReaperInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				m.reapIdle(now)
+			}
+		}
+	}()
+}
+
+// reapIdle shuts down every idle kernel whose last activity is older than its
+// language's idle timeout and returns how many were reaped.
+func (m *KernelManager) reapIdle(now time.Time) int {
+	// Read settings.json before taking the lock so file I/O never blocks
+	// kernel launches and lookups.
+	codeSettings := m.codeSettings()
+
+	m.mu.Lock()
+	victims := []*KernelInstance{}
+	for _, inst := range m.instances {
+		if isReapable(inst, codeSettings.KernelSettingsFor(inst.language), now) {
+			victims = append(victims, inst)
+		}
+	}
+	for _, inst := range victims {
+		m.removeFromMapsLocked(inst)
+	}
+	m.mu.Unlock()
+
+	for _, inst := range victims {
+		timeout := codeSettings.KernelSettingsFor(inst.language).IdleTimeoutMinutes
+		lastActivity := inst.LastActivity()
+		log.Printf("kernel_manager: reaping %s kernel %s, idle since %s", inst.language, inst.id, lastActivity.Format(time.RFC3339))
+		if err := inst.shutdown("idle_timeout", false); err != nil {
+			log.Printf("kernel_manager: error during idle shutdown: %v", err)
+		}
+		if app := application.Get(); app != nil {
+			app.Event.EmitEvent(&application.CustomEvent{
+				Name: kernelInstanceReapedEvent,
+				Data: KernelReapedEventData{
+					ID:                 inst.id,
+					Language:           inst.language,
+					NoteID:             inst.noteID,
+					IdleTimeoutMinutes: timeout,
+					LastActivityAt:     lastActivity.UnixMilli(),
+				},
+			})
+		}
+	}
+	return len(victims)
+}
+
+// isReapable reports whether inst has been idle past the language's timeout.
+// A timeout of zero disables reaping for the language. Attached kernels are
+// never reaped.
+func isReapable(inst *KernelInstance, settings config.KernelLanguageSettings, now time.Time) bool {
+	if settings.IdleTimeoutMinutes <= 0 || inst.isAttached() || !inst.IsIdle() {
+		return false
+	}
+	timeout := time.Duration(settings.IdleTimeoutMinutes) * time.Minute
+	return now.Sub(inst.LastActivity()) >= timeout
+}
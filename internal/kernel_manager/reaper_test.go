@@ -0,0 +1,98 @@
+package kernel_manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+func newTestInstance(id, language string, lastActivity time.Time, alive bool) *KernelInstance {
+	heartbeat := &jupyter_protocol.KernelHeartbeatState{}
+	heartbeat.UpdateHeartbeatStatus(alive)
+	return &KernelInstance{
+		id:               id,
+		language:         language,
+		scopeType:        config.KernelScopeNote,
+		scopeID:          id + ".md",
+		noteID:           id + ".md",
+		heartbeatState:   heartbeat,
+		activeExecutions: map[string]struct{}{},
+		lastActivityAt:   lastActivity,
+	}
+}
+
+func TestIsReapable(t *testing.T) {
+	now := time.Now()
+	settings := config.KernelLanguageSettings{IdleTimeoutMinutes: 10}
+
+	t.Run("idle past the timeout", func(t *testing.T) {
+		inst := newTestInstance("a", "python", now.Add(-11*time.Minute), true)
+		if !isReapable(inst, settings, now) {
+			t.Fatal("expected kernel to be reapable")
+		}
+	})
+
+	t.Run("recently active", func(t *testing.T) {
+		inst := newTestInstance("a", "python", now.Add(-time.Minute), true)
+		if isReapable(inst, settings, now) {
+			t.Fatal("recently active kernel should not be reaped")
+		}
+	})
+
+	t.Run("busy kernel", func(t *testing.T) {
+		inst := newTestInstance("a", "python", now.Add(-time.Hour), true)
+		inst.activeExecutions["block|exec|t"] = struct{}{}
+		if isReapable(inst, settings, now) {
+			t.Fatal("busy kernel should not be reaped")
+		}
+	})
+
+	t.Run("timeout disabled", func(t *testing.T) {
+		inst := newTestInstance("a", "python", now.Add(-24*time.Hour), true)
+		if isReapable(inst, config.KernelLanguageSettings{}, now) {
+			t.Fatal("kernel should not be reaped when the timeout is disabled")
+		}
+	})
+}
+
+func TestReapIdle(t *testing.T) {
+	projectPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectPath, "settings"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	settings := config.ProjectSettingsJson{
+		Code: config.CodeProjectSettingsJson{
+			KernelSettings: map[string]config.KernelLanguageSettings{
+				"python": {IdleTimeoutMinutes: 5},
+			},
+		},
+	}
+	if err := util.WriteJsonToPath(filepath.Join(projectPath, "settings", "settings.json"), settings); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	m := New(projectPath, config.AllKernels{})
+	stale := newTestInstance("stale", "python", now.Add(-time.Hour), true)
+	fresh := newTestInstance("fresh", "python", now, true)
+	noTimeout := newTestInstance("go-kernel", "go", now.Add(-time.Hour), true)
+	for _, inst := range []*KernelInstance{stale, fresh, noTimeout} {
+		m.instances[inst.id] = inst
+		m.byLangScope[newLangScopeKey(inst.language, inst.scope())] = inst
+	}
+
+	if reaped := m.reapIdle(now); reaped != 1 {
+		t.Fatalf("expected 1 kernel reaped, got %d", reaped)
+	}
+	if m.GetByID("stale") != nil {
+		t.Error("stale kernel should have been removed")
+	}
+	if m.GetByID("fresh") == nil || m.GetByID("go-kernel") == nil {
+		t.Error("fresh and timeout-less kernels should remain")
+	}
+}
@@ -0,0 +1,259 @@
+package kernel_manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+// pythonMissingRequirementsScript prints the requirements from argv[1] (a JSON
+// list) that are not installed in the running interpreter. Version specifiers
+// are only checked when the packaging library is available.
+const pythonMissingRequirementsScript = `
+import json, re, sys
+from importlib import metadata
+try:
+    from packaging.requirements import Requirement
+except ImportError:
+    Requirement = None
+missing = []
+for spec in json.loads(sys.argv[1]):
+    try:
+        name = re.match(r"[A-Za-z0-9][A-Za-z0-9._-]*", spec).group(0)
+        version = metadata.version(name)
+        if Requirement is not None:
+            specifier = Requirement(spec).specifier
+            if specifier and not specifier.contains(version, prereleases=True):
+                missing.append(spec)
+    except Exception:
+        missing.append(spec)
+print(json.dumps(missing))
+`
+
+// RequirementsEnvironment is where a note's requirements are checked and
+// installed: the Python environment for python and the project's code folder,
+// which holds node_modules, for javascript.
+type RequirementsEnvironment struct {
+	PythonEnvironment string
+	CodeFolder        string
+}
+
+var (
+	// requirementPattern allows package names with version specifiers, extras
+	// and npm scopes, e.g. "pandas[excel]>=2,<3" or "@std/path@^1".
+	requirementPattern = regexp.MustCompile(`^[A-Za-z0-9@][A-Za-z0-9._~/@+:=<>!,\[\]^*-]*$`)
+	// goRequirementPattern allows module paths with an optional version. It is
+	// stricter because gonb runs `go get` through a shell.
+	goRequirementPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._~/@+-]*$`)
+)
+
+// ValidateRequirements rejects requirements the package managers would read as
+// options or a shell would interpret, so a shared note cannot smuggle in flags
+// such as --index-url or extra commands.
+func ValidateRequirements(language string, requires []string) error {
+	pattern := requirementPattern
+	if language == "go" {
+		pattern = goRequirementPattern
+	}
+	for _, requirement := range requires {
+		if !pattern.MatchString(requirement) {
+			return fmt.Errorf("invalid requirement %q", requirement)
+		}
+	}
+	return nil
+}
+
+// MissingRequirements returns the requirements that are not installed in env.
+// Go requirements are fetched into each gonb kernel's own module, so all of
+// them are reported and the caller narrows them down with
+// KernelInstance.UnfetchedRequirements.
+func MissingRequirements(ctx context.Context, language string, env RequirementsEnvironment, requires []string) ([]string, error) {
+	if err := ValidateRequirements(language, requires); err != nil {
+		return nil, err
+	}
+	switch language {
+	case "python":
+		python := util.PythonInterpreter(env.PythonEnvironment)
+		if python == "" {
+			return nil, fmt.Errorf("python interpreter not found in %s", env.PythonEnvironment)
+		}
+		encoded, err := json.Marshal(requires)
+		if err != nil {
+			return nil, err
+		}
+		out, err := exec.CommandContext(ctx, python, "-c", pythonMissingRequirementsScript, string(encoded)).Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check installed packages: %w", err)
+		}
+		missing := []string{}
+		if err := json.Unmarshal(out, &missing); err != nil {
+			return nil, fmt.Errorf("failed to check installed packages: %w", err)
+		}
+		return missing, nil
+	case "javascript":
+		missing := []string{}
+		for _, requirement := range requires {
+			packageJSON := filepath.Join(env.CodeFolder, "node_modules", npmPackageName(requirement), "package.json")
+			if exists, _ := util.FileOrFolderExists(packageJSON); !exists {
+				missing = append(missing, requirement)
+			}
+		}
+		return missing, nil
+	case "go":
+		return requires, nil
+	}
+	return nil, LanguageNotSupportedError{Language: language}
+}
+
+// InstallRequirements installs requires into env, passing each line the
+// package manager prints to onLine. Python uses uv when it is on PATH and pip
+// otherwise; javascript uses npm in the code folder, where the Deno kernel
+// resolves node_modules from.
+func InstallRequirements(ctx context.Context, language string, env RequirementsEnvironment, requires []string, onLine func(string)) error {
+	if err := ValidateRequirements(language, requires); err != nil {
+		return err
+	}
+	argv, dir, err := installCommand(language, env, requires)
+	if err != nil {
+		return err
+	}
+	onLine("$ " + strings.Join(argv, " "))
+	if err := runStreaming(ctx, dir, argv, onLine); err != nil {
+		return fmt.Errorf("%s failed: %w", filepath.Base(argv[0]), err)
+	}
+	return nil
+}
+
+func installCommand(language string, env RequirementsEnvironment, requires []string) ([]string, string, error) {
+	switch language {
+	case "python":
+		python := util.PythonInterpreter(env.PythonEnvironment)
+		if python == "" {
+			return nil, "", fmt.Errorf("python interpreter not found in %s", env.PythonEnvironment)
+		}
+		if uvPath, err := exec.LookPath("uv"); err == nil && util.IsVirtualEnv(env.PythonEnvironment) {
+			return append([]string{uvPath, "pip", "install", "--python", python}, requires...), "", nil
+		}
+		return append([]string{python, "-m", "pip", "install"}, requires...), "", nil
+	case "javascript":
+		argv := []string{"npm", "install"}
+		for _, requirement := range requires {
+			argv = append(argv, strings.TrimPrefix(requirement, "npm:"))
+		}
+		return argv, env.CodeFolder, nil
+	}
+	return nil, "", fmt.Errorf("%s requirements are not installed with a package manager", language)
+}
+
+// UnfetchedRequirements returns the requires that have not been fetched into
+// this kernel with GoGetCode.
+func (i *KernelInstance) UnfetchedRequirements(requires []string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	unfetched := []string{}
+	for _, requirement := range requires {
+		if !i.fetchedRequires.Has(requirement) {
+			unfetched = append(unfetched, requirement)
+		}
+	}
+	return unfetched
+}
+
+// markRequirementsFetched records that requires were fetched into this kernel.
+func (i *KernelInstance) markRequirementsFetched(requires []string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.fetchedRequires == nil {
+		i.fetchedRequires = util.Set[string]{}
+	}
+	for _, requirement := range requires {
+		i.fetchedRequires.Add(requirement)
+	}
+}
+
+// FetchRequirements runs `go get` for requires in this gonb kernel as
+// codeBlockID's execution, so its output streams to the block. requires are
+// only marked fetched once the execute_reply reports success.
+func (i *KernelInstance) FetchRequirements(noteID, codeBlockID, executionID string, requires []string) error {
+	messageID := fmt.Sprintf("%s|%s", codeBlockID, executionID)
+	i.mu.Lock()
+	if i.pendingFetches == nil {
+		i.pendingFetches = map[string][]string{}
+	}
+	i.pendingFetches[messageID] = requires
+	i.mu.Unlock()
+
+	if err := i.SendExecute(noteID, codeBlockID, executionID, GoGetCode(requires)); err != nil {
+		i.mu.Lock()
+		delete(i.pendingFetches, messageID)
+		i.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// handleFetchReply marks the requirements of a FetchRequirements execution
+// fetched when its execute_reply succeeded. Replies to other executions are
+// ignored.
+func (i *KernelInstance) handleFetchReply(parentMsgID string, msg jupyter_protocol.Message) {
+	// SendExecuteRequest appends "|{timestamp}" to the id we registered.
+	messageID := parentMsgID
+	if cut := strings.LastIndex(parentMsgID, "|"); cut != -1 {
+		messageID = parentMsgID[:cut]
+	}
+
+	i.mu.Lock()
+	requires, ok := i.pendingFetches[messageID]
+	delete(i.pendingFetches, messageID)
+	i.mu.Unlock()
+	if !ok {
+		return
+	}
+	if status, _ := msg.Content["status"].(string); status == "ok" {
+		i.markRequirementsFetched(requires)
+	}
+}
+
+// MarkRequirementsChecked records that noteID's requirements were checked
+// for this kernel and reports whether they had not been yet. A folder or
+// project kernel is shared, so each note that joins it is checked once, not
+// only the note that started it.
+func (i *KernelInstance) MarkRequirementsChecked(noteID string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.checkedNotes == nil {
+		i.checkedNotes = util.Set[string]{}
+	}
+	if i.checkedNotes.Has(noteID) {
+		return false
+	}
+	i.checkedNotes.Add(noteID)
+	return true
+}
+
+// GoGetCode returns the gonb cell that fetches requires into the kernel's
+// module. gonb runs lines starting with "!*" as shell commands in that module.
+func GoGetCode(requires []string) string {
+	return "!*go get " + strings.Join(requires, " ")
+}
+
+// npmPackageName strips the version range from an npm requirement such as
+// "lodash@^4" or "@std/path@1".
+func npmPackageName(requirement string) string {
+	requirement = strings.TrimPrefix(requirement, "npm:")
+	if strings.HasPrefix(requirement, "@") {
+		if at := strings.Index(requirement[1:], "@"); at != -1 {
+			return requirement[:at+1]
+		}
+		return requirement
+	}
+	name, _, _ := strings.Cut(requirement, "@")
+	return name
+}
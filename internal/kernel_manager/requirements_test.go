@@ -0,0 +1,166 @@
+package kernel_manager
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+)
+
+func TestValidateRequirements(t *testing.T) {
+	valid := map[string][]string{
+		"python":     {"pandas>=2,<3", "httpx", "requests[socks]==2.31", "numpy!=1.0"},
+		"javascript": {"lodash@^4", "@std/path@1", "npm:chalk@5"},
+		"go":         {"github.com/google/uuid@v1.6.0", "golang.org/x/exp"},
+	}
+	for language, requires := range valid {
+		if err := ValidateRequirements(language, requires); err != nil {
+			t.Errorf("%s: unexpected error: %v", language, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"python":     "--index-url=https://example.com",
+		"javascript": "lodash; rm -rf /",
+		"go":         "github.com/a/b>out",
+	}
+	for language, requirement := range invalid {
+		if err := ValidateRequirements(language, []string{requirement}); err == nil {
+			t.Errorf("%s: expected %q to be rejected", language, requirement)
+		}
+	}
+}
+
+func TestNpmPackageName(t *testing.T) {
+	for requirement, want := range map[string]string{
+		"lodash":       "lodash",
+		"lodash@^4":    "lodash",
+		"@std/path":    "@std/path",
+		"@std/path@1":  "@std/path",
+		"npm:chalk@5":  "chalk",
+		"npm:@a/b@1.0": "@a/b",
+	} {
+		if got := npmPackageName(requirement); got != want {
+			t.Errorf("npmPackageName(%q) = %q, want %q", requirement, got, want)
+		}
+	}
+}
+
+func TestMissingRequirements(t *testing.T) {
+	t.Run("javascript checks node_modules in the code folder", func(t *testing.T) {
+		codeFolder := t.TempDir()
+		installed := filepath.Join(codeFolder, "node_modules", "@std", "path")
+		if err := os.MkdirAll(installed, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(installed, "package.json"), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		missing, err := MissingRequirements(context.Background(), "javascript", RequirementsEnvironment{CodeFolder: codeFolder}, []string{"@std/path@1", "lodash"})
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		if !reflect.DeepEqual(missing, []string{"lodash"}) {
+			t.Fatalf("unexpected missing requirements: %v", missing)
+		}
+	})
+
+	t.Run("python asks the environment's interpreter", func(t *testing.T) {
+		python, err := exec.LookPath("python3")
+		if err != nil {
+			t.Skip("python3 not available")
+		}
+		env := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(env, "bin"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(python, filepath.Join(env, "bin", "python3")); err != nil {
+			t.Fatal(err)
+		}
+
+		missing, err := MissingRequirements(context.Background(), "python", RequirementsEnvironment{PythonEnvironment: env}, []string{"bytebook-not-a-real-package>=1"})
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		if !reflect.DeepEqual(missing, []string{"bytebook-not-a-real-package>=1"}) {
+			t.Fatalf("unexpected missing requirements: %v", missing)
+		}
+	})
+
+	t.Run("go requirements are tracked per kernel", func(t *testing.T) {
+		requires := []string{"github.com/google/uuid", "golang.org/x/exp"}
+		missing, err := MissingRequirements(context.Background(), "go", RequirementsEnvironment{}, requires)
+		if err != nil || !reflect.DeepEqual(missing, requires) {
+			t.Fatalf("unexpected result: %v, %v", missing, err)
+		}
+
+		inst := &KernelInstance{}
+		inst.markRequirementsFetched(requires[:1])
+		if got := inst.UnfetchedRequirements(requires); !reflect.DeepEqual(got, requires[1:]) {
+			t.Fatalf("unexpected unfetched requirements: %v", got)
+		}
+		if got := GoGetCode(requires); got != "!*go get github.com/google/uuid golang.org/x/exp" {
+			t.Fatalf("unexpected go get cell: %q", got)
+		}
+	})
+
+	t.Run("rejects unsupported languages", func(t *testing.T) {
+		if _, err := MissingRequirements(context.Background(), "java", RequirementsEnvironment{}, []string{"junit"}); err == nil {
+			t.Fatal("expected an error for java")
+		}
+	})
+}
+
+func TestMarkRequirementsChecked(t *testing.T) {
+	inst := newTestInstance("a", "python", time.Now(), true)
+
+	if !inst.MarkRequirementsChecked("folder/one.md") {
+		t.Fatal("the first note on a kernel should be checked")
+	}
+	if inst.MarkRequirementsChecked("folder/one.md") {
+		t.Fatal("a note should only be checked once per kernel")
+	}
+	if !inst.MarkRequirementsChecked("folder/two.md") {
+		t.Fatal("a note joining a shared kernel should be checked")
+	}
+}
+
+func TestHandleFetchReply(t *testing.T) {
+	requires := []string{"github.com/google/uuid"}
+
+	t.Run("marks requirements fetched after an ok reply", func(t *testing.T) {
+		inst := newTestInstance("a", "go", time.Now(), true)
+		inst.pendingFetches = map[string][]string{"block|exec": requires}
+
+		ok := jupyter_protocol.Message{Content: map[string]any{"status": "ok"}}
+		if inst.handleShellReply("block|exec|2026-01-01T00:00:00Z", ok) {
+			t.Fatal("the reply should still reach the frontend")
+		}
+		if got := inst.UnfetchedRequirements(requires); len(got) != 0 {
+			t.Fatalf("unexpected unfetched requirements: %v", got)
+		}
+		if len(inst.pendingFetches) != 0 {
+			t.Fatalf("pending fetch was not cleared: %v", inst.pendingFetches)
+		}
+	})
+
+	t.Run("leaves requirements unfetched after a failed reply", func(t *testing.T) {
+		inst := newTestInstance("a", "go", time.Now(), true)
+		inst.pendingFetches = map[string][]string{"block|exec": requires}
+
+		failed := jupyter_protocol.Message{Content: map[string]any{"status": "error"}}
+		inst.handleShellReply("block|exec|2026-01-01T00:00:00Z", failed)
+		if got := inst.UnfetchedRequirements(requires); !reflect.DeepEqual(got, requires) {
+			t.Fatalf("unexpected unfetched requirements: %v", got)
+		}
+		if len(inst.pendingFetches) != 0 {
+			t.Fatalf("pending fetch was not cleared: %v", inst.pendingFetches)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package kernel_manager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProcessUsage is a point-in-time view of a kernel process's resource usage.
+type ProcessUsage struct {
+	RSSBytes   int64
+	CPUPercent float64
+}
+
+// usageSampler turns cumulative CPU time readings into a CPU percentage by
+// diffing against the previous sample. The first sample always reports 0%.
+type usageSampler struct {
+	mu          sync.Mutex
+	lastCPUTime time.Duration
+	lastSampled time.Time
+}
+
+// sample reads the current RSS and CPU usage of pid.
+func (s *usageSampler) sample(pid int) (ProcessUsage, error) {
+	rss, cpuTime, err := readProcessStats(pid)
+	if err != nil {
+		return ProcessUsage{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	usage := ProcessUsage{RSSBytes: rss}
+	if !s.lastSampled.IsZero() {
+		wall := now.Sub(s.lastSampled)
+		if wall > 0 && cpuTime >= s.lastCPUTime {
+			usage.CPUPercent = float64(cpuTime-s.lastCPUTime) / float64(wall) * 100
+		}
+	}
+	s.lastCPUTime = cpuTime
+	s.lastSampled = now
+	return usage, nil
+}
+
+// parseProcStat extracts utime+stime (in clock ticks) and rss (in pages) from
+// the contents of /proc/<pid>/stat. The comm field is parenthesised and may
+// itself contain spaces or parentheses, so parsing starts after the last ')'.
+func parseProcStat(contents string) (cpuTicks int64, rssPages int64, err error) {
+	end := strings.LastIndexByte(contents, ')')
+	if end == -1 {
+		return 0, 0, fmt.Errorf("malformed stat: missing comm terminator")
+	}
+	// Fields after comm start at field 3 (state); utime/stime are fields 14/15
+	// and rss is field 24, i.e. indexes 11, 12 and 21 of this slice.
+	fields := strings.Fields(contents[end+1:])
+	if len(fields) < 22 {
+		return 0, 0, fmt.Errorf("malformed stat: expected at least 22 fields after comm, got %d", len(fields))
+	}
+	utime, err := strconv.ParseInt(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed utime: %w", err)
+	}
+	stime, err := strconv.ParseInt(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed stime: %w", err)
+	}
+	rssPages, err = strconv.ParseInt(fields[21], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed rss: %w", err)
+	}
+	return utime + stime, rssPages, nil
+}
+
+// parsePsCPUTime parses the cumulative CPU time printed by `ps -o time=`,
+// which is formatted as [[dd-]hh:]mm:ss[.ss] depending on the platform.
+func parsePsCPUTime(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty cpu time")
+	}
+
+	var days int64
+	if dayPart, rest, ok := strings.Cut(value, "-"); ok {
+		d, err := strconv.ParseInt(dayPart, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed cpu time %q: %w", value, err)
+		}
+		days = d
+		value = rest
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("malformed cpu time %q", value)
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed cpu time %q: %w", value, err)
+	}
+	total := time.Duration(seconds * float64(time.Second))
+	multiplier := time.Minute
+	for idx := len(parts) - 2; idx >= 0; idx-- {
+		n, err := strconv.ParseInt(parts[idx], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed cpu time %q: %w", value, err)
+		}
+		total += time.Duration(n) * multiplier
+		multiplier *= 60
+	}
+	total += time.Duration(days) * 24 * time.Hour
+	return total, nil
+}
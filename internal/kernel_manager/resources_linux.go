@@ -0,0 +1,124 @@
+//go:build linux
+
+package kernel_manager
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+	// clockTicksPerSecond is USER_HZ, which is 100 on every mainstream Linux build.
+	clockTicksPerSecond = 100
+	// cgroupCPUPeriod is the cpu.max period in microseconds.
+	cgroupCPUPeriod = 100000
+)
+
+// readProcessStats reads RSS and cumulative CPU time for pid from /proc.
+func readProcessStats(pid int) (int64, time.Duration, error) {
+	contents, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	cpuTicks, rssPages, err := parseProcStat(string(contents))
+	if err != nil {
+		return 0, 0, err
+	}
+	cpuTime := time.Duration(cpuTicks) * time.Second / clockTicksPerSecond
+	return rssPages * int64(os.Getpagesize()), cpuTime, nil
+}
+
+// applyResourceLimits constrains a freshly started kernel process with a
+// cgroup v2 child group (memory.max and cpu.max) below the app's own cgroup.
+// When cgroups are not delegated to us the limits are skipped and logged:
+// RLIMIT_AS caps virtual address space rather than resident memory, so
+// runtimes that reserve large heaps up front, such as the JVM, Go and V8,
+// would fail to start well below the intended limit. The returned func
+// removes the cgroup once the process has exited.
+func applyResourceLimits(pid int, instanceID string, limits config.KernelLanguageSettings) func() {
+	noop := func() {}
+	if limits.MemoryLimitMB == 0 && limits.CPULimitPercent == 0 {
+		return noop
+	}
+
+	cgroupDir, err := createKernelCgroup(pid, instanceID, limits)
+	if err != nil {
+		log.Printf("kernel_manager: cgroup v2 is unavailable, skipping resource limits for kernel %s: %v", instanceID, err)
+		return noop
+	}
+	return func() {
+		if err := os.Remove(cgroupDir); err != nil && !os.IsNotExist(err) {
+			log.Printf("kernel_manager: failed to remove cgroup %s: %v", cgroupDir, err)
+		}
+	}
+}
+
+// createKernelCgroup creates a child cgroup for the kernel, writes its limits,
+// and moves pid into it.
+func createKernelCgroup(pid int, instanceID string, limits config.KernelLanguageSettings) (string, error) {
+	selfCgroup, err := currentCgroupPath()
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Join(cgroupRoot, selfCgroup)
+
+	// Controllers must be enabled in the parent's subtree before the child
+	// exposes memory.max / cpu.max. This fails harmlessly when they already are.
+	_ = os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory +cpu"), 0644)
+
+	dir := filepath.Join(parent, "bytebook-kernel-"+instanceID)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	if limits.MemoryLimitMB > 0 {
+		limit := strconv.FormatInt(int64(limits.MemoryLimitMB)*1024*1024, 10)
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(limit), 0644); err != nil {
+			_ = os.Remove(dir)
+			return "", fmt.Errorf("failed to write memory.max: %w", err)
+		}
+	}
+	if limits.CPULimitPercent > 0 {
+		quota := limits.CPULimitPercent * cgroupCPUPeriod / 100
+		value := fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)
+		if err := os.WriteFile(filepath.Join(dir, "cpu.max"), []byte(value), 0644); err != nil {
+			_ = os.Remove(dir)
+			return "", fmt.Errorf("failed to write cpu.max: %w", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		_ = os.Remove(dir)
+		return "", fmt.Errorf("failed to move kernel into cgroup: %w", err)
+	}
+	return dir, nil
+}
+
+// currentCgroupPath returns this process's cgroup v2 path relative to the
+// cgroup root, e.g. "/user.slice/user-1000.slice/session-2.scope".
+func currentCgroupPath() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no cgroup v2 hierarchy found")
+}
@@ -0,0 +1,45 @@
+//go:build !linux
+
+package kernel_manager
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+)
+
+// readProcessStats reads RSS and cumulative CPU time for pid by shelling out
+// to ps, which is available on every supported non-Linux platform.
+func readProcessStats(pid int) (int64, time.Duration, error) {
+	out, err := exec.Command("ps", "-o", "rss=,time=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected ps output %q", string(out))
+	}
+	rssKB, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed rss %q: %w", fields[0], err)
+	}
+	cpuTime, err := parsePsCPUTime(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return rssKB * 1024, cpuTime, nil
+}
+
+// applyResourceLimits is a no-op outside Linux: there is no way to apply
+// rlimits or cgroups to an already running child process.
+func applyResourceLimits(pid int, instanceID string, limits config.KernelLanguageSettings) func() {
+	if limits.MemoryLimitMB > 0 || limits.CPULimitPercent > 0 {
+		log.Printf("kernel_manager: resource limits are only enforced on Linux, ignoring for kernel %s", instanceID)
+	}
+	return func() {}
+}
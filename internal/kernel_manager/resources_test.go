@@ -0,0 +1,85 @@
+package kernel_manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	t.Run("parses utime, stime and rss", func(t *testing.T) {
+		stat := "4242 (python3) S 1 4242 4242 0 -1 4194560 12345 0 0 0 150 50 0 0 20 0 3 0 987654 123456789 2048 18446744073709551615"
+		cpuTicks, rssPages, err := parseProcStat(stat)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cpuTicks != 200 {
+			t.Errorf("cpuTicks = %d, want 200", cpuTicks)
+		}
+		if rssPages != 2048 {
+			t.Errorf("rssPages = %d, want 2048", rssPages)
+		}
+	})
+
+	t.Run("handles spaces and parentheses in comm", func(t *testing.T) {
+		stat := "7 (weird) name) R 1 7 7 0 -1 0 0 0 0 0 10 5 0 0 20 0 1 0 1 1 64 0"
+		cpuTicks, rssPages, err := parseProcStat(stat)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cpuTicks != 15 || rssPages != 64 {
+			t.Errorf("got cpuTicks=%d rssPages=%d, want 15 and 64", cpuTicks, rssPages)
+		}
+	})
+
+	t.Run("rejects truncated stat", func(t *testing.T) {
+		if _, _, err := parseProcStat("1 (init) S 0 1"); err == nil {
+			t.Fatal("expected error for truncated stat")
+		}
+	})
+}
+
+func TestParsePsCPUTime(t *testing.T) {
+	cases := []struct {
+		in   string
+		want time.Duration
+	}{
+		{in: "0:01.50", want: 1500 * time.Millisecond},
+		{in: "12:03", want: 12*time.Minute + 3*time.Second},
+		{in: "01:02:03", want: time.Hour + 2*time.Minute + 3*time.Second},
+		{in: "2-00:00:01", want: 48*time.Hour + time.Second},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := parsePsCPUTime(tc.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("parsePsCPUTime(%q) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "abc", "1", "1:2:3:4"} {
+		if _, err := parsePsCPUTime(bad); err == nil {
+			t.Errorf("parsePsCPUTime(%q) should fail", bad)
+		}
+	}
+}
+
+func TestTrackExecutionDisarmsTimeout(t *testing.T) {
+	inst := &KernelInstance{id: "k1"}
+	inst.settings.ExecutionTimeoutSeconds = 60
+
+	inst.trackExecutionStart("block|exec|2024-01-01T00:00:00Z")
+	if len(inst.executionTimers) != 1 {
+		t.Fatalf("expected one armed timer, got %d", len(inst.executionTimers))
+	}
+	inst.trackExecutionEnd("block|exec|2024-01-01T00:00:00Z")
+	if len(inst.executionTimers) != 0 {
+		t.Fatalf("expected timer to be disarmed, got %d", len(inst.executionTimers))
+	}
+	if inst.isExecutionActive("block|exec|2024-01-01T00:00:00Z") {
+		t.Fatal("execution should no longer be active")
+	}
+}
@@ -0,0 +1,46 @@
+package kernel_manager
+
+import (
+	"path"
+
+	"github.com/etesam913/bytebook/internal/config"
+)
+
+// KernelScope identifies which notes share a kernel. Kernels are keyed by
+// (language, Type, ID); NoteID is the note that asked for the kernel and is
+// only used for reporting.
+type KernelScope struct {
+	Type   string
+	ID     string
+	NoteID string
+}
+
+// NewKernelScope resolves the scope a note's code runs in. Folder scopes are
+// keyed by the note's parent folder ("" for top-level notes) and the project
+// scope shares a single kernel per language across every note. Unknown scope
+// types fall back to a per-note kernel.
+func NewKernelScope(scopeType, noteID string) KernelScope {
+	switch scopeType {
+	case config.KernelScopeFolder:
+		folder := path.Dir(noteID)
+		if folder == "." {
+			folder = ""
+		}
+		return KernelScope{Type: config.KernelScopeFolder, ID: folder, NoteID: noteID}
+	case config.KernelScopeProject:
+		return KernelScope{Type: config.KernelScopeProject, ID: "", NoteID: noteID}
+	default:
+		return KernelScope{Type: config.KernelScopeNote, ID: noteID, NoteID: noteID}
+	}
+}
+
+// langScopeKey is the manager's lookup key for a shared kernel.
+type langScopeKey struct {
+	language  string
+	scopeType string
+	scopeID   string
+}
+
+func newLangScopeKey(language string, scope KernelScope) langScopeKey {
+	return langScopeKey{language: language, scopeType: scope.Type, scopeID: scope.ID}
+}
@@ -0,0 +1,62 @@
+package kernel_manager
+
+import (
+	"testing"
+
+	"github.com/etesam913/bytebook/internal/config"
+)
+
+func TestNewKernelScope(t *testing.T) {
+	cases := []struct {
+		name      string
+		scopeType string
+		noteID    string
+		want      KernelScope
+	}{
+		{
+			name:      "note scope keys by note",
+			scopeType: config.KernelScopeNote,
+			noteID:    "data/analysis.md",
+			want:      KernelScope{Type: config.KernelScopeNote, ID: "data/analysis.md", NoteID: "data/analysis.md"},
+		},
+		{
+			name:      "folder scope keys by parent folder",
+			scopeType: config.KernelScopeFolder,
+			noteID:    "data/2024/analysis.md",
+			want:      KernelScope{Type: config.KernelScopeFolder, ID: "data/2024", NoteID: "data/2024/analysis.md"},
+		},
+		{
+			name:      "folder scope for a top-level note",
+			scopeType: config.KernelScopeFolder,
+			noteID:    "analysis.md",
+			want:      KernelScope{Type: config.KernelScopeFolder, ID: "", NoteID: "analysis.md"},
+		},
+		{
+			name:      "project scope shares one key",
+			scopeType: config.KernelScopeProject,
+			noteID:    "data/analysis.md",
+			want:      KernelScope{Type: config.KernelScopeProject, ID: "", NoteID: "data/analysis.md"},
+		},
+		{
+			name:      "unknown scope falls back to note",
+			scopeType: "galaxy",
+			noteID:    "data/analysis.md",
+			want:      KernelScope{Type: config.KernelScopeNote, ID: "data/analysis.md", NoteID: "data/analysis.md"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NewKernelScope(tc.scopeType, tc.noteID); got != tc.want {
+				t.Errorf("NewKernelScope(%q, %q) = %+v, want %+v", tc.scopeType, tc.noteID, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("notes in the same folder share a key", func(t *testing.T) {
+		a := newLangScopeKey("python", NewKernelScope(config.KernelScopeFolder, "data/a.md"))
+		b := newLangScopeKey("python", NewKernelScope(config.KernelScopeFolder, "data/b.md"))
+		if a != b {
+			t.Errorf("expected %+v == %+v", a, b)
+		}
+	})
+}
@@ -0,0 +1,454 @@
+package kernel_manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+	"github.com/etesam913/bytebook/internal/jupyter_protocol/sockets"
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+const (
+	historyDirName = ".history"
+
+	// transcriptRetention is how long a kernel's transcript is kept after its
+	// last write before SetupHistoryDir prunes it.
+	transcriptRetention = 30 * 24 * time.Hour
+
+	// maxTranscriptOutputBytes caps each recorded output value. Longer stream
+	// text is cut; longer rich data (e.g. base64 images) is dropped.
+	maxTranscriptOutputBytes = 64 * 1024
+	// maxTranscriptOutputs caps the number of outputs kept per execution.
+	maxTranscriptOutputs = 200
+)
+
+// Transcript entry statuses. An execution is "running" until the kernel goes
+// idle, "aborted" if the kernel skipped it after an earlier error, and
+// "incomplete" if the kernel shut down or died before it finished.
+const (
+	TranscriptStatusRunning    = "running"
+	TranscriptStatusOK         = "ok"
+	TranscriptStatusError      = "error"
+	TranscriptStatusAborted    = "aborted"
+	TranscriptStatusIncomplete = "incomplete"
+)
+
+// TranscriptEntry is a single execution recorded in a kernel's transcript.
+// Outputs are stored as the kernel sent them, without ANSI-to-HTML conversion.
+type TranscriptEntry struct {
+	KernelInstanceID string             `json:"kernelInstanceId"`
+	Language         string             `json:"language"`
+	NoteID           string             `json:"noteId"`
+	CodeBlockID      string             `json:"codeBlockId"`
+	ExecutionID      string             `json:"executionId"`
+	Code             string             `json:"code"`
+	ExecutionCount   int                `json:"executionCount"`
+	Status           string             `json:"status"`
+	StartedAt        int64              `json:"startedAt"`
+	FinishedAt       int64              `json:"finishedAt"`
+	Duration         string             `json:"duration"`
+	Outputs          []TranscriptOutput `json:"outputs"`
+	OutputsTruncated bool               `json:"outputsTruncated"`
+
+	// clearPending is set by clear_output(wait=True): the outputs are
+	// replaced by the next output instead of being cleared immediately.
+	clearPending bool
+}
+
+// TranscriptOutput is one iopub output of an execution. Type is the Jupyter
+// message type: stream, execute_result, display_data or error.
+type TranscriptOutput struct {
+	Type           string            `json:"type"`
+	Name           string            `json:"name,omitempty"`
+	Text           string            `json:"text,omitempty"`
+	Data           map[string]string `json:"data,omitempty"`
+	DisplayID      string            `json:"displayId,omitempty"`
+	ErrorName      string            `json:"errorName,omitempty"`
+	ErrorValue     string            `json:"errorValue,omitempty"`
+	ErrorTraceback []string          `json:"errorTraceback,omitempty"`
+	Truncated      bool              `json:"truncated,omitempty"`
+}
+
+// key identifies an entry within its kernel's transcript.
+func (e TranscriptEntry) key() string {
+	return e.CodeBlockID + "|" + e.ExecutionID
+}
+
+// historyDir returns the absolute path to the per-project transcript directory.
+func historyDir(projectPath string) string {
+	return filepath.Join(projectPath, "code", historyDirName)
+}
+
+// transcriptPath returns the transcript file for a given kernel instance id.
+func transcriptPath(projectPath, id string) string {
+	return filepath.Join(historyDir(projectPath), id+".jsonl")
+}
+
+// SetupHistoryDir creates the .history directory if it does not exist and
+// prunes transcripts that have not been written to within transcriptRetention.
+func SetupHistoryDir(projectPath string) error {
+	dir := historyDir(projectPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history dir: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read history dir: %w", err)
+	}
+	cutoff := time.Now().Add(-transcriptRetention)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".jsonl" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			log.Printf("kernel_manager: failed to prune transcript %s: %v", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// transcript is the append-only execution log of one kernel instance. Every
+// change to an entry appends a full snapshot of it; readers keep the last
+// snapshot per entry. This keeps the file append-only while still recording
+// executions that never finished because the kernel died.
+type transcript struct {
+	path             string
+	kernelInstanceID string
+	language         string
+
+	mu      sync.Mutex
+	pending map[string]*TranscriptEntry
+}
+
+func newTranscript(projectPath, kernelInstanceID, language string) *transcript {
+	return &transcript{
+		path:             transcriptPath(projectPath, kernelInstanceID),
+		kernelInstanceID: kernelInstanceID,
+		language:         language,
+		pending:          map[string]*TranscriptEntry{},
+	}
+}
+
+// transcriptKey strips the "|{startTime}" suffix SendExecuteRequest appends
+// to message ids, leaving {codeBlockId}|{executionId}.
+func transcriptKey(parentMsgID string) string {
+	if idx := strings.LastIndex(parentMsgID, "|"); idx != -1 {
+		return parentMsgID[:idx]
+	}
+	return parentMsgID
+}
+
+// begin records that code is being sent to the kernel. It runs before the
+// send so replies that arrive immediately find the entry; a failed send is
+// undone with abandonExecution.
+func (t *transcript) begin(noteID, codeBlockID, executionID, code string) {
+	if t == nil {
+		return
+	}
+	entry := &TranscriptEntry{
+		KernelInstanceID: t.kernelInstanceID,
+		Language:         t.language,
+		NoteID:           noteID,
+		CodeBlockID:      codeBlockID,
+		ExecutionID:      executionID,
+		Code:             code,
+		Status:           TranscriptStatusRunning,
+		StartedAt:        time.Now().UnixMilli(),
+		Outputs:          []TranscriptOutput{},
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[entry.key()] = entry
+	t.appendLocked(*entry)
+}
+
+// observe folds an iopub message into the execution it belongs to. The entry
+// is written out once the kernel reports idle for it.
+func (t *transcript) observe(parentMsgID string, msg jupyter_protocol.Message) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// An update may be parented to a different execution than the display it
+	// replaces, so it is matched by display_id across all running executions.
+	if msg.Header.MsgType == "update_display_data" {
+		t.updateDisplayLocked(msg)
+		return
+	}
+
+	key := transcriptKey(parentMsgID)
+	entry, ok := t.pending[key]
+	if !ok {
+		return
+	}
+
+	switch msg.Header.MsgType {
+	case "stream", "execute_result", "display_data", "error":
+		if entry.clearPending {
+			entry.clearOutputs()
+		}
+	}
+
+	switch msg.Header.MsgType {
+	case "execute_input":
+		if count, ok := msg.Content["execution_count"].(float64); ok {
+			entry.ExecutionCount = int(count)
+		}
+	case "stream":
+		name, _ := msg.Content["name"].(string)
+		text, _ := msg.Content["text"].(string)
+		// Kernels flush streams in small chunks; merge consecutive chunks.
+		if last := len(entry.Outputs) - 1; last >= 0 && entry.Outputs[last].Type == "stream" && entry.Outputs[last].Name == name {
+			entry.Outputs[last].Text, entry.Outputs[last].Truncated = capText(entry.Outputs[last].Text + text)
+			return
+		}
+		output := TranscriptOutput{Type: "stream", Name: name}
+		output.Text, output.Truncated = capText(text)
+		entry.addOutput(output)
+	case "execute_result", "display_data":
+		output := TranscriptOutput{Type: msg.Header.MsgType, DisplayID: sockets.TransientDisplayID(msg.Content)}
+		output.Data, output.Truncated = capMimeBundle(msg.Content)
+		entry.addOutput(output)
+	case "clear_output":
+		if wait, _ := msg.Content["wait"].(bool); wait {
+			entry.clearPending = true
+			return
+		}
+		entry.clearOutputs()
+	case "error":
+		output := TranscriptOutput{Type: "error", ErrorTraceback: []string{}}
+		output.ErrorName, _ = msg.Content["ename"].(string)
+		output.ErrorValue, _ = msg.Content["evalue"].(string)
+		if traceback, ok := msg.Content["traceback"].([]any); ok {
+			for _, line := range traceback {
+				if str, ok := line.(string); ok {
+					output.ErrorTraceback = append(output.ErrorTraceback, str)
+				}
+			}
+		}
+		entry.addOutput(output)
+		entry.Status = TranscriptStatusError
+	case "status":
+		state, _ := msg.Content["execution_state"].(string)
+		parentType, _ := msg.ParentHeader["msg_type"].(string)
+		if state != "idle" || parentType != "execute_request" {
+			return
+		}
+		finishedAt := time.Now()
+		entry.FinishedAt = finishedAt.UnixMilli()
+		entry.Duration = util.FormatExecutionDuration(time.UnixMilli(entry.StartedAt), finishedAt)
+		switch {
+		case entry.Status == TranscriptStatusError:
+		case entry.ExecutionCount == 0:
+			// Kernels skip queued requests after an error without ever
+			// broadcasting execute_input for them.
+			entry.Status = TranscriptStatusAborted
+		default:
+			entry.Status = TranscriptStatusOK
+		}
+		delete(t.pending, key)
+		t.appendLocked(*entry)
+	}
+}
+
+// abandon marks every unfinished execution as incomplete. Called when the
+// kernel shuts down or its process exits.
+func (t *transcript) abandon() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := make([]*TranscriptEntry, 0, len(t.pending))
+	for _, entry := range t.pending {
+		pending = append(pending, entry)
+	}
+	sort.Slice(pending, func(a, b int) bool { return pending[a].StartedAt < pending[b].StartedAt })
+	for _, entry := range pending {
+		entry.Status = TranscriptStatusIncomplete
+		t.appendLocked(*entry)
+	}
+	t.pending = map[string]*TranscriptEntry{}
+}
+
+// abandonExecution marks one unfinished execution as incomplete. Called when
+// its execute_request could not be sent after begin recorded it.
+func (t *transcript) abandonExecution(codeBlockID, executionID string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := fmt.Sprintf("%s|%s", codeBlockID, executionID)
+	entry, ok := t.pending[key]
+	if !ok {
+		return
+	}
+	entry.Status = TranscriptStatusIncomplete
+	delete(t.pending, key)
+	t.appendLocked(*entry)
+}
+
+// appendLocked writes one entry snapshot as a JSON line. Must be called with
+// t.mu held. Failures are logged; a broken transcript never affects execution.
+func (t *transcript) appendLocked(entry TranscriptEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("kernel_manager: failed to encode transcript entry: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		log.Printf("kernel_manager: failed to create history dir: %v", err)
+		return
+	}
+	file, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("kernel_manager: failed to open transcript %s: %v", t.path, err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		log.Printf("kernel_manager: failed to write transcript %s: %v", t.path, err)
+	}
+}
+
+// updateDisplayLocked replaces the data of every recorded output carrying the
+// message's display_id. Must be called with t.mu held.
+func (t *transcript) updateDisplayLocked(msg jupyter_protocol.Message) {
+	displayID := sockets.TransientDisplayID(msg.Content)
+	if displayID == "" {
+		return
+	}
+	for _, entry := range t.pending {
+		for idx := range entry.Outputs {
+			if entry.Outputs[idx].DisplayID == displayID {
+				entry.Outputs[idx].Data, entry.Outputs[idx].Truncated = capMimeBundle(msg.Content)
+			}
+		}
+	}
+}
+
+func (e *TranscriptEntry) clearOutputs() {
+	e.Outputs = []TranscriptOutput{}
+	e.OutputsTruncated = false
+	e.clearPending = false
+}
+
+func (e *TranscriptEntry) addOutput(output TranscriptOutput) {
+	if len(e.Outputs) >= maxTranscriptOutputs {
+		e.OutputsTruncated = true
+		return
+	}
+	e.Outputs = append(e.Outputs, output)
+}
+
+// capMimeBundle returns the string values of content.data, dropping values
+// larger than maxTranscriptOutputBytes.
+func capMimeBundle(content map[string]any) (map[string]string, bool) {
+	out := map[string]string{}
+	truncated := false
+	data, _ := content["data"].(map[string]any)
+	for mimeType, value := range data {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if len(str) > maxTranscriptOutputBytes {
+			truncated = true
+			continue
+		}
+		out[mimeType] = str
+	}
+	return out, truncated
+}
+
+// capText cuts text to maxTranscriptOutputBytes, keeping the most recent output.
+func capText(text string) (string, bool) {
+	if len(text) <= maxTranscriptOutputBytes {
+		return text, false
+	}
+	return text[len(text)-maxTranscriptOutputBytes:], true
+}
+
+// readTranscript reads a transcript file and returns its entries in the order
+// they were started, keeping the last snapshot written for each. A truncated
+// final line (e.g. from a crash mid-write) is ignored.
+func readTranscript(path string) ([]TranscriptEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []TranscriptEntry{}
+	indexByKey := map[string]int{}
+	decoder := json.NewDecoder(file)
+	for {
+		var entry TranscriptEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("kernel_manager: stopped reading transcript %s: %v", path, err)
+			}
+			break
+		}
+		if idx, ok := indexByKey[entry.key()]; ok {
+			entries[idx] = entry
+			continue
+		}
+		indexByKey[entry.key()] = len(entries)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// KernelHistory returns the transcript of a kernel instance, live or not.
+func (m *KernelManager) KernelHistory(kernelInstanceID string) ([]TranscriptEntry, error) {
+	if kernelInstanceID == "" || kernelInstanceID != filepath.Base(kernelInstanceID) {
+		return nil, fmt.Errorf("invalid kernel instance id %q", kernelInstanceID)
+	}
+	entries, err := readTranscript(transcriptPath(m.projectPath, kernelInstanceID))
+	if errors.Is(err, os.ErrNotExist) {
+		return []TranscriptEntry{}, nil
+	}
+	return entries, err
+}
+
+// NoteHistory returns every recorded execution sent from noteID across all
+// kernel transcripts, oldest first.
+func (m *KernelManager) NoteHistory(noteID string) ([]TranscriptEntry, error) {
+	files, err := filepath.Glob(filepath.Join(historyDir(m.projectPath), "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	out := []TranscriptEntry{}
+	for _, file := range files {
+		entries, err := readTranscript(file)
+		if err != nil {
+			log.Printf("kernel_manager: failed to read transcript %s: %v", file, err)
+			continue
+		}
+		for _, entry := range entries {
+			if entry.NoteID == noteID {
+				out = append(out, entry)
+			}
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].StartedAt < out[b].StartedAt })
+	return out, nil
+}
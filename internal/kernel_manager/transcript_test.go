@@ -0,0 +1,243 @@
+package kernel_manager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+)
+
+func iopubMessage(msgType string, content map[string]any) jupyter_protocol.Message {
+	return jupyter_protocol.Message{
+		Header:       jupyter_protocol.Header{MsgType: msgType},
+		ParentHeader: map[string]any{"msg_type": "execute_request"},
+		Content:      content,
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	t.Run("records a finished execution", func(t *testing.T) {
+		projectPath := t.TempDir()
+		tr := newTranscript(projectPath, "kernel-1", "python")
+		tr.begin("folder/note.md", "block", "exec", "print('hi')")
+
+		parentMsgID := "block|exec|2026-01-01T00:00:00Z"
+		tr.observe(parentMsgID, iopubMessage("status", map[string]any{"execution_state": "busy"}))
+		tr.observe(parentMsgID, iopubMessage("execute_input", map[string]any{"code": "print('hi')", "execution_count": float64(3)}))
+		tr.observe(parentMsgID, iopubMessage("stream", map[string]any{"name": "stdout", "text": "h"}))
+		tr.observe(parentMsgID, iopubMessage("stream", map[string]any{"name": "stdout", "text": "i\n"}))
+		tr.observe(parentMsgID, iopubMessage("status", map[string]any{"execution_state": "idle"}))
+
+		entries, err := readTranscript(transcriptPath(projectPath, "kernel-1"))
+		if err != nil {
+			t.Fatalf("readTranscript: %v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("expected 1 entry, got %d", len(entries))
+		}
+		entry := entries[0]
+		if entry.Status != TranscriptStatusOK || entry.ExecutionCount != 3 || entry.NoteID != "folder/note.md" {
+			t.Fatalf("unexpected entry: %+v", entry)
+		}
+		if len(entry.Outputs) != 1 || entry.Outputs[0].Text != "hi\n" {
+			t.Fatalf("expected merged stream output, got %+v", entry.Outputs)
+		}
+		if entry.Duration == "" {
+			t.Fatal("expected a duration")
+		}
+	})
+
+	t.Run("marks errors and aborted executions", func(t *testing.T) {
+		projectPath := t.TempDir()
+		tr := newTranscript(projectPath, "kernel-1", "python")
+		tr.begin("note.md", "a", "1", "1/0")
+		tr.begin("note.md", "b", "2", "x = 1")
+
+		tr.observe("a|1|t", iopubMessage("execute_input", map[string]any{"execution_count": float64(1)}))
+		tr.observe("a|1|t", iopubMessage("error", map[string]any{"ename": "ZeroDivisionError", "evalue": "division by zero", "traceback": []any{"tb"}}))
+		tr.observe("a|1|t", iopubMessage("status", map[string]any{"execution_state": "idle"}))
+		tr.observe("b|2|t", iopubMessage("status", map[string]any{"execution_state": "idle"}))
+
+		entries, err := readTranscript(transcriptPath(projectPath, "kernel-1"))
+		if err != nil {
+			t.Fatalf("readTranscript: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 entries, got %d", len(entries))
+		}
+		if entries[0].Status != TranscriptStatusError || entries[0].Outputs[0].ErrorName != "ZeroDivisionError" {
+			t.Fatalf("unexpected first entry: %+v", entries[0])
+		}
+		if entries[1].Status != TranscriptStatusAborted {
+			t.Fatalf("expected aborted, got %s", entries[1].Status)
+		}
+	})
+
+	t.Run("applies clear_output and update_display_data", func(t *testing.T) {
+		projectPath := t.TempDir()
+		tr := newTranscript(projectPath, "kernel-1", "python")
+		tr.begin("note.md", "a", "1", "for i in tqdm(range(3)): ...")
+
+		transient := map[string]any{"display_id": "bar"}
+		tr.observe("a|1|t", iopubMessage("execute_input", map[string]any{"execution_count": float64(1)}))
+		tr.observe("a|1|t", iopubMessage("display_data", map[string]any{"data": map[string]any{"text/plain": "0%"}, "transient": transient}))
+		tr.observe("a|1|t", iopubMessage("update_display_data", map[string]any{"data": map[string]any{"text/plain": "100%"}, "transient": transient}))
+		tr.observe("a|1|t", iopubMessage("stream", map[string]any{"name": "stdout", "text": "frame 1"}))
+		tr.observe("a|1|t", iopubMessage("clear_output", map[string]any{"wait": true}))
+		tr.observe("a|1|t", iopubMessage("stream", map[string]any{"name": "stdout", "text": "frame 2"}))
+		tr.observe("a|1|t", iopubMessage("status", map[string]any{"execution_state": "idle"}))
+
+		entries, err := readTranscript(transcriptPath(projectPath, "kernel-1"))
+		if err != nil {
+			t.Fatalf("readTranscript: %v", err)
+		}
+		outputs := entries[0].Outputs
+		if len(outputs) != 1 || outputs[0].Text != "frame 2" {
+			t.Fatalf("expected only the output after the clear, got %+v", outputs)
+		}
+
+		tr.begin("note.md", "b", "2", "display")
+		tr.observe("b|2|t", iopubMessage("execute_input", map[string]any{"execution_count": float64(2)}))
+		tr.observe("b|2|t", iopubMessage("display_data", map[string]any{"data": map[string]any{"text/plain": "0%"}, "transient": transient}))
+		tr.observe("c|3|t", iopubMessage("update_display_data", map[string]any{"data": map[string]any{"text/plain": "50%"}, "transient": transient}))
+		tr.observe("b|2|t", iopubMessage("status", map[string]any{"execution_state": "idle"}))
+
+		entries, err = readTranscript(transcriptPath(projectPath, "kernel-1"))
+		if err != nil {
+			t.Fatalf("readTranscript: %v", err)
+		}
+		if got := entries[1].Outputs[0].Data["text/plain"]; got != "50%" {
+			t.Fatalf("expected display to be updated in place, got %q", got)
+		}
+	})
+
+	t.Run("abandon marks unfinished executions incomplete", func(t *testing.T) {
+		projectPath := t.TempDir()
+		tr := newTranscript(projectPath, "kernel-1", "python")
+		tr.begin("note.md", "a", "1", "while True: pass")
+		tr.abandon()
+
+		entries, err := readTranscript(transcriptPath(projectPath, "kernel-1"))
+		if err != nil {
+			t.Fatalf("readTranscript: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Status != TranscriptStatusIncomplete {
+			t.Fatalf("unexpected entries: %+v", entries)
+		}
+	})
+
+	t.Run("abandonExecution marks only the unsent execution incomplete", func(t *testing.T) {
+		projectPath := t.TempDir()
+		tr := newTranscript(projectPath, "kernel-1", "python")
+		tr.begin("note.md", "a", "1", "x = 1")
+		tr.begin("note.md", "b", "2", "y = 2")
+		tr.abandonExecution("b", "2")
+
+		entries, err := readTranscript(transcriptPath(projectPath, "kernel-1"))
+		if err != nil {
+			t.Fatalf("readTranscript: %v", err)
+		}
+		if len(entries) != 2 || entries[0].Status != TranscriptStatusRunning || entries[1].Status != TranscriptStatusIncomplete {
+			t.Fatalf("unexpected entries: %+v", entries)
+		}
+		if _, ok := tr.pending["b|2"]; ok {
+			t.Fatal("abandoned execution should no longer be pending")
+		}
+	})
+
+	t.Run("ignores a torn final line", func(t *testing.T) {
+		projectPath := t.TempDir()
+		tr := newTranscript(projectPath, "kernel-1", "python")
+		tr.begin("note.md", "a", "1", "x = 1")
+
+		file, err := os.OpenFile(tr.path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = file.WriteString(`{"codeBlockId":"b","exec`)
+		file.Close()
+
+		entries, err := readTranscript(tr.path)
+		if err != nil {
+			t.Fatalf("readTranscript: %v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("expected 1 entry, got %d", len(entries))
+		}
+	})
+}
+
+func TestCapText(t *testing.T) {
+	text, truncated := capText("short")
+	if text != "short" || truncated {
+		t.Fatalf("short text should be kept, got %q %v", text, truncated)
+	}
+
+	long := strings.Repeat("a", maxTranscriptOutputBytes) + "tail"
+	text, truncated = capText(long)
+	if !truncated || len(text) != maxTranscriptOutputBytes || !strings.HasSuffix(text, "tail") {
+		t.Fatal("long text should keep the most recent bytes")
+	}
+}
+
+func TestNoteHistory(t *testing.T) {
+	projectPath := t.TempDir()
+	m := New(projectPath, config.AllKernels{})
+
+	first := newTranscript(projectPath, "kernel-1", "python")
+	first.begin("a.md", "block", "1", "x = 1")
+	first.abandon()
+	second := newTranscript(projectPath, "kernel-2", "python")
+	second.begin("b.md", "block", "2", "y = 2")
+	second.begin("a.md", "block", "3", "z = 3")
+	second.abandon()
+
+	entries, err := m.NoteHistory("a.md")
+	if err != nil {
+		t.Fatalf("NoteHistory: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ExecutionID != "1" || entries[1].ExecutionID != "3" {
+		t.Fatalf("unexpected note history: %+v", entries)
+	}
+
+	if _, err := m.KernelHistory("../settings"); err == nil {
+		t.Fatal("expected path-like kernel ids to be rejected")
+	}
+	missing, err := m.KernelHistory("kernel-3")
+	if err != nil || len(missing) != 0 {
+		t.Fatalf("missing transcript should be empty, got %v %v", missing, err)
+	}
+}
+
+func TestSetupHistoryDir(t *testing.T) {
+	projectPath := t.TempDir()
+	if err := SetupHistoryDir(projectPath); err != nil {
+		t.Fatalf("SetupHistoryDir: %v", err)
+	}
+
+	stale := filepath.Join(historyDir(projectPath), "stale.jsonl")
+	fresh := filepath.Join(historyDir(projectPath), "fresh.jsonl")
+	for _, path := range []string{stale, fresh} {
+		if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-transcriptRetention - time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetupHistoryDir(projectPath); err != nil {
+		t.Fatalf("SetupHistoryDir: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatal("stale transcript should be pruned")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Fatal("fresh transcript should be kept")
+	}
+}
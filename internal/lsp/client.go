@@ -8,8 +8,8 @@ import (
 
 // noopClient implements protocol.Client. It satisfies the interface so the
 // jsonrpc2 connection can route server-initiated callbacks somewhere, but
-// drops everything on the floor. v1.1 may grow this to handle
-// PublishDiagnostics for inline error squiggles.
+// drops everything on the floor. diagnosticsClient embeds it and overrides the
+// one callback Instance cares about.
 type noopClient struct{}
 
 func (c *noopClient) Progress(ctx context.Context, p *protocol.ProgressParams) error { return nil }
@@ -39,11 +39,26 @@ func (c *noopClient) ApplyEdit(ctx context.Context, p *protocol.ApplyWorkspaceEd
 	return false, nil
 }
 func (c *noopClient) Configuration(ctx context.Context, p *protocol.ConfigurationParams) ([]interface{}, error) {
-	// pyright queries python.* config at startup; returning a slice of nils tells
-	// it to fall back to its built-in defaults for each requested section.
+	// Servers query their config sections at startup (pyright asks for
+	// python.*, gopls for gopls); returning a slice of nils tells them to fall
+	// back to built-in defaults for each requested section.
 	out := make([]interface{}, len(p.Items))
 	return out, nil
 }
 func (c *noopClient) WorkspaceFolders(ctx context.Context) ([]protocol.WorkspaceFolder, error) {
 	return nil, nil
 }
+
+// diagnosticsClient forwards textDocument/publishDiagnostics to an Instance
+// and drops every other server callback via the embedded noopClient.
+type diagnosticsClient struct {
+	noopClient
+	onPublish func(*protocol.PublishDiagnosticsParams)
+}
+
+func (c *diagnosticsClient) PublishDiagnostics(ctx context.Context, p *protocol.PublishDiagnosticsParams) error {
+	if c.onPublish != nil {
+		c.onPublish(p)
+	}
+	return nil
+}
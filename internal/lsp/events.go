@@ -0,0 +1,25 @@
+package lsp
+
+import (
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+func init() {
+	application.RegisterEvent[DiagnosticsEventData](util.EventLSPDiagnostics)
+}
+
+// emitDiagnostics broadcasts the latest diagnostics for one (note, language)
+// pair so open editors can refresh their underlines.
+func emitDiagnostics(lspInstance *Instance, diagnostics []Diagnostic) {
+	if app := application.Get(); app != nil {
+		app.Event.EmitEvent(&application.CustomEvent{
+			Name: util.EventLSPDiagnostics,
+			Data: DiagnosticsEventData{
+				NoteID:      lspInstance.NoteID(),
+				Language:    lspInstance.Language(),
+				Diagnostics: diagnostics,
+			},
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"go.lsp.dev/protocol"
+)
+
+// formattingOptions is sent with every formatting request. Formatters with
+// their own canonical style (gofmt, google-java-format) ignore it.
+var formattingOptions = protocol.FormattingOptions{
+	InsertSpaces: true,
+	TabSize:      4,
+}
+
+// ErrDocChanged is returned by Format when a block was edited while the
+// server was formatting; the result would overwrite that edit.
+var ErrDocChanged = errors.New("document changed during formatting")
+
+// Format runs document formatting over the whole synthetic doc and splits
+// the result back into per-block sources. Only blocks whose source changed
+// are returned. Formatting the concatenated document, rather than each block
+// alone, lets the server see imports and declarations from earlier blocks.
+func (lspInstance *Instance) Format(ctx context.Context) ([]FormattedBlock, error) {
+	if err := lspInstance.checkAlive(); err != nil {
+		return nil, err
+	}
+	if err := lspInstance.didOpenIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	version := lspInstance.doc.Version()
+	text := lspInstance.doc.FullText()
+
+	edits, err := lspInstance.srv.Formatting(ctx, &protocol.DocumentFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: lspInstance.docURI},
+		Options:      formattingOptions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(edits) == 0 {
+		return nil, nil
+	}
+	formatted, err := applyTextEdits(text, edits)
+	if err != nil {
+		return nil, err
+	}
+	return lspInstance.doc.splitFormatted(formatted, version)
+}
+
+// splitFormatted maps a formatted copy of FullText back onto blocks using the
+// separator comments, which formatters leave in place. version must match the
+// version FullText was taken at. Returns the blocks whose source changed.
+func (v *VirtualDoc) splitFormatted(formatted string, version int32) ([]FormattedBlock, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.version != version {
+		return nil, ErrDocChanged
+	}
+	if len(v.blocks) == 0 {
+		return nil, nil
+	}
+	if !strings.HasPrefix(formatted, v.dialect.preamble) {
+		return nil, fmt.Errorf("formatter rewrote the %q preamble", v.dialect.preamble)
+	}
+	formatted = strings.TrimPrefix(formatted, v.dialect.preamble)
+
+	separator := regexp.MustCompile(`^\s*` + regexp.QuoteMeta(v.dialect.commentPrefix+blockSeparatorMarker) + `(\d+) ---\s*$`)
+	segments := []string{""}
+	orders := []int{v.blocks[0].order}
+	for _, line := range strings.SplitAfter(formatted, "\n") {
+		if match := separator.FindStringSubmatch(strings.TrimSuffix(line, "\n")); match != nil {
+			order, _ := strconv.Atoi(match[1])
+			segments = append(segments, "")
+			orders = append(orders, order)
+			continue
+		}
+		segments[len(segments)-1] += line
+	}
+	if len(segments) != len(v.blocks) {
+		return nil, fmt.Errorf("formatted document has %d blocks, want %d", len(segments), len(v.blocks))
+	}
+
+	var changed []FormattedBlock
+	for i, b := range v.blocks {
+		if orders[i] != b.order {
+			return nil, fmt.Errorf("formatter reordered block %s", b.blockID)
+		}
+		// FullText adds a blank line before each separator and formatters
+		// append a final newline, so trailing newlines are restored to
+		// match the block's original source.
+		source := strings.TrimRight(segments[i], "\n")
+		if strings.HasSuffix(b.source, "\n") {
+			source += "\n"
+		}
+		if source != b.source {
+			changed = append(changed, FormattedBlock{BlockID: b.blockID, Source: source})
+		}
+	}
+	return changed, nil
+}
+
+// applyTextEdits applies non-overlapping LSP edits to text. Edits are applied
+// from the end of the document backwards so earlier offsets stay valid.
+func applyTextEdits(text string, edits []protocol.TextEdit) (string, error) {
+	type span struct {
+		start, end int
+		newText    string
+	}
+	lineStarts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	spans := make([]span, 0, len(edits))
+	for _, edit := range edits {
+		start, err := positionOffset(text, lineStarts, edit.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := positionOffset(text, lineStarts, edit.Range.End)
+		if err != nil {
+			return "", err
+		}
+		if end < start {
+			return "", fmt.Errorf("edit range ends before it starts: %+v", edit.Range)
+		}
+		spans = append(spans, span{start: start, end: end, newText: edit.NewText})
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start > spans[j].start })
+	for i := 1; i < len(spans); i++ {
+		if spans[i].end > spans[i-1].start {
+			return "", errors.New("overlapping text edits")
+		}
+	}
+	for _, s := range spans {
+		text = text[:s.start] + s.newText + text[s.end:]
+	}
+	return text, nil
+}
+
+// positionOffset converts an LSP position (line, UTF-16 column) into a byte
+// offset in text. Positions past the end of a line clamp to the line end, as
+// the LSP spec requires.
+func positionOffset(text string, lineStarts []int, pos protocol.Position) (int, error) {
+	if int(pos.Line) >= len(lineStarts) {
+		if int(pos.Line) == len(lineStarts) && pos.Character == 0 {
+			return len(text), nil
+		}
+		return 0, fmt.Errorf("edit position line %d past end of document", pos.Line)
+	}
+	offset := lineStarts[pos.Line]
+	units := uint32(0)
+	for offset < len(text) && text[offset] != '\n' && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		units += uint32(utf16.RuneLen(r))
+		offset += size
+	}
+	return offset, nil
+}
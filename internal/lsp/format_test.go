@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestApplyTextEdits(t *testing.T) {
+	text := "a = 1\nb=é+2\n"
+	got, err := applyTextEdits(text, []protocol.TextEdit{
+		// Inserted out of order; applyTextEdits sorts them.
+		{Range: protocol.Range{Start: protocol.Position{Line: 1, Character: 3}, End: protocol.Position{Line: 1, Character: 4}}, NewText: " + "},
+		{Range: protocol.Range{Start: protocol.Position{Line: 1, Character: 1}, End: protocol.Position{Line: 1, Character: 2}}, NewText: " = "},
+		{Range: protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 2}}, NewText: "c = 3\n"},
+	})
+	if err != nil {
+		t.Fatalf("applyTextEdits: %v", err)
+	}
+	if want := "a = 1\nb = é + 2\nc = 3\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	_, err = applyTextEdits(text, []protocol.TextEdit{
+		{Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 0}, End: protocol.Position{Line: 0, Character: 3}}},
+		{Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 2}, End: protocol.Position{Line: 0, Character: 4}}},
+	})
+	if err == nil {
+		t.Fatal("expected error for overlapping edits")
+	}
+}
+
+func TestVirtualDocSplitFormatted(t *testing.T) {
+	v := NewVirtualDoc("go")
+	v.UpsertBlock("a", 0, "x:=1\n")
+	v.UpsertBlock("b", 4, "fmt.Println( x )")
+
+	formatted := "package main\n\nx := 1\n\n\n// --- bytebook block 4 ---\nfmt.Println(x)\n"
+	blocks, err := v.splitFormatted(formatted, v.Version())
+	if err != nil {
+		t.Fatalf("splitFormatted: %v", err)
+	}
+	want := []FormattedBlock{
+		{BlockID: "a", Source: "x := 1\n"},
+		{BlockID: "b", Source: "fmt.Println(x)"},
+	}
+	if len(blocks) != 2 || blocks[0] != want[0] || blocks[1] != want[1] {
+		t.Fatalf("blocks = %+v, want %+v", blocks, want)
+	}
+
+	if _, err := v.splitFormatted("package main\n\nx := 1\n", v.Version()); err == nil {
+		t.Fatal("expected error when a separator was removed")
+	}
+	if _, err := v.splitFormatted(formatted, v.Version()-1); !errors.Is(err, ErrDocChanged) {
+		t.Fatalf("err = %v, want ErrDocChanged", err)
+	}
+}
+
+func TestInstanceFormatReturnsChangedBlocks(t *testing.T) {
+	in, fake, teardown := newTestInstance(t)
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := in.DidChange(ctx, "a", 0, "x=1"); err != nil {
+		t.Fatalf("DidChange a: %v", err)
+	}
+	if err := in.DidChange(ctx, "b", 1, "y = 2"); err != nil {
+		t.Fatalf("DidChange b: %v", err)
+	}
+
+	fake.mu.Lock()
+	fake.formatEdits = []protocol.TextEdit{
+		{Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 1}, End: protocol.Position{Line: 0, Character: 2}}, NewText: " = "},
+	}
+	fake.mu.Unlock()
+
+	blocks, err := in.Format(ctx)
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	if len(blocks) != 1 || blocks[0] != (FormattedBlock{BlockID: "a", Source: "x = 1"}) {
+		t.Fatalf("blocks = %+v, want only block a reformatted", blocks)
+	}
+}
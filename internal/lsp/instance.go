@@ -10,6 +10,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
@@ -30,24 +32,34 @@ type instanceTransport interface {
 	io.ReadWriteCloser
 }
 
-// Instance owns one pyright child process for one note. All callers can issue
+// Instance owns one language-server child process for one (note, language)
+// pair. All callers can issue
 // concurrent Completion/Hover/DidChange calls; the underlying jsonrpc2.Conn
 // serializes writes and demuxes responses by id.
 type Instance struct {
 	noteID string
+	spec   serverSpec
 	docURI uri.URI
 	doc    *VirtualDoc
 
-	// cmd is the pyright child process. Nil when the instance was constructed
+	// cmd is the language-server child process. Nil when the instance was constructed
 	// for a test with a synthetic transport.
 	cmd       *exec.Cmd
 	transport instanceTransport
 
 	conn jsonrpc2.Conn
 	srv  protocol.Server
+	// capabilities is what the server advertised in its initialize result.
+	// Optional requests such as formatting are only sent when advertised.
+	capabilities protocol.ServerCapabilities
 
 	mu     sync.Mutex
 	opened bool
+	// diagnostics is the latest published set in block-local coordinates.
+	// Servers always publish the full set for a document, so each publish
+	// replaces it.
+	diagnostics   []Diagnostic
+	onDiagnostics func(*Instance, []Diagnostic)
 
 	ctx       context.Context
 	cancelCtx context.CancelFunc
@@ -56,11 +68,21 @@ type Instance struct {
 	done      chan struct{}
 }
 
-// spawnInstance launches pyright as a child process and returns a ready
-// Instance with `initialize` + `initialized` already negotiated.
-func spawnInstance(parent context.Context, noteID, binPath string) (*Instance, error) {
+// spawnInstance launches the language server described by spec as a child
+// process and returns a ready Instance with `initialize` + `initialized`
+// already negotiated. workspaceDir is passed with spec.workspaceFlag when the
+// server keeps a workspace.
+func spawnInstance(parent context.Context, noteID string, spec serverSpec, binPath, workspaceDir string) (*Instance, error) {
+	args := spec.args
+	if spec.workspaceFlag != "" {
+		if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+			return nil, fmt.Errorf("create %s workspace: %w", spec.binaryName, err)
+		}
+		args = append(slices.Clone(args), spec.workspaceFlag, workspaceDir)
+	}
+
 	cctx, cancel := context.WithCancel(parent)
-	cmd := exec.CommandContext(cctx, binPath, "--stdio")
+	cmd := exec.CommandContext(cctx, binPath, args...)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -79,13 +101,13 @@ func spawnInstance(parent context.Context, noteID, binPath string) (*Instance, e
 	}
 	if err := cmd.Start(); err != nil {
 		cancel()
-		return nil, fmt.Errorf("start pyright: %w", err)
+		return nil, fmt.Errorf("start %s: %w", spec.binaryName, err)
 	}
 
-	// Pyright exposes separate stdin/stdout pipes, while jsonrpc2.NewStream
+	// The server exposes separate stdin/stdout pipes, while jsonrpc2.NewStream
 	// wants one ReadWriteCloser. procRWC is the small adapter between those APIs.
 	transport := &procRWC{in: stdin, out: stdout}
-	lspInstance, err := newInstance(cctx, cancel, noteID, transport)
+	lspInstance, err := newInstance(cctx, cancel, noteID, spec, transport)
 	if err != nil {
 		_ = cmd.Process.Kill()
 		_ = cmd.Wait()
@@ -93,7 +115,7 @@ func spawnInstance(parent context.Context, noteID, binPath string) (*Instance, e
 	}
 	lspInstance.cmd = cmd
 
-	go drainStderr(stderr)
+	go drainStderr(spec.binaryName, stderr)
 	go lspInstance.waitProcess()
 
 	if err := lspInstance.initialize(); err != nil {
@@ -106,18 +128,20 @@ func spawnInstance(parent context.Context, noteID, binPath string) (*Instance, e
 // newInstance wires up the jsonrpc2 connection on top of `transport`. It does
 // NOT send initialize — callers do that themselves (so tests can stub the
 // handshake).
-func newInstance(ctx context.Context, cancel context.CancelFunc, noteID string, transport instanceTransport) (*Instance, error) {
+func newInstance(ctx context.Context, cancel context.CancelFunc, noteID string, spec serverSpec, transport instanceTransport) (*Instance, error) {
 	lspInstance := &Instance{
 		noteID:    noteID,
-		docURI:    syntheticDocURI(noteID),
-		doc:       NewVirtualDoc(),
+		spec:      spec,
+		docURI:    syntheticDocURI(noteID, spec.dialect.extension),
+		doc:       NewVirtualDoc(spec.language),
 		transport: transport,
 		ctx:       ctx,
 		cancelCtx: cancel,
 		done:      make(chan struct{}),
 	}
 	stream := jsonrpc2.NewStream(transport)
-	_, conn, srv := protocol.NewClient(ctx, &noopClient{}, stream, zap.NewNop())
+	client := &diagnosticsClient{onPublish: lspInstance.handleDiagnostics}
+	_, conn, srv := protocol.NewClient(ctx, client, stream, zap.NewNop())
 	lspInstance.conn = conn
 	lspInstance.srv = srv
 
@@ -125,11 +149,13 @@ func newInstance(ctx context.Context, cancel context.CancelFunc, noteID string,
 	return lspInstance, nil
 }
 
-// syntheticDocURI returns the URI that pyright sees for this note. We anchor
-// it under the system temp dir so the path is well-formed and unlikely to
-// collide with any real file the user might also open.
-func syntheticDocURI(noteID string) uri.URI {
-	return uri.File(filepath.Join(os.TempDir(), "bytebook-note-"+noteID+".py"))
+// syntheticDocURI returns the URI that the language server sees for this
+// note. We anchor it under the system temp dir so the path is well-formed and
+// unlikely to collide with any real file the user might also open. The
+// extension comes from the language's dialect, so a note with both Python and
+// Go blocks gets two distinct documents.
+func syntheticDocURI(noteID, extension string) uri.URI {
+	return uri.File(filepath.Join(os.TempDir(), "bytebook-note-"+noteID+extension))
 }
 
 // initialize sends the LSP `initialize` request and the `initialized`
@@ -138,7 +164,7 @@ func (lspInstance *Instance) initialize() error {
 	ctx, cancel := context.WithTimeout(lspInstance.ctx, 5*time.Second)
 	defer cancel()
 
-	_, err := lspInstance.srv.Initialize(ctx, &protocol.InitializeParams{
+	result, err := lspInstance.srv.Initialize(ctx, &protocol.InitializeParams{
 		ProcessID: int32(os.Getpid()),
 		RootURI:   uri.File(os.TempDir()),
 		Capabilities: protocol.ClientCapabilities{
@@ -151,15 +177,56 @@ func (lspInstance *Instance) initialize() error {
 				Hover: &protocol.HoverTextDocumentClientCapabilities{
 					ContentFormat: []protocol.MarkupKind{protocol.Markdown, protocol.PlainText},
 				},
+				PublishDiagnostics: &protocol.PublishDiagnosticsClientCapabilities{
+					VersionSupport: true,
+				},
 			},
 		},
 	})
 	if err != nil {
 		return err
 	}
+	if result != nil {
+		lspInstance.capabilities = result.Capabilities
+	}
 	return lspInstance.srv.Initialized(ctx, &protocol.InitializedParams{})
 }
 
+// Feature names an optional request that a server may not implement.
+type Feature int
+
+const (
+	FeatureFormatting Feature = iota
+	FeatureRename
+	FeatureSignatureHelp
+)
+
+// Supports reports whether the server advertised feature when it was
+// initialized. pyright, for example, has no formatter.
+func (lspInstance *Instance) Supports(feature Feature) bool {
+	switch feature {
+	case FeatureFormatting:
+		return providerEnabled(lspInstance.capabilities.DocumentFormattingProvider)
+	case FeatureRename:
+		return providerEnabled(lspInstance.capabilities.RenameProvider)
+	case FeatureSignatureHelp:
+		return lspInstance.capabilities.SignatureHelpProvider != nil
+	}
+	return false
+}
+
+// providerEnabled decodes a `boolean | Options` server capability: a missing
+// or false value means unsupported, and any options object means supported.
+func providerEnabled(provider interface{}) bool {
+	switch p := provider.(type) {
+	case nil:
+		return false
+	case bool:
+		return p
+	}
+	return true
+}
+
 // Done returns a channel closed when the underlying connection terminates.
 // The manager uses this to reap dead instances.
 func (lspInstance *Instance) Done() <-chan struct{} { return lspInstance.done }
@@ -167,8 +234,11 @@ func (lspInstance *Instance) Done() <-chan struct{} { return lspInstance.done }
 // NoteID returns the note this instance serves.
 func (lspInstance *Instance) NoteID() string { return lspInstance.noteID }
 
+// Language returns the code-block language this instance serves.
+func (lspInstance *Instance) Language() string { return lspInstance.spec.language }
+
 // didOpenIfNeeded sends `textDocument/didOpen` exactly once per instance.
-// Pyright rejects completion/hover requests for unknown documents.
+// Language servers reject completion/hover requests for unknown documents.
 func (lspInstance *Instance) didOpenIfNeeded(ctx context.Context) error {
 	lspInstance.mu.Lock()
 	if lspInstance.opened {
@@ -181,15 +251,15 @@ func (lspInstance *Instance) didOpenIfNeeded(ctx context.Context) error {
 	return lspInstance.srv.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
 		TextDocument: protocol.TextDocumentItem{
 			URI:        lspInstance.docURI,
-			LanguageID: protocol.PythonLanguage,
+			LanguageID: lspInstance.spec.languageID,
 			Version:    int32(lspInstance.doc.Version()),
 			Text:       lspInstance.doc.FullText(),
 		},
 	})
 }
 
-// DidChange upserts a block into the synthetic doc and notifies pyright. v1
-// uses full-document sync — the entire concatenated text is sent on every
+// DidChange upserts a block into the synthetic doc and notifies the server.
+// It uses full-document sync — the entire concatenated text is sent on every
 // change. This trades a few extra bytes for simpler coordinate logic.
 func (lspInstance *Instance) DidChange(ctx context.Context, blockID string, order int, source string) error {
 	if err := lspInstance.checkAlive(); err != nil {
@@ -209,7 +279,7 @@ func (lspInstance *Instance) DidChange(ctx context.Context, blockID string, orde
 }
 
 // RemoveBlock drops a block from the synthetic doc and pushes the change to
-// pyright.
+// the server.
 func (lspInstance *Instance) RemoveBlock(ctx context.Context, blockID string) error {
 	if err := lspInstance.checkAlive(); err != nil {
 		return err
@@ -227,9 +297,9 @@ func (lspInstance *Instance) RemoveBlock(ctx context.Context, blockID string) er
 	})
 }
 
-// Completion asks pyright for completion items at (blockID, pos). pos is in
-// block-local coordinates. Returns an empty slice on timeout or when pyright
-// has nothing to offer; returns ErrInstanceDown if the connection is gone.
+// Completion asks the server for completion items at (blockID, pos). pos is
+// in block-local coordinates. Returns an empty slice on timeout or when the
+// server has nothing to offer; returns ErrInstanceDown if the connection is gone.
 func (lspInstance *Instance) Completion(ctx context.Context, blockID string, pos protocol.Position) ([]CompletionItem, error) {
 	if err := lspInstance.checkAlive(); err != nil {
 		return nil, err
@@ -256,8 +326,8 @@ func (lspInstance *Instance) Completion(ctx context.Context, blockID string, pos
 	return convertItems(list.Items), nil
 }
 
-// Hover asks pyright for hover content at (blockID, pos). Returns
-// (contents, true, nil) when pyright found something; (_,_,nil) with found
+// Hover asks the server for hover content at (blockID, pos). Returns
+// (contents, true, nil) when the server found something; (_,_,nil) with found
 // false otherwise.
 func (lspInstance *Instance) Hover(ctx context.Context, blockID string, pos protocol.Position) (string, bool, error) {
 	if err := lspInstance.checkAlive(); err != nil {
@@ -285,6 +355,95 @@ func (lspInstance *Instance) Hover(ctx context.Context, blockID string, pos prot
 	return h.Contents.Value, true, nil
 }
 
+// Diagnostics returns the latest diagnostics the server published for this
+// instance's document, already mapped into block-local coordinates.
+func (lspInstance *Instance) Diagnostics() []Diagnostic {
+	lspInstance.mu.Lock()
+	defer lspInstance.mu.Unlock()
+	out := make([]Diagnostic, len(lspInstance.diagnostics))
+	copy(out, lspInstance.diagnostics)
+	return out
+}
+
+// setDiagnosticsHandler registers fn to run after every publish. The manager
+// installs it before the instance is handed to callers, so no didOpen (and
+// therefore no publish) can precede it.
+func (lspInstance *Instance) setDiagnosticsHandler(fn func(*Instance, []Diagnostic)) {
+	lspInstance.mu.Lock()
+	defer lspInstance.mu.Unlock()
+	lspInstance.onDiagnostics = fn
+}
+
+// handleDiagnostics maps a publishDiagnostics notification back onto blocks.
+// Publishes for a stale document version are dropped: their line numbers
+// refer to a block layout that no longer exists, and the server will publish
+// again for the current version.
+func (lspInstance *Instance) handleDiagnostics(p *protocol.PublishDiagnosticsParams) {
+	if p.URI != lspInstance.docURI {
+		return
+	}
+	if p.Version != 0 && int32(p.Version) != lspInstance.doc.Version() {
+		return
+	}
+	diagnostics := make([]Diagnostic, 0, len(p.Diagnostics))
+	for _, d := range p.Diagnostics {
+		if mapped, ok := lspInstance.mapDiagnostic(d); ok {
+			diagnostics = append(diagnostics, mapped)
+		}
+	}
+
+	lspInstance.mu.Lock()
+	lspInstance.diagnostics = diagnostics
+	onDiagnostics := lspInstance.onDiagnostics
+	lspInstance.mu.Unlock()
+
+	if onDiagnostics != nil {
+		onDiagnostics(lspInstance, diagnostics)
+	}
+}
+
+// mapDiagnostic translates one diagnostic into block-local coordinates.
+// Diagnostics that touch a separator or preamble line, span more than one
+// block, or carry a code listed in concatArtifactCodes are artifacts of the
+// synthetic document rather than problems in the user's code, so they are
+// filtered out.
+func (lspInstance *Instance) mapDiagnostic(d protocol.Diagnostic) (Diagnostic, bool) {
+	code := diagnosticCode(d.Code)
+	if code != "" && slices.Contains(lspInstance.spec.concatArtifactCodes, code) {
+		return Diagnostic{}, false
+	}
+	blockID, rng, ok := lspInstance.doc.TranslateDocRangeToBlock(d.Range)
+	if !ok {
+		return Diagnostic{}, false
+	}
+	return Diagnostic{
+		BlockID:   blockID,
+		StartLine: int(rng.Start.Line),
+		StartCol:  int(rng.Start.Character),
+		EndLine:   int(rng.End.Line),
+		EndCol:    int(rng.End.Character),
+		Severity:  int(d.Severity),
+		Message:   d.Message,
+		Code:      code,
+		Source:    d.Source,
+	}, true
+}
+
+// diagnosticCode normalizes protocol.Diagnostic.Code, which servers send as
+// either a string or a number.
+func diagnosticCode(code interface{}) string {
+	switch v := code.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 // Shutdown sends the LSP shutdown/exit handshake, then closes the connection
 // and kills the child process if it hasn't exited within a grace period.
 // Safe to call multiple times.
@@ -335,7 +494,7 @@ func (lspInstance *Instance) watchConn() {
 	<-lspInstance.conn.Done()
 	lspInstance.markDone()
 	if err := lspInstance.conn.Err(); err != nil {
-		log.Printf("lsp connection ended (noteID=%s): %v", lspInstance.noteID, err)
+		log.Printf("lsp connection ended (noteID=%s, language=%s): %v", lspInstance.noteID, lspInstance.spec.language, err)
 	}
 }
 
@@ -410,17 +569,17 @@ func (p *procRWC) Close() error {
 	return err2
 }
 
-// drainStderr reads pyright's stderr so the OS pipe buffer does not fill, and
-// logs it: it is the only place that explains why a language server died.
-func drainStderr(stderr io.Reader) {
+// drainStderr reads the server's stderr so the OS pipe buffer does not fill,
+// and logs it: it is the only place that explains why a language server died.
+func drainStderr(binaryName string, stderr io.Reader) {
 	scanner := bufio.NewScanner(stderr)
 	for scanner.Scan() {
-		log.Printf("pyright stderr: %s", scanner.Text())
+		log.Printf("%s stderr: %s", binaryName, scanner.Text())
 	}
 	if err := scanner.Err(); err != nil {
 		// A line past bufio.Scanner's 64 KiB cap stops the scan. Keep draining
-		// so the pipe cannot fill and block pyright's next write.
-		log.Printf("pyright stderr drain stopped: %v", err)
+		// so the pipe cannot fill and block the server's next write.
+		log.Printf("%s stderr drain stopped: %v", binaryName, err)
 		_, _ = io.Copy(io.Discard, stderr)
 	}
 }
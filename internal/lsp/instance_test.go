@@ -32,6 +32,11 @@ type fakeServer struct {
 	hoverContents    string
 	completionErrors atomic.Int32
 	initialized      chan struct{}
+
+	signatureHelp *protocol.SignatureHelp
+	locations     []protocol.Location
+	renameEdit    *protocol.WorkspaceEdit
+	formatEdits   []protocol.TextEdit
 }
 
 func (s *fakeServer) handler(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
@@ -40,7 +45,12 @@ func (s *fakeServer) handler(ctx context.Context, reply jsonrpc2.Replier, req js
 		s.mu.Lock()
 		s.gotInitialize = true
 		s.mu.Unlock()
-		return reply(ctx, &protocol.InitializeResult{}, nil)
+		return reply(ctx, &protocol.InitializeResult{
+			Capabilities: protocol.ServerCapabilities{
+				DocumentFormattingProvider: true,
+				RenameProvider:             map[string]any{"prepareProvider": true},
+			},
+		}, nil)
 
 	case protocol.MethodInitialized:
 		s.mu.Lock()
@@ -92,6 +102,30 @@ func (s *fakeServer) handler(ctx context.Context, reply jsonrpc2.Replier, req js
 			Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: contents},
 		}, nil)
 
+	case protocol.MethodTextDocumentSignatureHelp:
+		s.mu.Lock()
+		help := s.signatureHelp
+		s.mu.Unlock()
+		return reply(ctx, help, nil)
+
+	case protocol.MethodTextDocumentDefinition, protocol.MethodTextDocumentReferences:
+		s.mu.Lock()
+		locations := s.locations
+		s.mu.Unlock()
+		return reply(ctx, locations, nil)
+
+	case protocol.MethodTextDocumentRename:
+		s.mu.Lock()
+		edit := s.renameEdit
+		s.mu.Unlock()
+		return reply(ctx, edit, nil)
+
+	case protocol.MethodTextDocumentFormatting:
+		s.mu.Lock()
+		edits := s.formatEdits
+		s.mu.Unlock()
+		return reply(ctx, edits, nil)
+
 	case protocol.MethodShutdown:
 		s.mu.Lock()
 		s.gotShutdown = true
@@ -130,7 +164,7 @@ func newTestInstance(t *testing.T) (*Instance, *fakeServer, func()) {
 	serverJSONRPC.Go(srvCtx, fake.handler)
 
 	cctx, cancel := context.WithCancel(context.Background())
-	in, err := newInstance(cctx, cancel, "test-note", clientConn)
+	in, err := newInstance(cctx, cancel, "test-note", serverRegistry["python"], clientConn)
 	if err != nil {
 		srvCancel()
 		_ = serverJSONRPC.Close()
@@ -171,6 +205,21 @@ func TestInstanceInitializeHandshake(t *testing.T) {
 	}
 }
 
+func TestInstanceSupportsAdvertisedFeatures(t *testing.T) {
+	in, _, teardown := newTestInstance(t)
+	defer teardown()
+
+	if !in.Supports(FeatureFormatting) {
+		t.Error("expected formatting to be supported")
+	}
+	if !in.Supports(FeatureRename) {
+		t.Error("expected rename options to count as supported")
+	}
+	if in.Supports(FeatureSignatureHelp) {
+		t.Error("expected signature help to be unsupported when not advertised")
+	}
+}
+
 func TestInstanceDidChangeOpensDocOnce(t *testing.T) {
 	in, fake, teardown := newTestInstance(t)
 	defer teardown()
@@ -385,3 +434,88 @@ func TestInstanceServerSideCloseFiresDone(t *testing.T) {
 		t.Fatal("Done() should fire when transport closes")
 	}
 }
+
+func TestInstanceHandleDiagnosticsMapsAndFilters(t *testing.T) {
+	in, _, teardown := newTestInstance(t)
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := in.DidChange(ctx, "a", 0, "x = 1\ny = undefined_a"); err != nil { // doc lines 0,1
+		t.Fatalf("DidChange a: %v", err)
+	}
+	if err := in.DidChange(ctx, "b", 1, "def f(): pass"); err != nil { // doc line 4
+		t.Fatalf("DidChange b: %v", err)
+	}
+
+	var published []Diagnostic
+	in.setDiagnosticsHandler(func(_ *Instance, diagnostics []Diagnostic) {
+		published = diagnostics
+	})
+
+	in.handleDiagnostics(&protocol.PublishDiagnosticsParams{
+		URI:     in.docURI,
+		Version: uint32(in.doc.Version()),
+		Diagnostics: []protocol.Diagnostic{
+			{
+				Range:    protocol.Range{Start: protocol.Position{Line: 1, Character: 4}, End: protocol.Position{Line: 1, Character: 15}},
+				Severity: protocol.DiagnosticSeverityError,
+				Message:  `"undefined_a" is not defined`,
+				Code:     "reportUndefinedVariable",
+				Source:   "Pyright",
+			},
+			// Lands on the separator line between a and b.
+			{Range: protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 3, Character: 5}}},
+			// Spans from block a into block b.
+			{Range: protocol.Range{Start: protocol.Position{Line: 1}, End: protocol.Position{Line: 4, Character: 1}}},
+			// Redeclaration across blocks is a concatenation artifact.
+			{
+				Range: protocol.Range{Start: protocol.Position{Line: 4, Character: 4}, End: protocol.Position{Line: 4, Character: 5}},
+				Code:  "reportRedeclaration",
+			},
+			// Ends at column 0 of the next line: clamped to the end of block b.
+			{Range: protocol.Range{Start: protocol.Position{Line: 4}, End: protocol.Position{Line: 5}}, Code: float64(42)},
+		},
+	})
+
+	got := in.Diagnostics()
+	if len(got) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %+v", len(got), got)
+	}
+	want := Diagnostic{
+		BlockID: "a", StartLine: 1, StartCol: 4, EndLine: 1, EndCol: 15,
+		Severity: 1, Message: `"undefined_a" is not defined`, Code: "reportUndefinedVariable", Source: "Pyright",
+	}
+	if got[0] != want {
+		t.Fatalf("first diagnostic = %+v, want %+v", got[0], want)
+	}
+	if got[1].BlockID != "b" || got[1].EndLine != 0 || got[1].EndCol != 13 || got[1].Code != "42" {
+		t.Fatalf("clamped diagnostic = %+v, want block b ending at (0,13) code 42", got[1])
+	}
+	if len(published) != 2 {
+		t.Fatalf("handler saw %d diagnostics, want 2", len(published))
+	}
+}
+
+func TestInstanceHandleDiagnosticsIgnoresStaleVersion(t *testing.T) {
+	in, _, teardown := newTestInstance(t)
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := in.DidChange(ctx, "a", 0, "x"); err != nil {
+		t.Fatalf("DidChange: %v", err)
+	}
+	if err := in.DidChange(ctx, "a", 0, "x = y"); err != nil {
+		t.Fatalf("DidChange: %v", err)
+	}
+
+	in.handleDiagnostics(&protocol.PublishDiagnosticsParams{
+		URI:         in.docURI,
+		Version:     uint32(in.doc.Version() - 1),
+		Diagnostics: []protocol.Diagnostic{{Message: "stale"}},
+	})
+	if got := in.Diagnostics(); len(got) != 0 {
+		t.Fatalf("stale publish should be dropped, got %+v", got)
+	}
+}
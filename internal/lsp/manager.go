@@ -2,131 +2,172 @@ package lsp
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
-// pyrightBinaryName is the executable the user is expected to have on PATH
-// after running `npm install -g pyright`.
-const pyrightBinaryName = "pyright-langserver"
-
-// lookPathTTL caps how often we re-resolve pyright on PATH when the previous
-// lookup failed. Without this the manager would shell out on every call.
+// lookPathTTL caps how often we re-resolve a language server on PATH when the
+// previous lookup failed. Without this the manager would shell out on every
+// call.
 const lookPathTTL = 30 * time.Second
 
-// ErrNotAvailable is returned by GetOrCreate when pyright is not installed.
+// ErrNotAvailable is returned by GetOrCreate when the language has no
+// registered server or the server is not installed.
 var ErrNotAvailable = errors.New("lsp server not available")
 
-// LspManager owns one Instance per note. v1 supports python only.
+// instanceKey identifies one language server instance. A note with Python and
+// Go blocks runs one pyright and one gopls, each with its own synthetic doc.
+type instanceKey struct {
+	noteID   string
+	language string
+}
+
+// binaryLookup caches the exec.LookPath result for one server binary.
+type binaryLookup struct {
+	// path is empty when unresolved.
+	path       string
+	lastLookup time.Time
+	lastErr    error
+}
+
+// LspManager owns one Instance per (note, language) pair. Supported languages
+// are those in serverRegistry.
 //
-// Each instance is a Pyright child process, so callers should close note
-// instances when editors unmount. The expected steady state is "open notes
-// with Python code blocks", not every note in the project.
+// Each instance is a language-server child process, so callers should close
+// note instances when editors unmount. The expected steady state is "open
+// notes with code blocks", not every note in the project.
 type LspManager struct {
-	// mu protects both the instance map and the cached LookPath result. LookPath
-	// itself is not the reason for the lock; sharing these fields across Wails
-	// RPC goroutines is.
+	// mu protects both the instance map and the cached LookPath results.
+	// LookPath itself is not the reason for the lock; sharing these fields
+	// across Wails RPC goroutines is.
 	mu        sync.Mutex
-	instances map[string]*Instance
+	instances map[instanceKey]*Instance
+
+	// binaries caches LookPath results keyed by binary name.
+	binaries map[string]*binaryLookup
 
-	// binaryPath caches the result of exec.LookPath. Empty when unresolved.
-	binaryPath    string
-	lastLookup    time.Time
-	lastLookupErr error
+	// projectPath roots the on-disk workspaces of servers that keep one.
+	projectPath string
 
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
-// New constructs an LspManager.
-func New() *LspManager {
+// New constructs an LspManager for the project at projectPath. Server binaries
+// are resolved lazily on the first Available call for their language.
+func New(projectPath string) *LspManager {
 	ctx, cancel := context.WithCancel(context.Background())
-	m := &LspManager{
-		instances: map[string]*Instance{},
-		ctx:       ctx,
-		cancel:    cancel,
+	return &LspManager{
+		instances:   map[instanceKey]*Instance{},
+		binaries:    map[string]*binaryLookup{},
+		projectPath: projectPath,
+		ctx:         ctx,
+		cancel:      cancel,
 	}
-	m.resolveBinary()
-	return m
 }
 
-// resolveBinary runs exec.LookPath. Caller must hold m.mu (or call from New
-// before any other goroutine can observe the manager).
-func (m *LspManager) resolveBinary() {
-	path, err := exec.LookPath(pyrightBinaryName)
-	m.binaryPath = path
-	m.lastLookup = time.Now()
-	m.lastLookupErr = err
+// workspaceDir returns the directory a server that keeps a workspace uses for
+// noteID. Each note runs its own server and jdtls locks its workspace, so
+// notes get separate directories; hashing the note id keeps the name a single
+// path segment while reusing the same workspace across restarts.
+func (m *LspManager) workspaceDir(spec serverSpec, noteID string) string {
+	sum := sha256.Sum256([]byte(noteID))
+	return filepath.Join(m.projectPath, "code", ".lsp", spec.binaryName, hex.EncodeToString(sum[:8]))
+}
+
+// resolveBinaryLocked returns the PATH location of binaryName, re-running
+// exec.LookPath when the binary has never been looked up or the last failed
+// lookup is older than lookPathTTL. Caller must hold m.mu.
+func (m *LspManager) resolveBinaryLocked(binaryName string) string {
+	lookup, ok := m.binaries[binaryName]
+	if !ok {
+		lookup = &binaryLookup{}
+		m.binaries[binaryName] = lookup
+	}
+	if lookup.path != "" {
+		return lookup.path
+	}
+	if time.Since(lookup.lastLookup) > lookPathTTL {
+		path, err := exec.LookPath(binaryName)
+		lookup.path = path
+		lookup.lastLookup = time.Now()
+		lookup.lastErr = err
+	}
+	return lookup.path
 }
 
 // Available reports whether the given language has a working LSP backend.
-// v1 only knows about python. The PATH lookup is rechecked on a 30s TTL when
-// the previous resolution failed, so installing pyright mid-session
-// eventually starts working.
+// The PATH lookup is rechecked on a 30s TTL when the previous resolution
+// failed, so installing a server mid-session eventually starts working.
 func (m *LspManager) Available(language string) bool {
-	if language != "python" {
+	spec, ok := specFor(language)
+	if !ok {
 		return false
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.binaryPath != "" {
-		return true
-	}
-	if time.Since(m.lastLookup) > lookPathTTL {
-		m.resolveBinary()
-	}
-	return m.binaryPath != ""
+	return m.resolveBinaryLocked(spec.binaryName) != ""
 }
 
-// GetOrCreate returns the instance for noteID, spawning pyright if necessary.
-// Returns ErrNotAvailable if pyright is not installed.
-func (m *LspManager) GetOrCreate(noteID string) (*Instance, error) {
+// GetOrCreate returns the instance for (noteID, language), spawning the
+// language server if necessary. Returns ErrNotAvailable if the language has
+// no registered server or the server is not installed.
+func (m *LspManager) GetOrCreate(noteID, language string) (*Instance, error) {
 	if noteID == "" {
 		return nil, fmt.Errorf("empty noteID")
 	}
-	if !m.Available("python") {
+	spec, ok := specFor(language)
+	if !ok {
 		return nil, ErrNotAvailable
 	}
+	key := instanceKey{noteID: noteID, language: language}
 
 	m.mu.Lock()
-	if instance, ok := m.instances[noteID]; ok {
+	if instance, ok := m.instances[key]; ok {
 		// Done is a channel so liveness can be checked without racing a bool
 		// guarded by another lock, and reapWhenDone can wait on the same signal.
 		select {
 		case <-instance.Done():
-			delete(m.instances, noteID)
+			delete(m.instances, key)
 		default:
 			m.mu.Unlock()
 			return instance, nil
 		}
 	}
-	binaryPath := m.binaryPath
+	binaryPath := m.resolveBinaryLocked(spec.binaryName)
 	m.mu.Unlock()
+	if binaryPath == "" {
+		return nil, ErrNotAvailable
+	}
 
-	instance, err := spawnInstance(m.ctx, noteID, binaryPath)
+	instance, err := spawnInstance(m.ctx, noteID, spec, binaryPath, m.workspaceDir(spec, noteID))
 	if err != nil {
-		return nil, fmt.Errorf("spawn pyright for note %s: %w", noteID, err)
+		return nil, fmt.Errorf("spawn %s for note %s: %w", spec.binaryName, noteID, err)
 	}
 
 	m.mu.Lock()
-	// The lock is released while spawning Pyright so other note operations are
-	// not blocked on process startup. That means another goroutine may have
-	// installed an instance for this note while we were spawning; keep the first
+	// The lock is released while spawning the server so other note operations
+	// are not blocked on process startup. That means another goroutine may have
+	// installed an instance for this key while we were spawning; keep the first
 	// live one and shut down the duplicate.
-	if existing, ok := m.instances[noteID]; ok {
+	if existing, ok := m.instances[key]; ok {
 		select {
 		case <-existing.Done():
-			delete(m.instances, noteID)
+			delete(m.instances, key)
 		default:
 			m.mu.Unlock()
 			_ = instance.Shutdown()
 			return existing, nil
 		}
 	}
-	m.instances[noteID] = instance
+	instance.setDiagnosticsHandler(emitDiagnostics)
+	m.instances[key] = instance
 	m.mu.Unlock()
 
 	go m.reapWhenDone(instance)
@@ -134,28 +175,57 @@ func (m *LspManager) GetOrCreate(noteID string) (*Instance, error) {
 }
 
 // reapWhenDone removes an instance from the map once its connection ends. The
-// next GetOrCreate for the same noteID will spawn a fresh instance.
+// next GetOrCreate for the same (noteID, language) will spawn a fresh
+// instance.
 func (m *LspManager) reapWhenDone(lspInstance *Instance) {
 	<-lspInstance.Done()
+	key := instanceKey{noteID: lspInstance.NoteID(), language: lspInstance.Language()}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if current, ok := m.instances[lspInstance.NoteID()]; ok && current == lspInstance {
-		delete(m.instances, lspInstance.NoteID())
+	if current, ok := m.instances[key]; ok && current == lspInstance {
+		delete(m.instances, key)
 	}
 }
 
-// ShutdownNote shuts down the LSP instance for a note, if one exists.
+// ShutdownNote shuts down every LSP instance for a note, across languages.
 func (m *LspManager) ShutdownNote(noteID string) error {
 	m.mu.Lock()
-	instance, ok := m.instances[noteID]
-	if ok {
-		delete(m.instances, noteID)
+	var insts []*Instance
+	for key, instance := range m.instances {
+		if key.noteID == noteID {
+			insts = append(insts, instance)
+			delete(m.instances, key)
+		}
 	}
 	m.mu.Unlock()
-	if !ok {
-		return nil
+
+	var errs []error
+	for _, instance := range insts {
+		if err := instance.Shutdown(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Diagnostics returns the latest diagnostics from every live instance for a
+// note, across languages. Block IDs are unique within a note, so callers can
+// group the result by BlockID without knowing which server produced it.
+func (m *LspManager) Diagnostics(noteID string) []Diagnostic {
+	m.mu.Lock()
+	var insts []*Instance
+	for key, instance := range m.instances {
+		if key.noteID == noteID {
+			insts = append(insts, instance)
+		}
+	}
+	m.mu.Unlock()
+
+	diagnostics := []Diagnostic{}
+	for _, instance := range insts {
+		diagnostics = append(diagnostics, instance.Diagnostics()...)
 	}
-	return instance.Shutdown()
+	return diagnostics
 }
 
 // ShutdownAll terminates every running instance. Called at app exit.
@@ -165,7 +235,7 @@ func (m *LspManager) ShutdownAll() {
 	for _, lspInstance := range m.instances {
 		insts = append(insts, lspInstance)
 	}
-	m.instances = map[string]*Instance{}
+	m.instances = map[instanceKey]*Instance{}
 	m.mu.Unlock()
 
 	for _, lspInstance := range insts {
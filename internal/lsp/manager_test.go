@@ -2,88 +2,134 @@ package lsp
 
 import (
 	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 )
 
+// stubBinary forces the cached LookPath result for a server binary. A recent
+// lastLookup keeps the TTL from re-resolving during the test.
+func stubBinary(m *LspManager, binaryName, path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var err error
+	if path == "" {
+		err = errors.New("not found")
+	}
+	m.binaries[binaryName] = &binaryLookup{path: path, lastLookup: time.Now(), lastErr: err}
+}
+
 // TestManagerAvailableWhenBinaryMissing forces a missing-binary state and
-// asserts Available reports false. Achieves this by stubbing binaryPath to ""
-// and using a fresh-enough lastLookup that the TTL doesn't re-resolve.
+// asserts Available reports false.
 func TestManagerAvailableWhenBinaryMissing(t *testing.T) {
-	m := New()
+	m := New(t.TempDir())
 	t.Cleanup(m.ShutdownAll)
 
-	m.mu.Lock()
-	m.binaryPath = ""
-	m.lastLookup = time.Now() // recent — TTL skip
-	m.lastLookupErr = errors.New("not found")
-	m.mu.Unlock()
+	stubBinary(m, "pyright-langserver", "")
+	stubBinary(m, "gopls", "")
 
 	if m.Available("python") {
-		t.Fatal("Available(python) = true with empty binaryPath, want false")
+		t.Fatal("Available(python) = true with empty binary path, want false")
 	}
 	if m.Available("go") {
-		t.Fatal("Available(go) = true, want false (v1 supports python only)")
+		t.Fatal("Available(go) = true with empty binary path, want false")
 	}
 }
 
 func TestManagerAvailableLanguageGating(t *testing.T) {
-	m := New()
+	m := New(t.TempDir())
 	t.Cleanup(m.ShutdownAll)
 
-	// Force binary present so Available depends only on language.
-	m.mu.Lock()
-	m.binaryPath = "/fake/pyright"
-	m.lastLookup = time.Now()
-	m.lastLookupErr = nil
-	m.mu.Unlock()
+	// Force pyright present and gopls missing so Available depends only on
+	// each language's own server.
+	stubBinary(m, "pyright-langserver", "/fake/pyright")
+	stubBinary(m, "gopls", "")
 
 	if !m.Available("python") {
 		t.Fatal("Available(python) = false, want true")
 	}
-	if m.Available("go") || m.Available("java") || m.Available("javascript") {
-		t.Fatal("v1 should not advertise availability for non-python languages")
+	if m.Available("go") {
+		t.Fatal("Available(go) = true with gopls missing, want false")
+	}
+	if m.Available("text") || m.Available("") {
+		t.Fatal("languages without a registered server must not be available")
+	}
+}
+
+func TestManagerAvailableJavaScriptOnly(t *testing.T) {
+	m := New(t.TempDir())
+	t.Cleanup(m.ShutdownAll)
+
+	stubBinary(m, "typescript-language-server", "/fake/tsserver")
+
+	if !m.Available("javascript") {
+		t.Fatal("Available(javascript) = false with typescript-language-server stubbed")
+	}
+	if m.Available("typescript") {
+		t.Fatal("Available(typescript) = true, but no code block uses typescript")
+	}
+}
+
+func TestManagerWorkspaceDir(t *testing.T) {
+	projectPath := t.TempDir()
+	m := New(projectPath)
+	t.Cleanup(m.ShutdownAll)
+
+	spec, _ := specFor("java")
+	dir := m.workspaceDir(spec, "folder/note.md")
+	if filepath.Dir(dir) != filepath.Join(projectPath, "code", ".lsp", "jdtls") {
+		t.Fatalf("workspaceDir = %q, want it under code/.lsp/jdtls", dir)
+	}
+	if dir != m.workspaceDir(spec, "folder/note.md") {
+		t.Fatal("workspaceDir should be stable for a note")
+	}
+	if dir == m.workspaceDir(spec, "folder/other.md") {
+		t.Fatal("workspaceDir should differ between notes")
 	}
 }
 
 func TestManagerGetOrCreateRejectsWhenUnavailable(t *testing.T) {
-	m := New()
+	m := New(t.TempDir())
 	t.Cleanup(m.ShutdownAll)
 
-	m.mu.Lock()
-	m.binaryPath = ""
-	m.lastLookup = time.Now()
-	m.mu.Unlock()
+	stubBinary(m, "pyright-langserver", "")
 
-	_, err := m.GetOrCreate("note-x")
+	_, err := m.GetOrCreate("note-x", "python")
+	if !errors.Is(err, ErrNotAvailable) {
+		t.Fatalf("GetOrCreate err = %v, want ErrNotAvailable", err)
+	}
+}
+
+func TestManagerGetOrCreateRejectsUnknownLanguage(t *testing.T) {
+	m := New(t.TempDir())
+	t.Cleanup(m.ShutdownAll)
+
+	_, err := m.GetOrCreate("note-x", "text")
 	if !errors.Is(err, ErrNotAvailable) {
 		t.Fatalf("GetOrCreate err = %v, want ErrNotAvailable", err)
 	}
 }
 
 func TestManagerGetOrCreateRejectsEmptyNoteID(t *testing.T) {
-	m := New()
+	m := New(t.TempDir())
 	t.Cleanup(m.ShutdownAll)
 
 	// Even with binary "available", empty noteID must fail.
-	m.mu.Lock()
-	m.binaryPath = "/fake/pyright"
-	m.lastLookup = time.Now()
-	m.mu.Unlock()
+	stubBinary(m, "pyright-langserver", "/fake/pyright")
 
-	if _, err := m.GetOrCreate(""); err == nil {
+	if _, err := m.GetOrCreate("", "python"); err == nil {
 		t.Fatal("GetOrCreate(\"\") returned nil err, want non-nil")
 	}
 }
 
 func TestManagerShutdownAllSafeWhenEmpty(t *testing.T) {
-	m := New()
+	m := New(t.TempDir())
 	// Should not panic / block.
 	m.ShutdownAll()
 }
 
 func TestManagerShutdownNoteUnknownIsNoop(t *testing.T) {
-	m := New()
+	m := New(t.TempDir())
 	t.Cleanup(m.ShutdownAll)
 
 	if err := m.ShutdownNote("never-existed"); err != nil {
@@ -0,0 +1,161 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+
+	"go.lsp.dev/protocol"
+)
+
+// positionParams syncs the document open state and translates a block-local
+// position into the synthetic-doc request params shared by every
+// position-based LSP request.
+func (lspInstance *Instance) positionParams(ctx context.Context, blockID string, pos protocol.Position) (protocol.TextDocumentPositionParams, error) {
+	if err := lspInstance.checkAlive(); err != nil {
+		return protocol.TextDocumentPositionParams{}, err
+	}
+	if err := lspInstance.didOpenIfNeeded(ctx); err != nil {
+		return protocol.TextDocumentPositionParams{}, err
+	}
+	docPos, ok := lspInstance.doc.TranslateBlockToDoc(blockID, pos)
+	if !ok {
+		return protocol.TextDocumentPositionParams{}, fmt.Errorf("block %s not in virtual doc", blockID)
+	}
+	return protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: lspInstance.docURI},
+		Position:     docPos,
+	}, nil
+}
+
+// SignatureHelp asks the server for call signatures at (blockID, pos).
+// Returns nil when the cursor is not inside a call.
+func (lspInstance *Instance) SignatureHelp(ctx context.Context, blockID string, pos protocol.Position) (*SignatureHelp, error) {
+	params, err := lspInstance.positionParams(ctx, blockID, pos)
+	if err != nil {
+		return nil, err
+	}
+	help, err := lspInstance.srv.SignatureHelp(ctx, &protocol.SignatureHelpParams{TextDocumentPositionParams: params})
+	if err != nil {
+		return nil, err
+	}
+	if help == nil || len(help.Signatures) == 0 {
+		return nil, nil
+	}
+	out := &SignatureHelp{
+		Signatures:      make([]SignatureInformation, 0, len(help.Signatures)),
+		ActiveSignature: int(help.ActiveSignature),
+		ActiveParameter: int(help.ActiveParameter),
+	}
+	for _, sig := range help.Signatures {
+		params := make([]string, 0, len(sig.Parameters))
+		for _, p := range sig.Parameters {
+			params = append(params, p.Label)
+		}
+		out.Signatures = append(out.Signatures, SignatureInformation{
+			Label:         sig.Label,
+			Documentation: docToString(sig.Documentation),
+			Parameters:    params,
+		})
+	}
+	return out, nil
+}
+
+// Definition resolves the symbol at (blockID, pos) to its definition. The
+// result may point into a different block of the same note.
+func (lspInstance *Instance) Definition(ctx context.Context, blockID string, pos protocol.Position) ([]Location, error) {
+	params, err := lspInstance.positionParams(ctx, blockID, pos)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := lspInstance.srv.Definition(ctx, &protocol.DefinitionParams{TextDocumentPositionParams: params})
+	if err != nil {
+		return nil, err
+	}
+	return lspInstance.blockLocations(locations), nil
+}
+
+// References finds every use of the symbol at (blockID, pos) across the
+// note's blocks.
+func (lspInstance *Instance) References(ctx context.Context, blockID string, pos protocol.Position, includeDeclaration bool) ([]Location, error) {
+	params, err := lspInstance.positionParams(ctx, blockID, pos)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := lspInstance.srv.References(ctx, &protocol.ReferenceParams{
+		TextDocumentPositionParams: params,
+		Context:                    protocol.ReferenceContext{IncludeDeclaration: includeDeclaration},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lspInstance.blockLocations(locations), nil
+}
+
+// blockLocations keeps the locations inside this instance's synthetic doc and
+// maps them back to blocks.
+func (lspInstance *Instance) blockLocations(locations []protocol.Location) []Location {
+	out := make([]Location, 0, len(locations))
+	for _, loc := range locations {
+		if loc.URI != protocol.DocumentURI(lspInstance.docURI) {
+			continue
+		}
+		blockID, rng, ok := lspInstance.doc.TranslateDocRangeToBlock(loc.Range)
+		if !ok {
+			continue
+		}
+		out = append(out, Location{
+			BlockID:   blockID,
+			StartLine: int(rng.Start.Line),
+			StartCol:  int(rng.Start.Character),
+			EndLine:   int(rng.End.Line),
+			EndCol:    int(rng.End.Character),
+		})
+	}
+	return out
+}
+
+// Rename renames the symbol at (blockID, pos) to newName and returns the
+// edits per block. Edits to files outside the note are ignored. An edit that
+// cannot be mapped onto a single block fails the whole rename, since applying
+// only part of a rename would leave the note inconsistent.
+func (lspInstance *Instance) Rename(ctx context.Context, blockID string, pos protocol.Position, newName string) ([]TextEdit, error) {
+	params, err := lspInstance.positionParams(ctx, blockID, pos)
+	if err != nil {
+		return nil, err
+	}
+	workspaceEdit, err := lspInstance.srv.Rename(ctx, &protocol.RenameParams{
+		TextDocumentPositionParams: params,
+		NewName:                    newName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if workspaceEdit == nil {
+		return nil, nil
+	}
+
+	docURI := protocol.DocumentURI(lspInstance.docURI)
+	edits := append([]protocol.TextEdit(nil), workspaceEdit.Changes[docURI]...)
+	for _, change := range workspaceEdit.DocumentChanges {
+		if change.TextDocument.URI == docURI {
+			edits = append(edits, change.Edits...)
+		}
+	}
+
+	out := make([]TextEdit, 0, len(edits))
+	for _, edit := range edits {
+		editBlockID, rng, ok := lspInstance.doc.TranslateDocRangeToBlock(edit.Range)
+		if !ok {
+			return nil, fmt.Errorf("rename edit at line %d does not map to a block", edit.Range.Start.Line)
+		}
+		out = append(out, TextEdit{
+			BlockID:   editBlockID,
+			StartLine: int(rng.Start.Line),
+			StartCol:  int(rng.Start.Character),
+			EndLine:   int(rng.End.Line),
+			EndCol:    int(rng.End.Character),
+			NewText:   edit.NewText,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,181 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// seedTwoBlocks loads two Python blocks into the instance's doc: block a on
+// doc lines 0-1 and block b on doc line 4.
+func seedTwoBlocks(t *testing.T, in *Instance) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := in.DidChange(ctx, "a", 0, "def area(r):\n    return r * r"); err != nil {
+		t.Fatalf("DidChange a: %v", err)
+	}
+	if err := in.DidChange(ctx, "b", 1, "area(2)"); err != nil {
+		t.Fatalf("DidChange b: %v", err)
+	}
+}
+
+func TestInstanceSignatureHelp(t *testing.T) {
+	in, fake, teardown := newTestInstance(t)
+	defer teardown()
+	seedTwoBlocks(t, in)
+
+	fake.mu.Lock()
+	fake.signatureHelp = &protocol.SignatureHelp{
+		Signatures: []protocol.SignatureInformation{{
+			Label:         "area(r)",
+			Documentation: "Square of r.",
+			Parameters:    []protocol.ParameterInformation{{Label: "r"}},
+		}},
+		ActiveParameter: 0,
+	}
+	fake.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	help, err := in.SignatureHelp(ctx, "b", protocol.Position{Line: 0, Character: 5})
+	if err != nil {
+		t.Fatalf("SignatureHelp: %v", err)
+	}
+	if help == nil || len(help.Signatures) != 1 {
+		t.Fatalf("help = %+v, want one signature", help)
+	}
+	sig := help.Signatures[0]
+	if sig.Label != "area(r)" || sig.Documentation != "Square of r." || len(sig.Parameters) != 1 || sig.Parameters[0] != "r" {
+		t.Fatalf("signature = %+v", sig)
+	}
+}
+
+func TestInstanceSignatureHelpOutsideCall(t *testing.T) {
+	in, _, teardown := newTestInstance(t)
+	defer teardown()
+	seedTwoBlocks(t, in)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	help, err := in.SignatureHelp(ctx, "b", protocol.Position{})
+	if err != nil {
+		t.Fatalf("SignatureHelp: %v", err)
+	}
+	if help != nil {
+		t.Fatalf("help = %+v, want nil", help)
+	}
+}
+
+func TestInstanceDefinitionResolvesToOtherBlock(t *testing.T) {
+	in, fake, teardown := newTestInstance(t)
+	defer teardown()
+	seedTwoBlocks(t, in)
+
+	docURI := protocol.DocumentURI(in.docURI)
+	fake.mu.Lock()
+	fake.locations = []protocol.Location{
+		{URI: docURI, Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 4}, End: protocol.Position{Line: 0, Character: 8}}},
+		// Library source outside the note is dropped.
+		{URI: "file:///usr/lib/python3/builtins.pyi", Range: protocol.Range{}},
+	}
+	fake.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	locations, err := in.Definition(ctx, "b", protocol.Position{Line: 0, Character: 1})
+	if err != nil {
+		t.Fatalf("Definition: %v", err)
+	}
+	want := Location{BlockID: "a", StartLine: 0, StartCol: 4, EndLine: 0, EndCol: 8}
+	if len(locations) != 1 || locations[0] != want {
+		t.Fatalf("locations = %+v, want [%+v]", locations, want)
+	}
+}
+
+func TestInstanceReferencesAcrossBlocks(t *testing.T) {
+	in, fake, teardown := newTestInstance(t)
+	defer teardown()
+	seedTwoBlocks(t, in)
+
+	docURI := protocol.DocumentURI(in.docURI)
+	fake.mu.Lock()
+	fake.locations = []protocol.Location{
+		{URI: docURI, Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 4}, End: protocol.Position{Line: 0, Character: 8}}},
+		{URI: docURI, Range: protocol.Range{Start: protocol.Position{Line: 4, Character: 0}, End: protocol.Position{Line: 4, Character: 4}}},
+	}
+	fake.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	locations, err := in.References(ctx, "a", protocol.Position{Line: 0, Character: 5}, true)
+	if err != nil {
+		t.Fatalf("References: %v", err)
+	}
+	if len(locations) != 2 || locations[0].BlockID != "a" || locations[1].BlockID != "b" {
+		t.Fatalf("locations = %+v, want one in a and one in b", locations)
+	}
+}
+
+func TestInstanceRenameAcrossBlocks(t *testing.T) {
+	in, fake, teardown := newTestInstance(t)
+	defer teardown()
+	seedTwoBlocks(t, in)
+
+	docURI := protocol.DocumentURI(in.docURI)
+	fake.mu.Lock()
+	fake.renameEdit = &protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			docURI: {
+				{Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 4}, End: protocol.Position{Line: 0, Character: 8}}, NewText: "square"},
+			},
+		},
+		DocumentChanges: []protocol.TextDocumentEdit{{
+			TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: in.docURI},
+			},
+			Edits: []protocol.TextEdit{
+				{Range: protocol.Range{Start: protocol.Position{Line: 4, Character: 0}, End: protocol.Position{Line: 4, Character: 4}}, NewText: "square"},
+			},
+		}},
+	}
+	fake.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	edits, err := in.Rename(ctx, "a", protocol.Position{Line: 0, Character: 5}, "square")
+	if err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	want := []TextEdit{
+		{BlockID: "a", StartLine: 0, StartCol: 4, EndLine: 0, EndCol: 8, NewText: "square"},
+		{BlockID: "b", StartLine: 0, StartCol: 0, EndLine: 0, EndCol: 4, NewText: "square"},
+	}
+	if len(edits) != len(want) || edits[0] != want[0] || edits[1] != want[1] {
+		t.Fatalf("edits = %+v, want %+v", edits, want)
+	}
+}
+
+func TestInstanceRenameRejectsSeparatorEdit(t *testing.T) {
+	in, fake, teardown := newTestInstance(t)
+	defer teardown()
+	seedTwoBlocks(t, in)
+
+	fake.mu.Lock()
+	fake.renameEdit = &protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			protocol.DocumentURI(in.docURI): {
+				{Range: protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 3, Character: 1}}, NewText: "x"},
+			},
+		},
+	}
+	fake.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := in.Rename(ctx, "a", protocol.Position{}, "x"); err == nil {
+		t.Fatal("Rename touching a separator line should fail")
+	}
+}
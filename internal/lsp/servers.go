@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"go.lsp.dev/protocol"
+)
+
+// docDialect captures the per-language details of the synthetic document a
+// language server sees: how separators are commented out, which file
+// extension the document URI carries, and any preamble the server needs
+// before the first block can be parsed.
+type docDialect struct {
+	// commentPrefix starts a line comment, e.g. "#" or "//". Separators are
+	// written as comments so the server never treats them as code.
+	commentPrefix string
+	// extension is appended to the synthetic document path. Several servers
+	// pick their parser from it, so it must match the block language.
+	extension string
+	// preamble is written before the first block. It must be empty or end in a
+	// newline so block offsets stay line-aligned.
+	preamble string
+}
+
+// serverSpec describes how to launch the language server for one code-block
+// language and how to build the document it sees.
+type serverSpec struct {
+	language   string
+	binaryName string
+	args       []string
+	languageID protocol.LanguageIdentifier
+	dialect    docDialect
+	// concatArtifactCodes lists diagnostic codes that only fire because blocks
+	// are concatenated into one document, such as redeclaring a name in a
+	// later block, which is normal when re-running notebook cells. These are
+	// dropped before diagnostics reach the editor.
+	concatArtifactCodes []string
+	// workspaceFlag, when set, is passed with a per-note directory under the
+	// project's code/.lsp folder for servers that keep a workspace on disk.
+	workspaceFlag string
+}
+
+// serverRegistry maps a code-block language to its language server. The
+// binaries are expected on PATH; none of them are bundled with the app.
+var serverRegistry = map[string]serverSpec{
+	"python": {
+		language:            "python",
+		binaryName:          "pyright-langserver",
+		args:                []string{"--stdio"},
+		languageID:          protocol.PythonLanguage,
+		dialect:             docDialect{commentPrefix: "#", extension: ".py"},
+		concatArtifactCodes: []string{"reportRedeclaration"},
+	},
+	"go": {
+		language:   "go",
+		binaryName: "gopls",
+		args:       []string{"serve"},
+		languageID: protocol.GoLanguage,
+		// gopls refuses to analyze a file without a package clause. Go kernels
+		// wrap block code in package main themselves, so mirror that here.
+		dialect:             docDialect{commentPrefix: "//", extension: ".go", preamble: "package main\n\n"},
+		concatArtifactCodes: []string{"DuplicateDecl"},
+	},
+	"javascript": {
+		language:   "javascript",
+		binaryName: "typescript-language-server",
+		args:       []string{"--stdio"},
+		languageID: protocol.JavaScriptLanguage,
+		dialect:    docDialect{commentPrefix: "//", extension: ".js"},
+		// 2451: "Cannot redeclare block-scoped variable".
+		concatArtifactCodes: []string{"2451"},
+	},
+	"java": {
+		language:   "java",
+		binaryName: "jdtls",
+		languageID: protocol.JavaLanguage,
+		// Java kernels accept top-level statements, so there is no class
+		// wrapper; jdtls still offers completions for a standalone file.
+		dialect: docDialect{commentPrefix: "//", extension: ".java"},
+		// Without -data jdtls shares one workspace across every project it
+		// is started for.
+		workspaceFlag: "-data",
+	},
+}
+
+// specFor returns the server spec for a code-block language.
+func specFor(language string) (serverSpec, bool) {
+	spec, ok := serverRegistry[language]
+	return spec, ok
+}
@@ -1,6 +1,7 @@
 // Package lsp implements an LSP client supervisor used to add IntelliSense
-// (completion + hover) to code blocks. v1 is python-only via pyright;
-// the design accommodates additional languages in future PRs.
+// (completion + hover) to code blocks. Each supported language maps to a
+// language server in serverRegistry: pyright for Python, gopls for Go,
+// typescript-language-server for JavaScript and jdtls for Java.
 package lsp
 
 // CompletionItem is the slim wire-format completion item sent to the frontend.
@@ -21,7 +22,7 @@ type CompletionItem struct {
 }
 
 // CompletionResult is the response sent to the frontend. Available=false means
-// pyright is not installed or otherwise unreachable; the frontend should
+// the language server is not installed or otherwise unreachable; the frontend should
 // surface the install banner and fall back to kernel-only completions.
 type CompletionResult struct {
 	Available bool             `json:"available"`
@@ -35,3 +36,104 @@ type HoverResult struct {
 	Found     bool   `json:"found"`
 	Contents  string `json:"contents"`
 }
+
+// Diagnostic is one server diagnostic mapped into block-local coordinates so
+// the editor can underline it inside the owning code block.
+type Diagnostic struct {
+	BlockID   string `json:"blockId"`
+	StartLine int    `json:"startLine"`
+	StartCol  int    `json:"startCol"`
+	EndLine   int    `json:"endLine"`
+	EndCol    int    `json:"endCol"`
+	// Severity mirrors protocol.DiagnosticSeverity (1=Error, 2=Warning,
+	// 3=Information, 4=Hint).
+	Severity int    `json:"severity"`
+	Message  string `json:"message"`
+	Code     string `json:"code,omitempty"`
+	// Source names the tool that produced the diagnostic, e.g. "Pyright".
+	Source string `json:"source,omitempty"`
+}
+
+// DiagnosticsResult is the response to GetDiagnostics. Diagnostics holds the
+// latest set published for every language server running for the note.
+type DiagnosticsResult struct {
+	Available   bool         `json:"available"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// DiagnosticsEventData is the payload of the lsp:diagnostics event, emitted
+// each time a language server publishes diagnostics for a note. It replaces
+// every diagnostic previously reported for (NoteID, Language).
+type DiagnosticsEventData struct {
+	NoteID      string       `json:"noteId"`
+	Language    string       `json:"language"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Location is a block-local range inside the same note. Definition and
+// references results that resolve outside the note (library sources, other
+// files) are dropped, since the editor has nowhere to show them.
+type Location struct {
+	BlockID   string `json:"blockId"`
+	StartLine int    `json:"startLine"`
+	StartCol  int    `json:"startCol"`
+	EndLine   int    `json:"endLine"`
+	EndCol    int    `json:"endCol"`
+}
+
+// LocationsResult carries definition or references results.
+type LocationsResult struct {
+	Available bool       `json:"available"`
+	Locations []Location `json:"locations"`
+}
+
+// SignatureInformation is one overload offered by signature help.
+type SignatureInformation struct {
+	Label         string   `json:"label"`
+	Documentation string   `json:"documentation,omitempty"`
+	Parameters    []string `json:"parameters"`
+}
+
+// SignatureHelp is the slim form of protocol.SignatureHelp.
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+// SignatureHelpResult is the response to a signature help request. Found is
+// false when the cursor is not inside a call.
+type SignatureHelpResult struct {
+	Available bool          `json:"available"`
+	Found     bool          `json:"found"`
+	Help      SignatureHelp `json:"help"`
+}
+
+// TextEdit replaces a block-local range with NewText.
+type TextEdit struct {
+	BlockID   string `json:"blockId"`
+	StartLine int    `json:"startLine"`
+	StartCol  int    `json:"startCol"`
+	EndLine   int    `json:"endLine"`
+	EndCol    int    `json:"endCol"`
+	NewText   string `json:"newText"`
+}
+
+// RenameResult carries the edits for a rename, possibly spanning several
+// blocks of the note.
+type RenameResult struct {
+	Available bool       `json:"available"`
+	Edits     []TextEdit `json:"edits"`
+}
+
+// FormattedBlock is the new source for one block after document formatting.
+type FormattedBlock struct {
+	BlockID string `json:"blockId"`
+	Source  string `json:"source"`
+}
+
+// FormatResult lists the blocks whose source changed after formatting.
+type FormatResult struct {
+	Available bool             `json:"available"`
+	Blocks    []FormattedBlock `json:"blocks"`
+}
@@ -5,15 +5,17 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"unicode/utf16"
 
 	"go.lsp.dev/protocol"
 )
 
-// blockSeparatorPrefix is the start of each block-separator line in the
-// synthetic document. The full separator is built by appending the block
-// order, e.g. "# --- bytebook block 2 ---\n". Using a Python comment keeps
-// pyright from treating the separator as code.
-const blockSeparatorPrefix = "# --- bytebook block "
+// blockSeparatorMarker follows the dialect's comment prefix on each
+// block-separator line. The full separator is built by appending the block
+// order, e.g. "# --- bytebook block 2 ---\n" for Python or
+// "// --- bytebook block 2 ---\n" for Go. Writing it as a comment keeps the
+// language server from treating the separator as code.
+const blockSeparatorMarker = " --- bytebook block "
 
 // blockEntry tracks one code block's source plus its position within the
 // concatenated synthetic document.
@@ -25,8 +27,8 @@ type blockEntry struct {
 	lineCount int // number of newline-terminated lines in source (counting a trailing line if source doesn't end in \n)
 }
 
-// VirtualDoc maintains the synthetic per-note Python document that pyright
-// sees. Blocks are ordered by their `order` value (the Lexical node index).
+// VirtualDoc maintains the synthetic per-note, per-language document that a
+// language server sees. Blocks are ordered by their `order` value (the Lexical node index).
 // Coordinate translation between (blockID, position) and synthetic-doc
 // position lives here.
 //
@@ -60,6 +62,9 @@ type blockEntry struct {
 // This lets completions in block-b see names from block-a. For example,
 // block-b line 0 maps to synthetic document line 4 because the separator
 // between block-a and block-b occupies two lines.
+//
+// Languages with a preamble (Go's "package main") get it written before the
+// first block, and every block offset shifts down by the preamble's lines.
 type VirtualDoc struct {
 	// Completion, hover, and edit notifications can arrive concurrently from
 	// separate Wails RPCs. Protect the block map, sorted slice, offsets, and
@@ -68,13 +73,21 @@ type VirtualDoc struct {
 	blocks  []*blockEntry
 	byID    map[string]*blockEntry
 	version int32
+
+	dialect docDialect
 }
 
-// NewVirtualDoc returns an empty VirtualDoc.
-func NewVirtualDoc() *VirtualDoc {
+// NewVirtualDoc returns an empty VirtualDoc for the given code-block
+// language. Unknown languages fall back to the Python dialect.
+func NewVirtualDoc(language string) *VirtualDoc {
+	spec, ok := specFor(language)
+	if !ok {
+		spec = serverRegistry["python"]
+	}
 	return &VirtualDoc{
-		blocks: nil,
-		byID:   map[string]*blockEntry{},
+		blocks:  nil,
+		byID:    map[string]*blockEntry{},
+		dialect: spec.dialect,
 	}
 }
 
@@ -131,10 +144,10 @@ func (v *VirtualDoc) recomputeOffsetsLocked() {
 	sort.SliceStable(v.blocks, func(i, j int) bool {
 		return v.blocks[i].order < v.blocks[j].order
 	})
-	line := 0
+	line := strings.Count(v.dialect.preamble, "\n")
 	for i, b := range v.blocks {
 		if i > 0 {
-			// Separator: "\n# --- bytebook block N ---\n" inserted between blocks.
+			// Separator: "\n<comment> --- bytebook block N ---\n" inserted between blocks.
 			// The leading \n closes the previous block (whether or not its source
 			// ended with one); the separator line itself adds one line; the
 			// trailing \n moves us to the start of the next block's first line.
@@ -170,6 +183,7 @@ func (v *VirtualDoc) FullText() string {
 	defer v.mu.Unlock()
 
 	var b strings.Builder
+	b.WriteString(v.dialect.preamble)
 	for i, blk := range v.blocks {
 		if i > 0 {
 			// Close previous block (always end with newline before separator)
@@ -180,10 +194,10 @@ func (v *VirtualDoc) FullText() string {
 			// Add the blank line so block source starts cleanly:
 			//   prev_source\n
 			//   \n                       <- blank line (the "\n" we add here)
-			//   # --- bytebook block N ---\n
+			//   <comment> --- bytebook block N ---\n
 			//   next_source
 			b.WriteByte('\n')
-			fmt.Fprintf(&b, "%s%d ---\n", blockSeparatorPrefix, blk.order)
+			fmt.Fprintf(&b, "%s%s%d ---\n", v.dialect.commentPrefix, blockSeparatorMarker, blk.order)
 		}
 		b.WriteString(blk.source)
 	}
@@ -226,6 +240,49 @@ func (v *VirtualDoc) TranslateDocToBlock(pos protocol.Position) (string, protoco
 	return "", protocol.Position{}, false
 }
 
+// TranslateDocRangeToBlock converts a synthetic-doc range into a block-local
+// range. Both ends must land in the same block; a range that ends at column 0
+// of the line just past the block (how servers commonly mark "to end of
+// line") is clamped to the end of the block's last line. Returns ok=false
+// for ranges that start on a separator or preamble line or cross into
+// another block.
+func (v *VirtualDoc) TranslateDocRangeToBlock(rng protocol.Range) (string, protocol.Range, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, b := range v.blocks {
+		start := uint32(b.startLine)
+		end := start + uint32(b.lineCount) // exclusive
+		if rng.Start.Line < start || rng.Start.Line >= end {
+			continue
+		}
+		local := protocol.Range{
+			Start: protocol.Position{Line: rng.Start.Line - start, Character: rng.Start.Character},
+		}
+		switch {
+		case rng.End.Line < end:
+			local.End = protocol.Position{Line: rng.End.Line - start, Character: rng.End.Character}
+		case rng.End.Line == end && rng.End.Character == 0:
+			lastLine := uint32(b.lineCount - 1)
+			local.End = protocol.Position{Line: lastLine, Character: lineLength(b.source, int(lastLine))}
+		default:
+			return "", protocol.Range{}, false
+		}
+		return b.blockID, local, true
+	}
+	return "", protocol.Range{}, false
+}
+
+// lineLength returns the length of line n of s in UTF-16 code units, which is
+// how LSP counts characters by default.
+func lineLength(s string, n int) uint32 {
+	lines := strings.Split(s, "\n")
+	if n < 0 || n >= len(lines) {
+		return 0
+	}
+	return uint32(len(utf16.Encode([]rune(lines[n]))))
+}
+
 // blockCount returns the number of blocks tracked. Test helper.
 func (v *VirtualDoc) blockCount() int {
 	v.mu.Lock()
@@ -8,7 +8,7 @@ import (
 )
 
 func TestVirtualDocSingleBlock(t *testing.T) {
-	v := NewVirtualDoc()
+	v := NewVirtualDoc("python")
 	v.UpsertBlock("a", 0, "x = 1\ny = 2")
 
 	got := v.FullText()
@@ -30,7 +30,7 @@ func TestVirtualDocSingleBlock(t *testing.T) {
 }
 
 func TestVirtualDocMultipleBlocksOffsets(t *testing.T) {
-	v := NewVirtualDoc()
+	v := NewVirtualDoc("python")
 	v.UpsertBlock("a", 0, "a = 1\nb = 2") // 2 lines
 	v.UpsertBlock("b", 1, "c = 3")        // 1 line
 	v.UpsertBlock("c", 2, "d = 4\ne = 5") // 2 lines
@@ -62,7 +62,7 @@ func TestVirtualDocMultipleBlocksOffsets(t *testing.T) {
 }
 
 func TestVirtualDocOrderingNotInsertionOrder(t *testing.T) {
-	v := NewVirtualDoc()
+	v := NewVirtualDoc("python")
 	v.UpsertBlock("late", 5, "late_var = 1")
 	v.UpsertBlock("early", 1, "early_var = 1")
 
@@ -78,7 +78,7 @@ func TestVirtualDocOrderingNotInsertionOrder(t *testing.T) {
 }
 
 func TestVirtualDocUpsertReplacesContent(t *testing.T) {
-	v := NewVirtualDoc()
+	v := NewVirtualDoc("python")
 	v.UpsertBlock("a", 0, "old")
 	v.UpsertBlock("a", 0, "new")
 	if got := v.FullText(); got != "new" {
@@ -90,7 +90,7 @@ func TestVirtualDocUpsertReplacesContent(t *testing.T) {
 }
 
 func TestVirtualDocVersionBumpsOnChange(t *testing.T) {
-	v := NewVirtualDoc()
+	v := NewVirtualDoc("python")
 	v0 := v.Version()
 	v.UpsertBlock("a", 0, "x")
 	v1 := v.Version()
@@ -105,7 +105,7 @@ func TestVirtualDocVersionBumpsOnChange(t *testing.T) {
 }
 
 func TestVirtualDocRemoveBlock(t *testing.T) {
-	v := NewVirtualDoc()
+	v := NewVirtualDoc("python")
 	v.UpsertBlock("a", 0, "a = 1")
 	v.UpsertBlock("b", 1, "b = 2")
 	v.RemoveBlock("a")
@@ -128,7 +128,7 @@ func TestVirtualDocRemoveBlock(t *testing.T) {
 }
 
 func TestVirtualDocRemoveUnknownBlockNoop(t *testing.T) {
-	v := NewVirtualDoc()
+	v := NewVirtualDoc("python")
 	v.UpsertBlock("a", 0, "a = 1")
 	before := v.Version()
 	v.RemoveBlock("does-not-exist")
@@ -138,14 +138,14 @@ func TestVirtualDocRemoveUnknownBlockNoop(t *testing.T) {
 }
 
 func TestVirtualDocTranslateUnknownBlock(t *testing.T) {
-	v := NewVirtualDoc()
+	v := NewVirtualDoc("python")
 	if _, ok := v.TranslateBlockToDoc("nope", protocol.Position{}); ok {
 		t.Fatal("translation should fail for unknown block")
 	}
 }
 
 func TestVirtualDocTranslateDocToBlock(t *testing.T) {
-	v := NewVirtualDoc()
+	v := NewVirtualDoc("python")
 	v.UpsertBlock("a", 0, "a = 1\nb = 2") // doc lines 0,1
 	v.UpsertBlock("b", 1, "c = 3")        // doc line 4
 
@@ -166,6 +166,42 @@ func TestVirtualDocTranslateDocToBlock(t *testing.T) {
 	}
 }
 
+func TestVirtualDocGoPreambleAndComments(t *testing.T) {
+	v := NewVirtualDoc("go")
+	v.UpsertBlock("a", 0, "x := 1")
+	v.UpsertBlock("b", 3, "fmt.Println(x)")
+
+	want := "package main\n\n" + "x := 1" + "\n\n" + "// --- bytebook block 3 ---\n" + "fmt.Println(x)"
+	if got := v.FullText(); got != want {
+		t.Fatalf("FullText mismatch:\n got: %q\nwant: %q", got, want)
+	}
+
+	// The two preamble lines shift every block down.
+	pos, ok := v.TranslateBlockToDoc("a", protocol.Position{Line: 0, Character: 2})
+	if !ok || pos.Line != 2 || pos.Character != 2 {
+		t.Fatalf("block a origin: got %+v ok=%v, want line=2 char=2", pos, ok)
+	}
+	pos, ok = v.TranslateBlockToDoc("b", protocol.Position{Line: 0, Character: 0})
+	if !ok || pos.Line != 5 {
+		t.Fatalf("block b origin: got %+v ok=%v, want line=5", pos, ok)
+	}
+	if _, _, ok := v.TranslateDocToBlock(protocol.Position{Line: 0}); ok {
+		t.Fatal("preamble line should not map to any block")
+	}
+}
+
+func TestVirtualDocSlashCommentLanguages(t *testing.T) {
+	for _, language := range []string{"javascript", "java"} {
+		v := NewVirtualDoc(language)
+		v.UpsertBlock("a", 0, "let a = 1")
+		v.UpsertBlock("b", 1, "let b = 2")
+		want := "let a = 1\n\n// --- bytebook block 1 ---\nlet b = 2"
+		if got := v.FullText(); got != want {
+			t.Errorf("%s FullText = %q, want %q", language, got, want)
+		}
+	}
+}
+
 func TestCountLines(t *testing.T) {
 	cases := []struct {
 		in   string
@@ -184,3 +220,31 @@ func TestCountLines(t *testing.T) {
 		}
 	}
 }
+
+func TestVirtualDocTranslateDocRangeToBlock(t *testing.T) {
+	v := NewVirtualDoc("go")
+	v.UpsertBlock("a", 0, "x := 1\ny := \"é\"") // doc lines 2,3
+
+	id, rng, ok := v.TranslateDocRangeToBlock(protocol.Range{
+		Start: protocol.Position{Line: 2, Character: 0},
+		End:   protocol.Position{Line: 3, Character: 1},
+	})
+	if !ok || id != "a" || rng.Start.Line != 0 || rng.End.Line != 1 || rng.End.Character != 1 {
+		t.Fatalf("range -> %q %+v ok=%v, want a (0,0)-(1,1)", id, rng, ok)
+	}
+
+	// Column 0 of the line past the block clamps to the end of its last line,
+	// measured in UTF-16 code units.
+	_, rng, ok = v.TranslateDocRangeToBlock(protocol.Range{
+		Start: protocol.Position{Line: 3, Character: 0},
+		End:   protocol.Position{Line: 4, Character: 0},
+	})
+	if !ok || rng.End.Line != 1 || rng.End.Character != 8 {
+		t.Fatalf("clamped range = %+v ok=%v, want end (1,8)", rng, ok)
+	}
+
+	// Preamble lines belong to no block.
+	if _, _, ok := v.TranslateDocRangeToBlock(protocol.Range{End: protocol.Position{Character: 7}}); ok {
+		t.Fatal("preamble range should not map to any block")
+	}
+}
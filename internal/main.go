@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 	"sync"
 
 	bytebook "github.com/etesam913/bytebook"
@@ -23,6 +25,10 @@ import (
 
 // main function serves as the application's entry point.
 func main() {
+	if exitCode, ran := runCommand(os.Args[1:]); ran {
+		os.Exit(exitCode)
+	}
+
 	projectPath, err := config.GetProjectPath()
 	if err != nil {
 		log.Fatal(err.Error())
@@ -42,6 +48,11 @@ func main() {
 		log.Fatal(err.Error())
 	}
 
+	// Prune kernel execution transcripts that have not been touched in a while.
+	if err := kernel_manager.SetupHistoryDir(projectPath); err != nil {
+		log.Printf("failed to set up kernel history: %v", err)
+	}
+
 	searchIndex, err := search.OpenOrCreateIndex(projectPath)
 	if err != nil {
 		log.Fatal(err.Error())
@@ -51,8 +62,11 @@ func main() {
 
 	kernelManager := kernel_manager.New(projectPath, projectFiles.AllKernels)
 	defer kernelManager.ShutdownAll()
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	defer stopReaper()
+	kernelManager.StartIdleReaper(reaperCtx)
 
-	lspManager := lsp.New()
+	lspManager := lsp.New(projectPath)
 	defer lspManager.ShutdownAll()
 
 	watcher, err := fsnotify.NewWatcher()
@@ -76,6 +90,7 @@ func main() {
 			application.NewService(&services.SearchService{ProjectPath: projectPath, Index: indexHolder}),
 			application.NewService(&services.SettingsService{ProjectPath: projectPath}),
 			application.NewService(&services.TagsService{ProjectPath: projectPath, Index: indexHolder}),
+			application.NewService(&services.ImportService{Coordinator: importCoordinator}),
 			application.NewService(&services.CodeService{
 				ProjectPath: projectPath,
 				Manager:     kernelManager,
@@ -7,12 +7,13 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 // LocalFileMiddleware creates middleware that serves local filesystem files
 // for img/video tags. It intercepts requests to /notes/* paths and serves
-// files from the project's notes directory.
+// files from the project's notes directory or its linked folders.
 func LocalFileMiddleware(projectPath string) application.Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -25,28 +26,10 @@ func LocalFileMiddleware(projectPath string) application.Middleware {
 			// Check if this is a file request (starts with /notes/)
 			path := r.URL.Path
 			if relativePath, found := strings.CutPrefix(path, "/notes/"); found {
-				fullPath := filepath.Join(projectPath, "notes", relativePath)
-				// Clean the path to prevent directory traversal
-				fullPath = filepath.Clean(fullPath)
-
-				// Ensure the resolved path is still within the project notes directory
-				projectNotesPath := filepath.Join(projectPath, "notes")
-				projectNotesPathAbs, err := filepath.Abs(projectNotesPath)
-				if err != nil {
-					log.Printf("Error resolving project notes path: %v", err)
-					next.ServeHTTP(w, r)
-					return
-				}
-
-				fullPathAbs, err := filepath.Abs(fullPath)
+				// Resolve into notes/ or the linked folder the path names,
+				// rejecting directory traversal out of either.
+				fullPath, err := config.ResolveNotesPath(projectPath, relativePath)
 				if err != nil {
-					log.Printf("Error resolving file path: %v", err)
-					next.ServeHTTP(w, r)
-					return
-				}
-
-				// Security check: ensure the file is within the notes directory
-				if !strings.HasPrefix(fullPathAbs, projectNotesPathAbs) {
 					http.Error(w, "Forbidden", http.StatusForbidden)
 					return
 				}
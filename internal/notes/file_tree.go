@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+
+	"github.com/etesam913/bytebook/internal/config"
 )
 
 type FileOrFolder struct {
@@ -19,7 +21,8 @@ type FileOrFolder struct {
 }
 
 // GetAllPaths returns every file and folder path under notes/, relative to the
-// notes directory, sorted, with directories marked by a trailing slash. Hidden
+// notes directory, sorted, with directories marked by a trailing slash. Linked
+// folders appear as top-level folders under their configured name. Hidden
 // files and folders (those starting with '.') are skipped entirely.
 func GetAllPaths(projectPath string) ([]string, error) {
 	notesRoot := filepath.Join(projectPath, "notes")
@@ -31,12 +34,36 @@ func GetAllPaths(projectPath string) ([]string, error) {
 		return nil, fmt.Errorf("%s is not a directory", notesRoot)
 	}
 
+	ignore := NewIgnoreMatcher(projectPath)
 	paths := make([]string, 0, 512)
-	err = filepath.WalkDir(notesRoot, func(p string, d fs.DirEntry, err error) error {
+	paths, err = appendTreePaths(paths, notesRoot, "", ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	// An unreachable linked folder, such as one on an unmounted drive, is left
+	// out instead of failing the whole tree.
+	for _, folder := range config.LinkedFolders(projectPath) {
+		linkedPaths, err := appendTreePaths([]string{folder.Name + "/"}, folder.Path, folder.Name, ignore)
+		if err != nil {
+			log.Printf("GetAllPaths: skipping linked folder %s: %v", folder.Name, err)
+			continue
+		}
+		paths = append(paths, linkedPaths...)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// appendTreePaths walks root and appends the slash-separated path of every
+// visible entry below it, prefixed with prefix, to paths.
+func appendTreePaths(paths []string, root, prefix string, ignore *IgnoreMatcher) ([]string, error) {
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			// A single unreadable entry must not blank the whole tree, which is
 			// what returning the error here would do. Only the root is fatal.
-			if p == notesRoot {
+			if p == root {
 				return err
 			}
 			log.Printf("GetAllPaths: skipping %s: %v", p, err)
@@ -45,7 +72,7 @@ func GetAllPaths(projectPath string) ([]string, error) {
 			}
 			return nil
 		}
-		if p == notesRoot {
+		if p == root {
 			return nil
 		}
 		if len(d.Name()) > 0 && d.Name()[0] == '.' {
@@ -54,21 +81,22 @@ func GetAllPaths(projectPath string) ([]string, error) {
 			}
 			return nil
 		}
-		rel, relErr := filepath.Rel(notesRoot, p)
+		if ignore.Match(p, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, p)
 		if relErr != nil {
 			return relErr
 		}
-		rel = filepath.ToSlash(rel)
+		rel = filepath.ToSlash(filepath.Join(prefix, rel))
 		if d.IsDir() {
 			rel += "/"
 		}
 		paths = append(paths, rel)
 		return nil
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	sort.Strings(paths)
-	return paths, nil
+	return paths, err
 }
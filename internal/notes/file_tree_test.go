@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -42,6 +43,20 @@ func TestGetAllPaths(t *testing.T) {
 		}, paths)
 	})
 
+	t.Run("skips paths matched by .bytebookignore", func(t *testing.T) {
+		isolatedDir := t.TempDir()
+		isolatedNotes := filepath.Join(isolatedDir, "notes")
+		require.NoError(t, os.MkdirAll(filepath.Join(isolatedNotes, "project", "node_modules", "pkg"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(isolatedNotes, "project", "node_modules", "pkg", "readme.md"), []byte("content"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(isolatedNotes, "project", "notes.md"), []byte("content"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(isolatedNotes, "project", "run.log"), []byte("content"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(isolatedNotes, IgnoreFileName), []byte("node_modules/\n*.log\n"), 0644))
+
+		paths, err := GetAllPaths(isolatedDir)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"project/", "project/notes.md"}, paths)
+	})
+
 	t.Run("errors when notes directory does not exist", func(t *testing.T) {
 		_, err := GetAllPaths(filepath.Join(tempDir, "nonexistent"))
 		assert.Error(t, err)
@@ -66,4 +81,23 @@ func TestGetAllPaths(t *testing.T) {
 		// The directory itself is still listed; only its contents are skipped.
 		assert.Contains(t, paths, "locked/")
 	})
+
+	t.Run("lists linked folders as top-level folders", func(t *testing.T) {
+		isolatedDir := t.TempDir()
+		projectDir := filepath.Join(isolatedDir, "project")
+		require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "notes", "local"), 0755))
+		_, err := config.GetProjectSettings(projectDir)
+		require.NoError(t, err)
+
+		docsDir := filepath.Join(isolatedDir, "repo", "docs")
+		require.NoError(t, os.MkdirAll(filepath.Join(docsDir, "guide", "build"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(docsDir, "guide", "intro.md"), []byte("content"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(docsDir, "guide", "build", "out.md"), []byte("content"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(docsDir, IgnoreFileName), []byte("build/\n"), 0644))
+		require.NoError(t, config.AddLinkedFolder(projectDir, config.LinkedFolderJson{Name: "docs", Path: docsDir}))
+
+		paths, err := GetAllPaths(projectDir)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"docs/", "docs/guide/", "docs/guide/intro.md", "local/"}, paths)
+	})
 }
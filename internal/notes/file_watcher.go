@@ -53,7 +53,7 @@ type FileWatcher struct {
 	app                           *application.App
 	projectPath                   string
 	watcher                       *fsnotify.Watcher
-	knownWatchedDirectories       *DirectoryWatchRegistry        // Directory paths we have registered watches for, including old rename paths until cleanup runs
+	knownWatchedDirectories       *DirectoryWatchRegistry        // Directory paths we watch or poll, including old rename paths until cleanup runs
 	debounceTimer                 *time.Timer                    // Timer that fires after inactivity to flush accumulated events
 	debounceEvents                map[string][]map[string]string // Events keyed by type, accumulated until debounce fires
 	mostRecentFolderCreatedEvents []pendingWatcherEvent          // Folder creates accumulated for the current debounce batch
@@ -61,6 +61,9 @@ type FileWatcher struct {
 	pendingFolderRenameEvents     []pendingWatcherEvent          // Folder renames waiting to be paired with a create
 	pendingFileRenameEvents       []pendingWatcherEvent          // File renames waiting to be paired with a create
 	fileStateCache                map[string]fileState           // Cached modTime/size per path to dedupe changes
+	ignore                        *IgnoreMatcher                 // .bytebookignore rules, invalidated as ignore files change
+	pollTicker                    *time.Ticker                   // Fires pollDirectories for directories that could not be watched natively
+	polledEntries                 map[string]polledDirectory     // Entry states per polled directory as of the last poll
 }
 
 // newFileWatcher creates and initializes a new FileWatcher
@@ -78,6 +81,9 @@ func newFileWatcher(app *application.App, projectPath string, watcher *fsnotify.
 		debounceTimer:                 time.NewTimer(0),
 		debounceEvents:                make(map[string][]map[string]string),
 		fileStateCache:                make(map[string]fileState),
+		ignore:                        NewIgnoreMatcher(projectPath),
+		pollTicker:                    time.NewTicker(pollInterval),
+		polledEntries:                 make(map[string]polledDirectory),
 		mostRecentFolderCreatedEvents: []pendingWatcherEvent{},
 		mostRecentFileCreatedEvents:   []pendingWatcherEvent{},
 	}
@@ -128,16 +134,13 @@ func (fw *FileWatcher) handleFolderEvents(event fsnotify.Event) {
 
 // pathFromNotes returns the relative path from the notes root directory.
 // For example, if the full path is /project/notes/folder/note.md, it returns "folder/note.md".
+// Paths in a linked folder are prefixed with the folder's name instead.
 // Returns empty string if the path is the notes root itself.
 func (fw *FileWatcher) pathFromNotes(path string) string {
-	notesRoot := filepath.Join(fw.projectPath, "notes")
-	relPath, err := filepath.Rel(notesRoot, path)
-	if err != nil || strings.HasPrefix(relPath, "..") {
+	relPath, ok := config.NotesRelativePath(fw.projectPath, path)
+	if !ok {
 		return filepath.Base(path)
 	}
-	if relPath == "." {
-		return ""
-	}
 	return relPath
 }
 
@@ -370,7 +373,7 @@ func dedupeDebouncedEventsByPathPayload(events map[string][]map[string]string) m
 
 // shouldIgnoreFile checks if a file name should be ignored by the watcher
 func shouldIgnoreFile(fileName string) bool {
-	if sidecar.IsFileName(fileName) {
+	if sidecar.IsFileName(fileName) || IsIgnoreFile(fileName) {
 		return false
 	}
 	// Ignore hidden files and folders (names starting with '.')
@@ -416,7 +419,9 @@ func (fw *FileWatcher) renameFileState(oldPath, newPath string) {
 	}
 }
 
-func collectWatchableFolderPaths(rootPath string) []string {
+// collectWatchableFolderPaths lists rootPath and every directory below it that
+// is neither hidden nor excluded by ignore.
+func collectWatchableFolderPaths(rootPath string, ignore *IgnoreMatcher) []string {
 	info, err := os.Stat(rootPath)
 	if err != nil {
 		log.Printf("Error statting notes directory %s: %v", rootPath, err)
@@ -440,6 +445,9 @@ func collectWatchableFolderPaths(rootPath string) []string {
 		if strings.HasPrefix(d.Name(), ".") && path != rootPath {
 			return filepath.SkipDir
 		}
+		if path != rootPath && ignore.Match(path, true) {
+			return filepath.SkipDir
+		}
 
 		paths = append(paths, path)
 		return nil
@@ -452,8 +460,12 @@ func collectWatchableFolderPaths(rootPath string) []string {
 }
 
 func (fw *FileWatcher) addFolderTreeToWatcher(rootPath string) {
-	for _, path := range collectWatchableFolderPaths(rootPath) {
+	for _, path := range collectWatchableFolderPaths(rootPath, fw.ignore) {
 		if err := fw.watcher.Add(path); err != nil {
+			if IsWatchLimitError(err) {
+				fw.knownWatchedDirectories.AddPolled(path)
+				continue
+			}
 			log.Printf("Error adding watcher for %s: %v", path, err)
 			continue
 		}
@@ -468,6 +480,10 @@ func (fw *FileWatcher) removeFolderTreeWatches(rootPath string) {
 		if watchedPath != rootPath && !strings.HasPrefix(watchedPath, prefix) {
 			continue
 		}
+		if fw.knownWatchedDirectories.IsPolled(watchedPath) {
+			fw.knownWatchedDirectories.Remove(watchedPath)
+			continue
+		}
 		if err := fw.watcher.Remove(watchedPath); err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
 			log.Printf("Error removing watcher for %s: %v", watchedPath, err)
 		}
@@ -475,6 +491,30 @@ func (fw *FileWatcher) removeFolderTreeWatches(rootPath string) {
 	}
 }
 
+// handleIgnoreFileUpdate reloads the rules of a changed .bytebookignore file,
+// drops watches on directories it now excludes, and queues an ignore:update
+// event so listeners can reconcile the index and file tree with the new rules.
+// Directories that the change re-includes are picked up by those listeners.
+func (fw *FileWatcher) handleIgnoreFileUpdate(ignoreFilePath string) {
+	dirPath := filepath.Dir(ignoreFilePath)
+	if _, ok := config.NotesRelativePath(fw.projectPath, dirPath); !ok {
+		return
+	}
+
+	fw.ignore.Invalidate(dirPath)
+	prefix := dirPath + string(os.PathSeparator)
+	for _, watchedPath := range fw.knownWatchedDirectories.Snapshot() {
+		if strings.HasPrefix(watchedPath, prefix) && fw.ignore.Match(watchedPath, true) {
+			fw.removeFolderTreeWatches(watchedPath)
+		}
+	}
+
+	fw.appendDebouncedEvent(util.EventIgnoreRulesUpdate, map[string]string{
+		"folderPath": fw.pathFromNotes(dirPath),
+	})
+	fw.handleDebounceReset()
+}
+
 func (fw *FileWatcher) isDirectoryEvent(event fsnotify.Event) bool {
 	if util.IsDirectory(event.Name) {
 		return true
@@ -571,6 +611,18 @@ func (fw *FileWatcher) processEvent(event fsnotify.Event) {
 		return
 	}
 
+	if IsIgnoreFile(fileName) {
+		if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+			fw.handleIgnoreFileUpdate(event.Name)
+		}
+		return
+	}
+
+	// Changes inside ignored paths are invisible to the rest of the app.
+	if fw.ignore.Match(event.Name, isDir) {
+		return
+	}
+
 	projectSettingsRoot := filepath.Join(fw.projectPath, "settings")
 	projectSavedSearchesPath := filepath.Join(fw.projectPath, "search", "saved-searches.json")
 
@@ -702,6 +754,7 @@ func orderedDebouncedEventKeys(events map[string][]map[string]string) []string {
 		util.EventFolderCreate,
 		util.EventFileCreate,
 		util.EventFileWrite,
+		util.EventIgnoreRulesUpdate,
 	}
 
 	keys := []string{}
@@ -781,6 +834,12 @@ func typedDebouncedEventPayload(eventKey string, data []map[string]string) any {
 			}
 		}
 		return out
+	case util.EventIgnoreRulesUpdate:
+		out := make([]util.IgnoreRulesUpdateEventData, len(data))
+		for i, payload := range data {
+			out[i] = util.IgnoreRulesUpdateEventData{FolderPath: payload["folderPath"]}
+		}
+		return out
 	default:
 		return data
 	}
@@ -830,6 +889,10 @@ func (fw *FileWatcher) start() {
 		// Whenever the debounce timer expires
 		case <-fw.debounceTimer.C:
 			fw.emitDebouncedEvents()
+
+		// Whenever directories without a native watch are due for a poll
+		case <-fw.pollTicker.C:
+			fw.pollDirectories()
 		}
 	}
 }
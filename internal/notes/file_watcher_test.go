@@ -26,6 +26,10 @@ func TestShouldIgnoreFile(t *testing.T) {
 		assert.False(t, shouldIgnoreFile(".note.json"))
 	})
 
+	t.Run("should not ignore .bytebookignore files", func(t *testing.T) {
+		assert.False(t, shouldIgnoreFile(IgnoreFileName))
+	})
+
 	t.Run("should ignore hidden markdown files", func(t *testing.T) {
 		assert.True(t, shouldIgnoreFile(".hidden.md"))
 		assert.True(t, shouldIgnoreFile(".note.md"))
@@ -252,13 +256,63 @@ func TestCollectWatchableFolderPaths(t *testing.T) {
 	err = os.WriteFile(filepath.Join(notesDir, "root.md"), []byte("root"), 0644)
 	assert.NoError(t, err)
 
-	paths := collectWatchableFolderPaths(filepath.Join(testDir, "notes"))
+	ignoredDir := filepath.Join(notesDir, "node_modules", "pkg")
+	err = os.MkdirAll(ignoredDir, 0755)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(notesDir, IgnoreFileName), []byte("node_modules/\n"), 0644)
+	assert.NoError(t, err)
+
+	paths := collectWatchableFolderPaths(filepath.Join(testDir, "notes"), NewIgnoreMatcher(testDir))
 
 	assert.Contains(t, paths, notesDir)
 	assert.Contains(t, paths, alphaDir)
 	assert.Contains(t, paths, betaDir)
 	assert.NotContains(t, paths, hiddenDir)
 	assert.NotContains(t, paths, hiddenChild)
+	assert.NotContains(t, paths, filepath.Join(notesDir, "node_modules"))
+	assert.NotContains(t, paths, ignoredDir)
+}
+
+func TestProcessEventIgnoreRules(t *testing.T) {
+	t.Run("events inside ignored paths are dropped", func(t *testing.T) {
+		testDir, _, notesDir, _, _ := setupProjectFolders(t)
+		err := os.WriteFile(filepath.Join(notesDir, IgnoreFileName), []byte("*.log\n"), 0644)
+		assert.NoError(t, err)
+		logFile := filepath.Join(notesDir, "run.log")
+		err = os.WriteFile(logFile, []byte("output"), 0644)
+		assert.NoError(t, err)
+
+		fw := newTestFileWatcher(t, testDir)
+		fw.processEvent(fsnotify.Event{Name: logFile, Op: fsnotify.Write})
+
+		assert.Empty(t, fw.debounceEvents)
+	})
+
+	t.Run("ignore file change reloads rules, unwatches ignored folders and emits ignore update", func(t *testing.T) {
+		testDir, _, notesDir, _, _ := setupProjectFolders(t)
+		projectDir := filepath.Join(notesDir, "project")
+		buildDir := filepath.Join(projectDir, "build")
+		buildChild := filepath.Join(buildDir, "out")
+		err := os.MkdirAll(buildChild, 0755)
+		assert.NoError(t, err)
+
+		fw := newTestFileWatcher(t, testDir)
+		fw.addFolderTreeToWatcher(notesDir)
+		assert.True(t, fw.knownWatchedDirectories.Has(buildChild))
+
+		ignoreFile := filepath.Join(projectDir, IgnoreFileName)
+		err = os.WriteFile(ignoreFile, []byte("build/\n"), 0644)
+		assert.NoError(t, err)
+		fw.processEvent(fsnotify.Event{Name: ignoreFile, Op: fsnotify.Create})
+
+		assert.True(t, fw.knownWatchedDirectories.Has(projectDir))
+		assert.False(t, fw.knownWatchedDirectories.Has(buildDir))
+		assert.False(t, fw.knownWatchedDirectories.Has(buildChild))
+		assert.NotContains(t, fw.watcher.WatchList(), buildDir)
+		assert.Equal(t, []map[string]string{
+			{"folderPath": "project"},
+		}, fw.debounceEvents[util.EventIgnoreRulesUpdate])
+	})
 }
 
 func TestResolvePendingRenames(t *testing.T) {
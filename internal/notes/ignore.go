@@ -0,0 +1,237 @@
+package notes
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/etesam913/bytebook/internal/config"
+)
+
+// IgnoreFileName is the per-directory ignore file. It uses gitignore syntax
+// and applies to the directory that contains it and everything below, so a
+// notes folder can keep node_modules, build output or datasets out of the
+// watcher, the search index and the file tree.
+const IgnoreFileName = ".bytebookignore"
+
+// ignorePattern is one compiled line of an ignore file.
+type ignorePattern struct {
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreMatcher answers whether a path under notes/ or a linked folder is
+// excluded by the .bytebookignore files in its ancestor directories. Rules are
+// read lazily per directory and cached; Invalidate drops a directory's cache
+// after its ignore file changes. A nil *IgnoreMatcher ignores nothing.
+//
+// Semantics follow gitignore: patterns in deeper files override shallower
+// ones, the last matching pattern in a file wins, "!" re-includes, a trailing
+// "/" matches directories only, and nothing inside an ignored directory can
+// be re-included.
+type IgnoreMatcher struct {
+	projectPath string
+
+	mu    sync.RWMutex
+	rules map[string][]ignorePattern // keyed by slash-separated dir relative to notes/; "" is the root
+}
+
+// NewIgnoreMatcher returns a matcher for the notes directory of projectPath.
+func NewIgnoreMatcher(projectPath string) *IgnoreMatcher {
+	return &IgnoreMatcher{
+		projectPath: projectPath,
+		rules:       map[string][]ignorePattern{},
+	}
+}
+
+// IsIgnoreFile reports whether fileName is a .bytebookignore file.
+func IsIgnoreFile(fileName string) bool {
+	return fileName == IgnoreFileName
+}
+
+// Invalidate drops the cached rules for dirPath (absolute) so the next match
+// re-reads its ignore file.
+func (m *IgnoreMatcher) Invalidate(dirPath string) {
+	if m == nil {
+		return
+	}
+	rel, ok := m.relative(dirPath)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	delete(m.rules, rel)
+	m.mu.Unlock()
+}
+
+// Match reports whether absPath is ignored. isDir must describe absPath
+// itself; every ancestor is treated as a directory. Paths outside notes/ and
+// the linked folders are never ignored.
+func (m *IgnoreMatcher) Match(absPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	rel, ok := m.relative(absPath)
+	if !ok || rel == "" {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	// Check each ancestor first: once a directory is ignored, gitignore does
+	// not look inside it, so nothing below can be re-included.
+	for i := 1; i <= len(parts); i++ {
+		prefixIsDir := i < len(parts) || isDir
+		if m.matchOne(parts[:i], prefixIsDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOne applies every ignore file from the root down to the parent of
+// parts to the path parts, without considering whether an ancestor is
+// ignored. The deepest, last matching pattern decides.
+func (m *IgnoreMatcher) matchOne(parts []string, isDir bool) bool {
+	ignored := false
+	for depth := 0; depth < len(parts); depth++ {
+		dir := strings.Join(parts[:depth], "/")
+		target := strings.Join(parts[depth:], "/")
+		for _, p := range m.rulesFor(dir) {
+			if p.dirOnly && !isDir {
+				continue
+			}
+			if p.regex.MatchString(target) {
+				ignored = !p.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// rulesFor returns the cached rules of the ignore file in dir, reading it on
+// first use. A missing or unreadable file yields no rules.
+func (m *IgnoreMatcher) rulesFor(dir string) []ignorePattern {
+	m.mu.RLock()
+	patterns, ok := m.rules[dir]
+	m.mu.RUnlock()
+	if ok {
+		return patterns
+	}
+
+	dirPath, err := config.ResolveNotesPath(m.projectPath, filepath.FromSlash(dir))
+	if err == nil {
+		patterns = readIgnoreFile(filepath.Join(dirPath, IgnoreFileName))
+	}
+	m.mu.Lock()
+	m.rules[dir] = patterns
+	m.mu.Unlock()
+	return patterns
+}
+
+// relative converts absPath to a slash-separated path relative to notes/,
+// with paths in linked folders under the folder's name.
+func (m *IgnoreMatcher) relative(absPath string) (string, bool) {
+	rel, ok := config.NotesRelativePath(m.projectPath, absPath)
+	if !ok {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func readIgnoreFile(path string) []ignorePattern {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if p, ok := parseIgnoreLine(scanner.Text()); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// parseIgnoreLine compiles one gitignore-syntax line. Blank lines and
+// comments return ok=false.
+func parseIgnoreLine(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to the ignore file's
+	// directory; otherwise it matches a name at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	prefix := `^(?:.*/)?`
+	if anchored {
+		prefix = `^`
+	}
+	regex, err := regexp.Compile(prefix + globToRegexp(line) + `$`)
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	p.regex = regex
+	return p, true
+}
+
+// globToRegexp translates gitignore glob syntax into a regexp fragment.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString(`(?:.*/)?`)
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(`.*`)
+			i++
+		case c == '*':
+			b.WriteString(`[^/]*`)
+		case c == '?':
+			b.WriteString(`[^/]`)
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
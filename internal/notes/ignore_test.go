@@ -0,0 +1,102 @@
+package notes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeIgnoreFile(t *testing.T, dir, contents string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte(contents), 0644))
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	projectPath := t.TempDir()
+	notesDir := filepath.Join(projectPath, "notes")
+	writeIgnoreFile(t, notesDir, "# build output\nnode_modules/\n*.log\n/dist\ndata/**/*.csv\n!keep.log\n")
+	writeIgnoreFile(t, filepath.Join(notesDir, "project"), "secret.md\n!important.log\n")
+
+	matcher := NewIgnoreMatcher(projectPath)
+	path := func(rel string) string { return filepath.Join(notesDir, filepath.FromSlash(rel)) }
+
+	cases := []struct {
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{"node_modules", true, true},
+		{"project/node_modules", true, true},
+		{"project/node_modules/pkg/index.md", false, true},
+		// Directory-only pattern does not match a file of the same name.
+		{"node_modules", false, false},
+		{"debug.log", false, true},
+		{"project/nested/debug.log", false, true},
+		{"keep.log", false, false},
+		// Anchored to the root ignore file's directory.
+		{"dist", true, true},
+		{"project/dist", true, false},
+		{"data/a/b/rows.csv", false, true},
+		{"data/rows.csv", false, true},
+		{"data/readme.md", false, false},
+		// Deeper ignore files add and override rules for their subtree.
+		{"project/secret.md", false, true},
+		{"secret.md", false, false},
+		{"project/important.log", false, false},
+		{"notes.md", false, false},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.ignored, matcher.Match(path(tc.rel), tc.isDir), tc.rel)
+	}
+
+	t.Run("cannot re-include inside an ignored directory", func(t *testing.T) {
+		writeIgnoreFile(t, filepath.Join(notesDir, "node_modules"), "!*\n")
+		matcher.Invalidate(filepath.Join(notesDir, "node_modules"))
+		assert.True(t, matcher.Match(path("node_modules/keep.md"), false))
+	})
+
+	t.Run("invalidate picks up edited rules", func(t *testing.T) {
+		assert.False(t, matcher.Match(path("drafts"), true))
+		writeIgnoreFile(t, notesDir, "drafts/\n")
+		assert.False(t, matcher.Match(path("drafts"), true), "cached rules should still apply before Invalidate")
+		matcher.Invalidate(notesDir)
+		assert.True(t, matcher.Match(path("drafts"), true))
+		assert.False(t, matcher.Match(path("debug.log"), false))
+	})
+
+	t.Run("paths outside notes are never ignored", func(t *testing.T) {
+		assert.False(t, matcher.Match(filepath.Join(projectPath, "settings", "drafts"), true))
+		assert.False(t, matcher.Match(notesDir, true))
+	})
+
+	t.Run("nil matcher ignores nothing", func(t *testing.T) {
+		var nilMatcher *IgnoreMatcher
+		assert.False(t, nilMatcher.Match(path("drafts"), true))
+		nilMatcher.Invalidate(notesDir)
+	})
+}
+
+func TestParseIgnoreLine(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+		_, ok := parseIgnoreLine(line)
+		assert.False(t, ok, "line %q should not compile to a pattern", line)
+	}
+
+	p, ok := parseIgnoreLine(`\#literal`)
+	require.True(t, ok)
+	assert.True(t, p.regex.MatchString("#literal"))
+
+	p, ok = parseIgnoreLine("file[0-9].txt")
+	require.True(t, ok)
+	assert.True(t, p.regex.MatchString("dir/file3.txt"))
+	assert.False(t, p.regex.MatchString("filex.txt"))
+
+	p, ok = parseIgnoreLine("[!a]b")
+	require.True(t, ok)
+	assert.True(t, p.regex.MatchString("cb"))
+	assert.False(t, p.regex.MatchString("ab"))
+}
@@ -2,11 +2,11 @@ package notes
 
 import (
 	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/util"
 	"gopkg.in/yaml.v3"
 )
@@ -160,6 +160,64 @@ func GetTagsFromFrontmatter(markdown string) ([]string, bool) {
 	return []string{}, false
 }
 
+// GetKernelScopeFromFrontmatter extracts the kernelScope field from YAML frontmatter.
+// Returns the scope string and a boolean indicating whether the field was found and is a non-empty string.
+func GetKernelScopeFromFrontmatter(markdown string) (string, bool) {
+	frontmatter, ok := parseFrontmatter(markdown)
+	if !ok {
+		return "", false
+	}
+
+	if scope, ok := frontmatter["kernelScope"].(string); ok {
+		scope = strings.TrimSpace(scope)
+		return scope, scope != ""
+	}
+
+	return "", false
+}
+
+// GetPythonEnvironmentFromFrontmatter extracts the pythonEnvironment field from YAML frontmatter.
+// Returns the environment path and a boolean indicating whether the field was found and is a non-empty string.
+func GetPythonEnvironmentFromFrontmatter(markdown string) (string, bool) {
+	frontmatter, ok := parseFrontmatter(markdown)
+	if !ok {
+		return "", false
+	}
+
+	if environment, ok := frontmatter["pythonEnvironment"].(string); ok {
+		environment = strings.TrimSpace(environment)
+		return environment, environment != ""
+	}
+
+	return "", false
+}
+
+// GetRequirementsFromFrontmatter extracts the requires field from YAML frontmatter.
+// Returns the requirements and a boolean indicating whether the field was found and is a string or list of strings.
+func GetRequirementsFromFrontmatter(markdown string) ([]string, bool) {
+	frontmatter, ok := parseFrontmatter(markdown)
+	if !ok {
+		return []string{}, false
+	}
+
+	switch requires := frontmatter["requires"].(type) {
+	case []interface{}:
+		requirements := make([]string, 0, len(requires))
+		for _, requirement := range requires {
+			if requirementStr, ok := requirement.(string); ok && strings.TrimSpace(requirementStr) != "" {
+				requirements = append(requirements, strings.TrimSpace(requirementStr))
+			}
+		}
+		return requirements, true
+	case string:
+		if requires = strings.TrimSpace(requires); requires != "" {
+			return []string{requires}, true
+		}
+	}
+
+	return []string{}, false
+}
+
 // updateFrontmatterWithTags updates the frontmatter in markdown with the provided tags.
 // If no frontmatter exists, it creates new frontmatter with the tags.
 // Returns the updated markdown content.
@@ -295,8 +353,10 @@ func GetInternalLinksFromBody(markdown string) []string {
 // The folderAndNoteName parameter should be in format "folderName/noteName.md".
 // Returns the tags slice, a boolean indicating if tags exist, and any file reading error.
 func GetTagsFromNote(projectPath string, folderAndNoteName string) ([]string, bool, error) {
-	// Construct the full file path
-	noteFilePath := filepath.Join(projectPath, "notes", folderAndNoteName)
+	noteFilePath, err := config.ResolveNotesPath(projectPath, folderAndNoteName)
+	if err != nil {
+		return []string{}, false, err
+	}
 
 	// Read the file content
 	content, err := os.ReadFile(noteFilePath)
@@ -309,13 +369,72 @@ func GetTagsFromNote(projectPath string, folderAndNoteName string) ([]string, bo
 	return tags, exists, nil
 }
 
+// GetKernelScopeFromNote reads a note file and extracts the kernelScope field from its frontmatter.
+// The folderAndNoteName parameter should be in format "folderName/noteName.md".
+// Returns the scope, a boolean indicating if the field exists, and any file reading error.
+func GetKernelScopeFromNote(projectPath string, folderAndNoteName string) (string, bool, error) {
+	noteFilePath, err := config.ResolveNotesPath(projectPath, folderAndNoteName)
+	if err != nil {
+		return "", false, err
+	}
+
+	content, err := os.ReadFile(noteFilePath)
+	if err != nil {
+		return "", false, err
+	}
+
+	scope, exists := GetKernelScopeFromFrontmatter(string(content))
+	return scope, exists, nil
+}
+
+// GetPythonEnvironmentFromNote reads a note file and extracts the pythonEnvironment field from its frontmatter.
+// The folderAndNoteName parameter should be in format "folderName/noteName.md".
+// Returns the environment path, a boolean indicating if the field exists, and any file reading error.
+func GetPythonEnvironmentFromNote(projectPath string, folderAndNoteName string) (string, bool, error) {
+	noteFilePath, err := config.ResolveNotesPath(projectPath, folderAndNoteName)
+	if err != nil {
+		return "", false, err
+	}
+
+	content, err := os.ReadFile(noteFilePath)
+	if err != nil {
+		return "", false, err
+	}
+
+	environment, exists := GetPythonEnvironmentFromFrontmatter(string(content))
+	return environment, exists, nil
+}
+
+// GetRequirementsFromNote reads a note file and extracts the requires field from its frontmatter.
+// The folderAndNoteName parameter should be in format "folderName/noteName.md".
+// Returns the requirements, a boolean indicating if the field exists, and any file reading error.
+func GetRequirementsFromNote(projectPath string, folderAndNoteName string) ([]string, bool, error) {
+	noteFilePath, err := config.ResolveNotesPath(projectPath, folderAndNoteName)
+	if err != nil {
+		return []string{}, false, err
+	}
+
+	content, err := os.ReadFile(noteFilePath)
+	if err != nil {
+		return []string{}, false, err
+	}
+
+	requirements, exists := GetRequirementsFromFrontmatter(string(content))
+	return requirements, exists, nil
+}
+
 // AddTagsToNote adds the specified tags to a note's frontmatter.
 // It reads the existing tags from the frontmatter, adds new tags while removing duplicates, and writes the file back.
 // The folderAndNoteName parameter should be in format "folderName/noteName.md".
 // Returns an error if the file cannot be read, parsed, or written.
 func AddTagsToNote(projectPath string, folderAndNoteName string, newTags []string) error {
-	// Construct the full file path
-	noteFilePath := filepath.Join(projectPath, "notes", folderAndNoteName)
+	if err := config.CheckNotesPathWritable(projectPath, folderAndNoteName); err != nil {
+		return err
+	}
+	noteFilePath, err := config.ResolveNotesPath(projectPath, folderAndNoteName)
+	if err != nil {
+		return err
+	}
 
 	// Read the existing file content
 	content, err := os.ReadFile(noteFilePath)
@@ -351,8 +470,13 @@ func AddTagsToNote(projectPath string, folderAndNoteName string, newTags []strin
 // The folderAndNoteName parameter should be in format "folderName/noteName.md".
 // Returns the updated tags and an error if the file cannot be read, parsed, or written.
 func DeleteTagsFromNote(projectPath string, folderAndNoteName string, tagsToDelete []string) ([]string, error) {
-	// Construct the full file path
-	noteFilePath := filepath.Join(projectPath, "notes", folderAndNoteName)
+	if err := config.CheckNotesPathWritable(projectPath, folderAndNoteName); err != nil {
+		return nil, err
+	}
+	noteFilePath, err := config.ResolveNotesPath(projectPath, folderAndNoteName)
+	if err != nil {
+		return nil, err
+	}
 
 	content, err := os.ReadFile(noteFilePath)
 	if err != nil {
@@ -459,6 +459,71 @@ func TestGetTagsFromFrontmatter(t *testing.T) {
 	})
 }
 
+func TestGetKernelScopeFromFrontmatter(t *testing.T) {
+	t.Run("should extract kernelScope", func(t *testing.T) {
+		scope, exists := GetKernelScopeFromFrontmatter("---\nkernelScope: folder\n---\n# Content")
+		assert.True(t, exists)
+		assert.Equal(t, "folder", scope)
+	})
+
+	t.Run("should handle missing or invalid cases", func(t *testing.T) {
+		_, exists := GetKernelScopeFromFrontmatter("# Content without frontmatter")
+		assert.False(t, exists)
+
+		_, exists = GetKernelScopeFromFrontmatter("---\ntitle: Test\n---\n# Content")
+		assert.False(t, exists)
+
+		_, exists = GetKernelScopeFromFrontmatter("---\nkernelScope: \"  \"\n---\n# Content")
+		assert.False(t, exists)
+
+		_, exists = GetKernelScopeFromFrontmatter("---\nkernelScope:\n  - folder\n---\n# Content")
+		assert.False(t, exists)
+	})
+}
+
+func TestGetPythonEnvironmentFromFrontmatter(t *testing.T) {
+	t.Run("should extract pythonEnvironment", func(t *testing.T) {
+		environment, exists := GetPythonEnvironmentFromFrontmatter("---\npythonEnvironment: \" analysis/.venv \"\n---\n# Content")
+		assert.True(t, exists)
+		assert.Equal(t, "analysis/.venv", environment)
+	})
+
+	t.Run("should handle missing or invalid cases", func(t *testing.T) {
+		_, exists := GetPythonEnvironmentFromFrontmatter("# Content without frontmatter")
+		assert.False(t, exists)
+
+		_, exists = GetPythonEnvironmentFromFrontmatter("---\nkernelScope: folder\n---\n# Content")
+		assert.False(t, exists)
+
+		_, exists = GetPythonEnvironmentFromFrontmatter("---\npythonEnvironment: 3\n---\n# Content")
+		assert.False(t, exists)
+	})
+}
+
+func TestGetRequirementsFromFrontmatter(t *testing.T) {
+	t.Run("should extract requires as a list or a single string", func(t *testing.T) {
+		requirements, exists := GetRequirementsFromFrontmatter("---\nrequires: [pandas>=2, httpx]\n---\n# Content")
+		assert.True(t, exists)
+		assert.Equal(t, []string{"pandas>=2", "httpx"}, requirements)
+
+		requirements, exists = GetRequirementsFromFrontmatter("---\nrequires: numpy\n---\n# Content")
+		assert.True(t, exists)
+		assert.Equal(t, []string{"numpy"}, requirements)
+	})
+
+	t.Run("should handle missing or invalid cases", func(t *testing.T) {
+		_, exists := GetRequirementsFromFrontmatter("# Content without frontmatter")
+		assert.False(t, exists)
+
+		_, exists = GetRequirementsFromFrontmatter("---\nrequires: \"\"\n---\n# Content")
+		assert.False(t, exists)
+
+		requirements, exists := GetRequirementsFromFrontmatter("---\nrequires: [1, \"\", httpx]\n---\n# Content")
+		assert.True(t, exists)
+		assert.Equal(t, []string{"httpx"}, requirements)
+	})
+}
+
 func TestUpdateFrontmatterWithTags(t *testing.T) {
 	t.Run("should handle frontmatter updates", func(t *testing.T) {
 		// Add tags to markdown without frontmatter
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/util"
 )
 
@@ -28,9 +29,14 @@ func PathFor(filePath string) string {
 	return filepath.Join(dir, "."+stem+".json")
 }
 
-// PathForFile returns the sidecar path for a project-relative file at notes/<folder>/<fileName>.
+// PathForFile returns the sidecar path for a project-relative file at notes/<folder>/<fileName>,
+// which may live in a linked folder.
 func PathForFile(projectPath, folder, fileName string) string {
-	return PathFor(filepath.Join(projectPath, "notes", folder, fileName))
+	filePath, err := config.ResolveNotesPath(projectPath, filepath.Join(folder, fileName))
+	if err != nil {
+		filePath = filepath.Join(projectPath, "notes", folder, fileName)
+	}
+	return PathFor(filePath)
 }
 
 // IsFileName reports whether fileName matches the `.<base>.json` sidecar pattern.
@@ -1,8 +1,10 @@
 package sidecar
 
 import (
+	"path/filepath"
 	"strings"
 
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/util"
 )
 
@@ -29,7 +31,11 @@ func ReadTags(projectPath, folder, fileName string) ([]string, error) {
 }
 
 // WriteTags persists tags into the file's sidecar, preserving any other fields (e.g. CodeResults).
+// Files in read-only linked folders are refused.
 func WriteTags(projectPath, folder, fileName string, tags []string) error {
+	if err := config.CheckNotesPathWritable(projectPath, filepath.Join(folder, fileName)); err != nil {
+		return err
+	}
 	sidecarPath := PathForFile(projectPath, folder, fileName)
 	data, err := read(sidecarPath)
 	if err != nil {
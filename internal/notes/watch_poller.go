@@ -0,0 +1,140 @@
+package notes
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/fsnotify/fsnotify"
+)
+
+// pollInterval is how often directories that could not be watched natively
+// are checked for changes.
+const pollInterval = 2 * time.Second
+
+// IsWatchLimitError reports whether a failed watch add was caused by the OS
+// running out of watches: file descriptor exhaustion (EMFILE, ENFILE), which
+// is how kqueue fails, or the inotify watch limit (ENOSPC) on Linux.
+func IsWatchLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) || errors.Is(err, syscall.ENOSPC) {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "too many open files") || strings.Contains(message, "no space left on device")
+}
+
+// polledEntry is the state of one directory entry as of the last poll.
+type polledEntry struct {
+	state fileState
+	isDir bool
+}
+
+// polledDirectory holds the entries of a polled directory, keyed by name.
+type polledDirectory map[string]polledEntry
+
+// pollDirectories checks every polled directory and feeds the changes since
+// the previous poll through processEvent as synthesized fsnotify events, so
+// polled folders produce the same app events as watched ones.
+func (fw *FileWatcher) pollDirectories() {
+	polled := util.Set[string]{}
+	for _, dirPath := range fw.knownWatchedDirectories.PolledSnapshot() {
+		polled.Add(dirPath)
+		fw.pollDirectory(dirPath)
+	}
+
+	for dirPath := range fw.polledEntries {
+		if !polled.Has(dirPath) {
+			delete(fw.polledEntries, dirPath)
+		}
+	}
+}
+
+// pollDirectory compares the entries of dirPath with the previous poll. The
+// first poll of a directory only records a baseline.
+func (fw *FileWatcher) pollDirectory(dirPath string) {
+	current, err := readPolledEntries(dirPath)
+	if err != nil {
+		// A removed directory is reported by whatever watches its parent.
+		delete(fw.polledEntries, dirPath)
+		return
+	}
+
+	previous, ok := fw.polledEntries[dirPath]
+	fw.polledEntries[dirPath] = current
+	if !ok {
+		return
+	}
+
+	for _, event := range diffPolledEntries(dirPath, previous, current) {
+		fw.processEvent(event)
+	}
+}
+
+// readPolledEntries stats the entries of dirPath, keyed by name.
+func readPolledEntries(dirPath string) (polledDirectory, error) {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(polledDirectory, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since ReadDir; the next poll reports it.
+			continue
+		}
+		states[entry.Name()] = polledEntry{
+			state: fileState{modTime: info.ModTime(), size: info.Size()},
+			isDir: entry.IsDir(),
+		}
+	}
+	return states, nil
+}
+
+// diffPolledEntries returns the events a native watch on dirPath would have
+// sent between two polls, ordered by name. Entries that disappeared surface as
+// Rename, the way fsnotify reports a move out of a watched directory, so the
+// debounce cycle pairs them with a create or reports them as deleted.
+func diffPolledEntries(dirPath string, previous, current polledDirectory) []fsnotify.Event {
+	names := make([]string, 0, len(previous)+len(current))
+	for name := range previous {
+		names = append(names, name)
+	}
+	for name := range current {
+		if _, ok := previous[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	events := []fsnotify.Event{}
+	for _, name := range names {
+		path := filepath.Join(dirPath, name)
+		before, existed := previous[name]
+		after, exists := current[name]
+
+		switch {
+		case existed && !exists:
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Rename})
+		case !existed && exists:
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		case before.isDir != after.isDir:
+			events = append(events,
+				fsnotify.Event{Name: path, Op: fsnotify.Rename},
+				fsnotify.Event{Name: path, Op: fsnotify.Create},
+			)
+		case !after.isDir && before.state != after.state:
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+	}
+	return events
+}
@@ -0,0 +1,139 @@
+package notes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsWatchLimitError(t *testing.T) {
+	assert.True(t, IsWatchLimitError(syscall.EMFILE))
+	assert.True(t, IsWatchLimitError(fmt.Errorf("add watch: %w", syscall.ENOSPC)))
+	assert.True(t, IsWatchLimitError(errors.New("too many open files")))
+	assert.False(t, IsWatchLimitError(syscall.ENOENT))
+	assert.False(t, IsWatchLimitError(nil))
+}
+
+func TestDiffPolledEntries(t *testing.T) {
+	dirPath := filepath.Join("notes", "folder")
+	before := fileState{modTime: time.Unix(100, 0), size: 1}
+	after := fileState{modTime: time.Unix(200, 0), size: 2}
+
+	previous := polledDirectory{
+		"changed.md":   {state: before},
+		"removed.md":   {state: before},
+		"same.md":      {state: before},
+		"subfolder":    {state: before, isDir: true},
+		"touched-dir":  {state: before, isDir: true},
+		"was-file":     {state: before},
+		"removed-dir":  {state: before, isDir: true},
+		"unchanged-sz": {state: after},
+	}
+	current := polledDirectory{
+		"changed.md":   {state: after},
+		"added.md":     {state: after},
+		"same.md":      {state: before},
+		"subfolder":    {state: before, isDir: true},
+		"touched-dir":  {state: after, isDir: true},
+		"was-file":     {state: after, isDir: true},
+		"new-dir":      {state: after, isDir: true},
+		"unchanged-sz": {state: after},
+	}
+
+	assert.Equal(t, []fsnotify.Event{
+		{Name: filepath.Join(dirPath, "added.md"), Op: fsnotify.Create},
+		{Name: filepath.Join(dirPath, "changed.md"), Op: fsnotify.Write},
+		{Name: filepath.Join(dirPath, "new-dir"), Op: fsnotify.Create},
+		{Name: filepath.Join(dirPath, "removed-dir"), Op: fsnotify.Rename},
+		{Name: filepath.Join(dirPath, "removed.md"), Op: fsnotify.Rename},
+		{Name: filepath.Join(dirPath, "was-file"), Op: fsnotify.Rename},
+		{Name: filepath.Join(dirPath, "was-file"), Op: fsnotify.Create},
+	}, diffPolledEntries(dirPath, previous, current))
+}
+
+func TestPollDirectories(t *testing.T) {
+	t.Run("reports creates, writes and deletes in polled folders", func(t *testing.T) {
+		testDir, _, notesDir, _, _ := setupProjectFolders(t)
+		polledDir := filepath.Join(notesDir, "polled")
+		err := os.MkdirAll(polledDir, 0755)
+		assert.NoError(t, err)
+		editedPath := filepath.Join(polledDir, "edited.md")
+		removedPath := filepath.Join(polledDir, "removed.md")
+		err = os.WriteFile(editedPath, []byte("before"), 0644)
+		assert.NoError(t, err)
+		err = os.WriteFile(removedPath, []byte("removed"), 0644)
+		assert.NoError(t, err)
+
+		fw := newTestFileWatcher(t, testDir)
+		fw.knownWatchedDirectories.AddPolled(polledDir)
+
+		// The first poll only records a baseline.
+		fw.pollDirectories()
+		assert.Empty(t, fw.debounceEvents)
+
+		err = os.WriteFile(editedPath, []byte("after the edit"), 0644)
+		assert.NoError(t, err)
+		err = os.Remove(removedPath)
+		assert.NoError(t, err)
+		err = os.WriteFile(filepath.Join(polledDir, "added.md"), []byte("added"), 0644)
+		assert.NoError(t, err)
+		err = os.WriteFile(filepath.Join(polledDir, "another.md"), []byte("another"), 0644)
+		assert.NoError(t, err)
+
+		fw.pollDirectories()
+		fw.resolvePendingRenames(false)
+
+		assert.Equal(t, []map[string]string{
+			{"filePath": filepath.Join("polled", "edited.md"), "markdown": "after the edit"},
+		}, fw.debounceEvents[util.EventFileWrite])
+		assert.Equal(t, []map[string]string{
+			{"filePath": filepath.Join("polled", "removed.md")},
+		}, fw.debounceEvents[util.EventFileDelete])
+		assert.Equal(t, []map[string]string{
+			{"filePath": filepath.Join("polled", "added.md")},
+			{"filePath": filepath.Join("polled", "another.md")},
+		}, fw.debounceEvents[util.EventFileCreate])
+	})
+
+	t.Run("forgets directories that are no longer polled", func(t *testing.T) {
+		testDir, _, notesDir, _, _ := setupProjectFolders(t)
+		polledDir := filepath.Join(notesDir, "polled")
+		err := os.MkdirAll(polledDir, 0755)
+		assert.NoError(t, err)
+
+		fw := newTestFileWatcher(t, testDir)
+		fw.knownWatchedDirectories.AddPolled(polledDir)
+		fw.pollDirectories()
+		assert.Contains(t, fw.polledEntries, polledDir)
+
+		fw.removeFolderTreeWatches(polledDir)
+		fw.pollDirectories()
+		assert.NotContains(t, fw.polledEntries, polledDir)
+		assert.False(t, fw.knownWatchedDirectories.Has(polledDir))
+	})
+}
+
+func TestDirectoryWatchRegistryPolling(t *testing.T) {
+	registry := NewDirectoryWatchRegistry()
+	registry.Add("watched")
+	registry.AddPolled("watched")
+	registry.AddPolled("polled")
+
+	assert.True(t, registry.Has("polled"))
+	assert.True(t, registry.IsPolled("polled"))
+	assert.False(t, registry.IsPolled("watched"))
+	assert.Equal(t, WatcherStatus{Watched: 1, Polled: 1}, registry.Status())
+	assert.ElementsMatch(t, []string{"watched", "polled"}, registry.Snapshot())
+
+	// A native watch replaces the polling fallback.
+	registry.Add("polled")
+	assert.Equal(t, WatcherStatus{Watched: 2}, registry.Status())
+}
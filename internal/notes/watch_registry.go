@@ -8,20 +8,31 @@ import (
 )
 
 // DirectoryWatchRegistry tracks watched directories across the file watcher and
-// bulk import coordinator.
+// bulk import coordinator. A directory is either watched natively through
+// fsnotify or, once the OS refuses more watches, polled by the file watcher.
 type DirectoryWatchRegistry struct {
-	mu    sync.RWMutex
-	paths util.Set[string]
+	mu     sync.RWMutex
+	paths  util.Set[string]
+	polled util.Set[string]
+}
+
+// WatcherStatus counts the directories watched natively and those that fell
+// back to polling.
+type WatcherStatus struct {
+	Watched int `json:"watched"`
+	Polled  int `json:"polled"`
 }
 
 // NewDirectoryWatchRegistry creates an empty registry for tracked directory watches.
 func NewDirectoryWatchRegistry() *DirectoryWatchRegistry {
 	return &DirectoryWatchRegistry{
-		paths: make(util.Set[string]),
+		paths:  make(util.Set[string]),
+		polled: make(util.Set[string]),
 	}
 }
 
-// Add records a watched directory path in the registry.
+// Add records a natively watched directory path in the registry, replacing
+// any polling fallback for it.
 func (r *DirectoryWatchRegistry) Add(path string) {
 	if path == "" {
 		return
@@ -29,10 +40,26 @@ func (r *DirectoryWatchRegistry) Add(path string) {
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.polled.Remove(path)
 	r.paths.Add(path)
 }
 
-// Remove forgets a watched directory path in the registry.
+// AddPolled records a directory that could not be watched natively and is
+// polled instead. Natively watched paths are left as they are.
+func (r *DirectoryWatchRegistry) AddPolled(path string) {
+	if path == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.paths.Has(path) {
+		return
+	}
+	r.polled.Add(path)
+}
+
+// Remove forgets a watched or polled directory path in the registry.
 func (r *DirectoryWatchRegistry) Remove(path string) {
 	if path == "" {
 		return
@@ -41,28 +68,55 @@ func (r *DirectoryWatchRegistry) Remove(path string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.paths.Remove(path)
+	r.polled.Remove(path)
 }
 
-// Has reports whether the directory path is currently tracked as watched.
+// Has reports whether the directory path is currently tracked, either watched
+// or polled.
 func (r *DirectoryWatchRegistry) Has(path string) bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.paths.Has(path)
+	return r.paths.Has(path) || r.polled.Has(path)
+}
+
+// IsPolled reports whether the directory path is tracked through polling.
+func (r *DirectoryWatchRegistry) IsPolled(path string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.polled.Has(path)
 }
 
-// Snapshot returns a point-in-time copy of all tracked watched directories.
+// Snapshot returns a point-in-time copy of all tracked directories, watched
+// and polled.
 func (r *DirectoryWatchRegistry) Snapshot() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	paths := make([]string, 0, len(r.paths))
+	paths := make([]string, 0, len(r.paths)+len(r.polled))
 	for path := range r.paths {
 		paths = append(paths, path)
 	}
+	for path := range r.polled {
+		paths = append(paths, path)
+	}
 
 	return paths
 }
 
+// PolledSnapshot returns a point-in-time copy of the polled directories.
+func (r *DirectoryWatchRegistry) PolledSnapshot() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.polled.Elements()
+}
+
+// Status returns how many directories are watched natively and polled.
+func (r *DirectoryWatchRegistry) Status() WatcherStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return WatcherStatus{Watched: len(r.paths), Polled: len(r.polled)}
+}
+
 // SyncFromWatcher seeds the registry from the watcher's current directory watch list.
 func (r *DirectoryWatchRegistry) SyncFromWatcher(watcher *fsnotify.Watcher) {
 	if watcher == nil {
@@ -0,0 +1,139 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/etesam913/bytebook/internal/notes"
+)
+
+// UnusedAttachment is an attachment that no note links to or embeds.
+type UnusedAttachment struct {
+	// Path is relative to notes/, e.g. "folder/image.png".
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// FindUnusedAttachments returns the indexed attachments that no note
+// references, sorted by path. References come from the links field, which
+// holds every /notes/ link and image in a note's body, so the result is only
+// as fresh as the index.
+func FindUnusedAttachments(index bleve.Index) ([]UnusedAttachment, error) {
+	linked, err := linkedPaths(index)
+	if err != nil {
+		return nil, err
+	}
+
+	typeQuery := bleve.NewTermQuery(ATTACHMENT_TYPE)
+	typeQuery.SetField(FieldType)
+
+	unused := []UnusedAttachment{}
+	for from := 0; ; from += reconcilePageSize {
+		searchRequest := bleve.NewSearchRequestOptions(typeQuery, reconcilePageSize, from, false)
+		searchRequest.Fields = []string{FieldSize}
+		searchRequest.SortBy([]string{"_id"})
+
+		searchResult, err := index.Search(searchRequest)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range searchResult.Hits {
+			attachmentPath := filepath.ToSlash(hit.ID)
+			if linked[attachmentPath] {
+				continue
+			}
+			attachment := UnusedAttachment{Path: attachmentPath}
+			if size, ok := hit.Fields[FieldSize].(float64); ok {
+				attachment.Size = int64(size)
+			}
+			unused = append(unused, attachment)
+		}
+		if len(searchResult.Hits) < reconcilePageSize {
+			break
+		}
+	}
+
+	sort.Slice(unused, func(i, j int) bool { return unused[i].Path < unused[j].Path })
+	return unused, nil
+}
+
+// StillUnusedAttachments returns the candidates, paths relative to notes/,
+// that no note links to or embeds. Unlike FindUnusedAttachments it reads the
+// current body of every note instead of the index, so an attachment a note
+// started using since it was last indexed is not reported as unused.
+func StillUnusedAttachments(projectPath string, candidates []string) ([]string, error) {
+	filePaths, err := listIndexableFiles(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := map[string]bool{}
+	for _, filePath := range filePaths {
+		if filepath.Ext(filePath) != ".md" {
+			continue
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		for _, link := range notes.GetInternalLinksFromBody(string(content)) {
+			if linkedPath, ok := linkTargetPath(link); ok {
+				linked[linkedPath] = true
+			}
+		}
+	}
+
+	unused := []string{}
+	for _, candidate := range candidates {
+		if !linked[path.Clean(filepath.ToSlash(candidate))] {
+			unused = append(unused, candidate)
+		}
+	}
+	return unused, nil
+}
+
+// linkedPaths returns every path, relative to notes/, that some note links to.
+// The links field is not stored, so its terms are read back with a facet.
+func linkedPaths(index bleve.Index) (map[string]bool, error) {
+	searchRequest := bleve.NewSearchRequest(bleve.NewMatchAllQuery())
+	searchRequest.Size = 0
+	searchRequest.AddFacet(FieldLinks, bleve.NewFacetRequest(FieldLinks, MaxDeleteSearchResults))
+
+	searchResult, err := index.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := map[string]bool{}
+	facetResult := searchResult.Facets[FieldLinks]
+	if facetResult == nil || facetResult.Terms == nil {
+		return linked, nil
+	}
+	for _, term := range facetResult.Terms.Terms() {
+		if linkedPath, ok := linkTargetPath(term.Term); ok {
+			linked[linkedPath] = true
+		}
+	}
+	return linked, nil
+}
+
+// linkTargetPath decodes a /notes/ link into the path it points to, relative
+// to notes/, dropping any query or fragment.
+func linkTargetPath(link string) (string, bool) {
+	if !strings.HasPrefix(link, "/notes/") {
+		return "", false
+	}
+	link, _, _ = strings.Cut(link, "#")
+	link, _, _ = strings.Cut(link, "?")
+	decoded, err := url.PathUnescape(strings.TrimPrefix(link, "/notes/"))
+	if err != nil {
+		return "", false
+	}
+	return path.Clean(decoded), true
+}
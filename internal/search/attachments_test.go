@@ -0,0 +1,79 @@
+package search
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindUnusedAttachments(t *testing.T) {
+	t.Run("lists attachments no note links to or embeds", func(t *testing.T) {
+		projectDir, notesDir := setupTempNotesDir(t)
+		index := createTempIndex(t, projectDir)
+		defer index.Close()
+
+		writeFilesRelative(t, notesDir, map[string]string{
+			filepath.Join("folder", "note.md"): "![shot](/notes/folder/My%20Shot.png)\n\n" +
+				"[report](/notes/other/report.pdf#page=2)\n",
+			filepath.Join("folder", "My Shot.png"):  "png",
+			filepath.Join("folder", "unused.png"):   "unused",
+			filepath.Join("other", "report.pdf"):    "pdf",
+			filepath.Join("other", "forgotten.mov"): "movie",
+		})
+		require.NoError(t, IndexAllFiles(projectDir, index))
+
+		unused, err := FindUnusedAttachments(index)
+		require.NoError(t, err)
+
+		assert.Equal(t, []UnusedAttachment{
+			{Path: "folder/unused.png", Size: 6},
+			{Path: "other/forgotten.mov", Size: 5},
+		}, unused)
+	})
+
+	t.Run("returns an empty list for an empty index", func(t *testing.T) {
+		projectDir, _ := setupTempNotesDir(t)
+		index := createTempIndex(t, projectDir)
+		defer index.Close()
+
+		unused, err := FindUnusedAttachments(index)
+		require.NoError(t, err)
+		assert.Empty(t, unused)
+	})
+}
+
+func TestStillUnusedAttachments(t *testing.T) {
+	projectDir, notesDir := setupTempNotesDir(t)
+	writeFilesRelative(t, notesDir, map[string]string{
+		filepath.Join("folder", "note.md"):     "![shot](/notes/folder/My%20Shot.png)\n",
+		filepath.Join("folder", "My Shot.png"): "png",
+		filepath.Join("folder", "unused.png"):  "unused",
+	})
+
+	unused, err := StillUnusedAttachments(projectDir, []string{"folder/My Shot.png", "folder/unused.png"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"folder/unused.png"}, unused)
+}
+
+func TestLinkTargetPath(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+		ok       bool
+	}{
+		{"/notes/folder/image.png", "folder/image.png", true},
+		{"/notes/My%20Folder/My%20Image.png?v=1", "My Folder/My Image.png", true},
+		{"/notes/folder/note.md#heading", "folder/note.md", true},
+		{"https://example.com/notes/a.png", "", false},
+		{"/notes/bad%zz", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			linkedPath, ok := linkTargetPath(tt.link)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, linkedPath)
+		})
+	}
+}
@@ -25,6 +25,7 @@ const (
 	FieldLastUpdated           = "last_updated"
 	FieldCreatedDate           = "created_date"
 	FieldSize                  = "size"
+	FieldModTime               = "mod_time"
 )
 
 // Fields that should be highlighted in search results
@@ -1,6 +1,7 @@
 package search
 
 import (
+	"fmt"
 	"io/fs"
 	"log"
 	"os"
@@ -9,6 +10,8 @@ import (
 	"sync"
 
 	"github.com/blevesearch/bleve/v2"
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/notes"
 	"github.com/etesam913/bytebook/internal/util"
 )
 
@@ -26,7 +29,7 @@ type DocumentResult struct {
 	document  any
 }
 
-// IndexFiles scans the "notes" directory within the given projectPath for folders containing Markdown files (.md).
+// IndexFiles scans the "notes" directory within the given projectPath, and every linked folder, for folders containing Markdown files (.md).
 // It dispatches indexing jobs for each Markdown file using a pool of worker goroutines.
 // Results are collected and basic information about each indexed document is printed to stdout.
 // Returns an error if any directory or file access fails.
@@ -53,6 +56,7 @@ func IndexAllFiles(projectPath string, bleveIndex bleve.Index) error {
 		if err := populateJobs(folders, notesPath, jobs); err != nil {
 			log.Printf("Error populating jobs (some files may not be indexed): %v", err)
 		}
+		populateLinkedFolderJobs(projectPath, jobs)
 	}()
 
 	go func() {
@@ -60,7 +64,7 @@ func IndexAllFiles(projectPath string, bleveIndex bleve.Index) error {
 		close(results)
 	}()
 
-	bleveBatch, err := addResultsToIndex(bleveIndex, bleveIndex.NewBatch(), results)
+	bleveBatch, err := addResultsToIndex(bleveIndex, bleveIndex.NewBatch(), results, false)
 	if err != nil {
 		log.Printf("Error when adding results to index: %v", err)
 		return err
@@ -95,8 +99,9 @@ func IndexDiscoveredFiles(projectPath string, filePaths []string, bleveIndex ble
 
 	go func() {
 		defer close(jobs)
+		ignore := notes.NewIgnoreMatcher(projectPath)
 		for _, filePath := range filePaths {
-			if shouldSkipIndexedPath(projectPath, filePath) {
+			if shouldSkipIndexedPath(projectPath, filePath, ignore) {
 				continue
 			}
 			job, err := buildDocumentJob(projectPath, filePath)
@@ -113,7 +118,7 @@ func IndexDiscoveredFiles(projectPath string, filePaths []string, bleveIndex ble
 		close(results)
 	}()
 
-	bleveBatch, err := addResultsToIndex(bleveIndex, bleveIndex.NewBatch(), results)
+	bleveBatch, err := addResultsToIndex(bleveIndex, bleveIndex.NewBatch(), results, false)
 	if err != nil {
 		log.Printf("Error when adding discovered results to index: %v", err)
 		return err
@@ -127,21 +132,25 @@ func IndexDiscoveredFiles(projectPath string, filePaths []string, bleveIndex ble
 	return nil
 }
 
-func addResultsToIndex(bleveIndex bleve.Index, bleveBatch *bleve.Batch, results chan DocumentResult) (*bleve.Batch, error) {
+// addResultsToIndex batches worker results into the index. Results for
+// documents that are already indexed are skipped unless overwrite is set.
+func addResultsToIndex(bleveIndex bleve.Index, bleveBatch *bleve.Batch, results <-chan DocumentResult, overwrite bool) (*bleve.Batch, error) {
 	indexCount := 0
 	for docResult := range results {
 		if docResult.isError {
 			continue
 		}
-		document, err := bleveIndex.Document(docResult.entryId)
-		if err != nil {
-			log.Printf("Error when trying to fetch document in adding results: %v", err)
-			continue
-		}
+		if !overwrite {
+			document, err := bleveIndex.Document(docResult.entryId)
+			if err != nil {
+				log.Printf("Error when trying to fetch document in adding results: %v", err)
+				continue
+			}
 
-		// The entry is already indexed, so we can skip here
-		if document != nil {
-			continue
+			// The entry is already indexed, so we can skip here
+			if document != nil {
+				continue
+			}
 		}
 
 		switch doc := docResult.document.(type) {
@@ -168,13 +177,18 @@ func addResultsToIndex(bleveIndex bleve.Index, bleveBatch *bleve.Batch, results
 }
 
 // populateJobs creates DocumentJob tasks for files found recursively inside each
-// top-level folder under notesPath.
+// top-level folder under notesPath. Paths excluded by .bytebookignore files
+// are skipped.
 func populateJobs(folders []os.DirEntry, notesPath string, jobs chan<- DocumentJob) error {
+	ignore := notes.NewIgnoreMatcher(filepath.Dir(notesPath))
 	for _, folder := range folders {
 		// Skip hidden top-level entries, files and folders alike.
 		if strings.HasPrefix(folder.Name(), ".") {
 			continue
 		}
+		if ignore.Match(filepath.Join(notesPath, folder.Name()), folder.IsDir()) {
+			continue
+		}
 
 		// Files sitting at the notes root index with an empty folder, so they
 		// stay reachable from tree filtering like any nested note.
@@ -204,6 +218,13 @@ func populateJobs(folders []os.DirEntry, notesPath string, jobs chan<- DocumentJ
 				return nil
 			}
 
+			if ignore.Match(path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
 			if d.IsDir() {
 				return nil
 			}
@@ -231,21 +252,36 @@ func populateJobs(folders []os.DirEntry, notesPath string, jobs chan<- DocumentJ
 	return nil
 }
 
-func shouldSkipIndexedPath(projectPath, filePath string) bool {
-	notesPath := filepath.Join(projectPath, "notes")
-	relativePath, err := filepath.Rel(notesPath, filePath)
-	if err != nil {
+// populateLinkedFolderJobs creates DocumentJob tasks for the files of every
+// linked folder. Their IDs are prefixed with the linked folder's name, so they
+// are indexed as if the folder sat at the top of notes/.
+func populateLinkedFolderJobs(projectPath string, jobs chan<- DocumentJob) {
+	ignore := notes.NewIgnoreMatcher(projectPath)
+	for _, filePath := range appendLinkedFolderFiles(projectPath, []string{}, ignore) {
+		if shouldSkipIndexedPath(projectPath, filePath, ignore) {
+			continue
+		}
+		job, err := buildDocumentJob(projectPath, filePath)
+		if err != nil {
+			log.Printf("Error building indexing job for %s: %v", filePath, err)
+			continue
+		}
+		jobs <- job
+	}
+}
+
+func shouldSkipIndexedPath(projectPath, filePath string, ignore *notes.IgnoreMatcher) bool {
+	relativePath, ok := config.NotesRelativePath(projectPath, filePath)
+	if !ok {
 		return true
 	}
-	// filepath.Rel returns paths beginning with ".." for files outside the
-	// project notes directory; the dotted-part check skips those paths and
-	// hidden sidecar files.
+	// The dotted-part check skips hidden files and sidecars.
 	for _, pathPart := range strings.Split(relativePath, string(filepath.Separator)) {
 		if strings.HasPrefix(pathPart, ".") {
 			return true
 		}
 	}
-	return false
+	return ignore.Match(filePath, false)
 }
 
 // startWorker processes jobs from the jobs channel, reads the file contents,
@@ -270,11 +306,13 @@ func startWorker(projectPath string, jobs <-chan DocumentJob, results chan<- Doc
 				continue
 			}
 			markdown := string(content)
+			document := CreateMarkdownNoteBleveDocument(markdown, job.folder, job.entryName)
+			document.ModTime = FileModTime(job.entryPath)
 			results <- DocumentResult{
 				isError:   false,
 				entryPath: job.entryPath,
 				entryId:   job.entryId,
-				document:  CreateMarkdownNoteBleveDocument(markdown, job.folder, job.entryName),
+				document:  document,
 			}
 		} else {
 			results <- DocumentResult{
@@ -287,13 +325,12 @@ func startWorker(projectPath string, jobs <-chan DocumentJob, results chan<- Doc
 	}
 }
 
-// buildDocumentJob converts an absolute file path under notes/ into the metadata
-// needed by the existing worker/indexing pipeline.
+// buildDocumentJob converts an absolute file path under notes/ or a linked
+// folder into the metadata needed by the existing worker/indexing pipeline.
 func buildDocumentJob(projectPath, filePath string) (DocumentJob, error) {
-	notesPath := filepath.Join(projectPath, "notes")
-	fileID, err := filepath.Rel(notesPath, filePath)
-	if err != nil {
-		return DocumentJob{}, err
+	fileID, ok := config.NotesRelativePath(projectPath, filePath)
+	if !ok {
+		return DocumentJob{}, fmt.Errorf("%s is outside the notes directory and linked folders", filePath)
 	}
 
 	folderPath, fileName := filepath.Split(fileID)
@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/blevesearch/bleve/v2"
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/notes"
 	"github.com/etesam913/bytebook/internal/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -189,6 +191,24 @@ func TestPopulateJobs(t *testing.T) {
 		assert.Len(t, fileJobs, 1)
 		assert.Equal(t, "visible.md", fileJobs[0].entryName)
 	})
+
+	t.Run("should skip paths matched by .bytebookignore", func(t *testing.T) {
+		_, notesDir := setupTempNotesDir(t)
+
+		writeFilesRelative(t, notesDir, map[string]string{
+			notes.IgnoreFileName:                                  "node_modules/\n*.log\n",
+			filepath.Join("folder1", "visible.md"):                "# Visible",
+			filepath.Join("folder1", "run.log"):                   "output",
+			filepath.Join("folder1", "node_modules", "readme.md"): "# Dependency",
+			filepath.Join("node_modules", "pkg", "index.md"):      "# Top-level dependency",
+		})
+
+		receivedJobs := collectPopulateJobs(t, notesDir)
+		fileJobs := filterFileJobs(receivedJobs)
+
+		assert.Len(t, fileJobs, 1)
+		assert.Equal(t, "visible.md", fileJobs[0].entryName)
+	})
 }
 
 func TestStartWorker(t *testing.T) {
@@ -291,6 +311,45 @@ func TestIndexAllFiles(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, deepAttachmentDoc)
 	})
+
+	t.Run("should index linked folders under their name", func(t *testing.T) {
+		projectDir, notesDir := setupTempNotesDir(t)
+		_, err := config.GetProjectSettings(projectDir)
+		require.NoError(t, err)
+
+		index := createTempIndex(t, projectDir)
+		defer index.Close()
+
+		docsDir := filepath.Join(t.TempDir(), "docs")
+		writeFilesRelative(t, docsDir, map[string]string{
+			filepath.Join("guide", "intro.md"):  "# Intro",
+			filepath.Join("guide", "image.png"): "binary content",
+		})
+		writeFilesRelative(t, notesDir, map[string]string{
+			filepath.Join("folder", "note.md"): "# Note",
+		})
+		require.NoError(t, config.AddLinkedFolder(projectDir, config.LinkedFolderJson{Name: "docs", Path: docsDir}))
+
+		require.NoError(t, IndexAllFiles(projectDir, index))
+
+		for _, docID := range []string{
+			filepath.Join("folder", "note.md"),
+			filepath.Join("docs", "guide", "intro.md"),
+			filepath.Join("docs", "guide", "image.png"),
+		} {
+			doc, err := index.Document(docID)
+			require.NoError(t, err)
+			assert.NotNil(t, doc, docID)
+		}
+
+		filePaths, err := listIndexableFiles(projectDir)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			filepath.Join(notesDir, "folder", "note.md"),
+			filepath.Join(docsDir, "guide", "intro.md"),
+			filepath.Join(docsDir, "guide", "image.png"),
+		}, filePaths)
+	})
 }
 
 func TestIndexDiscoveredFilesSkipsHiddenSidecars(t *testing.T) {
@@ -316,3 +375,28 @@ func TestIndexDiscoveredFilesSkipsHiddenSidecars(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, sidecarDoc)
 }
+
+func TestIndexDiscoveredFilesSkipsIgnoredPaths(t *testing.T) {
+	projectDir, notesDir := setupTempNotesDir(t)
+	index := createTempIndex(t, projectDir)
+	defer index.Close()
+
+	writeFilesRelative(t, notesDir, map[string]string{
+		filepath.Join("folder", notes.IgnoreFileName): "drafts/\n",
+		filepath.Join("folder", "note.md"):            "# Visible",
+		filepath.Join("folder", "drafts", "wip.md"):   "# Draft",
+	})
+
+	require.NoError(t, IndexDiscoveredFiles(projectDir, []string{
+		filepath.Join(notesDir, "folder", "note.md"),
+		filepath.Join(notesDir, "folder", "drafts", "wip.md"),
+	}, index, 1))
+
+	noteDoc, err := index.Document(filepath.Join("folder", "note.md"))
+	require.NoError(t, err)
+	assert.NotNil(t, noteDoc)
+
+	draftDoc, err := index.Document(filepath.Join("folder", "drafts", "wip.md"))
+	require.NoError(t, err)
+	assert.Nil(t, draftDoc)
+}
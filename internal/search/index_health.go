@@ -0,0 +1,166 @@
+package search
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/notes"
+)
+
+// IndexSchemaVersion identifies the document mapping and stored fields the
+// app writes. Bump it whenever createIndex or the document structs change in
+// a way existing indexes cannot serve; indexes with any other version are
+// rebuilt on startup.
+//
+//	1: unversioned indexes from before the version was stored
+//	2: mod_time stored for startup reconciliation
+const IndexSchemaVersion = 2
+
+// schemaVersionKey is the bleve internal key holding the schema version.
+var schemaVersionKey = []byte("bytebook_schema_version")
+
+// IndexHealth compares the search index against the files under notes/.
+type IndexHealth struct {
+	SchemaVersion         int  `json:"schemaVersion"`
+	ExpectedSchemaVersion int  `json:"expectedSchemaVersion"`
+	DocumentCount         int  `json:"documentCount"`
+	FileCount             int  `json:"fileCount"`
+	MissingCount          int  `json:"missingCount"`  // files on disk with no document
+	StaleCount            int  `json:"staleCount"`    // documents whose file is gone
+	OutdatedCount         int  `json:"outdatedCount"` // documents older than their file
+	Healthy               bool `json:"healthy"`
+}
+
+// stampSchemaVersion records the current schema version in index.
+func stampSchemaVersion(index bleve.Index) error {
+	return index.SetInternal(schemaVersionKey, []byte(strconv.Itoa(IndexSchemaVersion)))
+}
+
+// SchemaVersionOf returns the schema version stored in index. Indexes created
+// before versioning report 1.
+func SchemaVersionOf(index bleve.Index) (int, error) {
+	raw, err := index.GetInternal(schemaVersionKey)
+	if err != nil {
+		return 0, err
+	}
+	if raw == nil {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(string(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid index schema version %q", raw)
+	}
+	return version, nil
+}
+
+// HasCurrentSchema reports whether index was built with IndexSchemaVersion.
+func HasCurrentSchema(index bleve.Index) bool {
+	version, err := SchemaVersionOf(index)
+	if err != nil {
+		log.Printf("Error reading index schema version: %v", err)
+		return false
+	}
+	return version == IndexSchemaVersion
+}
+
+// quarantineIndex moves an index that cannot be opened aside so a fresh one
+// can take its place, keeping the old data for inspection.
+func quarantineIndex(pathToIndex string) (string, error) {
+	quarantinePath := pathToIndex + ".corrupt-" + time.Now().Format("20060102-150405")
+	if err := os.Rename(pathToIndex, quarantinePath); err != nil {
+		return "", err
+	}
+	return quarantinePath, nil
+}
+
+// CheckIndexHealth counts the documents in index and compares them with the
+// indexable files under notes/, applying the same hidden and ignore rules as
+// indexing. The index is healthy when its schema is current and every file
+// has an up-to-date document.
+func CheckIndexHealth(projectPath string, index bleve.Index) (IndexHealth, error) {
+	health := IndexHealth{ExpectedSchemaVersion: IndexSchemaVersion}
+
+	version, err := SchemaVersionOf(index)
+	if err != nil {
+		return health, err
+	}
+	health.SchemaVersion = version
+
+	filePaths, err := listIndexableFiles(projectPath)
+	if err != nil {
+		return health, err
+	}
+	indexed, err := indexedFileStates(index)
+	if err != nil {
+		return health, err
+	}
+
+	diff := diffIndexAgainstDisk(projectPath, filePaths, indexed)
+	health.DocumentCount = len(indexed)
+	health.FileCount = len(diff.changed) + diff.unchanged
+	health.MissingCount = diff.missing
+	health.StaleCount = len(diff.stale)
+	health.OutdatedCount = len(diff.changed) - diff.missing
+	health.Healthy = health.SchemaVersion == health.ExpectedSchemaVersion &&
+		health.MissingCount == 0 &&
+		health.StaleCount == 0 &&
+		health.OutdatedCount == 0
+	return health, nil
+}
+
+// listIndexableFiles walks notes/ and every linked folder and returns each
+// file outside hidden and ignored directories. Individual files are filtered
+// later by shouldSkipIndexedPath.
+func listIndexableFiles(projectPath string) ([]string, error) {
+	ignore := notes.NewIgnoreMatcher(projectPath)
+	filePaths, err := appendIndexableFiles([]string{}, filepath.Join(projectPath, "notes"), ignore)
+	if err != nil {
+		return filePaths, err
+	}
+	return appendLinkedFolderFiles(projectPath, filePaths, ignore), nil
+}
+
+// appendLinkedFolderFiles appends the indexable files of every linked folder.
+// A linked folder that cannot be walked, such as one on an unmounted drive, is
+// logged and skipped so it does not hold up the rest of the vault.
+func appendLinkedFolderFiles(projectPath string, filePaths []string, ignore *notes.IgnoreMatcher) []string {
+	for _, folder := range config.LinkedFolders(projectPath) {
+		linkedPaths, err := appendIndexableFiles([]string{}, folder.Path, ignore)
+		if err != nil {
+			log.Printf("Error listing linked folder %s: %v", folder.Name, err)
+			continue
+		}
+		filePaths = append(filePaths, linkedPaths...)
+	}
+	return filePaths
+}
+
+// appendIndexableFiles walks root and appends every file outside hidden and
+// ignored directories.
+func appendIndexableFiles(filePaths []string, root string, ignore *notes.IgnoreMatcher) ([]string, error) {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || ignore.Match(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		filePaths = append(filePaths, path)
+		return nil
+	})
+	return filePaths, err
+}
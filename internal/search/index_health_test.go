@@ -0,0 +1,118 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexSchemaVersion(t *testing.T) {
+	t.Run("new indexes are stamped with the current version", func(t *testing.T) {
+		projectDir, _ := setupTempNotesDir(t)
+		index := createTempIndex(t, projectDir)
+		defer index.Close()
+
+		version, err := SchemaVersionOf(index)
+		require.NoError(t, err)
+		assert.Equal(t, IndexSchemaVersion, version)
+		assert.True(t, HasCurrentSchema(index))
+	})
+
+	t.Run("unversioned indexes report version 1", func(t *testing.T) {
+		projectDir, _ := setupTempNotesDir(t)
+		index := createTempIndex(t, projectDir)
+		defer index.Close()
+		require.NoError(t, index.DeleteInternal(schemaVersionKey))
+
+		version, err := SchemaVersionOf(index)
+		require.NoError(t, err)
+		assert.Equal(t, 1, version)
+		assert.False(t, HasCurrentSchema(index))
+	})
+
+	t.Run("regenerating replaces an outdated index with a current one", func(t *testing.T) {
+		projectDir, notesDir := setupTempNotesDir(t)
+		writeFilesRelative(t, notesDir, map[string]string{
+			filepath.Join("folder", "note.md"): "# Note",
+		})
+		index := createTempIndex(t, projectDir)
+		require.NoError(t, index.DeleteInternal(schemaVersionKey))
+
+		regenerated, err := RegenerateSearchIndex(projectDir, index)
+		require.NoError(t, err)
+		defer regenerated.Close()
+
+		assert.True(t, HasCurrentSchema(regenerated))
+		doc, err := regenerated.Document(filepath.Join("folder", "note.md"))
+		require.NoError(t, err)
+		assert.NotNil(t, doc)
+	})
+}
+
+func TestOpenOrCreateIndexQuarantinesCorruptIndex(t *testing.T) {
+	projectDir, _ := setupTempNotesDir(t)
+	indexPath := GetPathToIndex(projectDir)
+	require.NoError(t, os.MkdirAll(indexPath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(indexPath, "index_meta.json"), []byte("not json"), 0644))
+
+	index, err := OpenOrCreateIndex(projectDir)
+	require.NoError(t, err)
+	defer index.Close()
+	assert.True(t, HasCurrentSchema(index))
+
+	quarantined, err := filepath.Glob(indexPath + ".corrupt-*")
+	require.NoError(t, err)
+	require.Len(t, quarantined, 1)
+	content, err := os.ReadFile(filepath.Join(quarantined[0], "index_meta.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "not json", string(content))
+}
+
+func TestCheckIndexHealth(t *testing.T) {
+	projectDir, notesDir := setupTempNotesDir(t)
+	index := createTempIndex(t, projectDir)
+	defer index.Close()
+
+	writeFilesRelative(t, notesDir, map[string]string{
+		filepath.Join("folder", "indexed.md"):  "# Indexed",
+		filepath.Join("folder", "image.png"):   "binary",
+		filepath.Join(".hidden", "skipped.md"): "# Hidden",
+	})
+	_, err := ReconcileIndex(projectDir, []string{
+		filepath.Join(notesDir, "folder", "indexed.md"),
+		filepath.Join(notesDir, "folder", "image.png"),
+	}, index, 1, nil)
+	require.NoError(t, err)
+
+	health, err := CheckIndexHealth(projectDir, index)
+	require.NoError(t, err)
+	assert.Equal(t, IndexHealth{
+		SchemaVersion:         IndexSchemaVersion,
+		ExpectedSchemaVersion: IndexSchemaVersion,
+		DocumentCount:         2,
+		FileCount:             2,
+		Healthy:               true,
+	}, health)
+
+	writeFilesRelative(t, notesDir, map[string]string{
+		filepath.Join("folder", "new.md"): "# New",
+	})
+	require.NoError(t, os.Remove(filepath.Join(notesDir, "folder", "image.png")))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "folder", "indexed.md"), []byte("# Indexed and edited"), 0644))
+
+	health, err = CheckIndexHealth(projectDir, index)
+	require.NoError(t, err)
+	assert.Equal(t, IndexHealth{
+		SchemaVersion:         IndexSchemaVersion,
+		ExpectedSchemaVersion: IndexSchemaVersion,
+		DocumentCount:         2,
+		FileCount:             2,
+		MissingCount:          1,
+		StaleCount:            1,
+		OutdatedCount:         1,
+		Healthy:               false,
+	}, health)
+}
@@ -1,6 +1,7 @@
 package search
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
 	_ "github.com/blevesearch/bleve/v2/analysis/token/ngram"
 	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/notes"
 	"github.com/etesam913/bytebook/internal/notes/sidecar"
 	"github.com/etesam913/bytebook/internal/util"
@@ -69,6 +71,7 @@ type MarkdownNoteBleveDocument struct {
 	LastUpdated           string   `json:"last_updated"`
 	CreatedDate           string   `json:"created_date"`
 	Size                  int64    `json:"size"`
+	ModTime               string   `json:"mod_time"`
 }
 
 type AttachmentBleveDocument struct {
@@ -80,6 +83,7 @@ type AttachmentBleveDocument struct {
 	Tags          []string `json:"tags"`
 	CreatedDate   string   `json:"created_date"`
 	Size          int64    `json:"size"`
+	ModTime       string   `json:"mod_time"`
 }
 
 // DocumentIndexInfo contains information about a document's presence in the search index.
@@ -123,6 +127,21 @@ func CreateMarkdownNoteBleveDocument(markdown, folder, fileName string) Markdown
 	}
 }
 
+// FileModTime returns the modification time of the file at path in the form
+// stored in the mod_time field, or "" if the file cannot be statted. Startup
+// reconciliation compares it against disk to skip unchanged files.
+func FileModTime(path string) string {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return formatModTime(fileInfo.ModTime())
+}
+
+func formatModTime(modTime time.Time) string {
+	return modTime.UTC().Format(time.RFC3339Nano)
+}
+
 // createAttachmentBleveDocument constructs an AttachmentBleveDocument from file information.
 // It extracts the filename, file extension, and attachment tags for search indexing.
 func createAttachmentBleveDocument(projectPath, folder, fileName, fileExtension string) AttachmentBleveDocument {
@@ -133,11 +152,16 @@ func createAttachmentBleveDocument(projectPath, folder, fileName, fileExtension
 
 	size := int64(0)
 	createdDate := ""
-	attachmentPath := filepath.Join(projectPath, "notes", folder, fileName)
+	modTime := ""
+	attachmentPath, err := config.ResolveNotesPath(projectPath, filepath.Join(folder, fileName))
+	if err != nil {
+		attachmentPath = filepath.Join(projectPath, "notes", folder, fileName)
+	}
 	fileInfo, statErr := os.Stat(attachmentPath)
 	if statErr == nil {
 		size = fileInfo.Size()
 		createdDate = fileInfo.ModTime().UTC().Format(time.RFC3339)
+		modTime = formatModTime(fileInfo.ModTime())
 	}
 
 	return AttachmentBleveDocument{
@@ -149,6 +173,7 @@ func createAttachmentBleveDocument(projectPath, folder, fileName, fileExtension
 		Tags:          tags,
 		CreatedDate:   createdDate,
 		Size:          size,
+		ModTime:       modTime,
 	}
 }
 
@@ -221,28 +246,34 @@ func createIndex(pathToIndex string) (bleve.Index, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := stampSchemaVersion(index); err != nil {
+		index.Close()
+		return nil, err
+	}
 
 	return index, nil
 }
 
 // OpenOrCreateIndex opens an existing search index or creates a new one if it doesn't exist.
-// It first checks if an index exists at the project path, and if so, opens it.
-// If no index exists, it creates a new one. Returns the index or an error if the operation fails.
+// An index that exists but cannot be opened is treated as corrupt: it is moved
+// aside and replaced with an empty index, which the startup reconciliation
+// then fills. Callers should check HasCurrentSchema on the result, since an
+// index from an older release opens fine but may be missing fields.
 func OpenOrCreateIndex(projectPath string) (bleve.Index, error) {
-	indexExists := doesIndexExist(projectPath)
-	if indexExists {
-		openedIndex, err := bleve.Open(GetPathToIndex(projectPath))
-		if err != nil {
-			return nil, err
+	pathToIndex := GetPathToIndex(projectPath)
+	if doesIndexExist(projectPath) {
+		openedIndex, err := bleve.Open(pathToIndex)
+		if err == nil {
+			return openedIndex, nil
 		}
-		return openedIndex, nil
+		quarantinePath, quarantineErr := quarantineIndex(pathToIndex)
+		if quarantineErr != nil {
+			return nil, fmt.Errorf("open index: %w (quarantine failed: %v)", err, quarantineErr)
+		}
+		log.Printf("Search index could not be opened (%v); moved it to %s and creating a new one", err, quarantinePath)
 	}
 
-	index, err := createIndex(GetPathToIndex(projectPath))
-	if err != nil {
-		return nil, err
-	}
-	return index, nil
+	return createIndex(pathToIndex)
 }
 
 // createMarkdownNoteDocumentMapping creates a Bleve document mapping for markdown notes.
@@ -311,10 +342,21 @@ func createMarkdownNoteDocumentMapping() *mapping.DocumentMapping {
 	documentMapping.AddFieldMappingsAt(FieldLastUpdated, lastUpdatedFieldMapping)
 	documentMapping.AddFieldMappingsAt(FieldCreatedDate, createdDateFieldMapping)
 	documentMapping.AddFieldMappingsAt(FieldSize, sizeFieldMapping)
+	documentMapping.AddFieldMappingsAt(FieldModTime, modTimeFieldMapping())
 
 	return documentMapping
 }
 
+// modTimeFieldMapping stores the file modification time for reconciliation
+// without indexing it; nothing searches on it.
+func modTimeFieldMapping() *mapping.FieldMapping {
+	fieldMapping := bleve.NewTextFieldMapping()
+	fieldMapping.Analyzer = "keyword"
+	fieldMapping.Store = true
+	fieldMapping.Index = false
+	return fieldMapping
+}
+
 // createAttachmentDocumentMapping creates a Bleve document mapping for attachments.
 // It defines field mappings for all the fields in AttachmentBleveDocument to enable
 // proper indexing and searching of attachment metadata.
@@ -345,6 +387,7 @@ func createAttachmentDocumentMapping() *mapping.DocumentMapping {
 	documentMapping.AddFieldMappingsAt(FieldTags, keywordTextFieldMapping)
 	documentMapping.AddFieldMappingsAt(FieldCreatedDate, createdDateFieldMapping)
 	documentMapping.AddFieldMappingsAt(FieldSize, sizeFieldMapping)
+	documentMapping.AddFieldMappingsAt(FieldModTime, modTimeFieldMapping())
 
 	return documentMapping
 }
@@ -376,6 +419,7 @@ func AddMarkdownNoteToBatch(
 
 	if docInfo == nil || forceIndex {
 		bleveDocument := CreateMarkdownNoteBleveDocument(markdown, folderName, fileName)
+		bleveDocument.ModTime = FileModTime(filePath)
 		batch.Index(fileId, bleveDocument)
 	}
 	return fileId, nil
@@ -432,6 +476,13 @@ func IndexFilesInFolderWithBatch(
 	if err != nil {
 		return batch, err
 	}
+	// folderName may be nested, so walk up one level per segment to reach
+	// notes/ before resolving ignore rules.
+	notesPath := folderPath
+	for range strings.Split(filepath.ToSlash(folderName), "/") {
+		notesPath = filepath.Dir(notesPath)
+	}
+	ignore := notes.NewIgnoreMatcher(filepath.Dir(notesPath))
 
 	for _, file := range files {
 		// Ignore hidden files and folders.
@@ -444,6 +495,9 @@ func IndexFilesInFolderWithBatch(
 		}
 
 		filePath := filepath.Join(folderPath, file.Name())
+		if ignore.Match(filePath, false) {
+			continue
+		}
 
 		if strings.HasSuffix(file.Name(), ".md") {
 			// Handle markdown files
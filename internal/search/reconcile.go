@@ -0,0 +1,245 @@
+package search
+
+import (
+	"log"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/etesam913/bytebook/internal/notes"
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+const (
+	// reconcilePageSize is how many indexed documents are loaded per search
+	// request while comparing the index against disk.
+	reconcilePageSize = 5000
+	// reconcileProgressInterval is how many re-indexed files pass between
+	// progress reports.
+	reconcileProgressInterval = 250
+)
+
+// ReconcileStats summarizes a reconciliation pass.
+type ReconcileStats struct {
+	Indexed   int
+	Deleted   int
+	Unchanged int
+}
+
+// indexedFileState is the stored file metadata of one indexed document.
+type indexedFileState struct {
+	modTime string
+	size    int64
+}
+
+// ReconcileIndex brings the index in line with filePaths, the files currently
+// under notes/. Documents whose stored modification time and size still match
+// disk are left alone, changed or new files are re-indexed, and documents for
+// files that no longer exist (or are now hidden or ignored) are deleted. This
+// replaces a full re-import on startup, where most files are unchanged.
+//
+// onProgress, if non-nil, is called as the pass advances and once at the end
+// with phase "done".
+func ReconcileIndex(
+	projectPath string,
+	filePaths []string,
+	bleveIndex bleve.Index,
+	workerCount int,
+	onProgress func(util.SearchReconcileProgressEventData),
+) (ReconcileStats, error) {
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+	report := func(progress util.SearchReconcileProgressEventData) {
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+
+	report(util.SearchReconcileProgressEventData{Phase: "comparing", Total: len(filePaths)})
+	indexed, err := indexedFileStates(bleveIndex)
+	if err != nil {
+		return ReconcileStats{}, err
+	}
+
+	diff := diffIndexAgainstDisk(projectPath, filePaths, indexed)
+	stats := ReconcileStats{Unchanged: diff.unchanged}
+	changed := diff.changed
+
+	batch := bleveIndex.NewBatch()
+	for _, docID := range diff.stale {
+		batch.Delete(docID)
+		stats.Deleted++
+		if batch.Size() >= DefaultBatchSize {
+			if err := bleveIndex.Batch(batch); err != nil {
+				return stats, err
+			}
+			batch = bleveIndex.NewBatch()
+		}
+	}
+	if err := FlushBatch(bleveIndex, batch); err != nil {
+		return stats, err
+	}
+
+	if err := reindexJobs(projectPath, changed, bleveIndex, workerCount, func(processed int) {
+		report(util.SearchReconcileProgressEventData{
+			Phase:     "indexing",
+			Processed: processed,
+			Total:     len(changed),
+		})
+	}); err != nil {
+		return stats, err
+	}
+	stats.Indexed = len(changed)
+
+	report(util.SearchReconcileProgressEventData{
+		Phase:     "done",
+		Processed: len(changed),
+		Total:     len(changed),
+		Indexed:   stats.Indexed,
+		Deleted:   stats.Deleted,
+		Unchanged: stats.Unchanged,
+	})
+	return stats, nil
+}
+
+// indexDiff is the difference between the index and the files on disk.
+type indexDiff struct {
+	// changed holds jobs for files that are missing from the index or whose
+	// stored modification time or size no longer matches disk.
+	changed []DocumentJob
+	// missing counts the changed files that have no document at all.
+	missing int
+	// stale holds IDs of documents whose file is gone, hidden or ignored.
+	stale     []string
+	unchanged int
+}
+
+// diffIndexAgainstDisk compares indexed, as loaded by indexedFileStates,
+// against filePaths, applying the same hidden and ignore rules as indexing.
+func diffIndexAgainstDisk(projectPath string, filePaths []string, indexed map[string]indexedFileState) indexDiff {
+	diff := indexDiff{}
+	ignore := notes.NewIgnoreMatcher(projectPath)
+	onDisk := make(util.Set[string], len(filePaths))
+	for _, filePath := range filePaths {
+		if shouldSkipIndexedPath(projectPath, filePath, ignore) {
+			continue
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			// Removed since discovery; its document counts as stale.
+			continue
+		}
+		job, err := buildDocumentJob(projectPath, filePath)
+		if err != nil {
+			log.Printf("Error building indexing job for %s: %v", filePath, err)
+			continue
+		}
+		onDisk.Add(job.entryId)
+
+		state, ok := indexed[job.entryId]
+		if !ok {
+			diff.missing++
+		} else if state.modTime == formatModTime(info.ModTime()) && state.size == info.Size() {
+			diff.unchanged++
+			continue
+		}
+		diff.changed = append(diff.changed, job)
+	}
+
+	for docID := range indexed {
+		if !onDisk.Has(docID) {
+			diff.stale = append(diff.stale, docID)
+		}
+	}
+	sort.Strings(diff.stale)
+	return diff
+}
+
+// indexedFileStates loads the stored modification time and size of every
+// document in the index, keyed by document ID. Documents indexed before
+// mod_time was stored come back with an empty modTime and are re-indexed.
+func indexedFileStates(bleveIndex bleve.Index) (map[string]indexedFileState, error) {
+	states := map[string]indexedFileState{}
+	for from := 0; ; from += reconcilePageSize {
+		searchRequest := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), reconcilePageSize, from, false)
+		searchRequest.Fields = []string{FieldModTime, FieldSize}
+		searchRequest.SortBy([]string{"_id"})
+
+		searchResult, err := bleveIndex.Search(searchRequest)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range searchResult.Hits {
+			state := indexedFileState{}
+			if modTime, ok := hit.Fields[FieldModTime].(string); ok {
+				state.modTime = modTime
+			}
+			if size, ok := hit.Fields[FieldSize].(float64); ok {
+				state.size = int64(size)
+			}
+			states[hit.ID] = state
+		}
+		if len(searchResult.Hits) < reconcilePageSize {
+			return states, nil
+		}
+	}
+}
+
+// reindexJobs indexes jobs with bounded worker concurrency, overwriting any
+// existing documents. onProcessed receives the running count every
+// reconcileProgressInterval results and once at the end.
+func reindexJobs(projectPath string, jobsToRun []DocumentJob, bleveIndex bleve.Index, workerCount int, onProcessed func(int)) error {
+	if len(jobsToRun) == 0 {
+		return nil
+	}
+
+	jobs := make(chan DocumentJob, util.MAX_JOBS)
+	results := make(chan DocumentResult, util.MAX_JOBS)
+	counted := make(chan DocumentResult, util.MAX_JOBS)
+	var workerWaitGroup sync.WaitGroup
+
+	for w := 0; w < workerCount; w++ {
+		workerWaitGroup.Add(1)
+		go startWorker(projectPath, jobs, results, &workerWaitGroup)
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, job := range jobsToRun {
+			jobs <- job
+		}
+	}()
+
+	go func() {
+		workerWaitGroup.Wait()
+		close(results)
+	}()
+
+	// Count results on their way to the index so progress reflects work the
+	// workers have finished.
+	go func() {
+		defer close(counted)
+		processed := 0
+		for result := range results {
+			counted <- result
+			processed++
+			if processed%reconcileProgressInterval == 0 {
+				onProcessed(processed)
+			}
+		}
+		if processed%reconcileProgressInterval != 0 {
+			onProcessed(processed)
+		}
+	}()
+
+	bleveBatch, err := addResultsToIndex(bleveIndex, bleveIndex.NewBatch(), counted, true)
+	if err != nil {
+		// Drain so the counting goroutine and workers can exit.
+		for range counted {
+		}
+		return err
+	}
+	return FlushBatch(bleveIndex, bleveBatch)
+}
@@ -0,0 +1,137 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func discoverTestFiles(t *testing.T, notesDir string) []string {
+	t.Helper()
+	var filePaths []string
+	err := filepath.WalkDir(notesDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			filePaths = append(filePaths, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return filePaths
+}
+
+func TestReconcileIndex(t *testing.T) {
+	t.Run("indexes everything into an empty index", func(t *testing.T) {
+		projectDir, notesDir := setupTempNotesDir(t)
+		index := createTempIndex(t, projectDir)
+		defer index.Close()
+
+		writeFilesRelative(t, notesDir, map[string]string{
+			filepath.Join("folder", "note.md"):   "# Note",
+			filepath.Join("folder", "image.png"): "binary",
+		})
+
+		stats, err := ReconcileIndex(projectDir, discoverTestFiles(t, notesDir), index, 2, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ReconcileStats{Indexed: 2}, stats)
+
+		doc, err := index.Document(filepath.Join("folder", "note.md"))
+		require.NoError(t, err)
+		assert.NotNil(t, doc)
+	})
+
+	t.Run("skips unchanged files, reindexes changed ones and deletes stale documents", func(t *testing.T) {
+		projectDir, notesDir := setupTempNotesDir(t)
+		index := createTempIndex(t, projectDir)
+		defer index.Close()
+
+		writeFilesRelative(t, notesDir, map[string]string{
+			filepath.Join("folder", "same.md"):    "# Same",
+			filepath.Join("folder", "changed.md"): "# Before",
+			filepath.Join("folder", "deleted.md"): "# Deleted",
+		})
+		_, err := ReconcileIndex(projectDir, discoverTestFiles(t, notesDir), index, 2, nil)
+		require.NoError(t, err)
+
+		changedPath := filepath.Join(notesDir, "folder", "changed.md")
+		require.NoError(t, os.WriteFile(changedPath, []byte("# After the edit"), 0644))
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(changedPath, later, later))
+		require.NoError(t, os.Remove(filepath.Join(notesDir, "folder", "deleted.md")))
+		writeFilesRelative(t, notesDir, map[string]string{
+			filepath.Join("folder", "new.md"): "# New",
+		})
+
+		var progress []util.SearchReconcileProgressEventData
+		stats, err := ReconcileIndex(projectDir, discoverTestFiles(t, notesDir), index, 2, func(p util.SearchReconcileProgressEventData) {
+			progress = append(progress, p)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ReconcileStats{Indexed: 2, Deleted: 1, Unchanged: 1}, stats)
+
+		deletedDoc, err := index.Document(filepath.Join("folder", "deleted.md"))
+		require.NoError(t, err)
+		assert.Nil(t, deletedDoc)
+
+		editQuery := bleve.NewMatchQuery("edit")
+		editQuery.SetField(FieldTextContent)
+		results, err := index.Search(bleve.NewSearchRequest(editQuery))
+		require.NoError(t, err)
+		require.Len(t, results.Hits, 1)
+		assert.Equal(t, filepath.Join("folder", "changed.md"), results.Hits[0].ID)
+
+		require.NotEmpty(t, progress)
+		assert.Equal(t, "comparing", progress[0].Phase)
+		assert.Equal(t, util.SearchReconcileProgressEventData{
+			Phase:     "done",
+			Processed: 2,
+			Total:     2,
+			Indexed:   2,
+			Deleted:   1,
+			Unchanged: 1,
+		}, progress[len(progress)-1])
+	})
+
+	t.Run("reindexes documents stored without a modification time", func(t *testing.T) {
+		projectDir, notesDir := setupTempNotesDir(t)
+		index := createTempIndex(t, projectDir)
+		defer index.Close()
+
+		writeFilesRelative(t, notesDir, map[string]string{
+			filepath.Join("folder", "legacy.md"): "# Legacy",
+		})
+		legacy := CreateMarkdownNoteBleveDocument("# Legacy", "folder", "legacy.md")
+		require.NoError(t, index.Index(filepath.Join("folder", "legacy.md"), legacy))
+
+		stats, err := ReconcileIndex(projectDir, discoverTestFiles(t, notesDir), index, 1, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ReconcileStats{Indexed: 1}, stats)
+
+		stats, err = ReconcileIndex(projectDir, discoverTestFiles(t, notesDir), index, 1, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ReconcileStats{Unchanged: 1}, stats)
+	})
+
+	t.Run("deletes documents for hidden files", func(t *testing.T) {
+		projectDir, notesDir := setupTempNotesDir(t)
+		index := createTempIndex(t, projectDir)
+		defer index.Close()
+
+		writeFilesRelative(t, notesDir, map[string]string{
+			filepath.Join("folder", ".note.json"): "{}",
+		})
+		require.NoError(t, index.Index(filepath.Join("folder", ".note.json"), map[string]any{"type": ATTACHMENT_TYPE}))
+
+		stats, err := ReconcileIndex(projectDir, discoverTestFiles(t, notesDir), index, 1, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ReconcileStats{Deleted: 1}, stats)
+	})
+}
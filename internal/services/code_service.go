@@ -5,9 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/jupyter_protocol"
+	"github.com/etesam913/bytebook/internal/jupyter_protocol/sockets"
 	"github.com/etesam913/bytebook/internal/kernel_manager"
+	"github.com/etesam913/bytebook/internal/notes"
 	"github.com/etesam913/bytebook/internal/util"
 	"github.com/pebbe/zmq4"
 	"github.com/wailsapp/wails/v3/pkg/application"
@@ -24,10 +32,106 @@ type CodeService struct {
 // frontend so subsequent control calls (interrupt, shutdown) can target it directly.
 type SendExecuteRequestResponse struct {
 	KernelInstanceID string `json:"kernelInstanceId"`
+	// CheckRequirements is set the first time the note runs on this kernel,
+	// so the frontend can call CheckNoteRequirements and offer to install
+	// what is missing without holding up the execution.
+	CheckRequirements bool `json:"checkRequirements,omitempty"`
+	// EnvironmentMismatch is set on a failed request when the scope's kernel
+	// runs in another Python environment than the note asks for, so the
+	// frontend can offer to restart KernelInstanceID in the right one.
+	EnvironmentMismatch bool `json:"environmentMismatch,omitempty"`
 }
 
-// SendExecuteRequest resolves (language, noteId) to a kernel instance (creating it if
-// needed, evicting LRU idle if at the per-language cap), then sends the execute_request.
+// kernelScopeForNote resolves which kernel a note's code runs in. A
+// `kernelScope` frontmatter key on the note wins over the language's scope
+// setting; an unreadable note or unknown scope falls back to the setting. A
+// Python note that picks its own environment always gets its own kernel, so
+// it never shares one started in a different environment.
+func (c *CodeService) kernelScopeForNote(noteID, language string, projectSettings config.ProjectSettingsJson) kernel_manager.KernelScope {
+	scopeType := projectSettings.Code.KernelSettingsFor(language).Scope
+	noteScope, exists, err := notes.GetKernelScopeFromNote(c.ProjectPath, noteID)
+	if err != nil {
+		log.Printf("kernelScopeForNote: read frontmatter for %s: %v", noteID, err)
+	} else if exists && slices.Contains(config.ValidKernelScopes, noteScope) {
+		scopeType = noteScope
+	}
+	if language == "python" && filepath.Clean(c.pythonEnvironmentForNote(noteID, projectSettings)) != filepath.Clean(projectSettings.Code.PythonVenvPath) {
+		scopeType = config.KernelScopeNote
+	}
+	return kernel_manager.NewKernelScope(scopeType, noteID)
+}
+
+// pythonEnvironmentForNote resolves the Python environment a note's kernel
+// runs in. A `pythonEnvironment` frontmatter key wins over the pythonVenvPath
+// setting; relative paths are resolved against the project's code folder so
+// notes stay portable between machines.
+func (c *CodeService) pythonEnvironmentForNote(noteID string, projectSettings config.ProjectSettingsJson) string {
+	environment, exists, err := notes.GetPythonEnvironmentFromNote(c.ProjectPath, noteID)
+	if err != nil {
+		log.Printf("pythonEnvironmentForNote: read frontmatter for %s: %v", noteID, err)
+		return projectSettings.Code.PythonVenvPath
+	}
+	if !exists {
+		return projectSettings.Code.PythonVenvPath
+	}
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(environment, "~/") {
+		environment = filepath.Join(home, environment[2:])
+	}
+	if !filepath.IsAbs(environment) {
+		environment = filepath.Join(c.ProjectPath, "code", environment)
+	}
+	return filepath.Clean(environment)
+}
+
+// needsVirtualEnvironment reports whether launching a kernel for scope is
+// blocked on a missing Python environment. A kernel that already serves the
+// scope, e.g. an attached one, needs no environment.
+func (c *CodeService) needsVirtualEnvironment(language string, scope kernel_manager.KernelScope, venvPath string) bool {
+	return language == "python" && !util.IsPythonEnvironment(venvPath) && c.Manager.Lookup(language, scope) == nil
+}
+
+// kernelPoolErrorMessage returns a user-facing message when err means the
+// language's kernel pool is full, or "" for any other error.
+func kernelPoolErrorMessage(language string, err error) string {
+	var poolFull kernel_manager.KernelPoolFullError
+	switch {
+	case errors.Is(err, kernel_manager.ErrNoIdleKernelToEvict):
+		return fmt.Sprintf("Stop another %s kernel to start this one.", language)
+	case errors.As(err, &poolFull):
+		return fmt.Sprintf("The limit of %d %s kernels has been reached. Stop another %s kernel to start this one.", poolFull.MaxKernels, language, language)
+	}
+	return ""
+}
+
+// environmentMismatchResponse returns the failed response for a kernel that
+// runs in the wrong Python environment, or false if err is another error.
+func environmentMismatchResponse(err error) (config.BackendResponseWithData[SendExecuteRequestResponse], bool) {
+	var mismatch kernel_manager.KernelEnvironmentMismatchError
+	if !errors.As(err, &mismatch) {
+		return config.BackendResponseWithData[SendExecuteRequestResponse]{}, false
+	}
+	return config.BackendResponseWithData[SendExecuteRequestResponse]{
+		Success: false,
+		Message: fmt.Sprintf("The running Python kernel uses %s, but this note uses %s. Restart the kernel to switch environments.",
+			displayEnvironment(mismatch.Running), displayEnvironment(mismatch.Requested)),
+		Data: SendExecuteRequestResponse{
+			KernelInstanceID:    mismatch.KernelInstanceID,
+			EnvironmentMismatch: true,
+		},
+	}, true
+}
+
+// displayEnvironment names a Python environment by its folder.
+func displayEnvironment(venvPath string) string {
+	if venvPath == "" {
+		return "no environment"
+	}
+	return filepath.Base(venvPath)
+}
+
+// SendExecuteRequest resolves (language, noteId) to a kernel instance for the note's
+// kernel scope (creating it if needed, evicting LRU idle if at the per-language cap),
+// then sends the execute_request.
 func (c *CodeService) SendExecuteRequest(noteID, codeBlockID, executionID, language, code string) config.BackendResponseWithData[SendExecuteRequestResponse] {
 	projectSettings, err := config.GetProjectSettings(c.ProjectPath)
 	if err != nil {
@@ -38,21 +142,25 @@ func (c *CodeService) SendExecuteRequest(noteID, codeBlockID, executionID, langu
 		}
 	}
 
-	if language == "python" && !util.IsVirtualEnv(projectSettings.Code.PythonVenvPath) {
+	venvPath := c.pythonEnvironmentForNote(noteID, projectSettings)
+	scope := c.kernelScopeForNote(noteID, language, projectSettings)
+
+	if c.needsVirtualEnvironment(language, scope, venvPath) {
 		return config.BackendResponseWithData[SendExecuteRequestResponse]{
 			Success: false,
 			Message: "A virtual environment is not set. A virtual environment can be configured in the \"Code Block\" section of the settings.",
 		}
 	}
 
-	venvPath := projectSettings.Code.PythonVenvPath
-
-	inst, err := c.Manager.GetOrCreate(context.Background(), language, noteID, venvPath)
+	inst, err := c.Manager.GetOrCreate(context.Background(), language, scope, venvPath)
 	if err != nil {
-		if errors.Is(err, kernel_manager.ErrNoIdleKernelToEvict) {
+		if res, ok := environmentMismatchResponse(err); ok {
+			return res
+		}
+		if message := kernelPoolErrorMessage(language, err); message != "" {
 			return config.BackendResponseWithData[SendExecuteRequestResponse]{
 				Success: false,
-				Message: fmt.Sprintf("Stop another %s kernel to start this one.", language),
+				Message: message,
 			}
 		}
 		log.Printf("get-or-create %s kernel for note %s: %v", language, noteID, err)
@@ -61,8 +169,11 @@ func (c *CodeService) SendExecuteRequest(noteID, codeBlockID, executionID, langu
 			Message: "Failed to start kernel",
 		}
 	}
+	// Requirements are checked the first time each note runs on a kernel,
+	// including notes joining a folder or project kernel another note started.
+	checkRequirements := inst.MarkRequirementsChecked(noteID)
 
-	if err := inst.SendExecute(codeBlockID, executionID, code); err != nil {
+	if err := inst.SendExecute(noteID, codeBlockID, executionID, code); err != nil {
 		if !errors.Is(err, zmq4.ErrorSocketClosed) {
 			return config.BackendResponseWithData[SendExecuteRequestResponse]{
 				Success: false,
@@ -72,12 +183,12 @@ func (c *CodeService) SendExecuteRequest(noteID, codeBlockID, executionID, langu
 
 		// If the socket is closed, try to re-create it
 		_ = c.Manager.Shutdown(inst.ID(), true)
-		inst, err = c.Manager.GetOrCreate(context.Background(), language, noteID, venvPath)
+		inst, err = c.Manager.GetOrCreate(context.Background(), language, scope, venvPath)
 		if err != nil {
-			if errors.Is(err, kernel_manager.ErrNoIdleKernelToEvict) {
+			if message := kernelPoolErrorMessage(language, err); message != "" {
 				return config.BackendResponseWithData[SendExecuteRequestResponse]{
 					Success: false,
-					Message: fmt.Sprintf("Stop another %s kernel to start this one.", language),
+					Message: message,
 				}
 			}
 			log.Printf("SendExecuteRequest: restart %s kernel for note %s: %v", language, noteID, err)
@@ -86,7 +197,8 @@ func (c *CodeService) SendExecuteRequest(noteID, codeBlockID, executionID, langu
 				Message: "Failed to restart kernel",
 			}
 		}
-		if err := inst.SendExecute(codeBlockID, executionID, code); err != nil {
+		checkRequirements = inst.MarkRequirementsChecked(noteID) || checkRequirements
+		if err := inst.SendExecute(noteID, codeBlockID, executionID, code); err != nil {
 			return config.BackendResponseWithData[SendExecuteRequestResponse]{
 				Success: false,
 				Message: fmt.Sprintf("Failed to send execute request after reopening kernel: %v", err),
@@ -98,11 +210,178 @@ func (c *CodeService) SendExecuteRequest(noteID, codeBlockID, executionID, langu
 	return config.BackendResponseWithData[SendExecuteRequestResponse]{
 		Success: true,
 		Message: "Execute request sent successfully",
-		Data:    SendExecuteRequestResponse{KernelInstanceID: inst.ID()},
+		Data: SendExecuteRequestResponse{
+			KernelInstanceID:  inst.ID(),
+			CheckRequirements: checkRequirements,
+		},
+	}
+}
+
+// requirementsCheckTimeout bounds asking an environment which packages it has.
+const requirementsCheckTimeout = 30 * time.Second
+
+// missingRequirementsForNote returns the packages from the note's `requires`
+// frontmatter that the note's environment lacks. Failures are logged and
+// reported as nothing missing, so a broken check never blocks execution.
+func (c *CodeService) missingRequirementsForNote(noteID, language string, scope kernel_manager.KernelScope, venvPath string) []string {
+	requires, exists, err := notes.GetRequirementsFromNote(c.ProjectPath, noteID)
+	if err != nil || !exists || len(requires) == 0 {
+		return []string{}
+	}
+	if language == "go" {
+		if inst := c.Manager.Lookup(language, scope); inst != nil {
+			return inst.UnfetchedRequirements(requires)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requirementsCheckTimeout)
+	defer cancel()
+	missing, err := kernel_manager.MissingRequirements(ctx, language, c.requirementsEnvironment(venvPath), requires)
+	if err != nil {
+		log.Printf("missingRequirementsForNote: check %s requirements for %s: %v", language, noteID, err)
+		return []string{}
+	}
+	return missing
+}
+
+func (c *CodeService) requirementsEnvironment(venvPath string) kernel_manager.RequirementsEnvironment {
+	return kernel_manager.RequirementsEnvironment{
+		PythonEnvironment: venvPath,
+		CodeFolder:        filepath.Join(c.ProjectPath, "code"),
+	}
+}
+
+// NoteRequirementsResponse describes a note's `requires` frontmatter.
+type NoteRequirementsResponse struct {
+	Requires []string `json:"requires"`
+	Missing  []string `json:"missing"`
+}
+
+// CheckNoteRequirements reports which of the note's declared requirements are
+// missing from the environment its kernel runs in.
+func (c *CodeService) CheckNoteRequirements(noteID, language string) config.BackendResponseWithData[NoteRequirementsResponse] {
+	projectSettings, err := config.GetProjectSettings(c.ProjectPath)
+	if err != nil {
+		log.Printf("CheckNoteRequirements: read project settings: %v", err)
+		return config.BackendResponseWithData[NoteRequirementsResponse]{
+			Success: false,
+			Message: "Failed to retrieve project settings.",
+		}
+	}
+	requires, _, err := notes.GetRequirementsFromNote(c.ProjectPath, noteID)
+	if err != nil {
+		log.Printf("CheckNoteRequirements: read frontmatter for %s: %v", noteID, err)
+		return config.BackendResponseWithData[NoteRequirementsResponse]{
+			Success: false,
+			Message: "Failed to read the note's requirements.",
+		}
+	}
+	if err := kernel_manager.ValidateRequirements(language, requires); err != nil {
+		return config.BackendResponseWithData[NoteRequirementsResponse]{
+			Success: false,
+			Message: fmt.Sprintf("The note's requirements are invalid: %v", err),
+		}
+	}
+	venvPath := c.pythonEnvironmentForNote(noteID, projectSettings)
+	scope := c.kernelScopeForNote(noteID, language, projectSettings)
+	return config.BackendResponseWithData[NoteRequirementsResponse]{
+		Success: true,
+		Message: "Requirements checked",
+		Data: NoteRequirementsResponse{
+			Requires: requires,
+			Missing:  c.missingRequirementsForNote(noteID, language, scope, venvPath),
+		},
 	}
 }
 
-// EnsureKernel launches a kernel for (language, noteId) without sending an execute_request.
+// InstallNoteRequirements installs the note's missing requirements, streaming
+// the package manager's output to the code block as if it were the block's
+// own execution. Python packages are installed with pip (or uv) into the
+// note's environment and npm packages into the code folder for Deno. Go
+// modules are fetched with `go get` inside the gonb kernel, which is started
+// if needed.
+func (c *CodeService) InstallNoteRequirements(noteID, codeBlockID, executionID, language string) config.BackendResponseWithData[SendExecuteRequestResponse] {
+	projectSettings, err := config.GetProjectSettings(c.ProjectPath)
+	if err != nil {
+		log.Printf("InstallNoteRequirements: read project settings: %v", err)
+		return config.BackendResponseWithData[SendExecuteRequestResponse]{
+			Success: false,
+			Message: "Failed to retrieve project settings.",
+		}
+	}
+	venvPath := c.pythonEnvironmentForNote(noteID, projectSettings)
+	scope := c.kernelScopeForNote(noteID, language, projectSettings)
+	missing := c.missingRequirementsForNote(noteID, language, scope, venvPath)
+	if len(missing) == 0 {
+		return config.BackendResponseWithData[SendExecuteRequestResponse]{
+			Success: true,
+			Message: "All requirements are already installed",
+		}
+	}
+
+	if language == "go" {
+		inst, err := c.Manager.GetOrCreate(context.Background(), language, scope, venvPath)
+		if err != nil {
+			if message := kernelPoolErrorMessage(language, err); message != "" {
+				return config.BackendResponseWithData[SendExecuteRequestResponse]{
+					Success: false,
+					Message: message,
+				}
+			}
+			log.Printf("InstallNoteRequirements: get-or-create go kernel for note %s: %v", noteID, err)
+			return config.BackendResponseWithData[SendExecuteRequestResponse]{
+				Success: false,
+				Message: "Failed to start kernel",
+			}
+		}
+		if err := inst.FetchRequirements(noteID, codeBlockID, executionID, missing); err != nil {
+			return config.BackendResponseWithData[SendExecuteRequestResponse]{
+				Success: false,
+				Message: fmt.Sprintf("Failed to send go get request: %v", err),
+			}
+		}
+		return config.BackendResponseWithData[SendExecuteRequestResponse]{
+			Success: true,
+			Message: "Installing requirements",
+			Data:    SendExecuteRequestResponse{KernelInstanceID: inst.ID()},
+		}
+	}
+
+	messageID := fmt.Sprintf("%s|%s", codeBlockID, executionID)
+	emit := func(name string, data any) {
+		if app := application.Get(); app != nil {
+			app.Event.EmitEvent(&application.CustomEvent{Name: name, Data: data})
+		}
+	}
+	go func() {
+		start := time.Now()
+		emit(util.EventCodeBlockStatus, sockets.CodeBlockStatusEvent{MessageId: messageID, Status: "busy"})
+		err := kernel_manager.InstallRequirements(context.Background(), language, c.requirementsEnvironment(venvPath), missing, func(line string) {
+			emit(util.EventCodeBlockStream, sockets.StreamEvent{MessageId: messageID, Name: "stdout", Text: line + "\n"})
+		})
+		if err != nil {
+			log.Printf("InstallNoteRequirements: install %s requirements for %s: %v", language, noteID, err)
+			emit(util.EventCodeBlockIopubError, sockets.IopubErrorEvent{
+				MessageId:      messageID,
+				ErrorName:      "InstallError",
+				ErrorValue:     err.Error(),
+				ErrorTraceback: []string{},
+			})
+		}
+		emit(util.EventCodeBlockStatus, sockets.CodeBlockStatusEvent{
+			MessageId: messageID,
+			Status:    "idle",
+			Duration:  util.FormatExecutionDuration(start, time.Now()),
+		})
+	}()
+
+	return config.BackendResponseWithData[SendExecuteRequestResponse]{
+		Success: true,
+		Message: "Installing requirements",
+	}
+}
+
+// EnsureKernel launches the kernel for (language, noteId)'s scope without sending an execute_request.
 // Used by the "Turn on kernel" button on code blocks.
 func (c *CodeService) EnsureKernel(noteID, language string) config.BackendResponseWithData[SendExecuteRequestResponse] {
 	projectSettings, err := config.GetProjectSettings(c.ProjectPath)
@@ -113,19 +392,23 @@ func (c *CodeService) EnsureKernel(noteID, language string) config.BackendRespon
 			Message: "Failed to retrieve project settings.",
 		}
 	}
-	if language == "python" && !util.IsVirtualEnv(projectSettings.Code.PythonVenvPath) {
+	venvPath := c.pythonEnvironmentForNote(noteID, projectSettings)
+	scope := c.kernelScopeForNote(noteID, language, projectSettings)
+	if c.needsVirtualEnvironment(language, scope, venvPath) {
 		return config.BackendResponseWithData[SendExecuteRequestResponse]{
 			Success: false,
 			Message: "A virtual environment is not set. A virtual environment can be configured in the \"Code Block\" section of the settings.",
 		}
 	}
-
-	inst, err := c.Manager.GetOrCreate(context.Background(), language, noteID, projectSettings.Code.PythonVenvPath)
+	inst, err := c.Manager.GetOrCreate(context.Background(), language, scope, venvPath)
 	if err != nil {
-		if errors.Is(err, kernel_manager.ErrNoIdleKernelToEvict) {
+		if res, ok := environmentMismatchResponse(err); ok {
+			return res
+		}
+		if message := kernelPoolErrorMessage(language, err); message != "" {
 			return config.BackendResponseWithData[SendExecuteRequestResponse]{
 				Success: false,
-				Message: fmt.Sprintf("Stop another %s kernel to start this one.", language),
+				Message: message,
 			}
 		}
 		log.Printf("get-or-create %s kernel for note %s: %v", language, noteID, err)
@@ -141,6 +424,141 @@ func (c *CodeService) EnsureKernel(noteID, language string) config.BackendRespon
 	}
 }
 
+// attachKernelErrorMessage returns a user-facing message for a failed attach.
+func attachKernelErrorMessage(err error) string {
+	var scopeInUse kernel_manager.KernelScopeInUseError
+	if errors.As(err, &scopeInUse) {
+		return fmt.Sprintf("A %s kernel is already running for this note. Stop it before attaching another one.", scopeInUse.Language)
+	}
+	return fmt.Sprintf("Failed to connect to kernel: %v", err)
+}
+
+// AttachKernelConnectionFile connects the note's kernel scope to an already
+// running kernel, e.g. one started by JupyterLab or inside a container, using
+// its connection file.
+func (c *CodeService) AttachKernelConnectionFile(noteID, language, connectionFilePath string) config.BackendResponseWithData[SendExecuteRequestResponse] {
+	projectSettings, err := config.GetProjectSettings(c.ProjectPath)
+	if err != nil {
+		log.Printf("AttachKernelConnectionFile: read project settings: %v", err)
+		return config.BackendResponseWithData[SendExecuteRequestResponse]{
+			Success: false,
+			Message: "Failed to retrieve project settings.",
+		}
+	}
+	scope := c.kernelScopeForNote(noteID, language, projectSettings)
+	inst, err := c.Manager.AttachConnectionFile(connectionFilePath, language, scope)
+	if err != nil {
+		log.Printf("AttachKernelConnectionFile: attach %s for note %s: %v", connectionFilePath, noteID, err)
+		return config.BackendResponseWithData[SendExecuteRequestResponse]{
+			Success: false,
+			Message: attachKernelErrorMessage(err),
+		}
+	}
+	return config.BackendResponseWithData[SendExecuteRequestResponse]{
+		Success: true,
+		Message: "Kernel attached",
+		Data:    SendExecuteRequestResponse{KernelInstanceID: inst.ID()},
+	}
+}
+
+// ChooseKernelConnectionFile opens a file dialog for picking a kernel
+// connection file.
+func (c *CodeService) ChooseKernelConnectionFile() config.BackendResponseWithData[string] {
+	app := application.Get()
+	if app == nil || app.Dialog == nil {
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Application not initialized",
+		}
+	}
+
+	localFilePath, err := app.Dialog.OpenFile().
+		CanChooseDirectories(false).
+		CanChooseFiles(true).
+		AddFilter("Kernel connection file", "*.json").
+		PromptForSingleSelection()
+
+	if err != nil {
+		log.Printf("ChooseKernelConnectionFile: open file dialog: %v", err)
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Failed to open file dialog",
+		}
+	}
+
+	return config.BackendResponseWithData[string]{
+		Success: true,
+		Data:    localFilePath,
+		Message: "Successfully selected connection file",
+	}
+}
+
+// jupyterServerTimeout bounds starting or looking up a Jupyter Server kernel.
+const jupyterServerTimeout = 30 * time.Second
+
+// ConnectJupyterServerKernel connects the note's kernel scope to a kernel on a
+// Jupyter Server. An empty kernelID starts a new kernel from the kernelName
+// kernelspec, which is shut down again with the bytebook kernel.
+func (c *CodeService) ConnectJupyterServerKernel(noteID, language, serverURL, token, kernelID, kernelName string) config.BackendResponseWithData[SendExecuteRequestResponse] {
+	projectSettings, err := config.GetProjectSettings(c.ProjectPath)
+	if err != nil {
+		log.Printf("ConnectJupyterServerKernel: read project settings: %v", err)
+		return config.BackendResponseWithData[SendExecuteRequestResponse]{
+			Success: false,
+			Message: "Failed to retrieve project settings.",
+		}
+	}
+	scope := c.kernelScopeForNote(noteID, language, projectSettings)
+
+	ctx, cancel := context.WithTimeout(context.Background(), jupyterServerTimeout)
+	defer cancel()
+	inst, err := c.Manager.ConnectJupyterServer(ctx, language, scope, kernel_manager.JupyterServerKernelParams{
+		ServerURL:  serverURL,
+		Token:      token,
+		KernelID:   kernelID,
+		KernelName: kernelName,
+	})
+	if err != nil {
+		log.Printf("ConnectJupyterServerKernel: connect to %s for note %s: %v", serverURL, noteID, err)
+		return config.BackendResponseWithData[SendExecuteRequestResponse]{
+			Success: false,
+			Message: attachKernelErrorMessage(err),
+		}
+	}
+	return config.BackendResponseWithData[SendExecuteRequestResponse]{
+		Success: true,
+		Message: "Connected to Jupyter Server kernel",
+		Data:    SendExecuteRequestResponse{KernelInstanceID: inst.ID()},
+	}
+}
+
+// ListJupyterServerKernels lists the kernels running on a Jupyter Server so
+// the user can pick one to connect to.
+func (c *CodeService) ListJupyterServerKernels(serverURL, token string) config.BackendResponseWithData[[]jupyter_protocol.ServerKernel] {
+	client, err := jupyter_protocol.NewJupyterServerClient(serverURL, token)
+	if err != nil {
+		return config.BackendResponseWithData[[]jupyter_protocol.ServerKernel]{
+			Success: false,
+			Message: err.Error(),
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), jupyterServerTimeout)
+	defer cancel()
+	kernels, err := client.ListKernels(ctx)
+	if err != nil {
+		log.Printf("ListJupyterServerKernels: %s: %v", serverURL, err)
+		return config.BackendResponseWithData[[]jupyter_protocol.ServerKernel]{
+			Success: false,
+			Message: fmt.Sprintf("Failed to list kernels: %v", err),
+		}
+	}
+	return config.BackendResponseWithData[[]jupyter_protocol.ServerKernel]{
+		Success: true,
+		Message: "Kernels listed",
+		Data:    kernels,
+	}
+}
+
 // ShutdownKernel shuts down a specific kernel instance.
 func (c *CodeService) ShutdownKernel(kernelInstanceID string, restart bool) config.BackendResponseWithoutData {
 	if err := c.Manager.Shutdown(kernelInstanceID, restart); err != nil {
@@ -253,6 +671,275 @@ func (c *CodeService) SendCompleteRequest(kernelInstanceID, codeBlockID, executi
 	}
 }
 
+type SendCommMessageResponse struct {
+	MessageId *string `json:"messageId"`
+}
+
+// SendCommMessage sends a comm_open, comm_msg or comm_close to the named
+// instance. Used by interactive widgets rendered in a code block.
+func (c *CodeService) SendCommMessage(kernelInstanceID, codeBlockID, executionID string, message kernel_manager.CommMessage) config.BackendResponseWithData[SendCommMessageResponse] {
+	inst := c.Manager.GetByID(kernelInstanceID)
+	if inst == nil {
+		return config.BackendResponseWithData[SendCommMessageResponse]{
+			Success: false,
+			Message: "Kernel instance not found",
+		}
+	}
+	if !inst.IsHeartbeating() {
+		return config.BackendResponseWithData[SendCommMessageResponse]{
+			Success: false,
+			Message: "Kernel is not running.",
+		}
+	}
+	messageID, err := inst.SendComm(codeBlockID, executionID, message)
+	if err != nil {
+		log.Printf("SendCommMessage: send %s for comm %s: %v", message.MsgType, message.CommID, err)
+		return config.BackendResponseWithData[SendCommMessageResponse]{
+			Success: false,
+			Message: fmt.Sprintf("Failed to send %s", message.MsgType),
+		}
+	}
+	return config.BackendResponseWithData[SendCommMessageResponse]{
+		Success: true,
+		Message: "Comm message sent",
+		Data:    SendCommMessageResponse{MessageId: &messageID},
+	}
+}
+
+type SendCommInfoRequestResponse struct {
+	MessageId *string `json:"messageId"`
+}
+
+// SendCommInfoRequest asks the named instance for its open comms, e.g. to
+// restore widgets after the frontend reloads. The reply arrives as an event.
+func (c *CodeService) SendCommInfoRequest(kernelInstanceID, targetName string) config.BackendResponseWithData[SendCommInfoRequestResponse] {
+	inst := c.Manager.GetByID(kernelInstanceID)
+	if inst == nil {
+		return config.BackendResponseWithData[SendCommInfoRequestResponse]{
+			Success: false,
+			Message: "Kernel instance not found",
+		}
+	}
+	if !inst.IsHeartbeating() {
+		return config.BackendResponseWithData[SendCommInfoRequestResponse]{
+			Success: false,
+			Message: "Kernel is not running.",
+		}
+	}
+	messageID, err := inst.SendCommInfo(targetName)
+	if err != nil {
+		log.Printf("SendCommInfoRequest: instance %s: %v", kernelInstanceID, err)
+		return config.BackendResponseWithData[SendCommInfoRequestResponse]{
+			Success: false,
+			Message: "Failed to send comm info request",
+		}
+	}
+	return config.BackendResponseWithData[SendCommInfoRequestResponse]{
+		Success: true,
+		Message: "Comm info request sent",
+		Data:    SendCommInfoRequestResponse{MessageId: &messageID},
+	}
+}
+
+// maxTruncatedOutputPage caps how much held-back output one call may return.
+const maxTruncatedOutputPage = 256 * 1024
+
+// GetTruncatedOutput returns a page of the stream output that was held back
+// when an execution exceeded its output budget. handle comes from the
+// output truncated event.
+func (c *CodeService) GetTruncatedOutput(kernelInstanceID, handle string, offset, limit int) config.BackendResponseWithData[sockets.TruncatedOutputPage] {
+	inst := c.Manager.GetByID(kernelInstanceID)
+	if inst == nil {
+		return config.BackendResponseWithData[sockets.TruncatedOutputPage]{
+			Success: false,
+			Message: "Kernel instance not found",
+		}
+	}
+	page, err := inst.TruncatedOutput(handle, offset, min(limit, maxTruncatedOutputPage))
+	if err != nil {
+		log.Printf("GetTruncatedOutput: instance %s: %v", kernelInstanceID, err)
+		return config.BackendResponseWithData[sockets.TruncatedOutputPage]{
+			Success: false,
+			Message: "This output is no longer available",
+		}
+	}
+	return config.BackendResponseWithData[sockets.TruncatedOutputPage]{
+		Success: true,
+		Message: "Output retrieved",
+		Data:    page,
+	}
+}
+
+// variableIntrospectionTimeout bounds how long the variable explorer waits on
+// the kernel. A busy kernel queues the request behind the running cell.
+const variableIntrospectionTimeout = 10 * time.Second
+
+// ListVariables returns the user variables defined in the named instance.
+func (c *CodeService) ListVariables(kernelInstanceID string) config.BackendResponseWithData[[]kernel_manager.VariableSummary] {
+	inst := c.Manager.GetByID(kernelInstanceID)
+	if inst == nil {
+		return config.BackendResponseWithData[[]kernel_manager.VariableSummary]{
+			Success: false,
+			Message: "Kernel instance not found",
+		}
+	}
+	if !inst.IsHeartbeating() {
+		return config.BackendResponseWithData[[]kernel_manager.VariableSummary]{
+			Success: false,
+			Message: "Kernel is not running.",
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), variableIntrospectionTimeout)
+	defer cancel()
+	variables, err := inst.ListVariables(ctx)
+	if err != nil {
+		log.Printf("ListVariables: instance %s: %v", kernelInstanceID, err)
+		return config.BackendResponseWithData[[]kernel_manager.VariableSummary]{
+			Success: false,
+			Message: variableIntrospectionErrorMessage(err),
+		}
+	}
+	return config.BackendResponseWithData[[]kernel_manager.VariableSummary]{
+		Success: true,
+		Message: "Variables retrieved",
+		Data:    variables,
+	}
+}
+
+// GetVariable returns a page of rows for a single variable in the named instance.
+func (c *CodeService) GetVariable(kernelInstanceID, name string, offset, limit int) config.BackendResponseWithData[kernel_manager.VariablePreview] {
+	inst := c.Manager.GetByID(kernelInstanceID)
+	if inst == nil {
+		return config.BackendResponseWithData[kernel_manager.VariablePreview]{
+			Success: false,
+			Message: "Kernel instance not found",
+		}
+	}
+	if !inst.IsHeartbeating() {
+		return config.BackendResponseWithData[kernel_manager.VariablePreview]{
+			Success: false,
+			Message: "Kernel is not running.",
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), variableIntrospectionTimeout)
+	defer cancel()
+	preview, err := inst.GetVariable(ctx, name, offset, limit)
+	if err != nil {
+		log.Printf("GetVariable: instance %s variable %q: %v", kernelInstanceID, name, err)
+		return config.BackendResponseWithData[kernel_manager.VariablePreview]{
+			Success: false,
+			Message: variableIntrospectionErrorMessage(err),
+		}
+	}
+	return config.BackendResponseWithData[kernel_manager.VariablePreview]{
+		Success: true,
+		Message: "Variable retrieved",
+		Data:    preview,
+	}
+}
+
+// variableIntrospectionErrorMessage turns an introspection error into a
+// user-facing message.
+func variableIntrospectionErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, kernel_manager.ErrIntrospectionUnsupported):
+		return "The variable explorer is not supported for this language yet."
+	case errors.Is(err, context.DeadlineExceeded):
+		return "The kernel did not respond in time. It may be busy running a cell."
+	default:
+		return err.Error()
+	}
+}
+
+// GetKernelHistory returns the execution transcript of a kernel instance.
+// Transcripts outlive their kernel, so the instance does not need to be running.
+func (c *CodeService) GetKernelHistory(kernelInstanceID string) config.BackendResponseWithData[[]kernel_manager.TranscriptEntry] {
+	entries, err := c.Manager.KernelHistory(kernelInstanceID)
+	if err != nil {
+		log.Printf("GetKernelHistory: instance %s: %v", kernelInstanceID, err)
+		return config.BackendResponseWithData[[]kernel_manager.TranscriptEntry]{
+			Success: false,
+			Message: "Failed to read kernel history",
+		}
+	}
+	return config.BackendResponseWithData[[]kernel_manager.TranscriptEntry]{
+		Success: true,
+		Message: "Kernel history retrieved",
+		Data:    entries,
+	}
+}
+
+// GetNoteHistory returns every recorded execution sent from a note, across all
+// of the kernels it has used.
+func (c *CodeService) GetNoteHistory(noteID string) config.BackendResponseWithData[[]kernel_manager.TranscriptEntry] {
+	entries, err := c.Manager.NoteHistory(noteID)
+	if err != nil {
+		log.Printf("GetNoteHistory: note %s: %v", noteID, err)
+		return config.BackendResponseWithData[[]kernel_manager.TranscriptEntry]{
+			Success: false,
+			Message: "Failed to read note history",
+		}
+	}
+	return config.BackendResponseWithData[[]kernel_manager.TranscriptEntry]{
+		Success: true,
+		Message: "Note history retrieved",
+		Data:    entries,
+	}
+}
+
+// ReplayKernelHistoryResponse lists the executions sent by ReplayKernelHistory
+// so the frontend can follow their output events.
+type ReplayKernelHistoryResponse struct {
+	KernelInstanceID string                             `json:"kernelInstanceId"`
+	Executions       []kernel_manager.ReplayedExecution `json:"executions"`
+}
+
+// ReplayKernelHistory re-runs the successful executions recorded by
+// sourceKernelInstanceID in the kernel for (language, noteId)'s scope, starting
+// it if needed. Used to restore a session after a kernel restart or crash.
+func (c *CodeService) ReplayKernelHistory(noteID, language, sourceKernelInstanceID string) config.BackendResponseWithData[ReplayKernelHistoryResponse] {
+	entries, err := c.Manager.KernelHistory(sourceKernelInstanceID)
+	if err != nil {
+		log.Printf("ReplayKernelHistory: read history of %s: %v", sourceKernelInstanceID, err)
+		return config.BackendResponseWithData[ReplayKernelHistoryResponse]{
+			Success: false,
+			Message: "Failed to read kernel history",
+		}
+	}
+
+	ensured := c.EnsureKernel(noteID, language)
+	if !ensured.Success {
+		return config.BackendResponseWithData[ReplayKernelHistoryResponse]{
+			Success: false,
+			Message: ensured.Message,
+		}
+	}
+	inst := c.Manager.GetByID(ensured.Data.KernelInstanceID)
+	if inst == nil {
+		return config.BackendResponseWithData[ReplayKernelHistoryResponse]{
+			Success: false,
+			Message: "Kernel instance not found",
+		}
+	}
+
+	replayed, err := inst.Replay(entries)
+	if err != nil {
+		log.Printf("ReplayKernelHistory: replay %s into %s: %v", sourceKernelInstanceID, inst.ID(), err)
+		return config.BackendResponseWithData[ReplayKernelHistoryResponse]{
+			Success: false,
+			Message: fmt.Sprintf("Replay stopped after %d executions: %v", len(replayed), err),
+			Data:    ReplayKernelHistoryResponse{KernelInstanceID: inst.ID(), Executions: replayed},
+		}
+	}
+	return config.BackendResponseWithData[ReplayKernelHistoryResponse]{
+		Success: true,
+		Message: fmt.Sprintf("Replaying %d executions", len(replayed)),
+		Data:    ReplayKernelHistoryResponse{KernelInstanceID: inst.ID(), Executions: replayed},
+	}
+}
+
 // ListKernels returns snapshots of every live kernel instance.
 func (c *CodeService) ListKernels() config.BackendResponseWithData[[]kernel_manager.KernelInstanceSnapshot] {
 	return config.BackendResponseWithData[[]kernel_manager.KernelInstanceSnapshot]{
@@ -262,6 +949,32 @@ func (c *CodeService) ListKernels() config.BackendResponseWithData[[]kernel_mana
 	}
 }
 
+// KernelScopeResponse identifies the kernel scope a note's code runs in,
+// matching the scopeType and scopeId of KernelInstanceSnapshot.
+type KernelScopeResponse struct {
+	ScopeType string `json:"scopeType"`
+	ScopeID   string `json:"scopeId"`
+}
+
+// GetKernelScope resolves the kernel scope of (language, noteId), so the
+// frontend can find a folder or project kernel that another note started.
+func (c *CodeService) GetKernelScope(noteID, language string) config.BackendResponseWithData[KernelScopeResponse] {
+	projectSettings, err := config.GetProjectSettings(c.ProjectPath)
+	if err != nil {
+		log.Printf("GetKernelScope: read project settings: %v", err)
+		return config.BackendResponseWithData[KernelScopeResponse]{
+			Success: false,
+			Message: "Failed to retrieve project settings.",
+		}
+	}
+	scope := c.kernelScopeForNote(noteID, language, projectSettings)
+	return config.BackendResponseWithData[KernelScopeResponse]{
+		Success: true,
+		Message: "Kernel scope resolved",
+		Data:    KernelScopeResponse{ScopeType: scope.Type, ScopeID: scope.ID},
+	}
+}
+
 // GetPythonVirtualEnvironments retrieves the paths to all Python virtual environments in the project code directory.
 func (c *CodeService) GetPythonVirtualEnvironments() config.BackendResponseWithData[[]string] {
 	projectSettings, err := config.GetProjectSettings(c.ProjectPath)
@@ -291,6 +1004,34 @@ func (c *CodeService) GetPythonVirtualEnvironments() config.BackendResponseWithD
 	}
 }
 
+// GetPythonEnvironments lists every Python environment kernels can run in:
+// venv, uv and Poetry environments, conda environments and pyenv versions,
+// along with the user's custom paths.
+func (c *CodeService) GetPythonEnvironments() config.BackendResponseWithData[[]config.PythonEnvironment] {
+	projectSettings, err := config.GetProjectSettings(c.ProjectPath)
+	if err != nil {
+		log.Printf("GetPythonEnvironments: read project settings: %v", err)
+		return config.BackendResponseWithData[[]config.PythonEnvironment]{
+			Success: false,
+			Message: "Failed to retrieve project settings",
+		}
+	}
+
+	environments, err := config.FindPythonEnvironments(c.ProjectPath, projectSettings.Code.CustomPythonVenvPaths)
+	if err != nil {
+		log.Printf("GetPythonEnvironments: locate environments: %v", err)
+		return config.BackendResponseWithData[[]config.PythonEnvironment]{
+			Success: false,
+			Message: "Failed to locate Python environments",
+		}
+	}
+	return config.BackendResponseWithData[[]config.PythonEnvironment]{
+		Success: true,
+		Message: "Successfully got Python environments",
+		Data:    environments,
+	}
+}
+
 func (c *CodeService) IsPathAValidVirtualEnvironment(path string) config.BackendResponseWithoutData {
 	if path == "" {
 		return config.BackendResponseWithoutData{
@@ -298,15 +1039,71 @@ func (c *CodeService) IsPathAValidVirtualEnvironment(path string) config.Backend
 			Message: "The provided path is empty. Please provide a valid path to a virtual environment.",
 		}
 	}
-	if util.IsVirtualEnv(path) {
+	if util.IsPythonEnvironment(path) {
 		return config.BackendResponseWithoutData{
 			Success: true,
-			Message: fmt.Sprintf("%s is a valid virtual environment", path),
+			Message: fmt.Sprintf("%s is a valid Python environment", path),
 		}
 	}
 	return config.BackendResponseWithoutData{
 		Success: false,
-		Message: fmt.Sprintf("%s is not a valid virtual environment as a pyvenv.cfg could not be found", path),
+		Message: fmt.Sprintf("%s is not a valid Python environment as no Python interpreter could be found in its bin folder", path),
+	}
+}
+
+// CreatePythonEnvironment creates a virtual environment named name in the
+// project's code folder and installs ipykernel into it. Setup runs in the
+// background; progress is reported through python-environment progress events
+// keyed by the returned path.
+func (c *CodeService) CreatePythonEnvironment(name string) config.BackendResponseWithData[string] {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || name != filepath.Base(name) {
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Environment names cannot be empty or contain slashes.",
+		}
+	}
+	path := filepath.Join(c.ProjectPath, "code", name)
+	exists, err := util.FileOrFolderExists(path)
+	if err != nil {
+		log.Printf("CreatePythonEnvironment: stat %s: %v", path, err)
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Failed to create Python environment",
+		}
+	}
+	if exists {
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: fmt.Sprintf("%s already exists in the code folder.", name),
+		}
+	}
+
+	emit := func(stage, message string) {
+		if app := application.Get(); app != nil {
+			app.Event.EmitEvent(&application.CustomEvent{
+				Name: util.EventPythonEnvironmentProgress,
+				Data: kernel_manager.PythonEnvironmentProgressEventData{
+					Path:    path,
+					Stage:   stage,
+					Message: message,
+				},
+			})
+		}
+	}
+	go func() {
+		if err := kernel_manager.CreatePythonEnvironment(context.Background(), path, emit); err != nil {
+			log.Printf("CreatePythonEnvironment: set up %s: %v", path, err)
+			emit(kernel_manager.PythonEnvironmentStageError, err.Error())
+			return
+		}
+		emit(kernel_manager.PythonEnvironmentStageDone, fmt.Sprintf("%s is ready", name))
+	}()
+
+	return config.BackendResponseWithData[string]{
+		Success: true,
+		Message: "Creating Python environment",
+		Data:    path,
 	}
 }
 
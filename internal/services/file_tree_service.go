@@ -48,15 +48,20 @@ func (f *FileTreeService) MoveItemsToFolder(itemPaths []string, newFolder string
 	// to just the top-most entries so we only issue one rename per subtree.
 	normalizedItemPaths := util.DedupeDescendantPaths(itemPaths)
 
-	notesBase := filepath.Join(f.ProjectPath, "notes")
 	failedItemNames := []string{}
 	for _, pathToItem := range normalizedItemPaths {
-		fullPathToItem, err := util.SafeJoin(notesBase, pathToItem)
+		newPathToItem := filepath.Join(newFolder, filepath.Base(pathToItem))
+		if err := checkNotesMove(f.ProjectPath, pathToItem, newPathToItem); err != nil {
+			log.Printf("MoveItemsToFolder: %s: %v", pathToItem, err)
+			failedItemNames = append(failedItemNames, pathToItem)
+			continue
+		}
+		fullPathToItem, err := config.ResolveNotesPath(f.ProjectPath, pathToItem)
 		if err != nil {
 			failedItemNames = append(failedItemNames, pathToItem)
 			continue
 		}
-		fullPathWithNewFolder, err := util.SafeJoin(notesBase, filepath.Join(newFolder, filepath.Base(pathToItem)))
+		fullPathWithNewFolder, err := config.ResolveNotesPath(f.ProjectPath, newPathToItem)
 		if err != nil {
 			failedItemNames = append(failedItemNames, pathToItem)
 			continue
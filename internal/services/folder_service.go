@@ -3,10 +3,8 @@ package services
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/etesam913/bytebook/internal/config"
-	"github.com/etesam913/bytebook/internal/util"
 )
 
 type FolderService struct {
@@ -14,7 +12,10 @@ type FolderService struct {
 }
 
 func (f *FolderService) AddFolder(folderName string) config.BackendResponseWithoutData {
-	pathToFolder, err := util.SafeJoin(filepath.Join(f.ProjectPath, "notes"), folderName)
+	if err := config.CheckNotesPathWritable(f.ProjectPath, folderName); err != nil {
+		return config.BackendResponseWithoutData{Success: false, Message: err.Error()}
+	}
+	pathToFolder, err := config.ResolveNotesPath(f.ProjectPath, folderName)
 	if err != nil {
 		return config.BackendResponseWithoutData{
 			Success: false,
@@ -51,15 +52,17 @@ func (f *FolderService) AddFolder(folderName string) config.BackendResponseWitho
 
 // Updates the folder name
 func (f *FolderService) RenameFolder(oldFolderName string, newFolderName string) config.BackendResponseWithoutData {
-	folderBase := filepath.Join(f.ProjectPath, "notes")
-	pathToOldFolder, err := util.SafeJoin(folderBase, oldFolderName)
+	if err := checkNotesMove(f.ProjectPath, oldFolderName, newFolderName); err != nil {
+		return config.BackendResponseWithoutData{Success: false, Message: err.Error()}
+	}
+	pathToOldFolder, err := config.ResolveNotesPath(f.ProjectPath, oldFolderName)
 	if err != nil {
 		return config.BackendResponseWithoutData{
 			Success: false,
 			Message: fmt.Sprintf("Invalid folder name: %s", oldFolderName),
 		}
 	}
-	pathToNewFolder, err := util.SafeJoin(folderBase, newFolderName)
+	pathToNewFolder, err := config.ResolveNotesPath(f.ProjectPath, newFolderName)
 	if err != nil {
 		return config.BackendResponseWithoutData{
 			Success: false,
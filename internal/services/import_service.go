@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/ingest"
+	"github.com/etesam913/bytebook/internal/notes"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+type ImportService struct {
+	Coordinator *ingest.BulkImportCoordinator
+}
+
+// GetImportStatus returns the in-flight, queued and last finished bulk
+// imports.
+func (s *ImportService) GetImportStatus() config.BackendResponseWithData[ingest.ImportStatus] {
+	return config.BackendResponseWithData[ingest.ImportStatus]{
+		Success: true,
+		Message: "Successfully retrieved import status",
+		Data:    s.Coordinator.Status(),
+	}
+}
+
+// GetWatcherStatus returns how many folders are watched natively and how many
+// are polled because the OS ran out of file watchers.
+func (s *ImportService) GetWatcherStatus() config.BackendResponseWithData[notes.WatcherStatus] {
+	return config.BackendResponseWithData[notes.WatcherStatus]{
+		Success: true,
+		Message: "Successfully retrieved watcher status",
+		Data:    s.Coordinator.WatcherStatus(),
+	}
+}
+
+// CancelImport stops the queued or in-flight import of folderPath, relative to
+// notes. Files already indexed and folders already watched are kept.
+func (s *ImportService) CancelImport(folderPath string) config.BackendResponseWithoutData {
+	if !s.Coordinator.CancelImport(folderPath) {
+		return config.BackendResponseWithoutData{
+			Success: false,
+			Message: "No import is running for this folder",
+		}
+	}
+
+	return config.BackendResponseWithoutData{
+		Success: true,
+		Message: "Cancelled import",
+	}
+}
+
+// ChooseExternalImportPath opens a dialog for picking the export to import
+// from source: the vault folder for Obsidian, the export zip or folder for
+// Notion and Bear, or an .enex file or folder of them for Evernote.
+func (s *ImportService) ChooseExternalImportPath(source string) config.BackendResponseWithData[string] {
+	app := application.Get()
+	if app == nil || app.Dialog == nil {
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Application not initialized",
+		}
+	}
+
+	dialog := app.Dialog.OpenFile().CanChooseDirectories(true)
+	switch ingest.ExternalSource(source) {
+	case ingest.ExternalSourceObsidian:
+		dialog = dialog.CanChooseFiles(false)
+	case ingest.ExternalSourceNotion:
+		dialog = dialog.CanChooseFiles(true).AddFilter("Notion export", "*.zip")
+	case ingest.ExternalSourceBear:
+		dialog = dialog.CanChooseFiles(true).AddFilter("Bear export", "*.textbundle;*.textpack;*.zip")
+	case ingest.ExternalSourceEvernote:
+		dialog = dialog.CanChooseFiles(true).AddFilter("Evernote export", "*.enex")
+	default:
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: fmt.Sprintf("Cannot import from %s", source),
+		}
+	}
+
+	sourcePath, err := dialog.PromptForSingleSelection()
+	if err != nil {
+		log.Printf("ChooseExternalImportPath: open file dialog: %v", err)
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Failed to open file dialog",
+		}
+	}
+
+	return config.BackendResponseWithData[string]{
+		Success: true,
+		Message: "Successfully selected export",
+		Data:    sourcePath,
+	}
+}
+
+// ImportFromApp imports the export of another app at sourcePath into a new
+// top-level notes folder. The report lists anything that could not be
+// converted.
+func (s *ImportService) ImportFromApp(source string, sourcePath string) config.BackendResponseWithData[ingest.ExternalImportReport] {
+	report, err := s.Coordinator.ImportExternal(ingest.ExternalSource(source), sourcePath)
+	if err != nil {
+		return config.BackendResponseWithData[ingest.ExternalImportReport]{
+			Success: false,
+			Message: fmt.Sprintf("Could not import: %v", err),
+			Data:    report,
+		}
+	}
+
+	return config.BackendResponseWithData[ingest.ExternalImportReport]{
+		Success: true,
+		Message: fmt.Sprintf("Imported %d notes into %s", report.Notes, report.Folder),
+		Data:    report,
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/util"
+)
+
+// resolveProjectFilePath resolves a path relative to the project directory.
+// Paths under notes/ may point into a linked folder.
+func resolveProjectFilePath(projectPath, path string) (string, error) {
+	if notesPath, ok := strings.CutPrefix(filepath.ToSlash(path), "notes/"); ok {
+		return config.ResolveNotesPath(projectPath, notesPath)
+	}
+	return util.SafeJoin(projectPath, path)
+}
+
+// checkNotesMove reports why an item cannot be renamed or moved from oldPath
+// to newPath, both relative to notes/. Linked folder roots are managed in
+// settings, read-only linked folders cannot change, and items cannot cross
+// between notes/ and a linked folder or between two linked folders.
+func checkNotesMove(projectPath, oldPath, newPath string) error {
+	if config.IsLinkedFolderRoot(projectPath, oldPath) {
+		return errors.New("linked folders can only be renamed or removed in settings")
+	}
+	if err := config.CheckNotesPathWritable(projectPath, oldPath); err != nil {
+		return err
+	}
+	if err := config.CheckNotesPathWritable(projectPath, newPath); err != nil {
+		return err
+	}
+
+	oldFolder, oldLinked := config.LinkedFolderFor(projectPath, oldPath)
+	newFolder, newLinked := config.LinkedFolderFor(projectPath, newPath)
+	if oldLinked != newLinked || oldFolder.Name != newFolder.Name {
+		return errors.New("items cannot be moved into or out of a linked folder")
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -42,17 +43,17 @@ func (s *LSPService) IsAvailable(language string) config.BackendResponseWithData
 }
 
 // Completion returns LSP completion items for (noteID, blockID) at the given
-// block-local line/col. The source is pushed to pyright before the request,
-// so callers don't need to call NotifyBlockEdit first.
-func (s *LSPService) Completion(noteID, blockID string, blockOrder int, source string, line, col int) config.BackendResponseWithData[lsp.CompletionResult] {
-	if s.Manager == nil || !s.Manager.Available("python") {
+// block-local line/col. The source is pushed to the language's server before
+// the request, so callers don't need to call NotifyBlockEdit first.
+func (s *LSPService) Completion(noteID, language, blockID string, blockOrder int, source string, line, col int) config.BackendResponseWithData[lsp.CompletionResult] {
+	if s.Manager == nil || !s.Manager.Available(language) {
 		return config.BackendResponseWithData[lsp.CompletionResult]{
 			Success: true,
 			Message: "ok",
 			Data:    lsp.CompletionResult{Available: false},
 		}
 	}
-	instance, err := s.Manager.GetOrCreate(noteID)
+	instance, err := s.Manager.GetOrCreate(noteID, language)
 	if err != nil {
 		if errors.Is(err, lsp.ErrNotAvailable) {
 			return config.BackendResponseWithData[lsp.CompletionResult]{
@@ -69,7 +70,7 @@ func (s *LSPService) Completion(noteID, blockID string, blockOrder int, source s
 	}
 
 	syncCtx, cancel := context.WithTimeout(context.Background(), DID_CHANGE_TIMEOUT)
-	// Keep Pyright's synthetic document in sync before asking for completions.
+	// Keep the server's synthetic document in sync before asking for completions.
 	// This RPC also performs the sync done by NotifyBlockEdit, so callers do not
 	// need a separate edit notification before every completion request.
 	if err := instance.DidChange(syncCtx, blockID, blockOrder, source); err != nil {
@@ -113,15 +114,15 @@ func (s *LSPService) Completion(noteID, blockID string, blockOrder int, source s
 
 // Hover returns LSP hover content for (noteID, blockID) at the given
 // block-local position.
-func (s *LSPService) Hover(noteID, blockID string, blockOrder int, source string, line, col int) config.BackendResponseWithData[lsp.HoverResult] {
-	if s.Manager == nil || !s.Manager.Available("python") {
+func (s *LSPService) Hover(noteID, language, blockID string, blockOrder int, source string, line, col int) config.BackendResponseWithData[lsp.HoverResult] {
+	if s.Manager == nil || !s.Manager.Available(language) {
 		return config.BackendResponseWithData[lsp.HoverResult]{
 			Success: true,
 			Message: "ok",
 			Data:    lsp.HoverResult{Available: false},
 		}
 	}
-	instance, err := s.Manager.GetOrCreate(noteID)
+	instance, err := s.Manager.GetOrCreate(noteID, language)
 	if err != nil {
 		if errors.Is(err, lsp.ErrNotAvailable) {
 			return config.BackendResponseWithData[lsp.HoverResult]{
@@ -138,7 +139,7 @@ func (s *LSPService) Hover(noteID, blockID string, blockOrder int, source string
 	}
 
 	syncCtx, cancel := context.WithTimeout(context.Background(), DID_CHANGE_TIMEOUT)
-	// Hover asks Pyright about the current source, so first push the latest
+	// Hover asks the server about the current source, so first push the latest
 	// block text into the synthetic note document.
 	if err := instance.DidChange(syncCtx, blockID, blockOrder, source); err != nil {
 		cancel()
@@ -177,14 +178,14 @@ func (s *LSPService) Hover(noteID, blockID string, blockOrder int, source string
 	}
 }
 
-// NotifyBlockEdit is a fire-and-forget hint to keep pyright's synthetic doc
+// NotifyBlockEdit is a fire-and-forget hint to keep the server's synthetic doc
 // fresh while the user types. The Completion call also pushes the latest
 // source, so dropping this notification is harmless.
-func (s *LSPService) NotifyBlockEdit(noteID, blockID string, blockOrder int, newSource string) config.BackendResponseWithoutData {
-	if s.Manager == nil || !s.Manager.Available("python") {
+func (s *LSPService) NotifyBlockEdit(noteID, language, blockID string, blockOrder int, newSource string) config.BackendResponseWithoutData {
+	if s.Manager == nil || !s.Manager.Available(language) {
 		return config.BackendResponseWithoutData{Success: true, Message: "lsp unavailable"}
 	}
-	instance, err := s.Manager.GetOrCreate(noteID)
+	instance, err := s.Manager.GetOrCreate(noteID, language)
 	if err != nil {
 		if errors.Is(err, lsp.ErrNotAvailable) {
 			return config.BackendResponseWithoutData{Success: true, Message: "lsp unavailable"}
@@ -201,11 +202,11 @@ func (s *LSPService) NotifyBlockEdit(noteID, blockID string, blockOrder int, new
 
 // NotifyBlockRemoved drops a block from the synthetic doc. Called when a
 // code block is unmounted from the editor.
-func (s *LSPService) NotifyBlockRemoved(noteID, blockID string) config.BackendResponseWithoutData {
+func (s *LSPService) NotifyBlockRemoved(noteID, language, blockID string) config.BackendResponseWithoutData {
 	if s.Manager == nil {
 		return config.BackendResponseWithoutData{Success: true, Message: "ok"}
 	}
-	instance, err := s.Manager.GetOrCreate(noteID)
+	instance, err := s.Manager.GetOrCreate(noteID, language)
 	if err != nil {
 		// If we can't get an instance, there's nothing to remove from anyway.
 		return config.BackendResponseWithoutData{Success: true, Message: "ok"}
@@ -218,7 +219,7 @@ func (s *LSPService) NotifyBlockRemoved(noteID, blockID string) config.BackendRe
 	return config.BackendResponseWithoutData{Success: true, Message: "ok"}
 }
 
-// NotifyNoteClosed terminates the LSP instance for a note. Called when the
+// NotifyNoteClosed terminates every LSP instance for a note. Called when the
 // editor unmounts the last code block in a note.
 func (s *LSPService) NotifyNoteClosed(noteID string) config.BackendResponseWithoutData {
 	if s.Manager == nil {
@@ -229,3 +230,282 @@ func (s *LSPService) NotifyNoteClosed(noteID string) config.BackendResponseWitho
 	}
 	return config.BackendResponseWithoutData{Success: true, Message: "ok"}
 }
+
+// GetDiagnostics returns the latest diagnostics for every code block in a
+// note, in block-local coordinates. Live updates arrive via the
+// lsp:diagnostics event; this call lets an editor that mounts late catch up.
+func (s *LSPService) GetDiagnostics(noteID string) config.BackendResponseWithData[lsp.DiagnosticsResult] {
+	if s.Manager == nil {
+		return config.BackendResponseWithData[lsp.DiagnosticsResult]{
+			Success: true,
+			Message: "ok",
+			Data:    lsp.DiagnosticsResult{Available: false},
+		}
+	}
+	return config.BackendResponseWithData[lsp.DiagnosticsResult]{
+		Success: true,
+		Message: "ok",
+		Data:    lsp.DiagnosticsResult{Available: true, Diagnostics: s.Manager.Diagnostics(noteID)},
+	}
+}
+
+// NAVIGATION_TIMEOUT caps definition, references and rename requests. These
+// are user-initiated and may walk the whole document, so they get longer than
+// completion.
+const NAVIGATION_TIMEOUT = 2 * time.Second
+
+// FORMAT_TIMEOUT caps document formatting, which can shell out to an
+// external formatter.
+const FORMAT_TIMEOUT = 5 * time.Second
+
+// syncedInstance resolves the LSP instance for (noteID, language) and pushes
+// the latest block source into its synthetic doc, so position-based requests
+// see what the user sees. available is false when the language has no
+// installed server.
+func (s *LSPService) syncedInstance(noteID, language, blockID string, blockOrder int, source string) (instance *lsp.Instance, available bool, err error) {
+	if s.Manager == nil || !s.Manager.Available(language) {
+		return nil, false, nil
+	}
+	instance, err = s.Manager.GetOrCreate(noteID, language)
+	if err != nil {
+		if errors.Is(err, lsp.ErrNotAvailable) {
+			return nil, false, nil
+		}
+		return nil, true, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DID_CHANGE_TIMEOUT)
+	defer cancel()
+	if err := instance.DidChange(ctx, blockID, blockOrder, source); err != nil {
+		return nil, true, err
+	}
+	return instance, true, nil
+}
+
+// isSoftLSPError reports errors that should read as "no results" rather than
+// a failure: a timed out request or an instance that died mid-request.
+func isSoftLSPError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, lsp.ErrInstanceDown)
+}
+
+// SignatureHelp returns the call signatures around the cursor at the given
+// block-local position.
+func (s *LSPService) SignatureHelp(noteID, language, blockID string, blockOrder int, source string, line, col int) config.BackendResponseWithData[lsp.SignatureHelpResult] {
+	instance, available, err := s.syncedInstance(noteID, language, blockID, blockOrder, source)
+	if err != nil {
+		log.Printf("SignatureHelp: prepare LSP instance for block %s: %v", blockID, err)
+		return config.BackendResponseWithData[lsp.SignatureHelpResult]{
+			Success: false,
+			Message: "Failed to update document for signature help",
+		}
+	}
+	if !available || !instance.Supports(lsp.FeatureSignatureHelp) {
+		return config.BackendResponseWithData[lsp.SignatureHelpResult]{
+			Success: true,
+			Message: "ok",
+			Data:    lsp.SignatureHelpResult{Available: false},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), HOVER_TIMEOUT)
+	defer cancel()
+	help, err := instance.SignatureHelp(ctx, blockID, protocol.Position{
+		Line:      uint32(line),
+		Character: uint32(col),
+	})
+	if err != nil {
+		if isSoftLSPError(err) {
+			return config.BackendResponseWithData[lsp.SignatureHelpResult]{
+				Success: true,
+				Message: "ok",
+				Data:    lsp.SignatureHelpResult{Available: true, Found: false},
+			}
+		}
+		log.Printf("SignatureHelp: request for block %s: %v", blockID, err)
+		return config.BackendResponseWithData[lsp.SignatureHelpResult]{
+			Success: false,
+			Message: "LSP signature help request failed",
+		}
+	}
+	result := lsp.SignatureHelpResult{Available: true, Found: help != nil}
+	if help != nil {
+		result.Help = *help
+	}
+	return config.BackendResponseWithData[lsp.SignatureHelpResult]{
+		Success: true,
+		Message: "ok",
+		Data:    result,
+	}
+}
+
+// Definition resolves the symbol at the given block-local position. The
+// returned locations may point into other blocks of the same note.
+func (s *LSPService) Definition(noteID, language, blockID string, blockOrder int, source string, line, col int) config.BackendResponseWithData[lsp.LocationsResult] {
+	return s.locations("Definition", noteID, language, blockID, blockOrder, source, line, col, func(ctx context.Context, instance *lsp.Instance, pos protocol.Position) ([]lsp.Location, error) {
+		return instance.Definition(ctx, blockID, pos)
+	})
+}
+
+// References lists every use of the symbol at the given block-local position
+// across the note's blocks, including its declaration.
+func (s *LSPService) References(noteID, language, blockID string, blockOrder int, source string, line, col int) config.BackendResponseWithData[lsp.LocationsResult] {
+	return s.locations("References", noteID, language, blockID, blockOrder, source, line, col, func(ctx context.Context, instance *lsp.Instance, pos protocol.Position) ([]lsp.Location, error) {
+		return instance.References(ctx, blockID, pos, true)
+	})
+}
+
+// locations runs a location-returning request (definition or references) and
+// wraps the result. op names the RPC in logs.
+func (s *LSPService) locations(
+	op, noteID, language, blockID string,
+	blockOrder int,
+	source string,
+	line, col int,
+	request func(context.Context, *lsp.Instance, protocol.Position) ([]lsp.Location, error),
+) config.BackendResponseWithData[lsp.LocationsResult] {
+	instance, available, err := s.syncedInstance(noteID, language, blockID, blockOrder, source)
+	if err != nil {
+		log.Printf("%s: prepare LSP instance for block %s: %v", op, blockID, err)
+		return config.BackendResponseWithData[lsp.LocationsResult]{
+			Success: false,
+			Message: "Failed to update document for " + op,
+		}
+	}
+	if !available {
+		return config.BackendResponseWithData[lsp.LocationsResult]{
+			Success: true,
+			Message: "ok",
+			Data:    lsp.LocationsResult{Available: false},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), NAVIGATION_TIMEOUT)
+	defer cancel()
+	locations, err := request(ctx, instance, protocol.Position{
+		Line:      uint32(line),
+		Character: uint32(col),
+	})
+	if err != nil {
+		if isSoftLSPError(err) {
+			return config.BackendResponseWithData[lsp.LocationsResult]{
+				Success: true,
+				Message: "ok",
+				Data:    lsp.LocationsResult{Available: true},
+			}
+		}
+		log.Printf("%s: request for block %s: %v", op, blockID, err)
+		return config.BackendResponseWithData[lsp.LocationsResult]{
+			Success: false,
+			Message: "LSP " + op + " request failed",
+		}
+	}
+	return config.BackendResponseWithData[lsp.LocationsResult]{
+		Success: true,
+		Message: "ok",
+		Data:    lsp.LocationsResult{Available: true, Locations: locations},
+	}
+}
+
+// Rename renames the symbol at the given block-local position. The frontend
+// applies the returned edits to every affected block of the note.
+func (s *LSPService) Rename(noteID, language, blockID string, blockOrder int, source string, line, col int, newName string) config.BackendResponseWithData[lsp.RenameResult] {
+	instance, available, err := s.syncedInstance(noteID, language, blockID, blockOrder, source)
+	if err != nil {
+		log.Printf("Rename: prepare LSP instance for block %s: %v", blockID, err)
+		return config.BackendResponseWithData[lsp.RenameResult]{
+			Success: false,
+			Message: "Failed to update document for rename",
+		}
+	}
+	if !available {
+		return config.BackendResponseWithData[lsp.RenameResult]{
+			Success: true,
+			Message: "ok",
+			Data:    lsp.RenameResult{Available: false},
+		}
+	}
+	if !instance.Supports(lsp.FeatureRename) {
+		return config.BackendResponseWithData[lsp.RenameResult]{
+			Success: true,
+			Message: fmt.Sprintf("The %s language server does not support renaming", language),
+			Data:    lsp.RenameResult{Available: false},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), NAVIGATION_TIMEOUT)
+	defer cancel()
+	edits, err := instance.Rename(ctx, blockID, protocol.Position{
+		Line:      uint32(line),
+		Character: uint32(col),
+	}, newName)
+	if err != nil {
+		// Unlike completion, a timed out rename is surfaced: silently doing
+		// nothing would look like the rename succeeded.
+		log.Printf("Rename: request for block %s: %v", blockID, err)
+		return config.BackendResponseWithData[lsp.RenameResult]{
+			Success: false,
+			Message: "LSP rename request failed",
+		}
+	}
+	return config.BackendResponseWithData[lsp.RenameResult]{
+		Success: true,
+		Message: "ok",
+		Data:    lsp.RenameResult{Available: true, Edits: edits},
+	}
+}
+
+// FormatDocument formats every block of one language in a note. The blocks
+// must already be synced via NotifyBlockEdit; only blocks whose source
+// changed are returned.
+func (s *LSPService) FormatDocument(noteID, language string) config.BackendResponseWithData[lsp.FormatResult] {
+	if s.Manager == nil || !s.Manager.Available(language) {
+		return config.BackendResponseWithData[lsp.FormatResult]{
+			Success: true,
+			Message: "ok",
+			Data:    lsp.FormatResult{Available: false},
+		}
+	}
+	instance, err := s.Manager.GetOrCreate(noteID, language)
+	if err != nil {
+		if errors.Is(err, lsp.ErrNotAvailable) {
+			return config.BackendResponseWithData[lsp.FormatResult]{
+				Success: true,
+				Message: "ok",
+				Data:    lsp.FormatResult{Available: false},
+			}
+		}
+		log.Printf("FormatDocument: resolve LSP instance for note %s: %v", noteID, err)
+		return config.BackendResponseWithData[lsp.FormatResult]{
+			Success: false,
+			Message: "LSP server not available",
+		}
+	}
+	if !instance.Supports(lsp.FeatureFormatting) {
+		return config.BackendResponseWithData[lsp.FormatResult]{
+			Success: true,
+			Message: fmt.Sprintf("No formatter is available for %s", language),
+			Data:    lsp.FormatResult{Available: false},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), FORMAT_TIMEOUT)
+	defer cancel()
+	blocks, err := instance.Format(ctx)
+	if err != nil {
+		if errors.Is(err, lsp.ErrDocChanged) {
+			return config.BackendResponseWithData[lsp.FormatResult]{
+				Success: false,
+				Message: "The note changed while formatting. Try again.",
+			}
+		}
+		log.Printf("FormatDocument: request for note %s: %v", noteID, err)
+		return config.BackendResponseWithData[lsp.FormatResult]{
+			Success: false,
+			Message: "LSP formatting request failed",
+		}
+	}
+	return config.BackendResponseWithData[lsp.FormatResult]{
+		Success: true,
+		Message: "ok",
+		Data:    lsp.FormatResult{Available: true, Blocks: blocks},
+	}
+}
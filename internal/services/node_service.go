@@ -1,7 +1,13 @@
 package services
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"log"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/util"
@@ -12,17 +18,38 @@ type NodeService struct {
 	ProjectPath string
 }
 
-// Copies the files from the selected folder to the project folder and returns the file paths
+// Copies the files from the selected folder to the project folder and returns the file paths.
+// A file whose content already exists in the destination folder is not copied again;
+// the existing file's path is returned instead, unless the project keeps duplicate
+// attachments. Other folders are not searched, so the same file dropped into two
+// folders is stored in both.
 func addFilePathsToProject(projectPath string, filePaths []string, folderPath string) ([]string, error) {
 	// We have to use a string for filePaths instead of an array because of a binding problem, might get fixed later on
 	newFilePaths := make([]string, 0)
 
+	if err := config.CheckNotesPathWritable(projectPath, folderPath); err != nil {
+		return []string{}, err
+	}
+
+	reuseDuplicates := !config.KeepDuplicateAttachments(projectPath)
+
 	// Process the selected file
 	if len(filePaths) > 0 {
 		for _, file := range filePaths {
 			cleanedFileName := util.CleanFileName(filepath.Base(file))
-			fileInProjectPath := filepath.Join(projectPath, "notes", folderPath, cleanedFileName)
-			fileInProjectPath, err := util.CreateUniqueNameForFileIfExists(fileInProjectPath)
+			fileInProjectPath, err := config.ResolveNotesPath(projectPath, filepath.Join(folderPath, cleanedFileName))
+			if err != nil {
+				return []string{}, err
+			}
+
+			if reuseDuplicates {
+				if existingName, ok := findDuplicateAttachment(filepath.Dir(fileInProjectPath), file); ok {
+					newFilePaths = append(newFilePaths, filepath.Join("notes", folderPath, existingName))
+					continue
+				}
+			}
+
+			fileInProjectPath, err = util.CreateUniqueNameForFileIfExists(fileInProjectPath)
 			if err != nil {
 				return []string{}, err
 			}
@@ -42,6 +69,62 @@ func addFilePathsToProject(projectPath string, filePaths []string, folderPath st
 	return newFilePaths, nil
 }
 
+// findDuplicateAttachment returns the name of an attachment in folder whose
+// content matches the file at src. Sizes are compared first so only files
+// that could match are hashed.
+func findDuplicateAttachment(folder, src string) (string, bool) {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return "", false
+	}
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return "", false
+	}
+
+	var srcHash []byte
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.EqualFold(filepath.Ext(name), ".md") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() != srcInfo.Size() {
+			continue
+		}
+
+		if srcHash == nil {
+			if srcHash, err = hashFile(src); err != nil {
+				log.Printf("could not hash %s: %v", src, err)
+				return "", false
+			}
+		}
+		existingHash, err := hashFile(filepath.Join(folder, name))
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(existingHash, srcHash) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// hashFile returns the SHA-256 hash of the file at path.
+func hashFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
 func (n *NodeService) AddAttachments(folder string) config.BackendResponseWithData[[]string] {
 	app := application.Get()
 	if app == nil || app.Dialog == nil {
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeServiceTestFile(t *testing.T, dir, name, content string) string {
+	filePath := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	return filePath
+}
+
+func TestNodeServiceAddAttachmentsFromPaths(t *testing.T) {
+	t.Run("reuses an attachment with the same content", func(t *testing.T) {
+		projectPath := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(projectPath, "notes", "folder"), 0755))
+		sourceDir := t.TempDir()
+		shot := writeServiceTestFile(t, sourceDir, "shot.png", "screenshot")
+		sameShot := writeServiceTestFile(t, sourceDir, "shot copy.png", "screenshot")
+		other := writeServiceTestFile(t, sourceDir, "other.png", "different!")
+		service := NodeService{ProjectPath: projectPath}
+
+		res := service.AddAttachmentsFromPaths("folder", []string{shot, sameShot, shot, other})
+		require.True(t, res.Success, res.Message)
+
+		assert.Equal(t, []string{
+			filepath.Join("notes", "folder", "shot.png"),
+			filepath.Join("notes", "folder", "shot.png"),
+			filepath.Join("notes", "folder", "shot.png"),
+			filepath.Join("notes", "folder", "other.png"),
+		}, res.Data)
+		entries, err := os.ReadDir(filepath.Join(projectPath, "notes", "folder"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.NoFileExists(t, filepath.Join(projectPath, "settings", "settings.json"))
+	})
+
+	t.Run("copies duplicates when the project keeps them", func(t *testing.T) {
+		projectPath := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(projectPath, "notes", "folder"), 0755))
+		require.NoError(t, os.MkdirAll(filepath.Join(projectPath, "settings"), 0755))
+		require.NoError(t, util.WriteJsonToPath(
+			filepath.Join(projectPath, "settings", "settings.json"),
+			config.ProjectSettingsJson{Attachments: config.AttachmentsProjectSettingsJson{KeepDuplicates: true}},
+		))
+		shot := writeServiceTestFile(t, t.TempDir(), "shot.png", "screenshot")
+		service := NodeService{ProjectPath: projectPath}
+
+		res := service.AddAttachmentsFromPaths("folder", []string{shot, shot})
+		require.True(t, res.Success, res.Message)
+
+		assert.Equal(t, []string{
+			filepath.Join("notes", "folder", "shot.png"),
+			filepath.Join("notes", "folder", "shot 1.png"),
+		}, res.Data)
+	})
+}
@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 
 	"github.com/etesam913/bytebook/internal/config"
+	"github.com/etesam913/bytebook/internal/export"
 	"github.com/etesam913/bytebook/internal/notes/sidecar"
 	"github.com/etesam913/bytebook/internal/util"
+	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 type NoteService struct {
@@ -17,14 +19,17 @@ type NoteService struct {
 
 // RenameFile renames a file or folder from oldFolderNotePath to newFolderNotePath.
 // Both paths should be relative to the notes directory (e.g., "folder/note.ext" or "folder").
+// Items cannot leave or enter a linked folder, or change inside a read-only one.
 // Returns a BackendResponseWithData containing the new path or an error message.
 func (n *NoteService) RenameFile(oldFolderNotePath string, newFolderNotePath string) config.BackendResponseWithData[string] {
-	notesRoot := filepath.Join(n.ProjectPath, "notes")
-	oldPath, err := util.SafeJoin(notesRoot, oldFolderNotePath)
+	if err := checkNotesMove(n.ProjectPath, oldFolderNotePath, newFolderNotePath); err != nil {
+		return config.BackendResponseWithData[string]{Success: false, Message: err.Error(), Data: ""}
+	}
+	oldPath, err := config.ResolveNotesPath(n.ProjectPath, oldFolderNotePath)
 	if err != nil {
 		return config.BackendResponseWithData[string]{Success: false, Message: err.Error(), Data: ""}
 	}
-	newPath, err := util.SafeJoin(notesRoot, newFolderNotePath)
+	newPath, err := config.ResolveNotesPath(n.ProjectPath, newFolderNotePath)
 	if err != nil {
 		return config.BackendResponseWithData[string]{Success: false, Message: err.Error(), Data: ""}
 	}
@@ -144,7 +149,7 @@ func (n *NoteService) RenameFile(oldFolderNotePath string, newFolderNotePath str
 
 // GetNoteMarkdownWithCodeResults reads markdown and its Bytebook code-result sidecar.
 func (n *NoteService) GetNoteMarkdownWithCodeResults(path string) config.BackendResponseWithData[sidecar.NoteWithCodeResults] {
-	noteFilePath, err := util.SafeJoin(n.ProjectPath, path)
+	noteFilePath, err := resolveProjectFilePath(n.ProjectPath, path)
 	if err != nil {
 		return config.BackendResponseWithData[sidecar.NoteWithCodeResults]{Success: false, Message: err.Error()}
 	}
@@ -183,7 +188,10 @@ func (n *NoteService) SetNoteMarkdownWithCodeResults(
 	codeResults sidecar.CodeResults,
 ) config.BackendResponseWithData[string] {
 	noteName := fmt.Sprintf("%s.md", noteTitle)
-	noteFilePath, err := util.SafeJoin(filepath.Join(n.ProjectPath, "notes"), filepath.Join(folderName, noteName))
+	if err := config.CheckNotesPathWritable(n.ProjectPath, folderName); err != nil {
+		return config.BackendResponseWithData[string]{Success: false, Message: err.Error(), Data: ""}
+	}
+	noteFilePath, err := config.ResolveNotesPath(n.ProjectPath, filepath.Join(folderName, noteName))
 	if err != nil {
 		return config.BackendResponseWithData[string]{Success: false, Message: err.Error(), Data: ""}
 	}
@@ -217,8 +225,10 @@ func (n *NoteService) SetNoteMarkdownWithCodeResults(
 // AddNoteToFolder creates a new empty markdown note with the given noteName in the specified folder.
 // Returns a BackendResponseWithoutData indicating success or failure.
 func (n *NoteService) AddNoteToFolder(folderName string, noteName string) config.BackendResponseWithoutData {
-	notesRoot := filepath.Join(n.ProjectPath, "notes")
-	pathToNote, err := util.SafeJoin(notesRoot, filepath.Join(folderName, fmt.Sprintf("%s.md", noteName)))
+	if err := config.CheckNotesPathWritable(n.ProjectPath, folderName); err != nil {
+		return config.BackendResponseWithoutData{Success: false, Message: err.Error()}
+	}
+	pathToNote, err := config.ResolveNotesPath(n.ProjectPath, filepath.Join(folderName, fmt.Sprintf("%s.md", noteName)))
 	if err != nil {
 		return config.BackendResponseWithoutData{Success: false, Message: err.Error()}
 	}
@@ -254,8 +264,18 @@ func (n *NoteService) AddNoteToFolder(folderName string, noteName string) config
 }
 
 // MoveToTrash moves the specified folders and notes to the trash directory.
-// It returns restore metadata for app-level undo support.
+// It returns restore metadata for app-level undo support. Items in linked
+// folders are refused, since the trash can only restore into notes/.
 func (n *NoteService) MoveToTrash(folderAndNotes []string) config.BackendResponseWithData[[]util.TrashRestoreInfo] {
+	for _, folderAndNote := range folderAndNotes {
+		if folder, ok := config.LinkedFolderFor(n.ProjectPath, folderAndNote); ok {
+			return config.BackendResponseWithData[[]util.TrashRestoreInfo]{
+				Success: false,
+				Message: fmt.Sprintf("Items in the linked folder %s cannot be moved to the trash", folder.Name),
+				Data:    []util.TrashRestoreInfo{},
+			}
+		}
+	}
 	restoreItems, err := util.MoveNotesToTrash(n.ProjectPath, folderAndNotes)
 	if err != nil {
 		return config.BackendResponseWithData[[]util.TrashRestoreInfo]{
@@ -297,7 +317,7 @@ func (n *NoteService) RevealFolderOrFileInFinder(
 	path := pathToFolderOrFile
 
 	if shouldPrefixWithProjectPath {
-		safePath, err := util.SafeJoin(n.ProjectPath, pathToFolderOrFile)
+		safePath, err := resolveProjectFilePath(n.ProjectPath, pathToFolderOrFile)
 		if err != nil {
 			return config.BackendResponseWithoutData{Success: false, Message: err.Error()}
 		}
@@ -332,10 +352,93 @@ func (n *NoteService) RevealFolderOrFileInFinder(
 // DoesNoteExist checks if a note exists at the given path relative to the project's notes directory.
 // Returns true if the note exists, false otherwise.
 func (n *NoteService) DoesNoteExist(path string) bool {
-	fullPath, err := util.SafeJoin(filepath.Join(n.ProjectPath, "notes"), path)
+	fullPath, err := config.ResolveNotesPath(n.ProjectPath, path)
 	if err != nil {
 		return false
 	}
 	doesExist, _ := util.FileOrFolderExists(fullPath)
 	return doesExist
 }
+
+// ChooseExportPath opens a directory dialog for picking where exported notes
+// or websites are written.
+func (n *NoteService) ChooseExportPath() config.BackendResponseWithData[string] {
+	app := application.Get()
+	if app == nil || app.Dialog == nil {
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Application not initialized",
+		}
+	}
+
+	folderPath, err := app.Dialog.OpenFile().
+		CanChooseDirectories(true).
+		CanChooseFiles(false).
+		PromptForSingleSelection()
+	if err != nil {
+		log.Printf("ChooseExportPath: open file dialog: %v", err)
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Failed to open file dialog",
+		}
+	}
+
+	return config.BackendResponseWithData[string]{
+		Success: true,
+		Message: "Successfully selected folder",
+		Data:    folderPath,
+	}
+}
+
+// ExportFolderToSite exports folder, relative to notes/, as a static website
+// in a new folder inside parentPath. Pass "" to export the whole vault. The
+// report lists links that pointed outside the export.
+func (n *NoteService) ExportFolderToSite(folder string, parentPath string) config.BackendResponseWithData[export.SiteReport] {
+	siteName := "Bytebook Site"
+	if folder != "" {
+		siteName = filepath.Base(folder) + " Site"
+	}
+	destination, err := util.CreateUniqueNameForFileIfExists(filepath.Join(parentPath, siteName))
+	if err != nil {
+		return config.BackendResponseWithData[export.SiteReport]{Success: false, Message: err.Error()}
+	}
+
+	report, err := export.ExportSite(n.ProjectPath, folder, destination)
+	if err != nil {
+		return config.BackendResponseWithData[export.SiteReport]{
+			Success: false,
+			Message: fmt.Sprintf("Could not export website: %v", err),
+			Data:    report,
+		}
+	}
+
+	return config.BackendResponseWithData[export.SiteReport]{
+		Success: true,
+		Message: fmt.Sprintf("Exported %d notes to %s", report.Notes, filepath.Base(destination)),
+		Data:    report,
+	}
+}
+
+// ExportNotes exports the notes and folders at paths, relative to notes/, to
+// format ("markdown", "html", "pdf" or "docx") inside parentPath. Notes that
+// fail to export are listed in the report rather than failing the export.
+func (n *NoteService) ExportNotes(paths []string, format string, parentPath string) config.BackendResponseWithData[export.ExportReport] {
+	report, err := export.ExportNotes(n.ProjectPath, paths, export.Format(format), parentPath)
+	if err != nil {
+		return config.BackendResponseWithData[export.ExportReport]{
+			Success: false,
+			Message: fmt.Sprintf("Could not export notes: %v", err),
+			Data:    report,
+		}
+	}
+
+	noun := "notes"
+	if report.Notes == 1 {
+		noun = "note"
+	}
+	return config.BackendResponseWithData[export.ExportReport]{
+		Success: true,
+		Message: fmt.Sprintf("Exported %d %s to %s", report.Notes, noun, filepath.Base(report.Destination)),
+		Data:    report,
+	}
+}
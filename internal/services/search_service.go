@@ -43,27 +43,6 @@ type LinkedMention struct {
 	Note   string `json:"note"`
 }
 
-func buildEncodedNoteURLPath(pathToNote string) string {
-	trimmed := strings.Trim(pathToNote, "/")
-	if trimmed == "" {
-		return ""
-	}
-
-	encodedSegments := make([]string, 0, len(strings.Split(trimmed, "/")))
-	for _, segment := range strings.Split(trimmed, "/") {
-		if segment == "" {
-			continue
-		}
-		encodedSegments = append(encodedSegments, events.EncodeLinkSegment(segment))
-	}
-
-	if len(encodedSegments) == 0 {
-		return ""
-	}
-
-	return "/notes/" + strings.Join(encodedSegments, "/")
-}
-
 func splitNotePath(pathToNote string) (folder string, note string, ok bool) {
 	trimmed := strings.Trim(pathToNote, "/")
 	if trimmed == "" {
@@ -288,7 +267,7 @@ func (s *SearchService) RemoveSavedSearch(name string) config.BackendResponseWit
 // internal link to the given note. pathToNote is expected in the form
 // "<folder>/<noteName>".
 func (s *SearchService) GetLinkedMentions(pathToNote string, pageSize int) config.BackendResponseWithData[[]LinkedMention] {
-	urlPath := buildEncodedNoteURLPath(pathToNote)
+	urlPath := util.EncodeNoteURLPath(pathToNote)
 	if urlPath == "" {
 		return config.BackendResponseWithData[[]LinkedMention]{
 			Success: true,
@@ -342,3 +321,76 @@ func (s *SearchService) RegenerateSearchIndex() config.BackendResponseWithoutDat
 		Message: "Successfully regenerated search index",
 	}
 }
+
+// CheckIndexHealth reports the index schema version and compares the indexed
+// documents against the notes and attachments on disk.
+func (s *SearchService) CheckIndexHealth() config.BackendResponseWithData[search.IndexHealth] {
+	idx := s.Index.RLock()
+	defer s.Index.RUnlock()
+
+	health, err := search.CheckIndexHealth(s.ProjectPath, idx)
+	if err != nil {
+		return config.BackendResponseWithData[search.IndexHealth]{
+			Success: false,
+			Message: err.Error(),
+		}
+	}
+
+	return config.BackendResponseWithData[search.IndexHealth]{
+		Success: true,
+		Message: "Successfully checked search index health",
+		Data:    health,
+	}
+}
+
+// GetUnusedAttachments lists the attachments that no note links to or embeds,
+// as recorded in the search index. Attachments in linked folders are left out,
+// since they cannot be moved to the trash.
+func (s *SearchService) GetUnusedAttachments() config.BackendResponseWithData[[]search.UnusedAttachment] {
+	unused, err := func() ([]search.UnusedAttachment, error) {
+		idx := s.Index.RLock()
+		defer s.Index.RUnlock()
+		return search.FindUnusedAttachments(idx)
+	}()
+	if err != nil {
+		return config.BackendResponseWithData[[]search.UnusedAttachment]{
+			Success: false,
+			Message: err.Error(),
+			Data:    []search.UnusedAttachment{},
+		}
+	}
+
+	attachments := make([]search.UnusedAttachment, 0, len(unused))
+	for _, attachment := range unused {
+		if _, ok := config.LinkedFolderFor(s.ProjectPath, attachment.Path); ok {
+			continue
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return config.BackendResponseWithData[[]search.UnusedAttachment]{
+		Success: true,
+		Message: "Successfully found unused attachments",
+		Data:    attachments,
+	}
+}
+
+// CheckUnusedAttachments returns the paths that are still unused according to
+// the notes on disk. It is called right before unused attachments are moved to
+// the trash, since the index may not have caught up with recent edits.
+func (s *SearchService) CheckUnusedAttachments(paths []string) config.BackendResponseWithData[[]string] {
+	unused, err := search.StillUnusedAttachments(s.ProjectPath, paths)
+	if err != nil {
+		log.Printf("CheckUnusedAttachments: %v", err)
+		return config.BackendResponseWithData[[]string]{
+			Success: false,
+			Message: "Failed to check which attachments are still unused",
+			Data:    []string{},
+		}
+	}
+	return config.BackendResponseWithData[[]string]{
+		Success: true,
+		Message: "Successfully checked unused attachments",
+		Data:    unused,
+	}
+}
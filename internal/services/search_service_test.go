@@ -35,13 +35,6 @@ func indexTestNotes(t *testing.T, projectPath string, index bleve.Index, relativ
 	require.NoError(t, search.IndexDiscoveredFiles(projectPath, discoveredPaths, index, 1))
 }
 
-func TestBuildEncodedNoteURLPath(t *testing.T) {
-	assert.Equal(t, "/notes/root.md", buildEncodedNoteURLPath("root.md"))
-	assert.Equal(t, "/notes/team/specs/doc.md", buildEncodedNoteURLPath("team/specs/doc.md"))
-	assert.Equal(t, "/notes/My%20Folder/doc%20%281%29.md", buildEncodedNoteURLPath("My Folder/doc (1).md"))
-	assert.Equal(t, "", buildEncodedNoteURLPath("/"))
-}
-
 func TestSplitNotePath(t *testing.T) {
 	folder, note, ok := splitNotePath("root.md")
 	assert.True(t, ok)
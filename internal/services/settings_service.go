@@ -1,10 +1,13 @@
 package services
 
 import (
+	"fmt"
+	"log"
 	"path/filepath"
 
 	"github.com/etesam913/bytebook/internal/config"
 	"github.com/etesam913/bytebook/internal/util"
+	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 type SettingsService struct {
@@ -31,9 +34,19 @@ func (s *SettingsService) GetProjectSettings() config.BackendResponseWithData[co
 	}
 }
 
+// UpdateProjectSettings writes newProjectSettings to settings.json. Linked
+// folders are kept as they are on disk; they change only through
+// AddLinkedFolder and RemoveLinkedFolder, which validate them.
 func (s *SettingsService) UpdateProjectSettings(
 	newProjectSettings config.ProjectSettingsJson) config.BackendResponseWithData[config.ProjectSettingsJson] {
 	projectSettingsPath := filepath.Join(s.ProjectPath, "settings", "settings.json")
+	var currentProjectSettings config.ProjectSettingsJson
+	if err := util.ReadJsonFromPath(projectSettingsPath, &currentProjectSettings); err == nil {
+		newProjectSettings.LinkedFolders = currentProjectSettings.LinkedFolders
+	}
+	if newProjectSettings.LinkedFolders == nil {
+		newProjectSettings.LinkedFolders = []config.LinkedFolderJson{}
+	}
 	newProjectSettings.PinnedNotes = config.GetValidPinned(s.ProjectPath, newProjectSettings)
 	err := util.WriteJsonToPath(projectSettingsPath, newProjectSettings)
 	if err != nil {
@@ -48,3 +61,71 @@ func (s *SettingsService) UpdateProjectSettings(
 		Data:    newProjectSettings,
 	}
 }
+
+// ChooseLinkedFolderPath opens a directory dialog for picking the external
+// folder to link into the vault.
+func (s *SettingsService) ChooseLinkedFolderPath() config.BackendResponseWithData[string] {
+	app := application.Get()
+	if app == nil || app.Dialog == nil {
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Application not initialized",
+		}
+	}
+
+	folderPath, err := app.Dialog.OpenFile().
+		CanChooseDirectories(true).
+		CanChooseFiles(false).
+		PromptForSingleSelection()
+	if err != nil {
+		log.Printf("ChooseLinkedFolderPath: open file dialog: %v", err)
+		return config.BackendResponseWithData[string]{
+			Success: false,
+			Message: "Failed to open file dialog",
+		}
+	}
+
+	return config.BackendResponseWithData[string]{
+		Success: true,
+		Message: "Successfully selected folder",
+		Data:    folderPath,
+	}
+}
+
+// AddLinkedFolder mounts the directory at folderPath into the vault as a
+// top-level folder called name. The settings change is picked up by the file
+// watcher, which starts watching and indexing the folder.
+func (s *SettingsService) AddLinkedFolder(name string, folderPath string, readOnly bool) config.BackendResponseWithoutData {
+	err := config.AddLinkedFolder(s.ProjectPath, config.LinkedFolderJson{
+		Name:     name,
+		Path:     folderPath,
+		ReadOnly: readOnly,
+	})
+	if err != nil {
+		return config.BackendResponseWithoutData{
+			Success: false,
+			Message: fmt.Sprintf("Could not link folder: %v", err),
+		}
+	}
+
+	return config.BackendResponseWithoutData{
+		Success: true,
+		Message: fmt.Sprintf("Linked %s", name),
+	}
+}
+
+// RemoveLinkedFolder unmounts the linked folder called name. Its files stay
+// where they are; only the watches and search documents are dropped.
+func (s *SettingsService) RemoveLinkedFolder(name string) config.BackendResponseWithoutData {
+	if err := config.RemoveLinkedFolder(s.ProjectPath, name); err != nil {
+		return config.BackendResponseWithoutData{
+			Success: false,
+			Message: fmt.Sprintf("Could not unlink folder: %v", err),
+		}
+	}
+
+	return config.BackendResponseWithoutData{
+		Success: true,
+		Message: fmt.Sprintf("Unlinked %s", name),
+	}
+}
@@ -5,7 +5,7 @@ import "github.com/wailsapp/wails/v3/pkg/application"
 // Event name constants. Every custom Wails event used by the app is named here.
 // Events whose payload types live in this package are registered in init below;
 // events with payload types in other packages (config, notes, kernel_manager,
-// jupyter_protocol/sockets) are registered in those packages' init functions.
+// lsp, jupyter_protocol/sockets) are registered in those packages' init functions.
 // Each event must be registered exactly once — application.RegisterEvent panics
 // on duplicates — and registration gives emit-time payload validation plus
 // generated TypeScript typings for the frontend.
@@ -16,6 +16,10 @@ const (
 	EventFileRename = "file:rename"
 	EventFileWrite  = "file:write"
 
+	// EventLinksUnchanged reports links to a renamed file that could not be
+	// updated because the notes holding them are in read-only linked folders.
+	EventLinksUnchanged = "links:unchanged"
+
 	// Folder events
 	EventFolderRename = "folder:rename"
 	EventFolderDelete = "folder:delete"
@@ -40,6 +44,11 @@ const (
 	EventTagsIndexUpdate   = "tags:index_update"
 	EventSavedSearchUpdate = "saved-search:update"
 	EventCodeResultsUpdate = "code-results:update"
+	EventIgnoreRulesUpdate = "ignore:update"
+
+	// Search index events
+	EventSearchReconcileProgress = "search:reconcile:progress"
+	EventImportProgress          = "import:progress"
 
 	// Kernel instance events (per-instance, not per-language)
 	EventKernelInstanceCreated     = "kernel:instance:created"
@@ -49,17 +58,35 @@ const (
 	EventKernelInstanceLaunchError = "kernel:instance:launch_error"
 	EventKernelInstanceExited      = "kernel:instance:exited"
 
+	EventKernelInstanceExecutionTimeout = "kernel:instance:execution_timeout"
+	EventKernelInstanceReaped           = "kernel:instance:reaped"
+
+	// Python environment setup events
+	EventPythonEnvironmentProgress = "code:python-environment:progress"
+
+	// Kernel comm events (ipywidgets and other comm targets)
+	EventKernelCommOpen      = "kernel:comm:open"
+	EventKernelCommMsg       = "kernel:comm:msg"
+	EventKernelCommClose     = "kernel:comm:close"
+	EventKernelCommInfoReply = "kernel:comm:info_reply"
+
+	// LSP events
+	EventLSPDiagnostics = "lsp:diagnostics"
+
 	// Code block events (scoped by messageId)
-	EventCodeBlockStream        = "code:code-block:stream"
-	EventCodeBlockExecuteResult = "code:code-block:execute_result"
-	EventCodeBlockDisplayData   = "code:code-block:display_data"
-	EventCodeBlockExecuteInput  = "code:code-block:execute_input"
-	EventCodeBlockStatus        = "code:code-block:status"
-	EventCodeBlockIopubError    = "code:code-block:iopub_error"
-	EventCodeBlockInputRequest  = "code:code-block:input_request"
-	EventCodeBlockInspectReply  = "code:code-block:inspect_reply"
-	EventCodeBlockCompleteReply = "code:code-block:complete_reply"
-	EventCodeBlockExecuteReply  = "code:code-block:execute_reply"
+	EventCodeBlockStream            = "code:code-block:stream"
+	EventCodeBlockExecuteResult     = "code:code-block:execute_result"
+	EventCodeBlockDisplayData       = "code:code-block:display_data"
+	EventCodeBlockUpdateDisplayData = "code:code-block:update_display_data"
+	EventCodeBlockClearOutput       = "code:code-block:clear_output"
+	EventCodeBlockOutputTruncated   = "code:code-block:output_truncated"
+	EventCodeBlockExecuteInput      = "code:code-block:execute_input"
+	EventCodeBlockStatus            = "code:code-block:status"
+	EventCodeBlockIopubError        = "code:code-block:iopub_error"
+	EventCodeBlockInputRequest      = "code:code-block:input_request"
+	EventCodeBlockInspectReply      = "code:code-block:inspect_reply"
+	EventCodeBlockCompleteReply     = "code:code-block:complete_reply"
+	EventCodeBlockExecuteReply      = "code:code-block:execute_reply"
 )
 
 // A map of folderAndNoteNames to tags
@@ -103,6 +130,54 @@ type FileWriteEventData struct {
 	Markdown string `json:"markdown,omitempty"`
 }
 
+// UnchangedLinkEventData is a link to a renamed file that was left pointing
+// at OldURLPath because NotePath, relative to notes, is read-only.
+type UnchangedLinkEventData struct {
+	NotePath   string `json:"notePath"`
+	OldURLPath string `json:"oldUrlPath"`
+	NewURLPath string `json:"newUrlPath"`
+}
+
+// IgnoreRulesUpdateEventData represents the data structure for ignore rules
+// update events, emitted when a .bytebookignore file is created, edited or
+// removed. FolderPath is the folder containing it, relative to notes ("" is the
+// notes root).
+type IgnoreRulesUpdateEventData struct {
+	FolderPath string `json:"folderPath"`
+}
+
+// SearchReconcileProgressEventData reports progress of the startup pass that
+// reconciles the search index with the notes on disk. Phase is "rebuilding"
+// while an index with an outdated schema is rebuilt from scratch, "comparing"
+// while indexed documents are matched against disk, "indexing" while changed
+// files are re-indexed (Processed of Total), and "done" once finished, when the
+// counts describe the whole pass.
+type SearchReconcileProgressEventData struct {
+	Phase     string `json:"phase"`
+	Processed int    `json:"processed"`
+	Total     int    `json:"total"`
+	Indexed   int    `json:"indexed"`
+	Deleted   int    `json:"deleted"`
+	Unchanged int    `json:"unchanged"`
+}
+
+// ImportProgressEventData reports progress of a bulk subtree import such as a
+// large folder dropped into the vault. FolderPath is relative to notes ("" is
+// the notes root). Phase is "queued" while the import waits for the copy to
+// settle, "discovering", "indexing", "watching", and finally "done" or
+// "cancelled". Polled counts directories that fell back to polling because the
+// OS refused more watches; Warning then explains how to raise the limit.
+type ImportProgressEventData struct {
+	FolderPath  string `json:"folderPath"`
+	Phase       string `json:"phase"`
+	Discovered  int    `json:"discovered"`
+	Indexed     int    `json:"indexed"`
+	Directories int    `json:"directories"`
+	Watched     int    `json:"watched"`
+	Polled      int    `json:"polled"`
+	Warning     string `json:"warning,omitempty"`
+}
+
 // ContentDropEventData represents dropped OS files over a registered drop target
 // (file tree or editor).
 type ContentDropEventData struct {
@@ -122,6 +197,7 @@ func init() {
 	application.RegisterEvent[[]FolderCreateEventData](EventFolderCreate)
 	application.RegisterEvent[[]FolderDeleteEventData](EventFolderDelete)
 	application.RegisterEvent[[]FolderRenameEventData](EventFolderRename)
+	application.RegisterEvent[[]UnchangedLinkEventData](EventLinksUnchanged)
 
 	application.RegisterEvent[application.Void](EventZoomIn)
 	application.RegisterEvent[application.Void](EventZoomOut)
@@ -138,4 +214,7 @@ func init() {
 	application.RegisterEvent[TagsUpdateEventData](EventTagsUpdate)
 	application.RegisterEvent[application.Void](EventTagsIndexUpdate)
 	application.RegisterEvent[application.Void](EventSavedSearchUpdate)
+	application.RegisterEvent[[]IgnoreRulesUpdateEventData](EventIgnoreRulesUpdate)
+	application.RegisterEvent[SearchReconcileProgressEventData](EventSearchReconcileProgress)
+	application.RegisterEvent[ImportProgressEventData](EventImportProgress)
 }
@@ -15,6 +15,32 @@ func IsVirtualEnv(dir string) bool {
 	return false
 }
 
+// IsCondaEnv checks if a directory is a conda environment (or a conda base
+// install) by looking for its "conda-meta" directory.
+func IsCondaEnv(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, "conda-meta"))
+	return err == nil && info.IsDir()
+}
+
+// PythonInterpreter returns the path of the Python interpreter inside an
+// environment or installation directory, or "" if it has none. Virtual
+// environments, conda environments and pyenv versions all keep it in bin.
+func PythonInterpreter(dir string) string {
+	for _, name := range []string{"python3", "python"} {
+		pythonPath := filepath.Join(dir, "bin", name)
+		if info, err := os.Stat(pythonPath); err == nil && !info.IsDir() {
+			return pythonPath
+		}
+	}
+	return ""
+}
+
+// IsPythonEnvironment reports whether dir contains a Python interpreter that a
+// kernel can be launched with.
+func IsPythonEnvironment(dir string) bool {
+	return PythonInterpreter(dir) != ""
+}
+
 // IsSupportedLanguage reports whether the given language string corresponds to a
 // kernel descriptor known to the kernel_manager.
 func IsSupportedLanguage(language string) bool {
@@ -1,7 +1,9 @@
 package util
 
 import (
+	"fmt"
 	"path/filepath"
+	"strings"
 )
 
 // SplitFolderAndFile normalizes a folderAndFileName into folder and filename parts.
@@ -13,3 +15,45 @@ func SplitFolderAndFile(folderAndFileName string) (string, string) {
 	fileName := filepath.Base(folderAndFileName)
 	return folder, fileName
 }
+
+// EncodeLinkSegment percent-encodes a path segment using the same rules as the
+// frontend's encodeLinkUrl (JS encodeURIComponent + ( )-escaping) so generated
+// URL paths match the encoded form stored in markdown and indexed in bleve.
+func EncodeLinkSegment(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'),
+			c == '-', c == '_', c == '.', c == '~', c == '!', c == '*', c == '\'':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// EncodeNoteURLPath turns a path relative to notes/ into the encoded
+// "/notes/..." URL used for links between notes. It returns "" for an empty
+// path.
+func EncodeNoteURLPath(pathToNote string) string {
+	trimmed := strings.Trim(filepath.ToSlash(pathToNote), "/")
+	if trimmed == "" {
+		return ""
+	}
+
+	encodedSegments := make([]string, 0, len(strings.Split(trimmed, "/")))
+	for _, segment := range strings.Split(trimmed, "/") {
+		if segment == "" {
+			continue
+		}
+		encodedSegments = append(encodedSegments, EncodeLinkSegment(segment))
+	}
+
+	if len(encodedSegments) == 0 {
+		return ""
+	}
+
+	return "/notes/" + strings.Join(encodedSegments, "/")
+}
@@ -41,3 +41,10 @@ func TestSplitFolderAndFile(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeNoteURLPath(t *testing.T) {
+	assert.Equal(t, "/notes/root.md", EncodeNoteURLPath("root.md"))
+	assert.Equal(t, "/notes/team/specs/doc.md", EncodeNoteURLPath("team/specs/doc.md"))
+	assert.Equal(t, "/notes/My%20Folder/doc%20%281%29.md", EncodeNoteURLPath("My Folder/doc (1).md"))
+	assert.Equal(t, "", EncodeNoteURLPath("/"))
+}